@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// minRefreshInterval limits key refetches triggered by unknown key IDs
+const minRefreshInterval = 30 * time.Second
+
+// keySet fetches and caches an issuer's JSON Web Key Set
+type keySet struct {
+	issuer  string
+	jwksURL string
+	client  *http.Client
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+func newKeySet(issuer, jwksURL string, client *http.Client, ttl time.Duration, now func() time.Time) *keySet {
+	return &keySet{issuer: strings.TrimSuffix(issuer, "/"), jwksURL: jwksURL, client: client, ttl: ttl, now: now}
+}
+
+// get returns the key with the given ID, refreshing the set when it is stale or the key is unknown
+func (ks *keySet) get(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	age := ks.now().Sub(ks.fetchedAt)
+	key, ok := ks.lookup(kid)
+	if ok && age < ks.ttl {
+		return key, nil
+	}
+	if !ok && ks.keys != nil && age < minRefreshInterval {
+		return nil, calque.WrapErr(ctx, ErrInvalidToken, "unknown signing key "+kid)
+	}
+
+	if err := ks.refresh(ctx); err != nil {
+		if ok {
+			return key, nil // keep using cached keys while the issuer is unreachable
+		}
+		return nil, err
+	}
+	if key, ok = ks.lookup(kid); !ok {
+		return nil, calque.WrapErr(ctx, ErrInvalidToken, "unknown signing key "+kid)
+	}
+	return key, nil
+}
+
+// lookup finds a key by ID; tokens without a kid match a single-key set
+func (ks *keySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, true
+		}
+	}
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// refresh fetches the key set, discovering its URL from the issuer if needed
+func (ks *keySet) refresh(ctx context.Context) error {
+	if ks.jwksURL == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := ks.getJSON(ctx, ks.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+			return calque.WrapErr(ctx, err, "oidc discovery failed")
+		}
+		if discovery.JWKSURI == "" {
+			return calque.NewErr(ctx, "oidc discovery returned no jwks_uri")
+		}
+		ks.jwksURL = discovery.JWKSURI
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := ks.getJSON(ctx, ks.jwksURL, &set); err != nil {
+		return calque.WrapErr(ctx, err, "failed to fetch jwks")
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	ks.keys = keys
+	ks.fetchedAt = ks.now()
+	return nil
+}
+
+func (ks *keySet) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return calque.NewErr(ctx, url+" returned "+resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// jwk is a JSON Web Key (RFC 7517)
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	ctx := context.Background()
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, calque.NewErr(ctx, "unsupported curve "+k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, calque.NewErr(ctx, "unsupported key type "+k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,385 @@
+// Package auth provides JWT/OIDC authentication for flow-serving endpoints.
+//
+// JWT validates bearer tokens issued by an OIDC provider (keys are discovered
+// from the issuer's /.well-known/openid-configuration and cached), injects the
+// token claims into the request context and the flow's MetadataBus, and
+// optionally maps token scopes to the flows a caller may run. The same
+// verifier plugs into httpserver handlers and the remote gRPC server.
+//
+// Example:
+//
+//	jwt := auth.JWTWithConfig("https://accounts.example.com", "calque-api", &auth.Config{
+//		FlowScopes: map[string][]string{
+//			"summarize": {"flows:read"},
+//			"ingest":    {"flows:write"},
+//		},
+//	})
+//
+//	// HTTP
+//	http.Handle("POST /flows/{flow}", httpserver.Chain(httpserver.Handler(flow), jwt.HTTP))
+//
+//	// gRPC
+//	server := grpc.NewServerWithOptions(":9090", jwt.ServerOptions()...)
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	_ "crypto/sha512" // registers SHA-384/512 for RS384, RS512 and ES384
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/secrets"
+)
+
+// DefaultLeeway is the clock skew tolerated when checking exp, nbf and iat.
+const DefaultLeeway = time.Minute
+
+// MetadataBus keys set for authenticated requests.
+const (
+	MetadataSubject = "auth.subject"
+	MetadataIssuer  = "auth.issuer"
+	MetadataScopes  = "auth.scopes"
+	MetadataClaims  = "auth.claims"
+)
+
+// Authentication and authorization errors.
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+	ErrForbidden    = errors.New("flow not permitted for token scopes")
+)
+
+// Claims holds the validated contents of a token.
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	Scopes    []string
+	ExpiresAt time.Time
+	IssuedAt  time.Time
+	// Raw holds every claim in the token payload
+	Raw map[string]any
+}
+
+// HasScope reports whether the token was granted a scope.
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+// Config holds configuration for JWT authentication
+type Config struct {
+	// JWKSURL overrides OIDC discovery with a fixed JSON Web Key Set URL
+	JWKSURL string
+	// HMACSecret enables HS256 tokens signed with a shared secret (optional)
+	HMACSecret *secrets.Ref
+	// FlowScopes maps flow names to the scopes allowed to run them (any one
+	// suffices). When set, flows that aren't listed are denied. When nil, any
+	// valid token may run any flow.
+	FlowScopes map[string][]string
+	// FlowName extracts the flow name from an HTTP request.
+	// Default: the {flow} path value, or the last path segment.
+	FlowName func(r *http.Request) string
+	// Leeway is the tolerated clock skew. Default: DefaultLeeway
+	Leeway time.Duration
+	// KeyCacheTTL is how long fetched keys are cached. Default: 1 hour
+	KeyCacheTTL time.Duration
+	// HTTPClient is used for discovery and key fetches. Default: client with 10s timeout
+	HTTPClient *http.Client
+	// OnFailure is called when a request is rejected (optional)
+	OnFailure func(ctx context.Context, err error)
+
+	now func() time.Time // for tests
+}
+
+// JWTAuth validates bearer tokens and authorizes flow access.
+type JWTAuth struct {
+	issuer   string
+	audience string
+	config   Config
+	keys     *keySet
+}
+
+// JWT creates an authenticator for tokens from issuer intended for audience.
+//
+// Input: bearer tokens from the Authorization header (HTTP) or metadata (gRPC)
+// Output: requests carrying validated Claims, or 401/Unauthenticated
+// Behavior: Verifies RS256/RS384/RS512/ES256/ES384 signatures against the
+// issuer's published keys, and iss, aud, exp and nbf claims
+//
+// Example:
+//
+//	jwt := auth.JWT("https://accounts.google.com", "my-client-id")
+//	handler := jwt.HTTP(httpserver.Handler(flow))
+func JWT(issuer, audience string) *JWTAuth {
+	return JWTWithConfig(issuer, audience, nil)
+}
+
+// JWTWithConfig creates a JWT authenticator with custom configuration.
+//
+// Example:
+//
+//	jwt := auth.JWTWithConfig(issuer, "calque-api", &auth.Config{
+//		FlowScopes: map[string][]string{"admin-report": {"admin"}},
+//	})
+func JWTWithConfig(issuer, audience string, config *Config) *JWTAuth {
+	cfg := Config{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Leeway <= 0 {
+		cfg.Leeway = DefaultLeeway
+	}
+	if cfg.KeyCacheTTL <= 0 {
+		cfg.KeyCacheTTL = time.Hour
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.FlowName == nil {
+		cfg.FlowName = defaultFlowName
+	}
+	if cfg.now == nil {
+		cfg.now = time.Now
+	}
+
+	return &JWTAuth{
+		issuer:   strings.TrimSuffix(issuer, "/"),
+		audience: audience,
+		config:   cfg,
+		keys:     newKeySet(issuer, cfg.JWKSURL, cfg.HTTPClient, cfg.KeyCacheTTL, cfg.now),
+	}
+}
+
+// Verify validates a compact JWT and returns its claims.
+func (a *JWTAuth) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, calque.WrapErr(ctx, ErrInvalidToken, "malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, calque.WrapErr(ctx, ErrInvalidToken, "malformed header")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, calque.WrapErr(ctx, ErrInvalidToken, "malformed signature")
+	}
+	if err := a.verifySignature(ctx, header.Alg, header.Kid, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, err
+	}
+
+	var raw map[string]any
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return nil, calque.WrapErr(ctx, ErrInvalidToken, "malformed payload")
+	}
+	claims := parseClaims(raw)
+	if err := a.validateClaims(ctx, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Authorize reports whether claims permit running the named flow.
+func (a *JWTAuth) Authorize(ctx context.Context, claims *Claims, flow string) error {
+	if a.config.FlowScopes == nil {
+		return nil
+	}
+	for _, scope := range a.config.FlowScopes[flow] {
+		if claims.HasScope(scope) {
+			return nil
+		}
+	}
+	return calque.WrapErr(ctx, ErrForbidden, "flow "+flow)
+}
+
+// verifySignature checks the token signature for the algorithm in the header
+func (a *JWTAuth) verifySignature(ctx context.Context, alg, kid string, signed, sig []byte) error {
+	if alg == "HS256" {
+		if a.config.HMACSecret == nil {
+			return calque.WrapErr(ctx, ErrInvalidToken, "HS256 not enabled")
+		}
+		secret, err := a.config.HMACSecret.Resolve(ctx)
+		if err != nil {
+			return err
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return calque.WrapErr(ctx, ErrInvalidToken, "signature mismatch")
+		}
+		return nil
+	}
+
+	hash, ok := algHashes[alg]
+	if !ok {
+		return calque.WrapErr(ctx, ErrInvalidToken, "unsupported algorithm "+alg)
+	}
+	key, err := a.keys.get(ctx, kid)
+	if err != nil {
+		return err
+	}
+
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") || rsa.VerifyPKCS1v15(k, hash, digest, sig) != nil {
+			return calque.WrapErr(ctx, ErrInvalidToken, "signature mismatch")
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(alg, "ES") || len(sig) != 2*size {
+			return calque.WrapErr(ctx, ErrInvalidToken, "signature mismatch")
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return calque.WrapErr(ctx, ErrInvalidToken, "signature mismatch")
+		}
+	default:
+		return calque.WrapErr(ctx, ErrInvalidToken, "unsupported key type")
+	}
+	return nil
+}
+
+var algHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+}
+
+// validateClaims checks issuer, audience and time-based claims
+func (a *JWTAuth) validateClaims(ctx context.Context, c *Claims) error {
+	if strings.TrimSuffix(c.Issuer, "/") != a.issuer {
+		return calque.WrapErr(ctx, ErrInvalidToken, "unexpected issuer "+c.Issuer)
+	}
+	if a.audience != "" && !slices.Contains(c.Audience, a.audience) {
+		return calque.WrapErr(ctx, ErrInvalidToken, "token not intended for "+a.audience)
+	}
+
+	now := a.config.now()
+	if c.ExpiresAt.IsZero() {
+		return calque.WrapErr(ctx, ErrInvalidToken, "missing exp claim")
+	}
+	if now.After(c.ExpiresAt.Add(a.config.Leeway)) {
+		return calque.WrapErr(ctx, ErrTokenExpired, "expired at "+c.ExpiresAt.Format(time.RFC3339))
+	}
+	if nbf, ok := numericTime(c.Raw["nbf"]); ok && now.Add(a.config.Leeway).Before(nbf) {
+		return calque.WrapErr(ctx, ErrInvalidToken, "token not yet valid")
+	}
+	return nil
+}
+
+// parseClaims extracts registered claims and scopes from a payload
+func parseClaims(raw map[string]any) *Claims {
+	c := &Claims{Raw: raw}
+	c.Subject, _ = raw["sub"].(string)
+	c.Issuer, _ = raw["iss"].(string)
+	c.ExpiresAt, _ = numericTime(raw["exp"])
+	c.IssuedAt, _ = numericTime(raw["iat"])
+
+	switch aud := raw["aud"].(type) {
+	case string:
+		c.Audience = []string{aud}
+	case []any:
+		c.Audience = stringSlice(aud)
+	}
+
+	// OAuth2 "scope" is space-separated; some providers use a "scp" array
+	if scope, ok := raw["scope"].(string); ok {
+		c.Scopes = strings.Fields(scope)
+	}
+	switch scp := raw["scp"].(type) {
+	case string:
+		c.Scopes = append(c.Scopes, strings.Fields(scp)...)
+	case []any:
+		c.Scopes = append(c.Scopes, stringSlice(scp)...)
+	}
+	return c
+}
+
+func stringSlice(values []any) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// numericTime converts a JWT NumericDate claim
+func numericTime(v any) (time.Time, bool) {
+	f, ok := v.(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(f), 0), true
+}
+
+// decodeSegment decodes a base64url JSON segment
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+type claimsKey struct{}
+
+// WithClaims stores validated claims in the context.
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the authenticated caller, or nil.
+//
+// Example:
+//
+//	if claims := auth.ClaimsFromContext(req.Context); claims != nil {
+//		calque.LogInfo(req.Context, "request", "sub", claims.Subject)
+//	}
+func ClaimsFromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsKey{}).(*Claims)
+	return claims
+}
+
+// withFlowMetadata stores claims in the context and on the MetadataBus for the flow run.
+// The returned func closes the bus if one was created here.
+func withFlowMetadata(ctx context.Context, claims *Claims) (context.Context, func()) {
+	ctx = WithClaims(ctx, claims)
+	done := func() {}
+	mb := calque.GetMetadataBus(ctx)
+	if mb == nil {
+		mb = calque.NewMetadataBus(0)
+		ctx = calque.WithMetadataBus(ctx, mb)
+		done = mb.Close
+	}
+	mb.Set(MetadataSubject, claims.Subject)
+	mb.Set(MetadataIssuer, claims.Issuer)
+	mb.Set(MetadataScopes, claims.Scopes)
+	mb.Set(MetadataClaims, claims.Raw)
+	return ctx, done
+}
@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/secrets"
+)
+
+var testNow = time.Unix(1700000000, 0)
+
+// testIssuer serves OIDC discovery and a JWKS document
+type testIssuer struct {
+	server    *httptest.Server
+	rsaKey    *rsa.PrivateKey
+	ecKey     *ecdsa.PrivateKey
+	keys      []map[string]string
+	jwksHits  atomic.Int32
+	discovery atomic.Int32
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ti := &testIssuer{rsaKey: rsaKey, ecKey: ecKey}
+	ti.keys = []map[string]string{
+		{"kty": "RSA", "kid": "rsa1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec1", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		ti.discovery.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]string{"jwks_uri": ti.server.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, _ *http.Request) {
+		ti.jwksHits.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": ti.keys})
+	})
+	ti.server = httptest.NewServer(mux)
+	t.Cleanup(ti.server.Close)
+	return ti
+}
+
+func (ti *testIssuer) claims(overrides map[string]any) map[string]any {
+	c := map[string]any{
+		"iss":   ti.server.URL,
+		"aud":   "calque-api",
+		"sub":   "user-1",
+		"exp":   testNow.Add(time.Hour).Unix(),
+		"iat":   testNow.Unix(),
+		"scope": "flows:read profile",
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+	}
+	return c
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func signToken(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		sig, _ = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		r, s, _ := ecdsa.Sign(rand.Reader, k, digest[:])
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	}
+	return signed + "." + b64(sig)
+}
+
+func newTestAuth(ti *testIssuer, cfg *Config) *JWTAuth {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	cfg.now = func() time.Time { return testNow }
+	return JWTWithConfig(ti.server.URL, "calque-api", cfg)
+}
+
+func TestJWTAuth_Verify(t *testing.T) {
+	ti := newTestIssuer(t)
+	hmacKey := []byte("shared")
+	a := newTestAuth(ti, &Config{HMACSecret: secrets.Static(string(hmacKey))})
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"rs256", signToken(t, "RS256", "rsa1", ti.rsaKey, ti.claims(nil)), nil},
+		{"es256", signToken(t, "ES256", "ec1", ti.ecKey, ti.claims(nil)), nil},
+		{"hs256", signToken(t, "HS256", "", hmacKey, ti.claims(nil)), nil},
+		{"audience array", signToken(t, "RS256", "rsa1", ti.rsaKey, ti.claims(map[string]any{"aud": []string{"other", "calque-api"}})), nil},
+		{"expired", signToken(t, "RS256", "rsa1", ti.rsaKey, ti.claims(map[string]any{"exp": testNow.Add(-time.Hour).Unix()})), ErrTokenExpired},
+		{"within leeway", signToken(t, "RS256", "rsa1", ti.rsaKey, ti.claims(map[string]any{"exp": testNow.Add(-30 * time.Second).Unix()})), nil},
+		{"not yet valid", signToken(t, "RS256", "rsa1", ti.rsaKey, ti.claims(map[string]any{"nbf": testNow.Add(time.Hour).Unix()})), ErrInvalidToken},
+		{"missing exp", signToken(t, "RS256", "rsa1", ti.rsaKey, ti.claims(map[string]any{"exp": nil})), ErrInvalidToken},
+		{"wrong issuer", signToken(t, "RS256", "rsa1", ti.rsaKey, ti.claims(map[string]any{"iss": "https://evil.example.com"})), ErrInvalidToken},
+		{"wrong audience", signToken(t, "RS256", "rsa1", ti.rsaKey, ti.claims(map[string]any{"aud": "other"})), ErrInvalidToken},
+		{"wrong key", signToken(t, "RS256", "ec1", ti.rsaKey, ti.claims(nil)), ErrInvalidToken},
+		{"bad hmac", signToken(t, "HS256", "", []byte("guess"), ti.claims(nil)), ErrInvalidToken},
+		{"alg none", b64([]byte(`{"alg":"none"}`)) + "." + b64([]byte(`{}`)) + ".", ErrInvalidToken},
+		{"malformed", "not-a-token", ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := a.Verify(ctx, tt.token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if claims.Subject != "user-1" || !claims.HasScope("flows:read") {
+				t.Errorf("claims = %+v", claims)
+			}
+		})
+	}
+
+	if got := ti.discovery.Load(); got != 1 {
+		t.Errorf("discovery fetched %d times, want 1", got)
+	}
+}
+
+func TestJWTAuth_KeyRotation(t *testing.T) {
+	ti := newTestIssuer(t)
+	a := newTestAuth(ti, nil)
+	ctx := context.Background()
+
+	if _, err := a.Verify(ctx, signToken(t, "RS256", "rsa1", ti.rsaKey, ti.claims(nil))); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	// The issuer publishes a new key; unknown kids trigger a refetch after the minimum interval
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ti.keys = append(ti.keys, map[string]string{"kty": "RSA", "kid": "rsa2", "n": b64(newKey.N.Bytes()), "e": b64(big.NewInt(int64(newKey.E)).Bytes())})
+	token := signToken(t, "RS256", "rsa2", newKey, ti.claims(nil))
+
+	if _, err := a.Verify(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() before refresh interval error = %v, want ErrInvalidToken", err)
+	}
+
+	a.keys.now = func() time.Time { return testNow.Add(time.Minute) }
+	if _, err := a.Verify(ctx, token); err != nil {
+		t.Errorf("Verify() after refresh error = %v", err)
+	}
+	if got := ti.jwksHits.Load(); got != 2 {
+		t.Errorf("jwks fetched %d times, want 2", got)
+	}
+}
+
+func TestJWTAuth_Authorize(t *testing.T) {
+	claims := &Claims{Scopes: []string{"flows:read"}}
+	ctx := context.Background()
+
+	open := JWT("https://issuer", "aud")
+	if err := open.Authorize(ctx, claims, "anything"); err != nil {
+		t.Errorf("Authorize() without FlowScopes error = %v", err)
+	}
+
+	scoped := JWTWithConfig("https://issuer", "aud", &Config{FlowScopes: map[string][]string{
+		"summarize": {"flows:read", "flows:admin"},
+		"ingest":    {"flows:write"},
+	}})
+	tests := []struct {
+		flow    string
+		allowed bool
+	}{
+		{"summarize", true},
+		{"ingest", false},
+		{"unlisted", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.flow, func(t *testing.T) {
+			err := scoped.Authorize(ctx, claims, tt.flow)
+			if tt.allowed != (err == nil) {
+				t.Errorf("Authorize(%s) error = %v, want allowed=%v", tt.flow, err, tt.allowed)
+			}
+			if err != nil && !errors.Is(err, ErrForbidden) {
+				t.Errorf("Authorize(%s) error = %v, want ErrForbidden", tt.flow, err)
+			}
+		})
+	}
+}
+
+func TestParseClaims_Scopes(t *testing.T) {
+	claims := parseClaims(map[string]any{"scope": "a b", "scp": []any{"c"}})
+	for _, s := range []string{"a", "b", "c"} {
+		if !claims.HasScope(s) {
+			t.Errorf("missing scope %q in %v", s, claims.Scopes)
+		}
+	}
+}
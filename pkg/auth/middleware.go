@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/httpserver"
+)
+
+// HTTP is httpserver middleware that authenticates requests and authorizes the target flow.
+//
+// Input: HTTP request with "Authorization: Bearer <token>"
+// Output: request passed to next with claims in its context, or 401/403
+// Behavior: Validates the token, checks FlowScopes for the flow named by
+// Config.FlowName, then stores claims via WithClaims and on the MetadataBus
+//
+// Example:
+//
+//	mux.Handle("POST /flows/{flow}", jwt.HTTP(httpserver.Handler(router)))
+func (a *JWTAuth) HTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		claims, err := a.authenticate(ctx, r.Header.Get("Authorization"))
+		if err == nil {
+			err = a.Authorize(ctx, claims, a.config.FlowName(r))
+		}
+		if err != nil {
+			a.fail(ctx, err)
+			if errors.Is(err, ErrForbidden) {
+				httpserver.WriteError(w, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			httpserver.WriteError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+			return
+		}
+
+		ctx, done := withFlowMetadata(ctx, claims)
+		defer done()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// UnaryInterceptor authenticates unary gRPC calls.
+//
+// Requests exposing GetFlowName (such as calquepb.FlowRequest) are authorized
+// against FlowScopes; failures return Unauthenticated or PermissionDenied.
+func (a *JWTAuth) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		claims, err := a.authenticate(ctx, incomingAuthorization(ctx))
+		if err != nil {
+			return nil, a.grpcError(ctx, err)
+		}
+		if err := a.authorizeMessage(ctx, claims, req); err != nil {
+			return nil, a.grpcError(ctx, err)
+		}
+
+		ctx, done := withFlowMetadata(ctx, claims)
+		defer done()
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor authenticates streaming gRPC calls.
+//
+// The token is checked when the stream opens, and every received message
+// exposing GetFlowName is authorized against FlowScopes.
+func (a *JWTAuth) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		claims, err := a.authenticate(ctx, incomingAuthorization(ctx))
+		if err != nil {
+			return a.grpcError(ctx, err)
+		}
+
+		ctx, done := withFlowMetadata(ctx, claims)
+		defer done()
+		return handler(srv, &authStream{ServerStream: ss, ctx: ctx, auth: a, claims: claims})
+	}
+}
+
+// ServerOptions returns the unary and stream interceptors as gRPC server options.
+func (a *JWTAuth) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(a.UnaryInterceptor()),
+		grpc.ChainStreamInterceptor(a.StreamInterceptor()),
+	}
+}
+
+// authenticate validates a bearer Authorization value
+func (a *JWTAuth) authenticate(ctx context.Context, authorization string) (*Claims, error) {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, calque.WrapErr(ctx, ErrMissingToken, "authorization header")
+	}
+	return a.Verify(ctx, strings.TrimSpace(token))
+}
+
+// authorizeMessage checks flow access for messages that name a flow
+func (a *JWTAuth) authorizeMessage(ctx context.Context, claims *Claims, msg any) error {
+	if named, ok := msg.(interface{ GetFlowName() string }); ok {
+		return a.Authorize(ctx, claims, named.GetFlowName())
+	}
+	return nil
+}
+
+func (a *JWTAuth) fail(ctx context.Context, err error) {
+	if a.config.OnFailure != nil {
+		a.config.OnFailure(ctx, err)
+	}
+}
+
+// grpcError reports a failure and converts it to a gRPC status
+func (a *JWTAuth) grpcError(ctx context.Context, err error) error {
+	a.fail(ctx, err)
+	if errors.Is(err, ErrForbidden) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return status.Error(codes.Unauthenticated, err.Error())
+}
+
+// incomingAuthorization reads the authorization value from gRPC metadata
+func incomingAuthorization(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get("authorization"); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// defaultFlowName uses the {flow} path value, falling back to the last path segment
+func defaultFlowName(r *http.Request) string {
+	if name := r.PathValue("flow"); name != "" {
+		return name
+	}
+	return path.Base(r.URL.Path)
+}
+
+// authStream carries the authenticated context and authorizes each received message
+type authStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	auth   *JWTAuth
+	claims *Claims
+}
+
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *authStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if err := s.auth.authorizeMessage(s.ctx, s.claims, m); err != nil {
+		return s.auth.grpcError(s.ctx, err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/httpserver"
+	calquepb "github.com/calque-ai/go-calque/proto"
+)
+
+func TestJWTAuth_HTTP(t *testing.T) {
+	ti := newTestIssuer(t)
+	a := newTestAuth(ti, &Config{FlowScopes: map[string][]string{
+		"summarize": {"flows:read"},
+		"ingest":    {"flows:write"},
+	}})
+	token := signToken(t, "RS256", "rsa1", ti.rsaKey, ti.claims(nil))
+
+	// The flow reads the subject from the MetadataBus
+	flow := calque.NewFlow().UseFunc(func(req *calque.Request, res *calque.Response) error {
+		sub, _ := calque.GetMetadataBus(req.Context).GetString(MetadataSubject)
+		return calque.Write(res, "hello "+sub)
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /flows/{flow}", a.HTTP(httpserver.Handler(flow)))
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		wantStatus    int
+		wantBody      string
+	}{
+		{"authorized", "/flows/summarize", "Bearer " + token, http.StatusOK, "hello user-1"},
+		{"missing token", "/flows/summarize", "", http.StatusUnauthorized, ""},
+		{"wrong scheme", "/flows/summarize", "Basic " + token, http.StatusUnauthorized, ""},
+		{"invalid token", "/flows/summarize", "Bearer " + token + "x", http.StatusUnauthorized, ""},
+		{"forbidden flow", "/flows/ingest", "Bearer " + token, http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader("input"))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %q)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("missing WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestJWTAuth_UnaryInterceptor(t *testing.T) {
+	ti := newTestIssuer(t)
+	a := newTestAuth(ti, &Config{FlowScopes: map[string][]string{"summarize": {"flows:read"}}})
+	token := signToken(t, "RS256", "rsa1", ti.rsaKey, ti.claims(nil))
+	interceptor := a.UnaryInterceptor()
+
+	handler := func(ctx context.Context, _ any) (any, error) {
+		return ClaimsFromContext(ctx).Subject, nil
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		flow     string
+		wantCode codes.Code
+	}{
+		{"authorized", token, "summarize", codes.OK},
+		{"missing token", "", "summarize", codes.Unauthenticated},
+		{"forbidden flow", token, "ingest", codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.token != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+tt.token))
+			}
+
+			resp, err := interceptor(ctx, &calquepb.FlowRequest{FlowName: tt.flow}, &grpc.UnaryServerInfo{}, handler)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("code = %v, want %v (err %v)", got, tt.wantCode, err)
+			}
+			if tt.wantCode == codes.OK && resp != "user-1" {
+				t.Errorf("handler saw subject %v, want user-1", resp)
+			}
+		})
+	}
+}
+
+func TestJWTAuth_ServerOptions(t *testing.T) {
+	a := JWT("https://issuer", "aud")
+	if got := len(a.ServerOptions()); got != 2 {
+		t.Errorf("ServerOptions() returned %d options, want 2", got)
+	}
+}
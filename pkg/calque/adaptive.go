@@ -0,0 +1,154 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+// ConcurrencyAdaptive adjusts the concurrency limit at runtime with
+// additive-increase/multiplicative-decrease (AIMD), like TCP congestion
+// control. Handlers finishing quickly and without errors raise the limit by
+// about one slot per limit's worth of completions; rate limit, retryable and
+// deadline errors, or handlers running much slower than usual, cut it by
+// AdaptiveConcurrency.Backoff.
+//
+// Use it instead of guessing CPUMultiplier for API-bound flows, whose useful
+// concurrency depends on the provider rather than on local cores.
+//
+// Example usage:
+//
+//	flow := calque.NewFlow(calque.FlowConfig{
+//		MaxConcurrent: calque.ConcurrencyAdaptive,
+//		Adaptive:      calque.AdaptiveConcurrency{Min: 4, Max: 400},
+//	})
+const ConcurrencyAdaptive = -2
+
+// Adaptive concurrency defaults.
+const (
+	DefaultAdaptiveInitial  = 20
+	DefaultAdaptiveMax      = 1000
+	DefaultLatencyTolerance = 2.0
+	DefaultAdaptiveBackoff  = 0.75
+)
+
+// Latency signal tuning
+const (
+	latencyWarmup       = 10               // samples per handler before its latency is judged
+	latencyFloor        = time.Millisecond // faster handlers are never slow, their jitter is noise
+	latencyShortAlpha   = 0.2              // EWMA weight of recent durations
+	latencyLongAlpha    = 0.01             // EWMA weight of the long-run baseline
+	minLatencyTolerance = 1.0
+)
+
+// AdaptiveConcurrency tunes MaxConcurrent: ConcurrencyAdaptive.
+type AdaptiveConcurrency struct {
+	Initial int // starting limit (0 = DefaultAdaptiveInitial)
+	Min     int // lowest limit (0 = 1); never below the handlers of one run
+	Max     int // highest limit (0 = DefaultAdaptiveMax)
+
+	// LatencyTarget is the handler duration above which the limit is cut.
+	// If 0, a handler is too slow when its recent average duration exceeds
+	// LatencyTolerance times its long-run average.
+	LatencyTarget    time.Duration
+	LatencyTolerance float64 // 0 = DefaultLatencyTolerance
+	Backoff          float64 // multiplicative decrease factor in (0, 1) (0 = DefaultAdaptiveBackoff)
+}
+
+// aimd computes the adaptive limit from handler completions; guarded by
+// the admission mutex
+type aimd struct {
+	config       AdaptiveConcurrency
+	floor        int // handlers of the largest run admitted, so one run always fits
+	limit        float64
+	lastDecrease time.Time
+	baselines    map[string]*latencyBaseline
+}
+
+// latencyBaseline tracks a handler's recent and long-run average durations
+type latencyBaseline struct {
+	short   float64
+	long    float64
+	samples int
+}
+
+func newAIMD(config AdaptiveConcurrency) *aimd {
+	if config.Min <= 0 {
+		config.Min = 1
+	}
+	if config.Max <= 0 {
+		config.Max = DefaultAdaptiveMax
+	}
+	config.Max = max(config.Max, config.Min)
+	if config.Initial <= 0 {
+		config.Initial = DefaultAdaptiveInitial
+	}
+	config.Initial = min(max(config.Initial, config.Min), config.Max)
+	if config.LatencyTolerance <= minLatencyTolerance {
+		config.LatencyTolerance = DefaultLatencyTolerance
+	}
+	if config.Backoff <= 0 || config.Backoff >= 1 {
+		config.Backoff = DefaultAdaptiveBackoff
+	}
+	return &aimd{
+		config:    config,
+		floor:     config.Min,
+		limit:     float64(config.Initial),
+		baselines: make(map[string]*latencyBaseline),
+	}
+}
+
+// current returns the whole-slot limit
+func (a *aimd) current() int {
+	return int(a.limit)
+}
+
+// reserve keeps the limit at or above a run of n handlers
+func (a *aimd) reserve(n int) {
+	if n > a.floor {
+		a.floor = n
+		a.limit = math.Max(a.limit, float64(n))
+	}
+}
+
+// observe updates the limit with one finished handler that started at start
+func (a *aimd) observe(key string, start time.Time, latency time.Duration, err error, inUse int) {
+	if overloaded(err) || a.slow(key, latency) {
+		// Only handlers admitted after the last cut may cut again, so one
+		// burst of failures shrinks the limit once rather than per failure
+		if start.After(a.lastDecrease) {
+			a.limit = math.Max(float64(a.floor), math.Floor(a.limit*a.config.Backoff))
+			a.lastDecrease = time.Now()
+		}
+		return
+	}
+	if err != nil {
+		return // application errors say nothing about capacity
+	}
+	// Grow only while the limit is used, so idle flows do not drift upward
+	if float64(inUse+1)*2 >= a.limit {
+		a.limit = math.Max(float64(a.floor), math.Min(float64(a.config.Max), a.limit+1/a.limit))
+	}
+}
+
+// slow reports whether latency exceeds the target for the handler
+func (a *aimd) slow(key string, latency time.Duration) bool {
+	if a.config.LatencyTarget > 0 {
+		return latency > a.config.LatencyTarget
+	}
+	b, ok := a.baselines[key]
+	if !ok {
+		b = &latencyBaseline{short: float64(latency), long: float64(latency)}
+		a.baselines[key] = b
+	}
+	b.short += latencyShortAlpha * (float64(latency) - b.short)
+	b.long += latencyLongAlpha * (float64(latency) - b.long)
+	b.samples++
+	return b.samples > latencyWarmup && latency > latencyFloor && b.short > b.long*a.config.LatencyTolerance
+}
+
+// overloaded reports errors that signal downstream saturation
+func overloaded(err error) bool {
+	return err != nil && (IsRetryable(err) || errors.Is(err, context.DeadlineExceeded))
+}
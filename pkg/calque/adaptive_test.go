@@ -0,0 +1,157 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestAIMDIncrease(t *testing.T) {
+	a := newAIMD(AdaptiveConcurrency{Initial: 4, Max: 6})
+	start := time.Now()
+	for range 100 {
+		a.observe("h", start, time.Microsecond, nil, a.current())
+	}
+	if got := a.current(); got != 6 {
+		t.Errorf("limit = %d after fast successes, want it capped at Max 6", got)
+	}
+
+	idle := newAIMD(AdaptiveConcurrency{Initial: 10})
+	for range 100 {
+		idle.observe("h", start, time.Microsecond, nil, 0)
+	}
+	if got := idle.current(); got != 10 {
+		t.Errorf("limit = %d for an idle flow, want 10", got)
+	}
+}
+
+func TestAIMDDecrease(t *testing.T) {
+	a := newAIMD(AdaptiveConcurrency{Initial: 100, Min: 40, Backoff: 0.5})
+	start := time.Now()
+	limited := RateLimited(errors.New("429"), 0)
+
+	// One burst of failures from handlers admitted together cuts once
+	for range 10 {
+		a.observe("h", start, time.Millisecond, limited, 50)
+	}
+	if got := a.current(); got != 50 {
+		t.Errorf("limit = %d after one burst, want 50", got)
+	}
+
+	a.observe("h", time.Now(), time.Millisecond, limited, 10)
+	if got := a.current(); got != 40 {
+		t.Errorf("limit = %d, want it held at Min 40", got)
+	}
+
+	a.observe("h", time.Now(), time.Millisecond, errors.New("bad json"), 10)
+	if got := a.current(); got != 40 {
+		t.Errorf("limit = %d after an application error, want unchanged", got)
+	}
+}
+
+func TestAIMDLatency(t *testing.T) {
+	target := newAIMD(AdaptiveConcurrency{Initial: 10, LatencyTarget: 100 * time.Millisecond})
+	target.observe("h", time.Now(), 200*time.Millisecond, nil, 10)
+	if got := target.current(); got != 7 {
+		t.Errorf("limit = %d over LatencyTarget, want 7", got)
+	}
+
+	baseline := newAIMD(AdaptiveConcurrency{Initial: 10})
+	for range 50 {
+		baseline.observe("llm", time.Now(), 10*time.Millisecond, nil, 0)
+	}
+	for range 5 {
+		baseline.observe("llm", time.Now(), 100*time.Millisecond, nil, 0)
+	}
+	if got := baseline.current(); got >= 10 {
+		t.Errorf("limit = %d after a latency spike, want it cut", got)
+	}
+}
+
+func TestFlowAdaptiveConcurrency(t *testing.T) {
+	flow := NewFlow(FlowConfig{
+		MaxConcurrent: ConcurrencyAdaptive,
+		Adaptive:      AdaptiveConcurrency{Initial: 8, Min: 2},
+	})
+	if flow.sem == nil {
+		t.Fatal("ConcurrencyAdaptive flow has no admission control")
+	}
+	if _, limit := flow.Concurrency(); limit != 8 {
+		t.Errorf("initial limit = %d, want 8", limit)
+	}
+
+	flow.UseFunc(func(req *Request, _ *Response) error {
+		_, _ = io.Copy(io.Discard, req.Data)
+		return RateLimited(errors.New("slow down"), 0)
+	})
+	var out string
+	if err := flow.Run(context.Background(), "x", &out); !IsRateLimited(err) {
+		t.Fatalf("Run() error = %v, want rate limited", err)
+	}
+	if _, limit := flow.Concurrency(); limit != 6 {
+		t.Errorf("limit after rate limit = %d, want 6", limit)
+	}
+}
+
+func TestFlowAdaptiveConcurrencyKeepsRunsAdmissible(t *testing.T) {
+	flow := NewFlow(FlowConfig{
+		MaxConcurrent: ConcurrencyAdaptive,
+		Adaptive:      AdaptiveConcurrency{Initial: 4},
+	})
+	limited := true
+	flow.UseFunc(func(req *Request, res *Response) error {
+		if limited {
+			_, _ = io.Copy(io.Discard, req.Data)
+			return RateLimited(errors.New("slow down"), 0)
+		}
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+	for range 2 {
+		flow.UseFunc(func(req *Request, res *Response) error {
+			_, err := io.Copy(res.Data, req.Data)
+			return err
+		})
+	}
+
+	run := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		var out string
+		return flow.Run(ctx, "x", &out)
+	}
+	for range 5 {
+		if err := run(); !IsRateLimited(err) {
+			t.Fatalf("Run() error = %v, want rate limited", err)
+		}
+	}
+	if _, limit := flow.Concurrency(); limit != 3 {
+		t.Errorf("limit = %d after repeated rate limits, want it held at the 3 handlers of a run", limit)
+	}
+
+	limited = false
+	if err := run(); err != nil {
+		t.Fatalf("healthy run after backoff: %v", err)
+	}
+}
+
+func TestAdmissionQueuedDeadline(t *testing.T) {
+	a := newAdmission(0, time.Hour)
+	a.adaptive = newAIMD(AdaptiveConcurrency{Initial: 10})
+	if err := a.acquire(context.Background(), PriorityNormal, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// The run queued longer than its handler ran: not an overload signal
+	a.release("h", time.Now(), time.Second, context.DeadlineExceeded)
+	if _, limit := a.usage(); limit != 10 {
+		t.Errorf("limit = %d after a deadline spent queueing, want 10", limit)
+	}
+
+	a.release("h", time.Now().Add(-time.Second), time.Millisecond, context.DeadlineExceeded)
+	if _, limit := a.usage(); limit != 7 {
+		t.Errorf("limit = %d after a handler ran out its deadline, want 7", limit)
+	}
+}
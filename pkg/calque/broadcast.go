@@ -0,0 +1,201 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// DefaultBroadcastReplay is the replay buffer size of a Broadcast, in bytes.
+const DefaultBroadcastReplay = 1 << 20
+
+// ErrBroadcastLagged is returned by a Subscription whose next byte has
+// already left the replay buffer.
+var ErrBroadcastLagged = errors.New("broadcast subscriber fell behind the replay buffer")
+
+// BroadcastConfig configures NewBroadcast.
+type BroadcastConfig struct {
+	// ReplayBytes is how much recent output late subscribers can replay
+	// (0 = DefaultBroadcastReplay).
+	ReplayBytes int
+}
+
+// Broadcast fans one output stream out to any number of subscribers.
+//
+// Use it as the response writer of an in-progress run so several consumers,
+// like a user's two browser tabs, follow the same output. Writes never wait
+// for subscribers: the most recent ReplayBytes are kept in a ring buffer,
+// late joiners replay from its start, and a subscriber slower than the
+// buffer fails with ErrBroadcastLagged rather than slowing the run.
+//
+// Example:
+//
+//	b := calque.NewBroadcast()
+//	runs.Store(runID, b)
+//	go func() {
+//		err := flow.ServeFlow(calque.NewRequest(ctx, input), calque.NewResponse(b))
+//		b.CloseWithError(err)
+//	}()
+//
+//	// in each HTTP handler following the run
+//	sub := b.Subscribe(r.Context())
+//	defer sub.Close()
+//	io.Copy(w, sub)
+type Broadcast struct {
+	mu      sync.Mutex
+	ring    []byte
+	written int64         // total bytes written
+	notify  chan struct{} // closed and replaced on every write and on close
+	closed  bool
+	err     error
+}
+
+// NewBroadcast creates an open Broadcast.
+func NewBroadcast(config ...BroadcastConfig) *Broadcast {
+	cfg := BroadcastConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.ReplayBytes <= 0 {
+		cfg.ReplayBytes = DefaultBroadcastReplay
+	}
+	return &Broadcast{ring: make([]byte, cfg.ReplayBytes), notify: make(chan struct{})}
+}
+
+// Write appends p to the stream. It fails with io.ErrClosedPipe once closed.
+func (b *Broadcast) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0, io.ErrClosedPipe
+	}
+	n := len(p)
+	if len(p) > len(b.ring) {
+		b.written += int64(len(p) - len(b.ring))
+		p = p[len(p)-len(b.ring):]
+	}
+	for len(p) > 0 {
+		pos := int(b.written % int64(len(b.ring)))
+		copied := copy(b.ring[pos:], p)
+		p = p[copied:]
+		b.written += int64(copied)
+	}
+	b.wake()
+	return n, nil
+}
+
+// Close ends the stream; subscribers read io.EOF after the remaining output.
+func (b *Broadcast) Close() error {
+	return b.CloseWithError(nil)
+}
+
+// CloseWithError ends the stream; subscribers read err, or io.EOF when nil,
+// after the remaining output. Closing twice keeps the first error.
+func (b *Broadcast) CloseWithError(err error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed, b.err = true, err
+	b.wake()
+	return nil
+}
+
+// Written returns the number of bytes written so far, the offset live
+// subscribers reach next.
+func (b *Broadcast) Written() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.written
+}
+
+// Subscribe follows the stream from the oldest byte still buffered.
+// Reads stop with ctx's error when it is cancelled.
+func (b *Broadcast) Subscribe(ctx context.Context) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return &Subscription{b: b, ctx: ctx, offset: b.oldest()}
+}
+
+// SubscribeFrom follows the stream from offset, e.g. where a reconnecting
+// client stopped reading. Reads fail with ErrBroadcastLagged if offset is no
+// longer buffered; offsets past the end wait for new output.
+func (b *Broadcast) SubscribeFrom(ctx context.Context, offset int64) *Subscription {
+	return &Subscription{b: b, ctx: ctx, offset: max(offset, 0)}
+}
+
+// oldest returns the offset of the first buffered byte. Caller must hold mu.
+func (b *Broadcast) oldest() int64 {
+	return max(b.written-int64(len(b.ring)), 0)
+}
+
+// wake releases waiting subscribers. Caller must hold mu.
+func (b *Broadcast) wake() {
+	close(b.notify)
+	b.notify = make(chan struct{})
+}
+
+// Subscription reads a Broadcast from its own position. It is not safe for
+// concurrent use; subscribe once per consumer.
+type Subscription struct {
+	b      *Broadcast
+	ctx    context.Context
+	offset int64
+	closed bool
+}
+
+// Offset returns the stream offset of the next byte to read.
+func (s *Subscription) Offset() int64 {
+	return s.offset
+}
+
+// Read implements io.Reader, waiting for output until the Broadcast closes.
+func (s *Subscription) Read(p []byte) (int, error) {
+	if s.closed {
+		return 0, io.ErrClosedPipe
+	}
+	b := s.b
+	for {
+		b.mu.Lock()
+		if s.offset < b.oldest() {
+			b.mu.Unlock()
+			return 0, ErrBroadcastLagged
+		}
+		if s.offset < b.written {
+			n := 0
+			for n < len(p) && s.offset < b.written {
+				pos := int(s.offset % int64(len(b.ring)))
+				end := min(len(b.ring), pos+int(b.written-s.offset))
+				copied := copy(p[n:], b.ring[pos:end])
+				n += copied
+				s.offset += int64(copied)
+			}
+			b.mu.Unlock()
+			return n, nil
+		}
+		if b.closed {
+			err := b.err
+			b.mu.Unlock()
+			if err == nil {
+				err = io.EOF
+			}
+			return 0, err
+		}
+		wait := b.notify
+		b.mu.Unlock()
+
+		select {
+		case <-wait:
+		case <-s.ctx.Done():
+			return 0, s.ctx.Err()
+		}
+	}
+}
+
+// Close stops the subscription; later reads fail with io.ErrClosedPipe.
+func (s *Subscription) Close() error {
+	s.closed = true
+	return nil
+}
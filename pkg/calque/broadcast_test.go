@@ -0,0 +1,92 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBroadcastSubscribers(t *testing.T) {
+	b := NewBroadcast()
+	early := b.Subscribe(context.Background())
+	_, _ = io.WriteString(b, "hello ")
+
+	var wg sync.WaitGroup
+	var earlyOut []byte
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		earlyOut, _ = io.ReadAll(early)
+	}()
+
+	late := b.Subscribe(context.Background())
+	_, _ = io.WriteString(b, "world")
+	_ = b.Close()
+
+	lateOut, err := io.ReadAll(late)
+	wg.Wait()
+	if err != nil || string(lateOut) != "hello world" || string(earlyOut) != "hello world" {
+		t.Errorf("early = %q, late = %q, err = %v", earlyOut, lateOut, err)
+	}
+	if _, err := io.WriteString(b, "more"); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("write after close = %v", err)
+	}
+}
+
+func TestBroadcastReplayRing(t *testing.T) {
+	b := NewBroadcast(BroadcastConfig{ReplayBytes: 8})
+	lagging := b.Subscribe(context.Background())
+	_, _ = io.WriteString(b, "0123456789")
+	_, _ = io.WriteString(b, "abc")
+	_ = b.CloseWithError(errors.New("run failed"))
+
+	late := b.Subscribe(context.Background())
+	out, err := io.ReadAll(late)
+	if string(out) != "56789abc" {
+		t.Errorf("late replay = %q", out)
+	}
+	if err == nil || err.Error() != "run failed" {
+		t.Errorf("late err = %v, want the run error", err)
+	}
+	if _, err := lagging.Read(make([]byte, 4)); !errors.Is(err, ErrBroadcastLagged) {
+		t.Errorf("lagging read = %v, want ErrBroadcastLagged", err)
+	}
+
+	resumed := b.SubscribeFrom(context.Background(), 10)
+	if out, _ := io.ReadAll(resumed); string(out) != "abc" {
+		t.Errorf("resumed = %q", out)
+	}
+	if b.Written() != 13 || resumed.Offset() != 13 {
+		t.Errorf("written = %d, offset = %d", b.Written(), resumed.Offset())
+	}
+}
+
+func TestBroadcastFlowOutput(t *testing.T) {
+	b := NewBroadcast()
+	upper := HandlerFunc(func(req *Request, res *Response) error {
+		var in string
+		if err := Read(req, &in); err != nil {
+			return err
+		}
+		return Write(res, strings.ToUpper(in))
+	})
+	go func() {
+		_ = b.CloseWithError(NewFlow().Use(upper).ServeFlow(NewRequest(context.Background(), strings.NewReader("live")), NewResponse(b)))
+	}()
+
+	out, err := io.ReadAll(b.Subscribe(context.Background()))
+	if err != nil || string(out) != "LIVE" {
+		t.Errorf("out = %q, err = %v", out, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	idle := NewBroadcast()
+	if _, err := idle.Subscribe(ctx).Read(make([]byte, 1)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("idle read = %v, want the context error", err)
+	}
+}
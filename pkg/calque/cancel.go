@@ -0,0 +1,93 @@
+package calque
+
+import (
+	"context"
+	"io"
+)
+
+// CheckCancel returns the cancellation cause if the request context is done, or nil.
+//
+// Input: *Request whose context is checked
+// Output: context.Cause of the request context, or nil while it is active
+// Behavior: Non-blocking - safe to call in tight loops
+//
+// Long-running handlers call it between units of work (batches, pages, tool
+// calls) so they stop promptly when the flow is cancelled instead of running
+// to completion. The cause is returned unchanged, so errors.Is matches both
+// context.Canceled/DeadlineExceeded and causes such as ctrl.ErrBudgetExceeded.
+//
+// Example:
+//
+//	for _, item := range items {
+//		if err := calque.CheckCancel(req); err != nil {
+//			return err
+//		}
+//		process(item)
+//	}
+func CheckCancel(req *Request) error {
+	select {
+	case <-req.Context.Done():
+		return context.Cause(req.Context)
+	default:
+		return nil
+	}
+}
+
+// contextReader fails reads once its context is done
+type contextReader struct {
+	ctx  context.Context
+	done <-chan struct{}
+	r    io.Reader
+}
+
+// NewContextReader wraps r so reads fail with the context's cause once ctx is done.
+//
+// The check happens before each Read, so io.Copy and similar loops stop at the
+// next chunk after cancellation. A Read already blocked in r is not interrupted;
+// flows close their internal pipes on cancellation for that case.
+//
+// Example:
+//
+//	_, err := io.Copy(res.Data, calque.NewContextReader(req.Context, resp.Body))
+func NewContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, done: ctx.Done(), r: r}
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	select {
+	case <-c.done:
+		return 0, context.Cause(c.ctx)
+	default:
+		return c.r.Read(p)
+	}
+}
+
+// contextWriter fails writes once its context is done
+type contextWriter struct {
+	ctx  context.Context
+	done <-chan struct{}
+	w    io.Writer
+}
+
+// NewContextWriter wraps w so writes fail with the context's cause once ctx is done.
+//
+// Example:
+//
+//	out := calque.NewContextWriter(req.Context, res.Data)
+//	for row := range rows {
+//		if _, err := fmt.Fprintln(out, row); err != nil {
+//			return err
+//		}
+//	}
+func NewContextWriter(ctx context.Context, w io.Writer) io.Writer {
+	return &contextWriter{ctx: ctx, done: ctx.Done(), w: w}
+}
+
+func (c *contextWriter) Write(p []byte) (int, error) {
+	select {
+	case <-c.done:
+		return 0, context.Cause(c.ctx)
+	default:
+		return c.w.Write(p)
+	}
+}
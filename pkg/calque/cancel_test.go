@@ -0,0 +1,101 @@
+package calque
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckCancel(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	req := NewRequest(ctx, strings.NewReader(""))
+	if err := CheckCancel(req); err != nil {
+		t.Fatalf("active context: %v", err)
+	}
+
+	stop := errors.New("user aborted")
+	cancel(stop)
+	if err := CheckCancel(req); !errors.Is(err, stop) {
+		t.Errorf("cancelled context: %v, want cause", err)
+	}
+}
+
+func TestContextReaderWriter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r := NewContextReader(ctx, strings.NewReader("hello"))
+	buf := make([]byte, 2)
+	if n, err := r.Read(buf); n != 2 || err != nil {
+		t.Fatalf("Read before cancel = %d, %v", n, err)
+	}
+	var out bytes.Buffer
+	w := NewContextWriter(ctx, &out)
+	if _, err := w.Write([]byte("hi")); err != nil {
+		t.Fatalf("Write before cancel: %v", err)
+	}
+
+	cancel()
+	if _, err := r.Read(buf); !errors.Is(err, context.Canceled) {
+		t.Errorf("Read after cancel = %v", err)
+	}
+	if _, err := w.Write([]byte("more")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Write after cancel = %v", err)
+	}
+	if out.String() != "hi" {
+		t.Errorf("written = %q", out.String())
+	}
+}
+
+// endlessReader produces data forever
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'x'
+	}
+	return len(p), nil
+}
+
+func TestFlowCancelStopsHandlersMidCopy(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	stopped := make(chan error, 2)
+	flow := NewFlow().
+		UseFunc(func(_ *Request, res *Response) error {
+			// streams forever into a pipe nobody drains fast enough
+			_, err := io.Copy(res.Data, endlessReader{})
+			stopped <- err
+			return err
+		}).
+		UseFunc(func(req *Request, _ *Response) error {
+			<-req.Context.Done()
+			// ignores cancellation and keeps blocking on its input
+			_, err := req.Data.Read(make([]byte, 1))
+			for err == nil {
+				_, err = req.Data.Read(make([]byte, 1))
+			}
+			stopped <- err
+			return err
+		})
+
+	var out string
+	if err := flow.Run(ctx, "", &out); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run = %v, want deadline exceeded", err)
+	}
+
+	for range 2 {
+		select {
+		case err := <-stopped:
+			if err == nil {
+				t.Error("handler stopped without an error")
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("handler kept running after cancellation")
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const containerKey ctxKey = "calque.container"
+
+// ErrNotProvided is returned by Resolve when no provider is registered for a type.
+var ErrNotProvided = errors.New("no provider registered")
+
+// ErrDependencyCycle is returned by Resolve when providers depend on each other in a loop.
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// Container is a lightweight dependency injection container.
+//
+// Providers are registered per type with Provide or ProvideValue and built
+// lazily, once, the first time Resolve asks for that type. Constructors
+// resolve their own dependencies from the container they receive, so
+// clients, stores and loggers are wired where the handler is built instead of
+// threaded through every constructor call. Safe for concurrent use.
+//
+// Example:
+//
+//	c := calque.NewContainer()
+//	calque.ProvideValue(c, cfg)
+//	calque.Provide(c, func(c *calque.Container) (ai.Client, error) {
+//		cfg, err := calque.Resolve[Config](c)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return openai.New(cfg.Model)
+//	})
+//	defer c.Close()
+type Container struct {
+	state *containerState
+	held  bool // set on the view passed to constructors, which run with the lock held
+}
+
+// containerState is shared by a container and the views passed to its constructors
+type containerState struct {
+	mu        sync.Mutex
+	providers map[reflect.Type]func(*Container) (any, error)
+	instances map[reflect.Type]any
+	order     []reflect.Type        // instance creation order, for Close
+	resolving map[reflect.Type]bool // types being constructed, for cycle detection
+	path      []reflect.Type
+}
+
+// NewContainer creates an empty container.
+func NewContainer() *Container {
+	return &Container{state: &containerState{
+		providers: make(map[reflect.Type]func(*Container) (any, error)),
+		instances: make(map[reflect.Type]any),
+		resolving: make(map[reflect.Type]bool),
+	}}
+}
+
+// lock acquires the state lock unless this is a constructor's view that already holds it
+func (c *Container) lock() func() {
+	if c.held {
+		return func() {}
+	}
+	c.state.mu.Lock()
+	return c.state.mu.Unlock
+}
+
+// Provide registers a lazy singleton constructor for type T.
+//
+// The constructor runs on the first Resolve[T] and its result is cached.
+// Registering T again replaces the provider and drops any cached instance.
+// Use an interface type for T to let callers swap implementations.
+//
+// Constructors run one at a time. The container passed to a constructor is
+// only valid during that call; use it to resolve dependencies, not to keep.
+//
+// Example:
+//
+//	calque.Provide(c, func(c *calque.Container) (retrieval.VectorStore, error) {
+//		return qdrant.New(qdrantConfig)
+//	})
+func Provide[T any](c *Container, constructor func(c *Container) (T, error)) {
+	t := typeOf[T]()
+	defer c.lock()()
+	c.state.providers[t] = func(c *Container) (any, error) { return constructor(c) }
+	delete(c.state.instances, t)
+}
+
+// ProvideValue registers an existing value for type T.
+//
+// Example:
+//
+//	calque.ProvideValue(c, slog.Default())
+func ProvideValue[T any](c *Container, value T) {
+	t := typeOf[T]()
+	defer c.lock()()
+	delete(c.state.providers, t)
+	c.state.setInstance(t, value)
+}
+
+// Resolve returns the instance of type T, constructing it on first use.
+//
+// Returns an error wrapping ErrNotProvided when T has no provider and
+// ErrDependencyCycle when T's constructor (directly or indirectly) resolves T.
+// A failed construction is not cached, so the next Resolve retries it.
+//
+// Example:
+//
+//	client, err := calque.Resolve[ai.Client](c)
+func Resolve[T any](c *Container) (T, error) {
+	var zero T
+	defer c.lock()()
+	v, err := c.state.resolve(typeOf[T]())
+	if err != nil {
+		return zero, err
+	}
+	return v.(T), nil
+}
+
+// MustResolve is like Resolve but panics on error, for use during program setup.
+func MustResolve[T any](c *Container) T {
+	v, err := Resolve[T](c)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// resolve builds or returns the cached instance for t; the lock must be held
+func (s *containerState) resolve(t reflect.Type) (any, error) {
+	if v, ok := s.instances[t]; ok {
+		return v, nil
+	}
+	provider, ok := s.providers[t]
+	if !ok {
+		return nil, fmt.Errorf("resolve %v: %w", t, ErrNotProvided)
+	}
+	if s.resolving[t] {
+		return nil, fmt.Errorf("resolve %v: %w: %s", t, ErrDependencyCycle, s.cyclePath(t))
+	}
+
+	s.resolving[t] = true
+	s.path = append(s.path, t)
+	defer func() {
+		delete(s.resolving, t)
+		s.path = s.path[:len(s.path)-1]
+	}()
+
+	v, err := provider(&Container{state: s, held: true})
+	if err != nil {
+		return nil, fmt.Errorf("construct %v: %w", t, err)
+	}
+	s.setInstance(t, v)
+	return v, nil
+}
+
+// setInstance caches v for t; the lock must be held
+func (s *containerState) setInstance(t reflect.Type, v any) {
+	if _, ok := s.instances[t]; !ok {
+		s.order = append(s.order, t)
+	}
+	s.instances[t] = v
+}
+
+// cyclePath renders the resolution path that leads back to t
+func (s *containerState) cyclePath(t reflect.Type) string {
+	names := make([]string, 0, len(s.path)+1)
+	for _, p := range s.path {
+		names = append(names, p.String())
+	}
+	return strings.Join(append(names, t.String()), " -> ")
+}
+
+// Close closes every constructed instance implementing io.Closer, newest first.
+//
+// Errors from all Close calls are joined. Instances are dropped, so a later
+// Resolve constructs them again.
+func (c *Container) Close() error {
+	defer c.lock()()
+	s := c.state
+
+	var errs []error
+	for i := len(s.order) - 1; i >= 0; i-- {
+		t := s.order[i]
+		if closer, ok := s.instances[t].(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		delete(s.instances, t)
+	}
+	s.order = nil
+	return errors.Join(errs...)
+}
+
+// typeOf returns the reflect.Type of T, including interface types
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// WithContainer stores a dependency container in the context.
+//
+// Handlers built with Inject resolve their dependencies from it.
+//
+// Example:
+//
+//	ctx = calque.WithContainer(ctx, c)
+//	err := flow.Run(ctx, input, &output)
+func WithContainer(ctx context.Context, c *Container) context.Context {
+	return context.WithValue(ctx, containerKey, c)
+}
+
+// ContainerFrom retrieves the dependency container from context, or nil if none is set.
+func ContainerFrom(ctx context.Context) *Container {
+	if c, ok := ctx.Value(containerKey).(*Container); ok {
+		return c
+	}
+	return nil
+}
+
+// injectHandler builds its handler from the context's container on first use
+type injectHandler struct {
+	build func(c *Container) (Handler, error)
+
+	mu      sync.Mutex
+	handler Handler
+}
+
+// Inject creates a handler whose dependencies are resolved from a container.
+//
+// Input: any data type (streaming - delegated to the built handler)
+// Output: built handler's output
+// Behavior: STREAMING - builds the handler once, then delegates
+//
+// build runs once, with the container from the context (see WithContainer),
+// when the flow initializes (Flow.Init) or on the first request. A failed
+// build is retried on the next request. The built handler's Init and Close
+// run with the flow's lifecycle, so injected resources are torn down by
+// Flow.Close.
+//
+// Example:
+//
+//	flow := calque.NewFlow().
+//		Use(calque.Inject(func(c *calque.Container) (calque.Handler, error) {
+//			client, err := calque.Resolve[ai.Client](c)
+//			if err != nil {
+//				return nil, err
+//			}
+//			return ai.Agent(client), nil
+//		}))
+//
+//	err := flow.Run(calque.WithContainer(ctx, container), input, &output)
+func Inject(build func(c *Container) (Handler, error)) Handler {
+	return &injectHandler{build: build}
+}
+
+// ServeFlow runs the built handler, building it on first use.
+func (h *injectHandler) ServeFlow(req *Request, res *Response) error {
+	handler, err := h.get(req.Context)
+	if err != nil {
+		return err
+	}
+	return handler.ServeFlow(req, res)
+}
+
+// Init implements Initializer, building the handler when a container is available.
+func (h *injectHandler) Init(ctx context.Context) error {
+	if ContainerFrom(ctx) == nil {
+		return nil // resolved on the first request instead
+	}
+	_, err := h.get(ctx)
+	return err
+}
+
+// Close implements io.Closer, closing the built handler if it holds resources.
+func (h *injectHandler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if closer, ok := h.handler.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Name implements Namer.
+func (h *injectHandler) Name() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.handler != nil {
+		return HandlerName(h.handler)
+	}
+	return "calque.Inject"
+}
+
+// get returns the built handler, building it from the context's container on first use
+func (h *injectHandler) get(ctx context.Context) (Handler, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.handler != nil {
+		return h.handler, nil
+	}
+
+	c := ContainerFrom(ctx)
+	if c == nil {
+		return nil, NewErr(ctx, "no dependency container in context (use calque.WithContainer)")
+	}
+	handler, err := h.build(c)
+	if err != nil {
+		return nil, WrapErr(ctx, err, "failed to build injected handler")
+	}
+	if handler == nil {
+		return nil, NewErr(ctx, "injected handler builder returned nil")
+	}
+	if init, ok := handler.(Initializer); ok {
+		if err := init.Init(ctx); err != nil {
+			return nil, WrapErr(ctx, err, "failed to init injected handler")
+		}
+	}
+	h.handler = handler
+	return handler, nil
+}
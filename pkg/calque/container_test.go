@@ -0,0 +1,130 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type greeter interface{ Greet() string }
+
+type prefixGreeter struct {
+	prefix string
+	closed *bool
+}
+
+func (g *prefixGreeter) Greet() string { return g.prefix + "hello" }
+
+func (g *prefixGreeter) Close() error {
+	*g.closed = true
+	return nil
+}
+
+func TestContainerResolve(t *testing.T) {
+	c := NewContainer()
+	closed := false
+	builds := 0
+	ProvideValue(c, "> ")
+	Provide(c, func(c *Container) (greeter, error) {
+		builds++
+		prefix, err := Resolve[string](c)
+		if err != nil {
+			return nil, err
+		}
+		return &prefixGreeter{prefix: prefix, closed: &closed}, nil
+	})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g, err := Resolve[greeter](c)
+			if err != nil || g.Greet() != "> hello" {
+				t.Errorf("Resolve = %v, %v", g, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if builds != 1 {
+		t.Errorf("constructor ran %d times, want 1", builds)
+	}
+
+	if err := c.Close(); err != nil || !closed {
+		t.Errorf("Close = %v, closed = %v", err, closed)
+	}
+}
+
+func TestContainerErrors(t *testing.T) {
+	c := NewContainer()
+	if _, err := Resolve[greeter](c); !errors.Is(err, ErrNotProvided) {
+		t.Errorf("missing provider error = %v", err)
+	}
+
+	Provide(c, func(c *Container) (string, error) {
+		n, err := Resolve[int](c)
+		return strings.Repeat("x", n), err
+	})
+	Provide(c, func(c *Container) (int, error) {
+		_, err := Resolve[string](c)
+		return 1, err
+	})
+	_, err := Resolve[string](c)
+	if !errors.Is(err, ErrDependencyCycle) || !strings.Contains(err.Error(), "string -> int -> string") {
+		t.Errorf("cycle error = %v", err)
+	}
+
+	boom := errors.New("boom")
+	attempts := 0
+	Provide(c, func(*Container) (float64, error) {
+		attempts++
+		if attempts == 1 {
+			return 0, boom
+		}
+		return 2.5, nil
+	})
+	if _, err := Resolve[float64](c); !errors.Is(err, boom) {
+		t.Errorf("constructor error = %v", err)
+	}
+	if v, err := Resolve[float64](c); err != nil || v != 2.5 {
+		t.Errorf("retry after failure = %v, %v", v, err)
+	}
+}
+
+func TestInject(t *testing.T) {
+	c := NewContainer()
+	ProvideValue(c, "injected: ")
+	builds := 0
+	h := Inject(func(c *Container) (Handler, error) {
+		builds++
+		prefix, err := Resolve[string](c)
+		if err != nil {
+			return nil, err
+		}
+		return HandlerFunc(func(req *Request, res *Response) error {
+			var s string
+			if err := Read(req, &s); err != nil {
+				return err
+			}
+			return Write(res, prefix+s)
+		}), nil
+	})
+	flow := NewFlow().Use(h)
+
+	var out string
+	if err := flow.Run(context.Background(), "x", &out); err == nil {
+		t.Error("expected error without a container")
+	}
+
+	ctx := WithContainer(context.Background(), c)
+	for range 2 {
+		if err := flow.Run(ctx, "x", &out); err != nil || out != "injected: x" {
+			t.Fatalf("out = %q, err = %v", out, err)
+		}
+	}
+	if builds != 1 {
+		t.Errorf("handler built %d times, want 1", builds)
+	}
+}
@@ -202,6 +202,27 @@ func (mb *MetadataBus) Delete(key string) {
 	mb.store.Delete(key)
 }
 
+// Range calls fn for each value stored with Set, stopping if fn returns false.
+//
+// Iteration order is unspecified. Useful for middleware that snapshots
+// per-run tags, such as audit logs.
+//
+// Example:
+//
+//	mb.Range(func(key string, value any) bool {
+//	    fmt.Println(key, value)
+//	    return true
+//	})
+func (mb *MetadataBus) Range(fn func(key string, value any) bool) {
+	mb.store.Range(func(k, v any) bool {
+		key, ok := k.(string)
+		if !ok {
+			return true
+		}
+		return fn(key, v)
+	})
+}
+
 // Send sends metadata through the channel for streaming communication.
 //
 // Use Send for metadata that needs to flow between handlers in real-time.
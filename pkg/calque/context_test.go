@@ -209,6 +209,30 @@ func TestMetadataBus_Delete(t *testing.T) {
 	mb.Delete("non_existent")
 }
 
+func TestMetadataBus_Range(t *testing.T) {
+	mb := NewMetadataBus(10)
+	mb.Set("a", 1)
+	mb.Set("b", "two")
+
+	seen := make(map[string]any)
+	mb.Range(func(key string, value any) bool {
+		seen[key] = value
+		return true
+	})
+	if len(seen) != 2 || seen["a"] != 1 || seen["b"] != "two" {
+		t.Errorf("Range() visited %v, want a=1 b=two", seen)
+	}
+
+	count := 0
+	mb.Range(func(_ string, _ any) bool {
+		count++
+		return false
+	})
+	if count != 1 {
+		t.Errorf("Range() visited %d entries after stop, want 1", count)
+	}
+}
+
 func TestMetadataBus_SetGet_Concurrent(_ *testing.T) {
 	mb := NewMetadataBus(10)
 	var wg sync.WaitGroup
@@ -0,0 +1,293 @@
+package calque
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DeadLetter records a failed run so it can be inspected and replayed.
+type DeadLetter struct {
+	ID        string         `json:"id"`                   // random entry ID
+	Flow      string         `json:"flow,omitempty"`       // FlowConfig.Name of the failed flow
+	Time      time.Time      `json:"time"`                 // when the run failed
+	TraceID   string         `json:"trace_id,omitempty"`   // trace ID from the run context
+	RequestID string         `json:"request_id,omitempty"` // request ID from the run context
+	Input     []byte         `json:"input"`                // flow input
+	Truncated bool           `json:"truncated,omitempty"`  // Input is incomplete: cut at the capture limit or not read to the end
+	Metadata  map[string]any `json:"metadata,omitempty"`   // JSON-encodable FlowConfig.DeadLetterMetadata values at failure
+	Error     string         `json:"error"`                // error message
+	Retryable bool           `json:"retryable"`            // IsRetryable(err) at failure
+}
+
+// DefaultDeadLetterInputBytes is the flow input kept for a dead letter when
+// FlowConfig.DeadLetterInputBytes is 0.
+const DefaultDeadLetterInputBytes = 64 << 10
+
+// DeadLetterSink stores dead letters from failed runs (FlowConfig.DeadLetter).
+type DeadLetterSink interface {
+	// Send stores the entry; errors are logged and do not change the run's error
+	Send(ctx context.Context, entry DeadLetter) error
+}
+
+// DeadLetterFunc adapts a function to DeadLetterSink, for sinks such as a
+// message queue producer.
+//
+// Example:
+//
+//	sink := calque.DeadLetterFunc(func(ctx context.Context, entry calque.DeadLetter) error {
+//		value, err := json.Marshal(entry)
+//		if err != nil {
+//			return err
+//		}
+//		return writer.WriteMessages(ctx, kafka.Message{Key: []byte(entry.ID), Value: value})
+//	})
+type DeadLetterFunc func(ctx context.Context, entry DeadLetter) error
+
+// Send implements DeadLetterSink.
+func (f DeadLetterFunc) Send(ctx context.Context, entry DeadLetter) error {
+	return f(ctx, entry)
+}
+
+// FileDeadLetterSink appends dead letters to a JSON Lines file.
+type FileDeadLetterSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileDeadLetterSink opens (or creates) a JSON Lines dead letter file for appending.
+//
+// Example:
+//
+//	dlq, err := calque.NewFileDeadLetterSink("failed-jobs.jsonl")
+//	if err != nil {
+//		return err
+//	}
+//	defer dlq.Close()
+//	flow := calque.NewFlow(calque.FlowConfig{DeadLetter: dlq})
+func NewFileDeadLetterSink(path string) (*FileDeadLetterSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, WrapErr(context.Background(), err, "failed to open dead letter file")
+	}
+	return &FileDeadLetterSink{file: file}, nil
+}
+
+// Send writes the entry as a JSON line
+func (s *FileDeadLetterSink) Send(ctx context.Context, entry DeadLetter) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return WrapErr(ctx, err, "failed to encode dead letter")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return WrapErr(ctx, err, "failed to write dead letter")
+	}
+	return nil
+}
+
+// Close closes the underlying file
+func (s *FileDeadLetterSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// ReadDeadLetters reads every entry from a JSON Lines dead letter file.
+func ReadDeadLetters(path string) ([]DeadLetter, error) {
+	ctx := context.Background()
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, WrapErr(ctx, err, "failed to open dead letter file")
+	}
+	defer func() { _ = file.Close() }()
+
+	var entries []DeadLetter
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, WrapErr(ctx, err, "failed to decode dead letter")
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, WrapErr(ctx, err, "failed to read dead letter file")
+	}
+	return entries, nil
+}
+
+// SQLDeadLetterSink inserts dead letters into a database table.
+//
+// Query is an INSERT statement taking, in order: id, flow, time, trace_id,
+// request_id, input (bytes), metadata (JSON text), error and retryable. Use
+// the placeholder style of your driver.
+//
+// Example:
+//
+//	sink := &calque.SQLDeadLetterSink{
+//		DB: db,
+//		Query: `INSERT INTO dead_letters
+//			(id, flow, failed_at, trace_id, request_id, input, metadata, error, retryable)
+//			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+//	}
+type SQLDeadLetterSink struct {
+	DB    *sql.DB
+	Query string
+}
+
+// Send inserts the entry
+func (s *SQLDeadLetterSink) Send(ctx context.Context, entry DeadLetter) error {
+	metadata, err := json.Marshal(entry.Metadata)
+	if err != nil {
+		return WrapErr(ctx, err, "failed to encode dead letter metadata")
+	}
+	_, err = s.DB.ExecContext(ctx, s.Query,
+		entry.ID, entry.Flow, entry.Time, entry.TraceID, entry.RequestID,
+		entry.Input, string(metadata), entry.Error, entry.Retryable)
+	if err != nil {
+		return WrapErr(ctx, err, "failed to insert dead letter")
+	}
+	return nil
+}
+
+// inputCapture keeps the first limit bytes of the flow input as the first
+// handler reads it, for a dead letter. The flow's input goroutine may still be
+// reading when the dead letter is sent, hence the lock.
+type inputCapture struct {
+	r     io.Reader
+	limit int64
+
+	mu   sync.Mutex
+	data []byte
+	cut  bool // more than limit bytes were read
+	eof  bool // the input was read to the end
+}
+
+func (c *inputCapture) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.mu.Lock()
+	keep := min(int64(n), c.limit-int64(len(c.data)))
+	c.data = append(c.data, p[:keep]...)
+	if int64(n) > keep {
+		c.cut = true
+	}
+	if err == io.EOF {
+		c.eof = true
+	}
+	c.mu.Unlock()
+	return n, err
+}
+
+// snapshot returns the captured input and whether it is incomplete
+func (c *inputCapture) snapshot() ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]byte(nil), c.data...), c.cut || !c.eof
+}
+
+// sendDeadLetter records a failed run on the configured sink, logging sink failures
+func (f *Flow) sendDeadLetter(ctx context.Context, capture *inputCapture, runErr error) {
+	input, truncated := capture.snapshot()
+	entry := DeadLetter{
+		ID:        newDeadLetterID(),
+		Flow:      f.name,
+		Time:      time.Now(),
+		TraceID:   TraceID(ctx),
+		RequestID: RequestID(ctx),
+		Input:     input,
+		Truncated: truncated,
+		Error:     runErr.Error(),
+		Retryable: IsRetryable(runErr),
+	}
+	if mb := GetMetadataBus(ctx); mb != nil && len(f.deadLetterKeys) > 0 {
+		mb.Range(func(key string, value any) bool {
+			if !hasAnyPrefix(key, f.deadLetterKeys) {
+				return true
+			}
+			if _, err := json.Marshal(value); err == nil {
+				if entry.Metadata == nil {
+					entry.Metadata = make(map[string]any)
+				}
+				entry.Metadata[key] = value
+			}
+			return true
+		})
+	}
+
+	// the run context may already be cancelled; the dead letter must still be written
+	sendCtx := context.WithoutCancel(ctx)
+	if err := f.deadLetter.Send(sendCtx, entry); err != nil {
+		LogWarn(ctx, "failed to record dead letter", "flow", f.name, "dead_letter_id", entry.ID, "error", err)
+	}
+}
+
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// newDeadLetterID returns a random 128-bit hex ID
+func newDeadLetterID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Replay re-runs a dead letter's input through flow.
+//
+// Input: context.Context, dead letter entry, flow to run, output pointer (any type)
+// Output: error if the replayed run fails
+// Behavior: Restores the entry's trace ID, request ID and metadata when the
+// context doesn't already carry them, then runs flow with the recorded input
+//
+// Truncated entries are rejected, since their input is incomplete.
+//
+// Example:
+//
+//	entries, _ := calque.ReadDeadLetters("failed-jobs.jsonl")
+//	for _, entry := range entries {
+//		var out string
+//		if err := calque.Replay(ctx, entry, flow, &out); err != nil {
+//			log.Printf("replay %s failed: %v", entry.ID, err)
+//		}
+//	}
+func Replay(ctx context.Context, entry DeadLetter, flow *Flow, output any) error {
+	if entry.Truncated {
+		return NewErr(ctx, "cannot replay dead letter "+entry.ID+": input was truncated")
+	}
+	if entry.TraceID != "" && TraceID(ctx) == "" {
+		ctx = WithTraceID(ctx, entry.TraceID)
+	}
+	if entry.RequestID != "" && RequestID(ctx) == "" {
+		ctx = WithRequestID(ctx, entry.RequestID)
+	}
+	if len(entry.Metadata) > 0 && GetMetadataBus(ctx) == nil {
+		mb := NewMetadataBus(flow.metadataBusBuffer)
+		defer mb.Close()
+		for key, value := range entry.Metadata {
+			mb.Set(key, value)
+		}
+		ctx = WithMetadataBus(ctx, mb)
+	}
+	return flow.Run(ctx, bytes.NewReader(entry.Input), output)
+}
@@ -0,0 +1,171 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type memoryDeadLetters struct {
+	mu      sync.Mutex
+	entries []DeadLetter
+}
+
+func (m *memoryDeadLetters) Send(_ context.Context, entry DeadLetter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+// failOnce fails the first run and succeeds (upper-casing the input) afterwards
+func failOnce() Handler {
+	var calls int
+	var mu sync.Mutex
+	return HandlerFunc(func(req *Request, res *Response) error {
+		var s string
+		if err := Read(req, &s); err != nil {
+			return err
+		}
+		mu.Lock()
+		calls++
+		first := calls == 1
+		mu.Unlock()
+		if first {
+			return Retryable(errors.New("upstream unavailable"))
+		}
+		if v, ok := GetMetadataBus(req.Context).Get("tenant"); !ok || v != "acme" {
+			return errors.New("metadata not restored")
+		}
+		return Write(res, strings.ToUpper(s)+":"+TraceID(req.Context))
+	})
+}
+
+func TestDeadLetterAndReplay(t *testing.T) {
+	sink := &memoryDeadLetters{}
+	flow := NewFlow(FlowConfig{
+		Name:               "jobs",
+		DeadLetter:         sink,
+		DeadLetterMetadata: []string{"tenant", "unencodable"},
+	}).Use(failOnce())
+
+	mb := NewMetadataBus(0)
+	mb.Set("tenant", "acme")
+	mb.Set("unencodable", func() {})
+	mb.Set("auth.token", "secret")
+	ctx := WithMetadataBus(WithTraceID(context.Background(), "trace-1"), mb)
+
+	var out string
+	if err := flow.Run(ctx, "job input", &out); err == nil {
+		t.Fatal("expected first run to fail")
+	}
+	if len(sink.entries) != 1 {
+		t.Fatalf("dead letters = %d, want 1", len(sink.entries))
+	}
+	entry := sink.entries[0]
+	if entry.Flow != "jobs" || string(entry.Input) != "job input" || entry.TraceID != "trace-1" ||
+		!entry.Retryable || entry.Error != "upstream unavailable" || entry.ID == "" {
+		t.Errorf("entry = %+v", entry)
+	}
+	if entry.Metadata["tenant"] != "acme" {
+		t.Errorf("metadata = %v", entry.Metadata)
+	}
+	if _, ok := entry.Metadata["unencodable"]; ok {
+		t.Error("metadata that cannot be encoded should be skipped")
+	}
+	if _, ok := entry.Metadata["auth.token"]; ok {
+		t.Error("metadata outside DeadLetterMetadata should not be recorded")
+	}
+
+	if err := Replay(context.Background(), entry, flow, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != "JOB INPUT:trace-1" {
+		t.Errorf("replayed output = %q", out)
+	}
+	if len(sink.entries) != 1 {
+		t.Error("successful replay should not record a dead letter")
+	}
+}
+
+func TestFileDeadLetterSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dlq.jsonl")
+	sink, err := NewFileDeadLetterSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flow := NewFlow(FlowConfig{DeadLetter: sink}).Use(HandlerFunc(func(req *Request, _ *Response) error {
+		var s string
+		_ = Read(req, &s)
+		return InvalidInput(errors.New("bad " + s))
+	}))
+	for _, in := range []string{"a", "b"} {
+		var out string
+		_ = flow.Run(context.Background(), in, &out)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadDeadLetters(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || string(entries[1].Input) != "b" || entries[1].Error != "bad b" || entries[1].Retryable {
+		t.Errorf("entries = %+v", entries)
+	}
+}
+
+func TestDeadLetterTruncatedInput(t *testing.T) {
+	sink := &memoryDeadLetters{}
+	flow := NewFlow(FlowConfig{DeadLetter: sink, MaxInputBytes: 4}).Use(HandlerFunc(passthrough))
+
+	var out string
+	err := flow.Run(context.Background(), "too long", &out)
+	if !errors.Is(err, ErrInputTooLarge) {
+		t.Fatalf("error = %v, want ErrInputTooLarge", err)
+	}
+	if len(sink.entries) != 1 || string(sink.entries[0].Input) != "too " || !sink.entries[0].Truncated {
+		t.Fatalf("entries = %+v", sink.entries)
+	}
+	if err := Replay(context.Background(), sink.entries[0], flow, &out); err == nil {
+		t.Error("replaying a truncated entry should fail")
+	}
+}
+
+func TestDeadLetterSinkFailure(t *testing.T) {
+	boom := errors.New("boom")
+	sink := DeadLetterFunc(func(context.Context, DeadLetter) error { return errors.New("sink down") })
+	flow := NewFlow(FlowConfig{DeadLetter: sink}).Use(HandlerFunc(func(req *Request, _ *Response) error {
+		_ = Read(req, new(string))
+		return boom
+	}))
+	var out string
+	if err := flow.Run(context.Background(), "x", &out); !errors.Is(err, boom) {
+		t.Errorf("error = %v, want the handler error", err)
+	}
+}
+
+func TestDeadLetterCaptureLimit(t *testing.T) {
+	sink := &memoryDeadLetters{}
+	boom := errors.New("boom")
+	flow := NewFlow(FlowConfig{DeadLetter: sink, DeadLetterInputBytes: 8}).Use(HandlerFunc(func(req *Request, _ *Response) error {
+		buf := make([]byte, 16)
+		if _, err := io.ReadFull(req.Data, buf); err != nil {
+			return err
+		}
+		return boom
+	}))
+
+	var out string
+	if err := flow.Run(context.Background(), io.Reader(endlessReader{}), &out); !errors.Is(err, boom) {
+		t.Fatalf("error = %v, want the handler error", err)
+	}
+	if len(sink.entries) != 1 || string(sink.entries[0].Input) != "xxxxxxxx" || !sink.entries[0].Truncated {
+		t.Fatalf("entries = %+v", sink.entries)
+	}
+}
@@ -0,0 +1,132 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// StageTimeoutError is the cancellation cause when a handler exceeds its stage budget.
+//
+// It matches context.DeadlineExceeded with errors.Is, so existing timeout
+// handling keeps working.
+//
+// Example:
+//
+//	var stageErr *calque.StageTimeoutError
+//	if errors.As(err, &stageErr) {
+//		log.Printf("stage %s ran out of time", stageErr.Name)
+//	}
+type StageTimeoutError struct {
+	Index    int           // position of the handler in the flow
+	Name     string        // handler name (see HandlerName)
+	Deadline time.Time     // when the stage had to finish
+	Budget   time.Duration // the stage's own budget
+}
+
+func (e *StageTimeoutError) Error() string {
+	return fmt.Sprintf("stage %d (%s) exceeded its %v budget", e.Index, e.Name, e.Budget)
+}
+
+// Is reports whether target is context.DeadlineExceeded.
+func (e *StageTimeoutError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// UseWithTimeout adds a handler to the flow chain with its own time budget.
+//
+// Input: calque.Handler to add, time.Duration budget (0 uses FlowConfig defaults)
+// Output: *Flow (fluent interface for chaining)
+// Behavior: Appends handler to the flow chain
+//
+// Handlers in a flow run concurrently and typically start working as their
+// input arrives, so budgets are cumulative: a handler's context deadline is
+// the run start plus the budgets of every handler up to and including it,
+// capped by the parent deadline. A handler that overruns is cancelled with a
+// *StageTimeoutError cause, which is also the error the flow returns.
+//
+// Budgets come from, in order: UseWithTimeout, FlowConfig.StageTimeout, and
+// with FlowConfig.DivideDeadline an even share of the context deadline left
+// after the other budgets. Handlers with no budget only get the parent deadline.
+//
+// Example:
+//
+//	flow := calque.NewFlow(calque.FlowConfig{DivideDeadline: true}).
+//		UseWithTimeout(retrieval.VectorSearch(store, opts), 2*time.Second).
+//		Use(prompt.Template(tmpl)).
+//		Use(ai.Agent(client))
+//
+//	// with a 30s request deadline: search 2s, the other stages ~14s each
+//	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+func (f *Flow) UseWithTimeout(handler Handler, timeout time.Duration) *Flow {
+	f.handlers = append(f.handlers, handler)
+	f.stageTimeouts = append(f.stageTimeouts, max(timeout, 0))
+	return f
+}
+
+// stageDeadline is the resolved time limit of one handler (zero at = none)
+type stageDeadline struct {
+	at     time.Time
+	budget time.Duration
+}
+
+// stageDeadlines resolves each handler's budget and cumulative deadline for a run starting at start
+func (f *Flow) stageDeadlines(ctx context.Context, start time.Time) []stageDeadline {
+	deadlines := make([]stageDeadline, len(f.handlers))
+	var claimed time.Duration
+	unbudgeted := 0
+	for i := range deadlines {
+		budget := f.stageTimeout
+		if i < len(f.stageTimeouts) && f.stageTimeouts[i] > 0 {
+			budget = f.stageTimeouts[i]
+		}
+		deadlines[i].budget = budget
+		if budget > 0 {
+			claimed += budget
+		} else {
+			unbudgeted++
+		}
+	}
+
+	if parent, ok := ctx.Deadline(); ok && f.divideDeadline && unbudgeted > 0 {
+		// stages left without a budget share whatever the others don't claim
+		if share := (parent.Sub(start) - claimed) / time.Duration(unbudgeted); share > 0 {
+			for i := range deadlines {
+				if deadlines[i].budget == 0 {
+					deadlines[i].budget = share
+				}
+			}
+		}
+	}
+
+	var elapsed time.Duration
+	for i := range deadlines {
+		if deadlines[i].budget > 0 {
+			elapsed += deadlines[i].budget
+			deadlines[i].at = start.Add(elapsed)
+		}
+	}
+	return deadlines
+}
+
+// stageContext applies a stage deadline to the handler's context
+func stageContext(ctx context.Context, deadline stageDeadline, idx int, h Handler) (context.Context, context.CancelFunc) {
+	if deadline.at.IsZero() {
+		return ctx, func() {}
+	}
+	if parent, ok := ctx.Deadline(); ok && !deadline.at.Before(parent) {
+		return ctx, func() {} // the parent deadline comes first anyway
+	}
+	cause := &StageTimeoutError{Index: idx, Name: HandlerName(h), Deadline: deadline.at, Budget: deadline.budget}
+	return context.WithDeadlineCause(ctx, deadline.at, cause)
+}
+
+// stageError replaces a bare deadline error with the StageTimeoutError that caused it
+func stageError(stageCtx context.Context, err error) error {
+	var stageErr *StageTimeoutError
+	if errors.As(context.Cause(stageCtx), &stageErr) && errors.Is(err, context.DeadlineExceeded) && !errors.As(err, &stageErr) {
+		return stageErr
+	}
+	return err
+}
@@ -0,0 +1,151 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitForDeadline blocks until the request context is done and returns its cause
+func waitForDeadline(req *Request, _ *Response) error {
+	<-req.Context.Done()
+	return req.Context.Err()
+}
+
+// passthrough copies input to output
+func passthrough(req *Request, res *Response) error {
+	var s string
+	if err := Read(req, &s); err != nil {
+		return err
+	}
+	return Write(res, s)
+}
+
+func TestFlowStageTimeout(t *testing.T) {
+	flow := NewFlow(FlowConfig{StageTimeout: 20 * time.Millisecond}).
+		UseFunc(passthrough).
+		Use(HandlerFunc(waitForDeadline))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	var out string
+	err := flow.Run(ctx, "x", &out)
+
+	var stageErr *StageTimeoutError
+	if !errors.As(err, &stageErr) {
+		t.Fatalf("error = %v, want *StageTimeoutError", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("StageTimeoutError does not match context.DeadlineExceeded")
+	}
+	if stageErr.Index != 1 || stageErr.Budget != 20*time.Millisecond {
+		t.Errorf("stage error = %+v", stageErr)
+	}
+	// second stage deadline is cumulative: 20ms + 20ms
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("flow took %v", elapsed)
+	}
+}
+
+func TestFlowStageDeadlines(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	withDeadline := func(d time.Duration) context.Context {
+		ctx, cancel := context.WithDeadline(context.Background(), start.Add(d))
+		t.Cleanup(cancel)
+		return ctx
+	}
+
+	tests := []struct {
+		name    string
+		flow    *Flow
+		ctx     context.Context
+		budgets []time.Duration
+	}{
+		{
+			name:    "no budgets",
+			flow:    NewFlow().UseFunc(passthrough).UseFunc(passthrough),
+			ctx:     withDeadline(time.Minute),
+			budgets: []time.Duration{0, 0},
+		},
+		{
+			name:    "divide deadline",
+			flow:    NewFlow(FlowConfig{DivideDeadline: true}).UseFunc(passthrough).UseFunc(passthrough).UseFunc(passthrough),
+			ctx:     withDeadline(30 * time.Second),
+			budgets: []time.Duration{10 * time.Second, 10 * time.Second, 10 * time.Second},
+		},
+		{
+			name: "explicit budget with divided remainder",
+			flow: NewFlow(FlowConfig{DivideDeadline: true}).
+				UseWithTimeout(HandlerFunc(passthrough), 10*time.Second).
+				UseFunc(passthrough).
+				UseFunc(passthrough),
+			ctx:     withDeadline(30 * time.Second),
+			budgets: []time.Duration{10 * time.Second, 10 * time.Second, 10 * time.Second},
+		},
+		{
+			name: "explicit budget overrides stage timeout",
+			flow: NewFlow(FlowConfig{StageTimeout: time.Second}).
+				UseFunc(passthrough).
+				UseWithTimeout(HandlerFunc(passthrough), 5*time.Second),
+			ctx:     context.Background(),
+			budgets: []time.Duration{time.Second, 5 * time.Second},
+		},
+		{
+			name:    "divide without deadline",
+			flow:    NewFlow(FlowConfig{DivideDeadline: true}).UseFunc(passthrough),
+			ctx:     context.Background(),
+			budgets: []time.Duration{0},
+		},
+		{
+			name: "budgets claim all time",
+			flow: NewFlow(FlowConfig{DivideDeadline: true}).
+				UseWithTimeout(HandlerFunc(passthrough), time.Minute).
+				UseFunc(passthrough),
+			ctx:     withDeadline(30 * time.Second),
+			budgets: []time.Duration{time.Minute, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deadlines := tt.flow.stageDeadlines(tt.ctx, start)
+			if len(deadlines) != len(tt.budgets) {
+				t.Fatalf("got %d deadlines, want %d", len(deadlines), len(tt.budgets))
+			}
+			var elapsed time.Duration
+			for i, want := range tt.budgets {
+				got := deadlines[i]
+				if got.budget != want {
+					t.Errorf("stage %d budget = %v, want %v", i, got.budget, want)
+				}
+				if want == 0 {
+					if !got.at.IsZero() {
+						t.Errorf("stage %d has deadline %v, want none", i, got.at)
+					}
+					continue
+				}
+				elapsed += want
+				if !got.at.Equal(start.Add(elapsed)) {
+					t.Errorf("stage %d deadline = %v, want start+%v", i, got.at.Sub(start), elapsed)
+				}
+			}
+		})
+	}
+}
+
+func TestFlowStageTimeoutParentFirst(t *testing.T) {
+	// the parent deadline wins when it is earlier, and its error is returned unchanged
+	flow := NewFlow(FlowConfig{StageTimeout: time.Minute}).Use(HandlerFunc(waitForDeadline))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	var out string
+	err := flow.Run(ctx, "x", &out)
+	var stageErr *StageTimeoutError
+	if !errors.Is(err, context.DeadlineExceeded) || errors.As(err, &stageErr) {
+		t.Errorf("error = %v, want parent deadline exceeded", err)
+	}
+}
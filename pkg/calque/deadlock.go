@@ -0,0 +1,58 @@
+package calque
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrUnreadInput matches any UnreadInputError with errors.Is.
+var ErrUnreadInput = errors.New("handler returned without reading its input")
+
+// UnreadInputError reports a handler that returned before draining its input.
+//
+// An io.Pipe write blocks until the next handler reads it, so a handler that
+// returns early would leave the handler before it blocked forever. The flow
+// instead reads what is left of the handler's input: whitespace is discarded,
+// but the first other byte closes the input with this error, so the upstream
+// handler's writes fail with it and the flow returns a diagnostic naming the
+// handler that stopped reading. Handlers that intentionally ignore the rest
+// of their input should drain it (io.Copy(io.Discard, req.Data)) before
+// returning. The first handler of a flow may ignore flow input without error.
+//
+// Example:
+//
+//	var unread *calque.UnreadInputError
+//	if errors.As(err, &unread) {
+//		log.Printf("handler %s stopped reading its input", unread.Name)
+//	}
+type UnreadInputError struct {
+	Index int    // position of the handler that stopped reading
+	Name  string // handler name (see HandlerName)
+}
+
+func (e *UnreadInputError) Error() string {
+	return fmt.Sprintf("handler %d (%s) returned without reading its input; upstream writes cannot complete", e.Index, e.Name)
+}
+
+// Is reports whether target is ErrUnreadInput.
+func (e *UnreadInputError) Is(target error) bool {
+	return target == ErrUnreadInput
+}
+
+// drainWhitespace reads r to the end. At the first byte that is not
+// whitespace it closes r with unread and returns it.
+func drainWhitespace(r *io.PipeReader, unread *UnreadInputError) error {
+	buf := make([]byte, 512)
+	for {
+		n, err := r.Read(buf)
+		if len(bytes.TrimSpace(buf[:n])) > 0 {
+			_ = r.CloseWithError(unread)
+			return unread
+		}
+		if err != nil {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package calque
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ignoreInput writes a fixed response without reading its input
+type ignoreInput struct{}
+
+func (ignoreInput) ServeFlow(_ *Request, res *Response) error {
+	return Write(res, "ignored")
+}
+
+func (ignoreInput) Name() string { return "ignore-input" }
+
+// firstLine reads its input up to the first newline and returns
+type firstLine struct{}
+
+func (firstLine) Name() string { return "first-line" }
+
+func (firstLine) ServeFlow(req *Request, res *Response) error {
+	line, err := bufio.NewReader(req.Data).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	return Write(res, strings.TrimSpace(line))
+}
+
+// writeParts writes each part in a separate write, ignoring its input
+func writeParts(parts ...string) HandlerFunc {
+	return func(_ *Request, res *Response) error {
+		for _, part := range parts {
+			if err := Write(res, part); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestFlowUnreadInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		flow     *Flow
+		input    string
+		want     string
+		wantErr  bool
+		unreadBy string // handler named in the error
+	}{
+		{
+			name:  "first handler may ignore flow input",
+			flow:  NewFlow().Use(ignoreInput{}),
+			input: strings.Repeat("x", 1<<20),
+			want:  "ignored",
+		},
+		{
+			name:     "downstream handler ignoring upstream output",
+			flow:     NewFlow().UseFunc(passthrough).Use(ignoreInput{}),
+			input:    strings.Repeat("x", 1<<20),
+			wantErr:  true,
+			unreadBy: "ignore-input",
+		},
+		{
+			name:  "drained input is fine",
+			flow:  NewFlow().UseFunc(passthrough).UseFunc(passthrough),
+			input: "hello",
+			want:  "hello",
+		},
+		{
+			name: "trailing whitespace left unread is fine",
+			flow: NewFlow().UseFunc(writeParts("value\n", "\n  \t\n")).Use(firstLine{}),
+			want: "value",
+		},
+		{
+			name:     "trailing data left unread",
+			flow:     NewFlow().UseFunc(writeParts("value\n", "\n", "more")).Use(firstLine{}),
+			wantErr:  true,
+			unreadBy: "first-line",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var out string
+			err := tt.flow.Run(ctx, tt.input, &out)
+			if errors.Is(err, context.DeadlineExceeded) {
+				t.Fatal("flow hung")
+			}
+			if !tt.wantErr {
+				if err != nil || out != tt.want {
+					t.Errorf("out = %q, err = %v", out, err)
+				}
+				return
+			}
+			var unread *UnreadInputError
+			if !errors.As(err, &unread) || !errors.Is(err, ErrUnreadInput) {
+				t.Fatalf("error = %v, want *UnreadInputError", err)
+			}
+			if unread.Index != 1 || unread.Name != tt.unreadBy {
+				t.Errorf("unread = %+v", unread)
+			}
+		})
+	}
+}
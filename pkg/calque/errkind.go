@@ -0,0 +1,166 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Error kinds for classifying failures without string matching.
+//
+// Wrap errors with Retryable, RateLimited, InvalidInput, ProviderError or
+// Cancelled; errors.Is then matches both the kind and the original error.
+var (
+	ErrRetryable    = errors.New("retryable")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrInvalidInput = errors.New("invalid input")
+	ErrProvider     = errors.New("provider error")
+	ErrCancelled    = errors.New("cancelled")
+)
+
+// KindError tags an error with an error kind.
+//
+// The message is that of the wrapped error; errors.Is matches the kind and the
+// wrapped error.
+type KindError struct {
+	Kind       error         // one of the Err* kinds
+	Err        error         // wrapped error
+	RetryAfter time.Duration // wait suggested by the provider (ErrRateLimited only)
+}
+
+// Error implements the error interface.
+func (e *KindError) Error() string {
+	if e.Err == nil {
+		return e.Kind.Error()
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the kind and the wrapped error.
+func (e *KindError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+func withKind(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &KindError{Kind: kind, Err: err}
+}
+
+// Retryable marks err as transient, safe to retry with the same input.
+// Returns nil if err is nil.
+func Retryable(err error) error { return withKind(ErrRetryable, err) }
+
+// RateLimited marks err as a rate limit rejection; retryAfter is the wait the
+// provider asked for, or 0 if unknown. Rate-limited errors are retryable.
+// Returns nil if err is nil.
+//
+// Example:
+//
+//	if resp.StatusCode == http.StatusTooManyRequests {
+//	    return calque.RateLimited(err, parseRetryAfter(resp.Header))
+//	}
+func RateLimited(err error, retryAfter time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &KindError{Kind: ErrRateLimited, Err: err, RetryAfter: retryAfter}
+}
+
+// InvalidInput marks err as caused by the request itself; retrying or falling
+// back to another handler with the same input will not help.
+// Returns nil if err is nil.
+func InvalidInput(err error) error { return withKind(ErrInvalidInput, err) }
+
+// ProviderError marks err as a failure of an upstream service (model API,
+// vector store). Combine with Retryable for transient outages.
+// Returns nil if err is nil.
+func ProviderError(err error) error { return withKind(ErrProvider, err) }
+
+// Cancelled marks err as caused by cancellation. Returns nil if err is nil.
+func Cancelled(err error) error { return withKind(ErrCancelled, err) }
+
+// IsRetryable reports whether err is worth retrying.
+//
+// True for errors marked Retryable or RateLimited and for errors with an
+// IsRetryable() bool method reporting true (such as grpc.Error). Cancelled
+// and invalid-input errors are never retryable, even when also marked
+// Retryable; unclassified errors are not retryable.
+func IsRetryable(err error) bool {
+	if err == nil || IsCancelled(err) || IsInvalidInput(err) {
+		return false
+	}
+	if errors.Is(err, ErrRetryable) || errors.Is(err, ErrRateLimited) {
+		return true
+	}
+	var r interface{ IsRetryable() bool }
+	return errors.As(err, &r) && r.IsRetryable()
+}
+
+// IsRateLimited reports whether err is a rate limit rejection.
+func IsRateLimited(err error) bool { return errors.Is(err, ErrRateLimited) }
+
+// IsInvalidInput reports whether err was caused by invalid input.
+func IsInvalidInput(err error) bool { return errors.Is(err, ErrInvalidInput) }
+
+// IsProviderError reports whether err is an upstream provider failure.
+func IsProviderError(err error) bool { return errors.Is(err, ErrProvider) }
+
+// IsCancelled reports whether err is marked Cancelled or wraps context.Canceled.
+func IsCancelled(err error) bool {
+	return errors.Is(err, ErrCancelled) || errors.Is(err, context.Canceled)
+}
+
+// RetryAfter returns the wait requested by a rate-limited error.
+// Returns false if err is not rate limited or carries no wait.
+func RetryAfter(err error) (time.Duration, bool) {
+	var ke *KindError
+	for e := err; errors.As(e, &ke); e = ke.Err {
+		if ke.Kind == ErrRateLimited && ke.RetryAfter > 0 {
+			return ke.RetryAfter, true
+		}
+	}
+	return 0, false
+}
+
+// ClassifyHTTPStatus marks err with the kind implied by an HTTP status code
+// returned by a provider API.
+//
+// 429 is rate limited; 408, 500, 502, 503 and 504 are retryable provider
+// errors and other 5xx codes are provider errors. 413, 414 and 431 reject the
+// size of the request itself and are invalid input; other 4xx codes except 401
+// and 403 are provider errors, since a 400, 404 or 422 often means a model or
+// parameter only this provider lacks and another provider may accept the
+// request. Other codes return err unchanged.
+//
+// Example:
+//
+//	var apiErr *openai.Error
+//	if errors.As(err, &apiErr) {
+//	    err = calque.ClassifyHTTPStatus(apiErr.StatusCode, err)
+//	}
+func ClassifyHTTPStatus(status int, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case status == http.StatusTooManyRequests:
+		return RateLimited(err, 0)
+	case status == http.StatusRequestTimeout, status == http.StatusInternalServerError,
+		status == http.StatusBadGateway, status == http.StatusServiceUnavailable,
+		status == http.StatusGatewayTimeout:
+		return ProviderError(Retryable(err))
+	case status >= 500 && status < 600:
+		return ProviderError(err)
+	case status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return err
+	case status == http.StatusRequestEntityTooLarge, status == http.StatusRequestURITooLong,
+		status == http.StatusRequestHeaderFieldsTooLarge:
+		return InvalidInput(err)
+	case status >= 400 && status < 500:
+		return ProviderError(err)
+	default:
+		return err
+	}
+}
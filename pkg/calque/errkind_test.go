@@ -0,0 +1,111 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type retryableErr bool
+
+func (r retryableErr) Error() string     { return "custom" }
+func (r retryableErr) IsRetryable() bool { return bool(r) }
+
+func TestErrorKinds(t *testing.T) {
+	base := errors.New("base")
+	tests := []struct {
+		name         string
+		err          error
+		retryable    bool
+		rateLimited  bool
+		invalidInput bool
+		provider     bool
+		cancelled    bool
+	}{
+		{name: "nil", err: nil},
+		{name: "unclassified", err: base},
+		{name: "retryable", err: Retryable(base), retryable: true},
+		{name: "rate limited", err: RateLimited(base, time.Second), retryable: true, rateLimited: true},
+		{name: "invalid input", err: InvalidInput(base), invalidInput: true},
+		{name: "invalid beats retryable", err: Retryable(InvalidInput(base)), invalidInput: true},
+		{name: "retryable provider", err: ProviderError(Retryable(base)), retryable: true, provider: true},
+		{name: "cancelled", err: Cancelled(base), cancelled: true},
+		{name: "context canceled", err: fmt.Errorf("run: %w", context.Canceled), cancelled: true},
+		{name: "wrapped in calque error", err: WrapErr(context.Background(), RateLimited(base, 0), "call failed"), retryable: true, rateLimited: true},
+		{name: "IsRetryable method", err: fmt.Errorf("call: %w", retryableErr(true)), retryable: true},
+		{name: "IsRetryable method false", err: retryableErr(false)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.retryable {
+				t.Errorf("IsRetryable = %v", got)
+			}
+			if got := IsRateLimited(tt.err); got != tt.rateLimited {
+				t.Errorf("IsRateLimited = %v", got)
+			}
+			if got := IsInvalidInput(tt.err); got != tt.invalidInput {
+				t.Errorf("IsInvalidInput = %v", got)
+			}
+			if got := IsProviderError(tt.err); got != tt.provider {
+				t.Errorf("IsProviderError = %v", got)
+			}
+			if got := IsCancelled(tt.err); got != tt.cancelled {
+				t.Errorf("IsCancelled = %v", got)
+			}
+		})
+	}
+}
+
+func TestKindErrorKeepsCause(t *testing.T) {
+	base := errors.New("base")
+	err := ProviderError(Retryable(base))
+	if !errors.Is(err, base) || err.Error() != "base" {
+		t.Errorf("err = %v, want wrapping base", err)
+	}
+	if Retryable(nil) != nil || RateLimited(nil, time.Second) != nil {
+		t.Error("marking nil should return nil")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	err := WrapErr(context.Background(), ProviderError(RateLimited(errors.New("429"), 3*time.Second)), "call")
+	if d, ok := RetryAfter(err); !ok || d != 3*time.Second {
+		t.Errorf("RetryAfter = %v, %v", d, ok)
+	}
+	if _, ok := RetryAfter(RateLimited(errors.New("429"), 0)); ok {
+		t.Error("RetryAfter without a wait should report false")
+	}
+	if _, ok := RetryAfter(errors.New("x")); ok {
+		t.Error("RetryAfter on unclassified error should report false")
+	}
+}
+
+func TestClassifyHTTPStatus(t *testing.T) {
+	base := errors.New("api")
+	tests := []struct {
+		status int
+		check  func(error) bool
+	}{
+		{http.StatusTooManyRequests, IsRateLimited},
+		{http.StatusServiceUnavailable, func(err error) bool { return IsProviderError(err) && IsRetryable(err) }},
+		{http.StatusNotImplemented, func(err error) bool { return IsProviderError(err) && !IsRetryable(err) }},
+		{http.StatusRequestEntityTooLarge, IsInvalidInput},
+		{http.StatusBadRequest, func(err error) bool { return IsProviderError(err) && !IsInvalidInput(err) && !IsRetryable(err) }},
+		{http.StatusNotFound, func(err error) bool { return IsProviderError(err) && !IsInvalidInput(err) }},
+		{http.StatusUnprocessableEntity, func(err error) bool { return IsProviderError(err) && !IsInvalidInput(err) }},
+		{http.StatusUnauthorized, func(err error) bool { return err == base }},
+		{http.StatusOK, func(err error) bool { return err == base }},
+	}
+	for _, tt := range tests {
+		if err := ClassifyHTTPStatus(tt.status, base); !tt.check(err) {
+			t.Errorf("ClassifyHTTPStatus(%d) = %#v", tt.status, err)
+		}
+	}
+	if ClassifyHTTPStatus(http.StatusBadRequest, nil) != nil {
+		t.Error("nil error should stay nil")
+	}
+}
@@ -0,0 +1,259 @@
+package calque
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event is a progress event emitted during RunWithEvents.
+//
+// Receivers switch on the concrete type: *HandlerStarted, *HandlerFinished,
+// *BytesWritten, *ToolCalled or *TokenUsage.
+type Event interface {
+	// EventTime returns when the event occurred
+	EventTime() time.Time
+}
+
+// HandlerStarted is emitted when a flow handler begins executing.
+type HandlerStarted struct {
+	Index int    // position of the handler in the flow
+	Name  string // handler name (see HandlerName)
+	Time  time.Time
+}
+
+// HandlerFinished is emitted when a flow handler returns.
+type HandlerFinished struct {
+	Index    int
+	Name     string
+	Time     time.Time
+	Duration time.Duration
+	BytesIn  int64 // total bytes the handler read
+	BytesOut int64 // total bytes the handler wrote
+	Err      error // error returned by the handler, if any
+}
+
+// BytesWritten is emitted each time a flow handler writes output.
+type BytesWritten struct {
+	Index int
+	Name  string
+	Time  time.Time
+	Bytes int   // size of this write
+	Total int64 // bytes written by the handler so far
+}
+
+// ToolCalled is emitted by tools.Execute after each tool call completes and
+// attached to the run's Result.
+type ToolCalled struct {
+	Tool      string // tool name
+	ID        string // tool call ID from the model, if any
+	Arguments string // raw JSON arguments
+	Time      time.Time
+	Duration  time.Duration
+	Error     string // tool error, empty on success
+}
+
+// TokenUsage is emitted by ai.Agent when the provider reports token usage and
+// attached to the run's Result.
+type TokenUsage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	CostUSD          float64 // price of the call when the model is priced (see ai.WithModelPricing)
+	Time             time.Time
+}
+
+// EventTime implements Event.
+func (e *HandlerStarted) EventTime() time.Time { return e.Time }
+
+// EventTime implements Event.
+func (e *HandlerFinished) EventTime() time.Time { return e.Time }
+
+// EventTime implements Event.
+func (e *BytesWritten) EventTime() time.Time { return e.Time }
+
+// EventTime implements Event.
+func (e *ToolCalled) EventTime() time.Time { return e.Time }
+
+// EventTime implements Event.
+func (e *TokenUsage) EventTime() time.Time { return e.Time }
+
+type eventSinkKey struct{}
+
+// eventSink delivers events to a RunWithEvents channel until the run ends
+type eventSink struct {
+	ctx    context.Context
+	events chan<- Event
+	done   chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// emit sends an event, blocking until it is received, the run is cancelled or the sink is closed
+func (s *eventSink) emit(ev Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.events <- ev:
+	case <-s.done:
+	case <-s.ctx.Done():
+	}
+}
+
+// close stops delivery and closes the channel once no send is in progress
+func (s *eventSink) close() {
+	close(s.done)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	close(s.events)
+}
+
+// EmitEvent sends a progress event to the RunWithEvents channel in the context.
+//
+// Does nothing when the context has no event channel, so middleware can emit
+// unconditionally. Events from nested flows reach the outermost RunWithEvents.
+//
+// Example:
+//
+//	calque.EmitEvent(req.Context, &calque.TokenUsage{TotalTokens: n, Time: time.Now()})
+func EmitEvent(ctx context.Context, ev Event) {
+	if sink, ok := ctx.Value(eventSinkKey{}).(*eventSink); ok {
+		sink.emit(ev)
+	}
+}
+
+// HasEvents reports whether the context has a RunWithEvents channel.
+//
+// Use it to skip building events that are expensive to construct.
+func HasEvents(ctx context.Context) bool {
+	_, ok := ctx.Value(eventSinkKey{}).(*eventSink)
+	return ok
+}
+
+// RunWithEvents executes the flow like Run while streaming progress events to events.
+//
+// Input: context.Context for cancellation, input data (any type), output pointer (any type), event channel
+// Output: error if flow execution fails
+// Behavior: CONCURRENT - identical to Run, with each handler instrumented for progress
+//
+// Every handler of the flow emits HandlerStarted, BytesWritten for each write
+// and HandlerFinished. Middleware anywhere in the run, including nested flows,
+// adds events through EmitEvent: tools.Execute emits ToolCalled and ai.Agent
+// emits TokenUsage. Sends block until the event is received, so keep the
+// channel drained (a buffered channel smooths bursts). events is closed when
+// RunWithEvents returns; events from handlers still running after an error
+// are dropped.
+//
+// Example:
+//
+//	events := make(chan calque.Event, 64)
+//	go func() {
+//	    for ev := range events {
+//	        switch e := ev.(type) {
+//	        case *calque.HandlerStarted:
+//	            ui.SetStage(e.Name)
+//	        case *calque.TokenUsage:
+//	            ui.AddTokens(e.TotalTokens)
+//	        }
+//	    }
+//	}()
+//	err := flow.RunWithEvents(ctx, input, &result, events)
+func (f *Flow) RunWithEvents(ctx context.Context, input any, output any, events chan<- Event) error {
+	sink := &eventSink{ctx: ctx, events: events, done: make(chan struct{})}
+	defer sink.close()
+
+	done, err := f.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+	ctx = context.WithValue(ctx, eventSinkKey{}, sink)
+
+	instrumented := f.withHandlers(make([]Handler, len(f.handlers)))
+	for i, h := range f.handlers {
+		instrumented.handlers[i] = eventHandler(sink, i, h)
+	}
+	return instrumented.Run(ctx, input, output)
+}
+
+// eventHandler instruments a handler to emit lifecycle and write events
+func eventHandler(sink *eventSink, idx int, h Handler) Handler {
+	name := HandlerName(h)
+	return &tracedHandler{name: name, fn: func(req *Request, res *Response) error {
+		in := &countingReader{r: req.Data}
+		out := &eventWriter{w: res.Data, sink: sink, index: idx, name: name}
+
+		start := time.Now()
+		sink.emit(&HandlerStarted{Index: idx, Name: name, Time: start})
+
+		err := h.ServeFlow(&Request{Context: req.Context, Data: in}, &Response{Data: out})
+
+		end := time.Now()
+		sink.emit(&HandlerFinished{
+			Index:    idx,
+			Name:     name,
+			Time:     end,
+			Duration: end.Sub(start),
+			BytesIn:  in.total(),
+			BytesOut: out.total(),
+			Err:      err,
+		})
+		return err
+	}}
+}
+
+// countingReader counts bytes read by a handler
+type countingReader struct {
+	r  io.Reader
+	mu sync.Mutex
+	n  int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.mu.Lock()
+	c.n += int64(n)
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *countingReader) total() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n
+}
+
+// eventWriter emits a BytesWritten event for each write
+type eventWriter struct {
+	w     io.Writer
+	sink  *eventSink
+	index int
+	name  string
+
+	mu sync.Mutex
+	n  int64
+}
+
+func (e *eventWriter) Write(b []byte) (int, error) {
+	n, err := e.w.Write(b)
+	if n > 0 {
+		e.mu.Lock()
+		e.n += int64(n)
+		total := e.n
+		e.mu.Unlock()
+		e.sink.emit(&BytesWritten{Index: e.index, Name: e.name, Time: time.Now(), Bytes: n, Total: total})
+	}
+	return n, err
+}
+
+func (e *eventWriter) total() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.n
+}
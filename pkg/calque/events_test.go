@@ -0,0 +1,121 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// collectEvents runs flow with an event channel and returns the events it received
+func collectEvents(t *testing.T, ctx context.Context, flow *Flow, input string) ([]Event, string, error) {
+	t.Helper()
+	events := make(chan Event)
+	received := make(chan []Event)
+	go func() {
+		var got []Event
+		for ev := range events {
+			got = append(got, ev)
+		}
+		received <- got
+	}()
+
+	var out string
+	err := flow.RunWithEvents(ctx, input, &out, events)
+	return <-received, out, err
+}
+
+func TestFlow_RunWithEvents(t *testing.T) {
+	flow := NewFlow().
+		UseFunc(tracedUpper).
+		Use(namedHandler{}).
+		UseFunc(func(req *Request, res *Response) error {
+			var input string
+			if err := Read(req, &input); err != nil {
+				return err
+			}
+			EmitEvent(req.Context, &TokenUsage{TotalTokens: 7, Time: time.Now()})
+			return Write(res, input)
+		})
+
+	events, out, err := collectEvents(t, context.Background(), flow, "hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "HELLO!" {
+		t.Errorf("output = %q, want %q", out, "HELLO!")
+	}
+
+	started := map[int]string{}
+	finished := map[int]*HandlerFinished{}
+	written := map[int]int64{}
+	tokens := 0
+	for _, ev := range events {
+		if ev.EventTime().IsZero() {
+			t.Errorf("event %T has no time", ev)
+		}
+		switch e := ev.(type) {
+		case *HandlerStarted:
+			started[e.Index] = e.Name
+		case *HandlerFinished:
+			finished[e.Index] = e
+		case *BytesWritten:
+			written[e.Index] = e.Total
+		case *TokenUsage:
+			tokens += e.TotalTokens
+		}
+	}
+
+	if len(started) != 3 || started[1] != "custom-name" {
+		t.Errorf("started = %v", started)
+	}
+	if len(finished) != 3 || finished[1].BytesIn != 5 || finished[1].BytesOut != 6 || finished[1].Err != nil {
+		t.Errorf("finished[1] = %+v", finished[1])
+	}
+	if written[0] != 5 || written[1] != 6 {
+		t.Errorf("bytes written = %v", written)
+	}
+	if tokens != 7 {
+		t.Errorf("token usage = %d, want 7", tokens)
+	}
+}
+
+func TestFlow_RunWithEventsError(t *testing.T) {
+	boom := errors.New("boom")
+	flow := NewFlow().UseFunc(func(_ *Request, _ *Response) error {
+		return boom
+	})
+
+	events, _, err := collectEvents(t, context.Background(), flow, "x")
+	if !errors.Is(err, boom) {
+		t.Fatalf("error = %v, want boom", err)
+	}
+	if len(events) == 0 {
+		t.Fatal("no events received")
+	}
+	if _, ok := events[0].(*HandlerStarted); !ok {
+		t.Errorf("first event = %T, want *HandlerStarted", events[0])
+	}
+}
+
+func TestEmitEventWithoutChannel(t *testing.T) {
+	ctx := context.Background()
+	if HasEvents(ctx) {
+		t.Error("HasEvents on plain context")
+	}
+	EmitEvent(ctx, &TokenUsage{TotalTokens: 1}) // must not block or panic
+}
+
+func TestFlow_RunWithEventsLateEmit(t *testing.T) {
+	// events emitted after RunWithEvents returns are dropped, not sent on a closed channel
+	var saved context.Context
+	flow := NewFlow().UseFunc(func(req *Request, res *Response) error {
+		saved = req.Context
+		return Write(res, "ok")
+	})
+
+	if _, _, err := collectEvents(t, context.Background(), flow, ""); err != nil {
+		t.Fatal(err)
+	}
+	EmitEvent(saved, &TokenUsage{TotalTokens: 1})
+}
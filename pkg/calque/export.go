@@ -0,0 +1,275 @@
+package calque
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Export formats supported by Flow.Export.
+const (
+	ExportMermaid = "mermaid"
+	ExportDOT     = "dot"
+)
+
+// Group kinds reported by composite handlers.
+const (
+	GroupSequence = "sequence" // children run one after another (flows, chains)
+	GroupParallel = "parallel" // children all receive the input
+	GroupBranch   = "branch"   // one child is chosen per request
+	GroupFallback = "fallback" // children are tried in order until one succeeds
+	GroupWrap     = "wrap"     // a single child wrapped with extra behavior (retry, timeout)
+)
+
+// Group describes the handlers a composite handler runs.
+type Group struct {
+	Kind     string    // one of the Group* kinds
+	Children []Handler // contained handlers, in order
+	Labels   []string  // optional edge label per child (e.g. branch conditions)
+}
+
+// Grouper is implemented by handlers that contain other handlers.
+//
+// Flow.Export uses it to draw nested structure; Flow itself reports a
+// GroupSequence of its handlers.
+type Grouper interface {
+	Group() Group
+}
+
+// compositeHandler attaches a name and group structure to a handler
+type compositeHandler struct {
+	Handler
+	name  string
+	group Group
+}
+
+// Composite attaches a name and child structure to a handler for Flow.Export.
+//
+// Middleware that runs other handlers wraps its implementation with Composite
+// so exported diagrams show the contained handlers. The returned handler
+// behaves exactly like h.
+//
+// Example:
+//
+//	func Race(handlers ...calque.Handler) calque.Handler {
+//		return calque.Composite("mypkg.Race", calque.HandlerFunc(race), calque.Group{
+//			Kind:     calque.GroupParallel,
+//			Children: handlers,
+//		})
+//	}
+func Composite(name string, h Handler, group Group) Handler {
+	return &compositeHandler{Handler: h, name: name, group: group}
+}
+
+// Name implements Namer.
+func (c *compositeHandler) Name() string {
+	if c.name != "" {
+		return c.name
+	}
+	return HandlerName(c.Handler)
+}
+
+// Group implements Grouper.
+func (c *compositeHandler) Group() Group { return c.group }
+
+// Group implements Grouper, reporting the flow's handlers as a sequence.
+func (f *Flow) Group() Group {
+	return Group{Kind: GroupSequence, Children: f.Handlers()}
+}
+
+// Export renders the flow's handler chain as a Mermaid or Graphviz DOT diagram.
+//
+// Input: format, ExportMermaid or ExportDOT
+// Output: diagram source, or an error for unknown formats
+// Behavior: Walks nested flows and composite handlers (see Grouper)
+//
+// Nested flows, ctrl.Chain, ctrl.Parallel, ctrl.Branch, ctrl.Fallback and
+// wrapping middleware render as labelled subgraphs; branch edges carry their
+// condition labels.
+//
+// Example:
+//
+//	diagram, err := flow.Export(calque.ExportMermaid)
+//	if err != nil {
+//		return err
+//	}
+//	os.WriteFile("docs/pipeline.mmd", []byte(diagram), 0o644)
+func (f *Flow) Export(format string) (string, error) {
+	ex := &exporter{}
+	nodes := make([]*exportNode, 0, len(f.handlers))
+	for _, h := range f.handlers {
+		nodes = append(nodes, ex.node(h, 0))
+	}
+	ex.chain(nodes)
+
+	switch format {
+	case ExportMermaid:
+		return ex.mermaid(nodes), nil
+	case ExportDOT:
+		return ex.dot(f.name, nodes), nil
+	default:
+		return "", fmt.Errorf("unknown export format %q (want %q or %q)", format, ExportMermaid, ExportDOT)
+	}
+}
+
+// maxExportDepth bounds nesting so self-referencing groups cannot recurse forever
+const maxExportDepth = 32
+
+// exportNode is a handler in the diagram; nodes with a kind render as subgraphs
+type exportNode struct {
+	id       string
+	label    string
+	kind     string
+	children []*exportNode
+	labels   []string
+}
+
+// exportEntry is an edge target with an optional edge label
+type exportEntry struct {
+	id    string
+	label string
+}
+
+type exportEdge struct {
+	from, to, label string
+}
+
+// exporter assigns node IDs and collects edges while walking a flow
+type exporter struct {
+	next  int
+	edges []exportEdge
+}
+
+func (e *exporter) node(h Handler, depth int) *exportNode {
+	e.next++
+	n := &exportNode{id: "n" + strconv.Itoa(e.next), label: HandlerName(h)}
+	g, ok := h.(Grouper)
+	if !ok || depth >= maxExportDepth {
+		return n
+	}
+	group := g.Group()
+	if len(group.Children) == 0 {
+		return n
+	}
+	n.kind = group.Kind
+	n.labels = group.Labels
+	for _, child := range group.Children {
+		n.children = append(n.children, e.node(child, depth+1))
+	}
+	return n
+}
+
+// link adds the node's internal edges and returns where edges enter and leave it
+func (e *exporter) link(n *exportNode) ([]exportEntry, []string) {
+	switch {
+	case n.kind == "":
+		return []exportEntry{{id: n.id}}, []string{n.id}
+	case n.kind == GroupSequence:
+		return e.chain(n.children)
+	}
+
+	var entries []exportEntry
+	var exits []string
+	for i, child := range n.children {
+		in, out := e.link(child)
+		if i < len(n.labels) && n.labels[i] != "" {
+			for j := range in {
+				in[j].label = n.labels[i]
+			}
+		}
+		entries = append(entries, in...)
+		exits = append(exits, out...)
+	}
+	return entries, exits
+}
+
+// chain links nodes in order, returning the first node's entries and the last node's exits
+func (e *exporter) chain(nodes []*exportNode) ([]exportEntry, []string) {
+	var entries []exportEntry
+	var prev []string
+	for i, n := range nodes {
+		in, out := e.link(n)
+		if i == 0 {
+			entries = in
+		}
+		for _, from := range prev {
+			for _, to := range in {
+				e.edges = append(e.edges, exportEdge{from: from, to: to.id, label: to.label})
+			}
+		}
+		prev = out
+	}
+	return entries, prev
+}
+
+func (e *exporter) mermaid(nodes []*exportNode) string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	var write func(n *exportNode, indent string)
+	write = func(n *exportNode, indent string) {
+		if n.kind == "" {
+			fmt.Fprintf(&b, "%s%s[\"%s\"]\n", indent, n.id, mermaidEscape(n.label))
+			return
+		}
+		fmt.Fprintf(&b, "%ssubgraph %s[\"%s\"]\n", indent, n.id, mermaidEscape(n.label))
+		for _, child := range n.children {
+			write(child, indent+"    ")
+		}
+		fmt.Fprintf(&b, "%send\n", indent)
+	}
+	for _, n := range nodes {
+		write(n, "    ")
+	}
+	for _, edge := range e.edges {
+		if edge.label != "" {
+			fmt.Fprintf(&b, "    %s -->|\"%s\"| %s\n", edge.from, mermaidEscape(edge.label), edge.to)
+		} else {
+			fmt.Fprintf(&b, "    %s --> %s\n", edge.from, edge.to)
+		}
+	}
+	return b.String()
+}
+
+func (e *exporter) dot(name string, nodes []*exportNode) string {
+	if name == "" {
+		name = "flow"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(name))
+	b.WriteString("    rankdir=LR;\n    node [shape=box];\n")
+	var write func(n *exportNode, indent string)
+	write = func(n *exportNode, indent string) {
+		if n.kind == "" {
+			fmt.Fprintf(&b, "%s%s [label=%s];\n", indent, n.id, dotQuote(n.label))
+			return
+		}
+		fmt.Fprintf(&b, "%ssubgraph cluster_%s {\n", indent, n.id)
+		fmt.Fprintf(&b, "%s    label=%s;\n", indent, dotQuote(n.label))
+		for _, child := range n.children {
+			write(child, indent+"    ")
+		}
+		fmt.Fprintf(&b, "%s}\n", indent)
+	}
+	for _, n := range nodes {
+		write(n, "    ")
+	}
+	for _, edge := range e.edges {
+		if edge.label != "" {
+			fmt.Fprintf(&b, "    %s -> %s [label=%s];\n", edge.from, edge.to, dotQuote(edge.label))
+		} else {
+			fmt.Fprintf(&b, "    %s -> %s;\n", edge.from, edge.to)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// mermaidEscape makes a label safe inside a quoted Mermaid string
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
+
+// dotQuote quotes a DOT identifier or label
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
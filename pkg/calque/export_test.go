@@ -0,0 +1,92 @@
+package calque
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type labelHandler string
+
+func (n labelHandler) ServeFlow(req *Request, res *Response) error { return passthrough(req, res) }
+func (n labelHandler) Name() string                                { return string(n) }
+
+func exportTestFlow() *Flow {
+	inner := NewFlow(FlowConfig{Name: "inner"}).Use(labelHandler("c")).Use(labelHandler("d"))
+	fanout := Composite("fanout", HandlerFunc(passthrough), Group{
+		Kind:     GroupBranch,
+		Children: []Handler{labelHandler("e"), inner},
+		Labels:   []string{"yes", "no"},
+	})
+	return NewFlow(FlowConfig{Name: "main"}).
+		Use(labelHandler("a")).
+		Use(fanout).
+		Use(labelHandler(`say "hi"`))
+}
+
+func TestExportMermaid(t *testing.T) {
+	got, err := exportTestFlow().Export(ExportMermaid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `flowchart LR
+    n1["a"]
+    subgraph n2["fanout"]
+        n3["e"]
+        subgraph n4["inner"]
+            n5["c"]
+            n6["d"]
+        end
+    end
+    n7["say #quot;hi#quot;"]
+    n5 --> n6
+    n1 -->|"yes"| n3
+    n1 -->|"no"| n5
+    n3 --> n7
+    n6 --> n7
+`
+	if got != want {
+		t.Errorf("mermaid =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExportDOT(t *testing.T) {
+	got, err := exportTestFlow().Export(ExportDOT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`digraph "main" {`,
+		`subgraph cluster_n2 {`,
+		`label="fanout";`,
+		`n5 [label="c"];`,
+		`n7 [label="say \"hi\""];`,
+		`n1 -> n3 [label="yes"];`,
+		`n6 -> n7;`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("dot output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	if _, err := NewFlow().Export("svg"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+	empty, err := NewFlow().Export(ExportMermaid)
+	if err != nil || empty != "flowchart LR\n" {
+		t.Errorf("empty flow = %q, %v", empty, err)
+	}
+}
+
+func TestCompositeKeepsBehavior(t *testing.T) {
+	h := Composite("wrapped", HandlerFunc(passthrough), Group{Kind: GroupWrap})
+	if name := HandlerName(h); name != "wrapped" {
+		t.Errorf("HandlerName = %q", name)
+	}
+	var out string
+	if err := NewFlow().Use(h).Run(context.Background(), "x", &out); err != nil || out != "x" {
+		t.Errorf("run = %q, %v", out, err)
+	}
+}
@@ -3,9 +3,12 @@ package calque
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"runtime"
+	"runtime/pprof"
 	"sync"
+	"time"
 )
 
 // ConcurrencyUnlimited disables concurrency limits, allowing unlimited handler goroutines.
@@ -37,15 +40,53 @@ const DefaultCPUMultiplier = 50
 //
 // MaxConcurrent controls the maximum number of handler goroutines that can run
 // simultaneously across all flow executions. Use ConcurrencyUnlimited for no limits,
-// ConcurrencyAuto for CPU-based limits, or a positive integer for fixed limits.
+// ConcurrencyAuto for CPU-based limits, ConcurrencyAdaptive for a limit that
+// follows downstream latency and errors (tuned by Adaptive), or a positive
+// integer for fixed limits.
 //
 // CPUMultiplier is used when MaxConcurrent = ConcurrencyAuto to calculate the
 // actual limit: runtime.GOMAXPROCS(0) * CPUMultiplier. Higher values allow more
 // concurrency for I/O-bound workloads.
 //
+// When MaxConcurrent slots are taken, waiting handlers are admitted by run
+// priority (see WithPriority), oldest first within a priority. PriorityAging
+// promotes a waiting run one level per interval so low priority runs are not
+// starved. If 0, uses DefaultPriorityAging.
+//
 // MetadataBusBuffer sets the buffer size for the MetadataBus channel used for
 // metadata communication between concurrent handlers. If 0, uses DefaultMetadataBusBuffer.
 //
+// TracePreviewSize sets how many payload bytes RunTraced captures per handler.
+// If 0, uses DefaultTracePreviewSize.
+//
+// Name identifies the flow in traces, profiles and debugging tools. Optional.
+//
+// ProfilerLabels tags each handler goroutine with runtime/pprof labels
+// (calque_flow, calque_handler, calque_handler_index) so CPU and goroutine
+// profiles attribute time to pipeline stages. Off by default.
+//
+// StageTimeout and DivideDeadline give each handler its own slice of the
+// request deadline, so one slow stage can't consume all of it (see
+// UseWithTimeout for how stage budgets are applied).
+//
+// MaxInputBytes rejects oversized input as the first handler reads it; the
+// handler sees the *InputTooLargeError and the flow fails with it.
+// MaxMemoryBytes caps what a single Run may hold in buffers (see
+// WithMemoryLimit), failing with *MemoryLimitError instead of exhausting the
+// process.
+//
+// SlowHandlerThreshold enables a watchdog for pipelines that appear hung:
+// a handler still running after the threshold is flagged once per run with
+// its goroutine stack (usually showing the blocked pipe read or write). The
+// flag is set on the MetadataBus under MetadataSlowHandlerPrefix + name and
+// logged as a warning, or passed to OnSlowHandler when set.
+//
+// DeadLetter captures failed runs for inspection and Replay: Run keeps a copy
+// of the input as the first handler reads it, up to DeadLetterInputBytes (and
+// MaxInputBytes), and when a handler fails sends a DeadLetter with that
+// input, the error and the MetadataBus values under DeadLetterMetadata to the
+// sink. Other metadata, such as auth claims, is never recorded.
+//
 // Example configurations:
 //
 //	// Default: unlimited concurrency (best for development)
@@ -63,16 +104,51 @@ const DefaultCPUMultiplier = 50
 //	// With custom MetadataBus buffer
 //	flow := calque.NewFlow(calque.FlowConfig{MetadataBusBuffer: 200})
 type FlowConfig struct {
-	MaxConcurrent     int // ConcurrencyUnlimited, ConcurrencyAuto, or positive integer
-	CPUMultiplier     int // multiplier for GOMAXPROCS (used when MaxConcurrent = ConcurrencyAuto)
-	MetadataBusBuffer int // buffer size for MetadataBus channel (0 = DefaultMetadataBusBuffer)
+	MaxConcurrent     int                 // ConcurrencyUnlimited, ConcurrencyAuto, ConcurrencyAdaptive, or positive integer
+	CPUMultiplier     int                 // multiplier for GOMAXPROCS (used when MaxConcurrent = ConcurrencyAuto)
+	Adaptive          AdaptiveConcurrency // AIMD tuning (used when MaxConcurrent = ConcurrencyAdaptive)
+	PriorityAging     time.Duration       // promote waiting runs one Priority level per interval (0 = DefaultPriorityAging)
+	MetadataBusBuffer int                 // buffer size for MetadataBus channel (0 = DefaultMetadataBusBuffer)
+	TracePreviewSize  int                 // payload bytes captured per handler by RunTraced (0 = DefaultTracePreviewSize)
+	Name              string              // optional flow name used by traces, profiles and debug tools
+	ProfilerLabels    bool                // tag handler goroutines with runtime/pprof labels
+
+	StageTimeout   time.Duration // default budget for each handler (0 = none)
+	DivideDeadline bool          // split the context deadline not claimed by other budgets evenly across the remaining handlers
+
+	MaxInputBytes  int64 // fail with *InputTooLargeError when the flow input exceeds this size (0 = unlimited)
+	MaxMemoryBytes int64 // cap on bytes buffered in memory per Run, see WithMemoryLimit (0 = unlimited)
+
+	SlowHandlerThreshold time.Duration                               // flag handlers still running after this long (0 = off)
+	OnSlowHandler        func(ctx context.Context, slow SlowHandler) // called for flagged handlers instead of logging a warning
+
+	DeadLetter           DeadLetterSink // records the input, metadata and error of failed runs (nil = off)
+	DeadLetterInputBytes int64          // input kept for a dead letter (0 = DefaultDeadLetterInputBytes)
+	DeadLetterMetadata   []string       // MetadataBus key prefixes recorded in dead letters (nil = none)
+
+	ChunkBoundary ChunkBoundary // text mode: keep characters or grapheme clusters whole in each handler read (default ChunkBytes)
 }
 
 // Flow is the core flow orchestration primitive
 type Flow struct {
 	handlers          []Handler
-	sem               chan struct{} // nil = unlimited concurrency
-	metadataBusBuffer int           // buffer size for auto-created MetadataBus
+	sem               *admission      // nil = unlimited concurrency
+	metadataBusBuffer int             // buffer size for auto-created MetadataBus
+	tracePreviewSize  int             // payload preview size for RunTraced
+	name              string          // optional flow name
+	profilerLabels    bool            // apply pprof labels to handler goroutines
+	stageTimeouts     []time.Duration // explicit per-handler budgets from UseWithTimeout (0 = none)
+	stageTimeout      time.Duration   // default per-handler budget
+	divideDeadline    bool            // derive budgets from the context deadline
+	maxInputBytes     int64           // flow input size limit (0 = unlimited)
+	maxMemoryBytes    int64           // per-run buffered bytes cap (0 = unlimited)
+	slowThreshold     time.Duration   // watchdog threshold (0 = off)
+	onSlowHandler     func(context.Context, SlowHandler)
+	deadLetter        DeadLetterSink // failed-run sink (nil = off)
+	deadLetterInput   int64          // input bytes kept for a dead letter
+	deadLetterKeys    []string       // MetadataBus key prefixes recorded in dead letters
+	chunkBoundary     ChunkBoundary  // where handler reads may split text
+	life              lifecycle      // Init/Close state, see lifecycle.go
 }
 
 // NewFlow creates a new flow with optional concurrency configuration.
@@ -86,7 +162,8 @@ type Flow struct {
 // Each handler in the flow runs in its own goroutine, connected by io.Pipe.
 //
 // The semaphore limits the total number of handler goroutines across ALL flow
-// executions, preventing resource exhaustion under high concurrent load.
+// executions, preventing resource exhaustion under high concurrent load. A run
+// is admitted with slots for all of its handlers at once.
 //
 // Example usage:
 //
@@ -113,7 +190,7 @@ func NewFlow(configs ...FlowConfig) *Flow {
 		}
 	}
 
-	var sem chan struct{}
+	var sem *admission
 	switch config.MaxConcurrent {
 	case ConcurrencyUnlimited:
 		// Unlimited concurrency
@@ -125,11 +202,15 @@ func NewFlow(configs ...FlowConfig) *Flow {
 			multiplier = DefaultCPUMultiplier
 		}
 		limit := runtime.GOMAXPROCS(0) * multiplier
-		sem = make(chan struct{}, limit)
+		sem = newAdmission(limit, config.PriorityAging)
+	case ConcurrencyAdaptive:
+		// AIMD limit driven by handler outcomes
+		sem = newAdmission(0, config.PriorityAging)
+		sem.adaptive = newAIMD(config.Adaptive)
 	default:
 		// Fixed limit
 		if config.MaxConcurrent > 0 {
-			sem = make(chan struct{}, config.MaxConcurrent)
+			sem = newAdmission(config.MaxConcurrent, config.PriorityAging)
 		}
 	}
 
@@ -139,7 +220,79 @@ func NewFlow(configs ...FlowConfig) *Flow {
 		mbBuffer = DefaultMetadataBusBuffer
 	}
 
-	return &Flow{sem: sem, metadataBusBuffer: mbBuffer}
+	previewSize := config.TracePreviewSize
+	if previewSize <= 0 {
+		previewSize = DefaultTracePreviewSize
+	}
+
+	deadLetterInput := config.DeadLetterInputBytes
+	if deadLetterInput <= 0 {
+		deadLetterInput = DefaultDeadLetterInputBytes
+	}
+	if config.MaxInputBytes > 0 {
+		deadLetterInput = min(deadLetterInput, config.MaxInputBytes)
+	}
+
+	return &Flow{
+		sem:               sem,
+		metadataBusBuffer: mbBuffer,
+		tracePreviewSize:  previewSize,
+		name:              config.Name,
+		profilerLabels:    config.ProfilerLabels,
+		stageTimeout:      config.StageTimeout,
+		divideDeadline:    config.DivideDeadline,
+		maxInputBytes:     config.MaxInputBytes,
+		maxMemoryBytes:    config.MaxMemoryBytes,
+		slowThreshold:     config.SlowHandlerThreshold,
+		onSlowHandler:     config.OnSlowHandler,
+		deadLetter:        config.DeadLetter,
+		deadLetterInput:   deadLetterInput,
+		deadLetterKeys:    config.DeadLetterMetadata,
+		chunkBoundary:     config.ChunkBoundary,
+	}
+}
+
+// Name returns the flow name set via FlowConfig.Name (empty if unnamed).
+func (f *Flow) Name() string {
+	return f.name
+}
+
+// Concurrency reports how many handler goroutine slots of the MaxConcurrent
+// limit are in use across all runs, and the limit (0 when unlimited). With
+// ConcurrencyAdaptive the limit is the current adaptive one.
+func (f *Flow) Concurrency() (inUse, limit int) {
+	if f.sem == nil {
+		return 0, 0
+	}
+	return f.sem.usage()
+}
+
+// Handlers returns a copy of the handlers registered on the flow, in execution order.
+func (f *Flow) Handlers() []Handler {
+	handlers := make([]Handler, len(f.handlers))
+	copy(handlers, f.handlers)
+	return handlers
+}
+
+// withHandlers returns a flow with the same configuration running the given handlers
+func (f *Flow) withHandlers(handlers []Handler) *Flow {
+	return &Flow{
+		handlers:          handlers,
+		sem:               f.sem,
+		metadataBusBuffer: f.metadataBusBuffer,
+		tracePreviewSize:  f.tracePreviewSize,
+		name:              f.name,
+		profilerLabels:    f.profilerLabels,
+		stageTimeouts:     f.stageTimeouts,
+		stageTimeout:      f.stageTimeout,
+		divideDeadline:    f.divideDeadline,
+		maxInputBytes:     f.maxInputBytes,
+		maxMemoryBytes:    f.maxMemoryBytes,
+		slowThreshold:     f.slowThreshold,
+		onSlowHandler:     f.onSlowHandler,
+		deadLetter:        f.deadLetter,
+		chunkBoundary:     f.chunkBoundary,
+	}
 }
 
 // Use adds a handler to the flow chain.
@@ -159,8 +312,7 @@ func NewFlow(configs ...FlowConfig) *Flow {
 //		Use(ai.Agent(client)).
 //		Use(logger.Print("OUTPUT"))
 func (f *Flow) Use(handler Handler) *Flow {
-	f.handlers = append(f.handlers, handler)
-	return f
+	return f.UseWithTimeout(handler, 0)
 }
 
 // UseFunc adds a function as a handler using the HandlerFunc adapter.
@@ -198,6 +350,11 @@ func (f *Flow) UseFunc(fn HandlerFunc) *Flow {
 //	subFlow := calque.NewFlow().Use(handler1).Use(handler2)
 //	mainFlow := calque.NewFlow().Use(subFlow).Use(handler3)
 func (f *Flow) ServeFlow(req *Request, res *Response) error {
+	done, err := f.begin(req.Context)
+	if err != nil {
+		return err
+	}
+	defer done()
 	return f.runWithStreaming(req.Context, req.Data, res.Data)
 }
 
@@ -220,6 +377,9 @@ func (f *Flow) ServeFlow(req *Request, res *Response) error {
 // Context cancellation propagates through all handlers for clean shutdown.
 // Flow execution fails if any handler returns an error.
 //
+// The first run initializes handlers implementing Initializer (see Init);
+// runs after Close fail with ErrFlowClosed.
+//
 // Example:
 //
 //	var result string
@@ -229,6 +389,13 @@ func (f *Flow) ServeFlow(req *Request, res *Response) error {
 //	}
 //	fmt.Println("Output:", result)
 func (f *Flow) Run(ctx context.Context, input any, output any) error {
+	// Initialize handlers on first use and keep Close from tearing them down mid-run
+	done, err := f.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
 	// Auto-create MetadataBus if not present in context
 	var mb *MetadataBus
 	if GetMetadataBus(ctx) == nil {
@@ -237,6 +404,13 @@ func (f *Flow) Run(ctx context.Context, input any, output any) error {
 		defer mb.Close()
 	}
 
+	// Account buffered bytes against the per-run cap unless a caller already set one
+	if f.maxMemoryBytes > 0 {
+		if _, ok := MemoryUsed(ctx); !ok {
+			ctx = WithMemoryLimit(ctx, f.maxMemoryBytes)
+		}
+	}
+
 	if len(f.handlers) == 0 {
 		// No handlers, just copy input to output with conversion
 		return f.copyInputToOutput(input, output)
@@ -248,14 +422,33 @@ func (f *Flow) Run(ctx context.Context, input any, output any) error {
 		return err
 	}
 
+	// Keep a copy of the input for the dead letter sink as it streams
+	var capture *inputCapture
+	if f.deadLetter != nil {
+		capture = &inputCapture{r: reader, limit: f.deadLetterInput}
+		reader = capture
+	}
+
 	// 2. Execute flow with pure streaming I/O
-	var outputBuffer bytes.Buffer
-	if err := f.runWithStreaming(ctx, reader, &outputBuffer); err != nil {
+	outputBuffer := NewMemoryBuffer(ctx)
+	defer outputBuffer.Release()
+	if err := f.runWithStreaming(ctx, reader, outputBuffer); err != nil {
+		if f.deadLetter != nil {
+			f.sendDeadLetter(ctx, capture, err)
+		}
 		return err
 	}
 
 	// 3. Convert io.Reader -> output (any)
-	return f.readerToOutput(&outputBuffer, output)
+	return f.readerToOutput(bytes.NewReader(outputBuffer.Bytes()), output)
+}
+
+// releasableOutput is an output whose contents are dropped once the flow
+// returns, such as a MemoryBuffer. The flow stops copying into it before
+// returning on failure, so its writes must never block.
+type releasableOutput interface {
+	io.Writer
+	Release()
 }
 
 // runWithStreaming executes the flow with pure streaming I/O (no conversions).
@@ -273,6 +466,16 @@ func (f *Flow) runWithStreaming(ctx context.Context, input io.Reader, output io.
 		return err
 	}
 
+	// Take a slot for every handler at once, by run priority, if limiting is enabled
+	var waited time.Duration
+	if f.sem != nil {
+		queued := time.Now()
+		if err := f.sem.acquire(ctx, PriorityFrom(ctx), len(f.handlers)); err != nil {
+			return err // Flow cancelled while waiting for slots
+		}
+		waited = time.Since(queued)
+	}
+
 	// Create a chain of pipes between handlers
 	pipes := make([]struct {
 		r *PipeReader
@@ -298,8 +501,10 @@ func (f *Flow) runWithStreaming(ctx context.Context, input io.Reader, output io.
 				_ = err
 			}
 		}()
-		if _, err := io.Copy(inputW, input); err != nil {
+		var unread *UnreadInputError
+		if _, err := io.Copy(inputW, input); err != nil && !errors.As(err, &unread) {
 			// Send copy errors to error channel as they indicate real problems
+			// (the first handler may legitimately ignore the flow input)
 			select {
 			case errCh <- err:
 			default:
@@ -316,20 +521,16 @@ func (f *Flow) runWithStreaming(ctx context.Context, input io.Reader, output io.
 	//  Handler2:   [========]
 	//  Handler3:     [========]
 	var wg sync.WaitGroup
+	deadlines := f.stageDeadlines(ctx, time.Now())
 
 	for i, handler := range f.handlers {
 		wg.Add(1)
 		go func(idx int, h Handler) {
-			// Acquire semaphore if limiting is enabled
+			var handlerErr error
 			if f.sem != nil {
-				select {
-				case f.sem <- struct{}{}: // Try to acquire semaphore slot
-					defer func() { <-f.sem }() // Release when this handler completes
-				case <-ctx.Done():
-					errCh <- ctx.Err() // Flow cancelled while waiting for semaphore
-					wg.Done()
-					return
-				}
+				// Release this handler's slot when it completes, reporting its outcome
+				start := time.Now()
+				defer func() { f.sem.release(HandlerName(h), start, waited, handlerErr) }()
 			}
 
 			defer wg.Done()
@@ -341,18 +542,56 @@ func (f *Flow) runWithStreaming(ctx context.Context, input io.Reader, output io.
 				}
 			}()
 
+			// Fail upstream writes if this handler returns without draining its input,
+			// rather than leaving the upstream handler blocked forever
+			defer func() {
+				unread := &UnreadInputError{Index: idx, Name: HandlerName(h)}
+				if idx == 0 {
+					_ = inputR.CloseWithError(unread)
+					return
+				}
+				// Trailing whitespace, such as a final newline after a JSON value, is drained
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := drainWhitespace(pipes[idx-1].r.PipeReader, unread); err != nil {
+						select {
+						case errCh <- err:
+						default:
+						}
+					}
+				}()
+			}()
+
 			var reader io.Reader
 			if idx == 0 {
 				reader = inputReader // Handler 0 reads from inputReader
+				if f.maxInputBytes > 0 {
+					reader = NewLimitReader(inputReader, f.maxInputBytes)
+				}
 			} else {
 				reader = pipes[idx-1].r // Subsequent handlers read from the previous pipe's reader
 			}
+			reader = NewTextReader(reader, f.chunkBoundary)
 
 			// Each handler writes to its own pipe writer, which feeds the next handler
-			req := &Request{Context: ctx, Data: reader}
 			res := &Response{Data: pipes[idx].w}
-			if err := h.ServeFlow(req, res); err != nil {
-				errCh <- err
+			serve := func(ctx context.Context) {
+				stageCtx, cancel := stageContext(ctx, deadlines[idx], idx, h)
+				defer cancel()
+				defer f.watchHandler(stageCtx, idx, h)()
+				req := &Request{Context: stageCtx, Data: reader}
+				if err := h.ServeFlow(req, res); err != nil {
+					handlerErr = stageError(stageCtx, err)
+					errCh <- handlerErr
+				}
+			}
+
+			// Attribute CPU and goroutine profile samples to this pipeline stage
+			if f.profilerLabels {
+				pprof.Do(ctx, handlerLabels(f.name, h, idx), serve)
+			} else {
+				serve(ctx)
 			}
 		}(i, handler)
 	}
@@ -364,6 +603,15 @@ func (f *Flow) runWithStreaming(ctx context.Context, input io.Reader, output io.
 		outputDone <- err
 	}()
 
+	// Run releases its output buffer once the flow returns, so on failure wait
+	// for the copy into it to stop
+	waitBuffered := func(err error) {
+		if _, ok := output.(releasableOutput); ok {
+			_ = finalReader.CloseWithError(err)
+			<-outputDone
+		}
+	}
+
 	// Waits for either: context cancellation, handler error, or all handlers complete
 	done := make(chan struct{})
 	go func() {
@@ -373,10 +621,25 @@ func (f *Flow) runWithStreaming(ctx context.Context, input io.Reader, output io.
 
 	select {
 	case <-ctx.Done():
+		// Unblock handlers stuck reading or writing pipes so they stop mid-copy
+		cause := context.Cause(ctx)
+		_ = inputR.CloseWithError(cause)
+		for _, p := range pipes {
+			_ = p.w.CloseWithError(cause) // readers see the cause, writers a closed pipe
+		}
+		waitBuffered(cause)
 		return ctx.Err()
 	case err := <-errCh:
+		waitBuffered(err)
 		return err
 	case <-done:
+		// Errors sent just before the last handler finished still win over success
+		select {
+		case err := <-errCh:
+			waitBuffered(err)
+			return err
+		default:
+		}
 		// Wait for output collection to complete
 		return <-outputDone
 	}
@@ -116,9 +116,9 @@ func TestNewFlow_Configuration(t *testing.T) {
 			} else {
 				if flow.sem == nil {
 					t.Errorf("%s: expected non-nil semaphore but got nil", tt.description)
-				} else if cap(flow.sem) != tt.expectSemCap {
+				} else if flow.sem.limit != tt.expectSemCap {
 					t.Errorf("%s: expected semaphore capacity %d but got %d",
-						tt.description, tt.expectSemCap, cap(flow.sem))
+						tt.description, tt.expectSemCap, flow.sem.limit)
 				}
 			}
 
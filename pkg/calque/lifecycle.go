@@ -0,0 +1,200 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrFlowClosed is returned when running a flow after Close.
+var ErrFlowClosed = errors.New("flow is closed")
+
+// Initializer is implemented by handlers that need setup before their first run.
+//
+// Flow.Init (called automatically by the first run) invokes Init once on every
+// handler that implements it, in flow order. Nested flows initialize their own
+// handlers the same way.
+type Initializer interface {
+	Init(ctx context.Context) error
+}
+
+// CloserHandler is a handler holding resources released when its flow closes.
+//
+// Flow.Close calls Close on every handler that implements io.Closer, in reverse
+// flow order, after in-flight runs finish. Handlers holding connections (DB,
+// gRPC, model clients) implement it to tie teardown to the flow instead of
+// ad-hoc globals.
+type CloserHandler interface {
+	Handler
+	io.Closer
+}
+
+// lifecycle tracks a flow's Init/Close state and its in-flight runs
+type lifecycle struct {
+	mu      sync.Mutex
+	inited  bool
+	closed  bool
+	initErr error
+	running sync.WaitGroup
+}
+
+// Init initializes every handler in the flow that implements Initializer.
+//
+// Input: context.Context passed to each handler's Init
+// Output: error from the first failing handler
+// Behavior: Idempotent - handlers are initialized once; later calls return the first result
+//
+// Runs call Init automatically, so calling it explicitly is only needed to
+// fail fast at startup. If a handler fails, the handlers already initialized
+// are closed again and the error is returned by every later run.
+//
+// Example:
+//
+//	flow := calque.NewFlow().Use(dbLookup).Use(ai.Agent(client))
+//	if err := flow.Init(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+//	defer flow.Close()
+func (f *Flow) Init(ctx context.Context) error {
+	f.life.mu.Lock()
+	defer f.life.mu.Unlock()
+	if f.life.closed {
+		return ErrFlowClosed
+	}
+	return f.initLocked(ctx)
+}
+
+// initLocked runs handler Init hooks once; the lifecycle mutex must be held
+func (f *Flow) initLocked(ctx context.Context) error {
+	if f.life.inited {
+		return f.life.initErr
+	}
+	f.life.inited = true
+	for i, h := range f.handlers {
+		init, ok := h.(Initializer)
+		if !ok {
+			continue
+		}
+		if err := init.Init(ctx); err != nil {
+			f.life.initErr = WrapErr(ctx, errors.Join(err, closeHandlers(f.handlers[:i])), "init handler "+HandlerName(h))
+			return f.life.initErr
+		}
+	}
+	return nil
+}
+
+// Close releases every handler in the flow that implements io.Closer.
+//
+// Input: none
+// Output: errors from all failing Close calls, joined
+// Behavior: Idempotent - waits for in-flight runs, then closes handlers in reverse order
+//
+// New runs fail with ErrFlowClosed once Close has started. Nested flows close
+// their own handlers the same way.
+//
+// Example:
+//
+//	flow := calque.NewFlow().Use(grpcHandler).Use(ai.Agent(client))
+//	defer flow.Close()
+func (f *Flow) Close() error {
+	f.life.mu.Lock()
+	if f.life.closed {
+		f.life.mu.Unlock()
+		return nil
+	}
+	f.life.closed = true
+	f.life.mu.Unlock()
+
+	f.life.running.Wait()
+	return closeHandlers(f.handlers)
+}
+
+// begin initializes the flow if needed and registers an in-flight run
+func (f *Flow) begin(ctx context.Context) (func(), error) {
+	f.life.mu.Lock()
+	defer f.life.mu.Unlock()
+	if f.life.closed {
+		return nil, ErrFlowClosed
+	}
+	if err := f.initLocked(ctx); err != nil {
+		return nil, err
+	}
+	f.life.running.Add(1)
+	return f.life.running.Done, nil
+}
+
+// closeHandlers closes handlers implementing io.Closer in reverse order
+func closeHandlers(handlers []Handler) error {
+	var errs []error
+	for i := len(handlers) - 1; i >= 0; i-- {
+		if c, ok := handlers[i].(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// managedHandler attaches lifecycle hooks to a handler
+type managedHandler struct {
+	Handler
+	init  func(ctx context.Context) error
+	close func() error
+}
+
+// Managed attaches Init and Close hooks to a handler so its flow manages them.
+//
+// Input: handler to wrap, init hook (nil to skip), close hook (nil to skip)
+// Output: calque.Handler implementing Initializer and CloserHandler
+// Behavior: STREAMING - delegates to the wrapped handler
+//
+// Use it for function handlers that own a resource. The wrapper keeps the
+// wrapped handler's name, and the wrapped handler's own Init and Close (if
+// any) still run: Init before the hook, Close after it.
+//
+// Example:
+//
+//	var db *sql.DB
+//	lookup := calque.Managed(calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+//		return queryCustomer(req, res, db)
+//	}), func(ctx context.Context) (err error) {
+//		db, err = sql.Open("postgres", dsn)
+//		return err
+//	}, func() error {
+//		return db.Close()
+//	})
+func Managed(handler Handler, init func(ctx context.Context) error, close func() error) Handler {
+	return &managedHandler{Handler: handler, init: init, close: close}
+}
+
+// Init implements Initializer, initializing the wrapped handler first.
+func (m *managedHandler) Init(ctx context.Context) error {
+	if inner, ok := m.Handler.(Initializer); ok {
+		if err := inner.Init(ctx); err != nil {
+			return err
+		}
+	}
+	if m.init == nil {
+		return nil
+	}
+	return m.init(ctx)
+}
+
+// Close implements io.Closer, closing the wrapped handler last.
+func (m *managedHandler) Close() error {
+	var err error
+	if m.close != nil {
+		err = m.close()
+	}
+	if inner, ok := m.Handler.(io.Closer); ok {
+		err = errors.Join(err, inner.Close())
+	}
+	return err
+}
+
+// Name implements Namer using the wrapped handler's name.
+func (m *managedHandler) Name() string {
+	return HandlerName(m.Handler)
+}
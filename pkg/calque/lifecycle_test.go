@@ -0,0 +1,138 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// resourceHandler records lifecycle calls into a shared log
+type resourceHandler struct {
+	name    string
+	log     *[]string
+	mu      *sync.Mutex
+	initErr error
+}
+
+func (r *resourceHandler) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.log = append(*r.log, r.name+":"+event)
+}
+
+func (r *resourceHandler) Init(context.Context) error {
+	r.record("init")
+	return r.initErr
+}
+
+func (r *resourceHandler) Close() error {
+	r.record("close")
+	return nil
+}
+
+func (r *resourceHandler) ServeFlow(req *Request, res *Response) error {
+	_, err := io.Copy(res.Data, req.Data)
+	return err
+}
+
+func TestFlowLifecycle(t *testing.T) {
+	var log []string
+	var mu sync.Mutex
+	res := func(name string) *resourceHandler { return &resourceHandler{name: name, log: &log, mu: &mu} }
+
+	inner := NewFlow().Use(res("inner"))
+	var _ CloserHandler = inner
+	managed := res("managed")
+	flow := NewFlow().
+		Use(res("a")).
+		Use(inner).
+		Use(Managed(HandlerFunc(passthrough),
+			func(context.Context) error { managed.record("init"); return nil },
+			func() error { managed.record("close"); return nil },
+		))
+
+	for range 2 {
+		var out string
+		if err := flow.Run(context.Background(), "x", &out); err != nil || out != "x" {
+			t.Fatalf("out = %q, err = %v", out, err)
+		}
+	}
+	if err := flow.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := flow.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+
+	want := "a:init,inner:init,managed:init,managed:close,inner:close,a:close"
+	if got := strings.Join(log, ","); got != want {
+		t.Errorf("lifecycle = %s\nwant        %s", got, want)
+	}
+
+	var out string
+	if err := flow.Run(context.Background(), "x", &out); !errors.Is(err, ErrFlowClosed) {
+		t.Errorf("run after Close = %v, want ErrFlowClosed", err)
+	}
+}
+
+func TestFlowInitFailure(t *testing.T) {
+	var log []string
+	var mu sync.Mutex
+	boom := errors.New("connect failed")
+	flow := NewFlow().
+		Use(&resourceHandler{name: "ok", log: &log, mu: &mu}).
+		Use(&resourceHandler{name: "broken", log: &log, mu: &mu, initErr: boom})
+
+	if err := flow.Init(context.Background()); !errors.Is(err, boom) {
+		t.Fatalf("Init = %v, want %v", err, boom)
+	}
+	var out string
+	if err := flow.Run(context.Background(), "x", &out); !errors.Is(err, boom) {
+		t.Errorf("run after failed init = %v", err)
+	}
+	if got := strings.Join(log, ","); got != "ok:init,broken:init,ok:close" {
+		t.Errorf("lifecycle = %s", got)
+	}
+}
+
+func TestFlowCloseWaitsForRuns(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	closed := make(chan struct{})
+	h := Managed(HandlerFunc(func(req *Request, res *Response) error {
+		close(started)
+		<-release
+		return passthrough(req, res)
+	}), nil, func() error {
+		close(closed)
+		return nil
+	})
+	flow := NewFlow().Use(h)
+
+	runDone := make(chan error, 1)
+	go func() {
+		var out string
+		runDone <- flow.Run(context.Background(), "x", &out)
+	}()
+	<-started
+
+	closeDone := make(chan error, 1)
+	go func() { closeDone <- flow.Close() }()
+
+	select {
+	case <-closed:
+		t.Fatal("handler closed while a run was in flight")
+	default:
+	}
+	close(release)
+	if err := <-runDone; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-closeDone; err != nil {
+		t.Fatal(err)
+	}
+	<-closed
+}
@@ -0,0 +1,70 @@
+package calque
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrInputTooLarge matches any InputTooLargeError with errors.Is.
+var ErrInputTooLarge = errors.New("input too large")
+
+// InputTooLargeError is returned when a stream exceeds its size limit.
+//
+// Example:
+//
+//	var sizeErr *calque.InputTooLargeError
+//	if errors.As(err, &sizeErr) {
+//		log.Printf("rejected input over %d bytes", sizeErr.Limit)
+//	}
+type InputTooLargeError struct {
+	Limit int64 // configured limit in bytes
+}
+
+func (e *InputTooLargeError) Error() string {
+	return fmt.Sprintf("input too large: exceeds %d byte limit", e.Limit)
+}
+
+// Is reports whether target is ErrInputTooLarge.
+func (e *InputTooLargeError) Is(target error) bool {
+	return target == ErrInputTooLarge
+}
+
+// limitReader fails with InputTooLargeError once more than limit bytes are read
+type limitReader struct {
+	r     io.Reader
+	limit int64
+	n     int64
+}
+
+// NewLimitReader wraps r so reading more than limit bytes fails with *InputTooLargeError.
+//
+// Unlike io.LimitReader, which silently truncates, the oversized stream is an
+// error: the first limit bytes are delivered and the next Read fails. A stream
+// of exactly limit bytes reads cleanly to io.EOF.
+//
+// Example:
+//
+//	body := calque.NewLimitReader(req.Data, 10<<20) // 10 MB
+//	if err := calque.Read(&calque.Request{Context: req.Context, Data: body}, &input); err != nil {
+//		return err
+//	}
+func NewLimitReader(r io.Reader, limit int64) io.Reader {
+	return &limitReader{r: r, limit: limit}
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.n > l.limit {
+		return 0, &InputTooLargeError{Limit: l.limit}
+	}
+	// read one byte past the limit so an exact-size stream still hits EOF cleanly
+	if room := l.limit - l.n + 1; int64(len(p)) > room {
+		p = p[:room]
+	}
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.n > l.limit {
+		return n - int(l.n-l.limit), &InputTooLargeError{Limit: l.limit}
+	}
+	return n, err
+}
@@ -0,0 +1,60 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLimitReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int64
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "under limit", limit: 10, input: "hello", want: "hello"},
+		{name: "exactly at limit", limit: 5, input: "hello", want: "hello"},
+		{name: "over limit", limit: 3, input: "hello", want: "hel", wantErr: true},
+		{name: "empty", limit: 1, input: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := io.ReadAll(NewLimitReader(strings.NewReader(tt.input), tt.limit))
+			if string(got) != tt.want {
+				t.Errorf("read %q, want %q", got, tt.want)
+			}
+			var sizeErr *InputTooLargeError
+			if tt.wantErr != errors.As(err, &sizeErr) {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && (sizeErr.Limit != tt.limit || !errors.Is(err, ErrInputTooLarge)) {
+				t.Errorf("error = %+v", sizeErr)
+			}
+		})
+	}
+}
+
+func TestFlowMaxInputBytes(t *testing.T) {
+	flow := NewFlow(FlowConfig{MaxInputBytes: 8}).UseFunc(passthrough)
+
+	var out string
+	if err := flow.Run(context.Background(), "small", &out); err != nil || out != "small" {
+		t.Fatalf("small input: out = %q, err = %v", out, err)
+	}
+
+	err := flow.Run(context.Background(), strings.Repeat("x", 1<<20), &out)
+	if !errors.Is(err, ErrInputTooLarge) {
+		t.Errorf("large input error = %v, want ErrInputTooLarge", err)
+	}
+
+	// the limit applies to a flow's input when it is nested as a handler
+	outer := NewFlow().UseFunc(passthrough).Use(flow)
+	if err := outer.Run(context.Background(), "way too large", &out); !errors.Is(err, ErrInputTooLarge) {
+		t.Errorf("nested flow error = %v, want ErrInputTooLarge", err)
+	}
+}
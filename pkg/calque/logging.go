@@ -89,7 +89,7 @@ func LogError(ctx context.Context, msg string, err error, args ...any) {
 	logger.ErrorContext(ctx, msg, args...)
 }
 
-// appendContextFields adds trace_id and request_id to args if present in context.
+// appendContextFields adds trace_id, request_id and tenant_id to args if present in context.
 //
 // This is called by all log functions to ensure consistent context propagation.
 func appendContextFields(ctx context.Context, args []any) []any {
@@ -99,6 +99,9 @@ func appendContextFields(ctx context.Context, args []any) []any {
 	if requestID := RequestID(ctx); requestID != "" {
 		args = append(args, "request_id", requestID)
 	}
+	if tenantID := TenantID(ctx); tenantID != "" {
+		args = append(args, "tenant_id", tenantID)
+	}
 	return args
 }
 
@@ -386,6 +386,16 @@ func TestAppendContextFields(t *testing.T) {
 		}
 	})
 
+	t.Run("appends tenant ID", func(t *testing.T) {
+		ctx := WithTenant(context.Background(), "acme")
+
+		args := appendContextFields(ctx, []any{"key", "value"})
+
+		if len(args) != 4 || args[2] != "tenant_id" || args[3] != "acme" {
+			t.Errorf("expected tenant_id=acme appended, got %v", args)
+		}
+	})
+
 	t.Run("no append when no context fields", func(t *testing.T) {
 		ctx := context.Background()
 		args := appendContextFields(ctx, []any{"key", "value"})
@@ -0,0 +1,167 @@
+package calque
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrMemoryLimit matches any MemoryLimitError with errors.Is.
+var ErrMemoryLimit = errors.New("memory limit exceeded")
+
+// MemoryLimitError is returned when a run's buffered bytes would exceed its cap.
+//
+// Example:
+//
+//	var memErr *calque.MemoryLimitError
+//	if errors.As(err, &memErr) {
+//		log.Printf("run buffered %d of %d bytes", memErr.Used, memErr.Limit)
+//	}
+type MemoryLimitError struct {
+	Limit     int64 // configured cap in bytes
+	Used      int64 // bytes held when the reservation failed
+	Requested int64 // bytes the failed reservation asked for
+}
+
+func (e *MemoryLimitError) Error() string {
+	return fmt.Sprintf("memory limit exceeded: %d bytes buffered, %d more requested, limit %d", e.Used, e.Requested, e.Limit)
+}
+
+// Is reports whether target is ErrMemoryLimit.
+func (e *MemoryLimitError) Is(target error) bool {
+	return target == ErrMemoryLimit
+}
+
+// MemoryUsage is the buffered-byte accounting of a run.
+type MemoryUsage struct {
+	Limit int64 // cap in bytes (0 = unlimited, tracking only)
+	Used  int64 // bytes currently reserved
+	Peak  int64 // highest Used seen during the run
+}
+
+type memoryAccountKey struct{}
+
+// memoryAccount tracks bytes reserved by buffering handlers for one run
+type memoryAccount struct {
+	mu    sync.Mutex
+	usage MemoryUsage
+}
+
+// WithMemoryLimit returns a context whose run may buffer at most limit bytes.
+//
+// Input: parent context, limit in bytes (0 tracks usage without a cap)
+// Output: context carrying a fresh memory account
+// Behavior: Shared - every handler of the run, including nested flows, draws on the same account
+//
+// Flow.Run installs an account automatically when FlowConfig.MaxMemoryBytes is
+// set and the context has none. Handlers that hold data in memory (buffering,
+// batching, retry replays) charge it with ReserveMemory or NewMemoryBuffer;
+// streaming handlers are unaffected.
+//
+// Example:
+//
+//	ctx := calque.WithMemoryLimit(r.Context(), 64<<20) // 64 MB per request
+//	err := flow.Run(ctx, input, &output)
+func WithMemoryLimit(ctx context.Context, limit int64) context.Context {
+	return context.WithValue(ctx, memoryAccountKey{}, &memoryAccount{usage: MemoryUsage{Limit: max(limit, 0)}})
+}
+
+// ReserveMemory charges n buffered bytes to the run's memory account.
+//
+// Returns *MemoryLimitError without reserving anything if the cap would be
+// exceeded. Does nothing when the context has no account. Every successful
+// reservation must be paired with ReleaseMemory once the bytes are dropped.
+//
+// Example:
+//
+//	if err := calque.ReserveMemory(req.Context, int64(len(payload))); err != nil {
+//		return err
+//	}
+//	defer calque.ReleaseMemory(req.Context, int64(len(payload)))
+func ReserveMemory(ctx context.Context, n int64) error {
+	acct, ok := ctx.Value(memoryAccountKey{}).(*memoryAccount)
+	if !ok || n <= 0 {
+		return nil
+	}
+	acct.mu.Lock()
+	defer acct.mu.Unlock()
+	if acct.usage.Limit > 0 && acct.usage.Used+n > acct.usage.Limit {
+		return &MemoryLimitError{Limit: acct.usage.Limit, Used: acct.usage.Used, Requested: n}
+	}
+	acct.usage.Used += n
+	acct.usage.Peak = max(acct.usage.Peak, acct.usage.Used)
+	return nil
+}
+
+// ReleaseMemory returns n bytes reserved with ReserveMemory to the run's account.
+func ReleaseMemory(ctx context.Context, n int64) {
+	acct, ok := ctx.Value(memoryAccountKey{}).(*memoryAccount)
+	if !ok || n <= 0 {
+		return
+	}
+	acct.mu.Lock()
+	defer acct.mu.Unlock()
+	acct.usage.Used = max(acct.usage.Used-n, 0)
+}
+
+// MemoryUsed reports the memory accounting of the run in ctx.
+//
+// The second result is false when the context has no memory account.
+func MemoryUsed(ctx context.Context) (MemoryUsage, bool) {
+	acct, ok := ctx.Value(memoryAccountKey{}).(*memoryAccount)
+	if !ok {
+		return MemoryUsage{}, false
+	}
+	acct.mu.Lock()
+	defer acct.mu.Unlock()
+	return acct.usage, true
+}
+
+// MemoryBuffer is an in-memory buffer whose writes are charged to the run's memory account.
+//
+// Writes fail with *MemoryLimitError once the run's cap would be crossed, so a
+// pathological request fails instead of exhausting the process. Call Release
+// when the contents are no longer needed.
+//
+// Example:
+//
+//	buf := calque.NewMemoryBuffer(req.Context)
+//	defer buf.Release()
+//	if _, err := io.Copy(buf, req.Data); err != nil {
+//		return err
+//	}
+//	input := buf.Bytes()
+type MemoryBuffer struct {
+	ctx      context.Context
+	buf      bytes.Buffer
+	reserved int64
+}
+
+// NewMemoryBuffer creates a MemoryBuffer charged to the memory account in ctx.
+func NewMemoryBuffer(ctx context.Context) *MemoryBuffer {
+	return &MemoryBuffer{ctx: ctx}
+}
+
+// Write appends p after reserving its size.
+func (b *MemoryBuffer) Write(p []byte) (int, error) {
+	if err := ReserveMemory(b.ctx, int64(len(p))); err != nil {
+		return 0, err
+	}
+	b.reserved += int64(len(p))
+	return b.buf.Write(p)
+}
+
+// Bytes returns the buffered contents.
+func (b *MemoryBuffer) Bytes() []byte { return b.buf.Bytes() }
+
+// Len returns the number of buffered bytes.
+func (b *MemoryBuffer) Len() int { return b.buf.Len() }
+
+// Release drops the contents and returns their reservation to the account.
+func (b *MemoryBuffer) Release() {
+	ReleaseMemory(b.ctx, b.reserved)
+	b.reserved = 0
+	b.buf = bytes.Buffer{}
+}
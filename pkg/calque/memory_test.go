@@ -0,0 +1,85 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReserveMemory(t *testing.T) {
+	ctx := WithMemoryLimit(context.Background(), 100)
+
+	if err := ReserveMemory(ctx, 60); err != nil {
+		t.Fatalf("first reservation: %v", err)
+	}
+	err := ReserveMemory(ctx, 50)
+	var memErr *MemoryLimitError
+	if !errors.As(err, &memErr) || !errors.Is(err, ErrMemoryLimit) {
+		t.Fatalf("over-limit reservation error = %v", err)
+	}
+	if memErr.Used != 60 || memErr.Requested != 50 || memErr.Limit != 100 {
+		t.Errorf("error = %+v", memErr)
+	}
+
+	ReleaseMemory(ctx, 60)
+	if err := ReserveMemory(ctx, 100); err != nil {
+		t.Errorf("reservation after release: %v", err)
+	}
+	usage, ok := MemoryUsed(ctx)
+	if !ok || usage.Used != 100 || usage.Peak != 100 {
+		t.Errorf("usage = %+v, ok = %v", usage, ok)
+	}
+
+	// no account: reservations always succeed
+	if err := ReserveMemory(context.Background(), 1<<40); err != nil {
+		t.Errorf("reservation without account: %v", err)
+	}
+	if _, ok := MemoryUsed(context.Background()); ok {
+		t.Error("MemoryUsed reported an account on a plain context")
+	}
+}
+
+func TestMemoryBuffer(t *testing.T) {
+	ctx := WithMemoryLimit(context.Background(), 10)
+	buf := NewMemoryBuffer(ctx)
+
+	if _, err := buf.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := buf.Write([]byte("world!")); !errors.Is(err, ErrMemoryLimit) {
+		t.Errorf("write past cap error = %v", err)
+	}
+	if string(buf.Bytes()) != "hello" || buf.Len() != 5 {
+		t.Errorf("buffer = %q", buf.Bytes())
+	}
+
+	buf.Release()
+	if usage, _ := MemoryUsed(ctx); usage.Used != 0 || usage.Peak != 5 {
+		t.Errorf("usage after release = %+v", usage)
+	}
+}
+
+func TestFlowMaxMemoryBytes(t *testing.T) {
+	flow := NewFlow(FlowConfig{MaxMemoryBytes: 16}).UseFunc(passthrough)
+
+	var out string
+	if err := flow.Run(context.Background(), "small", &out); err != nil || out != "small" {
+		t.Fatalf("small output: out = %q, err = %v", out, err)
+	}
+
+	// the final output buffer is charged to the run
+	err := flow.Run(context.Background(), strings.Repeat("x", 1024), &out)
+	if !errors.Is(err, ErrMemoryLimit) {
+		t.Errorf("large output error = %v, want ErrMemoryLimit", err)
+	}
+
+	// a caller-provided account takes precedence
+	ctx := WithMemoryLimit(context.Background(), 0)
+	if err := flow.Run(ctx, strings.Repeat("x", 1024), &out); err != nil {
+		t.Errorf("run with caller account: %v", err)
+	}
+	if usage, _ := MemoryUsed(ctx); usage.Peak != 1024 || usage.Used != 0 {
+		t.Errorf("caller account usage = %+v", usage)
+	}
+}
@@ -0,0 +1,211 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Priority orders runs waiting for MaxConcurrent handler slots.
+type Priority int
+
+// Run priorities. The zero value is PriorityNormal.
+const (
+	PriorityLow    Priority = -1 // batch and background work, e.g. ingestion
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1 // interactive traffic, e.g. chat
+)
+
+// DefaultPriorityAging is how long a run waits before it is promoted one
+// priority level, when FlowConfig.PriorityAging is not set.
+const DefaultPriorityAging = 5 * time.Second
+
+const priorityKey ctxKey = "calque.priority"
+
+// String returns "low", "normal" or "high".
+func (p Priority) String() string {
+	switch {
+	case p <= PriorityLow:
+		return "low"
+	case p >= PriorityHigh:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// WithPriority tags a run with a priority for the flow's admission control.
+//
+// When a flow with MaxConcurrent has no free handler slot, waiting handlers
+// of higher priority runs are admitted first, so interactive traffic is not
+// queued behind batch jobs sharing the flow. Waiting runs are promoted one
+// level every FlowConfig.PriorityAging, so low priority work still progresses.
+//
+// Example:
+//
+//	// Chat requests
+//	err := flow.Run(calque.WithPriority(ctx, calque.PriorityHigh), question, &answer)
+//
+//	// Nightly ingestion sharing the same flow
+//	err := flow.Run(calque.WithPriority(ctx, calque.PriorityLow), document, &summary)
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey, p)
+}
+
+// PriorityFrom returns the run priority in the context, PriorityNormal if unset.
+func PriorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey).(Priority); ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+// admission is a priority-aware counting semaphore for handler goroutines.
+//
+// A run takes the slots of all its handlers at once: they are joined by
+// pipes, so a run holding only some of them would wait forever on the rest.
+// A run larger than the limit is admitted alone when no slot is in use.
+//
+// Free slots go to the waiter with the highest effective priority: its own
+// priority plus one level per aging interval waited, capped at PriorityHigh.
+// Ties go to the longest waiter, so equal priorities are FIFO. With an
+// adaptive controller the limit follows it instead of staying fixed.
+type admission struct {
+	aging    time.Duration
+	adaptive *aimd // nil for a fixed limit
+
+	mu      sync.Mutex
+	limit   int
+	inUse   int
+	waiters []*admissionWaiter
+}
+
+type admissionWaiter struct {
+	slots    int
+	priority Priority
+	since    time.Time
+	ready    chan struct{}
+}
+
+func newAdmission(limit int, aging time.Duration) *admission {
+	if aging <= 0 {
+		aging = DefaultPriorityAging
+	}
+	return &admission{limit: limit, aging: aging}
+}
+
+// acquire takes n slots together, waiting until they are granted or ctx is done
+func (a *admission) acquire(ctx context.Context, p Priority, n int) error {
+	a.mu.Lock()
+	if a.adaptive != nil {
+		a.adaptive.reserve(n)
+	}
+	if len(a.waiters) == 0 && a.fits(n) {
+		a.inUse += n
+		a.mu.Unlock()
+		return nil
+	}
+	w := &admissionWaiter{slots: n, priority: p, since: time.Now(), ready: make(chan struct{})}
+	a.waiters = append(a.waiters, w)
+	a.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		select {
+		case <-w.ready:
+			// Granted while cancelling: hand the slots on
+			a.inUse -= w.slots
+			a.grant()
+		default:
+			a.remove(w)
+		}
+		return ctx.Err()
+	}
+}
+
+// release frees one handler's slot and admits the next waiters. key, start
+// and err describe the finished handler for the adaptive controller, and
+// waited is how long its run queued for admission.
+func (a *admission) release(key string, start time.Time, waited time.Duration, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inUse--
+	if a.adaptive != nil {
+		latency := time.Since(start)
+		if errors.Is(err, context.DeadlineExceeded) && waited > latency {
+			// The deadline went mostly on queueing, which says nothing
+			// about downstream capacity; cutting the limit would only
+			// lengthen the queue
+			err = errQueuedDeadline
+		}
+		a.adaptive.observe(key, start, latency, err, a.inUse)
+	}
+	a.grant()
+}
+
+// errQueuedDeadline replaces deadline errors of runs that spent most of
+// their deadline waiting for admission
+var errQueuedDeadline = errors.New("deadline spent waiting for admission")
+
+// fits reports whether n more slots can be admitted; called with mu held
+func (a *admission) fits(n int) bool {
+	return a.inUse == 0 || a.inUse+n <= a.capacity()
+}
+
+// capacity is the current limit; called with mu held
+func (a *admission) capacity() int {
+	if a.adaptive != nil {
+		return a.adaptive.current()
+	}
+	return a.limit
+}
+
+// grant admits waiters while their slots are free; called with mu held.
+// The best waiter blocks the others until it fits, so small runs cannot
+// starve a large one.
+func (a *admission) grant() {
+	now := time.Now()
+	for len(a.waiters) > 0 {
+		best := 0
+		for i, w := range a.waiters[1:] {
+			if a.effective(w, now) > a.effective(a.waiters[best], now) {
+				best = i + 1
+			}
+		}
+		w := a.waiters[best]
+		if !a.fits(w.slots) {
+			return
+		}
+		a.waiters = append(a.waiters[:best], a.waiters[best+1:]...)
+		a.inUse += w.slots
+		close(w.ready)
+	}
+}
+
+// effective is the waiter priority after aging
+func (a *admission) effective(w *admissionWaiter, now time.Time) Priority {
+	p := w.priority + Priority(now.Sub(w.since)/a.aging)
+	return min(p, PriorityHigh)
+}
+
+// remove drops a waiter that gave up; called with mu held
+func (a *admission) remove(w *admissionWaiter) {
+	for i, other := range a.waiters {
+		if other == w {
+			a.waiters = append(a.waiters[:i], a.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// usage returns the slots in use and the limit
+func (a *admission) usage() (inUse, limit int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.inUse, a.capacity()
+}
@@ -0,0 +1,173 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForWaiters blocks until n acquirers are queued
+func waitForWaiters(t *testing.T, a *admission, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		a.mu.Lock()
+		queued := len(a.waiters)
+		a.mu.Unlock()
+		if queued >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d waiters queued, want %d", queued, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// queue starts an acquirer that records its name once admitted
+func queue(a *admission, p Priority, name string, order chan<- string) {
+	go func() {
+		if err := a.acquire(context.Background(), p, 1); err == nil {
+			order <- name
+		}
+	}()
+}
+
+func TestAdmissionPriorityOrder(t *testing.T) {
+	a := newAdmission(1, time.Hour)
+	if err := a.acquire(context.Background(), PriorityNormal, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan string, 4)
+	queue(a, PriorityLow, "low", order)
+	waitForWaiters(t, a, 1)
+	queue(a, PriorityNormal, "normal-1", order)
+	waitForWaiters(t, a, 2)
+	queue(a, PriorityHigh, "high", order)
+	waitForWaiters(t, a, 3)
+	queue(a, PriorityNormal, "normal-2", order)
+	waitForWaiters(t, a, 4)
+
+	var got []string
+	for range 4 {
+		a.release("", time.Time{}, 0, nil)
+		got = append(got, <-order)
+	}
+	want := []string{"high", "normal-1", "normal-2", "low"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("admission order = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestAdmissionAging(t *testing.T) {
+	a := newAdmission(1, 10*time.Millisecond)
+	if err := a.acquire(context.Background(), PriorityNormal, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan string, 2)
+	queue(a, PriorityLow, "low", order)
+	waitForWaiters(t, a, 1)
+	time.Sleep(30 * time.Millisecond) // aged two levels, to high
+	queue(a, PriorityHigh, "high", order)
+	waitForWaiters(t, a, 2)
+
+	a.release("", time.Time{}, 0, nil)
+	if got := <-order; got != "low" {
+		t.Errorf("first admitted = %q, want the aged low priority run", got)
+	}
+	a.release("", time.Time{}, 0, nil)
+	<-order
+}
+
+func TestAdmissionCancel(t *testing.T) {
+	a := newAdmission(1, time.Hour)
+	if err := a.acquire(context.Background(), PriorityNormal, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- a.acquire(ctx, PriorityHigh, 1) }()
+	waitForWaiters(t, a, 1)
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("acquire() error = %v, want context.Canceled", err)
+	}
+
+	a.release("", time.Time{}, 0, nil)
+	if inUse, limit := a.usage(); inUse != 0 || limit != 1 || len(a.waiters) != 0 {
+		t.Errorf("usage = %d/%d with %d waiters, want the cancelled waiter gone", inUse, limit, len(a.waiters))
+	}
+}
+
+func TestFlowPriority(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var ran []string
+	handler := HandlerFunc(func(req *Request, res *Response) error {
+		var in string
+		if err := Read(req, &in); err != nil {
+			return err
+		}
+		if in == "blocker" {
+			<-release
+		}
+		mu.Lock()
+		ran = append(ran, in)
+		mu.Unlock()
+		return Write(res, in)
+	})
+	flow := NewFlow(FlowConfig{MaxConcurrent: 1}).Use(handler)
+
+	run := func(p Priority, input string, wg *sync.WaitGroup) {
+		defer wg.Done()
+		var out string
+		if err := flow.Run(WithPriority(context.Background(), p), input, &out); err != nil {
+			t.Error(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go run(PriorityNormal, "blocker", &wg)
+	waitForUsage(t, flow, 1)
+	go run(PriorityLow, "ingest", &wg)
+	waitForWaiters(t, flow.sem, 1)
+	go run(PriorityHigh, "chat", &wg)
+	waitForWaiters(t, flow.sem, 2)
+	close(release)
+	wg.Wait()
+
+	if len(ran) != 3 || ran[1] != "chat" || ran[2] != "ingest" {
+		t.Errorf("run order = %v, want chat before ingest", ran)
+	}
+}
+
+func waitForUsage(t *testing.T, flow *Flow, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if inUse, _ := flow.Concurrency(); inUse >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("flow never had %d handlers running", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPriorityFrom(t *testing.T) {
+	if p := PriorityFrom(context.Background()); p != PriorityNormal {
+		t.Errorf("PriorityFrom() = %v, want normal", p)
+	}
+	if p := PriorityFrom(WithPriority(context.Background(), PriorityLow)); p != PriorityLow || p.String() != "low" {
+		t.Errorf("PriorityFrom() = %v, want low", p)
+	}
+}
@@ -0,0 +1,49 @@
+package calque
+
+import (
+	"context"
+	"runtime/pprof"
+	"strconv"
+)
+
+// pprof label keys applied to handler goroutines when FlowConfig.ProfilerLabels is enabled.
+//
+// Filter profiles by stage with, for example:
+//
+//	go tool pprof -tagfocus=calque_handler=ai.Agent cpu.pprof
+const (
+	ProfileLabelFlow         = "calque_flow"
+	ProfileLabelHandler      = "calque_handler"
+	ProfileLabelHandlerIndex = "calque_handler_index"
+)
+
+// handlerLabels builds the pprof label set for a handler at a given position in a flow
+func handlerLabels(flowName string, h Handler, idx int) pprof.LabelSet {
+	if flowName == "" {
+		flowName = "unnamed"
+	}
+	return pprof.Labels(
+		ProfileLabelFlow, flowName,
+		ProfileLabelHandler, HandlerName(h),
+		ProfileLabelHandlerIndex, strconv.Itoa(idx),
+	)
+}
+
+// ProfileLabels returns the calque pprof labels attached to the context, if any.
+//
+// Handlers running in a flow with FlowConfig.ProfilerLabels enabled receive a
+// context carrying these labels. Useful for tagging logs or custom profiles.
+//
+// Example:
+//
+//	labels := calque.ProfileLabels(req.Context)
+//	log.Printf("running stage %s of %s", labels[calque.ProfileLabelHandler], labels[calque.ProfileLabelFlow])
+func ProfileLabels(ctx context.Context) map[string]string {
+	labels := make(map[string]string, 3)
+	for _, key := range []string{ProfileLabelFlow, ProfileLabelHandler, ProfileLabelHandlerIndex} {
+		if v, ok := pprof.Label(ctx, key); ok {
+			labels[key] = v
+		}
+	}
+	return labels
+}
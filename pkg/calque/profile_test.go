@@ -0,0 +1,91 @@
+package calque
+
+import (
+	"context"
+	"testing"
+)
+
+// labelRecorder captures the pprof labels seen by a handler
+type labelRecorder struct {
+	labels map[string]string
+}
+
+func (l *labelRecorder) Name() string { return "recorder" }
+
+func (l *labelRecorder) ServeFlow(req *Request, res *Response) error {
+	l.labels = ProfileLabels(req.Context)
+	return tracedUpper(req, res)
+}
+
+func TestFlow_ProfilerLabels(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   FlowConfig
+		expected map[string]string
+	}{
+		{
+			name:   "enabled",
+			config: FlowConfig{Name: "ingest", ProfilerLabels: true},
+			expected: map[string]string{
+				ProfileLabelFlow:         "ingest",
+				ProfileLabelHandler:      "recorder",
+				ProfileLabelHandlerIndex: "1",
+			},
+		},
+		{
+			name:   "unnamed flow",
+			config: FlowConfig{ProfilerLabels: true},
+			expected: map[string]string{
+				ProfileLabelFlow:         "unnamed",
+				ProfileLabelHandler:      "recorder",
+				ProfileLabelHandlerIndex: "1",
+			},
+		},
+		{
+			name:     "disabled",
+			config:   FlowConfig{Name: "ingest"},
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &labelRecorder{}
+			flow := NewFlow(tt.config).
+				Use(namedHandler{}).
+				Use(recorder)
+
+			var result string
+			if err := flow.Run(context.Background(), "hi", &result); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != "HI!" {
+				t.Errorf("expected 'HI!', got %q", result)
+			}
+
+			labels := recorder.labels
+			if len(labels) != len(tt.expected) {
+				t.Fatalf("expected labels %v, got %v", tt.expected, labels)
+			}
+			for k, v := range tt.expected {
+				if labels[k] != v {
+					t.Errorf("label %s: expected %q, got %q", k, v, labels[k])
+				}
+			}
+		})
+	}
+}
+
+func TestFlow_ProfilerLabels_Traced(t *testing.T) {
+	recorder := &labelRecorder{}
+	flow := NewFlow(FlowConfig{Name: "traced", ProfilerLabels: true}).Use(recorder)
+
+	var result string
+	if _, err := flow.RunTraced(context.Background(), "x", &result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	labels := recorder.labels
+	if labels[ProfileLabelFlow] != "traced" || labels[ProfileLabelHandler] != "recorder" || labels[ProfileLabelHandlerIndex] != "0" {
+		t.Errorf("unexpected labels under RunTraced: %v", labels)
+	}
+}
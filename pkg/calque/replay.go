@@ -0,0 +1,152 @@
+package calque
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// StageDiff compares one handler of a recorded trace with its replay.
+type StageDiff struct {
+	Index    int           // handler position
+	Recorded *HandlerTrace // nil if the handler was added since the recording
+	Replayed *HandlerTrace // nil if the handler was removed since the recording
+	Diverged bool          // stage behaved differently
+	Reason   string        // what differed, empty if nothing did
+}
+
+// ReplayReport is the result of ReplayTrace.
+type ReplayReport struct {
+	Recorded        *FlowTrace  // trace passed to ReplayTrace
+	Replayed        *FlowTrace  // trace of the replay run
+	Stages          []StageDiff // one per handler position in either trace
+	FirstDivergence int         // index of the first diverging stage, -1 if none
+}
+
+// Diverged reports whether any stage behaved differently in the replay.
+func (r *ReplayReport) Diverged() bool {
+	return r.FirstDivergence >= 0
+}
+
+// String renders a per-stage summary, marking diverging stages with "!".
+//
+// Example output:
+//
+//	  0 prompt.Template  ok
+//	! 1 ai.Agent         output differs: "The capital is Paris" -> "Paris."
+//	! 2 text.Transform   output differs: ...
+func (r *ReplayReport) String() string {
+	var b strings.Builder
+	width := 0
+	for _, s := range r.Stages {
+		width = max(width, len(stageName(s)))
+	}
+	for _, s := range r.Stages {
+		mark := " "
+		detail := "ok"
+		if s.Diverged {
+			mark = "!"
+			detail = s.Reason
+		}
+		fmt.Fprintf(&b, "%s %d %-*s  %s\n", mark, s.Index, width, stageName(s), detail)
+	}
+	return b.String()
+}
+
+func stageName(s StageDiff) string {
+	if s.Replayed != nil {
+		return s.Replayed.Name
+	}
+	return s.Recorded.Name
+}
+
+// ReplayTrace re-runs a recorded trace's input through flow and compares each stage.
+//
+// Input: context.Context, trace from RunTraced (possibly loaded from JSON), flow to replay
+// Output: *ReplayReport with per-stage differences, error if the trace can't be replayed
+// Behavior: Runs flow with RunTraced using the first handler's recorded input
+//
+// The recorded input must be complete: record with a FlowConfig.TracePreviewSize
+// at least as large as the input. Stages are compared on handler name, status,
+// bytes written and output preview; outputs longer than the preview size are
+// compared by preview and length only. A replay that fails is reported through
+// the stage statuses rather than as an error. The trace ID of the recording is
+// reused when ctx has none.
+//
+// Example:
+//
+//	data, _ := os.ReadFile("trace.json")
+//	var trace calque.FlowTrace
+//	json.Unmarshal(data, &trace)
+//
+//	report, err := calque.ReplayTrace(ctx, &trace, flow)
+//	if err != nil {
+//		return err
+//	}
+//	if report.Diverged() {
+//		fmt.Print(report)
+//	}
+func ReplayTrace(ctx context.Context, trace *FlowTrace, flow *Flow) (*ReplayReport, error) {
+	if len(trace.Handlers) == 0 {
+		return nil, NewErr(ctx, "trace has no handlers to replay")
+	}
+	first := trace.Handlers[0]
+	if first.Status == TraceStatusPending {
+		return nil, NewErr(ctx, "trace has no recorded input: first handler never ran")
+	}
+	if int64(len(first.InputPreview)) != first.BytesIn {
+		return nil, NewErr(ctx, fmt.Sprintf("trace input is truncated (%d bytes recorded as a %d byte preview); record with a larger TracePreviewSize",
+			first.BytesIn, len(first.InputPreview)))
+	}
+
+	if trace.TraceID != "" && TraceID(ctx) == "" {
+		ctx = WithTraceID(ctx, trace.TraceID)
+	}
+	var out []byte
+	replayed, _ := flow.RunTraced(ctx, first.InputPreview, &out)
+	if replayed == nil {
+		return nil, NewErr(ctx, "flow could not start the replay")
+	}
+
+	report := &ReplayReport{Recorded: trace, Replayed: replayed, FirstDivergence: -1}
+	for i := range max(len(trace.Handlers), len(replayed.Handlers)) {
+		diff := StageDiff{Index: i}
+		if i < len(trace.Handlers) {
+			diff.Recorded = &trace.Handlers[i]
+		}
+		if i < len(replayed.Handlers) {
+			diff.Replayed = &replayed.Handlers[i]
+		}
+		diff.Reason = compareStage(diff.Recorded, diff.Replayed)
+		diff.Diverged = diff.Reason != ""
+		if diff.Diverged && report.FirstDivergence < 0 {
+			report.FirstDivergence = i
+		}
+		report.Stages = append(report.Stages, diff)
+	}
+	return report, nil
+}
+
+// compareStage describes how a replayed stage differs from the recording, or returns ""
+func compareStage(recorded, replayed *HandlerTrace) string {
+	switch {
+	case recorded == nil:
+		return "handler added"
+	case replayed == nil:
+		return "handler removed"
+	case recorded.Name != replayed.Name:
+		return fmt.Sprintf("handler changed: %s -> %s", recorded.Name, replayed.Name)
+	case recorded.Status != replayed.Status:
+		reason := fmt.Sprintf("status changed: %s -> %s", recorded.Status, replayed.Status)
+		if replayed.Error != "" {
+			reason += " (" + replayed.Error + ")"
+		}
+		return reason
+	case recorded.OutputPreview != replayed.OutputPreview:
+		return fmt.Sprintf("output differs: %q -> %q", recorded.OutputPreview, replayed.OutputPreview)
+	case recorded.BytesOut != replayed.BytesOut:
+		return fmt.Sprintf("output size differs: %d -> %d bytes", recorded.BytesOut, replayed.BytesOut)
+	default:
+		return ""
+	}
+}
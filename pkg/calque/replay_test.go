@@ -0,0 +1,84 @@
+package calque
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func transform(name string, fn func(string) string) Handler {
+	return &tracedHandler{name: name, fn: func(req *Request, res *Response) error {
+		var s string
+		if err := Read(req, &s); err != nil {
+			return err
+		}
+		return Write(res, fn(s))
+	}}
+}
+
+func TestReplayTrace(t *testing.T) {
+	recordedFlow := NewFlow().
+		Use(transform("upper", strings.ToUpper)).
+		Use(transform("exclaim", func(s string) string { return s + "!" }))
+
+	var out string
+	recorded, err := recordedFlow.RunTraced(WithTraceID(context.Background(), "t-1"), "hello", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// round-trip through JSON as an exported trace would
+	data, _ := recorded.JSON()
+	var loaded FlowTrace
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("unchanged flow", func(t *testing.T) {
+		report, err := ReplayTrace(context.Background(), &loaded, recordedFlow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Diverged() || report.Replayed.TraceID != "t-1" {
+			t.Errorf("report:\n%s", report)
+		}
+	})
+
+	t.Run("changed stage", func(t *testing.T) {
+		changed := NewFlow().
+			Use(transform("upper", strings.ToUpper)).
+			Use(transform("exclaim", func(s string) string { return s + "?" })).
+			Use(transform("trim", strings.TrimSpace))
+		report, err := ReplayTrace(context.Background(), &loaded, changed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.FirstDivergence != 1 || len(report.Stages) != 3 {
+			t.Fatalf("report:\n%s", report)
+		}
+		if got := report.Stages[1].Reason; got != `output differs: "HELLO!" -> "HELLO?"` {
+			t.Errorf("reason = %q", got)
+		}
+		if got := report.Stages[2].Reason; got != "handler added" {
+			t.Errorf("reason = %q", got)
+		}
+		if s := report.String(); !strings.Contains(s, "! 1 exclaim") || !strings.Contains(s, "  0 upper    ok") {
+			t.Errorf("String() =\n%s", s)
+		}
+	})
+}
+
+func TestReplayTraceTruncatedInput(t *testing.T) {
+	flow := NewFlow(FlowConfig{TracePreviewSize: 4}).Use(HandlerFunc(passthrough))
+	var out string
+	trace, err := flow.RunTraced(context.Background(), "longer than four", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReplayTrace(context.Background(), trace, flow); err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("error = %v, want truncated input error", err)
+	}
+	if _, err := ReplayTrace(context.Background(), &FlowTrace{}, flow); err == nil {
+		t.Error("expected error for empty trace")
+	}
+}
@@ -0,0 +1,132 @@
+package calque
+
+import (
+	"context"
+	"sync"
+)
+
+const resultKey ctxKey = "calque.result"
+
+// Result collects typed artifacts that handlers attach during a run.
+//
+// Artifacts travel beside the byte stream: ai.Agent attaches *TokenUsage,
+// tools.Execute attaches *ToolCalled and retrieval middleware attaches the
+// []retrieval.Source it returned. Handlers add their own with Attach. A
+// Result is safe for concurrent use.
+//
+// Example:
+//
+//	result, err := flow.RunWithResult(ctx, input, &answer)
+//	usage := result.TokenUsage()
+//	for _, call := range calque.Artifacts[*calque.ToolCalled](result) {
+//	    log.Printf("tool %s took %v", call.Tool, call.Duration)
+//	}
+type Result struct {
+	parent *Result
+
+	mu        sync.Mutex
+	artifacts []any
+}
+
+// NewResult creates an empty Result.
+func NewResult() *Result {
+	return &Result{}
+}
+
+// WithResult returns a context whose handlers attach artifacts to r.
+//
+// When ctx already carries a Result (an outer run), artifacts attached to r
+// are also recorded on the outer Result.
+func WithResult(ctx context.Context, r *Result) context.Context {
+	if r.parent == nil {
+		if parent := ResultFrom(ctx); parent != r {
+			r.parent = parent
+		}
+	}
+	return context.WithValue(ctx, resultKey, r)
+}
+
+// ResultFrom returns the Result in the context, or nil if there is none.
+func ResultFrom(ctx context.Context) *Result {
+	if ctx == nil {
+		return nil
+	}
+	r, _ := ctx.Value(resultKey).(*Result)
+	return r
+}
+
+// Attach records an artifact on the run's Result.
+//
+// Does nothing when the context has no Result, so handlers can attach
+// unconditionally. Attach values handlers define as their own types so callers
+// can select them with Artifacts.
+//
+// Example:
+//
+//	calque.Attach(req.Context, &ModerationVerdict{Flagged: flagged})
+func Attach(ctx context.Context, artifact any) {
+	for r := ResultFrom(ctx); r != nil; r = r.parent {
+		r.mu.Lock()
+		r.artifacts = append(r.artifacts, artifact)
+		r.mu.Unlock()
+	}
+}
+
+// All returns every attached artifact in attach order.
+func (r *Result) All() []any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]any(nil), r.artifacts...)
+}
+
+// Artifacts returns the artifacts of type T in attach order.
+//
+// Example:
+//
+//	sources := calque.Artifacts[[]retrieval.Source](result)
+func Artifacts[T any](r *Result) []T {
+	if r == nil {
+		return nil
+	}
+	var out []T
+	for _, a := range r.All() {
+		if v, ok := a.(T); ok {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// TokenUsage sums every *TokenUsage artifact; Time is that of the latest one.
+func (r *Result) TokenUsage() TokenUsage {
+	var total TokenUsage
+	for _, u := range Artifacts[*TokenUsage](r) {
+		total.PromptTokens += u.PromptTokens
+		total.CompletionTokens += u.CompletionTokens
+		total.TotalTokens += u.TotalTokens
+		total.CostUSD += u.CostUSD
+		if u.Time.After(total.Time) {
+			total.Time = u.Time
+		}
+	}
+	return total
+}
+
+// RunWithResult executes the flow like Run and returns the artifacts its handlers attached.
+//
+// Input: context.Context for cancellation, input data (any type), output pointer (any type)
+// Output: *Result with attached artifacts (also on error), error if flow execution fails
+// Behavior: identical to Run with a fresh Result in the context
+//
+// Example:
+//
+//	result, err := flow.RunWithResult(ctx, question, &answer)
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Println("tokens:", result.TokenUsage().TotalTokens)
+func (f *Flow) RunWithResult(ctx context.Context, input any, output any) (*Result, error) {
+	r := NewResult()
+	err := f.Run(WithResult(ctx, r), input, output)
+	return r, err
+}
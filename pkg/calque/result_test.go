@@ -0,0 +1,85 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type hitCount int
+
+func TestRunWithResult(t *testing.T) {
+	flow := NewFlow().
+		Use(HandlerFunc(func(req *Request, res *Response) error {
+			Attach(req.Context, &TokenUsage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5})
+			Attach(req.Context, hitCount(4))
+			return passthrough(req, res)
+		})).
+		Use(HandlerFunc(func(req *Request, res *Response) error {
+			Attach(req.Context, &TokenUsage{PromptTokens: 1, TotalTokens: 1})
+			return passthrough(req, res)
+		}))
+
+	var out string
+	result, err := flow.RunWithResult(context.Background(), "in", &out)
+	if err != nil || out != "in" {
+		t.Fatalf("run = %q, %v", out, err)
+	}
+	usage := result.TokenUsage()
+	if usage.PromptTokens != 4 || usage.CompletionTokens != 2 || usage.TotalTokens != 6 {
+		t.Errorf("usage = %+v", usage)
+	}
+	if hits := Artifacts[hitCount](result); len(hits) != 1 || hits[0] != 4 {
+		t.Errorf("hits = %v", hits)
+	}
+	if n := len(result.All()); n != 3 {
+		t.Errorf("artifacts = %d, want 3", n)
+	}
+}
+
+func TestRunWithResultError(t *testing.T) {
+	boom := errors.New("boom")
+	flow := NewFlow().Use(HandlerFunc(func(req *Request, _ *Response) error {
+		Attach(req.Context, hitCount(1))
+		return boom
+	}))
+	var out string
+	result, err := flow.RunWithResult(context.Background(), "in", &out)
+	if !errors.Is(err, boom) {
+		t.Fatalf("error = %v", err)
+	}
+	if len(Artifacts[hitCount](result)) != 1 {
+		t.Error("artifacts attached before the error were lost")
+	}
+}
+
+func TestNestedResults(t *testing.T) {
+	outer := NewResult()
+	ctx := WithResult(context.Background(), outer)
+	inner := NewResult()
+	innerCtx := WithResult(ctx, inner)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Attach(innerCtx, hitCount(1))
+		}()
+	}
+	wg.Wait()
+	Attach(ctx, hitCount(2))
+
+	if n := len(inner.All()); n != 10 {
+		t.Errorf("inner artifacts = %d, want 10", n)
+	}
+	if n := len(outer.All()); n != 11 {
+		t.Errorf("outer artifacts = %d, want 11", n)
+	}
+
+	Attach(context.Background(), hitCount(3)) // no Result: must not panic
+	if Artifacts[hitCount](nil) != nil {
+		t.Error("Artifacts(nil) should be nil")
+	}
+}
@@ -0,0 +1,152 @@
+package calque
+
+import (
+	"context"
+	"sync"
+)
+
+const tenantIDKey ctxKey = "calque.tenant_id"
+
+// WithTenant stores the tenant ID for the current request in the context.
+//
+// Tenant-aware middleware uses it to select per-tenant configuration (API
+// keys, models, rate limits) and to namespace stored state such as
+// conversation memory. The tenant ID is also attached to log records.
+//
+// Example:
+//
+//	ctx = calque.WithTenant(ctx, "acme")
+//	err := flow.Run(ctx, input, &output)
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantIDKey, tenantID)
+}
+
+// TenantID retrieves the tenant ID from context.
+//
+// Returns empty string if no tenant is set.
+//
+// Example:
+//
+//	if tenant := calque.TenantID(ctx); tenant != "" {
+//	    fmt.Println("Tenant:", tenant)
+//	}
+func TenantID(ctx context.Context) string {
+	if id, ok := ctx.Value(tenantIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// TenantKey namespaces a storage key by the tenant in the context.
+//
+// Returns "tenant/<id>/<key>" when a tenant is set, and key unchanged otherwise,
+// so single-tenant deployments keep their existing keys.
+//
+// Example:
+//
+//	ctx = calque.WithTenant(ctx, "acme")
+//	calque.TenantKey(ctx, "user123") // "tenant/acme/user123"
+func TenantKey(ctx context.Context, key string) string {
+	if tenant := TenantID(ctx); tenant != "" {
+		return "tenant/" + tenant + "/" + key
+	}
+	return key
+}
+
+// TenantConfig holds a default value with per-tenant overrides.
+//
+// Example:
+//
+//	models := calque.TenantConfig[string]{
+//		Default:   "gpt-4o-mini",
+//		Overrides: map[string]string{"acme": "gpt-4o"},
+//	}
+//	model := models.Resolve(ctx)
+type TenantConfig[T any] struct {
+	Default   T
+	Overrides map[string]T
+}
+
+// Resolve returns the override for the tenant in the context, or Default.
+func (c TenantConfig[T]) Resolve(ctx context.Context) T {
+	if v, ok := c.Overrides[TenantID(ctx)]; ok {
+		return v
+	}
+	return c.Default
+}
+
+// TenantHandler builds and caches a separate handler instance per tenant.
+//
+// Create it with PerTenant.
+type TenantHandler struct {
+	factory func(tenant string) (Handler, error)
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+}
+
+// PerTenant creates a handler that delegates to a per-tenant instance built by factory.
+//
+// Input: any data type (passed to the tenant's handler)
+// Output: same as the tenant's handler
+// Behavior: STREAMING - delegates directly to the tenant's handler
+//
+// The factory is called once per tenant ID (empty string when no tenant is
+// set) and its handler is reused for later requests, so stateful middleware
+// such as rate limiters and AI clients keep separate state per tenant. Factory
+// errors are returned to the caller and retried on the next request.
+//
+// Example:
+//
+//	limits := calque.TenantConfig[int]{Default: 10, Overrides: map[string]int{"acme": 100}}
+//	flow.Use(calque.PerTenant(func(tenant string) (calque.Handler, error) {
+//		ctx := calque.WithTenant(context.Background(), tenant)
+//		return ctrl.RateLimit(limits.Resolve(ctx), time.Second), nil
+//	}))
+//
+//	flow.Use(calque.PerTenant(func(tenant string) (calque.Handler, error) {
+//		key := secrets.NewRef(vault, "tenants/"+tenant+"/openai#api_key")
+//		client, err := openai.New("gpt-4o", openai.WithConfig(&openai.Config{APIKeyRef: key}))
+//		if err != nil {
+//			return nil, err
+//		}
+//		return ai.Agent(client), nil
+//	}))
+func PerTenant(factory func(tenant string) (Handler, error)) *TenantHandler {
+	return &TenantHandler{factory: factory, handlers: make(map[string]Handler)}
+}
+
+// ServeFlow runs the handler for the request's tenant.
+func (t *TenantHandler) ServeFlow(req *Request, res *Response) error {
+	h, err := t.handler(req.Context)
+	if err != nil {
+		return err
+	}
+	return h.ServeFlow(req, res)
+}
+
+// Name implements Namer.
+func (t *TenantHandler) Name() string {
+	return "calque.PerTenant"
+}
+
+// handler returns the cached handler for the tenant, building it on first use
+func (t *TenantHandler) handler(ctx context.Context) (Handler, error) {
+	tenant := TenantID(ctx)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if h, ok := t.handlers[tenant]; ok {
+		return h, nil
+	}
+	h, err := t.factory(tenant)
+	if err != nil {
+		return nil, WrapErr(ctx, err, "failed to create handler for tenant "+tenant)
+	}
+	if h == nil {
+		return nil, NewErr(ctx, "no handler for tenant "+tenant)
+	}
+	t.handlers[tenant] = h
+	return h, nil
+}
@@ -0,0 +1,114 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTenantID(t *testing.T) {
+	ctx := context.Background()
+	if got := TenantID(ctx); got != "" {
+		t.Errorf("TenantID() = %q, want empty", got)
+	}
+
+	ctx = WithTenant(ctx, "acme")
+	if got := TenantID(ctx); got != "acme" {
+		t.Errorf("TenantID() = %q, want %q", got, "acme")
+	}
+}
+
+func TestTenantKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		tenant string
+		key    string
+		want   string
+	}{
+		{"no tenant", "", "user1", "user1"},
+		{"tenant", "acme", "user1", "tenant/acme/user1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.tenant != "" {
+				ctx = WithTenant(ctx, tt.tenant)
+			}
+			if got := TenantKey(ctx, tt.key); got != tt.want {
+				t.Errorf("TenantKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenantConfig_Resolve(t *testing.T) {
+	cfg := TenantConfig[string]{
+		Default:   "small",
+		Overrides: map[string]string{"acme": "large"},
+	}
+
+	tests := []struct {
+		tenant string
+		want   string
+	}{
+		{"", "small"},
+		{"acme", "large"},
+		{"globex", "small"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tenant, func(t *testing.T) {
+			ctx := WithTenant(context.Background(), tt.tenant)
+			if got := cfg.Resolve(ctx); got != tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerTenant(t *testing.T) {
+	var builds atomic.Int32
+	handler := PerTenant(func(tenant string) (Handler, error) {
+		builds.Add(1)
+		if tenant == "banned" {
+			return nil, errors.New("tenant disabled")
+		}
+		return HandlerFunc(func(req *Request, res *Response) error {
+			var input string
+			if err := Read(req, &input); err != nil {
+				return err
+			}
+			return Write(res, tenant+":"+input)
+		}), nil
+	})
+
+	run := func(tenant string) (string, error) {
+		var out string
+		err := NewFlow().Use(handler).Run(WithTenant(context.Background(), tenant), "hi", &out)
+		return out, err
+	}
+
+	for _, tenant := range []string{"acme", "globex", "acme"} {
+		out, err := run(tenant)
+		if err != nil {
+			t.Fatalf("Run(%s) error = %v", tenant, err)
+		}
+		if out != tenant+":hi" {
+			t.Errorf("Run(%s) = %q, want %q", tenant, out, tenant+":hi")
+		}
+	}
+	if got := builds.Load(); got != 2 {
+		t.Errorf("factory called %d times, want 2", got)
+	}
+
+	if _, err := run("banned"); err == nil || !strings.Contains(err.Error(), "tenant disabled") {
+		t.Errorf("Run(banned) error = %v, want factory error", err)
+	}
+
+	if got := HandlerName(handler); got != "calque.PerTenant" {
+		t.Errorf("HandlerName() = %q, want %q", got, "calque.PerTenant")
+	}
+}
@@ -0,0 +1,152 @@
+package calque
+
+import (
+	"io"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ChunkBoundary selects where data passed between handlers may be split (FlowConfig.ChunkBoundary).
+type ChunkBoundary int
+
+// Chunk boundaries.
+const (
+	ChunkBytes     ChunkBoundary = iota // chunks split anywhere (default, binary safe)
+	ChunkRunes                          // chunks never split a multi-byte UTF-8 character
+	ChunkGraphemes                      // chunks never split a grapheme cluster (e.g. "é" as e + accent, emoji sequences)
+)
+
+// textReadSize is how much a TextReader reads from its source at a time
+const textReadSize = 4096
+
+// TextReader re-chunks a stream so each Read ends on a character boundary.
+type TextReader struct {
+	r        io.Reader
+	boundary ChunkBoundary
+	pending  []byte // read from r but not returned yet
+	err      error  // error from r, returned once pending is drained
+	scratch  []byte
+}
+
+// NewTextReader wraps r so that reads never split a UTF-8 character
+// (ChunkRunes) or grapheme cluster (ChunkGraphemes). ChunkBytes returns r unchanged.
+//
+// Input: io.Reader carrying UTF-8 text, chunk boundary
+// Output: io.Reader returning whole characters or clusters per Read
+// Behavior: STREAMING - holds back an incomplete character; with
+// ChunkGraphemes the last cluster is held until the next one starts or the
+// source ends, since a following combining mark could still extend it
+//
+// A Read into a buffer too small for one unit is filled anyway, so callers
+// always make progress. Invalid UTF-8 bytes pass through as single units.
+// Grapheme clusters follow the common Unicode rules (combining marks,
+// variation selectors, emoji modifiers and ZWJ sequences, flag pairs, CRLF)
+// rather than the full segmentation algorithm.
+//
+// Example:
+//
+//	// Redact per chunk without seeing broken characters
+//	reader := calque.NewTextReader(req.Data, calque.ChunkGraphemes)
+func NewTextReader(r io.Reader, boundary ChunkBoundary) io.Reader {
+	if boundary == ChunkBytes {
+		return r
+	}
+	return &TextReader{r: r, boundary: boundary}
+}
+
+// Read implements io.Reader.
+func (t *TextReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for {
+		final := t.err != nil
+		if len(t.pending) > 0 {
+			n := cutPoint(t.pending, len(p), final, t.boundary)
+			if n == 0 && len(t.pending) >= len(p) {
+				n = len(p) // p cannot hold a single unit
+			}
+			if n > 0 {
+				copy(p, t.pending[:n])
+				t.pending = t.pending[n:]
+				return n, nil
+			}
+		}
+		if final {
+			return 0, t.err
+		}
+
+		if t.scratch == nil {
+			t.scratch = make([]byte, textReadSize)
+		}
+		m, err := t.r.Read(t.scratch)
+		t.pending = append(t.pending, t.scratch[:m]...)
+		if err != nil {
+			t.err = err
+		}
+	}
+}
+
+// cutPoint returns the largest boundary position in b that is at most limit.
+// The end of b only counts as a boundary when no more input follows (final)
+// or, for runes, when b ends with a complete character.
+func cutPoint(b []byte, limit int, final bool, boundary ChunkBoundary) int {
+	if final && len(b) <= limit {
+		return len(b)
+	}
+
+	cut := 0
+	var prev rune
+	regional := 0 // consecutive regional indicators ending at prev
+	i := 0
+	for i < len(b) && i < limit {
+		if !final && !utf8.FullRune(b[i:]) {
+			break // incomplete trailing character
+		}
+		r, size := utf8.DecodeRune(b[i:])
+		if i > 0 && boundary == ChunkGraphemes && graphemeBreak(prev, r, regional) {
+			cut = i
+		}
+		if isRegionalIndicator(r) {
+			regional++
+		} else {
+			regional = 0
+		}
+		prev = r
+		i += size
+		if boundary == ChunkRunes && i <= limit {
+			cut = i
+		}
+	}
+	return cut
+}
+
+// graphemeBreak reports whether a cluster boundary falls between prev and next.
+// regional counts the regional indicators ending at prev.
+func graphemeBreak(prev, next rune, regional int) bool {
+	switch {
+	case prev == '\r' && next == '\n':
+		return false
+	case prev == '\u200d': // zero width joiner glues emoji sequences
+		return false
+	case isGraphemeExtend(next):
+		return false
+	case isRegionalIndicator(prev) && isRegionalIndicator(next):
+		return regional%2 == 0 // flags are pairs
+	default:
+		return true
+	}
+}
+
+func isGraphemeExtend(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		r == '\u200d' ||
+		(r >= 0xFE00 && r <= 0xFE0F) || // variation selectors
+		(r >= 0xE0100 && r <= 0xE01EF) ||
+		(r >= 0x1F3FB && r <= 0x1F3FF) || // emoji skin tone modifiers
+		(r >= 0xE0020 && r <= 0xE007F) // emoji tag sequences
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
@@ -0,0 +1,123 @@
+package calque
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+)
+
+// readChunks reads r with a buffer of size n and returns every chunk
+func readChunks(t *testing.T, r io.Reader, n int) []string {
+	t.Helper()
+	var chunks []string
+	buf := make([]byte, n)
+	for {
+		m, err := r.Read(buf)
+		if m > 0 {
+			chunks = append(chunks, string(buf[:m]))
+		}
+		if err == io.EOF {
+			return chunks
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+	}
+}
+
+func TestTextReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		boundary ChunkBoundary
+		input    string
+		bufSize  int
+		want     []string // nil = only check wholeness
+	}{
+		{name: "runes one byte reads", boundary: ChunkRunes, input: "aé世🙂", bufSize: 64, want: []string{"a", "é", "世", "🙂"}},
+		{name: "runes small buffer", boundary: ChunkRunes, input: "世界", bufSize: 4, want: []string{"世", "界"}},
+		{name: "graphemes combining mark", boundary: ChunkGraphemes, input: "e\u0301x", bufSize: 64, want: []string{"e\u0301", "x"}},
+		{name: "graphemes zwj family", boundary: ChunkGraphemes, input: "👨\u200d👩\u200d👧!", bufSize: 64, want: []string{"👨\u200d👩\u200d👧", "!"}},
+		{name: "graphemes flags pair", boundary: ChunkGraphemes, input: "🇫🇷🇩🇪", bufSize: 64, want: []string{"🇫🇷", "🇩🇪"}},
+		{name: "graphemes skin tone", boundary: ChunkGraphemes, input: "👍🏽ok", bufSize: 64, want: []string{"👍🏽", "o", "k"}},
+		{name: "graphemes crlf", boundary: ChunkGraphemes, input: "a\r\nb", bufSize: 64, want: []string{"a", "\r\n", "b"}},
+		{name: "buffer smaller than a rune", boundary: ChunkRunes, input: "世", bufSize: 2, want: []string{"\xe4\xb8", "\x96"}},
+		{name: "invalid bytes pass through", boundary: ChunkRunes, input: "a\xffb", bufSize: 64, want: []string{"a", "\xff", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewTextReader(iotest.OneByteReader(strings.NewReader(tt.input)), tt.boundary)
+			got := readChunks(t, r, tt.bufSize)
+			if strings.Join(got, "") != tt.input {
+				t.Fatalf("chunks %q do not reassemble input", got)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("chunks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextReaderBytesUnchanged(t *testing.T) {
+	src := strings.NewReader("x")
+	if NewTextReader(src, ChunkBytes) != io.Reader(src) {
+		t.Error("ChunkBytes should return the reader unchanged")
+	}
+}
+
+func TestFlowChunkBoundary(t *testing.T) {
+	input := strings.Repeat("héllo wörld 世界 🙂 ", 500)
+
+	// a writer that splits every write mid-character
+	splitter := HandlerFunc(func(req *Request, res *Response) error {
+		data, err := io.ReadAll(req.Data)
+		if err != nil {
+			return err
+		}
+		for len(data) > 0 {
+			n := min(3, len(data))
+			if _, err := res.Data.Write(data[:n]); err != nil {
+				return err
+			}
+			data = data[n:]
+		}
+		return nil
+	})
+
+	var broken int
+	checker := HandlerFunc(func(req *Request, res *Response) error {
+		buf := make([]byte, 7)
+		for {
+			n, err := req.Data.Read(buf)
+			if n > 0 {
+				if !utf8.Valid(buf[:n]) {
+					broken++
+				}
+				if _, werr := res.Data.Write(buf[:n]); werr != nil {
+					return werr
+				}
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	})
+
+	var out string
+	flow := NewFlow(FlowConfig{ChunkBoundary: ChunkRunes}).Use(splitter).Use(checker)
+	if err := flow.Run(context.Background(), input, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != input {
+		t.Error("output differs from input")
+	}
+	if broken > 0 {
+		t.Errorf("%d chunks split a character", broken)
+	}
+}
@@ -0,0 +1,375 @@
+package calque
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTracePreviewSize is the default number of payload bytes captured per handler in a FlowTrace.
+const DefaultTracePreviewSize = 256
+
+// Trace status values reported for each handler in a FlowTrace.
+const (
+	TraceStatusOK      = "ok"
+	TraceStatusError   = "error"
+	TraceStatusRunning = "running"
+	TraceStatusPending = "pending"
+)
+
+// Namer is implemented by handlers that report a human-readable name.
+//
+// Names are used in traces, profiles and visualizations. Handlers that don't
+// implement Namer are named after their Go type or function.
+type Namer interface {
+	Name() string
+}
+
+// HandlerTrace records the execution of a single handler within a flow run.
+type HandlerTrace struct {
+	Index         int           `json:"index"`
+	Name          string        `json:"name"`
+	Status        string        `json:"status"`
+	Start         time.Time     `json:"start"`
+	End           time.Time     `json:"end,omitzero"`
+	Duration      time.Duration `json:"duration_ns"`
+	BytesIn       int64         `json:"bytes_in"`
+	BytesOut      int64         `json:"bytes_out"`
+	InputPreview  string        `json:"input_preview,omitempty"`
+	OutputPreview string        `json:"output_preview,omitempty"`
+	Error         string        `json:"error,omitempty"`
+}
+
+// FlowTrace is a structured record of a flow run, suitable for timeline visualization.
+//
+// Example JSON:
+//
+//	{
+//	  "trace_id": "abc-123",
+//	  "start": "2025-01-01T10:00:00Z",
+//	  "duration_ns": 1250000,
+//	  "handlers": [
+//	    {"index": 0, "name": "prompt.Template", "status": "ok", "bytes_in": 12, "bytes_out": 48, ...},
+//	    {"index": 1, "name": "ai.Agent", "status": "ok", "bytes_in": 48, "bytes_out": 320, ...}
+//	  ]
+//	}
+type FlowTrace struct {
+	Flow      string         `json:"flow,omitempty"`
+	TraceID   string         `json:"trace_id,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	Start     time.Time      `json:"start"`
+	End       time.Time      `json:"end,omitzero"`
+	Duration  time.Duration  `json:"duration_ns"`
+	Handlers  []HandlerTrace `json:"handlers"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// JSON returns the trace encoded as indented JSON.
+func (t *FlowTrace) JSON() ([]byte, error) {
+	return json.MarshalIndent(t, "", "  ")
+}
+
+// RunTraced executes the flow like Run and returns a structured trace of the execution.
+//
+// Input: context.Context for cancellation, input data (any type), output pointer (any type)
+// Output: *FlowTrace (always non-nil), error if flow execution fails
+// Behavior: CONCURRENT - identical to Run, with byte counting and payload previews per handler
+//
+// Each handler records its start/end time, bytes read and written, the first
+// TracePreviewSize bytes of input and output, and any returned error. The trace
+// is returned even when the run fails so it can be attached to bug reports.
+// Handlers still running when the flow returns (e.g. after an error) are reported
+// with status "running", and handlers that never started with status "pending".
+//
+// Example:
+//
+//	trace, err := flow.RunTraced(ctx, "input", &result)
+//	data, _ := trace.JSON()
+//	os.WriteFile("trace.json", data, 0o644)
+func (f *Flow) RunTraced(ctx context.Context, input any, output any) (*FlowTrace, error) {
+	done, err := f.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	collector := newTraceCollector(len(f.handlers), f.tracePreviewSize)
+
+	traced := f.withHandlers(make([]Handler, len(f.handlers)))
+	for i, h := range f.handlers {
+		traced.handlers[i] = collector.wrap(i, h)
+	}
+
+	start := time.Now()
+	if live := getLiveTrace(ctx); live != nil {
+		live.attach(ctx, f.name, start, collector)
+	}
+
+	err = traced.Run(ctx, input, output)
+	end := time.Now()
+
+	trace := collector.snapshot()
+	trace.Flow = f.name
+	trace.TraceID = TraceID(ctx)
+	trace.RequestID = RequestID(ctx)
+	trace.Start = start
+	trace.End = end
+	trace.Duration = end.Sub(start)
+	if err != nil {
+		trace.Error = err.Error()
+	}
+
+	return trace, err
+}
+
+// LiveTrace exposes the in-progress trace of a RunTraced call to other goroutines.
+//
+// Attach it to the run context with WithLiveTrace; while the run is executing,
+// Snapshot returns the current per-handler status. Useful for dashboards that
+// show live runs.
+//
+// Example:
+//
+//	live := calque.NewLiveTrace()
+//	go flow.RunTraced(calque.WithLiveTrace(ctx, live), input, &out)
+//	snap := live.Snapshot() // nil until the run starts
+type LiveTrace struct {
+	mu        sync.Mutex
+	collector *traceCollector
+	flow      string
+	traceID   string
+	requestID string
+	start     time.Time
+}
+
+type liveTraceKey struct{}
+
+// NewLiveTrace creates an empty LiveTrace.
+func NewLiveTrace() *LiveTrace {
+	return &LiveTrace{}
+}
+
+// WithLiveTrace stores a LiveTrace in the context for RunTraced to populate.
+func WithLiveTrace(ctx context.Context, live *LiveTrace) context.Context {
+	return context.WithValue(ctx, liveTraceKey{}, live)
+}
+
+// getLiveTrace retrieves a LiveTrace from context
+func getLiveTrace(ctx context.Context) *LiveTrace {
+	if live, ok := ctx.Value(liveTraceKey{}).(*LiveTrace); ok {
+		return live
+	}
+	return nil
+}
+
+// Snapshot returns the current state of the run, or nil if it hasn't started.
+func (l *LiveTrace) Snapshot() *FlowTrace {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.collector == nil {
+		return nil
+	}
+	trace := l.collector.snapshot()
+	trace.Flow = l.flow
+	trace.TraceID = l.traceID
+	trace.RequestID = l.requestID
+	trace.Start = l.start
+	trace.Duration = time.Since(l.start)
+	return trace
+}
+
+// attach binds the live trace to a running collector
+func (l *LiveTrace) attach(ctx context.Context, flow string, start time.Time, c *traceCollector) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.collector = c
+	l.flow = flow
+	l.traceID = TraceID(ctx)
+	l.requestID = RequestID(ctx)
+	l.start = start
+}
+
+// HandlerName returns a human-readable name for a handler.
+//
+// Uses Namer if implemented (and non-empty), the function name for HandlerFunc
+// values, and the Go type name otherwise.
+func HandlerName(h Handler) string {
+	if n, ok := h.(Namer); ok {
+		if name := n.Name(); name != "" {
+			return name
+		}
+	}
+	if fn, ok := h.(HandlerFunc); ok {
+		if rf := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); rf != nil {
+			return shortFuncName(rf.Name())
+		}
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", h), "*")
+}
+
+// shortFuncName trims the module path and closure suffixes from a runtime function name
+// e.g. "github.com/calque-ai/go-calque/pkg/middleware/ai.Agent.func1" -> "ai.Agent"
+func shortFuncName(name string) string {
+	if idx := strings.LastIndex(name, "/"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	for {
+		idx := strings.LastIndex(name, ".func")
+		if idx < 0 {
+			break
+		}
+		name = name[:idx]
+	}
+	return name
+}
+
+// traceCollector gathers per-handler trace records from concurrent handler goroutines
+type traceCollector struct {
+	mu          sync.Mutex
+	handlers    []HandlerTrace
+	previewSize int
+}
+
+func newTraceCollector(n, previewSize int) *traceCollector {
+	if previewSize <= 0 {
+		previewSize = DefaultTracePreviewSize
+	}
+	return &traceCollector{
+		handlers:    make([]HandlerTrace, n),
+		previewSize: previewSize,
+	}
+}
+
+// wrap instruments a handler so its execution is recorded at the given index
+func (c *traceCollector) wrap(idx int, h Handler) Handler {
+	name := HandlerName(h)
+	c.handlers[idx] = HandlerTrace{Index: idx, Name: name, Status: TraceStatusPending}
+
+	return &tracedHandler{name: name, fn: func(req *Request, res *Response) error {
+		in := &tracingReader{r: req.Data, preview: newPreview(c.previewSize)}
+		out := &tracingWriter{w: res.Data, preview: newPreview(c.previewSize)}
+
+		start := time.Now()
+		c.mu.Lock()
+		c.handlers[idx] = HandlerTrace{Index: idx, Name: name, Status: TraceStatusRunning, Start: start}
+		c.mu.Unlock()
+
+		err := h.ServeFlow(&Request{Context: req.Context, Data: in}, &Response{Data: out})
+
+		end := time.Now()
+		c.mu.Lock()
+		ht := &c.handlers[idx]
+		ht.End = end
+		ht.Duration = end.Sub(start)
+		ht.BytesIn = in.count()
+		ht.BytesOut = out.count()
+		ht.InputPreview = in.preview.String()
+		ht.OutputPreview = out.preview.String()
+		ht.Status = TraceStatusOK
+		if err != nil {
+			ht.Status = TraceStatusError
+			ht.Error = err.Error()
+		}
+		c.mu.Unlock()
+
+		return err
+	}}
+}
+
+// tracedHandler is an instrumented handler that keeps the name of the handler it wraps
+type tracedHandler struct {
+	name string
+	fn   HandlerFunc
+}
+
+func (n *tracedHandler) Name() string { return n.name }
+
+func (n *tracedHandler) ServeFlow(req *Request, res *Response) error { return n.fn(req, res) }
+
+// snapshot copies the collected records so late-finishing handlers can't race with readers
+func (c *traceCollector) snapshot() *FlowTrace {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	handlers := make([]HandlerTrace, len(c.handlers))
+	copy(handlers, c.handlers)
+	return &FlowTrace{Handlers: handlers}
+}
+
+// preview captures the first N bytes of a stream
+type preview struct {
+	mu    sync.Mutex
+	limit int
+	buf   []byte
+	total int64
+}
+
+func newPreview(limit int) *preview {
+	return &preview{limit: limit}
+}
+
+func (p *preview) add(b []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total += int64(len(b))
+	if remaining := p.limit - len(p.buf); remaining > 0 {
+		if len(b) > remaining {
+			b = b[:remaining]
+		}
+		p.buf = append(p.buf, b...)
+	}
+}
+
+func (p *preview) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.total > int64(len(p.buf)) {
+		return string(p.buf) + "..."
+	}
+	return string(p.buf)
+}
+
+func (p *preview) size() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.total
+}
+
+// tracingReader counts and previews bytes read by a handler
+type tracingReader struct {
+	r       io.Reader
+	preview *preview
+}
+
+func (t *tracingReader) Read(b []byte) (int, error) {
+	n, err := t.r.Read(b)
+	if n > 0 {
+		t.preview.add(b[:n])
+	}
+	return n, err
+}
+
+func (t *tracingReader) count() int64 { return t.preview.size() }
+
+// tracingWriter counts and previews bytes written by a handler
+type tracingWriter struct {
+	w       io.Writer
+	preview *preview
+}
+
+func (t *tracingWriter) Write(b []byte) (int, error) {
+	n, err := t.w.Write(b)
+	if n > 0 {
+		t.preview.add(b[:n])
+	}
+	return n, err
+}
+
+func (t *tracingWriter) count() int64 { return t.preview.size() }
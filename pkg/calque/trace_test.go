@@ -0,0 +1,146 @@
+package calque
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type namedHandler struct{}
+
+func (namedHandler) Name() string { return "custom-name" }
+
+func (namedHandler) ServeFlow(req *Request, res *Response) error {
+	var input string
+	if err := Read(req, &input); err != nil {
+		return err
+	}
+	return Write(res, input+"!")
+}
+
+func tracedUpper(req *Request, res *Response) error {
+	var input string
+	if err := Read(req, &input); err != nil {
+		return err
+	}
+	return Write(res, strings.ToUpper(input))
+}
+
+func TestFlow_RunTraced(t *testing.T) {
+	flow := NewFlow().
+		UseFunc(tracedUpper).
+		Use(namedHandler{})
+
+	ctx := WithTraceID(context.Background(), "trace-1")
+	var result string
+	trace, err := flow.RunTraced(ctx, "hello", &result)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "HELLO!" {
+		t.Errorf("expected 'HELLO!', got %q", result)
+	}
+
+	if trace.TraceID != "trace-1" {
+		t.Errorf("expected trace id 'trace-1', got %q", trace.TraceID)
+	}
+	if len(trace.Handlers) != 2 {
+		t.Fatalf("expected 2 handler traces, got %d", len(trace.Handlers))
+	}
+
+	first := trace.Handlers[0]
+	if first.Name != "calque.tracedUpper" {
+		t.Errorf("expected function name, got %q", first.Name)
+	}
+	if first.BytesIn != 5 || first.BytesOut != 5 {
+		t.Errorf("expected 5 bytes in/out, got %d/%d", first.BytesIn, first.BytesOut)
+	}
+	if first.InputPreview != "hello" || first.OutputPreview != "HELLO" {
+		t.Errorf("unexpected previews: %q / %q", first.InputPreview, first.OutputPreview)
+	}
+	if first.Status != TraceStatusOK {
+		t.Errorf("expected ok status, got %q", first.Status)
+	}
+
+	if trace.Handlers[1].Name != "custom-name" {
+		t.Errorf("expected Namer name, got %q", trace.Handlers[1].Name)
+	}
+
+	data, err := trace.JSON()
+	if err != nil {
+		t.Fatalf("JSON failed: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("trace JSON is invalid: %v", err)
+	}
+	if _, ok := decoded["handlers"]; !ok {
+		t.Error("expected handlers in JSON output")
+	}
+}
+
+func TestFlow_RunTraced_Error(t *testing.T) {
+	flow := NewFlow().
+		UseFunc(func(_ *Request, _ *Response) error {
+			return errors.New("stage failed")
+		})
+
+	var result string
+	trace, err := flow.RunTraced(context.Background(), "x", &result)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if trace == nil {
+		t.Fatal("expected trace even on error")
+	}
+	if !strings.Contains(trace.Error, "stage failed") {
+		t.Errorf("expected flow error in trace, got %q", trace.Error)
+	}
+	if trace.Handlers[0].Status != TraceStatusError || trace.Handlers[0].Error != "stage failed" {
+		t.Errorf("expected handler error recorded, got %+v", trace.Handlers[0])
+	}
+}
+
+func TestFlow_RunTraced_PreviewTruncation(t *testing.T) {
+	flow := NewFlow(FlowConfig{TracePreviewSize: 4}).UseFunc(tracedUpper)
+
+	var result string
+	trace, err := flow.RunTraced(context.Background(), "abcdefgh", &result)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := trace.Handlers[0].InputPreview; got != "abcd..." {
+		t.Errorf("expected truncated preview 'abcd...', got %q", got)
+	}
+	if got := trace.Handlers[0].BytesIn; got != 8 {
+		t.Errorf("expected 8 bytes in, got %d", got)
+	}
+}
+
+func TestHandlerName(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  Handler
+		expected string
+	}{
+		{"namer", namedHandler{}, "custom-name"},
+		{"handler func", HandlerFunc(tracedUpper), "calque.tracedUpper"},
+		{"flow type", NewFlow(), "calque.Flow"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HandlerName(tt.handler); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestShortFuncName(t *testing.T) {
+	got := shortFuncName("github.com/calque-ai/go-calque/pkg/middleware/ai.Agent.func1")
+	if got != "ai.Agent" {
+		t.Errorf("expected 'ai.Agent', got %q", got)
+	}
+}
@@ -0,0 +1,89 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Warmer is implemented by handlers that can prepare for traffic ahead of time.
+//
+// Flow.Warmup calls Warmup on every handler that implements it, so connection
+// dials, pool opens, cache priming and tokenizer loads happen before the first
+// production request rather than during it. Unlike Init, Warmup may be called
+// more than once, e.g. after a deploy or before a traffic shift, and should be
+// safe to repeat.
+type Warmer interface {
+	Warmup(ctx context.Context) error
+}
+
+// maxWarmupDepth bounds nesting so self-referencing groups cannot recurse forever
+const maxWarmupDepth = 32
+
+// Warmup prepares every handler in the flow that implements Warmer.
+//
+// Input: context.Context bounding the warm-up, passed to each handler
+// Output: errors from all failing handlers, joined
+// Behavior: Initializes the flow first (see Init), then warms handlers concurrently
+//
+// Handlers nested in composites (ctrl.Chain, ctrl.Retry, ctrl.Parallel, ...)
+// are found through Grouper; a nested flow warms its own handlers. A failed
+// warm-up does not stop the others, and the flow stays usable: the failing
+// handler pays its cold start on the first request instead.
+//
+// Example:
+//
+//	flow := calque.NewFlow().Use(retriever).Use(ai.Agent(client))
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	if err := flow.Warmup(ctx); err != nil {
+//		log.Printf("warm-up incomplete: %v", err)
+//	}
+//	http.ListenAndServe(":8080", httpserver.Handler(flow)) // ready for traffic
+func (f *Flow) Warmup(ctx context.Context) error {
+	if err := f.Init(ctx); err != nil {
+		return err
+	}
+
+	var warmers []Handler
+	for _, h := range f.handlers {
+		warmers = collectWarmers(h, warmers, 0)
+	}
+
+	errs := make([]error, len(warmers))
+	var wg sync.WaitGroup
+	for i, h := range warmers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := h.(Warmer).Warmup(ctx); err != nil {
+				errs[i] = WrapErr(ctx, err, "warm up handler "+HandlerName(h))
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// collectWarmers appends h, or the Warmers nested in it, to warmers
+func collectWarmers(h Handler, warmers []Handler, depth int) []Handler {
+	if _, ok := h.(Warmer); ok {
+		return append(warmers, h) // warms its own children, like a nested Flow
+	}
+	grouper, ok := h.(Grouper)
+	if !ok || depth >= maxWarmupDepth {
+		return warmers
+	}
+	for _, child := range grouper.Group().Children {
+		warmers = collectWarmers(child, warmers, depth+1)
+	}
+	return warmers
+}
+
+// Warmup implements Warmer for a wrapped handler that implements it.
+func (m *managedHandler) Warmup(ctx context.Context) error {
+	if inner, ok := m.Handler.(Warmer); ok {
+		return inner.Warmup(ctx)
+	}
+	return nil
+}
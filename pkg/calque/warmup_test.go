@@ -0,0 +1,84 @@
+package calque
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// warmHandler records warm-ups and can fail them
+type warmHandler struct {
+	resourceHandler
+	warmErr error
+}
+
+func (w *warmHandler) Warmup(context.Context) error {
+	w.record("warmup")
+	return w.warmErr
+}
+
+func TestFlowWarmup(t *testing.T) {
+	var log []string
+	var mu sync.Mutex
+	warm := func(name string) *warmHandler {
+		return &warmHandler{resourceHandler: resourceHandler{name: name, log: &log, mu: &mu}}
+	}
+
+	inner := NewFlow().Use(warm("inner"))
+	var _ Warmer = inner
+	flow := NewFlow().
+		Use(warm("a")).
+		Use(inner).
+		Use(Composite("wrap", HandlerFunc(passthrough), Group{Kind: GroupWrap, Children: []Handler{warm("wrapped")}})).
+		Use(Managed(warm("managed"), nil, nil)).
+		Use(HandlerFunc(passthrough))
+
+	if err := flow.Warmup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	got := append([]string(nil), log...)
+	mu.Unlock()
+	sort.Strings(got)
+	want := []string{"a:init", "a:warmup", "inner:init", "inner:warmup", "managed:init", "managed:warmup", "wrapped:warmup"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+
+	// Repeated warm-ups are allowed; Init still runs once
+	if err := flow.Warmup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(strings.Join(log, ","), "a:warmup"); n != 2 {
+		t.Errorf("a warmed %d times, want 2", n)
+	}
+}
+
+func TestFlowWarmupErrors(t *testing.T) {
+	var log []string
+	var mu sync.Mutex
+	failing := &warmHandler{resourceHandler: resourceHandler{name: "db", log: &log, mu: &mu}, warmErr: errors.New("dial refused")}
+	ok := &warmHandler{resourceHandler: resourceHandler{name: "cache", log: &log, mu: &mu}}
+	flow := NewFlow().Use(failing).Use(ok)
+
+	err := flow.Warmup(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "dial refused") {
+		t.Fatalf("Warmup() error = %v, want the dial failure", err)
+	}
+	if !strings.Contains(strings.Join(log, ","), "cache:warmup") {
+		t.Errorf("calls = %v, want the other handler warmed", log)
+	}
+
+	var out string
+	if err := flow.Run(context.Background(), "x", &out); err != nil || out != "x" {
+		t.Errorf("Run() after failed warm-up = %q, %v", out, err)
+	}
+
+	_ = flow.Close()
+	if err := flow.Warmup(context.Background()); !errors.Is(err, ErrFlowClosed) {
+		t.Errorf("Warmup() after Close error = %v, want ErrFlowClosed", err)
+	}
+}
@@ -0,0 +1,98 @@
+package calque
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// MetadataSlowHandlerPrefix prefixes the MetadataBus key the watchdog sets for a slow handler.
+//
+// The full key is MetadataSlowHandlerPrefix + handler name and the value is
+// the elapsed time.Duration when the handler was flagged.
+const MetadataSlowHandlerPrefix = "calque.slow_handler."
+
+// SlowHandler describes a handler flagged by the flow watchdog.
+type SlowHandler struct {
+	Flow    string        // flow name (FlowConfig.Name), may be empty
+	Index   int           // position of the handler in the flow
+	Name    string        // handler name (see HandlerName)
+	Elapsed time.Duration // how long the handler had been running
+	Stack   string        // stack of the handler goroutine when it was flagged
+}
+
+// watchHandler flags the handler if it is still running after the slow threshold.
+// Must be called from the handler goroutine; the returned func stops the watchdog.
+func (f *Flow) watchHandler(ctx context.Context, idx int, h Handler) func() {
+	if f.slowThreshold <= 0 {
+		return func() {}
+	}
+	start := time.Now()
+	gid := goroutineID()
+	timer := time.AfterFunc(f.slowThreshold, func() {
+		slow := SlowHandler{
+			Flow:    f.name,
+			Index:   idx,
+			Name:    HandlerName(h),
+			Elapsed: time.Since(start),
+			Stack:   goroutineStack(gid),
+		}
+		if mb := GetMetadataBus(ctx); mb != nil {
+			mb.Set(MetadataSlowHandlerPrefix+slow.Name, slow.Elapsed)
+		}
+		if f.onSlowHandler != nil {
+			f.onSlowHandler(ctx, slow)
+			return
+		}
+		LogWarn(ctx, "slow handler",
+			"flow", slow.Flow,
+			"handler", slow.Name,
+			"handler_index", slow.Index,
+			"elapsed", slow.Elapsed,
+			"stack", slow.Stack,
+		)
+	})
+	return func() { timer.Stop() }
+}
+
+// goroutineID returns the ID of the calling goroutine, parsed from its stack header
+func goroutineID() string {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	header := bytes.TrimPrefix(buf[:n], []byte("goroutine "))
+	if i := bytes.IndexByte(header, ' '); i > 0 {
+		if _, err := strconv.ParseUint(string(header[:i]), 10, 64); err == nil {
+			return string(header[:i])
+		}
+	}
+	return ""
+}
+
+// goroutineStack returns the stack trace of the goroutine with the given ID
+func goroutineStack(id string) string {
+	if id == "" {
+		return ""
+	}
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		if len(buf) >= 8<<20 {
+			break // keep what fits rather than grow without bound
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+
+	prefix := []byte("goroutine " + id + " ")
+	for _, block := range bytes.Split(buf, []byte("\n\n")) {
+		if bytes.HasPrefix(block, prefix) {
+			return string(block)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package calque
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockUntilReleased blocks the handler until release is closed
+func blockUntilReleased(release <-chan struct{}) HandlerFunc {
+	return func(_ *Request, res *Response) error {
+		<-release
+		return Write(res, "done")
+	}
+}
+
+func TestFlowSlowHandlerWatchdog(t *testing.T) {
+	release := make(chan struct{})
+	flagged := make(chan SlowHandler, 2)
+	flow := NewFlow(FlowConfig{
+		Name:                 "hung",
+		SlowHandlerThreshold: 10 * time.Millisecond,
+		OnSlowHandler: func(ctx context.Context, slow SlowHandler) {
+			flagged <- slow
+			if mb := GetMetadataBus(ctx); mb != nil {
+				if _, ok := mb.Get(MetadataSlowHandlerPrefix + slow.Name); !ok {
+					t.Error("slow handler flag missing from MetadataBus")
+				}
+			}
+		},
+	}).Use(blockUntilReleased(release))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	var runErr error
+	go func() {
+		defer wg.Done()
+		var out string
+		runErr = flow.Run(context.Background(), "x", &out)
+	}()
+
+	select {
+	case slow := <-flagged:
+		if slow.Flow != "hung" || slow.Index != 0 || slow.Elapsed < 10*time.Millisecond {
+			t.Errorf("slow handler = %+v", slow)
+		}
+		if !strings.Contains(slow.Stack, "blockUntilReleased") {
+			t.Errorf("stack does not show the blocked handler:\n%s", slow.Stack)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("watchdog did not flag the slow handler")
+	}
+
+	close(release)
+	wg.Wait()
+	if runErr != nil {
+		t.Fatal(runErr)
+	}
+	if len(flagged) != 0 {
+		t.Error("handler flagged more than once")
+	}
+}
+
+func TestFlowSlowHandlerFastRun(t *testing.T) {
+	flow := NewFlow(FlowConfig{
+		SlowHandlerThreshold: 20 * time.Millisecond,
+		OnSlowHandler: func(context.Context, SlowHandler) {
+			t.Error("fast handler flagged as slow")
+		},
+	}).UseFunc(passthrough)
+
+	var out string
+	if err := flow.Run(context.Background(), "x", &out); err != nil || out != "x" {
+		t.Fatalf("out = %q, err = %v", out, err)
+	}
+	time.Sleep(50 * time.Millisecond) // the watchdog must have been stopped when the handler returned
+}
+
+func TestGoroutineStack(t *testing.T) {
+	id := goroutineID()
+	if id == "" {
+		t.Fatal("no goroutine ID")
+	}
+	if stack := goroutineStack(id); !strings.Contains(stack, "TestGoroutineStack") {
+		t.Errorf("stack of current goroutine:\n%s", stack)
+	}
+	if goroutineStack("") != "" {
+		t.Error("empty ID returned a stack")
+	}
+}
@@ -0,0 +1,478 @@
+// Package config loads flow and middleware configs from files and
+// environment variables.
+//
+// Load fills any config struct (calque.FlowConfig, provider and middleware
+// configs, or a service's own struct of them) in three layers: `default`
+// struct tags, then YAML or JSON files, then environment variables. Values
+// are parsed by field type, so a malformed duration or number fails loading
+// with the key and source at fault instead of silently falling back, and
+// `validate` tags check ranges and required fields. String formats a config
+// with API keys, tokens, passwords and `secret` tagged fields redacted.
+//
+// Keys come from the yaml or json tag of each field, or its snake_case name
+// (MaxConcurrent is max_concurrent). The environment variable of a key is
+// EnvPrefix plus the upper-cased key path joined by "_", unless an `env` tag
+// names it: with EnvPrefix "APP_", Flow.MaxConcurrent is read from
+// APP_FLOW_MAX_CONCURRENT.
+//
+// Example:
+//
+//	type Service struct {
+//		Addr   string            `yaml:"addr" default:":8080"`
+//		Flow   calque.FlowConfig `yaml:"flow"`
+//		OpenAI openai.Config     `yaml:"openai"`
+//		Store  struct {
+//			URL     string        `yaml:"url" validate:"required"`
+//			Timeout time.Duration `yaml:"timeout" default:"5s" validate:"min=1ms"`
+//		} `yaml:"store"`
+//	}
+//
+//	var cfg Service
+//	if err := config.Load(&cfg, config.LoadConfig{Files: []string{"service.yaml"}, EnvPrefix: "APP_"}); err != nil {
+//		log.Fatal(err)
+//	}
+//	slog.Info("starting", "config", config.String(cfg))
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/calque-ai/go-calque/pkg/secrets"
+)
+
+// LoadConfig configures Load.
+type LoadConfig struct {
+	// Files are YAML or JSON files (by extension, YAML otherwise) applied in
+	// order, so later files override earlier ones
+	Files []string
+	// OptionalFiles skips files that do not exist instead of failing
+	OptionalFiles bool
+	// EnvPrefix is prepended to every derived environment variable name
+	EnvPrefix string
+	// LookupEnv reads environment variables. Default: os.LookupEnv
+	LookupEnv func(key string) (string, bool)
+	// Secrets resolves *secrets.Ref fields, whose value is the secret name.
+	// Default: secrets.Env()
+	Secrets secrets.Provider
+}
+
+// Load fills dst, a pointer to a struct, from defaults, files and the
+// environment, then validates it. All problems are reported together.
+//
+// Supported field types are strings, bools, numbers, time.Duration,
+// encoding.TextUnmarshaler implementations, *secrets.Ref, pointers to these,
+// slices (comma-separated in the environment), maps (k=v pairs in the
+// environment) and nested structs. Function, channel and interface fields
+// are left as they are.
+//
+// Tags:
+//
+//	default:"30s"                // value used when no file or variable sets the field
+//	env:"OPENAI_API_KEY"         // environment variable, instead of the derived name
+//	validate:"required,min=1,max=100,oneof=json|text"
+//	secret:"true"                // redact in String
+func Load(dst any, config ...LoadConfig) error {
+	var cfg LoadConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.LookupEnv == nil {
+		cfg.LookupEnv = os.LookupEnv
+	}
+	if cfg.Secrets == nil {
+		cfg.Secrets = secrets.Env()
+	}
+
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: Load needs a pointer to a struct, got %T", dst)
+	}
+	l := &loader{cfg: cfg}
+	root := v.Elem()
+
+	l.applyDefaults(root, "")
+	for _, file := range cfg.Files {
+		l.applyFile(root, file)
+	}
+	l.applyEnv(root, nil)
+	if len(l.errs) == 0 {
+		l.validate(root, "")
+	}
+	return errors.Join(l.errs...)
+}
+
+// loader collects errors while filling a config
+type loader struct {
+	cfg  LoadConfig
+	errs []error
+}
+
+func (l *loader) fail(key, source string, err error) {
+	if source != "" {
+		key += " (" + source + ")"
+	}
+	l.errs = append(l.errs, fmt.Errorf("config: %s: %w", key, err))
+}
+
+// field is a settable struct field with its config key
+type field struct {
+	value reflect.Value
+	info  reflect.StructField
+	key   string
+}
+
+// fields lists the configurable fields of a struct, flattening embedded structs
+func fields(v reflect.Value) []field {
+	var out []field
+	t := v.Type()
+	for i := range t.NumField() {
+		info := t.Field(i)
+		if !info.IsExported() {
+			continue
+		}
+		key := fieldKey(info)
+		if key == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if info.Anonymous && fv.Kind() == reflect.Struct && key == "" {
+			out = append(out, fields(fv)...)
+			continue
+		}
+		if key == "" {
+			key = snakeCase(info.Name)
+		}
+		if skipped(fv.Type()) {
+			continue
+		}
+		out = append(out, field{value: fv, info: info, key: key})
+	}
+	return out
+}
+
+// fieldKey returns the name from the yaml or json tag ("" when untagged)
+func fieldKey(info reflect.StructField) string {
+	for _, tag := range []string{"yaml", "json"} {
+		if name, _, _ := strings.Cut(info.Tag.Get(tag), ","); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+var (
+	textUnmarshaler = reflect.TypeFor[encoding.TextUnmarshaler]()
+	durationType    = reflect.TypeFor[time.Duration]()
+	refType         = reflect.TypeFor[*secrets.Ref]()
+)
+
+// skipped reports types Load cannot fill from text
+func skipped(t reflect.Type) bool {
+	if t == refType || reflect.PointerTo(t).Implements(textUnmarshaler) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Func, reflect.Chan, reflect.Interface, reflect.UnsafePointer:
+		return true
+	case reflect.Pointer:
+		return skipped(t.Elem())
+	}
+	return false
+}
+
+// isStruct reports fields that are nested configs rather than values
+func isStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != reflect.TypeFor[secrets.Ref]() &&
+		!reflect.PointerTo(t).Implements(textUnmarshaler) && t != reflect.TypeFor[time.Time]()
+}
+
+// structValue returns the struct behind v, allocating nil pointers
+func structValue(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return v.Elem()
+	}
+	return v
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func (l *loader) applyDefaults(v reflect.Value, prefix string) {
+	for _, f := range fields(v) {
+		key := join(prefix, f.key)
+		if def, ok := f.info.Tag.Lookup("default"); ok && f.value.IsZero() {
+			if err := l.setString(f.value, def); err != nil {
+				l.fail(key, "default", err)
+			}
+			continue
+		}
+		if isStruct(f.value.Type()) && (f.value.Kind() == reflect.Struct || !f.value.IsNil()) {
+			l.applyDefaults(structValue(f.value), key)
+		}
+	}
+}
+
+func (l *loader) applyFile(root reflect.Value, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !(l.cfg.OptionalFiles && errors.Is(err, os.ErrNotExist)) {
+			l.errs = append(l.errs, fmt.Errorf("config: %w", err))
+		}
+		return
+	}
+	var doc map[string]any
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &doc)
+	} else {
+		err = yaml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("config: invalid %s: %w", path, err))
+		return
+	}
+	l.applyMap(root, doc, "", path)
+}
+
+// applyMap sets the fields of struct v from a decoded document
+func (l *loader) applyMap(v reflect.Value, doc map[string]any, prefix, source string) {
+	byKey := map[string]field{}
+	for _, f := range fields(v) {
+		byKey[f.key] = f
+	}
+	for name, raw := range doc {
+		key := join(prefix, name)
+		f, ok := byKey[name]
+		if !ok {
+			l.fail(key, source, errors.New("unknown key"))
+			continue
+		}
+		if err := l.setAny(f.value, raw, key, source); err != nil {
+			l.fail(key, source, err)
+		}
+	}
+}
+
+// setAny sets v from a value decoded from YAML or JSON
+func (l *loader) setAny(v reflect.Value, raw any, key, source string) error {
+	if raw == nil {
+		v.SetZero()
+		return nil
+	}
+	if isStruct(v.Type()) {
+		doc, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("expected a mapping, got %T", raw)
+		}
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			l.applyDefaults(structValue(v), key)
+		}
+		l.applyMap(structValue(v), doc, key, source)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		items, ok := raw.([]any)
+		if !ok {
+			if s, isString := raw.(string); isString {
+				return l.setString(v, s)
+			}
+			return fmt.Errorf("expected a list, got %T", raw)
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := l.setAny(slice.Index(i), item, fmt.Sprintf("%s[%d]", key, i), source); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	case reflect.Map:
+		doc, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("expected a mapping, got %T", raw)
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(doc))
+		for name, item := range doc {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := l.setAny(elem, item, join(key, name), source); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(name).Convert(v.Type().Key()), elem)
+		}
+		v.Set(m)
+		return nil
+	}
+	switch raw.(type) {
+	case map[string]any, []any:
+		return fmt.Errorf("expected a single value, got %T", raw)
+	}
+	return l.setString(v, fmt.Sprint(raw))
+}
+
+// applyEnv sets fields whose environment variable is set, reporting whether any was
+func (l *loader) applyEnv(v reflect.Value, path []string) bool {
+	set := false
+	for _, f := range fields(v) {
+		keyPath := append(path[:len(path):len(path)], f.key)
+		name, tagged := f.info.Tag.Lookup("env")
+		if !tagged {
+			name = l.cfg.EnvPrefix + strings.ToUpper(strings.Join(keyPath, "_"))
+		}
+		if isStruct(f.value.Type()) {
+			if f.value.Kind() == reflect.Pointer && f.value.IsNil() {
+				section := reflect.New(f.value.Type().Elem())
+				l.applyDefaults(section.Elem(), strings.Join(keyPath, "."))
+				if l.applyEnv(section.Elem(), keyPath) {
+					f.value.Set(section)
+					set = true
+				}
+				continue // optional sections stay nil when nothing sets them
+			}
+			set = l.applyEnv(structValue(f.value), keyPath) || set
+			continue
+		}
+		if value, ok := l.cfg.LookupEnv(name); ok {
+			set = true
+			if err := l.setString(f.value, value); err != nil {
+				l.fail(strings.Join(keyPath, "."), name, err)
+			}
+		}
+	}
+	return set
+}
+
+// setString parses s into v by its type
+func (l *loader) setString(v reflect.Value, s string) error {
+	if v.Type() == refType {
+		if s == "" {
+			v.SetZero()
+		} else {
+			v.Set(reflect.ValueOf(secrets.NewRef(l.cfg.Secrets, s)))
+		}
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		elem := reflect.New(v.Type().Elem())
+		if err := l.setString(elem.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshaler) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		parts := splitList(s)
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := l.setString(slice.Index(i), part); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		for _, pair := range splitList(s) {
+			name, value, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("invalid key=value pair %q", pair)
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := l.setString(elem, value); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(name)).Convert(v.Type().Key()), elem)
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// splitList splits a comma-separated list, trimming spaces and dropping empty items
+func splitList(s string) []string {
+	var out []string
+	for part := range strings.SplitSeq(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// snakeCase converts a Go field name to its config key
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
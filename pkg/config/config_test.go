@@ -0,0 +1,229 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/secrets"
+)
+
+type storeConfig struct {
+	URL     string        `yaml:"url" validate:"required"`
+	Timeout time.Duration `yaml:"timeout" default:"5s" validate:"min=1ms"`
+}
+
+type retryConfig struct {
+	Attempts int `yaml:"attempts" default:"3" validate:"min=1,max=10"`
+}
+
+type serviceConfig struct {
+	Addr     string            `yaml:"addr" default:":8080"`
+	Format   string            `yaml:"format" default:"json" validate:"oneof=json|text"`
+	Tags     []string          `yaml:"tags"`
+	Headers  map[string]string `yaml:"headers"`
+	Password string            `yaml:"password"`
+	Token    string            `yaml:"token" env:"SERVICE_TOKEN"`
+	Ratio    *float64          `yaml:"ratio"`
+	Level    slog.Level        `yaml:"level"`
+	OnError  func(error)       `yaml:"-"`
+	Store    storeConfig       `yaml:"store"`
+	Retry    *retryConfig      `yaml:"retry"`
+	Flow     calque.FlowConfig `yaml:"flow"`
+	APIKey   *secrets.Ref      `yaml:"api_key"`
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	base := writeFile(t, "base.yaml", `
+addr: ":9000"
+tags: [a, b]
+headers: {X-Team: search}
+store:
+  url: postgres://db/app
+  timeout: 2s
+flow:
+  max_concurrent: 10
+  stage_timeout: 30s
+`)
+	override := writeFile(t, "override.json", `{"store": {"timeout": "3s"}, "retry": {}}`)
+
+	var cfg serviceConfig
+	err := Load(&cfg, LoadConfig{
+		Files:     []string{base, override},
+		EnvPrefix: "APP_",
+		LookupEnv: env(map[string]string{
+			"APP_FLOW_MAX_CONCURRENT": "25",
+			"APP_TAGS":                "x, y",
+			"APP_RATIO":               "0.5",
+			"APP_LEVEL":               "debug",
+			"APP_API_KEY":             "OPENAI_API_KEY",
+			"SERVICE_TOKEN":           "t0ken",
+			"APP_TOKEN":               "ignored: env tag wins",
+		}),
+	})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	checks := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"default", cfg.Format, "json"},
+		{"file", cfg.Addr, ":9000"},
+		{"later file overrides", cfg.Store.Timeout, 3 * time.Second},
+		{"env overrides file", cfg.Flow.MaxConcurrent, 25},
+		{"file duration", cfg.Flow.StageTimeout, 30 * time.Second},
+		{"env list", strings.Join(cfg.Tags, ","), "x,y"},
+		{"file map", cfg.Headers["X-Team"], "search"},
+		{"env tag", cfg.Token, "t0ken"},
+		{"pointer", *cfg.Ratio, 0.5},
+		{"text unmarshaler", cfg.Level, slog.LevelDebug},
+		{"section defaults", cfg.Retry.Attempts, 3},
+		{"secret ref", cfg.APIKey.Name(), "OPENAI_API_KEY"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadOptionalSectionStaysNil(t *testing.T) {
+	var cfg serviceConfig
+	err := Load(&cfg, LoadConfig{LookupEnv: env(map[string]string{"STORE_URL": "x"})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Retry != nil {
+		t.Errorf("Retry = %+v, want nil when nothing configures it", cfg.Retry)
+	}
+
+	err = Load(&cfg, LoadConfig{LookupEnv: env(map[string]string{"STORE_URL": "x", "RETRY_ATTEMPTS": "5"})})
+	if err != nil || cfg.Retry == nil || cfg.Retry.Attempts != 5 {
+		t.Errorf("Retry = %+v, err = %v, want attempts 5", cfg.Retry, err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		want []string
+	}{
+		{
+			name: "invalid types",
+			env:  map[string]string{"STORE_URL": "x", "FLOW_MAX_CONCURRENT": "many", "STORE_TIMEOUT": "soon"},
+			want: []string{`flow.max_concurrent (FLOW_MAX_CONCURRENT): invalid integer "many"`, `store.timeout (STORE_TIMEOUT): invalid duration "soon"`},
+		},
+		{
+			name: "validation",
+			env:  map[string]string{"FORMAT": "xml", "RETRY_ATTEMPTS": "0", "STORE_TIMEOUT": "0s"},
+			want: []string{"store.url: is required", "format: must be one of json, text", "retry.attempts: must be at least 1", "store.timeout: must be at least 1ms"},
+		},
+		{
+			name: "unknown file key",
+			file: "store: {url: x, timout: 3s}\n",
+			want: []string{"store.timout", "unknown key"},
+		},
+		{
+			name: "file type",
+			file: "store: postgres://db\n",
+			want: []string{"store", "expected a mapping"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := LoadConfig{LookupEnv: env(tt.env)}
+			if tt.file != "" {
+				cfg.Files = []string{writeFile(t, "config.yaml", tt.file)}
+			}
+			var out serviceConfig
+			err := Load(&out, cfg)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		var out serviceConfig
+		missing := filepath.Join(t.TempDir(), "absent.yaml")
+		if err := Load(&out, LoadConfig{Files: []string{missing}, LookupEnv: env(nil)}); err == nil {
+			t.Error("expected error for a missing file")
+		}
+		if err := Load(&out, LoadConfig{Files: []string{missing}, OptionalFiles: true, LookupEnv: env(map[string]string{"STORE_URL": "x"})}); err != nil {
+			t.Errorf("optional missing file: error = %v", err)
+		}
+	})
+
+	t.Run("not a struct pointer", func(t *testing.T) {
+		if err := Load(serviceConfig{}); err == nil {
+			t.Error("expected error for a non-pointer")
+		}
+	})
+}
+
+func TestString(t *testing.T) {
+	cfg := serviceConfig{
+		Addr:     ":8080",
+		Password: "hunter2",
+		Token:    "",
+		Headers:  map[string]string{"Authorization": "Bearer abc", "X-Team": "search"},
+		Store:    storeConfig{URL: "postgres://db", Timeout: time.Second},
+		APIKey:   secrets.NewRef(secrets.Env(), "OPENAI_API_KEY"),
+	}
+	got := String(cfg)
+	for _, leak := range []string{"hunter2", "Bearer abc"} {
+		if strings.Contains(got, leak) {
+			t.Errorf("String() leaks %q: %s", leak, got)
+		}
+	}
+	for _, want := range []string{"Addr::8080", "Password:" + secrets.Redacted, "Token: ", "X-Team:search", "Timeout:1s", "secrets.Ref(OPENAI_API_KEY)", "Retry:<nil>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("String() = %s, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for in, want := range map[string]string{
+		"MaxConcurrent": "max_concurrent",
+		"CPUMultiplier": "cpu_multiplier",
+		"APIKey":        "api_key",
+		"BaseURL":       "base_url",
+		"TopK":          "top_k",
+		"HTTP2":         "http2",
+	} {
+		if got := snakeCase(in); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
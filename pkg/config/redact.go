@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/secrets"
+)
+
+// sensitiveKeys are key names, or key suffixes after "_", whose values String redacts
+var sensitiveKeys = []string{"api_key", "token", "password", "secret", "private_key", "access_key", "credentials", "authorization"}
+
+// String formats a config like %+v with secret values replaced by
+// secrets.Redacted: fields tagged `secret:"true"` and fields or map keys
+// named like api_key, token, password or secret (including suffixes such as
+// session_token). Empty secrets print as "" so missing credentials stay
+// visible. *secrets.Ref fields print their name only.
+//
+// Config types can use it to make fmt and slog output safe:
+//
+//	func (c Config) String() string { return config.String(c) }
+func String(v any) string {
+	var b strings.Builder
+	format(&b, reflect.ValueOf(v), false, true)
+	return b.String()
+}
+
+func format(b *strings.Builder, v reflect.Value, redact, top bool) {
+	if !v.IsValid() {
+		b.WriteString("<nil>")
+		return
+	}
+	if redact && !v.IsZero() && v.Type() != refType {
+		b.WriteString(secrets.Redacted)
+		return
+	}
+	if !top && v.CanInterface() {
+		if s, ok := v.Interface().(fmt.Stringer); ok && (v.Kind() != reflect.Pointer || !v.IsNil()) {
+			b.WriteString(s.String())
+			return
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			b.WriteString("<nil>")
+			return
+		}
+		format(b, v.Elem(), false, top)
+	case reflect.Struct:
+		b.WriteByte('{')
+		t := v.Type()
+		first := true
+		for i := range t.NumField() {
+			info := t.Field(i)
+			if !info.IsExported() {
+				continue
+			}
+			if !first {
+				b.WriteByte(' ')
+			}
+			first = false
+			b.WriteString(info.Name)
+			b.WriteByte(':')
+			key := fieldKey(info)
+			if key == "" {
+				key = snakeCase(info.Name)
+			}
+			format(b, v.Field(i), info.Tag.Get("secret") == "true" || sensitive(key), false)
+		}
+		b.WriteByte('}')
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		b.WriteString("map[")
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			name := fmt.Sprint(k)
+			b.WriteString(name)
+			b.WriteByte(':')
+			format(b, v.MapIndex(k), sensitive(snakeCase(name)), false)
+		}
+		b.WriteByte(']')
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteByte('[')
+		for i := range v.Len() {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			format(b, v.Index(i), false, false)
+		}
+		b.WriteByte(']')
+	case reflect.Func, reflect.Chan:
+		if v.IsNil() {
+			b.WriteString("<nil>")
+		} else {
+			fmt.Fprintf(b, "%s", v.Type())
+		}
+	default:
+		fmt.Fprint(b, v.Interface())
+	}
+}
+
+// sensitive reports whether a snake_case key names a secret
+func sensitive(key string) bool {
+	key = strings.ToLower(strings.ReplaceAll(key, "-", "_"))
+	for _, name := range sensitiveKeys {
+		if key == name || strings.HasSuffix(key, "_"+name) {
+			return true
+		}
+	}
+	return false
+}
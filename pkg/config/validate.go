@@ -0,0 +1,89 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// validate checks the validate tags of v's fields and its nested configs
+func (l *loader) validate(v reflect.Value, prefix string) {
+	for _, f := range fields(v) {
+		key := join(prefix, f.key)
+		if rules := f.info.Tag.Get("validate"); rules != "" {
+			for rule := range strings.SplitSeq(rules, ",") {
+				if err := check(f.value, strings.TrimSpace(rule)); err != nil {
+					l.fail(key, "", err)
+				}
+			}
+		}
+		if isStruct(f.value.Type()) && (f.value.Kind() == reflect.Struct || !f.value.IsNil()) {
+			l.validate(reflect.Indirect(f.value), key)
+		}
+	}
+}
+
+// check applies one rule: required, min=N, max=N or oneof=a|b
+func check(v reflect.Value, rule string) error {
+	name, arg, _ := strings.Cut(rule, "=")
+	if name == "required" {
+		if v.IsZero() {
+			return errors.New("is required")
+		}
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil // optional values are only checked when set
+		}
+		v = v.Elem()
+	}
+
+	switch name {
+	case "min", "max":
+		got, limit, err := measure(v, arg)
+		if err != nil {
+			return fmt.Errorf("invalid %s rule: %w", name, err)
+		}
+		if name == "min" && got < limit {
+			return fmt.Errorf("must be at least %s", arg)
+		}
+		if name == "max" && got > limit {
+			return fmt.Errorf("must be at most %s", arg)
+		}
+	case "oneof":
+		options := strings.Split(arg, "|")
+		if got := fmt.Sprint(v.Interface()); !slices.Contains(options, got) {
+			return fmt.Errorf("must be one of %s, got %q", strings.Join(options, ", "), got)
+		}
+	default:
+		return fmt.Errorf("unknown validate rule %q", rule)
+	}
+	return nil
+}
+
+// measure returns the value compared by min and max (the length of strings,
+// slices and maps) and the parsed limit
+func measure(v reflect.Value, arg string) (got, limit float64, err error) {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(arg)
+		return float64(v.Int()), float64(d), err
+	}
+	limit, err = strconv.ParseFloat(arg, 64)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), limit, err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), limit, err
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), limit, err
+	case reflect.String, reflect.Slice, reflect.Map:
+		return float64(v.Len()), limit, err
+	default:
+		return 0, 0, fmt.Errorf("not supported for %s", v.Type())
+	}
+}
@@ -0,0 +1,310 @@
+package convert
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Unarchive defaults
+const (
+	DefaultArchiveMaxSize      = 100 << 20 // zip bytes buffered for random access
+	DefaultArchiveMaxFileSize  = 10 << 20  // larger files are skipped
+	DefaultArchiveMaxTotalSize = 256 << 20 // uncompressed bytes emitted before failing
+	DefaultArchiveMaxFiles     = 10000     // files emitted before failing
+)
+
+// ArchiveEntryMIME is the MIME type of the FrameJSON header Unarchive writes
+// before each file's content frame.
+const ArchiveEntryMIME = "application/vnd.calque.archive-entry+json"
+
+// ArchiveFilter selects the archive files Unarchive emits by their slash-separated path.
+type ArchiveFilter func(name string) bool
+
+// ArchiveGlob creates an ArchiveFilter accepting files whose path or base
+// name matches any of the path.Match patterns, e.g. "*.go" or "docs/*.md".
+//
+// Example:
+//
+//	convert.Unarchive(convert.ArchiveGlob("*.go", "go.mod", "*.md"))
+func ArchiveGlob(patterns ...string) ArchiveFilter {
+	return func(name string) bool {
+		base := path.Base(name)
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, name); ok {
+				return true
+			}
+			if ok, _ := path.Match(p, base); ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ArchiveOptions limits what Unarchive expands.
+type ArchiveOptions struct {
+	MaxSize      int64 // zip archive bytes buffered (0 = DefaultArchiveMaxSize)
+	MaxFileSize  int64 // files larger than this are skipped (0 = DefaultArchiveMaxFileSize)
+	MaxTotalSize int64 // uncompressed bytes emitted before failing (0 = DefaultArchiveMaxTotalSize)
+	MaxFiles     int   // files emitted before failing (0 = DefaultArchiveMaxFiles)
+}
+
+// ArchiveEntry is one file expanded by Unarchive.
+type ArchiveEntry struct {
+	Path    string `json:"path"` // slash-separated, relative to the archive root
+	Size    int64  `json:"size"`
+	MIME    string `json:"-"` // from the content frame
+	Content []byte `json:"-"`
+}
+
+// Unarchive expands a zip, tar, tar.gz or tar.zst archive into frames.
+//
+// Input: archive bytes; the format is detected from its first bytes
+// Output: frame stream, two frames per file: a FrameJSON entry header
+// (MIME ArchiveEntryMIME, {"path":...,"size":...}) then the content as a
+// FrameText frame for UTF-8 text or a FrameBinary frame with a sniffed MIME type
+// Behavior: STREAMING - tar files are emitted as they are read; zip archives
+// are buffered first, as their index is at the end
+//
+// filter selects files by path; nil emits every file. Directories, links,
+// "__MACOSX/" metadata and paths escaping the archive root are skipped, as
+// are files over MaxFileSize. Exceeding MaxFiles or MaxTotalSize fails the
+// flow, guarding against archive bombs. Nested archives are emitted as files,
+// not expanded. A gzip or zstd stream that does not hold a tar archive is
+// emitted as a single file.
+//
+// Example:
+//
+//	flow := calque.NewFlow().
+//		Use(convert.Unarchive(convert.ArchiveGlob("*.go", "*.md"))).
+//		Use(codebaseSummary) // reads files with convert.ReadArchiveEntries
+//	err := flow.Run(ctx, uploadedZip, &summary)
+func Unarchive(filter ArchiveFilter, opts ...ArchiveOptions) calque.Handler {
+	cfg := ArchiveOptions{}
+	if len(opts) > 0 {
+		cfg = opts[0]
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = DefaultArchiveMaxSize
+	}
+	if cfg.MaxFileSize <= 0 {
+		cfg.MaxFileSize = DefaultArchiveMaxFileSize
+	}
+	if cfg.MaxTotalSize <= 0 {
+		cfg.MaxTotalSize = DefaultArchiveMaxTotalSize
+	}
+	if cfg.MaxFiles <= 0 {
+		cfg.MaxFiles = DefaultArchiveMaxFiles
+	}
+
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		x := &expander{cfg: cfg, filter: filter, fw: NewFrameWriter(res.Data)}
+		if err := x.expand(bufio.NewReaderSize(req.Data, 64*1024), ""); err != nil {
+			return calque.WrapErr(req.Context, err, "failed to expand archive")
+		}
+		return nil
+	})
+}
+
+// ReadArchiveEntries calls fn for every file in an Unarchive frame stream.
+// Other frames are ignored. Returning an error from fn stops reading.
+//
+// Example:
+//
+//	err := convert.ReadArchiveEntries(req.Data, func(e convert.ArchiveEntry) error {
+//		fmt.Fprintf(&prompt, "--- %s ---\n%s\n", e.Path, e.Content)
+//		return nil
+//	})
+func ReadArchiveEntries(r io.Reader, fn func(ArchiveEntry) error) error {
+	var pending *ArchiveEntry
+	return ReadFrames(r, func(f Frame) error {
+		if pending != nil {
+			entry := *pending
+			pending = nil
+			entry.MIME, entry.Content = f.MIME, f.Payload
+			return fn(entry)
+		}
+		if f.Type == FrameJSON && f.MIME == ArchiveEntryMIME {
+			var entry ArchiveEntry
+			if err := json.Unmarshal(f.Payload, &entry); err != nil {
+				return calque.WrapErr(context.Background(), err, "invalid archive entry header")
+			}
+			pending = &entry
+		}
+		return nil
+	})
+}
+
+// expander writes the files of one archive, enforcing the limits
+type expander struct {
+	cfg    ArchiveOptions
+	filter ArchiveFilter
+	fw     *FrameWriter
+	files  int
+	total  int64
+}
+
+var (
+	zipMagic  = []byte("PK\x03\x04")
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// expand detects the format of r; name is the fallback file name for a
+// compressed stream that is not a tar archive
+func (x *expander) expand(r *bufio.Reader, name string) error {
+	head, err := r.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return err
+	}
+
+	switch {
+	case bytes.HasPrefix(head, zipMagic) && name == "":
+		return x.expandZip(r)
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		return x.expandTar(r)
+	case bytes.HasPrefix(head, gzipMagic) && name == "":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = zr.Close() }()
+		fallback := path.Base(zr.Name)
+		if zr.Name == "" {
+			fallback = "data"
+		}
+		return x.expand(bufio.NewReaderSize(zr, 64*1024), fallback)
+	case bytes.HasPrefix(head, zstdMagic) && name == "":
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		return x.expand(bufio.NewReaderSize(zr, 64*1024), "data")
+	case name != "":
+		return x.emit(name, r)
+	}
+	return calque.InvalidInput(calque.NewErr(context.Background(), "unsupported archive format (want zip, tar, tar.gz or tar.zst)"))
+}
+
+func (x *expander) expandTar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if hdr.Size > x.cfg.MaxFileSize {
+			continue
+		}
+		if err := x.emit(hdr.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func (x *expander) expandZip(r io.Reader) error {
+	data, err := io.ReadAll(io.LimitReader(r, x.cfg.MaxSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > x.cfg.MaxSize {
+		return calque.InvalidInput(calque.NewErr(context.Background(), fmt.Sprintf("zip archive exceeds %d bytes", x.cfg.MaxSize)))
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return calque.InvalidInput(calque.WrapErr(context.Background(), err, "invalid zip archive"))
+	}
+
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() || f.UncompressedSize64 > uint64(x.cfg.MaxFileSize) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = x.emit(f.Name, rc)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emit writes one file's header and content frames if it passes the filter
+func (x *expander) emit(name string, r io.Reader) error {
+	name, ok := cleanArchivePath(name)
+	if !ok || (x.filter != nil && !x.filter(name)) {
+		return nil
+	}
+
+	// sizes in headers can lie; read one byte past the limit to find out
+	content, err := io.ReadAll(io.LimitReader(r, x.cfg.MaxFileSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(content)) > x.cfg.MaxFileSize {
+		return nil
+	}
+
+	x.files++
+	x.total += int64(len(content))
+	if x.files > x.cfg.MaxFiles {
+		return calque.InvalidInput(calque.NewErr(context.Background(), fmt.Sprintf("archive has more than %d files", x.cfg.MaxFiles)))
+	}
+	if x.total > x.cfg.MaxTotalSize {
+		return calque.InvalidInput(calque.NewErr(context.Background(), fmt.Sprintf("archive expands to more than %d bytes", x.cfg.MaxTotalSize)))
+	}
+
+	header, err := json.Marshal(ArchiveEntry{Path: name, Size: int64(len(content))})
+	if err != nil {
+		return err
+	}
+	if err := x.fw.WriteFrame(Frame{Type: FrameJSON, MIME: ArchiveEntryMIME, Payload: header}); err != nil {
+		return err
+	}
+	if utf8.Valid(content) && bytes.IndexByte(content, 0) < 0 {
+		return x.fw.WriteFrame(Frame{Type: FrameText, MIME: "text/plain; charset=utf-8", Payload: content})
+	}
+	return x.fw.WriteBinary(http.DetectContentType(content), content)
+}
+
+// cleanArchivePath normalises an entry name, rejecting paths outside the
+// archive root and macOS resource-fork metadata
+func cleanArchivePath(name string) (string, bool) {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") {
+		return "", false
+	}
+	name = path.Clean(name)
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	if name == "__MACOSX" || strings.HasPrefix(name, "__MACOSX/") {
+		return "", false
+	}
+	return name, true
+}
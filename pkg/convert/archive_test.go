@@ -0,0 +1,168 @@
+package convert
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+type archiveFile struct {
+	name    string
+	content string
+}
+
+var archiveFiles = []archiveFile{
+	{"repo/main.go", "package main\n"},
+	{"repo/README.md", "# Demo\n"},
+	{"repo/logo.png", "\x89PNG\r\n\x1a\n\x00\x00"},
+	{"__MACOSX/repo/._main.go", "junk"},
+	{"../escape.txt", "outside"},
+}
+
+func zipArchive(t *testing.T, files []archiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if _, err := zw.Create("repo/"); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(f.content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarArchive(t *testing.T, files []archiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	_ = tw.WriteHeader(&tar.Header{Name: "repo/", Typeflag: tar.TypeDir, Mode: 0o755})
+	_ = tw.WriteHeader(&tar.Header{Name: "repo/link", Typeflag: tar.TypeSymlink, Linkname: "main.go"})
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(f.content)), Format: tar.FormatUSTAR}); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = tw.Write([]byte(f.content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipBytes(t *testing.T, name string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Name = name
+	_, _ = zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zstdBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// unarchive runs h and lists the expanded entries as "path (MIME): content"
+func unarchive(t *testing.T, h calque.Handler, input []byte) ([]string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	if err := h.ServeFlow(calque.NewRequest(context.Background(), bytes.NewReader(input)), calque.NewResponse(&out)); err != nil {
+		return nil, err
+	}
+	var entries []string
+	err := ReadArchiveEntries(&out, func(e ArchiveEntry) error {
+		if e.Size != int64(len(e.Content)) {
+			t.Errorf("%s: size %d, content %d bytes", e.Path, e.Size, len(e.Content))
+		}
+		entries = append(entries, fmt.Sprintf("%s (%s): %q", e.Path, e.MIME, e.Content))
+		return nil
+	})
+	return entries, err
+}
+
+func TestUnarchive(t *testing.T) {
+	all := []string{
+		`repo/main.go (text/plain; charset=utf-8): "package main\n"`,
+		`repo/README.md (text/plain; charset=utf-8): "# Demo\n"`,
+		`repo/logo.png (image/png): "\x89PNG\r\n\x1a\n\x00\x00"`,
+	}
+	tarball := tarArchive(t, archiveFiles)
+
+	tests := []struct {
+		name     string
+		input    []byte
+		filter   ArchiveFilter
+		expected []string
+	}{
+		{name: "zip", input: zipArchive(t, archiveFiles), expected: all},
+		{name: "tar", input: tarball, expected: all},
+		{name: "tar.gz", input: gzipBytes(t, "", tarball), expected: all},
+		{name: "tar.zst", input: zstdBytes(t, tarball), expected: all},
+		{name: "glob filter", input: zipArchive(t, archiveFiles), filter: ArchiveGlob("*.go", "repo/*.md"), expected: all[:2]},
+		{name: "single gzip file", input: gzipBytes(t, "notes.txt", []byte("hello")), expected: []string{`notes.txt (text/plain; charset=utf-8): "hello"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := unarchive(t, Unarchive(tt.filter), tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(entries, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("entries =\n%s\nwant\n%s", strings.Join(entries, "\n"), strings.Join(tt.expected, "\n"))
+			}
+		})
+	}
+}
+
+func TestUnarchiveLimits(t *testing.T) {
+	files := []archiveFile{{"a.txt", "small"}, {"big.txt", strings.Repeat("x", 100)}, {"b.txt", "also small"}}
+
+	entries, err := unarchive(t, Unarchive(nil, ArchiveOptions{MaxFileSize: 50}), tarArchive(t, files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("oversized file should be skipped, got %v", entries)
+	}
+
+	if _, err := unarchive(t, Unarchive(nil, ArchiveOptions{MaxFiles: 2}), zipArchive(t, files)); !calque.IsInvalidInput(err) {
+		t.Errorf("MaxFiles error = %v, want invalid input", err)
+	}
+	if _, err := unarchive(t, Unarchive(nil, ArchiveOptions{MaxTotalSize: 100}), tarArchive(t, files)); !calque.IsInvalidInput(err) {
+		t.Errorf("MaxTotalSize error = %v, want invalid input", err)
+	}
+	if _, err := unarchive(t, Unarchive(nil), []byte("just some text")); !calque.IsInvalidInput(err) {
+		t.Errorf("unknown format error = %v, want invalid input", err)
+	}
+}
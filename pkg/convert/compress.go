@@ -0,0 +1,115 @@
+package convert
+
+import (
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Gzip compresses the stream with gzip.
+//
+// Input: any bytes (streaming)
+// Output: gzip-compressed stream
+// Behavior: STREAMING - compressed blocks are written as the encoder fills them
+//
+// level is a compress/gzip level (gzip.BestSpeed to gzip.BestCompression);
+// omitted, gzip.DefaultCompression is used.
+//
+// Example:
+//
+//	// Shrink large documents before sending them to a remote stage
+//	flow.Use(convert.Gzip()).Use(remoteStage)
+func Gzip(level ...int) calque.Handler {
+	lvl := gzip.DefaultCompression
+	if len(level) > 0 {
+		lvl = level[0]
+	}
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		zw, err := gzip.NewWriterLevel(res.Data, lvl)
+		if err != nil {
+			return calque.WrapErr(req.Context, err, "invalid gzip level")
+		}
+		return compressStream(req, zw, "gzip")
+	})
+}
+
+// Gunzip decompresses a gzip stream.
+//
+// Input: gzip-compressed stream (concatenated members are supported)
+// Output: decompressed bytes
+// Behavior: STREAMING - output is written as it is decoded
+//
+// Example:
+//
+//	flow.Use(convert.Gunzip()).Use(text.LineProcessor(parseLogLine))
+func Gunzip() calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		zr, err := gzip.NewReader(req.Data)
+		if err != nil {
+			return calque.WrapErr(req.Context, err, "failed to read gzip header")
+		}
+		defer func() { _ = zr.Close() }()
+		if _, err := io.Copy(res.Data, zr); err != nil {
+			return calque.WrapErr(req.Context, err, "failed to decompress gzip stream")
+		}
+		return nil
+	})
+}
+
+// Zstd compresses the stream with Zstandard, which is faster than gzip at a
+// similar ratio.
+//
+// Input: any bytes (streaming)
+// Output: zstd-compressed stream
+// Behavior: STREAMING - compressed blocks are written as the encoder fills them
+//
+// Example:
+//
+//	flow.Use(convert.Zstd()).Use(remoteStage)
+func Zstd() calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		zw, err := zstd.NewWriter(res.Data, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return calque.WrapErr(req.Context, err, "failed to create zstd encoder")
+		}
+		return compressStream(req, zw, "zstd")
+	})
+}
+
+// Unzstd decompresses a Zstandard stream.
+//
+// Input: zstd-compressed stream
+// Output: decompressed bytes
+// Behavior: STREAMING - output is written as it is decoded
+//
+// Example:
+//
+//	flow.Use(convert.Unzstd()).Use(ai.Agent(client))
+func Unzstd() calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		zr, err := zstd.NewReader(req.Data, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return calque.WrapErr(req.Context, err, "failed to create zstd decoder")
+		}
+		defer zr.Close()
+		if _, err := io.Copy(res.Data, zr); err != nil {
+			return calque.WrapErr(req.Context, err, "failed to decompress zstd stream")
+		}
+		return nil
+	})
+}
+
+// compressStream copies the input through an encoder and closes it to write the trailer
+func compressStream(req *calque.Request, zw io.WriteCloser, format string) error {
+	if _, err := io.Copy(zw, req.Data); err != nil {
+		_ = zw.Close()
+		return calque.WrapErr(req.Context, err, "failed to "+format+" compress stream")
+	}
+	if err := zw.Close(); err != nil {
+		return calque.WrapErr(req.Context, err, "failed to finish "+format+" stream")
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package convert
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestCompressionRoundTrip(t *testing.T) {
+	input := strings.Repeat("a large document about streaming flows. ", 2000)
+
+	tests := []struct {
+		name     string
+		compress calque.Handler
+		restore  calque.Handler
+		magic    []byte
+	}{
+		{name: "gzip", compress: Gzip(), restore: Gunzip(), magic: []byte{0x1f, 0x8b}},
+		{name: "gzip best speed", compress: Gzip(gzip.BestSpeed), restore: Gunzip(), magic: []byte{0x1f, 0x8b}},
+		{name: "zstd", compress: Zstd(), restore: Unzstd(), magic: []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var compressed []byte
+			if err := calque.NewFlow().Use(tt.compress).Run(context.Background(), input, &compressed); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(compressed, tt.magic) {
+				t.Errorf("output does not start with %x", tt.magic)
+			}
+			if len(compressed) >= len(input)/10 {
+				t.Errorf("compressed %d bytes to %d", len(input), len(compressed))
+			}
+
+			var restored string
+			if err := calque.NewFlow().Use(tt.restore).Run(context.Background(), compressed, &restored); err != nil {
+				t.Fatal(err)
+			}
+			if restored != input {
+				t.Error("round trip changed the data")
+			}
+		})
+	}
+}
+
+func TestDecompressErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler calque.Handler
+	}{
+		{name: "gunzip", handler: Gunzip()},
+		{name: "unzstd", handler: Unzstd()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out string
+			if err := calque.NewFlow().Use(tt.handler).Run(context.Background(), "not compressed data", &out); err == nil {
+				t.Error("expected error for uncompressed input")
+			}
+		})
+	}
+
+	if err := calque.NewFlow().Use(Gzip(42)).Run(context.Background(), "x", new(string)); err == nil {
+		t.Error("expected error for invalid gzip level")
+	}
+}
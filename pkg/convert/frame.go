@@ -0,0 +1,236 @@
+package convert
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// FrameType identifies the kind of payload a Frame carries.
+//
+// Values from 0x80 up are free for application-defined types.
+type FrameType byte
+
+const (
+	// FrameText carries UTF-8 text
+	FrameText FrameType = 1
+	// FrameJSON carries one JSON value, such as an event
+	FrameJSON FrameType = 2
+	// FrameBinary carries opaque bytes described by the frame MIME type (audio, images)
+	FrameBinary FrameType = 3
+)
+
+// DefaultMaxFramePayload is the largest payload a FrameReader accepts by default.
+const DefaultMaxFramePayload = 64 << 20
+
+// frameHeaderSize is type (1) + MIME length (2) + payload length (4)
+const frameHeaderSize = 7
+
+// Frame is one unit of a mixed-content stream.
+//
+// On the wire a frame is a 7-byte header, the MIME type and the payload:
+//
+//	type (1 byte) | MIME length (uint16, big endian) | payload length (uint32, big endian) | MIME | payload
+type Frame struct {
+	Type    FrameType
+	MIME    string // payload media type, e.g. "audio/wav"; optional
+	Payload []byte
+}
+
+// FrameWriter writes frames to a stream.
+type FrameWriter struct {
+	w io.Writer
+}
+
+// NewFrameWriter creates a FrameWriter on w.
+//
+// Example:
+//
+//	fw := convert.NewFrameWriter(res.Data)
+//	fw.WriteText("Here is the chart:")
+//	fw.WriteBinary("image/png", png)
+//	fw.WriteJSON(map[string]any{"event": "done"})
+func NewFrameWriter(w io.Writer) *FrameWriter {
+	return &FrameWriter{w: w}
+}
+
+// WriteFrame writes one frame as a single Write.
+func (fw *FrameWriter) WriteFrame(f Frame) error {
+	ctx := context.Background()
+	if len(f.MIME) > math.MaxUint16 {
+		return calque.NewErr(ctx, fmt.Sprintf("frame MIME type is %d bytes, limit is %d", len(f.MIME), math.MaxUint16))
+	}
+	if uint64(len(f.Payload)) > math.MaxUint32 {
+		return calque.NewErr(ctx, fmt.Sprintf("frame payload is %d bytes, limit is %d", len(f.Payload), uint64(math.MaxUint32)))
+	}
+
+	buf := make([]byte, frameHeaderSize, frameHeaderSize+len(f.MIME)+len(f.Payload))
+	buf[0] = byte(f.Type)
+	binary.BigEndian.PutUint16(buf[1:3], uint16(len(f.MIME)))
+	binary.BigEndian.PutUint32(buf[3:7], uint32(len(f.Payload)))
+	buf = append(buf, f.MIME...)
+	buf = append(buf, f.Payload...)
+	if _, err := fw.w.Write(buf); err != nil {
+		return calque.WrapErr(ctx, err, "failed to write frame")
+	}
+	return nil
+}
+
+// WriteText writes a FrameText frame.
+func (fw *FrameWriter) WriteText(text string) error {
+	return fw.WriteFrame(Frame{Type: FrameText, MIME: "text/plain; charset=utf-8", Payload: []byte(text)})
+}
+
+// WriteJSON writes v as a FrameJSON frame.
+func (fw *FrameWriter) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return calque.WrapErr(context.Background(), err, "failed to encode frame JSON")
+	}
+	return fw.WriteFrame(Frame{Type: FrameJSON, MIME: "application/json", Payload: data})
+}
+
+// WriteBinary writes a FrameBinary frame with the given MIME type.
+func (fw *FrameWriter) WriteBinary(mime string, data []byte) error {
+	return fw.WriteFrame(Frame{Type: FrameBinary, MIME: mime, Payload: data})
+}
+
+// FrameReader reads frames from a stream.
+type FrameReader struct {
+	r          *bufio.Reader
+	maxPayload int
+}
+
+// NewFrameReader creates a FrameReader on r. Frames with payloads over
+// maxPayload bytes are rejected; 0 means DefaultMaxFramePayload.
+//
+// Example:
+//
+//	fr := convert.NewFrameReader(req.Data, 0)
+//	for {
+//		frame, err := fr.ReadFrame()
+//		if err == io.EOF {
+//			break
+//		}
+//		if err != nil {
+//			return err
+//		}
+//		handle(frame)
+//	}
+func NewFrameReader(r io.Reader, maxPayload int) *FrameReader {
+	if maxPayload <= 0 {
+		maxPayload = DefaultMaxFramePayload
+	}
+	return &FrameReader{r: bufio.NewReader(r), maxPayload: maxPayload}
+}
+
+// ReadFrame reads the next frame.
+//
+// Returns io.EOF when the stream ends between frames and io.ErrUnexpectedEOF
+// (wrapped) when it ends inside one.
+func (fr *FrameReader) ReadFrame() (Frame, error) {
+	ctx := context.Background()
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(fr.r, header[:]); err != nil {
+		if err == io.EOF {
+			return Frame{}, io.EOF
+		}
+		return Frame{}, calque.WrapErr(ctx, err, "failed to read frame header")
+	}
+
+	mimeLen := int(binary.BigEndian.Uint16(header[1:3]))
+	payloadLen := uint64(binary.BigEndian.Uint32(header[3:7]))
+	if payloadLen > uint64(fr.maxPayload) {
+		return Frame{}, calque.NewErr(ctx, fmt.Sprintf("frame payload is %d bytes, limit is %d", payloadLen, fr.maxPayload))
+	}
+
+	body := make([]byte, mimeLen+int(payloadLen))
+	if _, err := io.ReadFull(fr.r, body); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return Frame{}, calque.WrapErr(ctx, err, "failed to read frame body")
+	}
+	return Frame{Type: FrameType(header[0]), MIME: string(body[:mimeLen]), Payload: body[mimeLen:]}, nil
+}
+
+// ReadFrames calls fn for every frame in r until the stream ends.
+// Returning an error from fn stops reading.
+func ReadFrames(r io.Reader, fn func(Frame) error) error {
+	fr := NewFrameReader(r, 0)
+	for {
+		frame, err := fr.ReadFrame()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(frame); err != nil {
+			return err
+		}
+	}
+}
+
+// ToFrames wraps a raw stream into frames of one type.
+//
+// Input: raw bytes (streaming)
+// Output: frame stream, one frame per chunk read
+// Behavior: STREAMING - each chunk is framed as it arrives
+//
+// Example:
+//
+//	// Frame a text-to-speech stage's audio for a mixed-content client
+//	flow.Use(ttsHandler).Use(convert.ToFrames(convert.FrameBinary, "audio/mpeg"))
+func ToFrames(frameType FrameType, mime string) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		fw := NewFrameWriter(res.Data)
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := req.Data.Read(buf)
+			if n > 0 {
+				if werr := fw.WriteFrame(Frame{Type: frameType, MIME: mime, Payload: buf[:n]}); werr != nil {
+					return werr
+				}
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// ExtractFrames writes the payloads of frames of the given types, dropping the rest.
+//
+// Input: frame stream (streaming)
+// Output: concatenated payloads of matching frames
+// Behavior: STREAMING - each frame's payload is written once the frame is complete
+//
+// With no types, every payload is written.
+//
+// Example:
+//
+//	// Keep only the text of a mixed stream for a downstream text handler
+//	flow.Use(convert.ExtractFrames(convert.FrameText)).Use(text.TrimSpace())
+func ExtractFrames(types ...FrameType) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		return ReadFrames(req.Data, func(f Frame) error {
+			if len(types) > 0 && !slices.Contains(types, f.Type) {
+				return nil
+			}
+			_, err := res.Data.Write(f.Payload)
+			return err
+		})
+	})
+}
@@ -0,0 +1,129 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	fw := NewFrameWriter(&buf)
+	png := []byte{0x89, 'P', 'N', 'G', 0, 1, 2}
+	if err := fw.WriteText("hello é"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.WriteBinary("image/png", png); err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.WriteJSON(map[string]any{"event": "done"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.WriteFrame(Frame{Type: 0x80}); err != nil {
+		t.Fatal(err)
+	}
+
+	var frames []Frame
+	err := ReadFrames(iotest.OneByteReader(&buf), func(f Frame) error {
+		frames = append(frames, f)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Frame{
+		{Type: FrameText, MIME: "text/plain; charset=utf-8", Payload: []byte("hello é")},
+		{Type: FrameBinary, MIME: "image/png", Payload: png},
+		{Type: FrameJSON, MIME: "application/json", Payload: []byte(`{"event":"done"}`)},
+		{Type: 0x80, Payload: []byte{}},
+	}
+	if len(frames) != len(want) {
+		t.Fatalf("got %d frames, want %d", len(frames), len(want))
+	}
+	for i, f := range frames {
+		if f.Type != want[i].Type || f.MIME != want[i].MIME || !bytes.Equal(f.Payload, want[i].Payload) {
+			t.Errorf("frame %d = %+v, want %+v", i, f, want[i])
+		}
+	}
+}
+
+func TestFrameReaderErrors(t *testing.T) {
+	var buf bytes.Buffer
+	_ = NewFrameWriter(&buf).WriteText("hello world")
+	full := buf.Bytes()
+
+	tests := []struct {
+		name       string
+		data       []byte
+		maxPayload int
+		wantErr    error // nil = any error
+	}{
+		{name: "truncated header", data: full[:3], wantErr: io.ErrUnexpectedEOF},
+		{name: "truncated body", data: full[:len(full)-2], wantErr: io.ErrUnexpectedEOF},
+		{name: "payload over limit", data: full, maxPayload: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFrameReader(bytes.NewReader(tt.data), tt.maxPayload).ReadFrame()
+			if err == nil || err == io.EOF {
+				t.Fatalf("ReadFrame() error = %v, want failure", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("ReadFrame() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := NewFrameReader(bytes.NewReader(nil), 0).ReadFrame(); err != io.EOF {
+		t.Errorf("empty stream error = %v, want io.EOF", err)
+	}
+}
+
+func TestFrameHandlers(t *testing.T) {
+	mixed := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		fw := NewFrameWriter(res.Data)
+		if err := fw.WriteText("Here is "); err != nil {
+			return err
+		}
+		if err := fw.WriteBinary("audio/wav", []byte{1, 2, 3}); err != nil {
+			return err
+		}
+		return fw.WriteText("the answer")
+	})
+
+	var out string
+	flow := calque.NewFlow().Use(mixed).Use(ExtractFrames(FrameText))
+	if err := flow.Run(context.Background(), "", &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != "Here is the answer" {
+		t.Errorf("ExtractFrames() = %q", out)
+	}
+
+	var framed []byte
+	flow = calque.NewFlow().Use(ToFrames(FrameText, "text/plain"))
+	if err := flow.Run(context.Background(), "streamed text", &framed); err != nil {
+		t.Fatal(err)
+	}
+	var text strings.Builder
+	if err := ReadFrames(bytes.NewReader(framed), func(f Frame) error {
+		if f.Type != FrameText || f.MIME != "text/plain" {
+			t.Errorf("frame = %+v", f)
+		}
+		text.Write(f.Payload)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if text.String() != "streamed text" {
+		t.Errorf("ToFrames() payloads = %q", text.String())
+	}
+}
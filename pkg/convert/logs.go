@@ -0,0 +1,490 @@
+package convert
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"maps"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// LogFormat selects how ParseLogs reads each line.
+type LogFormat int
+
+// Log formats
+const (
+	LogAuto   LogFormat = iota // detect the format of each line
+	LogJSON                    // one JSON object per line (zap, zerolog, slog, bunyan, pino, logrus)
+	LogLogfmt                  // key=value pairs (Heroku, Go kit, slog text)
+	LogSyslog                  // RFC 5424 or RFC 3164 syslog, with or without a <PRI> header
+)
+
+// Normalised log levels, from least to most severe
+const (
+	LevelTrace = "trace"
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+	LevelFatal = "fatal"
+)
+
+var levelRank = map[string]int{LevelTrace: 1, LevelDebug: 2, LevelInfo: 3, LevelWarn: 4, LevelError: 5, LevelFatal: 6}
+
+// LogRecord is one parsed log entry.
+type LogRecord struct {
+	Line    int            `json:"line"`             // 1-based input line the entry starts on
+	Time    time.Time      `json:"time,omitzero"`    // zero when the entry has no recognisable timestamp
+	Level   string         `json:"level,omitempty"`  // one of the Level constants, or "" when unknown
+	Message string         `json:"message"`          // includes continuation lines such as stack traces
+	Fields  map[string]any `json:"fields,omitempty"` // remaining keys; syslog host, app, pid and msgid
+	Raw     string         `json:"raw,omitempty"`    // original line, with LogOptions.Raw
+}
+
+// LogOptions configures ParseLogs.
+type LogOptions struct {
+	// MinLevel drops entries below this level, e.g. LevelWarn for triage.
+	// Entries with an unknown level are kept.
+	MinLevel string
+	// Raw keeps the original line in each record
+	Raw bool
+	// Location interprets timestamps without a zone (default time.UTC)
+	Location *time.Location
+}
+
+// ParseLogs converts raw log lines into structured records.
+//
+// Input: log text, one entry per line (streaming)
+// Output: NDJSON, one LogRecord per entry
+// Behavior: STREAMING - JSON entries are written as they are read, other
+// entries once the next line shows they are complete
+//
+// Timestamp, level and message keys are recognised under their common names
+// (time/ts/@timestamp, level/lvl/severity, msg/message) and the rest become
+// Fields. Levels are normalised - "WARNING", "W", syslog severities and
+// bunyan/pino numbers all become the Level constants - and timestamps are
+// parsed from RFC 3339, common layouts and Unix seconds or milliseconds. Lines
+// that do not match the format keep their text as the message, with a level
+// guessed from keywords such as ERROR. Indented lines that follow a text or
+// syslog entry (stack traces, wrapped messages) are appended to its message.
+//
+// Example:
+//
+//	flow := calque.NewFlow().
+//		Use(convert.ParseLogs(convert.LogAuto, convert.LogOptions{MinLevel: convert.LevelWarn})).
+//		Use(prompt.Template("Triage this incident from the warnings and errors below:\n\n{{.Input}}")).
+//		Use(ai.Agent(client))
+//	err := flow.Run(ctx, logFile, &triage)
+func ParseLogs(format LogFormat, opts ...LogOptions) calque.Handler {
+	cfg := LogOptions{}
+	if len(opts) > 0 {
+		cfg = opts[0]
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	minRank := levelRank[NormalizeLevel(cfg.MinLevel)]
+
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		reader := bufio.NewReader(req.Data)
+		writer := bufio.NewWriter(res.Data)
+		encoder := json.NewEncoder(writer)
+
+		// text entries wait for the next line, which may continue them
+		var pending *LogRecord
+		write := func(record *LogRecord) error {
+			if rank := levelRank[record.Level]; rank > 0 && rank < minRank {
+				return nil
+			}
+			return encoder.Encode(record)
+		}
+		emit := func() error {
+			if pending == nil {
+				return nil
+			}
+			record := pending
+			pending = nil
+			return write(record)
+		}
+
+		for n := 1; ; n++ {
+			line, err := reader.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return err
+			}
+			line = strings.TrimRight(line, "\r\n")
+
+			switch {
+			case pending != nil && isContinuation(line):
+				pending.Message += "\n" + line
+				if cfg.Raw {
+					pending.Raw += "\n" + line
+				}
+			case strings.TrimSpace(line) == "":
+			default:
+				if werr := emit(); werr != nil {
+					return werr
+				}
+				record := ParseLogLine(line, format, cfg.Location)
+				record.Line = n
+				if cfg.Raw {
+					record.Raw = line
+				}
+				if strings.HasPrefix(strings.TrimSpace(line), "{") && format != LogLogfmt && format != LogSyslog {
+					if werr := write(&record); werr != nil {
+						return werr
+					}
+				} else {
+					pending = &record
+				}
+			}
+
+			if err == io.EOF {
+				if werr := emit(); werr != nil {
+					return werr
+				}
+				return writer.Flush()
+			}
+			if reader.Buffered() == 0 {
+				if werr := writer.Flush(); werr != nil {
+					return werr
+				}
+			}
+		}
+	})
+}
+
+// isContinuation reports lines that extend the previous entry
+func isContinuation(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") ||
+		strings.HasPrefix(line, "Caused by:") || strings.HasPrefix(line, "goroutine ")
+}
+
+// ParseLogLine parses a single log line. loc interprets timestamps without a
+// zone; nil means UTC. Lines that do not match format become a message-only
+// record with a guessed level.
+func ParseLogLine(line string, format LogFormat, loc *time.Location) LogRecord {
+	if loc == nil {
+		loc = time.UTC
+	}
+	trimmed := strings.TrimSpace(line)
+
+	if format == LogAuto || format == LogJSON {
+		if strings.HasPrefix(trimmed, "{") {
+			var fields map[string]any
+			if json.Unmarshal([]byte(trimmed), &fields) == nil {
+				return structuredRecord(fields, loc)
+			}
+		}
+	}
+	if format == LogAuto || format == LogSyslog {
+		if record, ok := parseSyslog(trimmed, loc); ok {
+			return record
+		}
+	}
+	if format == LogAuto || format == LogLogfmt {
+		if fields, ok := parseLogfmt(trimmed); ok {
+			return structuredRecord(fields, loc)
+		}
+	}
+	return textRecord(trimmed, loc)
+}
+
+var (
+	logTimeKeys    = []string{"time", "timestamp", "ts", "@timestamp", "t", "date", "datetime"}
+	logLevelKeys   = []string{"level", "lvl", "severity", "loglevel", "log.level", "levelname", "@level"}
+	logMessageKeys = []string{"msg", "message", "@message", "text", "event"}
+)
+
+// structuredRecord picks the well-known keys out of parsed JSON or logfmt fields
+func structuredRecord(fields map[string]any, loc *time.Location) LogRecord {
+	fields = maps.Clone(fields)
+	var record LogRecord
+	take := func(keys []string) (any, bool) {
+		for _, k := range keys {
+			if v, ok := fields[k]; ok {
+				delete(fields, k)
+				return v, true
+			}
+		}
+		return nil, false
+	}
+
+	if v, ok := take(logTimeKeys); ok {
+		record.Time = logTime(v, loc)
+	}
+	if v, ok := take(logLevelKeys); ok {
+		switch v := v.(type) {
+		case string:
+			record.Level = NormalizeLevel(v)
+		case float64:
+			record.Level = numericLevel(v)
+		}
+	}
+	if v, ok := take(logMessageKeys); ok {
+		if s, ok := v.(string); ok {
+			record.Message = s
+		} else {
+			data, _ := json.Marshal(v)
+			record.Message = string(data)
+		}
+	}
+	if len(fields) > 0 {
+		record.Fields = fields
+	}
+	return record
+}
+
+// NormalizeLevel maps a level name to one of the Level constants, or "" when
+// it is not recognised.
+func NormalizeLevel(level string) string {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "trace", "trc", "finest", "finer", "verbose", "v":
+		return LevelTrace
+	case "debug", "dbg", "d", "fine", "debug1", "debug2":
+		return LevelDebug
+	case "info", "inf", "i", "information", "informational", "notice", "config":
+		return LevelInfo
+	case "warn", "warning", "wrn", "w":
+		return LevelWarn
+	case "error", "err", "eror", "e", "severe":
+		return LevelError
+	case "fatal", "ftl", "f", "crit", "critical", "alert", "emerg", "emergency", "panic", "dpanic":
+		return LevelFatal
+	}
+	return ""
+}
+
+// numericLevel maps bunyan/pino levels (10 trace .. 60 fatal)
+func numericLevel(v float64) string {
+	switch {
+	case v >= 60:
+		return LevelFatal
+	case v >= 50:
+		return LevelError
+	case v >= 40:
+		return LevelWarn
+	case v >= 30:
+		return LevelInfo
+	case v >= 20:
+		return LevelDebug
+	case v >= 10:
+		return LevelTrace
+	}
+	return ""
+}
+
+var logTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05,999",
+	"2006/01/02 15:04:05.999999",
+	"02/Jan/2006:15:04:05 -0700",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// logTime parses a timestamp value: a string in a known layout, or Unix seconds or milliseconds
+func logTime(v any, loc *time.Location) time.Time {
+	switch v := v.(type) {
+	case float64:
+		return unixTime(v)
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return unixTime(f)
+		}
+		for _, layout := range logTimeLayouts {
+			if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func unixTime(f float64) time.Time {
+	if f > 1e12 { // milliseconds
+		f /= 1000
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+}
+
+// parseLogfmt parses key=value pairs; a line needs at least two pairs, or
+// a level or message key, to count as logfmt
+func parseLogfmt(line string) (map[string]any, bool) {
+	fields := map[string]any{}
+	pairs := 0
+	for i := 0; i < len(line); {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' && line[i] != '"' {
+			i++
+		}
+		key := line[start:i]
+		if key == "" {
+			return nil, false
+		}
+		if i >= len(line) || line[i] != '=' {
+			if i < len(line) && line[i] == '"' {
+				return nil, false
+			}
+			fields[key] = true
+			continue
+		}
+		i++ // '='
+
+		var value string
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, false
+			}
+			unquoted, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				unquoted = line[i+1 : end]
+			}
+			value, i = unquoted, end+1
+		} else {
+			start := i
+			for i < len(line) && line[i] != ' ' {
+				i++
+			}
+			value = line[start:i]
+		}
+		fields[key] = value
+		pairs++
+	}
+
+	_, hasLevel := fields["level"]
+	_, hasMsg := fields["msg"]
+	if pairs >= 2 || (pairs == 1 && (hasLevel || hasMsg)) {
+		return fields, true
+	}
+	return nil, false
+}
+
+var (
+	syslogPRI  = regexp.MustCompile(`^<(\d{1,3})>`)
+	syslog5424 = regexp.MustCompile(`^1 (\S+) (\S+) (\S+) (\S+) (\S+) (-|(?:\[(?:[^\]\\]|\\.)*\])+) ?(.*)$`)
+	syslog3164 = regexp.MustCompile(`^([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}) (\S+) ([^\s:\[]+)(?:\[(\d+)\])?: ?(.*)$`)
+)
+
+// syslogSeverities maps the PRI severity (PRI % 8) to a level
+var syslogSeverities = []string{LevelFatal, LevelFatal, LevelFatal, LevelError, LevelWarn, LevelInfo, LevelInfo, LevelDebug}
+
+func parseSyslog(line string, loc *time.Location) (LogRecord, bool) {
+	var record LogRecord
+	hasPRI := false
+	if m := syslogPRI.FindStringSubmatch(line); m != nil {
+		pri, _ := strconv.Atoi(m[1])
+		if pri > 191 {
+			return record, false
+		}
+		hasPRI = true
+		record.Level = syslogSeverities[pri%8]
+		line = line[len(m[0]):]
+	}
+
+	fields := map[string]any{}
+	set := func(key, value string) {
+		if value != "" && value != "-" {
+			fields[key] = value
+		}
+	}
+	if m := syslog5424.FindStringSubmatch(line); m != nil && hasPRI {
+		record.Time = logTime(m[1], loc)
+		set("host", m[2])
+		set("app", m[3])
+		set("pid", m[4])
+		set("msgid", m[5])
+		set("structured_data", m[6])
+		record.Message = strings.TrimPrefix(m[7], "\ufeff")
+	} else if m := syslog3164.FindStringSubmatch(line); m != nil {
+		record.Time = syslogTime(m[1], loc)
+		set("host", m[2])
+		set("app", m[3])
+		set("pid", m[4])
+		record.Message = m[5]
+	} else {
+		return LogRecord{}, false
+	}
+
+	if record.Level == "" {
+		record.Level = guessLevel(record.Message)
+	}
+	if len(fields) > 0 {
+		record.Fields = fields
+	}
+	return record, true
+}
+
+// syslogTime parses an RFC 3164 timestamp, which has no year: the most
+// recent such time not more than a day ahead is used
+func syslogTime(stamp string, loc *time.Location) time.Time {
+	t, err := time.ParseInLocation("Jan _2 15:04:05", stamp, loc)
+	if err != nil {
+		return time.Time{}
+	}
+	now := time.Now().In(loc)
+	t = t.AddDate(now.Year(), 0, 0)
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
+
+var (
+	textTimestamp = regexp.MustCompile(`^\[?(\d{4}[-/]\d{2}[-/]\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?)\]?\s*`)
+	textLevel     = regexp.MustCompile(`^\[?(TRACE|DEBUG|INFO|NOTICE|WARN|WARNING|ERROR|ERR|FATAL|CRITICAL|CRIT|PANIC|trace|debug|info|warn|warning|error|fatal|panic)\]?:?\s+`)
+	levelWords    = regexp.MustCompile(`\b(FATAL|PANIC|CRITICAL|ERROR|WARN|WARNING|DEBUG|TRACE)\b|\w(?:Exception|Error)\b`)
+)
+
+// textRecord parses a plain-text line such as "2025-03-01 09:30:00 ERROR failed"
+func textRecord(line string, loc *time.Location) LogRecord {
+	var record LogRecord
+	if m := textTimestamp.FindStringSubmatch(line); m != nil {
+		stamp := strings.Replace(m[1], "/", "-", 2)
+		if t := logTime(stamp, loc); !t.IsZero() {
+			record.Time = t
+			line = line[len(m[0]):]
+		}
+	}
+	if m := textLevel.FindStringSubmatch(line); m != nil {
+		record.Level = NormalizeLevel(m[1])
+		line = line[len(m[0]):]
+	} else {
+		record.Level = guessLevel(line)
+	}
+	record.Message = line
+	return record
+}
+
+// guessLevel looks for a severity keyword in free text
+func guessLevel(message string) string {
+	m := levelWords.FindStringSubmatch(message)
+	if m == nil {
+		return ""
+	}
+	if m[1] == "" { // an exception or error type name
+		return LevelError
+	}
+	return NormalizeLevel(m[1])
+}
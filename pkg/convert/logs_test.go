@@ -0,0 +1,150 @@
+package convert
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestParseLogLine(t *testing.T) {
+	ts := time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		line    string
+		format  LogFormat
+		time    time.Time
+		level   string
+		message string
+		fields  map[string]any
+	}{
+		{
+			name: "json", line: `{"time":"2025-03-01T09:30:00Z","level":"WARNING","msg":"disk almost full","disk":"/dev/sda1","pct":91}`,
+			time: ts, level: LevelWarn, message: "disk almost full", fields: map[string]any{"disk": "/dev/sda1", "pct": float64(91)},
+		},
+		{
+			name: "pino numeric level and epoch millis", line: `{"level":50,"time":1740821400000,"msg":"request failed"}`,
+			time: ts, level: LevelError, message: "request failed",
+		},
+		{
+			name: "logfmt", line: `ts=2025-03-01T09:30:00Z level=error msg="upstream timeout" service=api retry`,
+			time: ts, level: LevelError, message: "upstream timeout", fields: map[string]any{"service": "api", "retry": true},
+		},
+		{
+			name: "syslog 5424", line: `<165>1 2025-03-01T09:30:00Z web01 nginx 2211 ID47 [meta seq="1"] upstream timed out`,
+			time: ts, level: LevelInfo, message: "upstream timed out",
+			fields: map[string]any{"host": "web01", "app": "nginx", "pid": "2211", "msgid": "ID47", "structured_data": `[meta seq="1"]`},
+		},
+		{
+			name: "syslog 3164 with PRI", line: `<11>Mar  1 09:30:00 db01 postgres[812]: connection refused`,
+			level: LevelError, message: "connection refused", fields: map[string]any{"host": "db01", "app": "postgres", "pid": "812"},
+		},
+		{
+			name: "syslog file line", line: `Mar  1 09:30:00 web01 sshd[99]: Failed password for root`, format: LogSyslog,
+			message: "Failed password for root", fields: map[string]any{"host": "web01", "app": "sshd", "pid": "99"},
+		},
+		{
+			name: "plain text", line: `2025-03-01 09:30:00,000 [ERROR] payment declined`,
+			time: ts, level: LevelError, message: "payment declined",
+		},
+		{
+			name: "guessed level", line: `java.lang.IllegalStateException: boom`,
+			level: LevelError, message: "java.lang.IllegalStateException: boom",
+		},
+		{
+			name: "format mismatch keeps text", line: `starting worker pool`, format: LogJSON,
+			message: "starting worker pool",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := ParseLogLine(tt.line, tt.format, nil)
+			if tt.time.IsZero() {
+				if tt.name == "syslog 3164 with PRI" && (r.Time.Month() != time.March || r.Time.Day() != 1 || r.Time.Hour() != 9) {
+					t.Errorf("time = %v, want March 1 09:30 of a recent year", r.Time)
+				}
+			} else if !r.Time.Equal(tt.time) {
+				t.Errorf("time = %v, want %v", r.Time, tt.time)
+			}
+			if r.Level != tt.level || r.Message != tt.message {
+				t.Errorf("level, message = %q, %q; want %q, %q", r.Level, r.Message, tt.level, tt.message)
+			}
+			got, _ := json.Marshal(r.Fields)
+			want, _ := json.Marshal(tt.fields)
+			if string(got) != string(want) {
+				t.Errorf("fields = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestParseLogs(t *testing.T) {
+	input := strings.Join([]string{
+		`2025-03-01T09:30:00Z INFO starting`,
+		`2025-03-01T09:30:01Z ERROR handler panicked`,
+		`goroutine 12 [running]:`,
+		`	main.handle(0x1)`,
+		``,
+		`{"level":"debug","msg":"cache miss"}`,
+		`{"level":"warn","msg":"slow query","ms":1200}`,
+		`level=fatal msg="out of memory"`,
+	}, "\n")
+
+	var out string
+	err := calque.NewFlow().Use(ParseLogs(LogAuto, LogOptions{MinLevel: "warning", Raw: true})).Run(context.Background(), input, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var records []LogRecord
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var r LogRecord
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", line, err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3: %s", len(records), out)
+	}
+	if r := records[0]; r.Line != 2 || r.Message != "handler panicked\ngoroutine 12 [running]:\n\tmain.handle(0x1)" || !strings.HasPrefix(r.Raw, "2025-03-01T09:30:01Z ERROR") {
+		t.Errorf("stack trace record = %+v", r)
+	}
+	if r := records[1]; r.Line != 7 || r.Level != LevelWarn || r.Fields["ms"] != float64(1200) {
+		t.Errorf("json record = %+v", r)
+	}
+	if r := records[2]; r.Level != LevelFatal || r.Message != "out of memory" {
+		t.Errorf("logfmt record = %+v", r)
+	}
+}
+
+func TestParseLogsStreaming(t *testing.T) {
+	pr, pw := io.Pipe()
+	outR, outW := io.Pipe()
+	go func() {
+		_ = ParseLogs(LogJSON).ServeFlow(calque.NewRequest(context.Background(), pr), calque.NewResponse(outW))
+		_ = outW.Close()
+	}()
+
+	// a JSON entry is written before the next line arrives
+	_, _ = pw.Write([]byte(`{"level":"info","msg":"first"}` + "\n"))
+	line, err := bufio.NewReader(outR).ReadString('\n')
+	if err != nil || !strings.Contains(line, `"first"`) {
+		t.Fatalf("first record = %q, %v", line, err)
+	}
+	_ = pw.Close()
+}
+
+func TestNormalizeLevel(t *testing.T) {
+	for in, want := range map[string]string{"WARNING": LevelWarn, "Err": LevelError, "crit": LevelFatal, "notice": LevelInfo, "dbg": LevelDebug, "loud": ""} {
+		if got := NormalizeLevel(in); got != want {
+			t.Errorf("NormalizeLevel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
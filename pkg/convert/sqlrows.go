@@ -0,0 +1,275 @@
+package convert
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultMaxCellLength is the default RowsOptions.MaxCellLength for markdown tables.
+const DefaultMaxCellLength = 200
+
+// RowsOptions configures RowsToMarkdown and RowsToJSON.
+type RowsOptions struct {
+	// MaxRows stops after this many rows, noting the truncation in markdown
+	// (0 = all rows)
+	MaxRows int
+	// MaxCellLength truncates longer markdown cells with "…"
+	// (0 = DefaultMaxCellLength, negative = no limit)
+	MaxCellLength int
+	// NullText is how markdown shows NULL (default "NULL")
+	NullText string
+}
+
+// RowsInputConverter streams a database/sql result set.
+type RowsInputConverter struct {
+	rows     *sql.Rows
+	opts     RowsOptions
+	markdown bool
+}
+
+// RowsToMarkdown creates an input converter rendering query results as a markdown table.
+//
+// Input: *sql.Rows from a query; closed once written
+// Output: calque.InputConverter for pipeline input position
+// Behavior: STREAMING - rows are written as they are scanned
+//
+// Models read markdown tables reliably, so "query then explain" flows can
+// pass results straight to an agent. Pipes and line breaks in values are
+// escaped, long cells are truncated, bytes that are valid UTF-8 are shown
+// as text and times use RFC 3339.
+//
+// Example usage:
+//
+//	rows, err := db.QueryContext(ctx, "SELECT region, SUM(total) FROM orders GROUP BY region")
+//	if err != nil {
+//		return err
+//	}
+//	var summary string
+//	err = calque.NewFlow().
+//		Use(prompt.Template("Explain these sales figures:\n\n{{.Input}}")).
+//		Use(ai.Agent(client)).
+//		Run(ctx, convert.RowsToMarkdown(rows), &summary)
+func RowsToMarkdown(rows *sql.Rows, opts ...RowsOptions) calque.InputConverter {
+	return newRowsConverter(rows, true, opts)
+}
+
+// RowsToJSON creates an input converter rendering query results as a JSON
+// array with one object per row, keyed by column name in column order.
+//
+// Input: *sql.Rows from a query; closed once written
+// Output: calque.InputConverter for pipeline input position
+// Behavior: STREAMING - rows are encoded as they are scanned
+//
+// Bytes that are valid UTF-8 become strings, other bytes base64 strings,
+// and NULL becomes null. RowsOptions.MaxRows cuts the array short silently.
+//
+// Example usage:
+//
+//	rows, err := db.QueryContext(ctx, "SELECT id, status FROM tickets WHERE open")
+//	if err != nil {
+//		return err
+//	}
+//	err = flow.Run(ctx, convert.RowsToJSON(rows), &answer)
+func RowsToJSON(rows *sql.Rows, opts ...RowsOptions) calque.InputConverter {
+	return newRowsConverter(rows, false, opts)
+}
+
+func newRowsConverter(rows *sql.Rows, markdown bool, opts []RowsOptions) *RowsInputConverter {
+	c := &RowsInputConverter{rows: rows, markdown: markdown}
+	if len(opts) > 0 {
+		c.opts = opts[0]
+	}
+	if c.opts.MaxCellLength == 0 {
+		c.opts.MaxCellLength = DefaultMaxCellLength
+	}
+	if c.opts.NullText == "" {
+		c.opts.NullText = "NULL"
+	}
+	return c
+}
+
+// ToReader scans the rows in the background, streaming the rendered result.
+func (c *RowsInputConverter) ToReader() (io.Reader, error) {
+	ctx := context.Background()
+	if c.rows == nil {
+		return nil, calque.NewErr(ctx, "sql rows are nil")
+	}
+	columns, err := c.rows.Columns()
+	if err != nil {
+		_ = c.rows.Close()
+		return nil, calque.WrapErr(ctx, err, "failed to read result columns")
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer func() { _ = c.rows.Close() }()
+		w := bufio.NewWriter(pw)
+		var err error
+		if c.markdown {
+			err = c.writeMarkdown(w, columns)
+		} else {
+			err = c.writeJSON(w, columns)
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
+
+// scanRows calls fn with the values of each row, up to MaxRows; more
+// reports whether rows were left unread
+func (c *RowsInputConverter) scanRows(columns []string, fn func(values []any) error) (more bool, err error) {
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	n := 0
+	for c.rows.Next() {
+		if c.opts.MaxRows > 0 && n == c.opts.MaxRows {
+			return true, nil
+		}
+		if err := c.rows.Scan(dest...); err != nil {
+			return false, calque.WrapErr(context.Background(), err, "failed to scan row")
+		}
+		if err := fn(values); err != nil {
+			return false, err
+		}
+		n++
+	}
+	if err := c.rows.Err(); err != nil {
+		return false, calque.WrapErr(context.Background(), err, "failed to read rows")
+	}
+	return false, nil
+}
+
+func (c *RowsInputConverter) writeMarkdown(w *bufio.Writer, columns []string) error {
+	cells := make([]string, len(columns))
+	for i, name := range columns {
+		cells[i] = escapeCell(name)
+	}
+	writeMarkdownRow(w, cells)
+	for i := range cells {
+		cells[i] = "---"
+	}
+	writeMarkdownRow(w, cells)
+
+	rows := 0
+	more, err := c.scanRows(columns, func(values []any) error {
+		for i, v := range values {
+			if v == nil {
+				cells[i] = c.opts.NullText
+			} else {
+				cells[i] = c.markdownCell(formatValue(v))
+			}
+		}
+		writeMarkdownRow(w, cells)
+		rows++
+		if rows%100 == 0 {
+			return w.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case more:
+		_, err = fmt.Fprintf(w, "\n_Truncated after %d rows._\n", rows)
+	case rows == 0:
+		_, err = w.WriteString("\n_No rows._\n")
+	}
+	return err
+}
+
+func writeMarkdownRow(w *bufio.Writer, cells []string) {
+	_, _ = w.WriteString("| ")
+	_, _ = w.WriteString(strings.Join(cells, " | "))
+	_, _ = w.WriteString(" |\n")
+}
+
+// markdownCell truncates and escapes a value for a table cell
+func (c *RowsInputConverter) markdownCell(s string) string {
+	if c.opts.MaxCellLength > 0 && utf8.RuneCountInString(s) > c.opts.MaxCellLength {
+		s = string([]rune(s)[:c.opts.MaxCellLength]) + "…"
+	}
+	return escapeCell(s)
+}
+
+var cellEscaper = strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ", "\r", " ")
+
+// escapeCell keeps s on one line and inside its cell
+func escapeCell(s string) string {
+	return cellEscaper.Replace(s)
+}
+
+func (c *RowsInputConverter) writeJSON(w *bufio.Writer, columns []string) error {
+	keys := make([][]byte, len(columns))
+	for i, name := range columns {
+		key, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		keys[i] = key
+	}
+
+	if err := w.WriteByte('['); err != nil {
+		return err
+	}
+	first := true
+	_, err := c.scanRows(columns, func(values []any) error {
+		if !first {
+			_ = w.WriteByte(',')
+		}
+		first = false
+		_ = w.WriteByte('{')
+		for i, v := range values {
+			if i > 0 {
+				_ = w.WriteByte(',')
+			}
+			_, _ = w.Write(keys[i])
+			_ = w.WriteByte(':')
+			if b, ok := v.([]byte); ok && utf8.Valid(b) {
+				v = string(b)
+			}
+			value, err := json.Marshal(v)
+			if err != nil {
+				return calque.WrapErr(context.Background(), err, fmt.Sprintf("failed to encode column %s", columns[i]))
+			}
+			_, _ = w.Write(value)
+		}
+		return w.WriteByte('}')
+	})
+	if err != nil {
+		return err
+	}
+	return w.WriteByte(']')
+}
+
+// formatValue renders a scanned value as text
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+		return fmt.Sprintf("0x%x", v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
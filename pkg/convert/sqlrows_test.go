@@ -0,0 +1,172 @@
+package convert
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDriver serves one canned result set per query, keyed by the query text
+type fakeDriver struct{}
+
+type fakeConn struct{}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+	err     error
+}
+
+var fakeResults = map[string]func() *fakeRows{
+	"orders": func() *fakeRows {
+		return &fakeRows{columns: []string{"id", "customer", "total", "note", "placed"}, values: [][]driver.Value{
+			{int64(1), "Alice", 12.5, nil, time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC)},
+			{int64(2), []byte("Bob | Co"), 99.0, "line one\nline two", time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)},
+		}}
+	},
+	"empty": func() *fakeRows { return &fakeRows{columns: []string{"id"}} },
+	"broken": func() *fakeRows {
+		return &fakeRows{columns: []string{"id"}, values: [][]driver.Value{{int64(1)}}, err: errors.New("connection lost")}
+	},
+}
+
+func init() { sql.Register("convertfake", fakeDriver{}) }
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt(query), nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type fakeStmt string
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return 0 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return nil, errors.New("not supported") }
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return fakeResults[string(s)](), nil
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		if r.err != nil {
+			return r.err
+		}
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func queryRows(t *testing.T, query string) *sql.Rows {
+	t.Helper()
+	db, err := sql.Open("convertfake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	rows, err := db.QueryContext(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func readConverter(t *testing.T, c interface{ ToReader() (io.Reader, error) }) (string, error) {
+	t.Helper()
+	r, err := c.ToReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(r)
+	return string(out), err
+}
+
+func TestRowsToMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		opts     RowsOptions
+		expected string
+	}{
+		{
+			name:  "table",
+			query: "orders",
+			expected: "| id | customer | total | note | placed |\n" +
+				"| --- | --- | --- | --- | --- |\n" +
+				"| 1 | Alice | 12.5 | NULL | 2025-03-01T09:30:00Z |\n" +
+				"| 2 | Bob \\| Co | 99 | line one line two | 2025-03-02T00:00:00Z |\n",
+		},
+		{
+			name:  "max rows and cell length",
+			query: "orders",
+			opts:  RowsOptions{MaxRows: 1, MaxCellLength: 3, NullText: "-"},
+			expected: "| id | customer | total | note | placed |\n" +
+				"| --- | --- | --- | --- | --- |\n" +
+				"| 1 | Ali… | 12.… | - | 202… |\n" +
+				"\n_Truncated after 1 rows._\n",
+		},
+		{
+			name:     "no rows",
+			query:    "empty",
+			expected: "| id |\n| --- |\n\n_No rows._\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := readConverter(t, RowsToMarkdown(queryRows(t, tt.query), tt.opts))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tt.expected {
+				t.Errorf("RowsToMarkdown() =\n%s\nwant\n%s", out, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRowsToJSON(t *testing.T) {
+	out, err := readConverter(t, RowsToJSON(queryRows(t, "orders")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, `[{"id":1,"customer":"Alice","total":12.5,"note":null,`) {
+		t.Errorf("column order or values not kept: %s", out)
+	}
+	var rows []map[string]any
+	if err := json.Unmarshal([]byte(out), &rows); err != nil {
+		t.Fatalf("invalid JSON %s: %v", out, err)
+	}
+	if len(rows) != 2 || rows[1]["customer"] != "Bob | Co" || rows[1]["note"] != "line one\nline two" {
+		t.Errorf("rows = %v", rows)
+	}
+
+	empty, err := readConverter(t, RowsToJSON(queryRows(t, "empty")))
+	if err != nil || empty != "[]" {
+		t.Errorf("empty = %q, err = %v", empty, err)
+	}
+
+	limited, err := readConverter(t, RowsToJSON(queryRows(t, "orders"), RowsOptions{MaxRows: 1}))
+	if err != nil || strings.Count(limited, `"id"`) != 1 {
+		t.Errorf("limited = %q, err = %v", limited, err)
+	}
+}
+
+func TestRowsError(t *testing.T) {
+	if _, err := readConverter(t, RowsToJSON(queryRows(t, "broken"))); err == nil || !strings.Contains(err.Error(), "connection lost") {
+		t.Errorf("error = %v, want the driver error", err)
+	}
+	if _, err := RowsToMarkdown(nil).ToReader(); err == nil {
+		t.Error("expected an error for nil rows")
+	}
+}
@@ -166,6 +166,12 @@ type SSEConverter struct {
 	keepAliveEnabled  bool
 	keepAliveCancel   context.CancelFunc
 	mu                sync.Mutex
+
+	// Event ID configuration, see WithEventIDs
+	idsEnabled bool
+	replay     SSEReplayStore
+	stream     string
+	lastID     int64
 }
 
 // Close forcefully terminates the SSE connection and releases resources.
@@ -292,6 +298,32 @@ func (s *SSEConverter) WithKeepAlive(interval time.Duration) *SSEConverter {
 	return s
 }
 
+// WithEventIDs numbers events and records them for Last-Event-ID replay.
+//
+// Input: replay store (nil only numbers events), stream ID of the run
+// Output: *SSEConverter for chaining
+// Behavior: Sends "id: N" with every event, N increasing from 1, and appends
+// each event to store under stream
+//
+// A client reconnecting with a Last-Event-ID header is served by ResumeSSE
+// from the same store and stream, so answers streamed while it was offline
+// are not lost. Use a unique stream ID per run.
+//
+// Example:
+//
+//	replay := convert.NewSSEReplayBuffer()
+//	sse := convert.ToSSE(w).WithEventIDs(replay, runID)
+//	err := sse.FromReader(answer)
+func (s *SSEConverter) WithEventIDs(store SSEReplayStore, stream string) *SSEConverter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.idsEnabled = true
+	s.replay = store
+	s.stream = stream
+	return s
+}
+
 // FromReader implements OutputConverter interface for streaming SSE responses.
 //
 // Input: io.Reader data source
@@ -561,7 +593,18 @@ func (s *SSEConverter) writeSSEEvent(event string, data any) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, err = fmt.Fprintf(s.writer, "event: %s\ndata: %s\n\n", event, jsonData)
+	if s.idsEnabled {
+		s.lastID++
+		if s.replay != nil {
+			stored := SSEStoredEvent{ID: s.lastID, Event: event, Data: jsonData}
+			if err := s.replay.Append(context.Background(), s.stream, stored); err != nil {
+				return calque.WrapErr(context.Background(), err, "failed to store SSE event")
+			}
+		}
+		_, err = fmt.Fprintf(s.writer, "id: %d\nevent: %s\ndata: %s\n\n", s.lastID, event, jsonData)
+	} else {
+		_, err = fmt.Fprintf(s.writer, "event: %s\ndata: %s\n\n", event, jsonData)
+	}
 	if err != nil {
 		return calque.WrapErr(context.Background(), err, "failed to write SSE event")
 	}
@@ -0,0 +1,228 @@
+package convert
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// SSE replay defaults, used for zero SSEReplayConfig fields.
+const (
+	DefaultSSEReplayEvents  = 10000                  // events kept per stream
+	DefaultSSEReplayStreams = 1000                   // streams kept
+	DefaultSSEResumePoll    = 100 * time.Millisecond // store polling while a stream is live
+)
+
+var (
+	// ErrSSEEventsExpired is returned when events a client has not seen were
+	// already dropped from the replay store.
+	ErrSSEEventsExpired = errors.New("SSE events expired from the replay store")
+
+	// ErrSSEStreamNotFound is returned for a stream the replay store has no
+	// record of.
+	ErrSSEStreamNotFound = errors.New("SSE stream not found in the replay store")
+)
+
+// SSEStoredEvent is an event recorded by SSEConverter.WithEventIDs.
+type SSEStoredEvent struct {
+	ID    int64           `json:"id"`
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// SSEReplayStore keeps the numbered events of streams for reconnecting clients.
+//
+// Implementations backed by Redis or a database let any server instance
+// resume a stream; NewSSEReplayBuffer keeps them in memory.
+type SSEReplayStore interface {
+	// Append records the next event of a stream; IDs increase by one from 1
+	Append(ctx context.Context, stream string, event SSEStoredEvent) error
+
+	// After returns the stream's events with IDs greater than lastID, in
+	// order. It returns ErrSSEEventsExpired if some of them were dropped,
+	// including when the whole stream was dropped after lastID, and
+	// ErrSSEStreamNotFound for a stream it never recorded.
+	After(ctx context.Context, stream string, lastID int64) ([]SSEStoredEvent, error)
+}
+
+// SSEReplayConfig configures NewSSEReplayBuffer.
+type SSEReplayConfig struct {
+	MaxEvents  int // newest events kept per stream (0 = DefaultSSEReplayEvents)
+	MaxStreams int // streams kept, least recently written dropped first (0 = DefaultSSEReplayStreams)
+}
+
+// SSEReplayBuffer is an in-memory SSEReplayStore.
+type SSEReplayBuffer struct {
+	cfg SSEReplayConfig
+
+	mu      sync.Mutex
+	streams map[string]*list.Element // values are *replayStream
+	order   *list.List               // most recently written first
+}
+
+type replayStream struct {
+	name   string
+	events []SSEStoredEvent
+}
+
+// NewSSEReplayBuffer creates an in-memory replay store.
+//
+// Example:
+//
+//	replay := convert.NewSSEReplayBuffer(convert.SSEReplayConfig{MaxEvents: 5000})
+func NewSSEReplayBuffer(config ...SSEReplayConfig) *SSEReplayBuffer {
+	cfg := SSEReplayConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.MaxEvents <= 0 {
+		cfg.MaxEvents = DefaultSSEReplayEvents
+	}
+	if cfg.MaxStreams <= 0 {
+		cfg.MaxStreams = DefaultSSEReplayStreams
+	}
+	return &SSEReplayBuffer{cfg: cfg, streams: make(map[string]*list.Element), order: list.New()}
+}
+
+// Append implements SSEReplayStore.
+func (b *SSEReplayBuffer) Append(_ context.Context, stream string, event SSEStoredEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	elem, ok := b.streams[stream]
+	if ok {
+		b.order.MoveToFront(elem)
+	} else {
+		elem = b.order.PushFront(&replayStream{name: stream})
+		b.streams[stream] = elem
+		for b.order.Len() > b.cfg.MaxStreams {
+			oldest := b.order.Back()
+			b.order.Remove(oldest)
+			delete(b.streams, oldest.Value.(*replayStream).name)
+		}
+	}
+
+	rs := elem.Value.(*replayStream)
+	rs.events = append(rs.events, event)
+	if extra := len(rs.events) - b.cfg.MaxEvents; extra > 0 {
+		rs.events = append(rs.events[:0:0], rs.events[extra:]...)
+	}
+	return nil
+}
+
+// After implements SSEReplayStore. A stream missing when lastID is above 0
+// was evicted or deleted, and is reported as ErrSSEEventsExpired.
+func (b *SSEReplayBuffer) After(_ context.Context, stream string, lastID int64) ([]SSEStoredEvent, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	elem, ok := b.streams[stream]
+	if !ok {
+		if lastID > 0 {
+			return nil, ErrSSEEventsExpired
+		}
+		return nil, ErrSSEStreamNotFound
+	}
+	events := elem.Value.(*replayStream).events
+	if len(events) > 0 && events[0].ID > lastID+1 {
+		return nil, ErrSSEEventsExpired
+	}
+	for i, ev := range events {
+		if ev.ID > lastID {
+			return append([]SSEStoredEvent(nil), events[i:]...), nil
+		}
+	}
+	return nil, nil
+}
+
+// Delete drops a stream, e.g. once its run is acknowledged by the client.
+func (b *SSEReplayBuffer) Delete(stream string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if elem, ok := b.streams[stream]; ok {
+		b.order.Remove(elem)
+		delete(b.streams, stream)
+	}
+}
+
+// ResumeSSE serves a reconnecting client the events of stream it has not seen.
+//
+// Input: HTTP response writer and request, replay store, stream ID
+// Output: error if the store fails, events expired or the client left
+// Behavior: STREAMING - replays events after the request's Last-Event-ID
+// header (all events without one), then follows the stream until its
+// completion or error event
+//
+// While the run is still streaming, the store is polled every
+// DefaultSSEResumePoll for new events. Events keep their original IDs, so
+// the client can reconnect again at any point. Expired events and unknown
+// streams are reported to the client as an error event.
+//
+// Example:
+//
+//	http.HandleFunc("/runs/{id}/events", func(w http.ResponseWriter, r *http.Request) {
+//		if err := convert.ResumeSSE(w, r, replay, r.PathValue("id")); err != nil {
+//			log.Printf("resume failed: %v", err)
+//		}
+//	})
+func ResumeSSE(w http.ResponseWriter, r *http.Request, store SSEReplayStore, stream string) error {
+	sse := ToSSE(w)
+	ctx := r.Context()
+
+	var lastID int64
+	if header := strings.TrimSpace(r.Header.Get("Last-Event-ID")); header != "" {
+		id, err := strconv.ParseInt(header, 10, 64)
+		if err != nil || id < 0 {
+			return calque.InvalidInput(calque.NewErr(ctx, fmt.Sprintf("invalid Last-Event-ID %q", header)))
+		}
+		lastID = id
+	}
+
+	ticker := time.NewTicker(DefaultSSEResumePoll)
+	defer ticker.Stop()
+	for {
+		events, err := store.After(ctx, stream, lastID)
+		if err != nil {
+			if errors.Is(err, ErrSSEEventsExpired) || errors.Is(err, ErrSSEStreamNotFound) {
+				_ = sse.sendError(err)
+			}
+			return calque.WrapErr(ctx, err, "failed to replay SSE events")
+		}
+		for _, ev := range events {
+			if err := sse.writeStoredEvent(ev); err != nil {
+				return err
+			}
+			lastID = ev.ID
+			if ev.Event == "completion" || ev.Event == "error" {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeStoredEvent writes a recorded event with its original ID
+func (s *SSEConverter) writeStoredEvent(ev SSEStoredEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := fmt.Fprintf(s.writer, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Event, ev.Data); err != nil {
+		return calque.WrapErr(context.Background(), err, "failed to write SSE event")
+	}
+	s.flusher.Flush()
+	return nil
+}
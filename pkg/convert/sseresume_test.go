@@ -0,0 +1,247 @@
+package convert
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var sseIDPattern = regexp.MustCompile(`(?m)^id: (\d+)$`)
+
+func sseIDs(body string) []string {
+	var ids []string
+	for _, m := range sseIDPattern.FindAllStringSubmatch(body, -1) {
+		ids = append(ids, m[1])
+	}
+	return ids
+}
+
+func TestSSEConverter_WithEventIDs(t *testing.T) {
+	w := newMockResponseWriter()
+	replay := NewSSEReplayBuffer()
+
+	if err := ToSSE(w).WithEventIDs(replay, "run-1").FromReader(strings.NewReader("one two three")); err != nil {
+		t.Fatalf("FromReader() error = %v", err)
+	}
+
+	body := w.Body.String()
+	if got := strings.Join(sseIDs(body), ","); got != "1,2,3,4" {
+		t.Errorf("event IDs = %s, want 1,2,3,4", got)
+	}
+
+	stored, err := replay.After(context.Background(), "run-1", 0)
+	if err != nil {
+		t.Fatalf("After() error = %v", err)
+	}
+	if len(stored) != 4 {
+		t.Fatalf("stored %d events, want 4", len(stored))
+	}
+	if stored[3].ID != 4 || stored[3].Event != testCompletion {
+		t.Errorf("last stored event = %+v, want completion with ID 4", stored[3])
+	}
+	if events := parseSSEEvents(t, body); len(events) != 4 || events[0].Data != "one " {
+		t.Errorf("events = %+v", events)
+	}
+}
+
+func TestSSEConverter_WithoutEventIDs(t *testing.T) {
+	w := newMockResponseWriter()
+	if err := ToSSE(w).FromReader(strings.NewReader("hello")); err != nil {
+		t.Fatalf("FromReader() error = %v", err)
+	}
+	if ids := sseIDs(w.Body.String()); len(ids) != 0 {
+		t.Errorf("unexpected event IDs %v", ids)
+	}
+}
+
+func TestSSEReplayBuffer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("after", func(t *testing.T) {
+		b := NewSSEReplayBuffer()
+		for id := int64(1); id <= 3; id++ {
+			_ = b.Append(ctx, "s", SSEStoredEvent{ID: id, Event: testEvent, Data: []byte(`{}`)})
+		}
+		events, err := b.After(ctx, "s", 1)
+		if err != nil || len(events) != 2 || events[0].ID != 2 {
+			t.Errorf("After(1) = %+v, %v", events, err)
+		}
+		if events, _ := b.After(ctx, "s", 3); len(events) != 0 {
+			t.Errorf("After(3) = %+v, want none", events)
+		}
+		if events, err := b.After(ctx, "missing", 0); len(events) != 0 || !errors.Is(err, ErrSSEStreamNotFound) {
+			t.Errorf("unknown stream = %+v, %v, want ErrSSEStreamNotFound", events, err)
+		}
+	})
+
+	t.Run("expired events", func(t *testing.T) {
+		b := NewSSEReplayBuffer(SSEReplayConfig{MaxEvents: 2})
+		for id := int64(1); id <= 5; id++ {
+			_ = b.Append(ctx, "s", SSEStoredEvent{ID: id, Event: testEvent, Data: []byte(`{}`)})
+		}
+		if _, err := b.After(ctx, "s", 2); !errors.Is(err, ErrSSEEventsExpired) {
+			t.Errorf("After(2) error = %v, want ErrSSEEventsExpired", err)
+		}
+		if events, err := b.After(ctx, "s", 3); err != nil || len(events) != 2 {
+			t.Errorf("After(3) = %+v, %v", events, err)
+		}
+	})
+
+	t.Run("evicts least recently written stream", func(t *testing.T) {
+		b := NewSSEReplayBuffer(SSEReplayConfig{MaxStreams: 2})
+		ev := SSEStoredEvent{ID: 1, Event: testEvent, Data: []byte(`{}`)}
+		_ = b.Append(ctx, "a", ev)
+		_ = b.Append(ctx, "b", ev)
+		_ = b.Append(ctx, "a", SSEStoredEvent{ID: 2, Event: testEvent, Data: []byte(`{}`)})
+		_ = b.Append(ctx, "c", ev)
+
+		if events, err := b.After(ctx, "b", 1); len(events) != 0 || !errors.Is(err, ErrSSEEventsExpired) {
+			t.Errorf("evicted stream b = %+v, %v, want ErrSSEEventsExpired", events, err)
+		}
+		if events, _ := b.After(ctx, "a", 0); len(events) != 2 {
+			t.Errorf("stream a = %+v, want 2 events", events)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		b := NewSSEReplayBuffer()
+		_ = b.Append(ctx, "s", SSEStoredEvent{ID: 1, Event: testEvent, Data: []byte(`{}`)})
+		b.Delete("s")
+		if events, err := b.After(ctx, "s", 0); len(events) != 0 || !errors.Is(err, ErrSSEStreamNotFound) {
+			t.Errorf("deleted stream = %+v, %v, want ErrSSEStreamNotFound", events, err)
+		}
+	})
+}
+
+func TestResumeSSE(t *testing.T) {
+	replay := NewSSEReplayBuffer()
+	if err := ToSSE(newMockResponseWriter()).WithEventIDs(replay, "run").FromReader(strings.NewReader("a b c")); err != nil {
+		t.Fatalf("FromReader() error = %v", err)
+	}
+
+	t.Run("replays after Last-Event-ID", func(t *testing.T) {
+		w := newMockResponseWriter()
+		r := httptest.NewRequest("GET", "/events", nil)
+		r.Header.Set("Last-Event-ID", "2")
+
+		if err := ResumeSSE(w, r, replay, "run"); err != nil {
+			t.Fatalf("ResumeSSE() error = %v", err)
+		}
+		verifySSEHeaders(t, w.Header())
+		if got := strings.Join(sseIDs(w.Body.String()), ","); got != "3,4" {
+			t.Errorf("replayed IDs = %s, want 3,4", got)
+		}
+		events := parseSSEEvents(t, w.Body.String())
+		if len(events) != 2 || events[0].Data != "c" || events[1].Event != testCompletion {
+			t.Errorf("replayed events = %+v", events)
+		}
+	})
+
+	t.Run("replays everything without header", func(t *testing.T) {
+		w := newMockResponseWriter()
+		if err := ResumeSSE(w, httptest.NewRequest("GET", "/events", nil), replay, "run"); err != nil {
+			t.Fatalf("ResumeSSE() error = %v", err)
+		}
+		if got := strings.Join(sseIDs(w.Body.String()), ","); got != "1,2,3,4" {
+			t.Errorf("replayed IDs = %s, want 1,2,3,4", got)
+		}
+	})
+
+	t.Run("invalid header", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/events", nil)
+		r.Header.Set("Last-Event-ID", "abc")
+		if err := ResumeSSE(newMockResponseWriter(), r, replay, "run"); err == nil {
+			t.Error("expected error for invalid Last-Event-ID")
+		}
+	})
+
+	t.Run("expired events", func(t *testing.T) {
+		small := NewSSEReplayBuffer(SSEReplayConfig{MaxEvents: 1})
+		_ = ToSSE(newMockResponseWriter()).WithEventIDs(small, "run").FromReader(strings.NewReader("a b c"))
+
+		w := newMockResponseWriter()
+		err := ResumeSSE(w, httptest.NewRequest("GET", "/events", nil), small, "run")
+		if !errors.Is(err, ErrSSEEventsExpired) {
+			t.Fatalf("ResumeSSE() error = %v, want ErrSSEEventsExpired", err)
+		}
+		if events := parseSSEEvents(t, w.Body.String()); len(events) != 1 || events[0].Event != testError {
+			t.Errorf("events = %+v, want one error event", events)
+		}
+	})
+
+	t.Run("follows a live stream", func(t *testing.T) {
+		live := NewSSEReplayBuffer()
+		sse := ToSSE(newMockResponseWriter()).WithEventIDs(live, "run")
+		if err := sse.WriteEvent(testEvent, "first"); err != nil {
+			t.Fatal(err)
+		}
+
+		done := make(chan error, 1)
+		w := newMockResponseWriter()
+		go func() {
+			done <- ResumeSSE(w, httptest.NewRequest("GET", "/events", nil), live, "run")
+		}()
+
+		time.Sleep(2 * DefaultSSEResumePoll)
+		if err := sse.sendCompletion(); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("ResumeSSE() error = %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("ResumeSSE did not finish after completion")
+		}
+		if got := strings.Join(sseIDs(w.Body.String()), ","); got != "1,2" {
+			t.Errorf("IDs = %s, want 1,2", got)
+		}
+	})
+
+	t.Run("evicted stream", func(t *testing.T) {
+		evicting := NewSSEReplayBuffer(SSEReplayConfig{MaxStreams: 1})
+		_ = ToSSE(newMockResponseWriter()).WithEventIDs(evicting, "old").WriteEvent(testEvent, "first")
+		_ = ToSSE(newMockResponseWriter()).WithEventIDs(evicting, "new").WriteEvent(testEvent, "first")
+
+		w := newMockResponseWriter()
+		r := httptest.NewRequest("GET", "/events", nil)
+		r.Header.Set("Last-Event-ID", "1")
+		done := make(chan error, 1)
+		go func() { done <- ResumeSSE(w, r, evicting, "old") }()
+
+		select {
+		case err := <-done:
+			if !errors.Is(err, ErrSSEEventsExpired) {
+				t.Fatalf("ResumeSSE() error = %v, want ErrSSEEventsExpired", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("ResumeSSE kept polling an evicted stream")
+		}
+		if events := parseSSEEvents(t, w.Body.String()); len(events) != 1 || events[0].Event != testError {
+			t.Errorf("events = %+v, want one error event", events)
+		}
+
+		if err := ResumeSSE(newMockResponseWriter(), httptest.NewRequest("GET", "/events", nil), evicting, "never"); !errors.Is(err, ErrSSEStreamNotFound) {
+			t.Errorf("unknown stream error = %v, want ErrSSEStreamNotFound", err)
+		}
+	})
+
+	t.Run("client disconnects", func(t *testing.T) {
+		idle := NewSSEReplayBuffer()
+		_ = ToSSE(newMockResponseWriter()).WithEventIDs(idle, "idle").WriteEvent(testEvent, "first")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		r := httptest.NewRequest("GET", "/events", nil).WithContext(ctx)
+		r.Header.Set("Last-Event-ID", "1")
+		cancel()
+		if err := ResumeSSE(newMockResponseWriter(), r, idle, "idle"); !errors.Is(err, context.Canceled) {
+			t.Errorf("ResumeSSE() error = %v, want context.Canceled", err)
+		}
+	})
+}
@@ -0,0 +1,500 @@
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// JSONEvent reports progress on a watched value inside a JSON stream.
+//
+// String values produce Delta events as characters arrive, followed by a final
+// event with Done set. Other values produce a single Done event once complete.
+type JSONEvent struct {
+	Path  string          `json:"path"`            // concrete path, e.g. "answer" or "citations[2]"
+	Delta string          `json:"delta,omitempty"` // newly received string content
+	Value json.RawMessage `json:"value,omitempty"` // complete value, set when Done
+	Done  bool            `json:"done"`
+}
+
+// StreamJSON extracts values from a JSON document while it is still being received.
+//
+// Input: JSON document (streaming - leading text such as a ```json fence is skipped)
+// Output: newline-delimited JSONEvent objects
+// Behavior: STREAMING - emits events as soon as watched values grow or complete
+//
+// Paths use dots for object keys and brackets for array elements: "answer",
+// "meta.model", "citations[0]", or "citations[*]" for every element. An empty
+// path watches the root document. With no paths, every top-level field is
+// watched.
+//
+// Example:
+//
+//	flow := calque.NewFlow().
+//		Use(ai.Agent(client, ai.WithSchema(&Answer{}))).
+//		Use(convert.StreamJSON("answer", "citations[*]"))
+//
+//	// {"path":"answer","delta":"The capital","done":false}
+//	// {"path":"answer","delta":" is Paris.","done":false}
+//	// {"path":"answer","value":"The capital is Paris.","done":true}
+//	// {"path":"citations[0]","value":{"url":"https://..."},"done":true}
+func StreamJSON(paths ...string) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		encoder := json.NewEncoder(res.Data)
+		return ScanJSON(req.Data, paths, func(event JSONEvent) error {
+			if err := req.Context.Err(); err != nil {
+				return err
+			}
+			return encoder.Encode(event)
+		})
+	})
+}
+
+// ScanJSON reads a JSON document from r and calls fn for each event on the watched paths.
+//
+// See StreamJSON for the path syntax. Returning an error from fn stops the scan.
+// Text after the document, such as a closing ``` fence or a trailing remark,
+// is read to the end of r and discarded.
+//
+// Example:
+//
+//	err := convert.ScanJSON(stream, []string{"answer"}, func(e convert.JSONEvent) error {
+//		fmt.Print(e.Delta)
+//		return nil
+//	})
+func ScanJSON(r io.Reader, paths []string, fn func(JSONEvent) error) error {
+	patterns := make([][]string, 0, len(paths))
+	for _, p := range paths {
+		patterns = append(patterns, splitJSONPath(p))
+	}
+	if len(paths) == 0 {
+		patterns = append(patterns, []string{"*"})
+	}
+
+	s := &jsonScanner{r: bufio.NewReader(r), patterns: patterns, emit: fn}
+	if err := s.skipPreamble(); err != nil {
+		return err
+	}
+	if err := s.value(nil); err != nil {
+		return err
+	}
+	if _, err := io.Copy(io.Discard, s.r); err != nil {
+		return calque.WrapErr(context.Background(), err, "failed to read past the JSON document")
+	}
+	return nil
+}
+
+// splitJSONPath turns "a.b[2].c[*]" into ["a", "b", "[2]", "c", "[*]"]
+func splitJSONPath(path string) []string {
+	var segments []string
+	for _, part := range strings.Split(path, ".") {
+		for part != "" {
+			i := strings.IndexByte(part, '[')
+			switch {
+			case i < 0:
+				segments = append(segments, part)
+				part = ""
+			case i > 0:
+				segments = append(segments, part[:i])
+				part = part[i:]
+			default:
+				end := strings.IndexByte(part, ']')
+				if end < 0 {
+					end = len(part) - 1
+				}
+				segments = append(segments, part[:end+1])
+				part = part[end+1:]
+			}
+		}
+	}
+	return segments
+}
+
+// formatJSONPath renders segments as a path string
+func formatJSONPath(segments []string) string {
+	var b strings.Builder
+	for _, seg := range segments {
+		if b.Len() > 0 && !strings.HasPrefix(seg, "[") {
+			b.WriteByte('.')
+		}
+		b.WriteString(seg)
+	}
+	return b.String()
+}
+
+// jsonScanner is an incremental recursive-descent JSON reader
+type jsonScanner struct {
+	r        *bufio.Reader
+	patterns [][]string
+	emit     func(JSONEvent) error
+	captures []*bytes.Buffer
+}
+
+// matches reports whether path is watched. A "*" key segment matches any key
+// and "[*]" matches any array index.
+func (s *jsonScanner) matches(path []string) bool {
+	for _, pattern := range s.patterns {
+		if len(pattern) != len(path) {
+			continue
+		}
+		ok := true
+		for i, seg := range pattern {
+			if seg == "*" && !strings.HasPrefix(path[i], "[") || seg == "[*]" && strings.HasPrefix(path[i], "[") {
+				continue
+			}
+			if seg != path[i] {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *jsonScanner) readByte() (byte, error) {
+	c, err := s.r.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, calque.WrapErr(context.Background(), err, "incomplete JSON")
+	}
+	for _, buf := range s.captures {
+		buf.WriteByte(c)
+	}
+	return c, nil
+}
+
+func (s *jsonScanner) peek() (byte, error) {
+	b, err := s.r.Peek(1)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, calque.WrapErr(context.Background(), err, "incomplete JSON")
+	}
+	return b[0], nil
+}
+
+// skipSpace consumes whitespace without capturing it
+func (s *jsonScanner) skipSpace() error {
+	for {
+		c, err := s.peek()
+		if err != nil {
+			return err
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			return nil
+		}
+		if _, err := s.r.ReadByte(); err != nil {
+			return err
+		}
+	}
+}
+
+// skipPreamble discards any text before the first '{' or '['
+func (s *jsonScanner) skipPreamble() error {
+	for {
+		c, err := s.peek()
+		if err != nil {
+			return err
+		}
+		if c == '{' || c == '[' {
+			return nil
+		}
+		if _, err := s.r.ReadByte(); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *jsonScanner) syntaxErr(c byte) error {
+	return calque.NewErr(context.Background(), fmt.Sprintf("invalid JSON: unexpected character %q", c))
+}
+
+// value parses the value at path, emitting events if it is watched
+func (s *jsonScanner) value(path []string) error {
+	if err := s.skipSpace(); err != nil {
+		return err
+	}
+	c, err := s.peek()
+	if err != nil {
+		return err
+	}
+
+	watched := s.matches(path)
+	if watched {
+		s.captures = append(s.captures, &bytes.Buffer{})
+	}
+
+	switch {
+	case c == '{':
+		err = s.object(path)
+	case c == '[':
+		err = s.array(path)
+	case c == '"':
+		var onDelta func(string) error
+		if watched {
+			name := formatJSONPath(path)
+			onDelta = func(delta string) error { return s.emit(JSONEvent{Path: name, Delta: delta}) }
+		}
+		err = s.str(onDelta)
+	default:
+		err = s.literal()
+	}
+	if err != nil || !watched {
+		return err
+	}
+
+	capture := s.captures[len(s.captures)-1]
+	s.captures = s.captures[:len(s.captures)-1]
+	return s.emit(JSONEvent{Path: formatJSONPath(path), Value: json.RawMessage(capture.Bytes()), Done: true})
+}
+
+func (s *jsonScanner) object(path []string) error {
+	if _, err := s.readByte(); err != nil { // '{'
+		return err
+	}
+	for first := true; ; first = false {
+		if err := s.skipSpace(); err != nil {
+			return err
+		}
+		c, err := s.readByte()
+		if err != nil {
+			return err
+		}
+		if c == '}' {
+			return nil
+		}
+		if !first {
+			if c != ',' {
+				return s.syntaxErr(c)
+			}
+			if err := s.skipSpace(); err != nil {
+				return err
+			}
+			if c, err = s.readByte(); err != nil {
+				return err
+			}
+		}
+		if c != '"' {
+			return s.syntaxErr(c)
+		}
+
+		var key strings.Builder
+		if err := s.strBody(&key, nil); err != nil {
+			return err
+		}
+		if err := s.skipSpace(); err != nil {
+			return err
+		}
+		if c, err = s.readByte(); err != nil {
+			return err
+		} else if c != ':' {
+			return s.syntaxErr(c)
+		}
+		if err := s.value(append(path[:len(path):len(path)], key.String())); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *jsonScanner) array(path []string) error {
+	if _, err := s.readByte(); err != nil { // '['
+		return err
+	}
+	for i := 0; ; i++ {
+		if err := s.skipSpace(); err != nil {
+			return err
+		}
+		c, err := s.peek()
+		if err != nil {
+			return err
+		}
+		if c == ']' {
+			_, err := s.readByte()
+			return err
+		}
+		if i > 0 {
+			if c != ',' {
+				return s.syntaxErr(c)
+			}
+			if _, err := s.readByte(); err != nil {
+				return err
+			}
+		}
+		if err := s.value(append(path[:len(path):len(path)], "["+strconv.Itoa(i)+"]")); err != nil {
+			return err
+		}
+	}
+}
+
+// str parses a string value, reporting decoded content through onDelta
+func (s *jsonScanner) str(onDelta func(string) error) error {
+	if _, err := s.readByte(); err != nil { // opening quote
+		return err
+	}
+	var pending strings.Builder
+	return s.strBody(&pending, onDelta)
+}
+
+// strBody decodes string content up to the closing quote into out. When onDelta
+// is set, content is flushed to it whenever the reader has no more buffered
+// input, so deltas track network chunks rather than single characters.
+func (s *jsonScanner) strBody(out *strings.Builder, onDelta func(string) error) error {
+	flush := func() error {
+		if onDelta == nil || out.Len() == 0 {
+			return nil
+		}
+		delta := out.String()
+		out.Reset()
+		return onDelta(delta)
+	}
+
+	for {
+		if s.r.Buffered() == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		c, err := s.readByte()
+		if err != nil {
+			return err
+		}
+		switch {
+		case c == '"':
+			return flush()
+		case c == '\\':
+			if err := s.escape(out); err != nil {
+				return err
+			}
+		case c < utf8.RuneSelf:
+			out.WriteByte(c)
+		default:
+			// keep multi-byte runes intact across deltas
+			out.WriteByte(c)
+			for n := utf8Len(c) - 1; n > 0; n-- {
+				b, err := s.readByte()
+				if err != nil {
+					return err
+				}
+				out.WriteByte(b)
+			}
+		}
+	}
+}
+
+func (s *jsonScanner) escape(out *strings.Builder) error {
+	c, err := s.readByte()
+	if err != nil {
+		return err
+	}
+	switch c {
+	case '"', '\\', '/':
+		out.WriteByte(c)
+	case 'b':
+		out.WriteByte('\b')
+	case 'f':
+		out.WriteByte('\f')
+	case 'n':
+		out.WriteByte('\n')
+	case 'r':
+		out.WriteByte('\r')
+	case 't':
+		out.WriteByte('\t')
+	case 'u':
+		r, err := s.hex4()
+		if err != nil {
+			return err
+		}
+		if utf16.IsSurrogate(r) {
+			// expect the low half as another \uXXXX escape
+			if b, err := s.readByte(); err != nil || b != '\\' {
+				return s.badEscape(err)
+			}
+			if b, err := s.readByte(); err != nil || b != 'u' {
+				return s.badEscape(err)
+			}
+			low, err := s.hex4()
+			if err != nil {
+				return err
+			}
+			r = utf16.DecodeRune(r, low)
+		}
+		out.WriteRune(r)
+	default:
+		return s.syntaxErr(c)
+	}
+	return nil
+}
+
+func (s *jsonScanner) badEscape(err error) error {
+	if err != nil {
+		return err
+	}
+	return calque.NewErr(context.Background(), "invalid JSON: unpaired surrogate escape")
+}
+
+func (s *jsonScanner) hex4() (rune, error) {
+	var digits [4]byte
+	for i := range digits {
+		c, err := s.readByte()
+		if err != nil {
+			return 0, err
+		}
+		digits[i] = c
+	}
+	v, err := strconv.ParseUint(string(digits[:]), 16, 16)
+	if err != nil {
+		return 0, calque.WrapErr(context.Background(), err, "invalid JSON unicode escape")
+	}
+	return rune(v), nil
+}
+
+// literal consumes a number, true, false or null
+func (s *jsonScanner) literal() error {
+	var lit []byte
+	for {
+		c, err := s.r.Peek(1)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(c) == 0 || strings.IndexByte(",}] \t\r\n", c[0]) >= 0 {
+			break
+		}
+		b, err := s.readByte()
+		if err != nil {
+			return err
+		}
+		lit = append(lit, b)
+	}
+	if !json.Valid(lit) {
+		return calque.NewErr(context.Background(), fmt.Sprintf("invalid JSON literal %q", lit))
+	}
+	return nil
+}
+
+// utf8Len returns the encoded length implied by a UTF-8 leading byte
+func utf8Len(lead byte) int {
+	switch {
+	case lead&0xE0 == 0xC0:
+		return 2
+	case lead&0xF0 == 0xE0:
+		return 3
+	case lead&0xF8 == 0xF0:
+		return 4
+	}
+	return 1
+}
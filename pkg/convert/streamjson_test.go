@@ -0,0 +1,199 @@
+package convert
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func collectEvents(t *testing.T, input string, paths ...string) []JSONEvent {
+	t.Helper()
+	var events []JSONEvent
+	err := ScanJSON(strings.NewReader(input), paths, func(e JSONEvent) error {
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ScanJSON() error = %v", err)
+	}
+	return events
+}
+
+func TestScanJSON(t *testing.T) {
+	input := "```json\n" + `{"answer": "Paris é\n", "meta": {"model": "gpt-4o", "score": 0.9},
+		"citations": [{"url": "a", "n": [1, 2]}, {"url": "b"}], "ok": true}` + "\n```"
+
+	tests := []struct {
+		name  string
+		paths []string
+		want  map[string]string // path -> final value
+	}{
+		{
+			name:  "top-level fields by default",
+			paths: nil,
+			want: map[string]string{
+				"answer":    `"Paris é\n"`,
+				"meta":      `{"model":"gpt-4o","score":0.9}`,
+				"citations": `[{"url":"a","n":[1,2]},{"url":"b"}]`,
+				"ok":        `true`,
+			},
+		},
+		{
+			name:  "nested key",
+			paths: []string{"meta.model"},
+			want:  map[string]string{"meta.model": `"gpt-4o"`},
+		},
+		{
+			name:  "array wildcard",
+			paths: []string{"citations[*]"},
+			want: map[string]string{
+				"citations[0]": `{"url":"a","n":[1,2]}`,
+				"citations[1]": `{"url":"b"}`,
+			},
+		},
+		{
+			name:  "array index and nested array",
+			paths: []string{"citations[0].n[1]"},
+			want:  map[string]string{"citations[0].n[1]": `2`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]string{}
+			for _, e := range collectEvents(t, input, tt.paths...) {
+				if e.Done {
+					got[e.Path] = string(e.Value)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d values %v, want %d", len(got), got, len(tt.want))
+			}
+			for path, want := range tt.want {
+				if !json.Valid([]byte(got[path])) {
+					t.Errorf("%s: invalid JSON %q", path, got[path])
+				}
+				if got[path] != want {
+					t.Errorf("%s = %s, want %s", path, got[path], want)
+				}
+			}
+		})
+	}
+}
+
+func TestScanJSONStringDeltas(t *testing.T) {
+	events := collectEvents(t, `{"answer": "a\"béc", "x": 1}`, "answer")
+
+	var deltas strings.Builder
+	var final JSONEvent
+	for _, e := range events {
+		deltas.WriteString(e.Delta)
+		if e.Done {
+			final = e
+		}
+	}
+	if deltas.String() != "a\"béc" {
+		t.Errorf("deltas = %q, want %q", deltas.String(), "a\"béc")
+	}
+	var decoded string
+	if err := json.Unmarshal(final.Value, &decoded); err != nil || decoded != deltas.String() {
+		t.Errorf("final value = %s (%v), want matching deltas", final.Value, err)
+	}
+}
+
+func TestScanJSONErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "truncated", input: `{"answer": "par`},
+		{name: "bad literal", input: `{"a": tru}`},
+		{name: "missing colon", input: `{"a" 1}`},
+		{name: "no document", input: `just text`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ScanJSON(strings.NewReader(tt.input), nil, func(JSONEvent) error { return nil })
+			if err == nil {
+				t.Error("ScanJSON() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestStreamJSONEmitsBeforeInputCompletes(t *testing.T) {
+	pr, pw := io.Pipe()
+	outR, outW := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		err := StreamJSON("answer", "citations[*]").ServeFlow(calque.NewRequest(context.Background(), pr), calque.NewResponse(outW))
+		outW.CloseWithError(err)
+		done <- err
+	}()
+
+	lines := bufio.NewScanner(outR)
+	next := func() JSONEvent {
+		t.Helper()
+		if !lines.Scan() {
+			t.Fatalf("expected event, got %v", lines.Err())
+		}
+		var e JSONEvent
+		if err := json.Unmarshal(lines.Bytes(), &e); err != nil {
+			t.Fatalf("invalid event %q: %v", lines.Text(), err)
+		}
+		return e
+	}
+
+	go func() { _, _ = io.WriteString(pw, `{"answer": "The capital`) }()
+	if e := next(); e.Path != "answer" || e.Delta != "The capital" {
+		t.Fatalf("first event = %+v, want answer delta", e)
+	}
+
+	go func() { _, _ = io.WriteString(pw, ` is Paris.", "citations": [{"url": "x"}`) }()
+	if e := next(); e.Delta != " is Paris." {
+		t.Fatalf("second event = %+v, want answer delta", e)
+	}
+	if e := next(); !e.Done || e.Path != "answer" {
+		t.Fatalf("third event = %+v, want answer done", e)
+	}
+	if e := next(); !e.Done || e.Path != "citations[0]" || string(e.Value) != `{"url":"x"}` {
+		t.Fatalf("fourth event = %+v, want citations[0]", e)
+	}
+
+	go func() {
+		_, _ = io.WriteString(pw, `]}`)
+		pw.Close()
+	}()
+	if lines.Scan() {
+		t.Errorf("unexpected event %q", lines.Text())
+	}
+	if err := <-done; err != nil {
+		t.Errorf("StreamJSON() error = %v", err)
+	}
+}
+
+func TestStreamJSONTrailingText(t *testing.T) {
+	// the model writes the document and its trailer separately, as streamed tokens would
+	source := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		_, _ = io.Copy(io.Discard, req.Data)
+		if _, err := res.Data.Write([]byte("```json\n{\"answer\": \"Paris\"}")); err != nil {
+			return err
+		}
+		_, err := res.Data.Write([]byte("\n```\nHope this helps!"))
+		return err
+	})
+
+	var out string
+	if err := calque.NewFlow().Use(source).Use(StreamJSON("answer")).Run(context.Background(), "", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !strings.Contains(out, `"value":"Paris"`) {
+		t.Errorf("output = %q, want the answer event", out)
+	}
+}
@@ -0,0 +1,514 @@
+package convert
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultXLSXMaxSize caps the workbook size when XLSXOptions.MaxSize is 0.
+const DefaultXLSXMaxSize = 100 << 20
+
+// ErrSheetNotFound is returned by XLSXToCSV when no sheet matches the selector.
+var ErrSheetNotFound = errors.New("sheet not found")
+
+// XLSXOptions configures XLSXToCSV.
+type XLSXOptions struct {
+	MaxSize  int64 // largest workbook accepted, in bytes (0 = DefaultXLSXMaxSize, negative = unlimited)
+	Comma    rune  // field delimiter (default ',')
+	RawDates bool  // keep date cells as Excel serial numbers instead of ISO 8601
+}
+
+// XLSXToCSV converts one sheet of an Excel workbook to CSV.
+//
+// Input: .xlsx file bytes
+// Output: CSV records, one per non-empty row
+// Behavior: STREAMING - the workbook is buffered (zip needs random access),
+// then the sheet is decoded and written row by row
+//
+// sheet selects the sheet by name, or by 1-based position when no sheet has
+// that name; "" selects the first sheet. Shared and inline strings, numbers,
+// booleans, errors and cached formula results are written as Excel shows
+// them, without number formatting; cells with a date format become ISO 8601
+// dates ("2025-03-01", "2025-03-01 09:30:00"). Rows are padded to the
+// sheet's width, and empty rows are skipped. Only .xlsx (Office Open XML)
+// workbooks are supported, not legacy .xls.
+//
+// Example:
+//
+//	flow := calque.NewFlow().
+//		Use(convert.XLSXToCSV("Q3 Sales")).
+//		Use(prompt.Template("Summarise the trends in this data:\n\n{{.Input}}")).
+//		Use(ai.Agent(client))
+//	err := flow.Run(ctx, workbookFile, &summary)
+func XLSXToCSV(sheet string, opts ...XLSXOptions) calque.Handler {
+	cfg := XLSXOptions{}
+	if len(opts) > 0 {
+		cfg = opts[0]
+	}
+	if cfg.MaxSize == 0 {
+		cfg.MaxSize = DefaultXLSXMaxSize
+	}
+
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		input := req.Data
+		if cfg.MaxSize > 0 {
+			input = io.LimitReader(req.Data, cfg.MaxSize+1)
+		}
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return err
+		}
+		if cfg.MaxSize > 0 && int64(len(data)) > cfg.MaxSize {
+			return calque.InvalidInput(calque.NewErr(req.Context, fmt.Sprintf("workbook exceeds %d bytes", cfg.MaxSize)))
+		}
+
+		book, err := openWorkbook(data)
+		if err != nil {
+			return calque.InvalidInput(calque.WrapErr(req.Context, err, "failed to read XLSX workbook"))
+		}
+		target, err := book.selectSheet(sheet)
+		if err != nil {
+			return calque.InvalidInput(calque.WrapErr(req.Context, err, "failed to select sheet"))
+		}
+
+		w := csv.NewWriter(res.Data)
+		if cfg.Comma != 0 {
+			w.Comma = cfg.Comma
+		}
+		if err := book.writeSheet(target, w, cfg.RawDates); err != nil {
+			return calque.WrapErr(req.Context, err, "failed to convert sheet")
+		}
+		w.Flush()
+		return w.Error()
+	})
+}
+
+// workbook is the parts of an XLSX package needed to read its sheets
+type workbook struct {
+	files      map[string]*zip.File
+	sheets     []workbookSheet
+	strings    []string
+	dateStyles []bool // per cell style index
+	date1904   bool
+}
+
+type workbookSheet struct {
+	name string
+	path string
+}
+
+func openWorkbook(data []byte) (*workbook, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	book := &workbook{files: make(map[string]*zip.File, len(zr.File))}
+	for _, f := range zr.File {
+		book.files[f.Name] = f
+	}
+
+	if err := book.readSheets(); err != nil {
+		return nil, err
+	}
+	if err := book.readSharedStrings(); err != nil {
+		return nil, err
+	}
+	if err := book.readStyles(); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// decodePart decodes a package part into v; missing optional parts are skipped
+func (b *workbook) decodePart(name string, v any, required bool) error {
+	f, ok := b.files[name]
+	if !ok {
+		if required {
+			return fmt.Errorf("missing %s", name)
+		}
+		return nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func (b *workbook) readSheets() error {
+	var wb struct {
+		Properties struct {
+			Date1904 string `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			Name  string     `xml:"name,attr"`
+			Attrs []xml.Attr `xml:",any,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := b.decodePart("xl/workbook.xml", &wb, true); err != nil {
+		return err
+	}
+	b.date1904 = wb.Properties.Date1904 == "1" || wb.Properties.Date1904 == "true"
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := b.decodePart("xl/_rels/workbook.xml.rels", &rels, true); err != nil {
+		return err
+	}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, r := range rels.Relationships {
+		if strings.HasPrefix(r.Target, "/") {
+			targets[r.ID] = strings.TrimPrefix(r.Target, "/")
+		} else {
+			targets[r.ID] = path.Join("xl", r.Target)
+		}
+	}
+
+	for _, s := range wb.Sheets {
+		for _, attr := range s.Attrs {
+			// r:id, in the transitional or strict relationships namespace
+			if attr.Name.Local == "id" && attr.Name.Space != "" {
+				b.sheets = append(b.sheets, workbookSheet{name: s.Name, path: targets[attr.Value]})
+			}
+		}
+	}
+	if len(b.sheets) == 0 {
+		return errors.New("workbook has no sheets")
+	}
+	return nil
+}
+
+func (b *workbook) readSharedStrings() error {
+	f, ok := b.files["xl/sharedStrings.xml"]
+	if !ok {
+		return nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+
+	dec := xml.NewDecoder(rc)
+	var current strings.Builder
+	inText, phonetic := false, false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("xl/sharedStrings.xml: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				current.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				phonetic = true // reading hints, not part of the value
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				b.strings = append(b.strings, current.String())
+			case "t":
+				inText = false
+			case "rPh":
+				phonetic = false
+			}
+		case xml.CharData:
+			if inText && !phonetic {
+				current.Write(t)
+			}
+		}
+	}
+}
+
+func (b *workbook) readStyles() error {
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := b.decodePart("xl/styles.xml", &styles, false); err != nil {
+		return err
+	}
+	custom := make(map[int]bool, len(styles.NumFmts))
+	for _, f := range styles.NumFmts {
+		custom[f.ID] = isDateFormat(f.Code)
+	}
+	b.dateStyles = make([]bool, len(styles.CellXfs))
+	for i, xf := range styles.CellXfs {
+		if isDate, ok := custom[xf.NumFmtID]; ok {
+			b.dateStyles[i] = isDate
+		} else {
+			b.dateStyles[i] = isBuiltinDateFormat(xf.NumFmtID)
+		}
+	}
+	return nil
+}
+
+// selectSheet finds a sheet by name, then by 1-based position
+func (b *workbook) selectSheet(selector string) (workbookSheet, error) {
+	if selector == "" {
+		return b.sheets[0], nil
+	}
+	names := make([]string, len(b.sheets))
+	for i, s := range b.sheets {
+		if s.name == selector {
+			return s, nil
+		}
+		names[i] = s.name
+	}
+	if n, err := strconv.Atoi(selector); err == nil && n >= 1 && n <= len(b.sheets) {
+		return b.sheets[n-1], nil
+	}
+	return workbookSheet{}, fmt.Errorf("%w: %q (sheets: %s)", ErrSheetNotFound, selector, strings.Join(names, ", "))
+}
+
+// writeSheet streams the rows of sheet to w
+func (b *workbook) writeSheet(sheet workbookSheet, w *csv.Writer, rawDates bool) error {
+	f, ok := b.files[sheet.path]
+	if !ok {
+		return fmt.Errorf("missing %s for sheet %q", sheet.path, sheet.name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+
+	dec := xml.NewDecoder(rc)
+	width, rows := 0, 0
+	var (
+		record []string
+		cell   xlsxCell
+		inCell bool
+		inText bool // in <v>, or <t> of an inline string
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "dimension":
+				width = dimensionWidth(attrValue(t, "ref"))
+			case "row":
+				record = record[:0]
+			case "c":
+				inCell = true
+				cell = xlsxCell{ref: attrValue(t, "r"), kind: attrValue(t, "t")}
+				if s, err := strconv.Atoi(attrValue(t, "s")); err == nil {
+					cell.style = s
+				}
+			case "v", "t":
+				inText = inCell
+			}
+		case xml.CharData:
+			if inText {
+				cell.value.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inText = false
+			case "c":
+				inCell = false
+				col := len(record)
+				if c, ok := columnIndex(cell.ref); ok && c >= col {
+					col = c
+				}
+				for len(record) < col {
+					record = append(record, "")
+				}
+				record = append(record, b.cellText(&cell, rawDates))
+			case "row":
+				if !hasValue(record) {
+					continue
+				}
+				for len(record) < width {
+					record = append(record, "")
+				}
+				if err := w.Write(record); err != nil {
+					return err
+				}
+				if rows++; rows%100 == 0 {
+					w.Flush()
+				}
+			}
+		}
+	}
+}
+
+type xlsxCell struct {
+	ref   string
+	kind  string
+	style int
+	value strings.Builder
+}
+
+// cellText renders a cell the way Excel shows its value
+func (b *workbook) cellText(c *xlsxCell, rawDates bool) string {
+	v := c.value.String()
+	switch c.kind {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || i < 0 || i >= len(b.strings) {
+			return ""
+		}
+		return b.strings[i]
+	case "b":
+		if v == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case "inlineStr", "str", "e", "d":
+		return v
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return v
+	}
+	if !rawDates && c.style < len(b.dateStyles) && b.dateStyles[c.style] {
+		return excelDate(f, b.date1904)
+	}
+	// Excel shows 15 significant digits, hiding binary rounding noise
+	f, _ = strconv.ParseFloat(strconv.FormatFloat(f, 'g', 15, 64), 64)
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// excelDate converts a serial date. The 1899-12-30 epoch absorbs Excel's
+// phantom 1900-02-29 from March 1900; earlier serials are shifted a day.
+func excelDate(serial float64, date1904 bool) string {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	days := math.Floor(serial)
+	if !date1904 && days >= 1 && days < 61 {
+		epoch = epoch.AddDate(0, 0, 1)
+	}
+	seconds := math.Round((serial - days) * 86400)
+	t := epoch.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+	switch {
+	case seconds == 0:
+		return t.Format("2006-01-02")
+	case days == 0 && !date1904:
+		return t.Format("15:04:05")
+	default:
+		return t.Format("2006-01-02 15:04:05")
+	}
+}
+
+// isBuiltinDateFormat reports the built-in number formats that show dates or times
+func isBuiltinDateFormat(id int) bool {
+	return (id >= 14 && id <= 22) || (id >= 27 && id <= 36) || (id >= 45 && id <= 47) || (id >= 50 && id <= 58)
+}
+
+// isDateFormat reports custom format codes with date or time parts, ignoring
+// quoted text, escapes and bracketed colors or locales
+func isDateFormat(code string) bool {
+	if strings.EqualFold(code, "General") {
+		return false
+	}
+	var depth int
+	quoted, escaped := false, false
+	for _, r := range code {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		case depth > 0:
+		case strings.ContainsRune("yYdDhHsSmM", r):
+			return true
+		}
+	}
+	return false
+}
+
+// columnIndex returns the 0-based column of a cell reference like "AB12"
+func columnIndex(ref string) (int, bool) {
+	col := 0
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		n++
+	}
+	return col - 1, n > 0
+}
+
+// dimensionWidth returns the column count of a range like "A1:D10"
+func dimensionWidth(ref string) int {
+	_, end, ok := strings.Cut(ref, ":")
+	if !ok {
+		end = ref
+	}
+	col, ok := columnIndex(end)
+	if !ok {
+		return 0
+	}
+	return col + 1
+}
+
+func attrValue(start xml.StartElement, name string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func hasValue(record []string) bool {
+	for _, v := range record {
+		if v != "" {
+			return true
+		}
+	}
+	return false
+}
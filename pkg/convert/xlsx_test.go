@@ -0,0 +1,158 @@
+package convert
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+const (
+	testWorkbookXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<workbookPr/>
+<sheets><sheet name="Summary" sheetId="1" r:id="rId1"/><sheet name="Q3 Sales" sheetId="2" r:id="rId2"/></sheets>
+</workbook>`
+	testRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/sheet2.xml"/>
+</Relationships>`
+	testSharedStringsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>Region</t></si><si><t>Total</t></si><si><t>Closed</t></si>
+<si><r><t>North</t></r><r><rPr><b/></rPr><t xml:space="preserve">, West</t></r><rPh><t>ignored</t></rPh></si>
+</sst>`
+	testStylesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts><numFmt numFmtId="164" formatCode="yyyy\-mm\-dd hh:mm"/><numFmt numFmtId="165" formatCode="&quot;days&quot; 0"/></numFmts>
+<cellXfs><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="164"/><xf numFmtId="165"/></cellXfs>
+</styleSheet>`
+	testSheet1XML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>summary sheet</t></is></c></row></sheetData>
+</worksheet>`
+	testSheet2XML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<dimension ref="A1:E4"/>
+<sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c><c r="D1" t="inlineStr"><is><t>Days</t></is></c></row>
+<row r="2"><c r="A2" t="s"><v>3</v></c><c r="B2"><f>SUM(X1:X9)</f><v>0.30000000000000004</v></c><c r="C2" s="1"><v>45717</v></c><c r="D2" s="3"><v>12</v></c><c r="E2" t="b"><v>1</v></c></row>
+<row r="3"/>
+<row r="4"><c r="A4" t="str"><v>South</v></c><c r="C4" s="2"><v>45717.395833333336</v></c><c r="E4" t="e"><v>#DIV/0!</v></c></row>
+</sheetData>
+</worksheet>`
+)
+
+func testWorkbook(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"xl/workbook.xml":            testWorkbookXML,
+		"xl/_rels/workbook.xml.rels": testRelsXML,
+		"xl/sharedStrings.xml":       testSharedStringsXML,
+		"xl/styles.xml":              testStylesXML,
+		"xl/worksheets/sheet1.xml":   testSheet1XML,
+		"xl/worksheets/sheet2.xml":   testSheet2XML,
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func runXLSX(t *testing.T, h calque.Handler, input []byte) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := h.ServeFlow(calque.NewRequest(context.Background(), bytes.NewReader(input)), calque.NewResponse(&out))
+	return out.String(), err
+}
+
+func TestXLSXToCSV(t *testing.T) {
+	book := testWorkbook(t)
+	sales := "Region,Total,Closed,Days,\n" +
+		"\"North, West\",0.3,2025-03-01,12,TRUE\n" +
+		"South,,2025-03-01 09:30:00,,#DIV/0!\n"
+
+	tests := []struct {
+		name     string
+		sheet    string
+		opts     XLSXOptions
+		expected string
+	}{
+		{name: "first sheet by default", expected: "summary sheet\n"},
+		{name: "by name", sheet: "Q3 Sales", expected: sales},
+		{name: "by position", sheet: "2", expected: sales},
+		{name: "delimiter", sheet: "Summary", opts: XLSXOptions{Comma: ';'}, expected: "summary sheet\n"},
+		{
+			name:     "raw dates",
+			sheet:    "Q3 Sales",
+			opts:     XLSXOptions{RawDates: true, Comma: '\t'},
+			expected: "Region\tTotal\tClosed\tDays\t\nNorth, West\t0.3\t45717\t12\tTRUE\nSouth\t\t45717.3958333333\t\t#DIV/0!\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := runXLSX(t, XLSXToCSV(tt.sheet, tt.opts), book)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tt.expected {
+				t.Errorf("XLSXToCSV() = %q, want %q", out, tt.expected)
+			}
+		})
+	}
+}
+
+func TestXLSXToCSVErrors(t *testing.T) {
+	book := testWorkbook(t)
+
+	_, err := runXLSX(t, XLSXToCSV("Missing"), book)
+	if !errors.Is(err, ErrSheetNotFound) || !calque.IsInvalidInput(err) {
+		t.Errorf("missing sheet error = %v, want invalid-input ErrSheetNotFound", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "Summary, Q3 Sales") {
+		t.Errorf("missing sheet error %q should list the sheets", err)
+	}
+
+	if _, err := runXLSX(t, XLSXToCSV(""), []byte("name,total\n")); !calque.IsInvalidInput(err) {
+		t.Errorf("non-XLSX error = %v, want invalid input", err)
+	}
+
+	if _, err := runXLSX(t, XLSXToCSV("", XLSXOptions{MaxSize: 64}), book); !calque.IsInvalidInput(err) {
+		t.Errorf("oversized error = %v, want invalid input", err)
+	}
+}
+
+func TestExcelDate(t *testing.T) {
+	tests := []struct {
+		serial   float64
+		date1904 bool
+		expected string
+	}{
+		{serial: 1, expected: "1900-01-01"},
+		{serial: 61, expected: "1900-03-01"},
+		{serial: 45717.5, expected: "2025-03-01 12:00:00"},
+		{serial: 0.25, expected: "06:00:00"},
+		{serial: 0, date1904: true, expected: "1904-01-01"},
+	}
+	for _, tt := range tests {
+		if got := excelDate(tt.serial, tt.date1904); got != tt.expected {
+			t.Errorf("excelDate(%v, %v) = %q, want %q", tt.serial, tt.date1904, got, tt.expected)
+		}
+	}
+}
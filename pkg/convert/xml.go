@@ -0,0 +1,347 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// XMLInputConverter is an input converter for transforming structured data to XML streams.
+type XMLInputConverter struct {
+	data any
+}
+
+// XMLOutputConverter is an output converter for parsing XML streams to structured data.
+type XMLOutputConverter struct {
+	target any
+}
+
+// ToXML creates an input converter for transforming structured data to XML streams.
+//
+// Input: any data type (structs with xml tags, XML strings, XML bytes)
+// Output: calque.InputConverter for pipeline input position
+// Behavior: STREAMING - uses xml.Encoder for structured data
+//
+// Converts various data types to XML for pipeline processing:
+// - Structs: Marshaled using encoding/xml
+// - XML strings/bytes: Validated and passed through
+//
+// Example usage:
+//
+//	type Order struct {
+//		XMLName xml.Name `xml:"order"`
+//		ID      string   `xml:"id,attr"`
+//		Total   float64  `xml:"total"`
+//	}
+//
+//	err := pipeline.Run(ctx, convert.ToXML(Order{ID: "42", Total: 9.5}), &result)
+func ToXML(data any) calque.InputConverter {
+	return &XMLInputConverter{data: data}
+}
+
+// FromXML creates an output converter for parsing XML streams to structured data.
+//
+// Input: pointer to target variable for unmarshaling
+// Output: calque.OutputConverter for pipeline output position
+// Behavior: STREAMING - uses xml.Decoder
+//
+// Example usage:
+//
+//	var order Order
+//	err := pipeline.Run(ctx, input, convert.FromXML(&order))
+func FromXML(target any) calque.OutputConverter {
+	return &XMLOutputConverter{target: target}
+}
+
+// ToReader converts the input data to an io.Reader for XML processing.
+func (x *XMLInputConverter) ToReader() (io.Reader, error) {
+	switch v := x.data.(type) {
+	case string:
+		if err := validateXML([]byte(v)); err != nil {
+			return nil, calque.WrapErr(context.Background(), err, "invalid XML string")
+		}
+		return strings.NewReader(v), nil
+	case []byte:
+		if err := validateXML(v); err != nil {
+			return nil, calque.WrapErr(context.Background(), err, "invalid XML bytes")
+		}
+		return bytes.NewReader(v), nil
+	case io.Reader:
+		return v, nil
+	default:
+		pr, pw := io.Pipe()
+		go func() {
+			encoder := xml.NewEncoder(pw)
+			if err := encoder.Encode(x.data); err != nil {
+				pw.CloseWithError(calque.WrapErr(context.Background(), err, fmt.Sprintf("failed to encode XML for type %T", x.data)))
+				return
+			}
+			pw.Close()
+		}()
+		return pr, nil
+	}
+}
+
+// FromReader implements the OutputConverter interface for XML streams -> structured data.
+func (x *XMLOutputConverter) FromReader(reader io.Reader) error {
+	if err := xml.NewDecoder(reader).Decode(x.target); err != nil {
+		return calque.WrapErr(context.Background(), err, "failed to decode XML")
+	}
+	return nil
+}
+
+// validateXML checks that data is well-formed XML
+func validateXML(data []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		if _, err := decoder.Token(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// XMLToJSON converts an XML document to JSON.
+//
+// Input: XML document
+// Output: JSON object keyed by the root element name
+// Behavior: STREAMING - decodes tokens incrementally, writes JSON once the root element closes
+//
+// Elements map to JSON using the common conventions: attributes become "@name"
+// keys, repeated child elements become arrays, mixed text becomes "#text", and
+// elements holding only text become strings. Namespace prefixes are dropped.
+// Input after the root element is read and discarded.
+//
+// Example:
+//
+//	// <feed><entry id="1"><title>Go</title></entry></feed>
+//	// → {"feed":{"entry":{"@id":"1","title":"Go"}}}
+//	flow.Use(convert.XMLToJSON())
+func XMLToJSON() calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		decoder := xml.NewDecoder(req.Data)
+		for {
+			tok, err := decoder.Token()
+			if err != nil {
+				if err == io.EOF {
+					return calque.NewErr(req.Context, "XML document has no root element")
+				}
+				return calque.WrapErr(req.Context, err, "failed to read XML")
+			}
+			if start, ok := tok.(xml.StartElement); ok {
+				node, err := readXMLElement(decoder, start)
+				if err != nil {
+					return calque.WrapErr(req.Context, err, "failed to read XML")
+				}
+				if err := json.NewEncoder(res.Data).Encode(map[string]any{start.Name.Local: node.toJSON()}); err != nil {
+					return err
+				}
+				// Anything after the root, such as a trailing newline, is ignored
+				_, err = io.Copy(io.Discard, req.Data)
+				return err
+			}
+		}
+	})
+}
+
+// ExtractXML streams the elements or attributes matching an XPath-like path.
+//
+// Input: XML document
+// Output: newline-delimited JSON, one value per match
+// Behavior: STREAMING - each match is written as soon as its closing tag is read
+//
+// Supported path syntax:
+//   - "/rss/channel/item" - absolute path from the root element
+//   - "//item" or "item" - element at any depth (suffix match, e.g. "channel/item")
+//   - "*" - any element name in a segment
+//   - trailing "@attr" - the attribute value of matching elements
+//
+// Matched elements are converted as in XMLToJSON; attributes are emitted as
+// JSON strings. Useful for splitting RSS feeds, sitemaps and SOAP envelopes
+// into records for retrieval pipelines.
+//
+// Example:
+//
+//	// One JSON object per RSS item
+//	flow.Use(convert.ExtractXML("//item"))
+//
+//	// Every sitemap URL
+//	flow.Use(convert.ExtractXML("/urlset/url/loc"))
+func ExtractXML(path string) calque.Handler {
+	pattern := parseXMLPath(path)
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		decoder := xml.NewDecoder(req.Data)
+		encoder := json.NewEncoder(res.Data)
+		var stack []string
+
+		for {
+			if err := req.Context.Err(); err != nil {
+				return err
+			}
+			tok, err := decoder.Token()
+			if err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return calque.WrapErr(req.Context, err, "failed to read XML")
+			}
+
+			switch t := tok.(type) {
+			case xml.StartElement:
+				stack = append(stack, t.Name.Local)
+				if !pattern.matches(stack) {
+					continue
+				}
+				if pattern.attr != "" {
+					for _, a := range t.Attr {
+						if a.Name.Local == pattern.attr {
+							if err := encoder.Encode(a.Value); err != nil {
+								return err
+							}
+						}
+					}
+					continue
+				}
+				node, err := readXMLElement(decoder, t)
+				if err != nil {
+					return calque.WrapErr(req.Context, err, "failed to read XML")
+				}
+				stack = stack[:len(stack)-1]
+				if err := encoder.Encode(node.toJSON()); err != nil {
+					return err
+				}
+			case xml.EndElement:
+				if len(stack) > 0 {
+					stack = stack[:len(stack)-1]
+				}
+			}
+		}
+	})
+}
+
+// xmlPath is a parsed ExtractXML path
+type xmlPath struct {
+	segments []string
+	absolute bool
+	attr     string
+}
+
+func parseXMLPath(path string) xmlPath {
+	var p xmlPath
+	switch {
+	case strings.HasPrefix(path, "//"):
+		path = path[2:]
+	case strings.HasPrefix(path, "/"):
+		p.absolute = true
+		path = path[1:]
+	}
+	for _, seg := range strings.Split(path, "/") {
+		if seg == "" {
+			continue
+		}
+		if strings.HasPrefix(seg, "@") {
+			p.attr = seg[1:]
+			continue
+		}
+		p.segments = append(p.segments, seg)
+	}
+	return p
+}
+
+// matches reports whether the open element stack matches the path
+func (p xmlPath) matches(stack []string) bool {
+	if len(p.segments) == 0 || len(stack) < len(p.segments) {
+		return false
+	}
+	if p.absolute && len(stack) != len(p.segments) {
+		return false
+	}
+	offset := len(stack) - len(p.segments)
+	for i, seg := range p.segments {
+		if seg != "*" && seg != stack[offset+i] {
+			return false
+		}
+	}
+	return true
+}
+
+// xmlNode is a decoded element subtree
+type xmlNode struct {
+	attrs    []xml.Attr
+	names    []string // child names in first-seen order
+	children map[string][]*xmlNode
+	text     strings.Builder
+}
+
+// readXMLElement decodes the subtree of start, consuming its end element
+func readXMLElement(decoder *xml.Decoder, start xml.StartElement) (*xmlNode, error) {
+	node := &xmlNode{attrs: start.Attr}
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := readXMLElement(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+			if node.children == nil {
+				node.children = make(map[string][]*xmlNode)
+			}
+			name := t.Name.Local
+			if _, seen := node.children[name]; !seen {
+				node.names = append(node.names, name)
+			}
+			node.children[name] = append(node.children[name], child)
+		case xml.CharData:
+			node.text.Write(t)
+		case xml.EndElement:
+			return node, nil
+		}
+	}
+}
+
+// toJSON converts the node to JSON-compatible values
+func (n *xmlNode) toJSON() any {
+	text := strings.TrimSpace(n.text.String())
+	if len(n.attrs) == 0 && len(n.children) == 0 {
+		return text
+	}
+
+	obj := make(map[string]any, len(n.attrs)+len(n.names)+1)
+	for _, a := range n.attrs {
+		if a.Name.Space == "xmlns" || a.Name.Local == "xmlns" {
+			continue
+		}
+		obj["@"+a.Name.Local] = a.Value
+	}
+	for _, name := range n.names {
+		children := n.children[name]
+		if len(children) == 1 {
+			obj[name] = children[0].toJSON()
+			continue
+		}
+		values := make([]any, len(children))
+		for i, child := range children {
+			values[i] = child.toJSON()
+		}
+		obj[name] = values
+	}
+	if text != "" {
+		obj["#text"] = text
+	}
+	return obj
+}
@@ -0,0 +1,194 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+const testRSS = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel>
+    <title>Go Blog</title>
+    <item><title>Generics</title><link>https://go.dev/a</link><dc:creator>rsc</dc:creator></item>
+    <item><title>Iterators</title><link>https://go.dev/b</link><category domain="lang">go</category></item>
+  </channel>
+</rss>`
+
+func runXMLHandler(t *testing.T, h calque.Handler, input string) (string, error) {
+	t.Helper()
+	var buf bytes.Buffer
+	err := h.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader(input)), calque.NewResponse(&buf))
+	return buf.String(), err
+}
+
+func TestXMLToJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "text only element",
+			input:    `<greeting>hello</greeting>`,
+			expected: `{"greeting":"hello"}`,
+		},
+		{
+			name:     "attributes and repeated children",
+			input:    `<list kind="todo"><li>a</li><li>b</li><note>x</note></list>`,
+			expected: `{"list":{"@kind":"todo","li":["a","b"],"note":"x"}}`,
+		},
+		{
+			name:     "mixed text",
+			input:    `<p class="x">Hello <b>world</b></p>`,
+			expected: `{"p":{"#text":"Hello","@class":"x","b":"world"}}`,
+		},
+		{
+			name:    "malformed",
+			input:   `<a><b></a>`,
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			input:   ``,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runXMLHandler(t, XMLToJSON(), tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("XMLToJSON() error = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("XMLToJSON() error = %v", err)
+			}
+			if strings.TrimSpace(got) != tt.expected {
+				t.Errorf("XMLToJSON() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractXML(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		expected []string
+	}{
+		{
+			name: "descendant elements",
+			path: "//item",
+			expected: []string{
+				`{"creator":"rsc","link":"https://go.dev/a","title":"Generics"}`,
+				`{"category":{"#text":"go","@domain":"lang"},"link":"https://go.dev/b","title":"Iterators"}`,
+			},
+		},
+		{
+			name:     "absolute path",
+			path:     "/rss/channel/item/title",
+			expected: []string{`"Generics"`, `"Iterators"`},
+		},
+		{
+			name:     "absolute path does not match deeper elements",
+			path:     "/rss/title",
+			expected: nil,
+		},
+		{
+			name:     "relative suffix with wildcard",
+			path:     "channel/*/link",
+			expected: []string{`"https://go.dev/a"`, `"https://go.dev/b"`},
+		},
+		{
+			name:     "attribute",
+			path:     "//category/@domain",
+			expected: []string{`"lang"`},
+		},
+		{
+			name:     "root attribute",
+			path:     "/rss/@version",
+			expected: []string{`"2.0"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runXMLHandler(t, ExtractXML(tt.path), testRSS)
+			if err != nil {
+				t.Fatalf("ExtractXML() error = %v", err)
+			}
+			var lines []string
+			for _, line := range strings.Split(strings.TrimSpace(got), "\n") {
+				if line != "" {
+					lines = append(lines, line)
+				}
+			}
+			if len(lines) != len(tt.expected) {
+				t.Fatalf("ExtractXML() = %v, want %v", lines, tt.expected)
+			}
+			for i := range lines {
+				if lines[i] != tt.expected[i] {
+					t.Errorf("line %d = %s, want %s", i, lines[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+type testOrder struct {
+	XMLName xml.Name `xml:"order"`
+	ID      string   `xml:"id,attr"`
+	Total   float64  `xml:"total"`
+}
+
+func TestXMLConverters(t *testing.T) {
+	reader, err := ToXML(testOrder{ID: "42", Total: 9.5}).ToReader()
+	if err != nil {
+		t.Fatalf("ToXML().ToReader() error = %v", err)
+	}
+
+	var order testOrder
+	if err := FromXML(&order).FromReader(reader); err != nil {
+		t.Fatalf("FromXML().FromReader() error = %v", err)
+	}
+	if order.ID != "42" || order.Total != 9.5 {
+		t.Errorf("round trip = %+v, want ID 42 total 9.5", order)
+	}
+
+	if _, err := ToXML("<order><total>1</order>").ToReader(); err == nil {
+		t.Error("ToXML(invalid string) error = nil, want error")
+	}
+	if _, err := ToXML([]byte(`<order id="1"/>`)).ToReader(); err != nil {
+		t.Errorf("ToXML(valid bytes) error = %v", err)
+	}
+}
+
+func TestXMLToJSONTrailingData(t *testing.T) {
+	source := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		if err := calque.Read(req, new(string)); err != nil {
+			return err
+		}
+		if _, err := res.Data.Write([]byte(`<note><to>Ann</to></note>`)); err != nil {
+			return err
+		}
+		_, err := res.Data.Write([]byte("\n"))
+		return err
+	})
+
+	var out string
+	if err := calque.NewFlow().Use(source).Use(XMLToJSON()).Run(context.Background(), "", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := `{"note":{"to":"Ann"}}`; strings.TrimSpace(out) != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+}
@@ -0,0 +1,322 @@
+// Package debug provides an optional HTTP UI for inspecting calque flows.
+//
+// Like net/http/pprof, it is intended for development and internal debugging
+// endpoints only: it lets anyone who can reach it execute registered flows with
+// arbitrary input. Never expose it on a public interface.
+//
+// The UI lists registered flows and their handlers, shows live runs with
+// per-handler status, keeps a ring buffer of recent traces, and accepts test
+// inputs. Runs executed through Server.Run (including UI submissions) are tracked.
+//
+// Example:
+//
+//	flow := calque.NewFlow(calque.FlowConfig{Name: "summarize"}).
+//		Use(prompt.Template("Summarize: {{.Input}}")).
+//		Use(ai.Agent(client))
+//
+//	go debug.Serve("localhost:6061", flow)
+package debug
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultMaxRecent is the default number of completed traces kept per server.
+const DefaultMaxRecent = 50
+
+// DefaultMaxInputSize limits the size of test inputs submitted through the UI.
+const DefaultMaxInputSize = 1 << 20
+
+// ErrFlowNotFound is returned when running a flow name that isn't registered.
+var ErrFlowNotFound = errors.New("flow not registered")
+
+//go:embed index.html
+var indexHTML string
+
+var indexTemplate = template.Must(template.New("index").Parse(indexHTML))
+
+// FlowInfo describes a registered flow.
+type FlowInfo struct {
+	Name     string   `json:"name"`
+	Handlers []string `json:"handlers"`
+}
+
+// RunRecord is a completed run with its trace and output preview.
+type RunRecord struct {
+	ID     int64             `json:"id"`
+	Output string            `json:"output,omitempty"`
+	Trace  *calque.FlowTrace `json:"trace"`
+}
+
+// Server tracks registered flows, live runs and recent traces.
+type Server struct {
+	mu        sync.RWMutex
+	flows     map[string]*calque.Flow
+	order     []string
+	live      map[int64]*calque.LiveTrace
+	recent    []*RunRecord
+	maxRecent int
+	nextID    int64
+}
+
+// NewServer creates a debug server with the given flows registered.
+//
+// Flows are registered under their FlowConfig.Name, or "flow-N" if unnamed.
+func NewServer(flows ...*calque.Flow) *Server {
+	s := &Server{
+		flows:     make(map[string]*calque.Flow),
+		live:      make(map[int64]*calque.LiveTrace),
+		maxRecent: DefaultMaxRecent,
+	}
+	for i, f := range flows {
+		name := f.Name()
+		if name == "" {
+			name = fmt.Sprintf("flow-%d", i)
+		}
+		s.Register(name, f)
+	}
+	return s
+}
+
+// Serve starts a debug UI on addr for the given flows. It blocks like http.ListenAndServe.
+//
+// Example:
+//
+//	go func() { log.Println(debug.Serve("localhost:6061", flowA, flowB)) }()
+func Serve(addr string, flows ...*calque.Flow) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           NewServer(flows...).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return srv.ListenAndServe()
+}
+
+// Register adds or replaces a named flow.
+func (s *Server) Register(name string, flow *calque.Flow) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.flows[name]; !exists {
+		s.order = append(s.order, name)
+	}
+	s.flows[name] = flow
+}
+
+// SetMaxRecent sets how many completed runs are retained (default DefaultMaxRecent).
+func (s *Server) SetMaxRecent(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n > 0 {
+		s.maxRecent = n
+		s.trimRecent()
+	}
+}
+
+// Run executes a registered flow with tracing so it appears in the UI.
+//
+// Use this from application code to make production runs visible as live and
+// recent runs. The returned trace is the same one shown in the UI.
+func (s *Server) Run(ctx context.Context, name string, input any, output any) (*calque.FlowTrace, error) {
+	s.mu.Lock()
+	flow, ok := s.flows[name]
+	if !ok {
+		s.mu.Unlock()
+		return nil, calque.WrapErr(ctx, ErrFlowNotFound, fmt.Sprintf("debug: flow %q", name))
+	}
+	s.nextID++
+	id := s.nextID
+	live := calque.NewLiveTrace()
+	s.live[id] = live
+	s.mu.Unlock()
+
+	trace, err := flow.RunTraced(calque.WithLiveTrace(ctx, live), input, output)
+
+	record := &RunRecord{ID: id, Trace: trace}
+	if str, ok := output.(*string); ok && str != nil {
+		record.Output = *str
+	}
+
+	s.mu.Lock()
+	delete(s.live, id)
+	s.recent = append(s.recent, record)
+	s.trimRecent()
+	s.mu.Unlock()
+
+	return trace, err
+}
+
+// Flows returns information about registered flows in registration order.
+func (s *Server) Flows() []FlowInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	infos := make([]FlowInfo, 0, len(s.order))
+	for _, name := range s.order {
+		handlers := s.flows[name].Handlers()
+		names := make([]string, len(handlers))
+		for i, h := range handlers {
+			names[i] = calque.HandlerName(h)
+		}
+		infos = append(infos, FlowInfo{Name: name, Handlers: names})
+	}
+	return infos
+}
+
+// LiveRuns returns snapshots of runs that are currently executing, oldest first.
+func (s *Server) LiveRuns() []*RunRecord {
+	s.mu.RLock()
+	ids := make([]int64, 0, len(s.live))
+	lives := make(map[int64]*calque.LiveTrace, len(s.live))
+	for id, l := range s.live {
+		ids = append(ids, id)
+		lives[id] = l
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	runs := make([]*RunRecord, 0, len(ids))
+	for _, id := range ids {
+		if snap := lives[id].Snapshot(); snap != nil {
+			runs = append(runs, &RunRecord{ID: id, Trace: snap})
+		}
+	}
+	return runs
+}
+
+// RecentRuns returns completed runs, newest first.
+func (s *Server) RecentRuns() []*RunRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	runs := make([]*RunRecord, len(s.recent))
+	for i, r := range s.recent {
+		runs[len(s.recent)-1-i] = r
+	}
+	return runs
+}
+
+// trimRecent drops the oldest records beyond maxRecent (must be called with mutex held)
+func (s *Server) trimRecent() {
+	if over := len(s.recent) - s.maxRecent; over > 0 {
+		s.recent = append([]*RunRecord(nil), s.recent[over:]...)
+	}
+}
+
+// Handler returns the HTTP handler serving the UI and JSON API.
+//
+// Routes:
+//
+//	GET  /                         HTML dashboard
+//	GET  /api/flows                registered flows
+//	GET  /api/runs/live            live run snapshots
+//	GET  /api/runs/recent          recent completed runs
+//	GET  /api/runs/{id}            a single recent run
+//	POST /api/flows/{name}/run     run a flow with the request body as input
+//	POST /flows/{name}/run         form submission from the dashboard
+//
+// Mount under a prefix with http.StripPrefix.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleIndex)
+	mux.HandleFunc("GET /api/flows", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, s.Flows())
+	})
+	mux.HandleFunc("GET /api/runs/live", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, s.LiveRuns())
+	})
+	mux.HandleFunc("GET /api/runs/recent", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, s.RecentRuns())
+	})
+	mux.HandleFunc("GET /api/runs/{id}", s.handleGetRun)
+	mux.HandleFunc("POST /api/flows/{name}/run", s.handleAPIRun)
+	mux.HandleFunc("POST /flows/{name}/run", s.handleFormRun)
+	return mux
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, _ *http.Request) {
+	data := struct {
+		Flows  []FlowInfo
+		Live   []*RunRecord
+		Recent []*RunRecord
+	}{s.Flows(), s.LiveRuns(), s.RecentRuns()}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := indexTemplate.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleGetRun(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid run id"})
+		return
+	}
+	for _, run := range s.RecentRuns() {
+		if run.ID == id {
+			writeJSON(w, http.StatusOK, run)
+			return
+		}
+	}
+	writeJSON(w, http.StatusNotFound, map[string]string{"error": "run not found"})
+}
+
+func (s *Server) handleAPIRun(w http.ResponseWriter, r *http.Request) {
+	input, err := io.ReadAll(io.LimitReader(r.Body, DefaultMaxInputSize))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var output string
+	trace, err := s.Run(r.Context(), r.PathValue("name"), string(input), &output)
+	if errors.Is(err, ErrFlowNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	if err != nil {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, RunRecord{Output: output, Trace: trace})
+}
+
+func (s *Server) handleFormRun(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, DefaultMaxInputSize)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var output string
+	// Detach from the HTTP request so the redirect doesn't cancel the run
+	ctx := context.WithoutCancel(r.Context())
+	if _, err := s.Run(ctx, r.PathValue("name"), r.Form.Get("input"), &output); errors.Is(err, ErrFlowNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	// Relative redirect keeps working when mounted under a prefix
+	http.Redirect(w, r, "../../", http.StatusSeeOther)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
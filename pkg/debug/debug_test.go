@@ -0,0 +1,187 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func upperFlow() *calque.Flow {
+	return calque.NewFlow(calque.FlowConfig{Name: "upper"}).
+		UseFunc(func(req *calque.Request, res *calque.Response) error {
+			var input string
+			if err := calque.Read(req, &input); err != nil {
+				return err
+			}
+			return calque.Write(res, strings.ToUpper(input))
+		})
+}
+
+func TestServerRun(t *testing.T) {
+	srv := NewServer(upperFlow(), calque.NewFlow())
+
+	flows := srv.Flows()
+	if len(flows) != 2 || flows[0].Name != "upper" || flows[1].Name != "flow-1" {
+		t.Fatalf("unexpected flows: %+v", flows)
+	}
+	if len(flows[0].Handlers) != 1 {
+		t.Errorf("expected 1 handler, got %v", flows[0].Handlers)
+	}
+
+	var out string
+	trace, err := srv.Run(context.Background(), "upper", "hi", &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "HI" || trace.Flow != "upper" {
+		t.Errorf("unexpected result %q / trace flow %q", out, trace.Flow)
+	}
+
+	recent := srv.RecentRuns()
+	if len(recent) != 1 || recent[0].Output != "HI" {
+		t.Fatalf("expected recent run with output, got %+v", recent)
+	}
+
+	if _, err := srv.Run(context.Background(), "missing", "x", &out); !errors.Is(err, ErrFlowNotFound) {
+		t.Errorf("expected ErrFlowNotFound, got %v", err)
+	}
+}
+
+func TestServerLiveRuns(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	blocking := calque.NewFlow(calque.FlowConfig{Name: "slow"}).
+		UseFunc(func(req *calque.Request, res *calque.Response) error {
+			close(started)
+			<-release
+			var input string
+			if err := calque.Read(req, &input); err != nil {
+				return err
+			}
+			return calque.Write(res, input)
+		})
+
+	srv := NewServer(blocking)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var out string
+		_, _ = srv.Run(context.Background(), "slow", "x", &out)
+	}()
+
+	<-started
+	live := srv.LiveRuns()
+	if len(live) != 1 {
+		t.Fatalf("expected 1 live run, got %d", len(live))
+	}
+	if status := live[0].Trace.Handlers[0].Status; status != calque.TraceStatusRunning {
+		t.Errorf("expected running status, got %q", status)
+	}
+
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("run did not complete")
+	}
+	if len(srv.LiveRuns()) != 0 {
+		t.Error("expected no live runs after completion")
+	}
+}
+
+func TestServerMaxRecent(t *testing.T) {
+	srv := NewServer(upperFlow())
+	srv.SetMaxRecent(2)
+
+	for _, in := range []string{"a", "b", "c"} {
+		var out string
+		if _, err := srv.Run(context.Background(), "upper", in, &out); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+	}
+
+	recent := srv.RecentRuns()
+	if len(recent) != 2 || recent[0].Output != "C" || recent[1].Output != "B" {
+		t.Errorf("expected newest two runs, got %+v", recent)
+	}
+}
+
+func TestServerHTTP(t *testing.T) {
+	srv := NewServer(upperFlow())
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	// API run
+	resp, err := http.Post(ts.URL+"/api/flows/upper/run", "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	var record RunRecord
+	if err := json.NewDecoder(resp.Body).Decode(&record); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || record.Output != "HELLO" {
+		t.Errorf("unexpected API run response: %d %+v", resp.StatusCode, record)
+	}
+
+	// Unknown flow
+	resp, err = http.Post(ts.URL+"/api/flows/nope/run", "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", resp.StatusCode)
+	}
+
+	// Form run redirects to dashboard, which renders the run
+	resp, err = http.PostForm(ts.URL+"/flows/upper/run", url.Values{"input": {"form input"}})
+	if err != nil {
+		t.Fatalf("form POST failed: %v", err)
+	}
+	body := readBody(t, resp)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "FORM INPUT") {
+		t.Errorf("expected dashboard with run output, got %d", resp.StatusCode)
+	}
+
+	// JSON endpoints
+	for _, path := range []string{"/api/flows", "/api/runs/live", "/api/runs/recent", "/api/runs/1"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s: expected 200, got %d", path, resp.StatusCode)
+		}
+	}
+
+	resp, err = http.Get(ts.URL + "/api/runs/999")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown run, got %d", resp.StatusCode)
+	}
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body failed: %v", err)
+	}
+	return string(data)
+}
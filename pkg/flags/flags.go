@@ -0,0 +1,369 @@
+// Package flags provides runtime flags for calque flows, loaded from a remote
+// flag or config provider, so model names, temperatures and prompt versions
+// can change without a redeploy.
+//
+// A Flags value polls its Provider and notifies OnChange listeners with the
+// keys that changed. Client, PinPrompts and ClearOnChange wire those change
+// events to AI clients, the prompt registry and response caches.
+package flags
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultRefresh is how often Watch polls the provider.
+const DefaultRefresh = 30 * time.Second
+
+// Provider loads the current flag values from a flag service, config store
+// or file.
+type Provider interface {
+	Load(ctx context.Context) (map[string]string, error)
+}
+
+// ProviderFunc adapts a function to the Provider interface.
+type ProviderFunc func(ctx context.Context) (map[string]string, error)
+
+// Load implements Provider.
+func (f ProviderFunc) Load(ctx context.Context) (map[string]string, error) {
+	return f(ctx)
+}
+
+// Static returns a provider that always loads values.
+func Static(values map[string]string) Provider {
+	values = maps.Clone(values)
+	return ProviderFunc(func(context.Context) (map[string]string, error) {
+		return values, nil
+	})
+}
+
+// HTTP returns a provider that GETs a JSON object of flags from url.
+// client nil uses http.DefaultClient.
+//
+// Numbers and booleans are loaded as their JSON text and nested objects are
+// flattened with "." ({"summarize": {"model": "gpt-4o"}} loads
+// "summarize.model"), so flag services that export JSON can be used as is.
+//
+// Example:
+//
+//	provider := flags.HTTP("http://flags.internal/v1/calque.json", nil)
+func HTTP(url string, client *http.Client) Provider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return ProviderFunc(func(ctx context.Context) (map[string]string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, calque.InvalidInput(calque.WrapErr(ctx, err, "invalid flag provider URL"))
+		}
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, calque.Retryable(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			err := calque.NewErr(ctx, fmt.Sprintf("flag provider returned HTTP %d: %s", resp.StatusCode, body))
+			if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+				return nil, calque.Retryable(err)
+			}
+			return nil, err
+		}
+
+		var doc map[string]json.RawMessage
+		if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to decode flags")
+		}
+		values := map[string]string{}
+		if err := flatten(values, "", doc); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to decode flags")
+		}
+		return values, nil
+	})
+}
+
+// flatten adds the leaves of a JSON object to values, keyed by their dotted path
+func flatten(values map[string]string, prefix string, doc map[string]json.RawMessage) error {
+	for key, raw := range doc {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		raw = bytes.TrimSpace(raw)
+		switch {
+		case len(raw) > 0 && raw[0] == '{':
+			var nested map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &nested); err != nil {
+				return fmt.Errorf("flag %s: %w", key, err)
+			}
+			if err := flatten(values, key, nested); err != nil {
+				return err
+			}
+		case len(raw) > 0 && raw[0] == '"':
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return fmt.Errorf("flag %s: %w", key, err)
+			}
+			values[key] = s
+		case string(raw) == "null":
+		default:
+			values[key] = string(raw)
+		}
+	}
+	return nil
+}
+
+// Config configures Flags.
+type Config struct {
+	// Refresh is how often Watch polls the provider. Default: DefaultRefresh
+	Refresh time.Duration
+	// Defaults are used for keys the provider does not set.
+	Defaults map[string]string
+}
+
+// Change is an update to one flag. Old or New is empty when the key was
+// added or removed.
+type Change struct {
+	Key string
+	Old string
+	New string
+}
+
+// listener is an OnChange callback and the keys it watches
+type listener struct {
+	fn   func(context.Context, []Change)
+	keys []string
+}
+
+// Flags holds the current flag values of a Provider.
+//
+// Reads are served from memory; Refresh or Watch reload the provider and
+// notify the OnChange listeners whose keys changed. If a reload fails the
+// last values stay in use.
+//
+// Example:
+//
+//	f := flags.New(flags.HTTP("http://flags.internal/v1/calque.json", nil), flags.Config{
+//		Defaults: map[string]string{"summarize.model": "gpt-4o-mini"},
+//	})
+//	if err := f.Watch(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+//	temperature := f.Float("summarize.temperature", 0.2)
+type Flags struct {
+	provider Provider
+	config   Config
+
+	mu        sync.RWMutex
+	values    map[string]string
+	listeners []listener
+	refreshMu sync.Mutex
+}
+
+// New creates flags loaded from provider. Values are the defaults until
+// Refresh or Watch is called.
+func New(provider Provider, config ...Config) *Flags {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Refresh <= 0 {
+		cfg.Refresh = DefaultRefresh
+	}
+	return &Flags{
+		provider: provider,
+		config:   cfg,
+		values:   maps.Clone(cfg.Defaults),
+	}
+}
+
+// Refresh loads the provider once and notifies listeners of the changes.
+//
+// Listeners run synchronously, so when Refresh returns prompts are pinned and
+// caches are cleared.
+func (f *Flags) Refresh(ctx context.Context) error {
+	f.refreshMu.Lock()
+	defer f.refreshMu.Unlock()
+
+	loaded, err := f.provider.Load(ctx)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to load flags")
+	}
+	values := maps.Clone(f.config.Defaults)
+	if values == nil {
+		values = map[string]string{}
+	}
+	maps.Copy(values, loaded)
+
+	f.mu.Lock()
+	changes := diff(f.values, values)
+	f.values = values
+	listeners := slices.Clone(f.listeners)
+	f.mu.Unlock()
+
+	if len(changes) == 0 {
+		return nil
+	}
+	calque.Logger(ctx).Info("flags changed", "keys", keys(changes))
+	for _, l := range listeners {
+		if matched := match(changes, l.keys); len(matched) > 0 {
+			l.fn(ctx, matched)
+		}
+	}
+	return nil
+}
+
+// Watch loads the provider, then keeps polling it every Config.Refresh until
+// ctx is cancelled. It returns the error of the first load; later failures
+// are logged and the last values stay in use.
+func (f *Flags) Watch(ctx context.Context) error {
+	if err := f.Refresh(ctx); err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(f.config.Refresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := f.Refresh(ctx); err != nil && ctx.Err() == nil {
+					calque.Logger(ctx).Warn("flag refresh failed, keeping last values", "error", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// OnChange calls fn with the changes to keys after each Refresh that changes
+// any of them. A key ending in "." watches every key with that prefix, and no
+// keys watches all flags.
+//
+// Example:
+//
+//	f.OnChange(func(ctx context.Context, changes []flags.Change) {
+//		for _, c := range changes {
+//			log.Printf("%s: %q -> %q", c.Key, c.Old, c.New)
+//		}
+//	}, "summarize.")
+func (f *Flags) OnChange(fn func(ctx context.Context, changes []Change), keys ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.listeners = append(f.listeners, listener{fn: fn, keys: keys})
+}
+
+// Lookup returns the value of a flag and whether it is set.
+func (f *Flags) Lookup(key string) (string, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	v, ok := f.values[key]
+	return v, ok
+}
+
+// Values returns a copy of all flag values.
+func (f *Flags) Values() map[string]string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return maps.Clone(f.values)
+}
+
+// String returns a flag, or def when it is unset or empty.
+func (f *Flags) String(key, def string) string {
+	if v, ok := f.Lookup(key); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// Float returns a flag parsed as a float, or def when it is unset or invalid.
+func (f *Flags) Float(key string, def float64) float64 {
+	return parse(f, key, def, func(s string) (float64, error) { return strconv.ParseFloat(s, 64) })
+}
+
+// Int returns a flag parsed as an int, or def when it is unset or invalid.
+func (f *Flags) Int(key string, def int) int {
+	return parse(f, key, def, strconv.Atoi)
+}
+
+// Bool returns a flag parsed by strconv.ParseBool, or def when it is unset or
+// invalid.
+func (f *Flags) Bool(key string, def bool) bool {
+	return parse(f, key, def, strconv.ParseBool)
+}
+
+// Duration returns a flag parsed by time.ParseDuration, or def when it is
+// unset or invalid.
+func (f *Flags) Duration(key string, def time.Duration) time.Duration {
+	return parse(f, key, def, time.ParseDuration)
+}
+
+// parse converts a flag, falling back to def so a bad remote value does not
+// break the flow
+func parse[T any](f *Flags, key string, def T, conv func(string) (T, error)) T {
+	s, ok := f.Lookup(key)
+	if !ok || s == "" {
+		return def
+	}
+	v, err := conv(strings.TrimSpace(s))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// diff returns the changes from old to values, sorted by key
+func diff(old, values map[string]string) []Change {
+	var changes []Change
+	for key, v := range values {
+		if prev, ok := old[key]; !ok || prev != v {
+			changes = append(changes, Change{Key: key, Old: old[key], New: v})
+		}
+	}
+	for key, prev := range old {
+		if _, ok := values[key]; !ok {
+			changes = append(changes, Change{Key: key, Old: prev})
+		}
+	}
+	slices.SortFunc(changes, func(a, b Change) int { return strings.Compare(a.Key, b.Key) })
+	return changes
+}
+
+// match returns the changes to the watched keys
+func match(changes []Change, watched []string) []Change {
+	if len(watched) == 0 {
+		return changes
+	}
+	var matched []Change
+	for _, c := range changes {
+		for _, key := range watched {
+			if c.Key == key || (strings.HasSuffix(key, ".") && strings.HasPrefix(c.Key, key)) {
+				matched = append(matched, c)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+func keys(changes []Change) []string {
+	names := make([]string, len(changes))
+	for i, c := range changes {
+		names[i] = c.Key
+	}
+	return names
+}
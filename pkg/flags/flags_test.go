@@ -0,0 +1,319 @@
+package flags
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/cache"
+	"github.com/calque-ai/go-calque/pkg/middleware/prompt"
+)
+
+// mutable is a provider whose values tests change between refreshes
+type mutable struct {
+	mu     sync.Mutex
+	values map[string]string
+	err    error
+}
+
+func (m *mutable) set(values map[string]string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values, m.err = values, err
+}
+
+func (m *mutable) Load(context.Context) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values, m.err
+}
+
+func TestFlagsRefresh(t *testing.T) {
+	ctx := context.Background()
+	provider := &mutable{values: map[string]string{"model": "small", "temperature": "0.5"}}
+	f := New(provider, Config{Defaults: map[string]string{"model": "tiny", "retries": "2"}})
+
+	if got := f.String("model", ""); got != "tiny" {
+		t.Errorf("String() before Refresh = %q, want default", got)
+	}
+	if err := f.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var got [][]Change
+	f.OnChange(func(_ context.Context, changes []Change) { got = append(got, changes) }, "model", "temperature")
+
+	provider.set(map[string]string{"model": "large", "temperature": "0.5", "extra": "x"}, nil)
+	if err := f.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]Change{{{Key: "model", Old: "small", New: "large"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+
+	if err := f.Refresh(ctx); err != nil || len(got) != 1 {
+		t.Errorf("unchanged Refresh notified listeners: %v, %v", got, err)
+	}
+
+	provider.set(nil, errors.New("flag service down"))
+	if err := f.Refresh(ctx); err == nil {
+		t.Error("Refresh() with failing provider error = nil")
+	}
+	if got := f.String("model", ""); got != "large" {
+		t.Errorf("String() after failed Refresh = %q, want last value", got)
+	}
+
+	if got := f.Float("temperature", 1); got != 0.5 {
+		t.Errorf("Float() = %v", got)
+	}
+	if got := f.Int("retries", 0); got != 2 {
+		t.Errorf("Int() from default = %v", got)
+	}
+	if got := f.Int("model", 7); got != 7 {
+		t.Errorf("Int() of invalid value = %v, want default", got)
+	}
+	if got := f.Bool("missing", true); !got {
+		t.Errorf("Bool() of missing key = %v, want default", got)
+	}
+}
+
+func TestFlagsOnChangePrefix(t *testing.T) {
+	provider := &mutable{values: map[string]string{"summarize.model": "a", "summarize.top_p": "1", "other": "x"}}
+	f := New(provider)
+
+	var got []Change
+	f.OnChange(func(_ context.Context, changes []Change) { got = changes }, "summarize.")
+	provider.set(map[string]string{"summarize.model": "a", "other": "y"}, nil)
+	if err := f.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Change{{Key: "summarize.model", New: "a"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"summarize": {"model": "gpt-4o", "temperature": 0.3, "stream": true, "stop": null}, "prompt.summarize": "v2"}`))
+	}))
+	defer srv.Close()
+
+	values, err := HTTP(srv.URL+"/flags.json", nil).Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"summarize.model":       "gpt-4o",
+		"summarize.temperature": "0.3",
+		"summarize.stream":      "true",
+		"prompt.summarize":      "v2",
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("Load() = %v, want %v", values, want)
+	}
+
+	if _, err := HTTP(srv.URL+"/down", nil).Load(context.Background()); !calque.IsRetryable(err) {
+		t.Errorf("Load() on 503 error = %v, want retryable", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	provider := &mutable{values: map[string]string{"model": "a"}}
+	f := New(provider, Config{Refresh: 10 * time.Millisecond})
+	changed := make(chan Change, 1)
+	f.OnChange(func(_ context.Context, changes []Change) { changed <- changes[0] }, "model")
+
+	if err := f.Watch(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c := <-changed; c.New != "a" {
+		t.Errorf("initial change = %v", c)
+	}
+	provider.set(map[string]string{"model": "b"}, nil)
+	select {
+	case c := <-changed:
+		if c.New != "b" {
+			t.Errorf("change = %v", c)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Watch did not pick up the change")
+	}
+
+	if err := New(&mutable{err: errors.New("down")}).Watch(ctx); err == nil {
+		t.Error("Watch() with failing provider error = nil")
+	}
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	provider := &mutable{values: map[string]string{"model": "small"}}
+	f := New(provider)
+	if err := f.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	builds := 0
+	client := Client(f, func(f *Flags) (ai.Client, error) {
+		builds++
+		model := f.String("model", "")
+		if model == "broken" {
+			return nil, errors.New("unknown model")
+		}
+		return ai.NewMockClient("answer from " + model), nil
+	}, "model")
+	flow := calque.NewFlow().Use(ai.Agent(client))
+
+	run := func() string {
+		t.Helper()
+		var out string
+		if err := flow.Run(ctx, "question", &out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	if got := run(); got != "answer from small" {
+		t.Errorf("output = %q", got)
+	}
+	run()
+	if builds != 1 {
+		t.Errorf("builds = %d, want client reused", builds)
+	}
+
+	provider.set(map[string]string{"model": "large"}, nil)
+	if err := f.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := run(); got != "answer from large" {
+		t.Errorf("output after change = %q", got)
+	}
+
+	provider.set(map[string]string{"model": "broken"}, nil)
+	if err := f.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := run(); got != "answer from large" {
+		t.Errorf("output after failed rebuild = %q, want previous client", got)
+	}
+}
+
+func TestClientBuildError(t *testing.T) {
+	client := Client(New(Static(nil)), func(*Flags) (ai.Client, error) {
+		return nil, errors.New("no api key")
+	})
+	var out string
+	err := calque.NewFlow().Use(ai.Agent(client)).Run(context.Background(), "q", &out)
+	if err == nil {
+		t.Error("Run() error = nil, want build error")
+	}
+}
+
+func TestPinPrompts(t *testing.T) {
+	ctx := context.Background()
+	reg := prompt.NewRegistry()
+	for _, v := range []string{"v1", "v2", "v3"} {
+		if err := reg.Register("summarize", v, v+": {{.Input}}"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	version := func() string {
+		t.Helper()
+		v, err := reg.Get("summarize")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v.Version
+	}
+
+	provider := &mutable{values: map[string]string{"prompt.summarize": "v1"}}
+	f := New(provider)
+	if err := f.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := PinPrompts(ctx, f, reg, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := version(); got != "v1" {
+		t.Errorf("version = %q, want pinned v1", got)
+	}
+
+	provider.set(map[string]string{"prompt.summarize": "v2"}, nil)
+	if err := f.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := version(); got != "v2" {
+		t.Errorf("version after change = %q", got)
+	}
+
+	provider.set(map[string]string{"prompt.summarize": "v9"}, nil)
+	if err := f.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := version(); got != "v2" {
+		t.Errorf("version after unknown pin = %q, want previous pin", got)
+	}
+
+	provider.set(map[string]string{}, nil)
+	if err := f.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := version(); got != "v3" {
+		t.Errorf("version after removing flag = %q, want latest", got)
+	}
+
+	bad := New(Static(map[string]string{"prompt.summarize": "v9"}))
+	if err := bad.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := PinPrompts(ctx, bad, reg, ""); !calque.IsInvalidInput(err) {
+		t.Errorf("PinPrompts() with unknown version error = %v, want invalid input", err)
+	}
+}
+
+func TestClearOnChange(t *testing.T) {
+	ctx := context.Background()
+	provider := &mutable{values: map[string]string{"model": "small", "unrelated": "1"}}
+	f := New(provider)
+	if err := f.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := cache.NewCache()
+	ClearOnChange(f, responses, "model")
+	if err := responses.Set("key", []byte("cached"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	provider.set(map[string]string{"model": "small", "unrelated": "2"}, nil)
+	if err := f.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !responses.Exists("key") {
+		t.Error("unrelated flag change cleared the cache")
+	}
+
+	provider.set(map[string]string{"model": "large", "unrelated": "2"}, nil)
+	if err := f.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if responses.Exists("key") {
+		t.Error("model change did not clear the cache")
+	}
+}
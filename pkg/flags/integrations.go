@@ -0,0 +1,145 @@
+package flags
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/prompt"
+)
+
+// PromptPrefix is the flag key prefix PinPrompts reads prompt versions from,
+// matching prompt.VersionMetadataPrefix: "prompt.summarize" = "v2".
+const PromptPrefix = prompt.VersionMetadataPrefix
+
+// Client returns an ai.Client built from flags, rebuilt when any of keys
+// changes, so the model and its settings follow the flag provider.
+//
+// build runs on the first Chat and on the first Chat after a change. If a
+// rebuild fails the previous client keeps serving and the error is logged.
+//
+// Example:
+//
+//	client := flags.Client(f, func(f *flags.Flags) (ai.Client, error) {
+//		temperature := float32(f.Float("summarize.temperature", 0.2))
+//		return openai.New(f.String("summarize.model", "gpt-4o-mini"),
+//			openai.WithConfig(&openai.Config{Temperature: &temperature}))
+//	}, "summarize.model", "summarize.temperature")
+//
+//	flow.Use(ai.Agent(client))
+func Client(f *Flags, build func(f *Flags) (ai.Client, error), keys ...string) ai.Client {
+	c := &dynamicClient{flags: f, build: build}
+	f.OnChange(func(context.Context, []Change) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.stale = true
+	}, keys...)
+	return c
+}
+
+type dynamicClient struct {
+	flags *Flags
+	build func(*Flags) (ai.Client, error)
+
+	mu     sync.Mutex
+	client ai.Client
+	stale  bool
+}
+
+// Chat implements ai.Client with the client for the current flags.
+func (c *dynamicClient) Chat(r *calque.Request, w *calque.Response, opts *ai.AgentOptions) error {
+	client, err := c.current(r.Context)
+	if err != nil {
+		return err
+	}
+	return client.Chat(r, w, opts)
+}
+
+// current returns the client, building it first if the flags changed
+func (c *dynamicClient) current(ctx context.Context) (ai.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client != nil && !c.stale {
+		return c.client, nil
+	}
+	client, err := c.build(c.flags)
+	if err != nil {
+		if c.client == nil {
+			return nil, calque.WrapErr(ctx, err, "failed to build client from flags")
+		}
+		calque.Logger(ctx).Warn("failed to rebuild client from flags, keeping previous client", "error", err)
+		c.stale = false
+		return c.client, nil
+	}
+	c.client, c.stale = client, false
+	return client, nil
+}
+
+// PinPrompts pins prompt versions in reg from the flags under prefix
+// (PromptPrefix if empty): "prompt.summarize" = "v2" pins summarize to v2, and
+// an empty or removed flag unpins it.
+//
+// Current flags are applied before PinPrompts returns, reporting versions the
+// registry does not have. Later changes are applied on Refresh, logging
+// unknown versions and keeping the previous pin.
+//
+// Example:
+//
+//	if err := flags.PinPrompts(ctx, f, reg, ""); err != nil {
+//		log.Fatal(err)
+//	}
+//	flow.Use(reg.Use("summarize")).Use(ai.Agent(client))
+func PinPrompts(ctx context.Context, f *Flags, reg *prompt.Registry, prefix string) error {
+	if prefix == "" {
+		prefix = PromptPrefix
+	}
+	f.OnChange(func(ctx context.Context, changes []Change) {
+		for _, c := range changes {
+			if err := pin(reg, strings.TrimPrefix(c.Key, prefix), c.New); err != nil {
+				calque.Logger(ctx).Warn("prompt flag not applied", "flag", c.Key, "error", err)
+			}
+		}
+	}, prefix)
+
+	for key, version := range f.Values() {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if err := pin(reg, strings.TrimPrefix(key, prefix), version); err != nil {
+			return calque.InvalidInput(calque.WrapErr(ctx, err, "prompt flag "+key+" not applied"))
+		}
+	}
+	return nil
+}
+
+func pin(reg *prompt.Registry, name, version string) error {
+	if version == "" {
+		reg.Unpin(name)
+		return nil
+	}
+	return reg.Pin(name, version)
+}
+
+// Clearer is a cache that can drop all its entries, such as cache.Memory.
+type Clearer interface {
+	Clear() error
+}
+
+// ClearOnChange clears cache whenever any of keys changes (all flags if
+// none), so responses produced with an old model or prompt are not served.
+//
+// Example:
+//
+//	responses := cache.NewCache()
+//	flags.ClearOnChange(f, responses, "summarize.", flags.PromptPrefix+"summarize")
+//	flow.Use(responses.Cache(ai.Agent(client), time.Hour))
+func ClearOnChange(f *Flags, cache Clearer, keys ...string) {
+	f.OnChange(func(ctx context.Context, changes []Change) {
+		if err := cache.Clear(); err != nil {
+			calque.Logger(ctx).Warn("failed to clear cache after flag change", "error", err)
+		}
+	}, keys...)
+}
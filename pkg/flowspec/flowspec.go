@@ -0,0 +1,124 @@
+// Package flowspec builds calque flows from declarative, versioned specs.
+//
+// A Spec lists steps by registered type name with per-step config, so
+// pipelines can be stored as JSON or YAML and built at startup. Specs carry
+// a version: a Registry migrates older specs to its current version before
+// building, so stored definitions keep working after step types are renamed
+// or their config changes.
+//
+// Example:
+//
+//	reg := flowspec.NewRegistry()
+//	reg.Register("agent", func(sc flowspec.StepContext) (calque.Handler, error) {
+//		client, err := calque.Resolve[ai.Client](sc.Container)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return ai.Agent(client), nil
+//	})
+//
+//	spec, err := flowspec.Parse(data)
+//	flow, err := reg.Build(ctx, spec, container)
+package flowspec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Spec is a declarative flow definition.
+type Spec struct {
+	Version int    `json:"version" yaml:"version"`     // spec version (0 is treated as 1)
+	Name    string `json:"name,omitempty" yaml:"name"` // flow name (FlowConfig.Name)
+	Steps   []Step `json:"steps" yaml:"steps"`         // handlers in flow order
+}
+
+// Step is one handler in a Spec.
+type Step struct {
+	Type   string         `json:"type" yaml:"type"`               // registered step type
+	Config map[string]any `json:"config,omitempty" yaml:"config"` // type-specific settings
+	Steps  []Step         `json:"steps,omitempty" yaml:"steps"`   // child steps for composite types
+}
+
+// Parse decodes a spec from JSON or YAML.
+//
+// Input is treated as JSON when it starts with '{', otherwise as YAML.
+//
+// Example:
+//
+//	spec, err := flowspec.Parse([]byte(`
+//	version: 2
+//	name: summarize
+//	steps:
+//	  - type: prompt
+//	    config: {template: "Summarize: {{.Input}}"}
+//	  - type: agent
+//	`))
+func Parse(data []byte) (*Spec, error) {
+	var spec Spec
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &spec); err != nil {
+			return nil, fmt.Errorf("invalid flow spec: %w", err)
+		}
+		return &spec, nil
+	}
+	if err := yaml.Unmarshal(trimmed, &spec); err != nil {
+		return nil, fmt.Errorf("invalid flow spec: %w", err)
+	}
+	return &spec, nil
+}
+
+// Decode converts the step config into v (a pointer to a struct with json tags).
+func (s Step) Decode(v any) error {
+	if len(s.Config) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(s.Config)
+	if err != nil {
+		return fmt.Errorf("step %s: encode config: %w", s.Type, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("step %s: invalid config: %w", s.Type, err)
+	}
+	return nil
+}
+
+// clone returns a deep copy of the spec so migrations never modify the caller's value
+func (s *Spec) clone() *Spec {
+	out := *s
+	out.Steps = cloneSteps(s.Steps)
+	return &out
+}
+
+func cloneSteps(steps []Step) []Step {
+	if steps == nil {
+		return nil
+	}
+	out := make([]Step, len(steps))
+	for i, st := range steps {
+		out[i] = Step{Type: st.Type, Config: cloneMap(st.Config), Steps: cloneSteps(st.Steps)}
+	}
+	return out
+}
+
+func cloneMap(m map[string]any) map[string]any {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		switch v := v.(type) {
+		case map[string]any:
+			out[k] = cloneMap(v)
+		case []any:
+			out[k] = append([]any(nil), v...)
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
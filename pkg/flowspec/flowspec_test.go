@@ -0,0 +1,194 @@
+package flowspec
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// testRegistry registers "suffix" (appends config.text) and "upper" step types
+func testRegistry() *Registry {
+	reg := NewRegistry()
+	reg.Register("suffix", func(sc StepContext) (calque.Handler, error) {
+		var cfg struct {
+			Text string `json:"text"`
+		}
+		if err := sc.Decode(&cfg); err != nil {
+			return nil, err
+		}
+		if cfg.Text == "" && sc.Container != nil {
+			text, err := calque.Resolve[string](sc.Container)
+			if err != nil {
+				return nil, err
+			}
+			cfg.Text = text
+		}
+		return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+			var s string
+			if err := calque.Read(req, &s); err != nil {
+				return err
+			}
+			return calque.Write(res, s+cfg.Text)
+		}), nil
+	})
+	reg.Register("upper", func(StepContext) (calque.Handler, error) {
+		return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+			var s string
+			if err := calque.Read(req, &s); err != nil {
+				return err
+			}
+			return calque.Write(res, strings.ToUpper(s))
+		}), nil
+	})
+	return reg
+}
+
+func run(t *testing.T, flow *calque.Flow, input string) string {
+	t.Helper()
+	var out string
+	if err := flow.Run(context.Background(), input, &out); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	return out
+}
+
+func TestParseAndBuild(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "yaml",
+			data: `
+version: 1
+name: shout
+steps:
+  - type: upper
+  - type: flow
+    steps:
+      - type: suffix
+        config: {text: "!"}
+`,
+		},
+		{
+			name: "json",
+			data: `{"version": 1, "name": "shout", "steps": [{"type": "upper"}, {"type": "flow", "steps": [{"type": "suffix", "config": {"text": "!"}}]}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := Parse([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			flow, err := testRegistry().Build(context.Background(), spec, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if flow.Name() != "shout" {
+				t.Errorf("flow name = %q", flow.Name())
+			}
+			if got := run(t, flow, "hi"); got != "HI!" {
+				t.Errorf("output = %q, want %q", got, "HI!")
+			}
+		})
+	}
+}
+
+func TestBuildWithContainer(t *testing.T) {
+	c := calque.NewContainer()
+	calque.ProvideValue(c, "?")
+	spec := &Spec{Steps: []Step{{Type: "suffix"}}}
+
+	flow, err := testRegistry().Build(context.Background(), spec, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := run(t, flow, "hi"); got != "hi?" {
+		t.Errorf("output = %q", got)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	reg := testRegistry()
+	tests := []struct {
+		name string
+		spec *Spec
+		want string
+	}{
+		{name: "unknown type", spec: &Spec{Steps: []Step{{Type: "upper"}, {Type: "flow", Steps: []Step{{Type: "nope"}}}}}, want: `steps[1].steps[0]: unknown step type "nope"`},
+		{name: "bad config", spec: &Spec{Steps: []Step{{Type: "suffix", Config: map[string]any{"text": 3}}}}, want: "invalid config"},
+		{name: "missing dependency", spec: &Spec{Steps: []Step{{Type: "suffix"}}}, want: "steps[0]: failed to build suffix step"},
+		{name: "newer version", spec: &Spec{Version: 7}, want: "newer than supported version 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := calque.NewContainer()
+			_, err := reg.Build(context.Background(), tt.spec, c)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	reg := testRegistry()
+	// v1 -> v2: "shout" was renamed to "upper"
+	reg.Migrate(1, func(spec *Spec) error {
+		Walk(spec, func(step *Step) {
+			if step.Type == "shout" {
+				step.Type = "upper"
+			}
+		})
+		return nil
+	})
+	// v2 -> v3: suffix config key "value" became "text"
+	reg.Migrate(2, func(spec *Spec) error {
+		Walk(spec, func(step *Step) {
+			if v, ok := step.Config["value"]; step.Type == "suffix" && ok {
+				step.Config["text"] = v
+				delete(step.Config, "value")
+			}
+		})
+		return nil
+	})
+	if reg.Version() != 3 {
+		t.Fatalf("registry version = %d, want 3", reg.Version())
+	}
+
+	stored := &Spec{Steps: []Step{
+		{Type: "flow", Steps: []Step{{Type: "shout"}}},
+		{Type: "suffix", Config: map[string]any{"value": "!"}},
+	}}
+	upgraded, err := reg.Upgrade(stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upgraded.Version != 3 || upgraded.Steps[0].Steps[0].Type != "upper" || upgraded.Steps[1].Config["text"] != "!" {
+		t.Errorf("upgraded = %+v", upgraded)
+	}
+	if stored.Version != 0 || stored.Steps[0].Steps[0].Type != "shout" || stored.Steps[1].Config["value"] != "!" {
+		t.Error("Upgrade modified the caller's spec")
+	}
+
+	flow, err := reg.Build(context.Background(), stored, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := run(t, flow, "hi"); got != "HI!" {
+		t.Errorf("output = %q", got)
+	}
+
+	failing := NewRegistry()
+	boom := errors.New("boom")
+	failing.Migrate(1, func(*Spec) error { return boom })
+	if _, err := failing.Upgrade(&Spec{}); !errors.Is(err, boom) {
+		t.Errorf("failing migration error = %v", err)
+	}
+}
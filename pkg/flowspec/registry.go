@@ -0,0 +1,213 @@
+package flowspec
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// StepContext is passed to a Factory when building a step.
+type StepContext struct {
+	Context   context.Context   // build context
+	Container *calque.Container // dependency container passed to Build (may be nil)
+	Step      Step              // the step being built (after migration)
+	Children  []calque.Handler  // built child steps, in order
+}
+
+// Decode converts the step config into v.
+func (sc StepContext) Decode(v any) error {
+	return sc.Step.Decode(v)
+}
+
+// Factory builds the handler for a step.
+type Factory func(sc StepContext) (calque.Handler, error)
+
+// Migration upgrades a spec from one version to the next, in place.
+//
+// Migrations typically rename step types or rewrite step config; use Walk to
+// visit nested steps.
+type Migration func(spec *Spec) error
+
+// Registry maps step types to factories and migrates specs to its version.
+//
+// A new registry is at version 1 and has one built-in step type, "flow",
+// which runs its child steps as a nested calque.Flow. Safe for concurrent use.
+type Registry struct {
+	mu         sync.RWMutex
+	version    int
+	factories  map[string]Factory
+	migrations map[int]Migration // keyed by the version they upgrade from
+}
+
+// NewRegistry creates a registry at spec version 1 with the built-in "flow" step type.
+func NewRegistry() *Registry {
+	r := &Registry{
+		version:    1,
+		factories:  make(map[string]Factory),
+		migrations: make(map[int]Migration),
+	}
+	r.Register("flow", func(sc StepContext) (calque.Handler, error) {
+		flow := calque.NewFlow()
+		for _, child := range sc.Children {
+			flow.Use(child)
+		}
+		return flow, nil
+	})
+	return r
+}
+
+// Register adds or replaces the factory for a step type.
+func (r *Registry) Register(stepType string, factory Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factories[stepType] = factory
+}
+
+// Types returns the registered step types, sorted.
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	types := make([]string, 0, len(r.factories))
+	for t := range r.factories {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Version returns the spec version the registry builds.
+func (r *Registry) Version() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.version
+}
+
+// Migrate registers the migration from version `from` to from+1.
+//
+// The registry's version becomes the highest version reachable through its
+// migrations, so registering migrations 1 and 2 makes the registry build
+// version 3 specs and upgrade stored version 1 and 2 specs automatically.
+//
+// Example:
+//
+//	// v2 renamed the "llm" step type to "agent"
+//	reg.Migrate(1, func(spec *flowspec.Spec) error {
+//		flowspec.Walk(spec, func(step *flowspec.Step) {
+//			if step.Type == "llm" {
+//				step.Type = "agent"
+//			}
+//		})
+//		return nil
+//	})
+func (r *Registry) Migrate(from int, migration Migration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.migrations[from] = migration
+	for r.migrations[r.version] != nil {
+		r.version++
+	}
+}
+
+// Upgrade returns a copy of spec migrated to the registry's version.
+//
+// Specs without a version are treated as version 1. Specs newer than the
+// registry, or older ones with a gap in the migration chain, are rejected.
+// The caller's spec is not modified.
+func (r *Registry) Upgrade(spec *Spec) (*Spec, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := spec.clone()
+	if out.Version == 0 {
+		out.Version = 1
+	}
+	if out.Version > r.version {
+		return nil, fmt.Errorf("flow spec version %d is newer than supported version %d", out.Version, r.version)
+	}
+	for out.Version < r.version {
+		migrate, ok := r.migrations[out.Version]
+		if !ok {
+			return nil, fmt.Errorf("no migration from flow spec version %d", out.Version)
+		}
+		if err := migrate(out); err != nil {
+			return nil, fmt.Errorf("migrate flow spec from version %d: %w", out.Version, err)
+		}
+		out.Version++
+	}
+	return out, nil
+}
+
+// Build migrates the spec to the registry's version and builds its flow.
+//
+// Input: build context, spec, dependency container for factories (may be nil)
+// Output: *calque.Flow running the spec's steps in order
+// Behavior: Steps are built depth-first; children before their parent
+//
+// Example:
+//
+//	c := calque.NewContainer()
+//	calque.ProvideValue[ai.Client](c, client)
+//	flow, err := reg.Build(ctx, spec, c)
+//	if err != nil {
+//		return err
+//	}
+//	defer flow.Close()
+func (r *Registry) Build(ctx context.Context, spec *Spec, container *calque.Container) (*calque.Flow, error) {
+	upgraded, err := r.Upgrade(spec)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to upgrade flow spec")
+	}
+
+	flow := calque.NewFlow(calque.FlowConfig{Name: upgraded.Name})
+	for i, step := range upgraded.Steps {
+		h, err := r.buildStep(ctx, step, container, fmt.Sprintf("steps[%d]", i))
+		if err != nil {
+			return nil, err
+		}
+		flow.Use(h)
+	}
+	return flow, nil
+}
+
+// buildStep builds a step and its children; path locates the step in error messages
+func (r *Registry) buildStep(ctx context.Context, step Step, container *calque.Container, path string) (calque.Handler, error) {
+	r.mu.RLock()
+	factory, ok := r.factories[step.Type]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, calque.NewErr(ctx, fmt.Sprintf("%s: unknown step type %q", path, step.Type))
+	}
+
+	children := make([]calque.Handler, 0, len(step.Steps))
+	for i, child := range step.Steps {
+		h, err := r.buildStep(ctx, child, container, fmt.Sprintf("%s.steps[%d]", path, i))
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, h)
+	}
+
+	h, err := factory(StepContext{Context: ctx, Container: container, Step: step, Children: children})
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("%s: failed to build %s step", path, step.Type))
+	}
+	if h == nil {
+		return nil, calque.NewErr(ctx, fmt.Sprintf("%s: %s factory returned nil", path, step.Type))
+	}
+	return h, nil
+}
+
+// Walk calls fn for every step in the spec, parents before children.
+func Walk(spec *Spec, fn func(step *Step)) {
+	walkSteps(spec.Steps, fn)
+}
+
+func walkSteps(steps []Step, fn func(step *Step)) {
+	for i := range steps {
+		fn(&steps[i])
+		walkSteps(steps[i].Steps, fn)
+	}
+}
@@ -5,19 +5,73 @@ import (
 	"context"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/resolver"
+
+	"github.com/calque-ai/go-calque/pkg/secrets"
 )
 
 // Config holds configuration for gRPC client connections.
 type Config struct {
+	// Endpoint is host:port or a gRPC target URI: "dns:///host:port",
+	// "srv:///_grpc._tcp.service.namespace.svc" (see SchemeSRV) or
+	// "xds:///service" (see SchemeXDS).
 	Endpoint    string
 	Timeout     time.Duration
 	Credentials credentials.TransportCredentials
 	KeepAlive   *KeepAliveConfig
 	Retry       *RetryConfig
+	// Token is sent as a bearer token on every RPC when set. It is resolved per
+	// call, so rotated tokens are picked up without reconnecting.
+	Token *secrets.Ref
+	// Compression compresses every request with the named compressor,
+	// CompressionGzip or CompressionZstd (empty = none).
+	Compression string
+	// Tracing adds the OpenTelemetry gRPC stats handler, so calls appear as
+	// client spans under the flow's spans and propagate trace context. It uses
+	// the global tracer and meter providers, which observability's OTLP
+	// provider sets.
+	Tracing bool
+	// Metrics records Prometheus client metrics (see ClientMetrics) with
+	// MetricsRegisterer, or prometheus.DefaultRegisterer when that is nil.
+	Metrics           bool
+	MetricsRegisterer prometheus.Registerer
+	// SRVRefresh is how often "srv:///" endpoints are re-resolved
+	// (0 = DefaultSRVRefresh).
+	SRVRefresh time.Duration
+	// LoadBalancing is the gRPC load balancing policy, e.g. "round_robin".
+	// Empty uses round_robin for srv endpoints, which usually resolve to
+	// several pods, and gRPC's pick_first otherwise.
+	LoadBalancing string
+	// StreamWindowSize fixes the HTTP/2 flow control window, in bytes, of
+	// each stream and of the connection (0 = gRPC's adaptive window, which
+	// grows up to 16 MiB). A server-streaming RPC then holds at most about this
+	// much unread data while a slow downstream handler is not receiving; see
+	// DefaultStreamWindowSize. gRPC's minimum is 64 KiB.
+	StreamWindowSize int32
+	// OnStateChange is called on every connectivity state change (Idle,
+	// Connecting, Ready, TransientFailure, Shutdown) for the life of the
+	// connection; see WatchState.
+	OnStateChange StateChangeFunc
+}
+
+// DefaultStreamWindowSize is a flow control window that keeps server-streaming
+// RPCs close to the pace of the flow reading them.
+const DefaultStreamWindowSize = 64 * 1024
+
+// StreamWindowOptions returns the dial options that fix the flow control
+// window of each stream and of the connection to size bytes.
+func StreamWindowOptions(size int32) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithInitialWindowSize(size),
+		grpc.WithInitialConnWindowSize(size),
+	}
 }
 
 // KeepAliveConfig configures gRPC keep-alive settings.
@@ -61,6 +115,11 @@ func NewClient(ctx context.Context, config *Config) (*grpc.ClientConn, error) {
 		return nil, NewInvalidArgumentError(ctx, "grpc endpoint cannot be empty", nil)
 	}
 
+	scheme := endpointScheme(config.Endpoint)
+	if scheme == SchemeXDS && resolver.Get(SchemeXDS) == nil {
+		return nil, NewInvalidArgumentError(ctx, "xds endpoints need a blank import of github.com/calque-ai/go-calque/pkg/grpc/xds", nil)
+	}
+
 	opts := []grpc.DialOption{
 		grpc.WithTransportCredentials(config.Credentials),
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
@@ -70,11 +129,57 @@ func NewClient(ctx context.Context, config *Config) (*grpc.ClientConn, error) {
 		}),
 	}
 
+	if config.Token != nil {
+		requireTLS := config.Credentials.Info().SecurityProtocol != "insecure"
+		opts = append(opts, grpc.WithPerRPCCredentials(NewTokenCredentials(config.Token, requireTLS)))
+	}
+
+	if config.Compression != "" {
+		if encoding.GetCompressor(config.Compression) == nil {
+			return nil, NewInvalidArgumentError(ctx, "unknown grpc compressor "+config.Compression, nil)
+		}
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.UseCompressor(config.Compression)))
+	}
+
+	if config.StreamWindowSize != 0 {
+		if config.StreamWindowSize < DefaultStreamWindowSize {
+			return nil, NewInvalidArgumentError(ctx, "grpc stream window must be at least 64 KiB", nil)
+		}
+		opts = append(opts, StreamWindowOptions(config.StreamWindowSize)...)
+	}
+
+	lbPolicy := config.LoadBalancing
+	if scheme == SchemeSRV {
+		opts = append(opts, grpc.WithResolvers(NewSRVResolver(config.SRVRefresh)))
+		if lbPolicy == "" {
+			lbPolicy = "round_robin"
+		}
+	}
+	if lbPolicy != "" {
+		opts = append(opts, grpc.WithDefaultServiceConfig(`{"loadBalancingConfig":[{"`+lbPolicy+`":{}}]}`))
+	}
+
+	if config.Tracing {
+		opts = append(opts, grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
+	}
+	if config.Metrics {
+		metrics, err := NewClientMetrics(config.MetricsRegisterer)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithStatsHandler(metrics))
+	}
+
 	conn, err := grpc.NewClient(config.Endpoint, opts...)
 	if err != nil {
 		return nil, WrapError(ctx, err, "failed to connect to gRPC service", config.Endpoint)
 	}
 
+	if config.OnStateChange != nil {
+		// watch for the life of the connection, not of ctx
+		WatchState(context.Background(), conn, config.OnStateChange)
+	}
+
 	return conn, nil
 }
 
@@ -112,3 +217,33 @@ func CloseConnection(ctx context.Context, conn *grpc.ClientConn, timeout time.Du
 		return NewDeadlineExceededError(ctx, "connection close timeout", nil)
 	}
 }
+
+// TokenCredentials sends a bearer token resolved from a secrets.Ref on every RPC.
+type TokenCredentials struct {
+	token      *secrets.Ref
+	requireTLS bool
+}
+
+// NewTokenCredentials creates per-RPC credentials backed by a secret reference.
+//
+// Example:
+//
+//	token := secrets.NewRef(secrets.Env(), "SERVICE_TOKEN")
+//	conn, err := grpc.NewClient(addr, grpc.WithPerRPCCredentials(calquegrpc.NewTokenCredentials(token, true)))
+func NewTokenCredentials(token *secrets.Ref, requireTLS bool) *TokenCredentials {
+	return &TokenCredentials{token: token, requireTLS: requireTLS}
+}
+
+// GetRequestMetadata returns the authorization header for an RPC.
+func (c *TokenCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	token, err := c.token.Resolve(ctx)
+	if err != nil {
+		return nil, WrapErrorSimple(ctx, err, "failed to resolve gRPC token")
+	}
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+// RequireTransportSecurity reports whether the token may only be sent over TLS.
+func (c *TokenCredentials) RequireTransportSecurity() bool {
+	return c.requireTLS
+}
@@ -7,6 +7,8 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/calque-ai/go-calque/pkg/secrets"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -108,3 +110,48 @@ func TestCloseConnection(t *testing.T) {
 		t.Errorf("CloseConnection failed: %v", err)
 	}
 }
+
+func TestTokenCredentials(t *testing.T) {
+	ctx := context.Background()
+	value := "token-1"
+	ref := secrets.NewRef(secrets.ProviderFunc(func(context.Context, string) (string, error) {
+		return value, nil
+	}), "token", secrets.WithTTL(-1))
+
+	creds := NewTokenCredentials(ref, true)
+	if !creds.RequireTransportSecurity() {
+		t.Error("Expected transport security to be required")
+	}
+
+	md, err := creds.GetRequestMetadata(ctx)
+	if err != nil {
+		t.Fatalf("GetRequestMetadata() error = %v", err)
+	}
+	if md["authorization"] != "Bearer token-1" {
+		t.Errorf("authorization = %q, want %q", md["authorization"], "Bearer token-1")
+	}
+
+	value = "token-2"
+	md, _ = creds.GetRequestMetadata(ctx)
+	if md["authorization"] != "Bearer token-2" {
+		t.Errorf("authorization after rotation = %q, want %q", md["authorization"], "Bearer token-2")
+	}
+
+	failing := NewTokenCredentials(secrets.NewRef(secrets.ProviderFunc(func(context.Context, string) (string, error) {
+		return "", secrets.ErrNotFound
+	}), "missing"), false)
+	if _, err := failing.GetRequestMetadata(ctx); err == nil {
+		t.Error("Expected error for unresolvable token")
+	}
+}
+
+func TestNewClient_WithToken(t *testing.T) {
+	config := DefaultConfig("localhost:8080")
+	config.Token = secrets.Static("token")
+
+	conn, err := NewClient(context.Background(), config)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	_ = conn.Close()
+}
@@ -0,0 +1,92 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestZstdCompressor(t *testing.T) {
+	c := encoding.GetCompressor(CompressionZstd)
+	if c == nil {
+		t.Fatal("zstd compressor not registered")
+	}
+	payload := []byte(strings.Repeat("flow stage payload ", 500))
+
+	// twice, so the second round uses pooled encoders and decoders
+	for range 2 {
+		var buf bytes.Buffer
+		w, err := c.Compress(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(payload); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() >= len(payload)/10 {
+			t.Errorf("compressed %d bytes to %d", len(payload), buf.Len())
+		}
+
+		r, err := c.Decompress(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, payload) {
+			t.Error("round trip changed the payload")
+		}
+	}
+}
+
+func TestClientCompression(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	for _, name := range []string{CompressionGzip, CompressionZstd} {
+		t.Run(name, func(t *testing.T) {
+			config := DefaultConfig(lis.Addr().String())
+			config.Credentials = insecure.NewCredentials()
+			config.Compression = name
+			conn, err := NewClient(context.Background(), config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+			if err != nil {
+				t.Fatalf("Check() over %s error = %v", name, err)
+			}
+			if resp.Status != healthpb.HealthCheckResponse_SERVING {
+				t.Errorf("status = %v", resp.Status)
+			}
+		})
+	}
+
+	config := DefaultConfig(lis.Addr().String())
+	config.Compression = "brotli"
+	if _, err := NewClient(context.Background(), config); err == nil {
+		t.Error("expected error for unknown compressor")
+	}
+}
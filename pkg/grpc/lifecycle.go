@@ -0,0 +1,57 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/keepalive"
+)
+
+// StateChangeFunc is called with the previous and new state of a connection,
+// e.g. connectivity.Ready after connectivity.TransientFailure once a backend
+// is reachable again.
+type StateChangeFunc func(from, to connectivity.State)
+
+// WatchState calls fn on every state change of conn until conn is closed or
+// ctx is done, ending with a change to connectivity.Shutdown when conn is
+// closed. Callbacks run on one goroutine, in order; a slow callback delays
+// later ones but never the connection. NewClient calls it for
+// Config.OnStateChange.
+//
+// States of a connection nobody uses stay Idle; call conn.Connect to connect
+// eagerly.
+//
+// Example:
+//
+//	calquegrpc.WatchState(ctx, conn, func(_, to connectivity.State) {
+//		if to == connectivity.TransientFailure {
+//			health.SetServingStatus("flows", healthpb.HealthCheckResponse_NOT_SERVING)
+//		}
+//	})
+func WatchState(ctx context.Context, conn *grpc.ClientConn, fn StateChangeFunc) {
+	go func() {
+		state := conn.GetState()
+		for state != connectivity.Shutdown && conn.WaitForStateChange(ctx, state) {
+			next := conn.GetState()
+			fn(state, next)
+			state = next
+		}
+	}()
+}
+
+// MaxConnectionAge returns a server option that closes each client connection
+// after about age (gRPC adds ±10% jitter), letting in-flight RPCs finish for
+// up to grace. Clients reconnect and re-resolve, so load spreads over backends
+// added since they first connected.
+//
+// Example:
+//
+//	server := remotegrpc.NewServerWithOptions(":8080", calquegrpc.MaxConnectionAge(5*time.Minute, 30*time.Second))
+func MaxConnectionAge(age, grace time.Duration) grpc.ServerOption {
+	return grpc.KeepaliveParams(keepalive.ServerParameters{
+		MaxConnectionAge:      age,
+		MaxConnectionAgeGrace: grace,
+	})
+}
@@ -0,0 +1,125 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// waitForState reads transitions until one reaches want
+func waitForState(t *testing.T, states <-chan connectivity.State, want connectivity.State) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case s := <-states:
+			if s == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("connection never reached %v", want)
+		}
+	}
+}
+
+// recordStates returns a StateChangeFunc that forwards new states to a channel
+func recordStates() (StateChangeFunc, <-chan connectivity.State) {
+	states := make(chan connectivity.State, 64)
+	return func(_, to connectivity.State) { states <- to }, states
+}
+
+func TestNewClient_OnStateChange(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go func() { _ = server.Serve(lis) }()
+
+	onChange, states := recordStates()
+	config := DefaultConfig(lis.Addr().String())
+	config.OnStateChange = onChange
+	conn, err := NewClient(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.Connect()
+	waitForState(t, states, connectivity.Ready)
+
+	// backend goes away: the connection leaves Ready
+	server.Stop()
+	timeout := time.After(5 * time.Second)
+	for left := false; !left; {
+		select {
+		case s := <-states:
+			left = s != connectivity.Ready
+		case <-timeout:
+			t.Fatal("connection stayed Ready after the server stopped")
+		}
+	}
+
+	_ = conn.Close()
+	waitForState(t, states, connectivity.Shutdown)
+}
+
+func TestWatchStateStopsWithContext(t *testing.T) {
+	conn, err := NewClient(context.Background(), DefaultConfig("127.0.0.1:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	called := make(chan struct{}, 64)
+	WatchState(ctx, conn, func(_, _ connectivity.State) { called <- struct{}{} })
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	for len(called) > 0 {
+		<-called
+	}
+
+	conn.Connect()
+	time.Sleep(100 * time.Millisecond)
+	if len(called) > 0 {
+		t.Error("callback ran after its context was cancelled")
+	}
+}
+
+func TestMaxConnectionAge(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(MaxConnectionAge(100*time.Millisecond, 50*time.Millisecond))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	onChange, states := recordStates()
+	config := DefaultConfig(lis.Addr().String())
+	config.OnStateChange = onChange
+	conn, err := NewClient(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	waitForState(t, states, connectivity.Ready)
+	// the server closes the aged connection, which goes idle until used again
+	waitForState(t, states, connectivity.Idle)
+
+	if _, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Errorf("Check() after reconnect error = %v", err)
+	}
+}
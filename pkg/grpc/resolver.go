@@ -0,0 +1,156 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/resolver"
+)
+
+// Endpoint schemes understood by NewClient besides plain host:port and the
+// schemes built into gRPC (dns, unix, passthrough).
+const (
+	// SchemeSRV resolves the ports and hosts of a DNS SRV record, e.g.
+	// "srv:///_grpc._tcp.flows.default.svc.cluster.local", as published for
+	// named ports of Kubernetes services.
+	SchemeSRV = "srv"
+	// SchemeXDS uses service mesh discovery ("xds:///flows.example.com"). It
+	// requires a blank import of github.com/calque-ai/go-calque/pkg/grpc/xds
+	// and a GRPC_XDS_BOOTSTRAP configuration.
+	SchemeXDS = "xds"
+)
+
+// DefaultSRVRefresh is how often SRV records are looked up again.
+const DefaultSRVRefresh = 30 * time.Second
+
+// lookupSRV is replaced in tests
+var lookupSRV = net.DefaultResolver.LookupSRV
+
+// NewSRVResolver creates a gRPC resolver for SchemeSRV targets that looks the
+// record up again every refresh (0 = DefaultSRVRefresh) and whenever gRPC
+// asks, e.g. after a connection fails. Only the targets with the lowest
+// priority are used; weights are left to the load balancer.
+//
+// NewClient installs it for srv endpoints. Pass it to grpc.WithResolvers to
+// use SRV targets with grpc.NewClient directly:
+//
+//	conn, err := grpc.NewClient("srv:///_grpc._tcp.flows.default.svc.cluster.local",
+//		grpc.WithResolvers(calquegrpc.NewSRVResolver(0)), ...)
+func NewSRVResolver(refresh time.Duration) resolver.Builder {
+	if refresh <= 0 {
+		refresh = DefaultSRVRefresh
+	}
+	return &srvBuilder{refresh: refresh}
+}
+
+type srvBuilder struct {
+	refresh time.Duration
+}
+
+func (b *srvBuilder) Scheme() string { return SchemeSRV }
+
+func (b *srvBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	name := strings.TrimPrefix(target.Endpoint(), "/")
+	if name == "" {
+		return nil, NewInvalidArgumentError(context.Background(), "srv endpoint needs a record name, e.g. srv:///_grpc._tcp.service.namespace.svc", nil)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &srvResolver{
+		name:    name,
+		cc:      cc,
+		refresh: b.refresh,
+		cancel:  cancel,
+		now:     make(chan struct{}, 1),
+	}
+	r.wg.Add(1)
+	go r.watch(ctx)
+	return r, nil
+}
+
+// srvResolver resolves one SRV record until closed
+type srvResolver struct {
+	name    string
+	cc      resolver.ClientConn
+	refresh time.Duration
+	cancel  context.CancelFunc
+	now     chan struct{}
+	wg      sync.WaitGroup
+}
+
+func (r *srvResolver) watch(ctx context.Context) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.refresh)
+	defer ticker.Stop()
+	for {
+		r.resolve(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.now:
+		}
+	}
+}
+
+func (r *srvResolver) resolve(ctx context.Context) {
+	_, records, err := lookupSRV(ctx, "", "", r.name)
+	if ctx.Err() != nil {
+		return
+	}
+	if err == nil && len(records) == 0 {
+		err = NewUnavailableError(ctx, "no srv records for "+r.name, nil)
+	}
+	if err != nil {
+		r.cc.ReportError(WrapError(ctx, err, "failed to resolve srv record", r.name))
+		return
+	}
+	_ = r.cc.UpdateState(resolver.State{Addresses: srvAddresses(records)})
+}
+
+// ResolveNow implements resolver.Resolver.
+func (r *srvResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.now <- struct{}{}:
+	default:
+	}
+}
+
+// Close implements resolver.Resolver.
+func (r *srvResolver) Close() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// srvAddresses returns the host:port of the lowest-priority records, sorted for stable updates
+func srvAddresses(records []*net.SRV) []resolver.Address {
+	lowest := records[0].Priority
+	for _, rec := range records {
+		lowest = min(lowest, rec.Priority)
+	}
+	var hosts []string
+	for _, rec := range records {
+		if rec.Priority == lowest {
+			hosts = append(hosts, net.JoinHostPort(strings.TrimSuffix(rec.Target, "."), strconv.Itoa(int(rec.Port))))
+		}
+	}
+	slices.Sort(hosts)
+	addrs := make([]resolver.Address, 0, len(hosts))
+	for _, host := range slices.Compact(hosts) {
+		addrs = append(addrs, resolver.Address{Addr: host})
+	}
+	return addrs
+}
+
+// endpointScheme returns the resolver scheme of a gRPC target, or "" for host:port
+func endpointScheme(endpoint string) string {
+	scheme, _, ok := strings.Cut(endpoint, "://")
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(scheme)
+}
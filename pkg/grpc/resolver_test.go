@@ -0,0 +1,151 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+)
+
+// fakeSRV replaces lookupSRV for the duration of a test
+func fakeSRV(t *testing.T, fn func(name string) ([]*net.SRV, error)) {
+	t.Helper()
+	orig := lookupSRV
+	lookupSRV = func(_ context.Context, _, _, name string) (string, []*net.SRV, error) {
+		records, err := fn(name)
+		return name, records, err
+	}
+	t.Cleanup(func() { lookupSRV = orig })
+}
+
+// serverPort returns the port of a local server address
+func serverPort(t *testing.T, addr string) uint16 {
+	t.Helper()
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uint16(n)
+}
+
+func TestSRVAddresses(t *testing.T) {
+	records := []*net.SRV{
+		{Target: "b.flows.svc.", Port: 9000, Priority: 0},
+		{Target: "backup.flows.svc.", Port: 9000, Priority: 10},
+		{Target: "a.flows.svc.", Port: 9001, Priority: 0},
+		{Target: "a.flows.svc.", Port: 9001, Priority: 0, Weight: 5},
+	}
+	got := srvAddresses(records)
+	want := []string{"a.flows.svc:9001", "b.flows.svc:9000"}
+	if len(got) != len(want) {
+		t.Fatalf("srvAddresses() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i].Addr != want[i] {
+			t.Errorf("address %d = %q, want %q", i, got[i].Addr, want[i])
+		}
+	}
+}
+
+func TestEndpointScheme(t *testing.T) {
+	tests := map[string]string{
+		"localhost:50051":             "",
+		"dns:///flows:50051":          "dns",
+		"srv:///_grpc._tcp.flows.svc": "srv",
+		"XDS:///flows.example.com":    "xds",
+	}
+	for endpoint, want := range tests {
+		if got := endpointScheme(endpoint); got != want {
+			t.Errorf("endpointScheme(%q) = %q, want %q", endpoint, got, want)
+		}
+	}
+}
+
+func TestNewClient_SRV(t *testing.T) {
+	port1, port2 := serverPort(t, startHealthServer(t)), serverPort(t, startHealthServer(t))
+	var lookups atomic.Int32
+	fakeSRV(t, func(name string) ([]*net.SRV, error) {
+		lookups.Add(1)
+		if name != "_grpc._tcp.flows.default.svc.cluster.local" {
+			return nil, errors.New("no such host")
+		}
+		return []*net.SRV{
+			{Target: "127.0.0.1.", Port: port1},
+			{Target: "127.0.0.1.", Port: port2},
+		}, nil
+	})
+
+	config := DefaultConfig("srv:///_grpc._tcp.flows.default.svc.cluster.local")
+	config.SRVRefresh = 20 * time.Millisecond
+	conn, err := NewClient(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// round_robin spreads calls over both targets
+	client := healthpb.NewHealthClient(conn)
+	seen := map[string]bool{}
+	for range 10 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		var p peer.Peer
+		_, err := client.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Peer(&p))
+		cancel()
+		if err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+		seen[p.Addr.String()] = true
+	}
+	for _, port := range []uint16{port1, port2} {
+		if addr := "127.0.0.1:" + strconv.Itoa(int(port)); !seen[addr] {
+			t.Errorf("no calls reached %s, saw %v", addr, seen)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for lookups.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if lookups.Load() < 3 {
+		t.Errorf("srv record resolved %d times, want periodic refresh", lookups.Load())
+	}
+}
+
+func TestNewClient_SRVLookupFailure(t *testing.T) {
+	fakeSRV(t, func(string) ([]*net.SRV, error) { return nil, nil })
+
+	conn, err := NewClient(context.Background(), DefaultConfig("srv:///_grpc._tcp.missing.svc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}); err == nil {
+		t.Error("expected error when the srv record has no targets")
+	}
+}
+
+func TestNewClient_XDSWithoutImport(t *testing.T) {
+	_, err := NewClient(context.Background(), DefaultConfig("xds:///flows.example.com"))
+	if err == nil {
+		t.Fatal("expected error for xds endpoint without the xds package")
+	}
+	var grpcErr *Error
+	if !errors.As(err, &grpcErr) || grpcErr.Code != codes.InvalidArgument {
+		t.Errorf("error = %v, want InvalidArgument", err)
+	}
+}
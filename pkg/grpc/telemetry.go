@@ -0,0 +1,136 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+)
+
+// ClientMetrics records Prometheus metrics for client RPCs, named like the
+// go-grpc-middleware client metrics so existing dashboards apply:
+//
+//	grpc_client_started_total{grpc_type, grpc_service, grpc_method}
+//	grpc_client_handled_total{grpc_type, grpc_service, grpc_method, grpc_code}
+//	grpc_client_handling_seconds{grpc_type, grpc_service, grpc_method}
+//
+// ClientMetrics is a gRPC stats.Handler; NewClient installs it when
+// Config.Metrics is set. It covers unary and streaming RPCs.
+type ClientMetrics struct {
+	started  *prometheus.CounterVec
+	handled  *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewClientMetrics creates client metrics registered with reg
+// (nil = prometheus.DefaultRegisterer). Metrics already registered with reg,
+// e.g. by another client, are reused.
+func NewClientMetrics(reg prometheus.Registerer) (*ClientMetrics, error) {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	labels := []string{"grpc_type", "grpc_service", "grpc_method"}
+
+	started, err := registerCollector(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_client_started_total",
+		Help: "Total number of RPCs started on the client.",
+	}, labels))
+	if err != nil {
+		return nil, err
+	}
+	handled, err := registerCollector(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_client_handled_total",
+		Help: "Total number of RPCs completed by the client, regardless of success or failure.",
+	}, append(labels, "grpc_code")))
+	if err != nil {
+		return nil, err
+	}
+	duration, err := registerCollector(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_client_handling_seconds",
+		Help:    "Histogram of response latency (seconds) of the gRPC until it is finished by the application.",
+		Buckets: prometheus.DefBuckets,
+	}, labels))
+	if err != nil {
+		return nil, err
+	}
+	return &ClientMetrics{started: started, handled: handled, duration: duration}, nil
+}
+
+// registerCollector registers c, returning the existing collector when an identical one is registered
+func registerCollector[C prometheus.Collector](reg prometheus.Registerer, c C) (C, error) {
+	if err := reg.Register(c); err != nil {
+		var already prometheus.AlreadyRegisteredError
+		if errors.As(err, &already) {
+			if existing, ok := already.ExistingCollector.(C); ok {
+				return existing, nil
+			}
+		}
+		return c, WrapError(context.Background(), err, "failed to register grpc client metrics")
+	}
+	return c, nil
+}
+
+// rpcInfo is stored in the RPC context by TagRPC
+type rpcInfo struct {
+	service, method string
+	rpcType         string
+}
+
+type rpcInfoKey struct{}
+
+// TagRPC implements stats.Handler.
+func (m *ClientMetrics) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	service, method := splitMethodName(info.FullMethodName)
+	return context.WithValue(ctx, rpcInfoKey{}, &rpcInfo{service: service, method: method})
+}
+
+// HandleRPC implements stats.Handler.
+func (m *ClientMetrics) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	info, ok := ctx.Value(rpcInfoKey{}).(*rpcInfo)
+	if !ok || !s.IsClient() {
+		return
+	}
+	switch s := s.(type) {
+	case *stats.Begin:
+		info.rpcType = rpcType(s.IsClientStream, s.IsServerStream)
+		m.started.WithLabelValues(info.rpcType, info.service, info.method).Inc()
+	case *stats.End:
+		code := status.Code(s.Error).String()
+		m.handled.WithLabelValues(info.rpcType, info.service, info.method, code).Inc()
+		m.duration.WithLabelValues(info.rpcType, info.service, info.method).Observe(s.EndTime.Sub(s.BeginTime).Seconds())
+	}
+}
+
+// TagConn implements stats.Handler.
+func (m *ClientMetrics) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+// HandleConn implements stats.Handler.
+func (m *ClientMetrics) HandleConn(context.Context, stats.ConnStats) {}
+
+// rpcType returns the go-grpc-middleware grpc_type label
+func rpcType(clientStream, serverStream bool) string {
+	switch {
+	case clientStream && serverStream:
+		return "bidi_stream"
+	case clientStream:
+		return "client_stream"
+	case serverStream:
+		return "server_stream"
+	default:
+		return "unary"
+	}
+}
+
+// splitMethodName splits "/pkg.Service/Method" into service and method
+func splitMethodName(full string) (string, string) {
+	full = strings.TrimPrefix(full, "/")
+	if i := strings.LastIndex(full, "/"); i >= 0 {
+		return full[:i], full[i+1:]
+	}
+	return "unknown", full
+}
@@ -0,0 +1,120 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func startHealthServer(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+func TestClientTelemetry(t *testing.T) {
+	addr := startHealthServer(t)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(prev)
+
+	reg := prometheus.NewRegistry()
+	config := DefaultConfig(addr)
+	config.Credentials = insecure.NewCredentials()
+	config.Tracing = true
+	config.Metrics = true
+	config.MetricsRegisterer = reg
+
+	conn, err := NewClient(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	client := healthpb.NewHealthClient(conn)
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "missing"}); err == nil {
+		t.Fatal("expected NotFound for unknown service")
+	}
+
+	// a second client on the same registry reuses the collectors
+	if conn2, err := NewClient(context.Background(), config); err != nil {
+		t.Fatalf("second client error = %v", err)
+	} else {
+		conn2.Close()
+	}
+
+	const service, method = "grpc.health.v1.Health", "Check"
+	if got := testutil.ToFloat64(clientCounter(t, reg, "grpc_client_started_total", "unary", service, method)); got != 2 {
+		t.Errorf("started = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(clientCounter(t, reg, "grpc_client_handled_total", "unary", service, method, "OK")); got != 1 {
+		t.Errorf("handled OK = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(clientCounter(t, reg, "grpc_client_handled_total", "unary", service, method, "NotFound")); got != 1 {
+		t.Errorf("handled NotFound = %v, want 1", got)
+	}
+	if n := testutil.CollectAndCount(reg, "grpc_client_handling_seconds"); n != 1 {
+		t.Errorf("latency series = %d, want 1", n)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 || spans[0].Name != "grpc.health.v1.Health/Check" {
+		t.Errorf("spans = %d (first %q), want 2 client spans", len(spans), spanName(spans))
+	}
+}
+
+// clientCounter looks up one series of a registered client counter
+func clientCounter(t *testing.T, reg *prometheus.Registry, name string, labels ...string) prometheus.Counter {
+	t.Helper()
+	metrics, err := NewClientMetrics(reg) // returns the registered collectors
+	if err != nil {
+		t.Fatal(err)
+	}
+	vec := metrics.started
+	if name == "grpc_client_handled_total" {
+		vec = metrics.handled
+	}
+	return vec.WithLabelValues(labels...)
+}
+
+func spanName(spans tracetest.SpanStubs) string {
+	if len(spans) == 0 {
+		return ""
+	}
+	return spans[0].Name
+}
+
+func TestSplitMethodName(t *testing.T) {
+	tests := []struct{ full, service, method string }{
+		{"/calque.FlowService/ExecuteFlow", "calque.FlowService", "ExecuteFlow"},
+		{"Method", "unknown", "Method"},
+	}
+	for _, tt := range tests {
+		if service, method := splitMethodName(tt.full); service != tt.service || method != tt.method {
+			t.Errorf("splitMethodName(%q) = %q, %q", tt.full, service, method)
+		}
+	}
+}
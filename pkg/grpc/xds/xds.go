@@ -0,0 +1,25 @@
+// Package xds enables "xds:///" endpoints for go-calque gRPC clients.
+//
+// Importing it registers gRPC's xDS resolver and balancers, so clients in a
+// service mesh (Istio proxyless, Traffic Director) get endpoints, load
+// balancing and routing from the control plane instead of a fixed host:port.
+// It is a separate package because xDS support adds the Envoy API
+// dependencies, which most builds do not need.
+//
+// The control plane is read from the bootstrap file named by the
+// GRPC_XDS_BOOTSTRAP environment variable (or inline JSON in
+// GRPC_XDS_BOOTSTRAP_CONFIG).
+//
+// Example:
+//
+//	import (
+//		calquegrpc "github.com/calque-ai/go-calque/pkg/grpc"
+//		_ "github.com/calque-ai/go-calque/pkg/grpc/xds"
+//	)
+//
+//	conn, err := calquegrpc.NewClient(ctx, calquegrpc.DefaultConfig("xds:///flows.example.com"))
+package xds
+
+import (
+	_ "google.golang.org/grpc/xds" // registers the xds resolver and balancers
+)
@@ -0,0 +1,21 @@
+package xds
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/resolver"
+
+	calquegrpc "github.com/calque-ai/go-calque/pkg/grpc"
+)
+
+func TestRegistersXDSResolver(t *testing.T) {
+	if resolver.Get(calquegrpc.SchemeXDS) == nil {
+		t.Fatal("xds resolver not registered")
+	}
+	conn, err := calquegrpc.NewClient(context.Background(), calquegrpc.DefaultConfig("xds:///flows.example.com"))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	_ = conn.Close()
+}
@@ -0,0 +1,196 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/observability"
+)
+
+// Shutdown defaults, sized for the Kubernetes default 30s termination grace period.
+const (
+	DefaultDrainDelay      = 5 * time.Second
+	DefaultShutdownTimeout = 20 * time.Second
+)
+
+// Health serves Kubernetes liveness and readiness probes for a flow server.
+//
+// /healthz reports that the process is up and never runs dependency checks,
+// so a slow provider does not get the pod restarted. /readyz runs the
+// readiness checks (provider reachability, store connectivity, flow
+// saturation) and answers 503 when any fails or once Drain was called, so
+// traffic moves to other replicas. Both answer with an
+// observability.HealthReport.
+//
+// Example:
+//
+//	health := httpserver.NewHealth(
+//		&observability.HTTPHealthCheck{CheckName: "ollama", URL: "http://ollama:11434/"},
+//		&observability.TCPHealthCheck{CheckName: "qdrant", Addr: "qdrant:6334"},
+//		&observability.SaturationHealthCheck{CheckName: "flow", Usage: flow.Concurrency, Threshold: 0.9},
+//	)
+//	mux := http.NewServeMux()
+//	health.Mount(mux)
+//	mux.Handle("POST /summarize", httpserver.Handler(flow))
+type Health struct {
+	checks   *observability.HealthCheckRegistry
+	draining atomic.Bool
+}
+
+// NewHealth creates probes whose readiness runs checks.
+func NewHealth(checks ...observability.HealthChecker) *Health {
+	h := &Health{checks: observability.NewHealthCheckRegistry()}
+	for _, check := range checks {
+		h.checks.Register(check)
+	}
+	return h
+}
+
+// Register adds a readiness check.
+func (h *Health) Register(check observability.HealthChecker) {
+	h.checks.Register(check)
+}
+
+// Drain makes readiness fail from now on, ahead of a shutdown.
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Draining reports whether Drain was called.
+func (h *Health) Draining() bool {
+	return h.draining.Load()
+}
+
+// Mount registers GET /healthz and GET /readyz on mux.
+func (h *Health) Mount(mux *http.ServeMux) {
+	mux.Handle("GET /healthz", h.LiveHandler())
+	mux.Handle("GET /readyz", h.ReadyHandler())
+}
+
+// LiveHandler answers liveness probes with 200 while the process serves.
+func (h *Health) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeReport(w, observability.HealthReport{
+			Status:    observability.HealthStatusHealthy,
+			Checks:    map[string]observability.HealthCheckResult{},
+			Timestamp: time.Now(),
+		})
+	})
+}
+
+// ReadyHandler answers readiness probes with 200 when every check passes,
+// otherwise 503.
+func (h *Health) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := h.checks.RunAll(r.Context())
+		if h.Draining() {
+			report.Status = observability.HealthStatusUnhealthy
+			report.Checks["shutdown"] = observability.HealthCheckResult{Name: "shutdown", Status: "error", Error: "draining"}
+		}
+		writeReport(w, report)
+	})
+}
+
+func writeReport(w http.ResponseWriter, report observability.HealthReport) {
+	status := http.StatusOK
+	if report.Status != observability.HealthStatusHealthy {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// ShutdownConfig configures graceful shutdown in Serve.
+type ShutdownConfig struct {
+	// DrainDelay is how long /readyz fails before the server stops accepting
+	// connections, so the pod is removed from Service endpoints first.
+	// Default: DefaultDrainDelay
+	DrainDelay time.Duration
+	// Timeout is how long in-flight requests get to finish.
+	// Default: DefaultShutdownTimeout
+	Timeout time.Duration
+	// Signals start the shutdown. Default: SIGTERM and os.Interrupt
+	Signals []os.Signal
+}
+
+// ListenAndServe listens on server.Addr and calls Serve.
+//
+// Example:
+//
+//	server := &http.Server{Addr: ":8080", Handler: mux}
+//	if err := httpserver.ListenAndServe(ctx, server, health); err != nil {
+//		log.Fatal(err)
+//	}
+func ListenAndServe(ctx context.Context, server *http.Server, health *Health, config ...ShutdownConfig) error {
+	addr := server.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to listen on "+addr)
+	}
+	return Serve(ctx, server, lis, health, config...)
+}
+
+// Serve runs server on lis until ctx is cancelled or a shutdown signal
+// arrives, then shuts down gracefully: health (if not nil) starts draining,
+// the server keeps serving for DrainDelay, then stops accepting connections
+// and waits up to Timeout for in-flight requests before closing them.
+//
+// It returns nil after a graceful shutdown, and the error otherwise.
+func Serve(ctx context.Context, server *http.Server, lis net.Listener, health *Health, config ...ShutdownConfig) error {
+	var cfg ShutdownConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.DrainDelay <= 0 {
+		cfg.DrainDelay = DefaultDrainDelay
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultShutdownTimeout
+	}
+	if len(cfg.Signals) == 0 {
+		cfg.Signals = []os.Signal{syscall.SIGTERM, os.Interrupt}
+	}
+
+	sigCtx, stop := signal.NotifyContext(ctx, cfg.Signals...)
+	defer stop()
+
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(lis) }()
+
+	select {
+	case err := <-served:
+		return calque.WrapErr(ctx, err, "http server failed")
+	case <-sigCtx.Done():
+	}
+
+	calque.Logger(ctx).Info("shutting down http server", "drain_delay", cfg.DrainDelay, "timeout", cfg.Timeout)
+	if health != nil {
+		health.Drain()
+	}
+	time.Sleep(cfg.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.Timeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		_ = server.Close()
+		return calque.WrapErr(ctx, err, "http server did not shut down in time")
+	}
+	if err := <-served; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return calque.WrapErr(ctx, err, "http server failed")
+	}
+	return nil
+}
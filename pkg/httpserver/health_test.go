@@ -0,0 +1,163 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/observability"
+)
+
+func probe(t *testing.T, h http.Handler, path string) (int, observability.HealthReport) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var report observability.HealthReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("%s body is not a health report: %q", path, rec.Body.String())
+	}
+	return rec.Code, report
+}
+
+func TestHealthProbes(t *testing.T) {
+	storeUp := true
+	store := &observability.FuncHealthCheck{CheckName: "store", CheckFunc: func(context.Context) error {
+		if !storeUp {
+			return errors.New("connection refused")
+		}
+		return nil
+	}}
+	health := NewHealth(store)
+	mux := http.NewServeMux()
+	health.Mount(mux)
+
+	tests := []struct {
+		name      string
+		setup     func()
+		path      string
+		wantCode  int
+		wantCheck string
+	}{
+		{name: "live", path: "/healthz", wantCode: http.StatusOK},
+		{name: "ready", path: "/readyz", wantCode: http.StatusOK},
+		{name: "store down", setup: func() { storeUp = false }, path: "/readyz", wantCode: http.StatusServiceUnavailable, wantCheck: "store"},
+		{name: "live while store down", path: "/healthz", wantCode: http.StatusOK},
+		{name: "draining", setup: func() { storeUp = true; health.Drain() }, path: "/readyz", wantCode: http.StatusServiceUnavailable, wantCheck: "shutdown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup()
+			}
+			code, report := probe(t, mux, tt.path)
+			if code != tt.wantCode {
+				t.Errorf("status = %d, want %d", code, tt.wantCode)
+			}
+			if tt.wantCheck != "" && report.Checks[tt.wantCheck].Status != "error" {
+				t.Errorf("checks = %+v, want %s failing", report.Checks, tt.wantCheck)
+			}
+		})
+	}
+}
+
+func TestHealthSaturation(t *testing.T) {
+	flow := calque.NewFlow(calque.FlowConfig{MaxConcurrent: 1})
+	release := make(chan struct{})
+	started := make(chan struct{})
+	flow.UseFunc(func(req *calque.Request, res *calque.Response) error {
+		close(started)
+		<-release
+		return nil
+	})
+	health := NewHealth(&observability.SaturationHealthCheck{CheckName: "flow", Usage: flow.Concurrency})
+
+	if code, _ := probe(t, health.ReadyHandler(), "/readyz"); code != http.StatusOK {
+		t.Fatalf("idle flow: status = %d", code)
+	}
+	done := make(chan error, 1)
+	go func() {
+		var out string
+		done <- flow.Run(context.Background(), "x", &out)
+	}()
+	<-started
+	if code, _ := probe(t, health.ReadyHandler(), "/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("saturated flow: status = %d, want 503", code)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServeGracefulShutdown(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "http://" + lis.Addr().String()
+
+	inFlight := make(chan struct{})
+	health := NewHealth()
+	mux := http.NewServeMux()
+	health.Mount(mux)
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, _ *http.Request) {
+		close(inFlight)
+		time.Sleep(200 * time.Millisecond)
+		_, _ = io.WriteString(w, "finished")
+	})
+
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(context.Background(), &http.Server{Handler: mux}, lis, health, ShutdownConfig{
+			DrainDelay: 100 * time.Millisecond,
+			Timeout:    5 * time.Second,
+			Signals:    []os.Signal{syscall.SIGUSR1},
+		})
+	}()
+
+	slow := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(url + "/slow")
+		if err != nil {
+			slow <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		slow <- string(body)
+	}()
+	<-inFlight
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+
+	// readiness fails during the drain delay while the server still answers
+	time.Sleep(20 * time.Millisecond)
+	resp, err := http.Get(url + "/readyz")
+	if err != nil {
+		t.Fatalf("server stopped answering before the drain delay: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("readyz while draining = %d, want 503", resp.StatusCode)
+	}
+
+	if got := <-slow; got != "finished" {
+		t.Errorf("in-flight request = %q, want it to finish", got)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve() error = %v", err)
+	}
+	if _, err := http.Get(url + "/healthz"); err == nil {
+		t.Error("server still accepts connections after shutdown")
+	}
+}
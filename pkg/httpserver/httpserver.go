@@ -0,0 +1,173 @@
+// Package httpserver serves calque flows over HTTP.
+//
+// Handler adapts any calque.Handler (typically a *calque.Flow) to an
+// http.Handler: the request body streams into the flow and the flow output
+// streams back to the client. Middleware such as VerifySignature wraps the
+// resulting http.Handler to authenticate requests before the flow runs.
+//
+// Example:
+//
+//	flow := calque.NewFlow().
+//		Use(prompt.Template("Triage this issue: {{.Input}}")).
+//		Use(ai.Agent(client))
+//
+//	secret := secrets.NewRef(secrets.Env(), "GITHUB_WEBHOOK_SECRET")
+//	http.Handle("POST /webhooks/github", httpserver.VerifySignature(httpserver.GitHub(), secret)(
+//		httpserver.Handler(flow)))
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultMaxBodySize is the default request body limit (10 MB).
+const DefaultMaxBodySize = 10 << 20
+
+// RequestIDHeader is the header used to propagate request IDs into flows.
+const RequestIDHeader = "X-Request-ID"
+
+// Middleware wraps an http.Handler.
+type Middleware func(http.Handler) http.Handler
+
+// Chain applies middleware so the first in the list runs first.
+//
+// Example:
+//
+//	h := httpserver.Chain(httpserver.Handler(flow), auth, verify)
+func Chain(h http.Handler, middleware ...Middleware) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// Config holds configuration for the flow HTTP adapter
+type Config struct {
+	// MaxBodySize limits the request body in bytes. Default: DefaultMaxBodySize
+	MaxBodySize int64
+	// ContentType is set on responses. Default: "text/plain; charset=utf-8"
+	ContentType string
+	// OnError is called when a flow fails (optional)
+	OnError func(r *http.Request, err error)
+}
+
+// Handler creates an http.Handler that runs a calque handler for each request.
+//
+// Input: HTTP request body (streamed to the handler)
+// Output: handler output (streamed to the response)
+// Behavior: STREAMING - output is flushed to the client as it is written
+//
+// The request context is passed to the flow, with the X-Request-ID header
+// stored via calque.WithRequestID. If the flow fails before writing any
+// output, the client receives a JSON error body with a status derived from
+// the error kind: 400 for calque.InvalidInput, 429 (with Retry-After) for
+// calque.RateLimited, 502 for calque.ProviderError, otherwise 500. Failures
+// after output has started end the response early.
+//
+// Example:
+//
+//	http.Handle("POST /summarize", httpserver.Handler(flow))
+func Handler(h calque.Handler) http.Handler {
+	return HandlerWithConfig(h, nil)
+}
+
+// HandlerWithConfig creates a flow HTTP adapter with custom configuration.
+//
+// Input: HTTP request body (streamed to the handler)
+// Output: handler output (streamed to the response)
+// Behavior: STREAMING - output is flushed to the client as it is written
+//
+// Example:
+//
+//	h := httpserver.HandlerWithConfig(flow, &httpserver.Config{
+//		MaxBodySize: 1 << 20,
+//		ContentType: "application/json",
+//	})
+func HandlerWithConfig(h calque.Handler, config *Config) http.Handler {
+	cfg := Config{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = DefaultMaxBodySize
+	}
+	if cfg.ContentType == "" {
+		cfg.ContentType = "text/plain; charset=utf-8"
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if id := r.Header.Get(RequestIDHeader); id != "" && calque.RequestID(ctx) == "" {
+			ctx = calque.WithRequestID(ctx, id)
+		}
+
+		out := &flushWriter{w: w, contentType: cfg.ContentType}
+		body := http.MaxBytesReader(w, r.Body, cfg.MaxBodySize)
+
+		err := h.ServeFlow(calque.NewRequest(ctx, body), calque.NewResponse(out))
+		if err == nil {
+			return
+		}
+		if cfg.OnError != nil {
+			cfg.OnError(r, err)
+		}
+		if out.started {
+			return
+		}
+
+		status := errorStatus(err)
+		if wait, ok := calque.RetryAfter(err); ok && status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		}
+		WriteError(w, status, http.StatusText(status))
+	})
+}
+
+// errorStatus maps a flow error to an HTTP status using its calque error kind
+func errorStatus(err error) int {
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr) || errors.Is(err, calque.ErrInputTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case calque.IsInvalidInput(err):
+		return http.StatusBadRequest
+	case calque.IsRateLimited(err):
+		return http.StatusTooManyRequests
+	case calque.IsProviderError(err):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// WriteError writes a JSON error response of the form {"error": message}.
+func WriteError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// flushWriter sets the content type on first write and flushes after every write
+type flushWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	started     bool
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	if !f.started {
+		f.started = true
+		f.w.Header().Set("Content-Type", f.contentType)
+	}
+	n, err := f.w.Write(p)
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
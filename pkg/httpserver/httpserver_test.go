@@ -0,0 +1,148 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func upper() calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var input string
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+		return calque.Write(res, strings.ToUpper(input)+":"+calque.RequestID(req.Context))
+	})
+}
+
+func failWith(err error) calque.Handler {
+	return calque.HandlerFunc(func(*calque.Request, *calque.Response) error { return err })
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    calque.Handler
+		config     *Config
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "streams flow output",
+			handler:    calque.NewFlow().Use(upper()),
+			body:       "hello",
+			wantStatus: http.StatusOK,
+			wantBody:   "HELLO:req-1",
+		},
+		{
+			name: "error before output",
+			handler: calque.HandlerFunc(func(*calque.Request, *calque.Response) error {
+				return errors.New("boom")
+			}),
+			body:       "hello",
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"error":"Internal Server Error"}`,
+		},
+		{
+			name:       "body too large",
+			handler:    upper(),
+			config:     &Config{MaxBodySize: 3},
+			body:       "hello",
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantBody:   `{"error":"Request Entity Too Large"}`,
+		},
+		{
+			name:       "flow input limit",
+			handler:    calque.NewFlow(calque.FlowConfig{MaxInputBytes: 3}).Use(upper()),
+			body:       "hello",
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantBody:   `{"error":"Request Entity Too Large"}`,
+		},
+		{
+			name:       "invalid input",
+			handler:    failWith(calque.InvalidInput(errors.New("bad prompt"))),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"Bad Request"}`,
+		},
+		{
+			name:       "rate limited",
+			handler:    failWith(calque.RateLimited(errors.New("slow down"), 0)),
+			wantStatus: http.StatusTooManyRequests,
+			wantBody:   `{"error":"Too Many Requests"}`,
+		},
+		{
+			name:       "provider error",
+			handler:    failWith(calque.WrapErr(context.Background(), calque.ProviderError(errors.New("503")), "call failed")),
+			wantStatus: http.StatusBadGateway,
+			wantBody:   `{"error":"Bad Gateway"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var flowErr error
+			cfg := tt.config
+			if cfg == nil {
+				cfg = &Config{}
+			}
+			cfg.OnError = func(_ *http.Request, err error) { flowErr = err }
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set(RequestIDHeader, "req-1")
+			rec := httptest.NewRecorder()
+			HandlerWithConfig(tt.handler, cfg).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+			if (flowErr != nil) != (tt.wantStatus != http.StatusOK) {
+				t.Errorf("OnError called with %v", flowErr)
+			}
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	var order []string
+	mw := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	h := Chain(Handler(upper()), mw("first"), mw("second"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("x")))
+
+	if strings.Join(order, ",") != "first,second" {
+		t.Errorf("order = %v, want [first second]", order)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	if string(body) != "X:" {
+		t.Errorf("body = %q, want %q", body, "X:")
+	}
+}
+
+func TestHandlerRetryAfter(t *testing.T) {
+	h := Handler(failWith(calque.RateLimited(errors.New("slow down"), 1500*time.Millisecond)))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("hi")))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "2" {
+		t.Errorf("status = %d, Retry-After = %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
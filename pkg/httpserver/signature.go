@@ -0,0 +1,273 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/secrets"
+)
+
+// DefaultSignatureTolerance is the maximum age of timestamped signatures (Slack, Stripe).
+const DefaultSignatureTolerance = 5 * time.Minute
+
+// Signature verification errors. All are reported to the client as 401.
+var (
+	ErrMissingSignature = errors.New("missing signature")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrStaleSignature   = errors.New("signature timestamp outside tolerance")
+)
+
+// SignedRequest is the data a Scheme verifies.
+type SignedRequest struct {
+	Header    http.Header
+	Body      []byte
+	Secret    []byte
+	Now       time.Time
+	Tolerance time.Duration
+}
+
+// Scheme verifies a webhook signature format.
+type Scheme interface {
+	Verify(ctx context.Context, req *SignedRequest) error
+}
+
+// SchemeFunc adapts a function to the Scheme interface.
+type SchemeFunc func(ctx context.Context, req *SignedRequest) error
+
+// Verify calls f(ctx, req).
+func (f SchemeFunc) Verify(ctx context.Context, req *SignedRequest) error {
+	return f(ctx, req)
+}
+
+// HMACSHA256 verifies a hex HMAC-SHA256 of the raw body in header, after stripping prefix.
+//
+// Example:
+//
+//	scheme := httpserver.HMACSHA256("X-Signature", "")
+func HMACSHA256(header, prefix string) Scheme {
+	return SchemeFunc(func(ctx context.Context, req *SignedRequest) error {
+		sig := req.Header.Get(header)
+		if sig == "" {
+			return calque.WrapErr(ctx, ErrMissingSignature, header)
+		}
+		if !strings.HasPrefix(sig, prefix) {
+			return calque.WrapErr(ctx, ErrInvalidSignature, header)
+		}
+		if !hmacEqual(req.Secret, req.Body, strings.TrimPrefix(sig, prefix)) {
+			return calque.WrapErr(ctx, ErrInvalidSignature, header)
+		}
+		return nil
+	})
+}
+
+// GitHub verifies GitHub webhook signatures (X-Hub-Signature-256: sha256=<hex>).
+func GitHub() Scheme {
+	return HMACSHA256("X-Hub-Signature-256", "sha256=")
+}
+
+// Slack verifies Slack request signatures.
+//
+// The signature in X-Slack-Signature is "v0=" + HMAC-SHA256 of
+// "v0:<X-Slack-Request-Timestamp>:<body>"; timestamps older than the
+// tolerance are rejected to prevent replays.
+func Slack() Scheme {
+	return SchemeFunc(func(ctx context.Context, req *SignedRequest) error {
+		sig := req.Header.Get("X-Slack-Signature")
+		ts := req.Header.Get("X-Slack-Request-Timestamp")
+		if sig == "" || ts == "" {
+			return calque.WrapErr(ctx, ErrMissingSignature, "X-Slack-Signature")
+		}
+		if err := checkTimestamp(ctx, ts, req); err != nil {
+			return err
+		}
+		if !strings.HasPrefix(sig, "v0=") {
+			return calque.WrapErr(ctx, ErrInvalidSignature, "X-Slack-Signature")
+		}
+		payload := append([]byte("v0:"+ts+":"), req.Body...)
+		if !hmacEqual(req.Secret, payload, strings.TrimPrefix(sig, "v0=")) {
+			return calque.WrapErr(ctx, ErrInvalidSignature, "X-Slack-Signature")
+		}
+		return nil
+	})
+}
+
+// Stripe verifies Stripe webhook signatures.
+//
+// The Stripe-Signature header carries "t=<timestamp>,v1=<hex>[,v1=<hex>...]"
+// where each v1 is an HMAC-SHA256 of "<timestamp>.<body>". Any matching v1
+// passes, which lets Stripe sign with old and new secrets during rotation.
+func Stripe() Scheme {
+	return SchemeFunc(func(ctx context.Context, req *SignedRequest) error {
+		header := req.Header.Get("Stripe-Signature")
+		if header == "" {
+			return calque.WrapErr(ctx, ErrMissingSignature, "Stripe-Signature")
+		}
+
+		var ts string
+		var sigs []string
+		for part := range strings.SplitSeq(header, ",") {
+			key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+			if !ok {
+				continue
+			}
+			switch key {
+			case "t":
+				ts = value
+			case "v1":
+				sigs = append(sigs, value)
+			}
+		}
+		if ts == "" || len(sigs) == 0 {
+			return calque.WrapErr(ctx, ErrMissingSignature, "Stripe-Signature")
+		}
+		if err := checkTimestamp(ctx, ts, req); err != nil {
+			return err
+		}
+
+		payload := append([]byte(ts+"."), req.Body...)
+		for _, sig := range sigs {
+			if hmacEqual(req.Secret, payload, sig) {
+				return nil
+			}
+		}
+		return calque.WrapErr(ctx, ErrInvalidSignature, "Stripe-Signature")
+	})
+}
+
+// SignatureConfig holds configuration for signature verification
+type SignatureConfig struct {
+	// Tolerance is the maximum age of timestamped signatures. Default: DefaultSignatureTolerance
+	Tolerance time.Duration
+	// MaxBodySize limits the buffered body in bytes. Default: DefaultMaxBodySize
+	MaxBodySize int64
+	// OnFailure is called when a request is rejected (optional)
+	OnFailure func(r *http.Request, err error)
+
+	now func() time.Time // for tests
+}
+
+// VerifySignature creates middleware that rejects requests without a valid signature.
+//
+// Input: HTTP request with signature headers
+// Output: request passed to next with the body intact, or 401
+// Behavior: BUFFERED - reads the full body to compute the HMAC
+//
+// The secret is resolved per request, so rotated secrets are picked up without
+// restarts. Rejected requests get a 401 JSON error and never reach the flow.
+//
+// Example:
+//
+//	secret := secrets.NewRef(secrets.Env(), "SLACK_SIGNING_SECRET")
+//	http.Handle("POST /slack/events", httpserver.VerifySignature(httpserver.Slack(), secret)(
+//		httpserver.Handler(flow)))
+func VerifySignature(scheme Scheme, secret *secrets.Ref) Middleware {
+	return VerifySignatureWithConfig(scheme, secret, nil)
+}
+
+// VerifySignatureWithConfig creates signature verification middleware with custom configuration.
+//
+// Input: HTTP request with signature headers
+// Output: request passed to next with the body intact, or 401
+// Behavior: BUFFERED - reads the full body to compute the HMAC
+//
+// Example:
+//
+//	verify := httpserver.VerifySignatureWithConfig(httpserver.Stripe(), secret, &httpserver.SignatureConfig{
+//		Tolerance: time.Minute,
+//	})
+func VerifySignatureWithConfig(scheme Scheme, secret *secrets.Ref, config *SignatureConfig) Middleware {
+	cfg := SignatureConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Tolerance <= 0 {
+		cfg.Tolerance = DefaultSignatureTolerance
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = DefaultMaxBodySize
+	}
+	if cfg.now == nil {
+		cfg.now = time.Now
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			reject := func(status int, err error) {
+				if cfg.OnFailure != nil {
+					cfg.OnFailure(r, err)
+				}
+				WriteError(w, status, http.StatusText(status))
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, cfg.MaxBodySize))
+			if err != nil {
+				var maxErr *http.MaxBytesError
+				if errors.As(err, &maxErr) {
+					reject(http.StatusRequestEntityTooLarge, err)
+				} else {
+					reject(http.StatusBadRequest, err)
+				}
+				return
+			}
+
+			key, err := secret.Resolve(ctx)
+			if err != nil {
+				reject(http.StatusInternalServerError, err)
+				return
+			}
+
+			err = scheme.Verify(ctx, &SignedRequest{
+				Header:    r.Header,
+				Body:      body,
+				Secret:    []byte(key),
+				Now:       cfg.now(),
+				Tolerance: cfg.Tolerance,
+			})
+			if err != nil {
+				reject(http.StatusUnauthorized, err)
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// hmacEqual reports whether sigHex is the hex HMAC-SHA256 of payload, in constant time
+func hmacEqual(secret, payload []byte, sigHex string) bool {
+	sig, err := hex.DecodeString(sigHex)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), sig)
+}
+
+// checkTimestamp rejects unix timestamps outside the tolerance window
+func checkTimestamp(ctx context.Context, ts string, req *SignedRequest) error {
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return calque.WrapErr(ctx, ErrInvalidSignature, "invalid timestamp")
+	}
+	age := req.Now.Sub(time.Unix(sec, 0))
+	if age < 0 {
+		age = -age
+	}
+	if age > req.Tolerance {
+		return calque.WrapErr(ctx, ErrStaleSignature, "timestamp "+ts)
+	}
+	return nil
+}
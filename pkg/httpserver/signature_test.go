@@ -0,0 +1,141 @@
+package httpserver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/secrets"
+)
+
+const testSecret = "shh"
+
+func sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	stale := strconv.FormatInt(now.Add(-time.Hour).Unix(), 10)
+	body := `{"event":"push"}`
+
+	tests := []struct {
+		name       string
+		scheme     Scheme
+		headers    map[string]string
+		wantStatus int
+		wantErr    error
+	}{
+		{
+			name:       "github valid",
+			scheme:     GitHub(),
+			headers:    map[string]string{"X-Hub-Signature-256": "sha256=" + sign(body)},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "github invalid",
+			scheme:     GitHub(),
+			headers:    map[string]string{"X-Hub-Signature-256": "sha256=" + sign("other")},
+			wantStatus: http.StatusUnauthorized,
+			wantErr:    ErrInvalidSignature,
+		},
+		{
+			name:       "github missing",
+			scheme:     GitHub(),
+			wantStatus: http.StatusUnauthorized,
+			wantErr:    ErrMissingSignature,
+		},
+		{
+			name:   "slack valid",
+			scheme: Slack(),
+			headers: map[string]string{
+				"X-Slack-Request-Timestamp": ts,
+				"X-Slack-Signature":         "v0=" + sign("v0:"+ts+":"+body),
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "slack stale",
+			scheme: Slack(),
+			headers: map[string]string{
+				"X-Slack-Request-Timestamp": stale,
+				"X-Slack-Signature":         "v0=" + sign("v0:"+stale+":"+body),
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantErr:    ErrStaleSignature,
+		},
+		{
+			name:       "stripe valid with rotated secret",
+			scheme:     Stripe(),
+			headers:    map[string]string{"Stripe-Signature": "t=" + ts + ",v1=deadbeef,v1=" + sign(ts+"."+body)},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "stripe invalid",
+			scheme:     Stripe(),
+			headers:    map[string]string{"Stripe-Signature": "t=" + ts + ",v1=" + sign(stale+"."+body)},
+			wantStatus: http.StatusUnauthorized,
+			wantErr:    ErrInvalidSignature,
+		},
+		{
+			name:       "generic hmac",
+			scheme:     HMACSHA256("X-Signature", ""),
+			headers:    map[string]string{"X-Signature": sign(body)},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var failure error
+			var gotBody string
+			next := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				gotBody = string(data)
+			})
+
+			mw := VerifySignatureWithConfig(tt.scheme, secrets.Static(testSecret), &SignatureConfig{
+				OnFailure: func(_ *http.Request, err error) { failure = err },
+				now:       func() time.Time { return now },
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			mw(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantErr != nil && !errors.Is(failure, tt.wantErr) {
+				t.Errorf("failure = %v, want %v", failure, tt.wantErr)
+			}
+			if tt.wantStatus == http.StatusOK && gotBody != body {
+				t.Errorf("next received body %q, want %q", gotBody, body)
+			}
+		})
+	}
+}
+
+func TestVerifySignature_BodyTooLarge(t *testing.T) {
+	mw := VerifySignatureWithConfig(GitHub(), secrets.Static(testSecret), &SignatureConfig{MaxBodySize: 4})
+	rec := httptest.NewRecorder()
+	mw(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too large")))
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+}
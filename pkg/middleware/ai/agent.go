@@ -4,8 +4,10 @@
 package ai
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
 	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
@@ -37,17 +39,78 @@ func Agent(client Client, opts ...AgentOption) calque.Handler {
 		for _, opt := range opts {
 			opt.Apply(agentOpts)
 		}
+		chargeBudget(r.Context, agentOpts)
+		emitUsage(r.Context, agentOpts)
+		priceUsage(agentOpts) // runs first, so budgets and events see the cost
+
+		chatClient := client
+		rec := startTranscript(r.Context, agentOpts.Transcript)
+		if rec != nil {
+			chatClient = &transcriptClient{client: client, transcript: rec}
+			if agentOpts.ToolFormatterClient != nil {
+				agentOpts.ToolFormatterClient = &transcriptClient{client: agentOpts.ToolFormatterClient, transcript: rec}
+			}
+			r = r.WithContext(rec.observeTools(r.Context))
+		}
 
-		// Determine behavior based on options
-		if len(agentOpts.Tools) > 0 {
-			// Tool-calling agent behavior
-			return runToolCallingAgent(client, agentOpts, r, w)
+		err := chatConstrained(client, agentOpts, r, w, func(w *calque.Response) error {
+			// Determine behavior based on options
+			if len(agentOpts.Tools) > 0 {
+				// Tool-calling agent behavior
+				return runToolCallingAgent(chatClient, agentOpts, r, w)
+			}
+			// Simple chat behavior
+			return chatClient.Chat(r, w, agentOpts)
+		})
+		if rec != nil {
+			rec.finish(err)
 		}
-		// Simple chat behavior
-		return client.Chat(r, w, agentOpts)
+		return err
 	})
 }
 
+// chargeBudget reports provider token usage to any ctrl.Budget in the context,
+// chaining the caller's usage handler
+func chargeBudget(ctx context.Context, agentOpts *AgentOptions) {
+	if _, ok := ctrl.BudgetUsed(ctx); !ok {
+		return
+	}
+	next := agentOpts.UsageHandler
+	agentOpts.UsageHandler = func(usage *UsageMetadata) {
+		if next != nil {
+			next(usage)
+		}
+		_ = ctrl.ChargeTokens(ctx, usage.TotalTokens) // exceeding cancels the run
+		if usage.CostUSD > 0 {
+			_ = ctrl.ChargeCost(ctx, usage.CostUSD)
+		}
+	}
+}
+
+// emitUsage reports provider token usage as calque.TokenUsage events and
+// result artifacts when the run has a RunWithEvents channel or a calque.Result,
+// chaining the caller's usage handler
+func emitUsage(ctx context.Context, agentOpts *AgentOptions) {
+	if !calque.HasEvents(ctx) && calque.ResultFrom(ctx) == nil {
+		return
+	}
+	next := agentOpts.UsageHandler
+	agentOpts.UsageHandler = func(usage *UsageMetadata) {
+		if next != nil {
+			next(usage)
+		}
+		tokens := &calque.TokenUsage{
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+			TotalTokens:      usage.TotalTokens,
+			CostUSD:          usage.CostUSD,
+			Time:             time.Now(),
+		}
+		calque.EmitEvent(ctx, tokens)
+		calque.Attach(ctx, tokens)
+	}
+}
+
 // runToolCallingAgent implements the full agent loop with tools
 func runToolCallingAgent(client Client, agentOpts *AgentOptions, r *calque.Request, w *calque.Response) error {
 	// Use default tools config if none provided
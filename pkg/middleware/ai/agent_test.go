@@ -3,12 +3,14 @@ package ai
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 	"testing"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
 	"github.com/calque-ai/go-calque/pkg/middleware/tools"
 )
 
@@ -319,3 +321,69 @@ type errorReader struct {
 func (e *errorReader) Read(_ []byte) (n int, err error) {
 	return 0, e.err
 }
+
+// usageClient replies with a fixed response and reports fixed token usage
+type usageClient struct {
+	tokens int
+}
+
+func (c *usageClient) Chat(r *calque.Request, w *calque.Response, opts *AgentOptions) error {
+	if _, err := io.Copy(io.Discard, r.Data); err != nil {
+		return err
+	}
+	if opts.UsageHandler != nil {
+		opts.UsageHandler(&UsageMetadata{TotalTokens: c.tokens})
+	}
+	return calque.Write(w, "reply")
+}
+
+func TestAgentChargesBudget(t *testing.T) {
+	var reported int
+	agent := Agent(&usageClient{tokens: 120}, WithUsageHandler(func(u *UsageMetadata) {
+		reported += u.TotalTokens
+	}))
+
+	var buf bytes.Buffer
+	err := ctrl.Budget(agent, ctrl.BudgetLimits{MaxTokens: 100}).
+		ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("hi")), calque.NewResponse(&buf))
+	if !errors.Is(err, ctrl.ErrBudgetExceeded) {
+		t.Fatalf("error = %v, want ErrBudgetExceeded", err)
+	}
+	if reported != 120 {
+		t.Errorf("caller usage handler got %d tokens, want 120", reported)
+	}
+}
+
+func TestAgentEmitsTokenUsage(t *testing.T) {
+	var reported int
+	agent := Agent(&usageClient{tokens: 42}, WithUsageHandler(func(u *UsageMetadata) {
+		reported += u.TotalTokens
+	}))
+
+	events := make(chan calque.Event, 16)
+	var out string
+	if err := calque.NewFlow().Use(agent).RunWithEvents(context.Background(), "hi", &out, events); err != nil {
+		t.Fatal(err)
+	}
+
+	total := 0
+	for ev := range events {
+		if usage, ok := ev.(*calque.TokenUsage); ok {
+			total += usage.TotalTokens
+		}
+	}
+	if total != 42 || reported != 42 {
+		t.Errorf("event tokens = %d, handler tokens = %d, want 42", total, reported)
+	}
+}
+
+func TestAgentAttachesTokenUsage(t *testing.T) {
+	var out string
+	result, err := calque.NewFlow().Use(Agent(&usageClient{tokens: 42})).RunWithResult(context.Background(), "hi", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.TokenUsage().TotalTokens; got != 42 {
+		t.Errorf("result tokens = %d, want 42", got)
+	}
+}
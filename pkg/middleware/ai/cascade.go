@@ -0,0 +1,127 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// MetadataCascadeEscalated is the MetadataBus key set to true when a Cascade
+// escalated to its expensive model, false when the cheap answer was kept
+const MetadataCascadeEscalated = "ai.cascade.escalated"
+
+// EscalateFunc decides whether a cheap model's output is not good enough.
+type EscalateFunc func(ctx context.Context, input, output []byte) bool
+
+// DefaultUncertainPhrases are the hedges EscalateIfUncertain looks for when none are given.
+var DefaultUncertainPhrases = []string{
+	"i'm not sure",
+	"i am not sure",
+	"i don't know",
+	"i do not know",
+	"i cannot answer",
+	"i can't answer",
+	"unable to determine",
+}
+
+// Cascade creates a Client that answers with a cheap model and escalates to
+// an expensive one when the cheap answer fails a check.
+//
+// Input: prompt, as for any Client
+// Output: the cheap model's response, or the expensive model's when escalated
+// Behavior: BUFFERED - the cheap response is buffered to run escalateIf; the
+// expensive response streams
+//
+// The expensive model is also used when the cheap model returns an error,
+// except for invalid-input and cancellation errors. A nil escalateIf only
+// escalates on errors. Whether the request escalated is recorded on the
+// MetadataBus under MetadataCascadeEscalated.
+//
+// Example:
+//
+//	client := ai.Cascade(miniClient, largeClient, ai.EscalateIfAny(
+//		ai.EscalateIfUncertain(),
+//		ai.EscalateIfInvalid(func(out []byte) error { return json.Unmarshal(out, &Answer{}) }),
+//	))
+//	flow.Use(ai.Agent(client))
+func Cascade(cheap, expensive Client, escalateIf EscalateFunc) Client {
+	return &cascade{cheap: cheap, expensive: expensive, escalateIf: escalateIf}
+}
+
+type cascade struct {
+	cheap, expensive Client
+	escalateIf       EscalateFunc
+}
+
+// Chat implements Client
+func (c *cascade) Chat(r *calque.Request, w *calque.Response, opts *AgentOptions) error {
+	var input []byte
+	if err := calque.Read(r, &input); err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	err := c.cheap.Chat(calque.NewRequest(r.Context, bytes.NewReader(input)), calque.NewResponse(&out), opts)
+	if err != nil && (calque.IsInvalidInput(err) || calque.IsCancelled(err) || r.Context.Err() != nil) {
+		return err
+	}
+	if err == nil && (c.escalateIf == nil || !c.escalateIf(r.Context, input, out.Bytes())) {
+		recordEscalation(r.Context, false)
+		return calque.Write(w, out.Bytes())
+	}
+
+	recordEscalation(r.Context, true)
+	return c.expensive.Chat(calque.NewRequest(r.Context, bytes.NewReader(input)), w, opts)
+}
+
+func recordEscalation(ctx context.Context, escalated bool) {
+	if mb := calque.GetMetadataBus(ctx); mb != nil {
+		mb.Set(MetadataCascadeEscalated, escalated)
+	}
+}
+
+// EscalateIfUncertain escalates when the output contains a hedging phrase
+// (case-insensitive), or is empty. With no phrases, DefaultUncertainPhrases are used.
+func EscalateIfUncertain(phrases ...string) EscalateFunc {
+	if len(phrases) == 0 {
+		phrases = DefaultUncertainPhrases
+	}
+	lowered := make([]string, len(phrases))
+	for i, p := range phrases {
+		lowered[i] = strings.ToLower(p)
+	}
+	return func(_ context.Context, _, output []byte) bool {
+		text := strings.ToLower(strings.TrimSpace(string(output)))
+		if text == "" {
+			return true
+		}
+		for _, p := range lowered {
+			if strings.Contains(text, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// EscalateIfInvalid escalates when validate rejects the output, e.g. a JSON
+// schema check or a business rule.
+func EscalateIfInvalid(validate func(output []byte) error) EscalateFunc {
+	return func(_ context.Context, _, output []byte) bool {
+		return validate(output) != nil
+	}
+}
+
+// EscalateIfAny escalates when any of the checks does.
+func EscalateIfAny(checks ...EscalateFunc) EscalateFunc {
+	return func(ctx context.Context, input, output []byte) bool {
+		for _, check := range checks {
+			if check(ctx, input, output) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestCascade(t *testing.T) {
+	validJSON := EscalateIfInvalid(func(out []byte) error {
+		var v map[string]any
+		return json.Unmarshal(out, &v)
+	})
+
+	tests := []struct {
+		name          string
+		cheapReply    string
+		cheapErr      error
+		escalateIf    EscalateFunc
+		want          string
+		wantEscalated bool
+		wantErr       bool
+	}{
+		{name: "cheap answer accepted", cheapReply: "Paris", escalateIf: EscalateIfUncertain(), want: "Paris"},
+		{name: "uncertain answer escalates", cheapReply: "I'm not sure, maybe Lyon", escalateIf: EscalateIfUncertain(), want: "expensive", wantEscalated: true},
+		{name: "empty answer escalates", cheapReply: " ", escalateIf: EscalateIfUncertain(), want: "expensive", wantEscalated: true},
+		{name: "invalid json escalates", cheapReply: "{broken", escalateIf: EscalateIfAny(EscalateIfUncertain(), validJSON), want: "expensive", wantEscalated: true},
+		{name: "valid json kept", cheapReply: `{"a":1}`, escalateIf: validJSON, want: `{"a":1}`},
+		{name: "cheap error escalates", cheapErr: errors.New("overloaded"), want: "expensive", wantEscalated: true},
+		{name: "invalid input does not escalate", cheapErr: calque.InvalidInput(errors.New("too long")), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cheap := &scriptedClient{name: tt.cheapReply, err: tt.cheapErr}
+			cheap.fail.Store(tt.cheapErr != nil)
+			expensive := &scriptedClient{name: "expensive"}
+
+			mb := calque.NewMetadataBus(0)
+			ctx := calque.WithMetadataBus(context.Background(), mb)
+			got, err := chat(ctx, Cascade(cheap, expensive, tt.escalateIf))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+			if escalated, _ := mb.GetBool(MetadataCascadeEscalated); escalated != tt.wantEscalated {
+				t.Errorf("escalated = %v", escalated)
+			}
+			if tt.wantEscalated != (expensive.calls.Load() == 1) {
+				t.Errorf("expensive calls = %d", expensive.calls.Load())
+			}
+		})
+	}
+}
+
+func TestCascadeWithAgent(t *testing.T) {
+	var out string
+	agent := Agent(Cascade(NewMockClient("i don't know").WithStreamDelay(0), NewMockClient("42").WithStreamDelay(0), EscalateIfUncertain()))
+	if err := calque.NewFlow().Use(agent).Run(context.Background(), "meaning of life?", &out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out) != "42" {
+		t.Errorf("output = %q", out)
+	}
+}
@@ -0,0 +1,218 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Catalog is a concurrency-safe table of model capabilities and prices.
+//
+// The process-wide catalog returned by Models starts with the built-in
+// table of the bundled providers. CostRouter, WithModelPricing (budgets and
+// usage cost) read it at request time, so Set, Load and Refresh take effect
+// without rebuilding clients.
+type Catalog struct {
+	mu     sync.RWMutex
+	models map[string]ModelInfo
+}
+
+var defaultCatalog = NewCatalog(builtinModels...)
+
+// Models returns the process-wide model catalog.
+//
+// Example:
+//
+//	info, ok := ai.Models().Lookup("openai/gpt-4o-mini")
+//	if ok && info.Supports(ai.CapabilityVision) {
+//		fmt.Printf("%d tokens at $%.2f/M input\n", info.ContextWindow, info.InputPerMillion)
+//	}
+func Models() *Catalog {
+	return defaultCatalog
+}
+
+// NewCatalog creates a catalog holding models, e.g. for tests or an
+// isolated pricing table.
+func NewCatalog(models ...ModelInfo) *Catalog {
+	c := &Catalog{models: make(map[string]ModelInfo, len(models))}
+	c.Set(models...)
+	return c
+}
+
+// Set adds or replaces models, matched by Name.
+func (c *Catalog) Set(models ...ModelInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, m := range models {
+		c.models[m.Name] = m
+	}
+}
+
+// Lookup returns a model by "provider/model" name. A bare model name, like
+// "gpt-4o", matches when exactly one provider lists it.
+func (c *Catalog) Lookup(name string) (ModelInfo, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if m, ok := c.models[name]; ok {
+		return m, true
+	}
+	if strings.Contains(name, "/") {
+		return ModelInfo{}, false
+	}
+	var found ModelInfo
+	matches := 0
+	for key, m := range c.models {
+		if strings.HasSuffix(key, "/"+name) {
+			found = m
+			matches++
+		}
+	}
+	return found, matches == 1
+}
+
+// List returns every model, sorted by name.
+func (c *Catalog) List() []ModelInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	models := make([]ModelInfo, 0, len(c.models))
+	for _, m := range c.models {
+		models = append(models, m)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+	return models
+}
+
+// Cost prices a call of the named model; false if the model is unknown.
+func (c *Catalog) Cost(name string, promptTokens, completionTokens int) (float64, bool) {
+	m, ok := c.Lookup(name)
+	if !ok {
+		return 0, false
+	}
+	return m.Cost(promptTokens, completionTokens), true
+}
+
+// Load merges models from JSON into the catalog: a ModelInfo array or an
+// object with a "models" array. Entries replace models of the same name;
+// others are kept. Nothing is changed if the document is invalid.
+//
+// Example:
+//
+//	// prices.json: {"models": [{"name": "openai/gpt-4o", "context_window": 128000,
+//	//   "capabilities": ["vision", "tools", "json"], "input_per_million": 2.5, "output_per_million": 10}]}
+//	f, _ := os.Open("prices.json")
+//	defer f.Close()
+//	if err := ai.Models().Load(f); err != nil {
+//		log.Fatal(err)
+//	}
+func (c *Catalog) Load(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return calque.WrapErr(context.Background(), err, "failed to read model catalog")
+	}
+	models, err := decodeCatalog(data)
+	if err != nil {
+		return err
+	}
+	c.Set(models...)
+	return nil
+}
+
+// Refresh fetches a JSON document from url, in the format accepted by Load,
+// and merges it into the catalog. A nil client uses http.DefaultClient.
+// Failures leave the catalog unchanged; 429 and 5xx answers are retryable.
+//
+// Example - keep prices current from a maintained source:
+//
+//	go func() {
+//		for range time.Tick(time.Hour) {
+//			if err := ai.Models().Refresh(ctx, pricesURL, nil); err != nil {
+//				slog.Warn("model catalog refresh failed", "error", err)
+//			}
+//		}
+//	}()
+func (c *Catalog) Refresh(ctx context.Context, url string, client *http.Client) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return calque.InvalidInput(calque.WrapErr(ctx, err, "invalid model catalog URL"))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return calque.Retryable(calque.WrapErr(ctx, err, "model catalog request failed"))
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		err := calque.NewErr(ctx, fmt.Sprintf("model catalog returned HTTP %d", resp.StatusCode))
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return calque.Retryable(err)
+		}
+		return err
+	}
+	return c.Load(resp.Body)
+}
+
+// decodeCatalog parses a ModelInfo array or {"models": [...]}
+func decodeCatalog(data []byte) ([]ModelInfo, error) {
+	var models []ModelInfo
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var doc struct {
+			Models []ModelInfo `json:"models"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, calque.InvalidInput(calque.WrapErr(context.Background(), err, "invalid model catalog"))
+		}
+		models = doc.Models
+	} else if err := json.Unmarshal(data, &models); err != nil {
+		return nil, calque.InvalidInput(calque.WrapErr(context.Background(), err, "invalid model catalog"))
+	}
+	for i, m := range models {
+		if m.Name == "" {
+			return nil, calque.InvalidInput(calque.NewErr(context.Background(), fmt.Sprintf("model catalog entry %d has no name", i)))
+		}
+	}
+	return models, nil
+}
+
+// priceUsage fills UsageMetadata.CostUSD from the catalog entry of
+// AgentOptions.PricingModel, chaining the usage handler
+func priceUsage(agentOpts *AgentOptions) {
+	if agentOpts.PricingModel == "" {
+		return
+	}
+	next := agentOpts.UsageHandler
+	name := agentOpts.PricingModel
+	agentOpts.UsageHandler = func(usage *UsageMetadata) {
+		if usage.CostUSD == 0 {
+			if cost, ok := Models().Cost(name, usage.PromptTokens, usage.CompletionTokens); ok {
+				usage.CostUSD = cost
+			}
+		}
+		if next != nil {
+			next(usage)
+		}
+	}
+}
+
+// pricedOptions returns opts whose usage handler is priced with model
+func pricedOptions(opts *AgentOptions, model ModelInfo) *AgentOptions {
+	if opts == nil || opts.UsageHandler == nil || model.Name == "" {
+		return opts
+	}
+	priced := *opts
+	next := opts.UsageHandler
+	priced.UsageHandler = func(usage *UsageMetadata) {
+		usage.CostUSD = model.Cost(usage.PromptTokens, usage.CompletionTokens)
+		next(usage)
+	}
+	return &priced
+}
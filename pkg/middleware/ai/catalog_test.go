@@ -0,0 +1,133 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+)
+
+// pricedClient answers "ok" and reports fixed token usage
+type pricedClient struct{ prompt, completion int }
+
+func (c pricedClient) Chat(r *calque.Request, w *calque.Response, opts *AgentOptions) error {
+	if _, err := io.Copy(io.Discard, r.Data); err != nil {
+		return err
+	}
+	if opts != nil && opts.UsageHandler != nil {
+		opts.UsageHandler(&UsageMetadata{PromptTokens: c.prompt, CompletionTokens: c.completion, TotalTokens: c.prompt + c.completion})
+	}
+	return calque.Write(w, "ok")
+}
+
+func TestCatalogLookup(t *testing.T) {
+	c := NewCatalog(builtinModels...)
+	m, ok := c.Lookup("openai/gpt-4o-mini")
+	if !ok || m.ContextWindow != 128000 || !m.Supports(CapabilityVision, CapabilityTools) {
+		t.Errorf("Lookup() = %+v, %v", m, ok)
+	}
+	if m, ok := c.Lookup("gpt-4o-mini"); !ok || m.Name != "openai/gpt-4o-mini" {
+		t.Errorf("bare Lookup() = %+v, %v", m, ok)
+	}
+	if _, ok := c.Lookup("acme/gpt-4o-mini"); ok {
+		t.Error("Lookup() matched another provider's model")
+	}
+
+	c.Set(ModelInfo{Name: "acme/gpt-4o-mini"})
+	if _, ok := c.Lookup("gpt-4o-mini"); ok {
+		t.Error("bare Lookup() matched an ambiguous name")
+	}
+
+	cost, ok := c.Cost("openai/gpt-4o", 1_000_000, 100_000)
+	if !ok || math.Abs(cost-3.5) > 1e-9 {
+		t.Errorf("Cost() = %v, %v, want 3.5", cost, ok)
+	}
+	if len(c.List()) != len(builtinModels)+1 {
+		t.Errorf("List() has %d models", len(c.List()))
+	}
+}
+
+func TestCatalogLoadAndRefresh(t *testing.T) {
+	c := NewCatalog(builtinModels...)
+	if err := c.Load(strings.NewReader(`[{"name": "openai/gpt-4o", "context_window": 128000, "input_per_million": 1, "output_per_million": 4}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := c.Lookup("openai/gpt-4o"); m.InputPerMillion != 1 {
+		t.Errorf("price after Load = %v, want 1", m.InputPerMillion)
+	}
+	if _, ok := c.Lookup("openai/gpt-4o-mini"); !ok {
+		t.Error("Load dropped models missing from the document")
+	}
+	if err := c.Load(strings.NewReader(`[{"context_window": 10}]`)); !calque.IsInvalidInput(err) {
+		t.Errorf("Load() unnamed entry error = %v, want invalid input", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, `{"models": [{"name": "acme/new", "context_window": 8000, "capabilities": ["tools"]}]}`)
+	}))
+	defer srv.Close()
+
+	if err := c.Refresh(context.Background(), srv.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := c.Lookup("acme/new"); !ok || !m.Supports(CapabilityTools) {
+		t.Errorf("refreshed model = %+v, %v", m, ok)
+	}
+	if err := c.Refresh(context.Background(), srv.URL+"/down", nil); !calque.IsRetryable(err) {
+		t.Errorf("Refresh() on 503 error = %v, want retryable", err)
+	}
+}
+
+func TestWithModelPricing(t *testing.T) {
+	var reported float64
+	agent := Agent(pricedClient{prompt: 1_000_000, completion: 1_000_000},
+		WithModelPricing("gpt-4o-mini"),
+		WithUsageHandler(func(u *UsageMetadata) { reported = u.CostUSD }))
+
+	flow := calque.NewFlow().Use(ctrl.Budget(agent, ctrl.BudgetLimits{MaxCostUSD: 100}))
+	var out string
+	result, err := flow.RunWithResult(context.Background(), "hi", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(reported-0.75) > 1e-9 {
+		t.Errorf("usage handler cost = %v, want 0.75", reported)
+	}
+	if got := result.TokenUsage().CostUSD; math.Abs(got-0.75) > 1e-9 {
+		t.Errorf("Result cost = %v, want 0.75", got)
+	}
+
+	tight := ctrl.Budget(Agent(pricedClient{prompt: 1_000_000}, WithModelPricing("openai/gpt-4o")), ctrl.BudgetLimits{MaxCostUSD: 1})
+	var buf bytes.Buffer
+	err = tight.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("hi")), calque.NewResponse(&buf))
+	if !errors.Is(err, ctrl.ErrBudgetExceeded) {
+		t.Errorf("error = %v, want the catalog price to exceed the budget", err)
+	}
+}
+
+func TestCostRouterPricesUsage(t *testing.T) {
+	router := NewCostRouter([]Provider{{Name: "acme/cheap", Client: pricedClient{prompt: 1_000_000}}}, CostRouterConfig{
+		Catalog: NewCatalog(ModelInfo{Name: "acme/cheap", ContextWindow: 8000, InputPerMillion: 0.5}),
+	})
+	var cost float64
+	agent := Agent(router, WithModelPricing("openai/gpt-4o"), WithUsageHandler(func(u *UsageMetadata) { cost = u.CostUSD }))
+	var out string
+	if err := calque.NewFlow().Use(agent).Run(context.Background(), "hi", &out); err != nil {
+		t.Fatal(err)
+	}
+	if cost != 0.5 {
+		t.Errorf("cost = %v, want the routed model's price 0.5", cost)
+	}
+}
@@ -47,7 +47,8 @@ type ResponseFormat struct {
 //		log.Printf("Total tokens: %d", usage.TotalTokens)
 //	}))
 type UsageMetadata struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd,omitempty"` // priced from the model catalog, see WithModelPricing
 }
@@ -0,0 +1,242 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// MetadataCompressionRatio is the MetadataBus key holding the estimated
+// compressed/original token ratio of the last CompressContext stage
+const MetadataCompressionRatio = "ai.compress.ratio"
+
+// Compressor shortens text to roughly a token budget.
+type Compressor interface {
+	Compress(ctx context.Context, text string, targetTokens int) (string, error)
+}
+
+// CompressorFunc adapts a function to Compressor.
+type CompressorFunc func(ctx context.Context, text string, targetTokens int) (string, error)
+
+// Compress implements Compressor.
+func (f CompressorFunc) Compress(ctx context.Context, text string, targetTokens int) (string, error) {
+	return f(ctx, text, targetTokens)
+}
+
+// CompressContext creates a handler that compresses long input before the main model call.
+//
+// Input: text (context, retrieved documents, conversation history)
+// Output: the input, compressed to about targetTokens when it is longer
+// Behavior: BUFFERED - reads entire input to estimate its size
+//
+// Input already within targetTokens (estimated at ~4 characters per token)
+// passes through unchanged. Use ExtractiveCompressor for a fast, local
+// compression or ModelCompressor to have a small model rewrite the text. The
+// achieved ratio is recorded on the MetadataBus under MetadataCompressionRatio.
+// Place one stage per input that needs it, each with its own budget.
+//
+// Example:
+//
+//	flow.Use(retrieval.VectorSearch(store, opts)).
+//		Use(ai.CompressContext(ai.ExtractiveCompressor(), 2000)).
+//		Use(prompt.Template(answerTemplate)).
+//		Use(ai.Agent(client))
+func CompressContext(compressor Compressor, targetTokens int) calque.Handler {
+	return calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
+		var input string
+		if err := calque.Read(r, &input); err != nil {
+			return err
+		}
+
+		original := estimateTokens(input)
+		if targetTokens <= 0 || original <= targetTokens {
+			return calque.Write(w, input)
+		}
+
+		compressed, err := compressor.Compress(r.Context, input, targetTokens)
+		if err != nil {
+			return calque.WrapErr(r.Context, err, "context compression failed")
+		}
+		if mb := calque.GetMetadataBus(r.Context); mb != nil {
+			mb.Set(MetadataCompressionRatio, float64(estimateTokens(compressed))/float64(original))
+		}
+		return calque.Write(w, compressed)
+	})
+}
+
+// ExtractiveConfig configures ExtractiveCompressor.
+type ExtractiveConfig struct {
+	Query     func(ctx context.Context) string // question to favour relevant sentences for (nil = no query)
+	KeepFirst int                              // leading sentences always kept, e.g. instructions (0 = none)
+}
+
+// ExtractiveCompressor creates a Compressor that keeps the most informative
+// sentences within the budget, in their original order.
+//
+// Sentences are scored by the frequency of their content words across the
+// text, with a boost for words of ExtractiveConfig.Query. No model is called.
+//
+// Example:
+//
+//	compressor := ai.ExtractiveCompressor(ai.ExtractiveConfig{
+//		Query: func(ctx context.Context) string { return userQuestion(ctx) },
+//	})
+func ExtractiveCompressor(config ...ExtractiveConfig) Compressor {
+	cfg := ExtractiveConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	return CompressorFunc(func(ctx context.Context, text string, targetTokens int) (string, error) {
+		query := ""
+		if cfg.Query != nil {
+			query = cfg.Query(ctx)
+		}
+		return extract(text, targetTokens, query, cfg.KeepFirst), nil
+	})
+}
+
+// extract selects sentences greedily by score until the budget is spent
+func extract(text string, targetTokens int, query string, keepFirst int) string {
+	sentences := splitSentences(text)
+
+	freq := make(map[string]int)
+	for _, s := range sentences {
+		for _, word := range contentWords(s) {
+			freq[word]++
+		}
+	}
+	queryWords := make(map[string]bool)
+	for _, word := range contentWords(query) {
+		queryWords[word] = true
+	}
+
+	scores := make([]float64, len(sentences))
+	for i, s := range sentences {
+		words := contentWords(s)
+		if len(words) == 0 {
+			continue
+		}
+		var score float64
+		for _, word := range words {
+			score += float64(freq[word])
+			if queryWords[word] {
+				score += float64(len(sentences))
+			}
+		}
+		scores[i] = score / float64(len(words))
+	}
+
+	order := make([]int, len(sentences))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+
+	keep := make([]bool, len(sentences))
+	used := 0
+	for i := 0; i < keepFirst && i < len(sentences); i++ {
+		keep[i] = true
+		used += estimateTokens(sentences[i])
+	}
+	for _, i := range order {
+		if keep[i] {
+			continue
+		}
+		if cost := estimateTokens(sentences[i]); used+cost <= targetTokens {
+			keep[i] = true
+			used += cost
+		}
+	}
+
+	var b strings.Builder
+	for i, s := range sentences {
+		if keep[i] {
+			b.WriteString(s)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// splitSentences splits text after sentence punctuation or line breaks,
+// keeping trailing whitespace with each sentence so layout survives
+func splitSentences(text string) []string {
+	var sentences []string
+	runes := []rune(text)
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		end := runes[i] == '\n' ||
+			(strings.ContainsRune(".!?", runes[i]) && (i+1 == len(runes) || unicode.IsSpace(runes[i+1])))
+		if !end {
+			continue
+		}
+		for i+1 < len(runes) && unicode.IsSpace(runes[i+1]) {
+			i++
+		}
+		sentences = append(sentences, string(runes[start:i+1]))
+		start = i + 1
+	}
+	if start < len(runes) {
+		sentences = append(sentences, string(runes[start:]))
+	}
+	return sentences
+}
+
+// stopWords are common words ignored when scoring sentences
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "has": true, "have": true, "in": true, "is": true,
+	"it": true, "its": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "were": true, "with": true, "what": true, "which": true,
+}
+
+func contentWords(s string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words := fields[:0]
+	for _, f := range fields {
+		if !stopWords[f] {
+			words = append(words, f)
+		}
+	}
+	return words
+}
+
+// ModelCompressor creates a Compressor that asks a model to rewrite the text
+// within the budget, keeping facts, names, numbers and instructions.
+//
+// Use a small, cheap model; the rewrite costs one call per compression.
+//
+// Example:
+//
+//	flow.Use(ai.CompressContext(ai.ModelCompressor(miniClient), 1500)).
+//		Use(ai.Agent(largeClient))
+func ModelCompressor(client Client, opts ...AgentOption) Compressor {
+	return CompressorFunc(func(ctx context.Context, text string, targetTokens int) (string, error) {
+		prompt := fmt.Sprintf("Compress the following text to at most %d tokens. "+
+			"Keep every fact, name, number and instruction; remove repetition and filler. "+
+			"Respond with the compressed text only.\n\n%s", targetTokens, text)
+
+		agentOpts := &AgentOptions{}
+		for _, opt := range opts {
+			opt.Apply(agentOpts)
+		}
+		chargeBudget(ctx, agentOpts)
+
+		var out bytes.Buffer
+		if err := client.Chat(calque.NewRequest(ctx, strings.NewReader(prompt)), calque.NewResponse(&out), agentOpts); err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(out.String()), nil
+	})
+}
+
+// estimateTokens approximates a token count at ~4 characters per token
+func estimateTokens(text string) int {
+	return (len([]rune(text)) + 3) / 4
+}
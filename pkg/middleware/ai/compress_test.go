@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+const compressDoc = "Go is a programming language designed at Google. " +
+	"The weather was pleasant that year. " +
+	"Go programs compile quickly and Go has built-in concurrency. " +
+	"Lunch was served at noon.\n" +
+	"Goroutines make Go concurrency cheap."
+
+func TestCompressContext(t *testing.T) {
+	tests := []struct {
+		name         string
+		compressor   Compressor
+		target       int
+		wantContains []string
+		wantMissing  []string
+		wantRatio    bool
+	}{
+		{
+			name:         "short input passes through",
+			compressor:   ExtractiveCompressor(),
+			target:       1000,
+			wantContains: []string{compressDoc},
+		},
+		{
+			name:         "extractive keeps frequent topic sentences",
+			compressor:   ExtractiveCompressor(),
+			target:       30,
+			wantContains: []string{"Go programs compile quickly", "Goroutines make Go concurrency cheap."},
+			wantMissing:  []string{"weather", "Lunch"},
+			wantRatio:    true,
+		},
+		{
+			name: "query favours matching sentences",
+			compressor: ExtractiveCompressor(ExtractiveConfig{
+				Query: func(context.Context) string { return "when is lunch served?" },
+			}),
+			target:       10,
+			wantContains: []string{"Lunch was served at noon."},
+			wantRatio:    true,
+		},
+		{
+			name:         "keep first preserves leading sentence",
+			compressor:   ExtractiveCompressor(ExtractiveConfig{KeepFirst: 1}),
+			target:       20,
+			wantContains: []string{"Go is a programming language designed at Google."},
+			wantRatio:    true,
+		},
+		{
+			name:         "model compressor uses model output",
+			compressor:   ModelCompressor(NewMockClient("Go: fast, concurrent.").WithStreamDelay(0)),
+			target:       10,
+			wantContains: []string{"Go: fast, concurrent."},
+			wantRatio:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mb := calque.NewMetadataBus(0)
+			ctx := calque.WithMetadataBus(context.Background(), mb)
+
+			var out string
+			if err := calque.NewFlow().Use(CompressContext(tt.compressor, tt.target)).Run(ctx, compressDoc, &out); err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.wantContains {
+				if !strings.Contains(out, s) {
+					t.Errorf("output %q missing %q", out, s)
+				}
+			}
+			for _, s := range tt.wantMissing {
+				if strings.Contains(out, s) {
+					t.Errorf("output %q should not contain %q", out, s)
+				}
+			}
+			_, hasRatio := mb.Get(MetadataCompressionRatio)
+			if hasRatio != tt.wantRatio {
+				t.Errorf("ratio recorded = %v, want %v", hasRatio, tt.wantRatio)
+			}
+			if tt.wantRatio && estimateTokens(out) > tt.target {
+				t.Errorf("output has %d tokens, budget %d", estimateTokens(out), tt.target)
+			}
+		})
+	}
+}
+
+func TestCompressContextError(t *testing.T) {
+	failing := CompressorFunc(func(context.Context, string, int) (string, error) {
+		return "", errors.New("boom")
+	})
+	var out string
+	err := calque.NewFlow().Use(CompressContext(failing, 1)).Run(context.Background(), compressDoc, &out)
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("error = %v, want compressor error", err)
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+	got := splitSentences("One. Two! Three?\nv1.2 stays\n\nLast")
+	want := []string{"One. ", "Two! ", "Three?\n", "v1.2 stays\n\n", "Last"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("splitSentences = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,207 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	googleschema "github.com/google/jsonschema-go/jsonschema"
+	"github.com/invopop/jsonschema"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// ConstraintKind identifies the shape a Constraint requires.
+type ConstraintKind string
+
+// Constraint kinds
+const (
+	ConstraintRegex      ConstraintKind = "regex"       // the whole output matches a regular expression
+	ConstraintGrammar    ConstraintKind = "grammar"     // the output is derivable from a GBNF grammar
+	ConstraintJSONSchema ConstraintKind = "json_schema" // the output is JSON valid against a schema
+)
+
+// ErrConstraintViolated is wrapped by the error an Agent returns when output
+// checked after generation does not satisfy its Constraint.
+var ErrConstraintViolated = errors.New("output violates constraint")
+
+// Constraint is a required output shape, created with Regex, Grammar or
+// JSONSchema and applied with Constrain.
+type Constraint struct {
+	Kind    ConstraintKind
+	Pattern string             // regular expression or GBNF grammar source
+	Schema  *jsonschema.Schema // for ConstraintJSONSchema
+
+	check func(output []byte) error // post-hoc validation, nil when impossible
+	err   error                     // invalid constraint, reported at request time
+}
+
+// ConstraintEnforcer is implemented by clients that enforce constraints
+// while decoding, so their output needs no validation afterwards.
+type ConstraintEnforcer interface {
+	EnforcesConstraint(c Constraint) bool
+}
+
+// Regex constrains the whole output to match pattern.
+//
+// Providers enforcing regexes receive pattern as is; otherwise the output is
+// checked with Go's regexp syntax, anchored at both ends.
+//
+// Example:
+//
+//	agent := ai.Agent(client, ai.Constrain(ai.Regex(`(yes|no)`)))
+func Regex(pattern string) Constraint {
+	c := Constraint{Kind: ConstraintRegex, Pattern: pattern}
+	re, err := regexp.Compile(`\A(?:` + pattern + `)\z`)
+	if err != nil {
+		c.err = err
+		return c
+	}
+	c.check = func(output []byte) error {
+		if !re.Match(output) {
+			return fmt.Errorf("%w: output does not match %s", ErrConstraintViolated, pattern)
+		}
+		return nil
+	}
+	return c
+}
+
+// Grammar constrains the output to a GBNF grammar, as accepted by llama.cpp
+// and vLLM-compatible servers.
+//
+// Grammars cannot be checked after generation: Agent returns an invalid
+// input error when the client does not enforce them.
+//
+// Example:
+//
+//	agent := ai.Agent(client, ai.Constrain(ai.Grammar(`root ::= "yes" | "no"`)))
+func Grammar(gbnf string) Constraint {
+	return Constraint{Kind: ConstraintGrammar, Pattern: gbnf}
+}
+
+// JSONSchema constrains the output to JSON valid against a schema.
+// Like WithSchema, it accepts a *ResponseFormat, a *jsonschema.Schema, or any
+// struct or pointer to reflect the schema from.
+//
+// The schema is also set as AgentOptions.Schema, so every bundled provider
+// enforces it through its structured output support.
+//
+// Example:
+//
+//	agent := ai.Agent(client, ai.Constrain(ai.JSONSchema(&Invoice{})))
+func JSONSchema(source any) Constraint {
+	var schema *jsonschema.Schema
+	switch v := source.(type) {
+	case *jsonschema.Schema:
+		schema = v
+	case *ResponseFormat:
+		schema = v.Schema
+	case ResponseFormat:
+		schema = v.Schema
+	default:
+		reflector := jsonschema.Reflector{}
+		schema = reflector.Reflect(v)
+	}
+
+	c := Constraint{Kind: ConstraintJSONSchema, Schema: schema}
+	if schema == nil {
+		c.err = errors.New("JSON schema constraint has no schema")
+		return c
+	}
+	resolved, err := resolveSchema(schema)
+	if err != nil {
+		c.err = err
+		return c
+	}
+	c.check = func(output []byte) error {
+		var instance any
+		if err := json.Unmarshal(bytes.TrimSpace(output), &instance); err != nil {
+			return fmt.Errorf("%w: invalid JSON: %v", ErrConstraintViolated, err)
+		}
+		if err := resolved.Validate(instance); err != nil {
+			return fmt.Errorf("%w: %v", ErrConstraintViolated, err)
+		}
+		return nil
+	}
+	return c
+}
+
+// resolveSchema converts a reflected schema into a validator
+func resolveSchema(schema *jsonschema.Schema) (*googleschema.Resolved, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	var s googleschema.Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return s.Resolve(nil)
+}
+
+type constraintOption struct{ constraint Constraint }
+
+func (o constraintOption) Apply(opts *AgentOptions) {
+	c := o.constraint
+	opts.Constraint = &c
+	if c.Kind == ConstraintJSONSchema && c.Schema != nil {
+		opts.Schema = &ResponseFormat{Type: "json_schema", Schema: c.Schema}
+	}
+}
+
+// Constrain requires the agent's output to match a Regex, Grammar or JSONSchema.
+//
+// Input: Constraint describing the required output shape
+// Output: AgentOption for configuration
+// Behavior: Passed to clients implementing ConstraintEnforcer, which keep
+// streaming; for other clients the output is BUFFERED and checked
+//
+// Clients enforcing the constraint during decoding (response_format JSON
+// schemas, guided regexes and grammars) get matching output on the first
+// try. Otherwise the output is validated after generation and an error
+// wrapping ErrConstraintViolated is returned, which validate.With or
+// ctrl.Retry can act on.
+//
+// Example:
+//
+//	sentiment := ai.Agent(client, ai.Constrain(ai.Regex(`(positive|negative|neutral)`)))
+//	invoice := ai.Agent(client, ai.Constrain(ai.JSONSchema(&Invoice{})))
+func Constrain(c Constraint) AgentOption {
+	return constraintOption{constraint: c}
+}
+
+// GetConstraint extracts the constraint from AgentOptions, returns nil if none
+func GetConstraint(opts *AgentOptions) *Constraint {
+	if opts != nil {
+		return opts.Constraint
+	}
+	return nil
+}
+
+// chatConstrained runs chat, checking its output when client does not enforce the constraint
+func chatConstrained(client Client, agentOpts *AgentOptions, r *calque.Request, w *calque.Response, chat func(*calque.Response) error) error {
+	c := agentOpts.Constraint
+	if c == nil {
+		return chat(w)
+	}
+	if c.err != nil {
+		return calque.InvalidInput(calque.WrapErr(r.Context, c.err, fmt.Sprintf("invalid %s constraint", c.Kind)))
+	}
+	if enforcer, ok := client.(ConstraintEnforcer); ok && enforcer.EnforcesConstraint(*c) {
+		return chat(w)
+	}
+	if c.check == nil {
+		return calque.InvalidInput(calque.NewErr(r.Context, fmt.Sprintf("client cannot enforce %s constraints", c.Kind)))
+	}
+
+	var output bytes.Buffer
+	if err := chat(calque.NewResponse(&output)); err != nil {
+		return err
+	}
+	if err := c.check(output.Bytes()); err != nil {
+		return calque.WrapErr(r.Context, err, "constrained generation failed")
+	}
+	return calque.Write(w, output.Bytes())
+}
@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// enforcingClient answers like MockClient and claims to enforce every constraint
+type enforcingClient struct {
+	*MockClient
+	seen *Constraint
+}
+
+func (c *enforcingClient) EnforcesConstraint(constraint Constraint) bool {
+	c.seen = &constraint
+	return true
+}
+
+func runConstrained(client Client, c Constraint) (string, error) {
+	var out bytes.Buffer
+	err := Agent(client, Constrain(c)).ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("hi")), calque.NewResponse(&out))
+	return out.String(), err
+}
+
+func TestConstrainRegex(t *testing.T) {
+	if out, err := runConstrained(NewMockClient("positive"), Regex(`positive|negative`)); err != nil || out != "positive" {
+		t.Errorf("out = %q, err = %v", out, err)
+	}
+	_, err := runConstrained(NewMockClient("positive, mostly"), Regex(`positive|negative`))
+	if !errors.Is(err, ErrConstraintViolated) || calque.IsInvalidInput(err) {
+		t.Errorf("partial match error = %v, want a constraint violation", err)
+	}
+	if _, err := runConstrained(NewMockClient("x"), Regex(`(`)); !calque.IsInvalidInput(err) {
+		t.Errorf("bad pattern error = %v, want invalid input", err)
+	}
+}
+
+func TestConstrainJSONSchema(t *testing.T) {
+	type invoice struct {
+		Total float64 `json:"total"`
+	}
+	var opts AgentOptions
+	Constrain(JSONSchema(&invoice{})).Apply(&opts)
+	if opts.Schema == nil || opts.Schema.Type != "json_schema" || opts.Constraint.Kind != ConstraintJSONSchema {
+		t.Fatalf("options = %+v", opts)
+	}
+
+	if _, err := runConstrained(NewMockClient(`{"total": 12.5}`), JSONSchema(&invoice{})); err != nil {
+		t.Errorf("valid output error = %v", err)
+	}
+	for _, bad := range []string{`{"total": "12"}`, `{}`, `total: 12`} {
+		if _, err := runConstrained(NewMockClient(bad), JSONSchema(&invoice{})); !errors.Is(err, ErrConstraintViolated) {
+			t.Errorf("%s: err = %v, want a constraint violation", bad, err)
+		}
+	}
+}
+
+func TestConstrainEnforced(t *testing.T) {
+	client := &enforcingClient{MockClient: NewMockClient("anything")}
+	out, err := runConstrained(client, Grammar(`root ::= "yes" | "no"`))
+	if err != nil || out != "anything" {
+		t.Errorf("out = %q, err = %v, want the unchecked output", out, err)
+	}
+	if client.seen == nil || client.seen.Kind != ConstraintGrammar {
+		t.Errorf("client saw %+v", client.seen)
+	}
+
+	if _, err := runConstrained(NewMockClient("yes"), Grammar(`root ::= "yes"`)); !calque.IsInvalidInput(err) {
+		t.Errorf("unenforced grammar error = %v, want invalid input", err)
+	}
+}
@@ -0,0 +1,251 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultExpectedOutputTokens is the completion size a CostRouter assumes
+// when estimating context needs and prices.
+const DefaultExpectedOutputTokens = 500
+
+// costLatencyAlpha is the EWMA weight of each observed call latency
+const costLatencyAlpha = 0.2
+
+type routeConstraintsKey struct{}
+
+// RouteConstraints are the requirements a model must meet to be chosen by a CostRouter.
+type RouteConstraints struct {
+	ContextTokens int           // context window needed (0 = estimated from the input and expected output)
+	Require       []Capability  // capabilities needed besides those implied by the request
+	MaxLatency    time.Duration // highest acceptable typical response time (0 = any)
+}
+
+// WithRouteConstraints adds per-request constraints for a CostRouter, on top
+// of CostRouterConfig.Constraints.
+//
+// Example:
+//
+//	ctx = ai.WithRouteConstraints(ctx, ai.RouteConstraints{MaxLatency: 2 * time.Second})
+//	err := flow.Run(ctx, question, &answer)
+func WithRouteConstraints(ctx context.Context, c RouteConstraints) context.Context {
+	return context.WithValue(ctx, routeConstraintsKey{}, c)
+}
+
+// CostRouterConfig configures a CostRouter.
+type CostRouterConfig struct {
+	// Catalog is the capability and pricing table (nil = Models()).
+	Catalog *Catalog
+	// Models override or extend Catalog for this router, matched to
+	// providers by Name.
+	Models []ModelInfo
+	// ExpectedOutputTokens is the assumed completion size (0 = DefaultExpectedOutputTokens).
+	ExpectedOutputTokens int
+	// Constraints apply to every request.
+	Constraints RouteConstraints
+}
+
+// CostRouter is a Client that sends each request to the cheapest model able to serve it.
+//
+// A provider is eligible when its model, looked up by Provider.Name in the
+// catalog (see Models), has a context window for the estimated prompt plus the
+// expected output, supports every required capability and, with a latency
+// constraint, answers fast enough. Capabilities are implied by the request:
+// tools need CapabilityTools, a response schema CapabilityJSON, image and
+// audio parts CapabilityVision and CapabilityAudio. Latency is the observed
+// average of the provider's calls, or ModelInfo.TypicalLatency before any.
+//
+// Eligible providers are tried from the lowest estimated price, failing over
+// to the next while no output was written. Providers only missing the latency
+// constraint are tried last, so a provider that was slow once can recover.
+// Providers missing from the catalog are never chosen. The chosen provider's
+// name is recorded on the MetadataBus under MetadataRouterProvider, and its
+// price fills UsageMetadata.CostUSD. Safe for concurrent use.
+type CostRouter struct {
+	providers []Provider
+	overrides map[string]ModelInfo
+	config    CostRouterConfig
+
+	mu      sync.Mutex
+	latency []time.Duration // observed EWMA per provider, 0 until the first success
+}
+
+// NewCostRouter creates a CostRouter over providers, in order of preference for equal prices.
+//
+// Example:
+//
+//	router := ai.NewCostRouter([]ai.Provider{
+//		{Name: "openai/gpt-4o", Client: gpt4o},
+//		{Name: "openai/gpt-4o-mini", Client: gpt4oMini},
+//		{Name: "gemini/gemini-2.0-flash", Client: flash},
+//		{Name: "acme/private-model", Client: private},
+//	}, ai.CostRouterConfig{
+//		Models: []ai.ModelInfo{{Name: "acme/private-model", ContextWindow: 32000, InputPerMillion: 0.05, OutputPerMillion: 0.05}},
+//		Constraints: ai.RouteConstraints{MaxLatency: 5 * time.Second},
+//	})
+//	agent := ai.Agent(router, ai.WithTools(search)) // only tool-calling models qualify
+func NewCostRouter(providers []Provider, config ...CostRouterConfig) *CostRouter {
+	var cfg CostRouterConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.ExpectedOutputTokens <= 0 {
+		cfg.ExpectedOutputTokens = DefaultExpectedOutputTokens
+	}
+
+	if cfg.Catalog == nil {
+		cfg.Catalog = Models()
+	}
+
+	overrides := make(map[string]ModelInfo, len(cfg.Models))
+	for _, m := range cfg.Models {
+		overrides[m.Name] = m
+	}
+	return &CostRouter{
+		providers: providers,
+		overrides: overrides,
+		config:    cfg,
+		latency:   make([]time.Duration, len(providers)),
+	}
+}
+
+// Chat implements Client, trying eligible providers from cheapest to most expensive.
+func (r *CostRouter) Chat(req *calque.Request, res *calque.Response, opts *AgentOptions) error {
+	var input []byte
+	if err := calque.Read(req, &input); err != nil {
+		return err
+	}
+
+	need := r.constraints(req.Context, input, opts)
+	order := r.eligible(need, estimateTokens(string(input)))
+	if len(order) == 0 {
+		return calque.InvalidInput(calque.NewErr(req.Context, fmt.Sprintf(
+			"no model satisfies the request: %d context tokens, capabilities %v, max latency %v",
+			need.ContextTokens, need.Require, need.MaxLatency)))
+	}
+
+	var lastErr error
+	for _, idx := range order {
+		provider := r.providers[idx]
+		out := &startedWriter{w: res.Data}
+		model, _ := r.Model(provider.Name)
+
+		start := time.Now()
+		err := provider.Client.Chat(calque.NewRequest(req.Context, bytes.NewReader(input)), calque.NewResponse(out), pricedOptions(opts, model))
+		if err == nil {
+			r.observe(idx, time.Since(start))
+			if mb := calque.GetMetadataBus(req.Context); mb != nil {
+				mb.Set(MetadataRouterProvider, provider.Name)
+			}
+			return nil
+		}
+
+		lastErr = calque.WrapErr(req.Context, err, fmt.Sprintf("provider %s failed", provider.Name))
+		if out.started || calque.IsCancelled(err) || req.Context.Err() != nil || calque.IsInvalidInput(err) {
+			return lastErr
+		}
+	}
+	return calque.WrapErr(req.Context, lastErr, "all eligible providers failed")
+}
+
+// Model returns the catalog entry used for a provider name, with overrides applied.
+func (r *CostRouter) Model(name string) (ModelInfo, bool) {
+	if m, ok := r.overrides[name]; ok {
+		return m, true
+	}
+	return r.config.Catalog.Lookup(name)
+}
+
+// constraints merges configured, per-request and request-implied requirements
+func (r *CostRouter) constraints(ctx context.Context, input []byte, opts *AgentOptions) RouteConstraints {
+	need := r.config.Constraints
+	need.Require = slices.Clone(need.Require)
+	if c, ok := ctx.Value(routeConstraintsKey{}).(RouteConstraints); ok {
+		need.ContextTokens = max(need.ContextTokens, c.ContextTokens)
+		need.Require = append(need.Require, c.Require...)
+		if c.MaxLatency > 0 && (need.MaxLatency == 0 || c.MaxLatency < need.MaxLatency) {
+			need.MaxLatency = c.MaxLatency
+		}
+	}
+	if need.ContextTokens == 0 {
+		need.ContextTokens = estimateTokens(string(input)) + r.config.ExpectedOutputTokens
+	}
+
+	if opts != nil {
+		if len(opts.Tools) > 0 {
+			need.Require = append(need.Require, CapabilityTools)
+		}
+		if opts.Schema != nil {
+			need.Require = append(need.Require, CapabilityJSON)
+		}
+		if opts.MultimodalData != nil {
+			for _, part := range opts.MultimodalData.Parts {
+				switch part.Type {
+				case "image", "video":
+					need.Require = append(need.Require, CapabilityVision)
+				case "audio":
+					need.Require = append(need.Require, CapabilityAudio)
+				}
+			}
+		}
+	}
+	slices.Sort(need.Require)
+	need.Require = slices.Compact(need.Require)
+	return need
+}
+
+// eligible returns the providers meeting need, cheapest first, followed by
+// those only too slow
+func (r *CostRouter) eligible(need RouteConstraints, promptTokens int) []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var order, slow []int
+	prices := make(map[int]float64)
+	for i, p := range r.providers {
+		m, ok := r.Model(p.Name)
+		if !ok || m.ContextWindow < need.ContextTokens || !m.Supports(need.Require...) {
+			continue
+		}
+		if m.MaxOutputTokens > 0 && m.MaxOutputTokens < r.config.ExpectedOutputTokens {
+			continue
+		}
+		latency := r.latency[i]
+		if latency == 0 {
+			latency = m.TypicalLatency
+		}
+		prices[i] = m.Cost(promptTokens, r.config.ExpectedOutputTokens)
+		if need.MaxLatency > 0 && latency > need.MaxLatency {
+			slow = append(slow, i)
+			continue
+		}
+		order = append(order, i)
+	}
+	if len(order) == 0 {
+		return nil // nothing meets the latency constraint
+	}
+	byPrice := func(ids []int) {
+		sort.SliceStable(ids, func(a, b int) bool { return prices[ids[a]] < prices[ids[b]] })
+	}
+	byPrice(order)
+	byPrice(slow)
+	return append(order, slow...)
+}
+
+// observe folds a successful call's latency into the provider's average
+func (r *CostRouter) observe(idx int, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.latency[idx] == 0 {
+		r.latency[idx] = latency
+		return
+	}
+	r.latency[idx] += time.Duration(costLatencyAlpha * float64(latency-r.latency[idx]))
+}
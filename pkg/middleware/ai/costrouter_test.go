@@ -0,0 +1,92 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func costChat(ctx context.Context, client Client, input string, opts *AgentOptions) (string, error) {
+	var sb strings.Builder
+	err := client.Chat(calque.NewRequest(ctx, strings.NewReader(input)), calque.NewResponse(&sb), opts)
+	return sb.String(), err
+}
+
+func TestCostRouterCheapest(t *testing.T) {
+	mini := &scriptedClient{name: "mini"}
+	large := &scriptedClient{name: "large"}
+	local := &scriptedClient{name: "local"}
+	router := NewCostRouter([]Provider{
+		{Name: "openai/gpt-4o", Client: large},
+		{Name: "openai/gpt-4o-mini", Client: mini},
+		{Name: "ollama/llava", Client: local},
+	})
+
+	if got, err := costChat(context.Background(), router, "hi", &AgentOptions{}); err != nil || got != "local" {
+		t.Errorf("plain request = %q, %v, want the free local model", got, err)
+	}
+
+	// A schema needs CapabilityJSON, which llava lacks
+	if got, _ := costChat(context.Background(), router, "hi", &AgentOptions{Schema: &ResponseFormat{}}); got != "mini" {
+		t.Errorf("schema request = %q, want mini", got)
+	}
+
+	// A prompt beyond llava's 4096-token window goes to a larger model
+	long := strings.Repeat("word ", 20000)
+	if got, _ := costChat(context.Background(), router, long, &AgentOptions{}); got != "mini" {
+		t.Errorf("long request = %q, want mini", got)
+	}
+
+	ctx := WithRouteConstraints(context.Background(), RouteConstraints{ContextTokens: 200000})
+	if _, err := costChat(ctx, router, "hi", &AgentOptions{}); !calque.IsInvalidInput(err) {
+		t.Errorf("unsatisfiable request error = %v, want invalid input", err)
+	}
+}
+
+func TestCostRouterOverridesAndFailover(t *testing.T) {
+	private := &scriptedClient{name: "private"}
+	mini := &scriptedClient{name: "mini"}
+	unknown := &scriptedClient{name: "unknown"}
+	router := NewCostRouter([]Provider{
+		{Name: "acme/unlisted", Client: unknown},
+		{Name: "openai/gpt-4o-mini", Client: mini},
+		{Name: "acme/private", Client: private},
+	}, CostRouterConfig{Models: []ModelInfo{
+		{Name: "acme/private", ContextWindow: 32000, InputPerMillion: 0.01, OutputPerMillion: 0.01},
+	}})
+
+	if got, _ := costChat(context.Background(), router, "hi", &AgentOptions{}); got != "private" {
+		t.Errorf("got %q, want the overridden cheapest model", got)
+	}
+	if m, ok := router.Model("acme/private"); !ok || m.ContextWindow != 32000 {
+		t.Errorf("Model() = %+v, %v", m, ok)
+	}
+
+	private.fail.Store(true)
+	if got, err := costChat(context.Background(), router, "hi", &AgentOptions{}); err != nil || got != "mini" {
+		t.Errorf("failover = %q, %v, want mini", got, err)
+	}
+	if unknown.calls.Load() != 0 {
+		t.Error("a provider missing from the table was called")
+	}
+}
+
+func TestCostRouterLatency(t *testing.T) {
+	slow := &scriptedClient{name: "slow", delay: 30 * time.Millisecond}
+	fast := &scriptedClient{name: "fast"}
+	router := NewCostRouter([]Provider{
+		{Name: "ollama/llama3.2", Client: slow},
+		{Name: "openai/gpt-4o-mini", Client: fast},
+	}, CostRouterConfig{Constraints: RouteConstraints{MaxLatency: 10 * time.Millisecond}})
+
+	// Unknown latency passes; the first call shows the free model is too slow
+	if got, _ := costChat(context.Background(), router, "hi", &AgentOptions{}); got != "slow" {
+		t.Fatalf("first request = %q, want slow", got)
+	}
+	if got, _ := costChat(context.Background(), router, "hi", &AgentOptions{}); got != "fast" {
+		t.Errorf("second request = %q, want fast", got)
+	}
+}
@@ -6,17 +6,21 @@ package gemini
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sync"
 
 	"google.golang.org/genai"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
+	calqueconfig "github.com/calque-ai/go-calque/pkg/config"
 	"github.com/calque-ai/go-calque/pkg/helpers"
 	"github.com/calque-ai/go-calque/pkg/middleware/ai"
 	"github.com/calque-ai/go-calque/pkg/middleware/ai/config"
 	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+	"github.com/calque-ai/go-calque/pkg/secrets"
 )
 
 const applicationJSON = "application/json"
@@ -35,6 +39,9 @@ type Client struct {
 	model     string
 	config    *Config
 	lastUsage *ai.UsageMetadata
+
+	mu        sync.Mutex // guards client rebuilds when APIKeyRef rotates
+	clientKey string     // API key the current client was built with
 }
 
 // Config holds Gemini-specific configuration.
@@ -52,6 +59,10 @@ type Config struct {
 	// Required. API key for Google AI/Vertex AI authentication
 	APIKey string
 
+	// Optional. Secret reference for the API key, resolved per request so rotated
+	// keys are picked up without restarting. Takes precedence over APIKey.
+	APIKeyRef *secrets.Ref
+
 	// Optional. Controls randomness in token selection (0.0-2.0)
 	// Lower values = more deterministic, higher values = more creative
 	Temperature *float32
@@ -99,6 +110,11 @@ type Config struct {
 	Stream *bool
 }
 
+// String formats the config with the API key redacted.
+func (c Config) String() string {
+	return calqueconfig.String(c)
+}
+
 // Option interface for functional options pattern
 type Option interface {
 	Apply(*Config)
@@ -170,10 +186,15 @@ func New(model string, opts ...Option) (*Client, error) {
 	}
 
 	// Validate API key
-	if config.APIKey == "" {
+	if config.APIKey == "" && config.APIKeyRef == nil {
 		return nil, calque.NewErr(ctx, "GOOGLE_API_KEY environment variable not set or provided in config")
 	}
 
+	// With a key reference the client is created on first use with the resolved key
+	if config.APIKeyRef != nil {
+		return &Client{model: model, config: config}, nil
+	}
+
 	// Configure the GenAI client
 	clientConfig := &genai.ClientConfig{
 		APIKey: config.APIKey,
@@ -191,6 +212,31 @@ func New(model string, opts ...Option) (*Client, error) {
 	}, nil
 }
 
+// genaiClient returns the GenAI client, rebuilding it when APIKeyRef resolves to a new key
+func (g *Client) genaiClient(ctx context.Context) (*genai.Client, error) {
+	if g.config == nil || g.config.APIKeyRef == nil {
+		return g.client, nil
+	}
+
+	key, err := g.config.APIKeyRef.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.client == nil || key != g.clientKey {
+		client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: key})
+		if err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to create genai client")
+		}
+		g.client = client
+		g.clientKey = key
+	}
+	return g.client, nil
+}
+
 // RequestConfig holds configuration for a Gemini request
 type RequestConfig struct {
 	GenaiConfig *genai.GenerateContentConfig
@@ -199,6 +245,12 @@ type RequestConfig struct {
 	HasTools    bool
 }
 
+// EnforcesConstraint implements ai.ConstraintEnforcer. JSON schemas are sent as
+// the response JSON schema; the agent checks regexes after generation.
+func (g *Client) EnforcesConstraint(constraint ai.Constraint) bool {
+	return constraint.Kind == ai.ConstraintJSONSchema
+}
+
 // Chat implements the Client interface with streaming support.
 //
 // Input: user prompt/query via calque.Request
@@ -331,8 +383,13 @@ func (g *Client) buildRequestConfig(ctx context.Context, input *ai.ClassifiedInp
 		genaiConfig.Tools = []*genai.Tool{{FunctionDeclarations: geminiFunctions}}
 	}
 
+	client, err := g.genaiClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create chat once
-	chat, err := g.client.Chats.Create(ctx, g.model, genaiConfig, nil)
+	chat, err := client.Chats.Create(ctx, g.model, genaiConfig, nil)
 	if err != nil {
 		return nil, calque.WrapErr(ctx, err, "failed to create chat")
 	}
@@ -371,12 +428,21 @@ func (g *Client) reportUsage(opts *ai.AgentOptions) {
 	}
 }
 
+// classifyError tags Gemini API errors with the calque error kind of their status code
+func classifyError(err error) error {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return calque.ClassifyHTTPStatus(apiErr.Code, err)
+	}
+	return err
+}
+
 // executeNonStreamingRequest executes a non-streaming request using SendMessage
 func (g *Client) executeNonStreamingRequest(config *RequestConfig, r *calque.Request, w *calque.Response, opts *ai.AgentOptions) error {
 	// Use SendMessage for buffered response
 	result, err := config.Chat.SendMessage(r.Context, config.Parts...)
 	if err != nil {
-		return calque.WrapErr(r.Context, err, "failed to get response")
+		return calque.WrapErr(r.Context, classifyError(err), "failed to get response")
 	}
 
 	// Capture usage metadata
@@ -412,7 +478,7 @@ func (g *Client) executeStreamingRequest(config *RequestConfig, r *calque.Reques
 	// Stream response chunks directly
 	for result, err := range config.Chat.SendMessageStream(r.Context, config.Parts...) {
 		if err != nil {
-			return calque.WrapErr(r.Context, err, "failed to get response")
+			return calque.WrapErr(r.Context, classifyError(err), "failed to get response")
 		}
 
 		// Capture usage metadata from stream chunks
@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"slices"
+	"time"
+)
+
+// Capability is a feature a model may support.
+type Capability string
+
+// Model capabilities.
+const (
+	CapabilityVision Capability = "vision" // image input
+	CapabilityAudio  Capability = "audio"  // audio input
+	CapabilityTools  Capability = "tools"  // function calling
+	CapabilityJSON   Capability = "json"   // structured output with a response schema
+)
+
+// ModelInfo describes a model's limits, features and prices.
+type ModelInfo struct {
+	Name             string        `json:"name"`                        // "provider/model", e.g. "openai/gpt-4o-mini"
+	ContextWindow    int           `json:"context_window"`              // input plus output tokens
+	MaxOutputTokens  int           `json:"max_output_tokens,omitempty"` // 0 = limited by the context window only
+	Capabilities     []Capability  `json:"capabilities,omitempty"`
+	InputPerMillion  float64       `json:"input_per_million"`            // USD per million prompt tokens
+	OutputPerMillion float64       `json:"output_per_million"`           // USD per million completion tokens
+	TypicalLatency   time.Duration `json:"typical_latency_ns,omitempty"` // expected response time, used until latency is observed
+}
+
+// Supports reports whether the model has every capability.
+func (m ModelInfo) Supports(caps ...Capability) bool {
+	for _, want := range caps {
+		if !slices.Contains(m.Capabilities, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// Cost returns the USD price of a call with the given token counts.
+func (m ModelInfo) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*m.InputPerMillion + float64(completionTokens)*m.OutputPerMillion) / 1e6
+}
+
+// builtinModels are list prices of the models of the bundled providers;
+// local Ollama models cost nothing
+var builtinModels = []ModelInfo{
+	{Name: "openai/gpt-4o", ContextWindow: 128000, MaxOutputTokens: 16384, Capabilities: []Capability{CapabilityVision, CapabilityTools, CapabilityJSON}, InputPerMillion: 2.50, OutputPerMillion: 10.00},
+	{Name: "openai/gpt-4o-mini", ContextWindow: 128000, MaxOutputTokens: 16384, Capabilities: []Capability{CapabilityVision, CapabilityTools, CapabilityJSON}, InputPerMillion: 0.15, OutputPerMillion: 0.60},
+	{Name: "openai/gpt-4.1", ContextWindow: 1047576, MaxOutputTokens: 32768, Capabilities: []Capability{CapabilityVision, CapabilityTools, CapabilityJSON}, InputPerMillion: 2.00, OutputPerMillion: 8.00},
+	{Name: "openai/gpt-4.1-mini", ContextWindow: 1047576, MaxOutputTokens: 32768, Capabilities: []Capability{CapabilityVision, CapabilityTools, CapabilityJSON}, InputPerMillion: 0.40, OutputPerMillion: 1.60},
+	{Name: "openai/gpt-4.1-nano", ContextWindow: 1047576, MaxOutputTokens: 32768, Capabilities: []Capability{CapabilityVision, CapabilityTools, CapabilityJSON}, InputPerMillion: 0.10, OutputPerMillion: 0.40},
+	{Name: "gemini/gemini-2.0-flash", ContextWindow: 1048576, MaxOutputTokens: 8192, Capabilities: []Capability{CapabilityVision, CapabilityAudio, CapabilityTools, CapabilityJSON}, InputPerMillion: 0.10, OutputPerMillion: 0.40},
+	{Name: "gemini/gemini-2.5-flash", ContextWindow: 1048576, MaxOutputTokens: 65536, Capabilities: []Capability{CapabilityVision, CapabilityAudio, CapabilityTools, CapabilityJSON}, InputPerMillion: 0.30, OutputPerMillion: 2.50},
+	{Name: "gemini/gemini-2.5-pro", ContextWindow: 1048576, MaxOutputTokens: 65536, Capabilities: []Capability{CapabilityVision, CapabilityAudio, CapabilityTools, CapabilityJSON}, InputPerMillion: 1.25, OutputPerMillion: 10.00},
+	{Name: "ollama/llama3.2", ContextWindow: 131072, Capabilities: []Capability{CapabilityTools, CapabilityJSON}},
+	{Name: "ollama/qwen2.5", ContextWindow: 32768, Capabilities: []Capability{CapabilityTools, CapabilityJSON}},
+	{Name: "ollama/llava", ContextWindow: 4096, Capabilities: []Capability{CapabilityVision}},
+}
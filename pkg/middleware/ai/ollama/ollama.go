@@ -6,6 +6,7 @@ package ollama
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"maps"
@@ -190,6 +191,12 @@ type RequestConfig struct {
 	ChatRequest *api.ChatRequest
 }
 
+// EnforcesConstraint implements ai.ConstraintEnforcer. JSON schemas are sent as
+// the format field; the agent checks regexes after generation.
+func (o *Client) EnforcesConstraint(constraint ai.Constraint) bool {
+	return constraint.Kind == ai.ConstraintJSONSchema
+}
+
 // Chat implements the Client interface.
 //
 // Input: user prompt/query via calque.Request
@@ -284,7 +291,7 @@ func (o *Client) executeRequest(config *RequestConfig, r *calque.Request, w *cal
 	// Send chat request
 	err := o.client.Chat(r.Context, config.ChatRequest, responseFunc)
 	if err != nil {
-		return calque.WrapErr(r.Context, err, "failed to chat with ollama")
+		return calque.WrapErr(r.Context, classifyError(err), "failed to chat with ollama")
 	}
 
 	// Capture usage metadata
@@ -329,6 +336,15 @@ func (o *Client) executeRequest(config *RequestConfig, r *calque.Request, w *cal
 	return nil
 }
 
+// classifyError tags Ollama API errors with the calque error kind of their status code
+func classifyError(err error) error {
+	var statusErr api.StatusError
+	if errors.As(err, &statusErr) {
+		return calque.ClassifyHTTPStatus(statusErr.StatusCode, err)
+	}
+	return err
+}
+
 // inputToChatRequest converts classified input to Ollama ChatRequest
 func (o *Client) inputToChatRequest(ctx context.Context, input *ai.ClassifiedInput) (*api.ChatRequest, error) {
 	req := &api.ChatRequest{
@@ -27,6 +27,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -38,10 +39,12 @@ import (
 	"github.com/openai/openai-go/v2/shared/constant"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
+	calqueconfig "github.com/calque-ai/go-calque/pkg/config"
 	"github.com/calque-ai/go-calque/pkg/helpers"
 	"github.com/calque-ai/go-calque/pkg/middleware/ai"
 	"github.com/calque-ai/go-calque/pkg/middleware/ai/config"
 	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+	"github.com/calque-ai/go-calque/pkg/secrets"
 )
 
 // Client implements the Client interface for OpenAI.
@@ -76,6 +79,10 @@ type Config struct {
 	// Required. API key for OpenAI authentication
 	APIKey string
 
+	// Optional. Secret reference for the API key, resolved per request so rotated
+	// keys are picked up without restarting. Takes precedence over APIKey.
+	APIKeyRef *secrets.Ref
+
 	// Optional. Base URL for OpenAI API (defaults to official OpenAI API)
 	BaseURL string
 
@@ -118,6 +125,15 @@ type Config struct {
 
 	// Optional. Enable/disable streaming of responses (true by default)
 	Stream *bool
+
+	// Optional. Send ai.Regex and ai.Grammar constraints as the guided_regex
+	// and guided_grammar fields of vLLM-compatible servers (BaseURL)
+	GuidedDecoding bool
+}
+
+// String formats the config with the API key redacted.
+func (c Config) String() string {
+	return calqueconfig.String(c)
 }
 
 // Option interface for functional options pattern
@@ -193,13 +209,15 @@ func New(model string, opts ...Option) (*Client, error) {
 	}
 
 	// Validate API key
-	if config.APIKey == "" {
+	if config.APIKey == "" && config.APIKeyRef == nil {
 		return nil, calque.NewErr(context.Background(), "OPENAI_API_KEY environment variable not set or provided in config")
 	}
 
-	// Create client options
+	// Create client options (a key reference is applied per request instead)
 	var clientOptions []option.RequestOption
-	clientOptions = append(clientOptions, option.WithAPIKey(config.APIKey))
+	if config.APIKeyRef == nil {
+		clientOptions = append(clientOptions, option.WithAPIKey(config.APIKey))
+	}
 
 	if config.BaseURL != "" {
 		clientOptions = append(clientOptions, option.WithBaseURL(config.BaseURL))
@@ -238,6 +256,7 @@ func (c *Client) Chat(r *calque.Request, w *calque.Response, opts *ai.AgentOptio
 	if err != nil {
 		return err
 	}
+	c.applyConstraint(&params, ai.GetConstraint(opts))
 
 	// Execute the request
 	return c.executeRequest(params, r, w, opts)
@@ -285,6 +304,36 @@ func (c *Client) executeRequest(params openai.ChatCompletionNewParams, r *calque
 
 }
 
+// requestOptions resolves per-request options such as a rotated API key
+func (c *Client) requestOptions(ctx context.Context) ([]option.RequestOption, error) {
+	if c.config.APIKeyRef == nil {
+		return nil, nil
+	}
+	key, err := c.config.APIKeyRef.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []option.RequestOption{option.WithAPIKey(key)}, nil
+}
+
+// checkAuthError invalidates the API key reference when OpenAI rejects it,
+// so the next request fetches a rotated key
+func (c *Client) checkAuthError(err error) {
+	var apiErr *openai.Error
+	if c.config.APIKeyRef != nil && errors.As(err, &apiErr) && apiErr.StatusCode == 401 {
+		c.config.APIKeyRef.Invalidate()
+	}
+}
+
+// classifyError tags OpenAI API errors with the calque error kind of their status code
+func classifyError(err error) error {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		return calque.ClassifyHTTPStatus(apiErr.StatusCode, err)
+	}
+	return err
+}
+
 // reportUsage invokes the usage handler if present
 func (c *Client) reportUsage(opts *ai.AgentOptions) {
 	if c.lastUsage != nil && opts != nil && opts.UsageHandler != nil {
@@ -299,8 +348,13 @@ func (c *Client) executeStreamingRequest(params openai.ChatCompletionNewParams,
 		IncludeUsage: openai.Bool(true),
 	}
 
+	reqOpts, err := c.requestOptions(r.Context)
+	if err != nil {
+		return err
+	}
+
 	// Create streaming request
-	stream := c.client.Chat.Completions.NewStreaming(r.Context, params)
+	stream := c.client.Chat.Completions.NewStreaming(r.Context, params, reqOpts...)
 	defer func() {
 		if closeErr := stream.Close(); closeErr != nil && err == nil {
 			// Only set the error if no other error occurred
@@ -340,7 +394,8 @@ func (c *Client) executeStreamingRequest(params openai.ChatCompletionNewParams,
 	}
 
 	if err := stream.Err(); err != nil {
-		return calque.WrapErr(r.Context, err, "failed to receive stream response")
+		c.checkAuthError(err)
+		return calque.WrapErr(r.Context, classifyError(err), "failed to receive stream response")
 	}
 
 	// Report usage before finalizing
@@ -410,10 +465,16 @@ func (c *Client) finalizeToolCalls(toolCalls map[int]*openai.ChatCompletionMessa
 
 // executeNonStreamingRequest executes a non-streaming request
 func (c *Client) executeNonStreamingRequest(params openai.ChatCompletionNewParams, r *calque.Request, w *calque.Response, opts *ai.AgentOptions) error {
+	reqOpts, err := c.requestOptions(r.Context)
+	if err != nil {
+		return err
+	}
+
 	// Create request
-	response, err := c.client.Chat.Completions.New(r.Context, params)
+	response, err := c.client.Chat.Completions.New(r.Context, params, reqOpts...)
 	if err != nil {
-		return calque.WrapErr(r.Context, err, "failed to create chat completion")
+		c.checkAuthError(err)
+		return calque.WrapErr(r.Context, classifyError(err), "failed to create chat completion")
 	}
 
 	if len(response.Choices) == 0 {
@@ -644,6 +705,31 @@ func (c *Client) setJSONSchemaFormat(schema any, params *openai.ChatCompletionNe
 	}
 }
 
+// EnforcesConstraint implements ai.ConstraintEnforcer. JSON schemas are sent as
+// response_format; regexes and grammars only with Config.GuidedDecoding.
+func (c *Client) EnforcesConstraint(constraint ai.Constraint) bool {
+	switch constraint.Kind {
+	case ai.ConstraintJSONSchema:
+		return true
+	case ai.ConstraintRegex, ai.ConstraintGrammar:
+		return c.config.GuidedDecoding
+	}
+	return false
+}
+
+// applyConstraint adds guided decoding fields for regex and grammar constraints
+func (c *Client) applyConstraint(params *openai.ChatCompletionNewParams, constraint *ai.Constraint) {
+	if constraint == nil || !c.config.GuidedDecoding {
+		return
+	}
+	switch constraint.Kind {
+	case ai.ConstraintRegex:
+		params.SetExtraFields(map[string]any{"guided_regex": constraint.Pattern})
+	case ai.ConstraintGrammar:
+		params.SetExtraFields(map[string]any{"guided_grammar": constraint.Pattern})
+	}
+}
+
 // convertToOpenAITools converts our tool interface to OpenAI's tool format
 func (c *Client) convertToOpenAITools(ctx context.Context, toolList []tools.Tool) ([]openai.ChatCompletionToolUnionParam, error) {
 	openaiTools := make([]openai.ChatCompletionToolUnionParam, len(toolList))
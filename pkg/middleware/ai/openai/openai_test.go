@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -19,6 +21,7 @@ import (
 	"github.com/calque-ai/go-calque/pkg/helpers"
 	"github.com/calque-ai/go-calque/pkg/middleware/ai"
 	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+	"github.com/calque-ai/go-calque/pkg/secrets"
 )
 
 const testModel = "gpt-5"
@@ -1324,3 +1327,90 @@ func TestUsageHandler_NilOptions(_ *testing.T) {
 	// Should not panic with options but no handler
 	client.reportUsage(&ai.AgentOptions{})
 }
+
+func TestAPIKeyRef_Rotation(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		seen = append(seen, auth)
+		if auth != "Bearer sk-rotated" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"invalid key","type":"invalid_request_error"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"1","object":"chat.completion","model":"gpt-5","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	defer srv.Close()
+
+	keys := []string{"sk-old", "sk-rotated"}
+	calls := 0
+	ref := secrets.NewRef(secrets.ProviderFunc(func(_ context.Context, _ string) (string, error) {
+		key := keys[min(calls, len(keys)-1)]
+		calls++
+		return key, nil
+	}), "openai")
+
+	client, err := New(testModel, WithConfig(&Config{
+		APIKeyRef: ref,
+		BaseURL:   srv.URL,
+		Stream:    helpers.PtrOf(false),
+	}))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	chat := func() (string, error) {
+		buf := calque.NewWriter[string]()
+		req := calque.NewRequest(context.Background(), strings.NewReader("hi"))
+		err := client.Chat(req, calque.NewResponse(buf), nil)
+		return buf.String(), err
+	}
+
+	// First call uses the stale key and is rejected, invalidating the reference
+	if _, err := chat(); err == nil {
+		t.Fatal("expected auth error with stale key")
+	}
+	out, err := chat()
+	if err != nil {
+		t.Fatalf("expected success with rotated key, got %v", err)
+	}
+	if out != "ok" {
+		t.Errorf("expected 'ok', got %q", out)
+	}
+	if seen[0] != "Bearer sk-old" || seen[len(seen)-1] != "Bearer sk-rotated" {
+		t.Errorf("unexpected auth headers: %v", seen)
+	}
+}
+
+func TestGuidedDecoding(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"1","object":"chat.completion","model":"local","choices":[{"index":0,"message":{"role":"assistant","content":"yes"},"finish_reason":"stop"}]}`))
+	}))
+	defer srv.Close()
+
+	client, err := New("local", WithConfig(&Config{APIKey: "test", BaseURL: srv.URL, Stream: helpers.PtrOf(false), GuidedDecoding: true}))
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if !client.EnforcesConstraint(ai.Regex(`yes|no`)) || !client.EnforcesConstraint(ai.Grammar(`root ::= "yes"`)) {
+		t.Error("guided decoding client should enforce regexes and grammars")
+	}
+
+	buf := calque.NewWriter[string]()
+	req := calque.NewRequest(context.Background(), strings.NewReader("agree?"))
+	if err := ai.Agent(client, ai.Constrain(ai.Regex(`yes|no`))).ServeFlow(req, calque.NewResponse(buf)); err != nil {
+		t.Fatal(err)
+	}
+	if body["guided_regex"] != "yes|no" || buf.String() != "yes" {
+		t.Errorf("guided_regex = %v, output = %q", body["guided_regex"], buf.String())
+	}
+
+	plain, _ := New("gpt-4o", WithConfig(&Config{APIKey: "test"}))
+	if plain.EnforcesConstraint(ai.Regex(`yes|no`)) || !plain.EnforcesConstraint(ai.JSONSchema(&struct{ A int }{})) {
+		t.Error("without guided decoding only JSON schemas are enforced")
+	}
+}
@@ -35,6 +35,9 @@ type AgentOptions struct {
 	ToolResultFormatter ToolResultFormatterFunc
 	ToolFormatterClient Client
 	UsageHandler        func(*UsageMetadata)
+	PricingModel        string         // catalog model pricing UsageMetadata.CostUSD, see WithModelPricing
+	Constraint          *Constraint    // required output shape, see Constrain
+	Transcript          TranscriptSink // records every agent step, see WithTranscript
 }
 
 // AgentOption interface for functional options pattern.
@@ -263,3 +266,27 @@ func (o usageHandlerOption) Apply(opts *AgentOptions) {
 func WithUsageHandler(handler func(*UsageMetadata)) AgentOption {
 	return usageHandlerOption{handler: handler}
 }
+
+type modelPricingOption struct{ model string }
+
+func (o modelPricingOption) Apply(opts *AgentOptions) { opts.PricingModel = o.model }
+
+// WithModelPricing prices the agent's token usage with a model from the
+// catalog (see Models).
+//
+// Input: model name, "provider/model" or a bare model name
+// Output: AgentOption for configuration
+// Behavior: Fills UsageMetadata.CostUSD from the catalog prices at call time
+//
+// The cost reaches usage handlers, calque.TokenUsage events and Result
+// artifacts, usage.Meter records, and ctrl.Budget MaxCostUSD limits. Unknown
+// models leave CostUSD at 0; clients that price usage themselves, like
+// CostRouter, take precedence.
+//
+// Example:
+//
+//	agent := ctrl.Budget(ai.Agent(client, ai.WithModelPricing("openai/gpt-4o")),
+//		ctrl.BudgetLimits{MaxCostUSD: 0.50})
+func WithModelPricing(model string) AgentOption {
+	return modelPricingOption{model: model}
+}
@@ -0,0 +1,320 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// MetadataRouterProvider is the MetadataBus key holding the name of the provider a Router used
+const MetadataRouterProvider = "ai.router.provider"
+
+// Router defaults.
+const (
+	DefaultRouterWindow          = 50
+	DefaultRouterLatencyWeight   = 0.5
+	DefaultRouterUnhealthyErrors = 0.5
+	DefaultRouterSessionTTL      = 30 * time.Minute
+)
+
+// Provider is one provider/model option of a Router.
+type Provider struct {
+	Name   string  // identifies the option in health reports, e.g. "openai/gpt-4o"
+	Client Client  // client for the provider and model
+	Cost   float64 // relative cost per request or token, used with RouterConfig.CostWeight
+}
+
+// RouterConfig configures provider scoring and stickiness.
+//
+// Each provider is scored as its rolling error rate plus LatencyWeight times
+// its average latency relative to the slowest provider, plus CostWeight times
+// its cost relative to the most expensive one. The lowest score wins.
+type RouterConfig struct {
+	Window          int                              // recent calls per provider used for scoring (0 = DefaultRouterWindow)
+	LatencyWeight   float64                          // latency weight (0 = DefaultRouterLatencyWeight, negative = ignore latency)
+	CostWeight      float64                          // cost weight (0 = ignore cost)
+	UnhealthyErrors float64                          // error rate at which a sticky session moves (0 = DefaultRouterUnhealthyErrors)
+	SessionKey      func(ctx context.Context) string // conversation key for sticky routing (nil or "" = no stickiness)
+	SessionTTL      time.Duration                    // idle time after which a sticky session is forgotten (0 = DefaultRouterSessionTTL)
+}
+
+// ProviderHealth is a snapshot of a provider's rolling statistics.
+type ProviderHealth struct {
+	Name       string
+	Calls      int           // calls in the window
+	ErrorRate  float64       // failed fraction of calls in the window
+	AvgLatency time.Duration // mean latency of successful calls in the window
+	Score      float64       // current routing score (lower is better)
+}
+
+// Router is a Client that routes each request to the healthiest provider.
+//
+// It tracks a rolling window of outcomes and latencies per provider and fails
+// over to the next best provider when a call fails before writing any output.
+// Invalid-input and cancelled errors (calque.IsInvalidInput, calque.IsCancelled)
+// are returned without failover. With RouterConfig.SessionKey, requests of
+// the same conversation stay on one provider while it remains healthy.
+// Safe for concurrent use.
+type Router struct {
+	providers []Provider
+	config    RouterConfig
+
+	mu       sync.Mutex
+	stats    []*providerStats
+	sessions map[string]*stickySession
+}
+
+type stickySession struct {
+	provider int
+	lastUsed time.Time
+}
+
+// providerStats is a ring buffer of recent call outcomes
+type providerStats struct {
+	outcomes []callOutcome
+	next     int
+	full     bool
+}
+
+type callOutcome struct {
+	failed  bool
+	latency time.Duration
+}
+
+// NewRouter creates a Router over the given providers, in order of preference for ties.
+//
+// Example:
+//
+//	router := ai.NewRouter([]ai.Provider{
+//		{Name: "openai/gpt-4o-mini", Client: openaiClient, Cost: 0.15},
+//		{Name: "gemini/gemini-2.0-flash", Client: geminiClient, Cost: 0.10},
+//		{Name: "ollama/llama3.2", Client: ollamaClient},
+//	}, ai.RouterConfig{
+//		CostWeight: 0.2,
+//		SessionKey: func(ctx context.Context) string { return conversationID(ctx) },
+//	})
+//	agent := ai.Agent(router)
+func NewRouter(providers []Provider, config ...RouterConfig) *Router {
+	cfg := RouterConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultRouterWindow
+	}
+	if cfg.LatencyWeight == 0 {
+		cfg.LatencyWeight = DefaultRouterLatencyWeight
+	} else if cfg.LatencyWeight < 0 {
+		cfg.LatencyWeight = 0
+	}
+	if cfg.UnhealthyErrors <= 0 {
+		cfg.UnhealthyErrors = DefaultRouterUnhealthyErrors
+	}
+	if cfg.SessionTTL <= 0 {
+		cfg.SessionTTL = DefaultRouterSessionTTL
+	}
+
+	stats := make([]*providerStats, len(providers))
+	for i := range stats {
+		stats[i] = &providerStats{outcomes: make([]callOutcome, cfg.Window)}
+	}
+	return &Router{
+		providers: providers,
+		config:    cfg,
+		stats:     stats,
+		sessions:  make(map[string]*stickySession),
+	}
+}
+
+// Chat implements Client, trying providers from healthiest to least healthy.
+func (r *Router) Chat(req *calque.Request, res *calque.Response, opts *AgentOptions) error {
+	if len(r.providers) == 0 {
+		return calque.NewErr(req.Context, "router has no providers")
+	}
+
+	var input []byte
+	if err := calque.Read(req, &input); err != nil {
+		return err
+	}
+
+	session := ""
+	if r.config.SessionKey != nil {
+		session = r.config.SessionKey(req.Context)
+	}
+
+	var lastErr error
+	for _, idx := range r.order(session) {
+		provider := r.providers[idx]
+		out := &startedWriter{w: res.Data}
+
+		start := time.Now()
+		err := provider.Client.Chat(calque.NewRequest(req.Context, bytes.NewReader(input)), calque.NewResponse(out), opts)
+		elapsed := time.Since(start)
+
+		cancelled := calque.IsCancelled(err) || req.Context.Err() != nil
+		if !cancelled {
+			// the caller's input or cancellation says nothing about the provider's health
+			r.record(idx, err != nil && !calque.IsInvalidInput(err), elapsed)
+		}
+		if err == nil {
+			r.stick(session, idx)
+			if mb := calque.GetMetadataBus(req.Context); mb != nil {
+				mb.Set(MetadataRouterProvider, provider.Name)
+			}
+			return nil
+		}
+
+		lastErr = calque.WrapErr(req.Context, err, fmt.Sprintf("provider %s failed", provider.Name))
+		if out.started || cancelled || calque.IsInvalidInput(err) {
+			return lastErr
+		}
+	}
+	return calque.WrapErr(req.Context, lastErr, "all providers failed")
+}
+
+// Health returns a snapshot of every provider's rolling statistics, in provider order.
+func (r *Router) Health() []ProviderHealth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	scores := r.scores()
+	health := make([]ProviderHealth, len(r.providers))
+	for i, p := range r.providers {
+		calls, errRate, latency := r.stats[i].summary()
+		health[i] = ProviderHealth{Name: p.Name, Calls: calls, ErrorRate: errRate, AvgLatency: latency, Score: scores[i]}
+	}
+	return health
+}
+
+// order returns provider indexes to try: the sticky provider first when healthy, then by score
+func (r *Router) order(session string) []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	scores := r.scores()
+	order := make([]int, len(r.providers))
+	for i := range order {
+		order[i] = i
+	}
+	// stable insertion sort keeps provider order for equal scores
+	for i := 1; i < len(order); i++ {
+		for j := i; j > 0 && scores[order[j]] < scores[order[j-1]]; j-- {
+			order[j], order[j-1] = order[j-1], order[j]
+		}
+	}
+
+	if session == "" {
+		return order
+	}
+	now := time.Now()
+	for key, s := range r.sessions {
+		if now.Sub(s.lastUsed) > r.config.SessionTTL {
+			delete(r.sessions, key)
+		}
+	}
+	s, ok := r.sessions[session]
+	if !ok {
+		return order
+	}
+	if _, errRate, _ := r.stats[s.provider].summary(); errRate >= r.config.UnhealthyErrors {
+		return order
+	}
+	sticky := []int{s.provider}
+	for _, idx := range order {
+		if idx != s.provider {
+			sticky = append(sticky, idx)
+		}
+	}
+	return sticky
+}
+
+// scores computes each provider's routing score; callers hold r.mu
+func (r *Router) scores() []float64 {
+	var maxLatency time.Duration
+	var maxCost float64
+	for i, p := range r.providers {
+		_, _, latency := r.stats[i].summary()
+		maxLatency = max(maxLatency, latency)
+		maxCost = max(maxCost, p.Cost)
+	}
+
+	scores := make([]float64, len(r.providers))
+	for i, p := range r.providers {
+		_, errRate, latency := r.stats[i].summary()
+		score := errRate
+		if maxLatency > 0 {
+			score += r.config.LatencyWeight * float64(latency) / float64(maxLatency)
+		}
+		if maxCost > 0 {
+			score += r.config.CostWeight * p.Cost / maxCost
+		}
+		scores[i] = score
+	}
+	return scores
+}
+
+func (r *Router) record(idx int, failed bool, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats[idx].add(callOutcome{failed: failed, latency: latency})
+}
+
+func (r *Router) stick(session string, idx int) {
+	if session == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[session] = &stickySession{provider: idx, lastUsed: time.Now()}
+}
+
+func (s *providerStats) add(o callOutcome) {
+	s.outcomes[s.next] = o
+	s.next = (s.next + 1) % len(s.outcomes)
+	if s.next == 0 {
+		s.full = true
+	}
+}
+
+// summary returns the call count, error rate and mean success latency in the window
+func (s *providerStats) summary() (int, float64, time.Duration) {
+	n := s.next
+	if s.full {
+		n = len(s.outcomes)
+	}
+	if n == 0 {
+		return 0, 0, 0
+	}
+	var failures, successes int
+	var total time.Duration
+	for _, o := range s.outcomes[:n] {
+		if o.failed {
+			failures++
+			continue
+		}
+		successes++
+		total += o.latency
+	}
+	var latency time.Duration
+	if successes > 0 {
+		latency = total / time.Duration(successes)
+	}
+	return n, float64(failures) / float64(n), latency
+}
+
+// startedWriter records whether any output reached the response
+type startedWriter struct {
+	w       io.Writer
+	started bool
+}
+
+func (s *startedWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		s.started = true
+	}
+	return s.w.Write(p)
+}
@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// scriptedClient replies with its name, failing while fail is set
+type scriptedClient struct {
+	name  string
+	fail  atomic.Bool
+	err   error
+	delay time.Duration
+	calls atomic.Int32
+}
+
+func (c *scriptedClient) Chat(r *calque.Request, w *calque.Response, _ *AgentOptions) error {
+	c.calls.Add(1)
+	if _, err := io.Copy(io.Discard, r.Data); err != nil {
+		return err
+	}
+	time.Sleep(c.delay)
+	if c.fail.Load() {
+		if c.err != nil {
+			return c.err
+		}
+		return errors.New(c.name + " unavailable")
+	}
+	return calque.Write(w, c.name)
+}
+
+func chat(ctx context.Context, client Client) (string, error) {
+	var sb strings.Builder
+	err := client.Chat(calque.NewRequest(ctx, strings.NewReader("hi")), calque.NewResponse(&sb), &AgentOptions{})
+	return sb.String(), err
+}
+
+func TestRouterFailover(t *testing.T) {
+	primary := &scriptedClient{name: "primary"}
+	backup := &scriptedClient{name: "backup"}
+	primary.fail.Store(true)
+	router := NewRouter([]Provider{{Name: "primary", Client: primary}, {Name: "backup", Client: backup}})
+
+	got, err := chat(context.Background(), router)
+	if err != nil || got != "backup" {
+		t.Fatalf("chat = %q, %v", got, err)
+	}
+
+	// primary now has a 100% error rate, so backup is tried first
+	primary.calls.Store(0)
+	if got, _ := chat(context.Background(), router); got != "backup" || primary.calls.Load() != 0 {
+		t.Errorf("chat = %q, primary calls = %d", got, primary.calls.Load())
+	}
+
+	health := router.Health()
+	if health[0].ErrorRate != 1 || health[1].ErrorRate != 0 || health[1].Calls != 2 {
+		t.Errorf("health = %+v", health)
+	}
+
+	backup.fail.Store(true)
+	if _, err := chat(context.Background(), router); err == nil || !strings.Contains(err.Error(), "all providers failed") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestRouterInvalidInputSkipsFailover(t *testing.T) {
+	primary := &scriptedClient{name: "primary", err: calque.InvalidInput(errors.New("prompt too long"))}
+	primary.fail.Store(true)
+	backup := &scriptedClient{name: "backup"}
+	router := NewRouter([]Provider{{Name: "primary", Client: primary}, {Name: "backup", Client: backup}})
+
+	if _, err := chat(context.Background(), router); !calque.IsInvalidInput(err) || backup.calls.Load() != 0 {
+		t.Errorf("error = %v, backup calls = %d", err, backup.calls.Load())
+	}
+	if router.Health()[0].ErrorRate != 0 {
+		t.Error("invalid input should not count against provider health")
+	}
+}
+
+func TestRouterLatencyAndCost(t *testing.T) {
+	slow := &scriptedClient{name: "slow", delay: 20 * time.Millisecond}
+	fast := &scriptedClient{name: "fast"}
+	router := NewRouter([]Provider{{Name: "slow", Client: slow}, {Name: "fast", Client: fast}})
+	// ties go to the first provider; once its latency is known the fast one wins
+	if got, _ := chat(context.Background(), router); got != "slow" {
+		t.Errorf("first chat = %q, want slow provider", got)
+	}
+	for range 2 {
+		if got, _ := chat(context.Background(), router); got != "fast" {
+			t.Errorf("chat = %q, want fast provider", got)
+		}
+	}
+
+	cheap := NewRouter([]Provider{
+		{Name: "pricey", Client: &scriptedClient{name: "pricey"}, Cost: 10},
+		{Name: "cheap", Client: &scriptedClient{name: "cheap"}, Cost: 1},
+	}, RouterConfig{CostWeight: 1})
+	if got, _ := chat(context.Background(), cheap); got != "cheap" {
+		t.Errorf("chat = %q, want cheap provider", got)
+	}
+}
+
+type sessionKey struct{}
+
+func TestRouterStickySessions(t *testing.T) {
+	a := &scriptedClient{name: "a"}
+	b := &scriptedClient{name: "b"}
+	router := NewRouter([]Provider{{Name: "a", Client: a}, {Name: "b", Client: b}}, RouterConfig{
+		LatencyWeight: -1,
+		SessionKey: func(ctx context.Context) string {
+			s, _ := ctx.Value(sessionKey{}).(string)
+			return s
+		},
+	})
+	ctx := context.WithValue(context.Background(), sessionKey{}, "conv-1")
+
+	// conv-1 lands on b after a fails once
+	a.fail.Store(true)
+	if got, _ := chat(ctx, router); got != "b" {
+		t.Fatalf("chat = %q", got)
+	}
+	a.fail.Store(false)
+	// make a look better than b; the session still sticks to b
+	for range 10 {
+		router.record(0, false, time.Millisecond)
+	}
+	router.record(1, false, time.Millisecond)
+	router.record(1, false, time.Millisecond)
+	router.record(1, true, time.Millisecond) // 25% errors: worse than a, still healthy
+	if got, _ := chat(ctx, router); got != "b" {
+		t.Errorf("sticky chat = %q, want b", got)
+	}
+	if got, _ := chat(context.Background(), router); got != "a" {
+		t.Errorf("unsticky chat = %q, want a", got)
+	}
+
+	mb := calque.NewMetadataBus(0)
+	if _, err := chat(calque.WithMetadataBus(ctx, mb), router); err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := mb.GetString(MetadataRouterProvider); name != "b" {
+		t.Errorf("metadata provider = %q", name)
+	}
+}
@@ -0,0 +1,208 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+// TranscriptKind identifies a step of an agent run.
+type TranscriptKind string
+
+// Transcript entry kinds
+const (
+	TranscriptModelRequest  TranscriptKind = "model_request"  // prompt sent to the client
+	TranscriptModelResponse TranscriptKind = "model_response" // client output, usage and error
+	TranscriptToolCall      TranscriptKind = "tool_call"      // tool call requested by the model
+	TranscriptToolResult    TranscriptKind = "tool_result"    // tool output or error
+	TranscriptRetry         TranscriptKind = "retry"          // the agent is rerun by ctrl.Retry
+	TranscriptError         TranscriptKind = "error"          // the agent call failed
+)
+
+// TranscriptEntry is one step of an agent run, as recorded by WithTranscript.
+type TranscriptEntry struct {
+	Time       time.Time      `json:"time"`
+	RequestID  string         `json:"request_id,omitempty"`
+	TraceID    string         `json:"trace_id,omitempty"`
+	Seq        int            `json:"seq"`               // order within the agent call, from 1
+	Attempt    int            `json:"attempt,omitempty"` // ctrl.Retry attempt, 0 for the first try
+	Kind       TranscriptKind `json:"kind"`
+	Content    string         `json:"content,omitempty"` // prompt, response, tool arguments or tool result
+	Tool       string         `json:"tool,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+	Duration   time.Duration  `json:"duration_ns,omitempty"`
+	Usage      *UsageMetadata `json:"usage,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// TranscriptSink stores transcript entries, such as a file or a database.
+type TranscriptSink interface {
+	// Record stores an entry; entries of one agent call arrive in Seq order
+	Record(ctx context.Context, entry TranscriptEntry) error
+}
+
+// TranscriptSinkFunc adapts a function to the TranscriptSink interface.
+type TranscriptSinkFunc func(ctx context.Context, entry TranscriptEntry) error
+
+// Record implements TranscriptSink.
+func (f TranscriptSinkFunc) Record(ctx context.Context, entry TranscriptEntry) error {
+	return f(ctx, entry)
+}
+
+// TranscriptWriter writes entries to w as JSON Lines, one entry per line.
+func TranscriptWriter(w io.Writer) TranscriptSink {
+	var mu sync.Mutex
+	return TranscriptSinkFunc(func(ctx context.Context, entry TranscriptEntry) error {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return calque.WrapErr(ctx, err, "failed to encode transcript entry")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if _, err := w.Write(append(data, '\n')); err != nil {
+			return calque.WrapErr(ctx, err, "failed to write transcript entry")
+		}
+		return nil
+	})
+}
+
+type transcriptOption struct{ sink TranscriptSink }
+
+func (o transcriptOption) Apply(opts *AgentOptions) { opts.Transcript = o.sink }
+
+// WithTranscript records every step of the agent to sink.
+//
+// Input: TranscriptSink, e.g. TranscriptWriter(file)
+// Output: AgentOption for configuration
+// Behavior: Records model requests and responses (with usage), tool calls and
+// results, retries and failures as TranscriptEntry values
+//
+// The transcript covers the tool loop, including the synthesis call of the
+// result formatter, and every ctrl.Retry attempt around the agent. Entries
+// carry the run's request and trace IDs. Sink errors are ignored so a
+// failing transcript never fails the run.
+//
+// Example:
+//
+//	f, _ := os.Create("agent.jsonl")
+//	defer f.Close()
+//	agent := ai.Agent(client, ai.WithTools(search, calc), ai.WithTranscript(ai.TranscriptWriter(f)))
+func WithTranscript(sink TranscriptSink) AgentOption {
+	return transcriptOption{sink: sink}
+}
+
+// transcript numbers and stores the entries of one agent call
+type transcript struct {
+	ctx     context.Context
+	sink    TranscriptSink
+	attempt int
+
+	mu  sync.Mutex
+	seq int
+}
+
+// startTranscript begins the transcript of an agent call, nil without a sink
+func startTranscript(ctx context.Context, sink TranscriptSink) *transcript {
+	if sink == nil {
+		return nil
+	}
+	t := &transcript{ctx: ctx, sink: sink, attempt: ctrl.RetryAttempt(ctx)}
+	if t.attempt > 0 {
+		t.record(TranscriptEntry{Kind: TranscriptRetry})
+	}
+	return t
+}
+
+func (t *transcript) record(entry TranscriptEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seq++
+	entry.Seq = t.seq
+	entry.Attempt = t.attempt
+	entry.RequestID = calque.RequestID(t.ctx)
+	entry.TraceID = calque.TraceID(t.ctx)
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	_ = t.sink.Record(t.ctx, entry)
+}
+
+// finish records the agent's error, if any
+func (t *transcript) finish(err error) {
+	if err != nil {
+		t.record(TranscriptEntry{Kind: TranscriptError, Error: err.Error()})
+	}
+}
+
+// observeTools records the tool calls made with the returned context
+func (t *transcript) observeTools(ctx context.Context) context.Context {
+	return tools.WithCallObserver(ctx, func(_ context.Context, result tools.ToolResult, duration time.Duration) {
+		start := time.Now().Add(-duration)
+		t.record(TranscriptEntry{
+			Time:       start,
+			Kind:       TranscriptToolCall,
+			Tool:       result.ToolCall.Name,
+			ToolCallID: result.ToolCall.ID,
+			Content:    result.ToolCall.Arguments,
+		})
+		t.record(TranscriptEntry{
+			Kind:       TranscriptToolResult,
+			Tool:       result.ToolCall.Name,
+			ToolCallID: result.ToolCall.ID,
+			Content:    string(result.Result),
+			Duration:   duration,
+			Error:      result.Error,
+		})
+	})
+}
+
+// transcriptClient records the requests and responses of a client
+type transcriptClient struct {
+	client     Client
+	transcript *transcript
+}
+
+// Chat implements Client
+func (c *transcriptClient) Chat(r *calque.Request, w *calque.Response, opts *AgentOptions) error {
+	var prompt []byte
+	if err := calque.Read(r, &prompt); err != nil {
+		return err
+	}
+	c.transcript.record(TranscriptEntry{Kind: TranscriptModelRequest, Content: string(prompt)})
+
+	var usage *UsageMetadata
+	recorded := AgentOptions{}
+	if opts != nil {
+		recorded = *opts
+	}
+	next := recorded.UsageHandler
+	recorded.UsageHandler = func(u *UsageMetadata) {
+		if next != nil {
+			next(u)
+		}
+		reported := *u
+		usage = &reported
+	}
+
+	var output bytes.Buffer
+	start := time.Now()
+	err := c.client.Chat(calque.NewRequest(r.Context, bytes.NewReader(prompt)), calque.NewResponse(io.MultiWriter(w.Data, &output)), &recorded)
+	entry := TranscriptEntry{
+		Kind:     TranscriptModelResponse,
+		Content:  output.String(),
+		Duration: time.Since(start),
+		Usage:    usage,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	c.transcript.record(entry)
+	return err
+}
@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+// entries collects transcript entries
+type entries struct {
+	mu   sync.Mutex
+	list []TranscriptEntry
+}
+
+func (e *entries) Record(_ context.Context, entry TranscriptEntry) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.list = append(e.list, entry)
+	return nil
+}
+
+func (e *entries) kinds() []string {
+	var kinds []string
+	for _, entry := range e.list {
+		kinds = append(kinds, string(entry.Kind))
+	}
+	return kinds
+}
+
+func TestWithTranscriptToolLoop(t *testing.T) {
+	calc := tools.Simple("calculator", "Math Calculator", func(string) string { return "4" })
+	client := NewMockClientWithResponses([]string{
+		`{"tool_calls": [{"type": "function", "function": {"name": "calculator", "arguments": "2+2"}}]}`,
+		"The answer is 4",
+	}).WithStreamDelay(0)
+
+	rec := &entries{}
+	agent := Agent(client, WithTools(calc), WithTranscript(rec))
+	var out bytes.Buffer
+	if err := agent.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("what is 2+2?")), calque.NewResponse(&out)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "model_request model_response tool_call tool_result model_request model_response"
+	if got := strings.Join(rec.kinds(), " "); got != want {
+		t.Fatalf("kinds = %s, want %s", got, want)
+	}
+	if !strings.HasPrefix(rec.list[0].Content, "what is 2+2?") || rec.list[2].Tool != "calculator" || rec.list[2].Content != "2+2" || rec.list[3].Content != "4" {
+		t.Errorf("entries = %+v", rec.list)
+	}
+	for i, entry := range rec.list {
+		if entry.Seq != i+1 {
+			t.Errorf("entry %d has Seq %d", i, entry.Seq)
+		}
+	}
+}
+
+// flakyClient fails its first call
+type flakyClient struct{ calls int }
+
+func (c *flakyClient) Chat(r *calque.Request, w *calque.Response, _ *AgentOptions) error {
+	var in string
+	if err := calque.Read(r, &in); err != nil {
+		return err
+	}
+	if c.calls++; c.calls == 1 {
+		return errors.New("overloaded")
+	}
+	return calque.Write(w, "ok")
+}
+
+func TestWithTranscriptRetry(t *testing.T) {
+	var buf bytes.Buffer
+	sink := TranscriptWriter(&buf)
+	handler := ctrl.Retry(Agent(&flakyClient{}, WithTranscript(sink)), 2)
+	var out bytes.Buffer
+	if err := handler.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("hi")), calque.NewResponse(&out)); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry TranscriptEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		got = append(got, string(entry.Kind)+"/"+entry.Error)
+		if entry.Kind == TranscriptRetry && entry.Attempt != 1 {
+			t.Errorf("retry entry attempt = %d", entry.Attempt)
+		}
+	}
+	want := "model_request/ model_response/overloaded error/overloaded retry/ model_request/ model_response/"
+	if strings.Join(got, " ") != want {
+		t.Errorf("transcript = %v, want %s", got, want)
+	}
+}
@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"io"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/text"
+)
+
+// languageNames maps ISO 639-1 codes to names used in translation prompts
+var languageNames = map[string]string{
+	"ar": "Arabic", "de": "German", "el": "Greek", "en": "English", "es": "Spanish",
+	"fr": "French", "he": "Hebrew", "hi": "Hindi", "it": "Italian", "ja": "Japanese",
+	"ko": "Korean", "nl": "Dutch", "pl": "Polish", "pt": "Portuguese", "ru": "Russian",
+	"sv": "Swedish", "th": "Thai", "tr": "Turkish", "uk": "Ukrainian", "zh": "Chinese",
+}
+
+// Translate creates a handler that translates the input into targetLang.
+//
+// Input: text in any language
+// Output: the translated text only
+// Behavior: BUFFERED - reads entire input to build the translation prompt
+//
+// targetLang may be an ISO 639-1 code ("fr") or a language name ("French").
+// When text.DetectLanguage has already recorded the input as targetLang, the
+// input passes through without a model call.
+//
+// Example:
+//
+//	// Answer in the user's language
+//	flow.Use(text.DetectLanguage()).
+//		Use(ai.Translate(client, "en")).
+//		Use(ai.Agent(client))
+func Translate(client Client, targetLang string, opts ...AgentOption) calque.Handler {
+	target := strings.TrimSpace(targetLang)
+	name := target
+	if n, ok := languageNames[strings.ToLower(target)]; ok {
+		name = n
+	}
+
+	return calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
+		if detected := text.Language(r.Context); detected != "" && strings.EqualFold(detected, target) {
+			_, err := io.Copy(w.Data, r.Data)
+			return err
+		}
+
+		var input string
+		if err := calque.Read(r, &input); err != nil {
+			return err
+		}
+
+		prompt := "Translate the following text into " + name + ". " +
+			"Preserve formatting, names and code. Respond with the translation only.\n\n" + input
+
+		agentOpts := &AgentOptions{}
+		for _, opt := range opts {
+			opt.Apply(agentOpts)
+		}
+		chargeBudget(r.Context, agentOpts)
+		return client.Chat(calque.NewRequest(r.Context, strings.NewReader(prompt)), w, agentOpts)
+	})
+}
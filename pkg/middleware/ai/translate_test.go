@@ -0,0 +1,74 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/text"
+)
+
+// promptClient records the prompt it receives and replies with a fixed response
+type promptClient struct {
+	prompt   string
+	response string
+}
+
+func (c *promptClient) Chat(r *calque.Request, w *calque.Response, _ *AgentOptions) error {
+	if err := calque.Read(r, &c.prompt); err != nil {
+		return err
+	}
+	return calque.Write(w, c.response)
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		input      string
+		wantPrompt string
+	}{
+		{name: "code target", target: "fr", input: "Hello", wantPrompt: "into French"},
+		{name: "name target", target: "Klingon", input: "Hello", wantPrompt: "into Klingon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &promptClient{response: "translated"}
+
+			var buf bytes.Buffer
+			err := Translate(client, tt.target).ServeFlow(calque.NewRequest(context.Background(), strings.NewReader(tt.input)), calque.NewResponse(&buf))
+			if err != nil {
+				t.Fatalf("Translate() error = %v", err)
+			}
+			if buf.String() != "translated" {
+				t.Errorf("output = %q, want %q", buf.String(), "translated")
+			}
+			if !strings.Contains(client.prompt, tt.wantPrompt) || !strings.HasSuffix(client.prompt, tt.input) {
+				t.Errorf("prompt = %q, want it to contain %q and end with input", client.prompt, tt.wantPrompt)
+			}
+		})
+	}
+}
+
+func TestTranslateSkipsMatchingLanguage(t *testing.T) {
+	mb := calque.NewMetadataBus(0)
+	defer mb.Close()
+	ctx := calque.WithMetadataBus(context.Background(), mb)
+	mb.Set(text.MetadataLanguage, "en")
+
+	client := &promptClient{response: "translated"}
+	var buf bytes.Buffer
+	err := Translate(client, "EN").ServeFlow(calque.NewRequest(ctx, strings.NewReader("Already English")), calque.NewResponse(&buf))
+	if err != nil {
+		t.Fatalf("Translate() error = %v", err)
+	}
+	if buf.String() != "Already English" {
+		t.Errorf("output = %q, want input unchanged", buf.String())
+	}
+	if client.prompt != "" {
+		t.Errorf("client was called with %q, want no call", client.prompt)
+	}
+}
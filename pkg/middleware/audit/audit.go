@@ -0,0 +1,312 @@
+// Package audit provides tamper-evident audit logging for calque flows.
+//
+// Each run of an audited handler produces an Entry recording who ran it, when,
+// hashes of the input and output, the handler chain, and model/prompt versions
+// tagged on the MetadataBus. Entries are hash-chained: every entry includes the
+// hash of its predecessor, so modifying or deleting a past entry breaks Verify.
+//
+// Example:
+//
+//	store, _ := audit.NewFileStore("audit.jsonl")
+//	flow := calque.NewFlow().
+//		Use(prompt.Template("Summarize: {{.Input}}")).
+//		Use(ai.Agent(client))
+//
+//	audited := audit.Log(store, flow)
+//	ctx := audit.WithActor(context.Background(), "user:42")
+//	err := calque.NewFlow().Use(audited).Run(ctx, input, &output)
+//
+//	// Later, prove the log hasn't been altered
+//	entries, _ := store.List(ctx)
+//	err = audit.Verify(entries)
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// ModelMetadataPrefix is the MetadataBus key prefix for model versions recorded with RecordModel.
+const ModelMetadataPrefix = "model."
+
+// DefaultMetadataPrefixes are the MetadataBus key prefixes captured in each entry:
+// model versions, prompt registry versions and experiment variants.
+var DefaultMetadataPrefixes = []string{ModelMetadataPrefix, "prompt.", "experiment."}
+
+// ErrChainBroken is returned by Verify when an entry's hash or link doesn't match.
+var ErrChainBroken = errors.New("audit chain broken")
+
+// Entry is a single audit record.
+type Entry struct {
+	Seq        uint64            `json:"seq"`
+	Time       time.Time         `json:"time"`
+	Actor      string            `json:"actor,omitempty"`
+	Action     string            `json:"action,omitempty"`
+	TraceID    string            `json:"trace_id,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+	Handlers   []string          `json:"handlers"`
+	InputHash  string            `json:"input_hash"`
+	OutputHash string            `json:"output_hash"`
+	Versions   map[string]string `json:"versions,omitempty"`
+	Duration   time.Duration     `json:"duration_ns"`
+	Error      string            `json:"error,omitempty"`
+	PrevHash   string            `json:"prev_hash"`
+	Hash       string            `json:"hash"`
+}
+
+// ComputeHash returns the SHA-256 hash of the entry contents and PrevHash, excluding Hash.
+func (e Entry) ComputeHash() string {
+	e.Hash = ""
+	data, _ := json.Marshal(e) // Entry contains only JSON-safe types
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Config holds configuration for the audit middleware
+type Config struct {
+	// Action names the audited operation (e.g. "summarize"). Optional.
+	Action string
+	// Actor resolves who made the request. Defaults to ActorFromContext.
+	Actor func(ctx context.Context) string
+	// MetadataPrefixes selects MetadataBus string values recorded as versions
+	// (defaults to DefaultMetadataPrefixes)
+	MetadataPrefixes []string
+	// FailOpen lets requests succeed when the store can't be written.
+	// By default a failed audit write fails the request.
+	FailOpen bool
+	// OnError is called when an audit write fails (optional)
+	OnError func(error)
+}
+
+// Logger appends hash-chained entries to a Store.
+type Logger struct {
+	store   Store
+	handler calque.Handler
+	config  Config
+	chain   []string
+}
+
+// Log wraps a handler so every run is recorded in a tamper-evident audit log.
+//
+// Input: any data type (streaming - hashed as it passes through)
+// Output: same as wrapped handler
+// Behavior: STREAMING - input and output are hashed without buffering
+//
+// Entries record the actor (see WithActor), trace and request IDs, SHA-256
+// hashes of input and output, the handler chain (the flow's handlers when
+// wrapping a *calque.Flow), model and prompt versions from the MetadataBus,
+// duration and error. Payloads themselves are never stored.
+//
+// Example:
+//
+//	store := audit.NewInMemoryStore()
+//	flow.Use(audit.Log(store, ai.Agent(client)))
+func Log(store Store, handler calque.Handler) *Logger {
+	return LogWithConfig(store, handler, &Config{})
+}
+
+// LogWithConfig creates an audit logging middleware with custom configuration.
+//
+// Input: any data type (streaming - hashed as it passes through)
+// Output: same as wrapped handler
+// Behavior: STREAMING - input and output are hashed without buffering
+//
+// Example:
+//
+//	audited := audit.LogWithConfig(store, flow, &audit.Config{
+//		Action: "loan-decision",
+//		Actor: func(ctx context.Context) string {
+//			if claims := auth.ClaimsFromContext(ctx); claims != nil {
+//				return claims.Subject
+//			}
+//			return ""
+//		},
+//	})
+func LogWithConfig(store Store, handler calque.Handler, config *Config) *Logger {
+	cfg := Config{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Actor == nil {
+		cfg.Actor = ActorFromContext
+	}
+	if cfg.MetadataPrefixes == nil {
+		cfg.MetadataPrefixes = DefaultMetadataPrefixes
+	}
+
+	return &Logger{
+		store:   store,
+		handler: handler,
+		config:  cfg,
+		chain:   handlerChain(handler),
+	}
+}
+
+// ServeFlow runs the wrapped handler and appends an audit entry.
+func (l *Logger) ServeFlow(req *calque.Request, res *calque.Response) error {
+	ctx := req.Context
+
+	// Ensure a bus exists so versions tagged by inner handlers are visible here
+	mb := calque.GetMetadataBus(ctx)
+	if mb == nil {
+		mb = calque.NewMetadataBus(0)
+		defer mb.Close()
+		ctx = calque.WithMetadataBus(ctx, mb)
+	}
+
+	inHash := sha256.New()
+	outHash := sha256.New()
+	innerReq := &calque.Request{Context: ctx, Data: io.TeeReader(req.Data, inHash)}
+	innerRes := &calque.Response{Data: io.MultiWriter(res.Data, outHash)}
+
+	start := time.Now()
+	err := l.handler.ServeFlow(innerReq, innerRes)
+	duration := time.Since(start)
+
+	// Drain unread input so the hash covers the full request
+	_, _ = io.Copy(io.Discard, innerReq.Data)
+
+	entry := Entry{
+		Time:       start.UTC(),
+		Actor:      l.config.Actor(ctx),
+		Action:     l.config.Action,
+		TraceID:    calque.TraceID(ctx),
+		RequestID:  calque.RequestID(ctx),
+		Handlers:   l.chain,
+		InputHash:  hex.EncodeToString(inHash.Sum(nil)),
+		OutputHash: hex.EncodeToString(outHash.Sum(nil)),
+		Versions:   l.versions(mb),
+		Duration:   duration,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	if appendErr := l.append(ctx, entry); appendErr != nil {
+		if l.config.OnError != nil {
+			l.config.OnError(appendErr)
+		}
+		if !l.config.FailOpen && err == nil {
+			return calque.WrapErr(ctx, appendErr, "audit log write failed")
+		}
+	}
+	return err
+}
+
+// append links the entry to the last stored entry and writes it
+func (l *Logger) append(ctx context.Context, entry Entry) error {
+	return l.store.AppendNext(ctx, func(last *Entry) Entry {
+		if last != nil {
+			entry.Seq = last.Seq + 1
+			entry.PrevHash = last.Hash
+		}
+		entry.Hash = entry.ComputeHash()
+		return entry
+	})
+}
+
+// versions collects string MetadataBus values under the configured prefixes
+func (l *Logger) versions(mb *calque.MetadataBus) map[string]string {
+	versions := make(map[string]string)
+	mb.Range(func(key string, value any) bool {
+		str, ok := value.(string)
+		if !ok {
+			return true
+		}
+		for _, prefix := range l.config.MetadataPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				versions[key] = str
+				break
+			}
+		}
+		return true
+	})
+	if len(versions) == 0 {
+		return nil
+	}
+	return versions
+}
+
+// handlerChain names the handlers an entry should list
+func handlerChain(h calque.Handler) []string {
+	if flow, ok := h.(*calque.Flow); ok {
+		handlers := flow.Handlers()
+		names := make([]string, len(handlers))
+		for i, inner := range handlers {
+			names[i] = calque.HandlerName(inner)
+		}
+		return names
+	}
+	return []string{calque.HandlerName(h)}
+}
+
+// Verify checks that entries form an unbroken hash chain in sequence order.
+//
+// Returns an error wrapping ErrChainBroken identifying the first entry whose
+// hash doesn't match its contents or whose PrevHash doesn't match its predecessor.
+//
+// Example:
+//
+//	entries, _ := store.List(ctx)
+//	if err := audit.Verify(entries); errors.Is(err, audit.ErrChainBroken) {
+//		alert("audit log tampered", err)
+//	}
+func Verify(entries []Entry) error {
+	sorted := append([]Entry(nil), entries...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Seq < sorted[j].Seq })
+
+	ctx := context.Background()
+	prev := ""
+	for i, e := range sorted {
+		if e.Seq != sorted[0].Seq+uint64(i) {
+			return calque.WrapErr(ctx, ErrChainBroken, fmt.Sprintf("missing entry before seq %d", e.Seq))
+		}
+		if i > 0 && e.PrevHash != prev {
+			return calque.WrapErr(ctx, ErrChainBroken, fmt.Sprintf("seq %d does not link to seq %d", e.Seq, sorted[i-1].Seq))
+		}
+		if e.ComputeHash() != e.Hash {
+			return calque.WrapErr(ctx, ErrChainBroken, fmt.Sprintf("seq %d hash mismatch", e.Seq))
+		}
+		prev = e.Hash
+	}
+	return nil
+}
+
+type actorKey struct{}
+
+// WithActor stores who is making the request, for recording in audit entries.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set with WithActor, or empty if unset.
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok {
+		return actor
+	}
+	return ""
+}
+
+// RecordModel tags the model version used by the current run on the MetadataBus.
+//
+// Call from a handler (or an agent option callback) so the audit entry records
+// which model produced the output.
+//
+// Example:
+//
+//	audit.RecordModel(req.Context, "chat", "gpt-4o-2024-08-06")
+func RecordModel(ctx context.Context, name, version string) {
+	if mb := calque.GetMetadataBus(ctx); mb != nil {
+		mb.Set(ModelMetadataPrefix+name, version)
+	}
+}
@@ -0,0 +1,188 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func upper(req *calque.Request, res *calque.Response) error {
+	var input string
+	if err := calque.Read(req, &input); err != nil {
+		return err
+	}
+	RecordModel(req.Context, "chat", "test-model-v2")
+	return calque.Write(res, strings.ToUpper(input))
+}
+
+func sha(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestLog(t *testing.T) {
+	store := NewInMemoryStore()
+	inner := calque.NewFlow().UseFunc(upper)
+	flow := calque.NewFlow().Use(LogWithConfig(store, inner, &Config{Action: "shout"}))
+
+	ctx := WithActor(calque.WithTraceID(context.Background(), "trace-1"), "user:42")
+	for _, in := range []string{"hello", "world"} {
+		var out string
+		if err := flow.Run(ctx, in, &out); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if out != strings.ToUpper(in) {
+			t.Errorf("expected %q, got %q", strings.ToUpper(in), out)
+		}
+	}
+
+	entries, _ := store.List(ctx)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	first := entries[0]
+	if first.Actor != "user:42" || first.Action != "shout" || first.TraceID != "trace-1" {
+		t.Errorf("unexpected entry metadata: %+v", first)
+	}
+	if first.InputHash != sha("hello") || first.OutputHash != sha("HELLO") {
+		t.Errorf("unexpected hashes: %s / %s", first.InputHash, first.OutputHash)
+	}
+	if len(first.Handlers) != 1 || first.Handlers[0] != "audit.upper" {
+		t.Errorf("expected handler chain [audit.upper], got %v", first.Handlers)
+	}
+	if first.Versions["model.chat"] != "test-model-v2" {
+		t.Errorf("expected model version recorded, got %v", first.Versions)
+	}
+	if entries[1].Seq != 1 || entries[1].PrevHash != first.Hash {
+		t.Errorf("expected second entry chained to first, got %+v", entries[1])
+	}
+
+	if err := Verify(entries); err != nil {
+		t.Errorf("expected valid chain, got %v", err)
+	}
+}
+
+func TestLog_HandlerError(t *testing.T) {
+	store := NewInMemoryStore()
+	failing := calque.HandlerFunc(func(_ *calque.Request, _ *calque.Response) error {
+		return errors.New("boom")
+	})
+
+	var out string
+	err := calque.NewFlow().Use(Log(store, failing)).Run(context.Background(), "x", &out)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	entries, _ := store.List(context.Background())
+	if len(entries) != 1 || entries[0].Error != "boom" {
+		t.Fatalf("expected entry recording error, got %+v", entries)
+	}
+	if entries[0].InputHash != sha("x") {
+		t.Error("expected input hash to cover unread input")
+	}
+}
+
+func TestLog_StoreFailure(t *testing.T) {
+	tests := []struct {
+		name      string
+		failOpen  bool
+		expectErr bool
+	}{
+		{"fail closed", false, true},
+		{"fail open", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reported error
+			handler := LogWithConfig(failingStore{}, calque.HandlerFunc(upper), &Config{
+				FailOpen: tt.failOpen,
+				OnError:  func(err error) { reported = err },
+			})
+
+			var out string
+			err := calque.NewFlow().Use(handler).Run(context.Background(), "x", &out)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("expected error=%v, got %v", tt.expectErr, err)
+			}
+			if reported == nil {
+				t.Error("expected OnError to be called")
+			}
+		})
+	}
+}
+
+func TestVerify_Tampering(t *testing.T) {
+	store := NewInMemoryStore()
+	flow := calque.NewFlow().Use(Log(store, calque.HandlerFunc(upper)))
+	for _, in := range []string{"a", "b", "c"} {
+		var out string
+		if err := flow.Run(context.Background(), in, &out); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+	}
+	entries, _ := store.List(context.Background())
+
+	tests := []struct {
+		name   string
+		mutate func([]Entry) []Entry
+	}{
+		{"modified field", func(e []Entry) []Entry { e[1].Actor = "attacker"; return e }},
+		{"deleted entry", func(e []Entry) []Entry { return append(e[:1], e[2:]...) }},
+		{"rehashed entry", func(e []Entry) []Entry {
+			e[1].OutputHash = sha("forged")
+			e[1].Hash = e[1].ComputeHash()
+			return e
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := tt.mutate(append([]Entry(nil), entries...))
+			if err := Verify(tampered); !errors.Is(err, ErrChainBroken) {
+				t.Errorf("expected ErrChainBroken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLog_SharedStore(t *testing.T) {
+	store := NewInMemoryStore()
+	loggers := []*Logger{Log(store, calque.HandlerFunc(upper)), Log(store, calque.HandlerFunc(upper))}
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Go(func() {
+			var out string
+			if err := calque.NewFlow().Use(loggers[i%2]).Run(context.Background(), "x", &out); err != nil {
+				t.Errorf("run failed: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	entries, _ := store.List(context.Background())
+	if len(entries) != 20 {
+		t.Fatalf("expected 20 entries, got %d", len(entries))
+	}
+	if err := Verify(entries); err != nil {
+		t.Errorf("loggers sharing a store forked the chain: %v", err)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Append(_ context.Context, _ Entry) error { return errors.New("disk full") }
+func (failingStore) AppendNext(_ context.Context, _ func(*Entry) Entry) error {
+	return errors.New("disk full")
+}
+func (failingStore) Last(_ context.Context) (*Entry, error)  { return nil, nil }
+func (failingStore) List(_ context.Context) ([]Entry, error) { return nil, nil }
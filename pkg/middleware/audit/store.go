@@ -0,0 +1,206 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Store persists audit entries in append order.
+//
+// Implementations should be append-only: entries are never modified or
+// deleted once written.
+type Store interface {
+	// Append writes an entry after all existing entries
+	Append(ctx context.Context, entry Entry) error
+
+	// AppendNext builds an entry from the most recently appended one (nil if
+	// the store is empty) and writes it, with no other append in between, so
+	// loggers sharing the store keep one linear chain
+	AppendNext(ctx context.Context, next func(last *Entry) Entry) error
+
+	// Last returns the most recently appended entry, or nil if the store is empty
+	Last(ctx context.Context) (*Entry, error)
+
+	// List returns all entries in append order
+	List(ctx context.Context) ([]Entry, error)
+}
+
+// InMemoryStore keeps audit entries in memory (for tests and development)
+type InMemoryStore struct {
+	mu      sync.RWMutex
+	entries []Entry
+}
+
+// NewInMemoryStore creates an empty in-memory audit store
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{}
+}
+
+// Append adds an entry
+func (s *InMemoryStore) Append(_ context.Context, entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+// AppendNext adds the entry built from the most recent one
+func (s *InMemoryStore) AppendNext(_ context.Context, next func(last *Entry) Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var last *Entry
+	if len(s.entries) > 0 {
+		prev := s.entries[len(s.entries)-1]
+		last = &prev
+	}
+	s.entries = append(s.entries, next(last))
+	return nil
+}
+
+// Last returns the most recent entry
+func (s *InMemoryStore) Last(_ context.Context) (*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.entries) == 0 {
+		return nil, nil
+	}
+	last := s.entries[len(s.entries)-1]
+	return &last, nil
+}
+
+// List returns a copy of all entries
+func (s *InMemoryStore) List(_ context.Context) ([]Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Entry(nil), s.entries...), nil
+}
+
+// FileStore appends audit entries to a JSON Lines file.
+//
+// Each entry is written as one line and synced to disk before Append returns.
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	last *Entry
+}
+
+// NewFileStore opens (or creates) a JSON Lines audit file for appending.
+//
+// Example:
+//
+//	store, err := audit.NewFileStore("/var/log/calque/audit.jsonl")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer store.Close()
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path}
+
+	entries, err := s.readAll(context.Background())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(entries) > 0 {
+		s.last = &entries[len(entries)-1]
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, calque.WrapErr(context.Background(), err, "failed to open audit file")
+	}
+	s.file = file
+	return s, nil
+}
+
+// Append writes an entry as a JSON line
+func (s *FileStore) Append(ctx context.Context, entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(ctx, entry)
+}
+
+// AppendNext writes the entry built from the most recently written one
+func (s *FileStore) AppendNext(ctx context.Context, next func(last *Entry) Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var last *Entry
+	if s.last != nil {
+		prev := *s.last
+		last = &prev
+	}
+	return s.write(ctx, next(last))
+}
+
+// write appends entry to the file; the caller holds s.mu
+func (s *FileStore) write(ctx context.Context, entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to encode audit entry")
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return calque.WrapErr(ctx, err, "failed to write audit entry")
+	}
+	if err := s.file.Sync(); err != nil {
+		return calque.WrapErr(ctx, err, "failed to sync audit file")
+	}
+	s.last = &entry
+	return nil
+}
+
+// Last returns the most recently written entry
+func (s *FileStore) Last(_ context.Context) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last == nil {
+		return nil, nil
+	}
+	last := *s.last
+	return &last, nil
+}
+
+// List reads all entries from the file
+func (s *FileStore) List(ctx context.Context) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readAll(ctx)
+}
+
+// Close closes the underlying file
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// readAll decodes every line in the file
+func (s *FileStore) readAll(ctx context.Context) ([]Entry, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to decode audit entry")
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to read audit file")
+	}
+	return entries, nil
+}
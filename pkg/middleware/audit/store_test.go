@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestInMemoryStore(t *testing.T) {
+	store := NewInMemoryStore()
+	ctx := context.Background()
+
+	if last, err := store.Last(ctx); err != nil || last != nil {
+		t.Fatalf("expected empty store, got %v / %v", last, err)
+	}
+	for i := range 3 {
+		if err := store.Append(ctx, Entry{Seq: uint64(i)}); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+	}
+	last, _ := store.Last(ctx)
+	if last == nil || last.Seq != 2 {
+		t.Errorf("expected last seq 2, got %+v", last)
+	}
+	entries, _ := store.List(ctx)
+	if len(entries) != 3 {
+		t.Errorf("expected 3 entries, got %d", len(entries))
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	flow := calque.NewFlow().Use(Log(store, calque.HandlerFunc(upper)))
+	var out string
+	if err := flow.Run(context.Background(), "one", &out); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	// Reopen and continue the chain
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer store.Close()
+
+	flow = calque.NewFlow().Use(Log(store, calque.HandlerFunc(upper)))
+	if err := flow.Run(context.Background(), "two", &out); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	entries, err := store.List(context.Background())
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(entries) != 2 || entries[1].Seq != 1 {
+		t.Fatalf("expected 2 chained entries, got %+v", entries)
+	}
+	if err := Verify(entries); err != nil {
+		t.Errorf("expected valid chain after reopen, got %v", err)
+	}
+}
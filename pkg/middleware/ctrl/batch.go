@@ -94,15 +94,15 @@ func BatchWithConfig(handler calque.Handler, config *BatchConfig) calque.Handler
 
 	go batcher.processBatches()
 
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
-		var input []byte
-		err := calque.Read(req, &input)
+	return calque.Composite("ctrl.BatchWithConfig", calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		input, err := bufferInput(req)
 		if err != nil {
 			return err
 		}
+		defer input.Release()
 
 		batchReq := &batchRequest{
-			input:    input,
+			input:    input.Bytes(),
 			response: make(chan batchResponse, 1),
 			ctx:      req.Context,
 		}
@@ -122,7 +122,7 @@ func BatchWithConfig(handler calque.Handler, config *BatchConfig) calque.Handler
 		case <-req.Context.Done():
 			return req.Context.Err()
 		}
-	})
+	}), wrapGroup(handler))
 }
 
 // processBatches runs in background to collect and process batches
@@ -0,0 +1,232 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Budget resources reported by BudgetExceededError.
+const (
+	BudgetTokens    = "tokens"
+	BudgetCost      = "cost_usd"
+	BudgetToolCalls = "tool_calls"
+	BudgetDuration  = "duration"
+)
+
+// ErrBudgetExceeded matches any BudgetExceededError with errors.Is.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetLimits caps what a single run may consume. Zero fields are unlimited.
+type BudgetLimits struct {
+	MaxTokens    int           // total prompt + completion tokens
+	MaxCostUSD   float64       // total model cost reported via ChargeCost
+	MaxToolCalls int           // tool executions
+	MaxDuration  time.Duration // wall-clock time for the run
+}
+
+// BudgetUsage is the consumption recorded for a run.
+type BudgetUsage struct {
+	Tokens    int
+	CostUSD   float64
+	ToolCalls int
+	Elapsed   time.Duration
+}
+
+// BudgetExceededError is returned when a run exceeds one of its limits.
+//
+// Example:
+//
+//	var budgetErr *ctrl.BudgetExceededError
+//	if errors.As(err, &budgetErr) {
+//		log.Printf("aborted: %s used %v of %v", budgetErr.Resource, budgetErr.Used, budgetErr.Limit)
+//	}
+type BudgetExceededError struct {
+	Resource string  // one of BudgetTokens, BudgetCost, BudgetToolCalls, BudgetDuration
+	Limit    float64 // configured limit (seconds for BudgetDuration)
+	Used     float64 // consumption when the limit was crossed
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("budget exceeded: %s used %v of %v", e.Resource, e.Used, e.Limit)
+}
+
+// Is reports whether target is ErrBudgetExceeded.
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+type budgetContextKey struct{}
+
+// budgetTracker records consumption for one Budget run
+type budgetTracker struct {
+	limits BudgetLimits
+	start  time.Time
+	parent *budgetTracker
+	cancel context.CancelCauseFunc
+
+	mu       sync.Mutex
+	usage    BudgetUsage
+	exceeded *BudgetExceededError
+}
+
+// Budget enforces resource limits on a handler and everything nested inside it.
+//
+// Input: any data type (streaming - passed through to handler)
+// Output: same as wrapped handler's output
+// Behavior: STREAMING - cancels the run as soon as a limit is crossed
+//
+// Consumption is charged against the budget in the request context: ai.Agent
+// charges tokens from provider usage reports, tools.Execute charges one tool
+// call per execution, and custom handlers can call ChargeTokens, ChargeCost and
+// ChargeToolCall. Nested budgets are charged together, so an inner budget can
+// only tighten an outer one. When any limit is exceeded the handler's context
+// is cancelled and, once the handler returns, a *BudgetExceededError is returned.
+//
+// Example:
+//
+//	agent := ctrl.Budget(ai.Agent(client, ai.WithTools(searchTool)), ctrl.BudgetLimits{
+//		MaxTokens:    20000,
+//		MaxToolCalls: 10,
+//		MaxDuration:  2 * time.Minute,
+//	})
+//	flow.Use(agent)
+func Budget(handler calque.Handler, limits BudgetLimits) calque.Handler {
+	return calque.Composite("ctrl.Budget", calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		ctx, cancel := context.WithCancelCause(req.Context)
+		defer cancel(nil)
+
+		tracker := &budgetTracker{
+			limits: limits,
+			start:  time.Now(),
+			parent: budgetFromContext(req.Context),
+			cancel: cancel,
+		}
+		if limits.MaxDuration > 0 {
+			timer := time.AfterFunc(limits.MaxDuration, func() {
+				tracker.exceed(&BudgetExceededError{
+					Resource: BudgetDuration,
+					Limit:    limits.MaxDuration.Seconds(),
+					Used:     time.Since(tracker.start).Seconds(),
+				})
+			})
+			defer timer.Stop()
+		}
+
+		// Served inline so a cancelled handler never writes to res after Budget returns
+		budgetReq := req.WithContext(context.WithValue(ctx, budgetContextKey{}, tracker))
+		err := handler.ServeFlow(budgetReq, res)
+
+		if exceeded := tracker.exceededErr(); exceeded != nil {
+			return calque.WrapErr(budgetReq.Context, exceeded, "run aborted")
+		}
+		if err == nil && ctx.Err() != nil {
+			// parent context was cancelled before the handler returned
+			err = context.Cause(ctx)
+		}
+		return err
+	}), wrapGroup(handler))
+}
+
+// ChargeTokens records token consumption against the run's budgets.
+//
+// Returns a *BudgetExceededError if a limit is crossed, or nil when within
+// budget or when no budget is in the context.
+func ChargeTokens(ctx context.Context, tokens int) error {
+	return charge(ctx, func(u *BudgetUsage) { u.Tokens += tokens })
+}
+
+// ChargeCost records model cost in USD against the run's budgets.
+//
+// Example:
+//
+//	ai.WithUsageHandler(func(u *ai.UsageMetadata) {
+//		ctrl.ChargeCost(req.Context, float64(u.TotalTokens)*pricePerToken)
+//	})
+func ChargeCost(ctx context.Context, usd float64) error {
+	return charge(ctx, func(u *BudgetUsage) { u.CostUSD += usd })
+}
+
+// ChargeToolCall records one tool execution against the run's budgets.
+func ChargeToolCall(ctx context.Context) error {
+	return charge(ctx, func(u *BudgetUsage) { u.ToolCalls++ })
+}
+
+// BudgetUsed returns the consumption recorded by the innermost budget in ctx.
+// Returns false if no budget is in the context.
+func BudgetUsed(ctx context.Context) (BudgetUsage, bool) {
+	tracker := budgetFromContext(ctx)
+	if tracker == nil {
+		return BudgetUsage{}, false
+	}
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	usage := tracker.usage
+	usage.Elapsed = time.Since(tracker.start)
+	return usage, true
+}
+
+// charge applies fn to every budget in ctx, returning the first exceeded limit
+func charge(ctx context.Context, fn func(*BudgetUsage)) error {
+	var first error
+	for tracker := budgetFromContext(ctx); tracker != nil; tracker = tracker.parent {
+		if err := tracker.charge(fn); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (t *budgetTracker) charge(fn func(*BudgetUsage)) error {
+	t.mu.Lock()
+	fn(&t.usage)
+	exceeded := t.check()
+	t.mu.Unlock()
+
+	if exceeded != nil {
+		t.exceed(exceeded)
+		return exceeded
+	}
+	return nil
+}
+
+// check compares usage against limits. Caller must hold mu.
+func (t *budgetTracker) check() *BudgetExceededError {
+	switch {
+	case t.limits.MaxTokens > 0 && t.usage.Tokens > t.limits.MaxTokens:
+		return &BudgetExceededError{Resource: BudgetTokens, Limit: float64(t.limits.MaxTokens), Used: float64(t.usage.Tokens)}
+	case t.limits.MaxCostUSD > 0 && t.usage.CostUSD > t.limits.MaxCostUSD:
+		return &BudgetExceededError{Resource: BudgetCost, Limit: t.limits.MaxCostUSD, Used: t.usage.CostUSD}
+	case t.limits.MaxToolCalls > 0 && t.usage.ToolCalls > t.limits.MaxToolCalls:
+		return &BudgetExceededError{Resource: BudgetToolCalls, Limit: float64(t.limits.MaxToolCalls), Used: float64(t.usage.ToolCalls)}
+	}
+	return nil
+}
+
+// exceed records the first exceeded limit and cancels the run
+func (t *budgetTracker) exceed(err *BudgetExceededError) {
+	t.mu.Lock()
+	if t.exceeded == nil {
+		t.exceeded = err
+	}
+	t.mu.Unlock()
+	t.cancel(err)
+}
+
+func (t *budgetTracker) exceededErr() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.exceeded == nil {
+		return nil
+	}
+	return t.exceeded
+}
+
+func budgetFromContext(ctx context.Context) *budgetTracker {
+	tracker, _ := ctx.Value(budgetContextKey{}).(*budgetTracker)
+	return tracker
+}
@@ -0,0 +1,190 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestBudget(t *testing.T) {
+	tests := []struct {
+		name         string
+		limits       BudgetLimits
+		charge       func(ctx context.Context)
+		wantResource string
+	}{
+		{
+			name:   "within limits",
+			limits: BudgetLimits{MaxTokens: 100, MaxCostUSD: 1, MaxToolCalls: 2},
+			charge: func(ctx context.Context) {
+				_ = ChargeTokens(ctx, 100)
+				_ = ChargeCost(ctx, 0.5)
+				_ = ChargeToolCall(ctx)
+			},
+		},
+		{
+			name:         "tokens exceeded",
+			limits:       BudgetLimits{MaxTokens: 100},
+			charge:       func(ctx context.Context) { _ = ChargeTokens(ctx, 60); _ = ChargeTokens(ctx, 60) },
+			wantResource: BudgetTokens,
+		},
+		{
+			name:         "cost exceeded",
+			limits:       BudgetLimits{MaxCostUSD: 0.01},
+			charge:       func(ctx context.Context) { _ = ChargeCost(ctx, 0.02) },
+			wantResource: BudgetCost,
+		},
+		{
+			name:   "tool calls exceeded",
+			limits: BudgetLimits{MaxToolCalls: 1},
+			charge: func(ctx context.Context) {
+				_ = ChargeToolCall(ctx)
+				_ = ChargeToolCall(ctx)
+			},
+			wantResource: BudgetToolCalls,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+				tt.charge(req.Context)
+				return calque.Write(res, "done")
+			})
+
+			var out strings.Builder
+			err := Budget(handler, tt.limits).ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("in")), calque.NewResponse(&out))
+
+			if tt.wantResource == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if out.String() != "done" {
+					t.Errorf("output = %q, want %q", out.String(), "done")
+				}
+				return
+			}
+
+			var budgetErr *BudgetExceededError
+			if !errors.As(err, &budgetErr) {
+				t.Fatalf("error = %v, want BudgetExceededError", err)
+			}
+			if budgetErr.Resource != tt.wantResource {
+				t.Errorf("resource = %q, want %q", budgetErr.Resource, tt.wantResource)
+			}
+			if !errors.Is(err, ErrBudgetExceeded) {
+				t.Error("errors.Is(err, ErrBudgetExceeded) = false")
+			}
+		})
+	}
+}
+
+func TestBudgetDurationAbortsRun(t *testing.T) {
+	handler := calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+		<-req.Context.Done()
+		return req.Context.Err()
+	})
+
+	start := time.Now()
+	err := Budget(handler, BudgetLimits{MaxDuration: 20 * time.Millisecond}).
+		ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("")), calque.NewResponse(&strings.Builder{}))
+
+	var budgetErr *BudgetExceededError
+	if !errors.As(err, &budgetErr) || budgetErr.Resource != BudgetDuration {
+		t.Fatalf("error = %v, want duration BudgetExceededError", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("run took %v, want abort near 20ms", elapsed)
+	}
+}
+
+func TestBudgetCancelsOnExceed(t *testing.T) {
+	cancelled := make(chan struct{})
+	handler := calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+		if err := ChargeTokens(req.Context, 50); err == nil {
+			t.Error("ChargeTokens() error = nil, want exceeded")
+		}
+		<-req.Context.Done()
+		close(cancelled)
+		return req.Context.Err()
+	})
+
+	err := Budget(handler, BudgetLimits{MaxTokens: 10}).
+		ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("")), calque.NewResponse(&strings.Builder{}))
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("error = %v, want ErrBudgetExceeded", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("handler context was not cancelled")
+	}
+}
+
+func TestBudgetWaitsForHandler(t *testing.T) {
+	var out strings.Builder
+	handler := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		<-req.Context.Done()
+		time.Sleep(10 * time.Millisecond) // still winding down after cancellation
+		return calque.Write(res, "partial")
+	})
+
+	req := calque.NewRequest(context.Background(), strings.NewReader(""))
+	callerCtx := req.Context
+	err := Budget(handler, BudgetLimits{MaxDuration: 10 * time.Millisecond}).ServeFlow(req, calque.NewResponse(&out))
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("error = %v, want ErrBudgetExceeded", err)
+	}
+	if out.String() != "partial" {
+		t.Errorf("output = %q, want the handler to finish before Budget returns", out.String())
+	}
+	if req.Context != callerCtx {
+		t.Error("Budget replaced the caller's request context")
+	}
+}
+
+func TestNestedBudgets(t *testing.T) {
+	var inner, outer BudgetUsage
+	handler := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		_ = ChargeTokens(req.Context, 30)
+		inner, _ = BudgetUsed(req.Context)
+		return calque.Write(res, "ok")
+	})
+
+	wrapped := Chain(
+		calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+			_ = ChargeTokens(req.Context, 10)
+			return calque.Write(res, "ok")
+		}),
+		Budget(handler, BudgetLimits{MaxTokens: 100}),
+	)
+	outerHandler := Budget(calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		err := wrapped.ServeFlow(req, res)
+		outer, _ = BudgetUsed(req.Context)
+		return err
+	}), BudgetLimits{MaxTokens: 100})
+
+	err := outerHandler.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("")), calque.NewResponse(&strings.Builder{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if inner.Tokens != 30 {
+		t.Errorf("inner tokens = %d, want 30", inner.Tokens)
+	}
+	if outer.Tokens != 40 {
+		t.Errorf("outer tokens = %d, want 40", outer.Tokens)
+	}
+}
+
+func TestChargeWithoutBudget(t *testing.T) {
+	if err := ChargeTokens(context.Background(), 1_000_000); err != nil {
+		t.Errorf("ChargeTokens() without budget error = %v", err)
+	}
+	if _, ok := BudgetUsed(context.Background()); ok {
+		t.Error("BudgetUsed() without budget ok = true")
+	}
+}
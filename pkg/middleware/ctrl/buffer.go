@@ -0,0 +1,17 @@
+package ctrl
+
+import (
+	"io"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// bufferInput reads the whole request into a buffer charged to the run's memory account
+func bufferInput(req *calque.Request) (*calque.MemoryBuffer, error) {
+	buf := calque.NewMemoryBuffer(req.Context)
+	if _, err := io.Copy(buf, req.Data); err != nil {
+		buf.Release()
+		return nil, err
+	}
+	return buf, nil
+}
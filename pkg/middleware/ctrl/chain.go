@@ -1,7 +1,6 @@
 package ctrl
 
 import (
-	"bytes"
 	"io"
 	"strings"
 
@@ -35,7 +34,7 @@ import (
 // Data is buffered between handlers in the chain, but each individual handler
 // can still stream internally.
 func Chain(handlers ...calque.Handler) calque.Handler {
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+	return calque.Composite("ctrl.Chain", calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
 		if len(handlers) == 0 {
 			// Empty chain - just pass through
 			_, err := io.Copy(res.Data, req.Data)
@@ -43,14 +42,14 @@ func Chain(handlers ...calque.Handler) calque.Handler {
 		}
 
 		// Read all input data for buffered sequential processing
-		var inputData []byte
-		err := calque.Read(req, &inputData)
+		input, err := bufferInput(req)
 		if err != nil {
 			return err
 		}
+		defer func() { input.Release() }()
 
 		// Execute handlers sequentially with context propagation
-		currentData := inputData
+		currentData := input.Bytes()
 		currentCtx := req.Context
 
 		for i, handler := range handlers {
@@ -64,22 +63,25 @@ func Chain(handlers ...calque.Handler) calque.Handler {
 			}
 
 			// Intermediate handler - buffer output
-			var buf bytes.Buffer
+			buf := calque.NewMemoryBuffer(currentCtx)
 			tempReq := &calque.Request{
 				Context: currentCtx,
 				Data:    io.NopCloser(strings.NewReader(string(currentData))),
 			}
-			tempRes := &calque.Response{Data: &buf}
+			tempRes := &calque.Response{Data: buf}
 
 			if err := handler.ServeFlow(tempReq, tempRes); err != nil {
+				buf.Release()
 				return err
 			}
 
-			// Update data and context for next handler
+			// Update data and context for next handler, dropping the previous stage's buffer
+			input.Release()
+			input = buf
 			currentData = buf.Bytes()
 			currentCtx = tempReq.Context // Context may have been modified by handler
 		}
 
 		return nil
-	})
+	}), calque.Group{Kind: calque.GroupSequence, Children: handlers})
 }
@@ -0,0 +1,254 @@
+package ctrl
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// ExperimentMetadataPrefix is the MetadataBus key prefix used to tag the selected variant.
+// The full key is ExperimentMetadataPrefix + experiment name (e.g. "experiment.prompt-v2").
+const ExperimentMetadataPrefix = "experiment."
+
+// Variant is a named handler participating in an experiment.
+//
+// Weight controls the relative share of traffic routed to the variant by the
+// built-in split functions. Variants with a weight <= 0 receive no traffic
+// unless every variant has a weight <= 0, in which case traffic is split evenly.
+type Variant struct {
+	Name    string
+	Handler calque.Handler
+	Weight  int
+}
+
+// SplitFunc selects the index of the variant that should serve a request.
+//
+// Returning an index outside the variants slice fails the request.
+type SplitFunc func(ctx context.Context, variants []Variant) int
+
+// VariantStats contains per-variant counters collected by an experiment.
+type VariantStats struct {
+	Requests      int64
+	Errors        int64
+	TotalDuration time.Duration
+}
+
+// AvgDuration returns the average handler duration for the variant.
+func (s VariantStats) AvgDuration() time.Duration {
+	if s.Requests == 0 {
+		return 0
+	}
+	return s.TotalDuration / time.Duration(s.Requests)
+}
+
+// ExperimentHandler routes traffic between variants and records per-variant stats.
+type ExperimentHandler struct {
+	name     string
+	variants []Variant
+	split    SplitFunc
+
+	mu    sync.Mutex
+	stats map[string]*VariantStats
+}
+
+// Experiment creates an A/B routing handler that splits traffic between variants.
+//
+// Input: any data type (streaming - passed through to selected variant)
+// Output: response from the selected variant
+// Behavior: STREAMING - selects a variant before reading input, then delegates
+//
+// For every request the split function picks a variant. The variant name is
+// stored on the MetadataBus under ExperimentMetadataPrefix + name so downstream
+// handlers and loggers can attribute results, and request count, error count and
+// duration are recorded per variant (see Stats). If split is nil, WeightedSplit is used.
+//
+// Example:
+//
+//	exp := ctrl.Experiment("summary-prompt", []ctrl.Variant{
+//		{Name: "control", Handler: ai.Agent(client), Weight: 90},
+//		{Name: "concise", Handler: conciseFlow, Weight: 10},
+//	}, nil)
+//	flow.Use(exp)
+//
+//	// later
+//	for name, s := range exp.Stats() {
+//		fmt.Println(name, s.Requests, s.Errors, s.AvgDuration())
+//	}
+func Experiment(name string, variants []Variant, split SplitFunc) *ExperimentHandler {
+	if split == nil {
+		split = WeightedSplit()
+	}
+
+	stats := make(map[string]*VariantStats, len(variants))
+	for _, v := range variants {
+		stats[v.Name] = &VariantStats{}
+	}
+
+	return &ExperimentHandler{
+		name:     name,
+		variants: variants,
+		split:    split,
+		stats:    stats,
+	}
+}
+
+// ServeFlow implements the calque.Handler interface.
+func (e *ExperimentHandler) ServeFlow(req *calque.Request, res *calque.Response) error {
+	if len(e.variants) == 0 {
+		return calque.NewErr(req.Context, fmt.Sprintf("experiment %q has no variants", e.name))
+	}
+
+	idx := e.split(req.Context, e.variants)
+	if idx < 0 || idx >= len(e.variants) {
+		return calque.NewErr(req.Context, fmt.Sprintf("experiment %q: split selected invalid variant index %d", e.name, idx))
+	}
+	variant := e.variants[idx]
+
+	if mb := calque.GetMetadataBus(req.Context); mb != nil {
+		mb.Set(ExperimentMetadataPrefix+e.name, variant.Name)
+	}
+
+	start := time.Now()
+	err := variant.Handler.ServeFlow(req, res)
+	e.record(variant.Name, time.Since(start), err)
+
+	return err
+}
+
+// Name returns the experiment name.
+func (e *ExperimentHandler) Name() string {
+	return e.name
+}
+
+// Group implements calque.Grouper, reporting the variants as branches.
+func (e *ExperimentHandler) Group() calque.Group {
+	g := calque.Group{Kind: calque.GroupBranch}
+	for _, v := range e.variants {
+		g.Children = append(g.Children, v.Handler)
+		g.Labels = append(g.Labels, v.Name)
+	}
+	return g
+}
+
+// Stats returns a snapshot of per-variant stats keyed by variant name.
+func (e *ExperimentHandler) Stats() map[string]VariantStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	snapshot := make(map[string]VariantStats, len(e.stats))
+	for name, s := range e.stats {
+		snapshot[name] = *s
+	}
+	return snapshot
+}
+
+// record updates the stats for a variant
+func (e *ExperimentHandler) record(variant string, duration time.Duration, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s, ok := e.stats[variant]
+	if !ok {
+		s = &VariantStats{}
+		e.stats[variant] = s
+	}
+	s.Requests++
+	s.TotalDuration += duration
+	if err != nil {
+		s.Errors++
+	}
+}
+
+// ExperimentVariant returns the variant selected for the named experiment in this run.
+//
+// Returns false if the experiment has not run or no MetadataBus is present in context.
+//
+// Example:
+//
+//	if variant, ok := ctrl.ExperimentVariant(ctx, "summary-prompt"); ok {
+//		log.Printf("served by %s", variant)
+//	}
+func ExperimentVariant(ctx context.Context, name string) (string, bool) {
+	mb := calque.GetMetadataBus(ctx)
+	if mb == nil {
+		return "", false
+	}
+	return mb.GetString(ExperimentMetadataPrefix + name)
+}
+
+// WeightedSplit returns a SplitFunc that picks variants randomly in proportion to their weights.
+//
+// Example:
+//
+//	exp := ctrl.Experiment("model", variants, ctrl.WeightedSplit())
+func WeightedSplit() SplitFunc {
+	return func(_ context.Context, variants []Variant) int {
+		total := totalWeight(variants)
+		if total == 0 {
+			return rand.IntN(len(variants))
+		}
+		return pickWeighted(variants, rand.IntN(total))
+	}
+}
+
+// StickySplit returns a SplitFunc that deterministically maps a key to a variant.
+//
+// The key function extracts a stable identifier (user ID, session ID, request ID)
+// from the context. The same key always lands on the same variant for a fixed
+// set of weights, so users get a consistent experience. An empty key falls back
+// to a weighted random choice.
+//
+// Example:
+//
+//	split := ctrl.StickySplit(func(ctx context.Context) string {
+//		return calque.RequestID(ctx)
+//	})
+func StickySplit(key func(ctx context.Context) string) SplitFunc {
+	fallback := WeightedSplit()
+	return func(ctx context.Context, variants []Variant) int {
+		k := key(ctx)
+		if k == "" {
+			return fallback(ctx, variants)
+		}
+
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(k))
+		sum := int(h.Sum32() & 0x7fffffff)
+
+		total := totalWeight(variants)
+		if total == 0 {
+			return sum % len(variants)
+		}
+		return pickWeighted(variants, sum%total)
+	}
+}
+
+// totalWeight sums the positive weights of the variants
+func totalWeight(variants []Variant) int {
+	total := 0
+	for _, v := range variants {
+		if v.Weight > 0 {
+			total += v.Weight
+		}
+	}
+	return total
+}
+
+// pickWeighted maps a point in [0, totalWeight) to a variant index
+func pickWeighted(variants []Variant, point int) int {
+	for i, v := range variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		if point < v.Weight {
+			return i
+		}
+		point -= v.Weight
+	}
+	return len(variants) - 1
+}
@@ -0,0 +1,188 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func prefixHandler(prefix string) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var input string
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+		return calque.Write(res, prefix+input)
+	})
+}
+
+func TestExperiment(t *testing.T) {
+	failing := calque.HandlerFunc(func(_ *calque.Request, _ *calque.Response) error {
+		return errors.New("variant failed")
+	})
+
+	tests := []struct {
+		name     string
+		variants []Variant
+		split    SplitFunc
+		expected string
+		variant  string
+		wantErr  string
+	}{
+		{
+			name: "fixed split selects variant",
+			variants: []Variant{
+				{Name: "control", Handler: prefixHandler("a:")},
+				{Name: "treatment", Handler: prefixHandler("b:")},
+			},
+			split:    func(context.Context, []Variant) int { return 1 },
+			expected: "b:hello",
+			variant:  "treatment",
+		},
+		{
+			name: "weighted split with single positive weight",
+			variants: []Variant{
+				{Name: "control", Handler: prefixHandler("a:"), Weight: 0},
+				{Name: "treatment", Handler: prefixHandler("b:"), Weight: 10},
+			},
+			expected: "b:hello",
+			variant:  "treatment",
+		},
+		{
+			name:     "no variants",
+			variants: nil,
+			wantErr:  "has no variants",
+		},
+		{
+			name: "invalid index",
+			variants: []Variant{
+				{Name: "control", Handler: prefixHandler("a:")},
+			},
+			split:   func(context.Context, []Variant) int { return 5 },
+			wantErr: "invalid variant index",
+		},
+		{
+			name: "variant error is returned",
+			variants: []Variant{
+				{Name: "broken", Handler: failing},
+			},
+			wantErr: "variant failed",
+			variant: "broken",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exp := Experiment("test-exp", tt.variants, tt.split)
+
+			mb := calque.NewMetadataBus(0)
+			defer mb.Close()
+			ctx := calque.WithMetadataBus(context.Background(), mb)
+
+			var out strings.Builder
+			err := exp.ServeFlow(calque.NewRequest(ctx, strings.NewReader("hello")), calque.NewResponse(&out))
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.expected != "" && out.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, out.String())
+			}
+
+			if tt.variant != "" {
+				got, ok := ExperimentVariant(ctx, "test-exp")
+				if !ok || got != tt.variant {
+					t.Errorf("expected variant %q tagged, got %q (ok=%v)", tt.variant, got, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestExperimentStats(t *testing.T) {
+	calls := 0
+	split := func(context.Context, []Variant) int {
+		calls++
+		return calls % 2
+	}
+	failing := calque.HandlerFunc(func(_ *calque.Request, _ *calque.Response) error {
+		return errors.New("boom")
+	})
+
+	exp := Experiment("stats", []Variant{
+		{Name: "ok", Handler: prefixHandler("")},
+		{Name: "bad", Handler: failing},
+	}, split)
+
+	for i := 0; i < 4; i++ {
+		var out strings.Builder
+		_ = exp.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("x")), calque.NewResponse(&out))
+	}
+
+	stats := exp.Stats()
+	if stats["ok"].Requests != 2 || stats["ok"].Errors != 0 {
+		t.Errorf("unexpected ok stats: %+v", stats["ok"])
+	}
+	if stats["bad"].Requests != 2 || stats["bad"].Errors != 2 {
+		t.Errorf("unexpected bad stats: %+v", stats["bad"])
+	}
+	if exp.Name() != "stats" {
+		t.Errorf("expected name 'stats', got %q", exp.Name())
+	}
+}
+
+func TestStickySplit(t *testing.T) {
+	variants := []Variant{
+		{Name: "a", Weight: 50},
+		{Name: "b", Weight: 50},
+	}
+	split := StickySplit(func(ctx context.Context) string { return calque.RequestID(ctx) })
+
+	ctx := calque.WithRequestID(context.Background(), "user-42")
+	first := split(ctx, variants)
+	for i := 0; i < 20; i++ {
+		if got := split(ctx, variants); got != first {
+			t.Fatalf("sticky split changed variant: %d != %d", got, first)
+		}
+	}
+
+	// Empty key falls back to weighted random, which must stay in range
+	for i := 0; i < 20; i++ {
+		if got := split(context.Background(), variants); got < 0 || got >= len(variants) {
+			t.Fatalf("fallback returned out of range index %d", got)
+		}
+	}
+}
+
+func TestWeightedSplitDistribution(t *testing.T) {
+	variants := []Variant{
+		{Name: "a", Weight: 1},
+		{Name: "b", Weight: 3},
+	}
+	split := WeightedSplit()
+
+	counts := make([]int, len(variants))
+	for i := 0; i < 4000; i++ {
+		counts[split(context.Background(), variants)]++
+	}
+
+	if counts[1] < counts[0]*2 {
+		t.Errorf("expected weighted distribution roughly 1:3, got %v", counts)
+	}
+
+	// All-zero weights split evenly without panicking
+	zero := []Variant{{Name: "a"}, {Name: "b"}}
+	for i := 0; i < 10; i++ {
+		if got := split(context.Background(), zero); got < 0 || got > 1 {
+			t.Fatalf("unexpected index %d", got)
+		}
+	}
+}
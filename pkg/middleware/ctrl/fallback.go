@@ -3,6 +3,7 @@ package ctrl
 import (
 	"bytes"
 	"context"
+	"errors"
 	"sync"
 	"time"
 
@@ -32,7 +33,10 @@ type circuitBreaker struct {
 //
 // Attempts primary handler first. On failure, tries fallback handlers
 // in sequence until one succeeds. Includes circuit breaker logic to
-// skip known-failing handlers temporarily.
+// skip known-failing handlers temporarily. Buffered input and output count
+// against the run's memory cap (calque.WithMemoryLimit). Invalid-input and
+// cancelled errors (calque.IsInvalidInput, calque.IsCancelled) are returned
+// immediately without trying the remaining handlers.
 //
 // Example:
 //
@@ -56,12 +60,12 @@ func Fallback(handlers ...calque.Handler) calque.Handler {
 		}
 	}
 
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
-		var input []byte
-		err := calque.Read(req, &input)
+	return calque.Composite("ctrl.Fallback", calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		input, err := bufferInput(req)
 		if err != nil {
 			return err
 		}
+		defer input.Release()
 
 		var lastErr error
 		for i, handler := range handlers {
@@ -69,22 +73,31 @@ func Fallback(handlers ...calque.Handler) calque.Handler {
 				continue // Skip if circuit breaker is open
 			}
 
-			var output bytes.Buffer
-			handlerReq := calque.NewRequest(req.Context, bytes.NewReader(input))
-			handlerRes := calque.NewResponse(&output)
+			output := calque.NewMemoryBuffer(req.Context)
+			handlerReq := calque.NewRequest(req.Context, bytes.NewReader(input.Bytes()))
+			handlerRes := calque.NewResponse(output)
 			err := handler.ServeFlow(handlerReq, handlerRes)
 
 			if err == nil {
 				breakers[i].RecordSuccess()
-				return calque.Write(res, output.Bytes())
+				err = calque.Write(res, output.Bytes())
+				output.Release()
+				return err
 			}
+			output.Release()
 
+			if errors.Is(err, calque.ErrMemoryLimit) {
+				return err // the run's memory cap, not a handler fault
+			}
+			if calque.IsInvalidInput(err) || calque.IsCancelled(err) {
+				return err // every handler would reject the same input
+			}
 			breakers[i].RecordFailure()
 			lastErr = err
 		}
 
 		return calque.WrapErr(req.Context, lastErr, "all handlers failed")
-	})
+	}), calque.Group{Kind: calque.GroupFallback, Children: handlers})
 }
 
 // Allow checks if requests should be allowed through
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"io"
+	"net/http"
 	"strings"
 	"testing"
 	"time"
@@ -485,3 +487,60 @@ func TestFallbackContextCancellation(t *testing.T) {
 		t.Errorf("Fallback() = %q, want %q", got, expected)
 	}
 }
+
+func TestFallbackMemoryLimit(t *testing.T) {
+	calls := 0
+	primary := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		calls++
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+
+	ctx := calque.WithMemoryLimit(context.Background(), 10)
+	var out string
+	err := calque.NewFlow().Use(Fallback(primary, primary)).Run(ctx, "1234567", &out)
+	if !errors.Is(err, calque.ErrMemoryLimit) {
+		t.Fatalf("error = %v, want ErrMemoryLimit", err)
+	}
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+	if usage, _ := calque.MemoryUsed(ctx); usage.Used != 0 {
+		t.Errorf("%d bytes still reserved", usage.Used)
+	}
+}
+
+func TestFallbackStopsOnInvalidInput(t *testing.T) {
+	secondCalled := false
+	flow := calque.NewFlow().Use(Fallback(
+		calque.HandlerFunc(func(*calque.Request, *calque.Response) error {
+			return calque.InvalidInput(errors.New("bad"))
+		}),
+		calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+			secondCalled = true
+			_, err := io.Copy(res.Data, req.Data)
+			return err
+		}),
+	))
+	var out string
+	err := flow.Run(context.Background(), "x", &out)
+	if !calque.IsInvalidInput(err) || secondCalled {
+		t.Errorf("error = %v, second handler called = %v", err, secondCalled)
+	}
+}
+
+func TestFallbackTriesNextOnProviderClientError(t *testing.T) {
+	flow := calque.NewFlow().Use(Fallback(
+		calque.HandlerFunc(func(*calque.Request, *calque.Response) error {
+			return calque.ClassifyHTTPStatus(http.StatusNotFound, errors.New("model not found"))
+		}),
+		calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+			_, err := io.Copy(res.Data, req.Data)
+			return err
+		}),
+	))
+	var out string
+	if err := flow.Run(context.Background(), "x", &out); err != nil || out != "x" {
+		t.Errorf("output = %q, error = %v, want the second handler's output", out, err)
+	}
+}
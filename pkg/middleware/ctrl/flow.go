@@ -3,6 +3,7 @@ package ctrl
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"time"
@@ -36,7 +37,7 @@ func PassThrough() calque.Handler {
 //	  textHandler,
 //	)
 func Branch(condition func([]byte) bool, ifHandler calque.Handler, elseHandler calque.Handler) calque.Handler {
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+	return calque.Composite("ctrl.Branch", calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
 		var input []byte
 		err := calque.Read(req, &input)
 		if err != nil {
@@ -49,6 +50,10 @@ func Branch(condition func([]byte) bool, ifHandler calque.Handler, elseHandler c
 			return ifHandler.ServeFlow(req, res)
 		}
 		return elseHandler.ServeFlow(req, res)
+	}), calque.Group{
+		Kind:     calque.GroupBranch,
+		Children: []calque.Handler{ifHandler, elseHandler},
+		Labels:   []string{"true", "false"},
 	})
 }
 
@@ -97,7 +102,7 @@ func TeeReader(destinations ...io.Writer) calque.Handler {
 //	parallel := ctrl.Parallel(handler1, handler2, handler3)
 //	// All three handlers process the same input concurrently via TeeReader
 func Parallel(handlers ...calque.Handler) calque.Handler {
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+	return calque.Composite("ctrl.Parallel", calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
 		if len(handlers) == 0 {
 			_, err := io.Copy(res.Data, req.Data)
 			return err
@@ -173,7 +178,7 @@ func Parallel(handlers ...calque.Handler) calque.Handler {
 		combined := bytes.Join(outputs, []byte("\n---\n"))
 		err := calque.Write(res, combined)
 		return err
-	})
+	}), calque.Group{Kind: calque.GroupParallel, Children: handlers})
 }
 
 // Timeout wraps a handler with timeout protection.
@@ -191,7 +196,7 @@ func Parallel(handlers ...calque.Handler) calque.Handler {
 //	timeoutHandler := ctrl.Timeout(someHandler, 30*time.Second)
 //	pipe.Use(timeoutHandler)
 func Timeout(handler calque.Handler, timeout time.Duration) calque.Handler {
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+	return calque.Composite("ctrl.Timeout", calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
 		timeoutCtx, cancel := context.WithTimeout(req.Context, timeout)
 		defer cancel()
 
@@ -208,9 +213,13 @@ func Timeout(handler calque.Handler, timeout time.Duration) calque.Handler {
 		case <-timeoutCtx.Done():
 			return calque.WrapErr(req.Context, timeoutCtx.Err(), fmt.Sprintf("handler timeout after %v", timeout))
 		}
-	})
+	}), wrapGroup(handler))
 }
 
+// MaxRetryWait caps how long Retry waits between attempts, however long a
+// rate limiter's Retry-After asks for.
+const MaxRetryWait = time.Minute
+
 // Retry wraps a handler with retry logic and exponential backoff.
 //
 // Input: any data type (buffered - reads entire input into memory)
@@ -219,40 +228,79 @@ func Timeout(handler calque.Handler, timeout time.Duration) calque.Handler {
 //
 // The function attempts to execute the wrapped handler up to maxAttempts times.
 // If the handler fails, it retries with exponential backoff (100ms, 200ms, 400ms, etc.).
-// The same input is replayed for each retry attempt.
+// The same input is replayed for each retry attempt. The buffered input and
+// each attempt's output count against the run's memory cap
+// (calque.WithMemoryLimit); a *calque.MemoryLimitError is not retried.
+// Errors classified as invalid input or cancelled (calque.IsInvalidInput,
+// calque.IsCancelled) are returned without retrying, and a rate-limited
+// error's calque.RetryAfter wait replaces a shorter backoff, up to
+// MaxRetryWait. Cancelling the request's context ends a wait early. The
+// wrapped handler can read the attempt number with RetryAttempt.
 //
 // Example:
 //
 //	retryHandler := ctrl.Retry(someHandler, 3)
 //	pipe.Use(retryHandler)
 func Retry(handler calque.Handler, maxAttempts int) calque.Handler {
-	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
-		var input []byte
-		err := calque.Read(req, &input)
+	return calque.Composite("ctrl.Retry", calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		input, err := bufferInput(req)
 		if err != nil {
 			return err
 		}
+		defer input.Release()
 
 		var lastErr error
 		for attempt := range maxAttempts {
-			// Reset the request data for each attempt
-			req.Data = bytes.NewReader(input)
+			// Replay the input for each attempt, numbered for RetryAttempt
+			attemptReq := calque.NewRequest(context.WithValue(req.Context, retryAttemptKey{}, attempt), bytes.NewReader(input.Bytes()))
 
-			var output bytes.Buffer
-			tempRes := &calque.Response{Data: &output}
-			err := handler.ServeFlow(req, tempRes)
+			output := calque.NewMemoryBuffer(req.Context)
+			tempRes := &calque.Response{Data: output}
+			err := handler.ServeFlow(attemptReq, tempRes)
 			if err == nil {
 				_, writeErr := res.Data.Write(output.Bytes())
+				output.Release()
 				return writeErr
 			}
+			output.Release()
 			lastErr = err
+			if errors.Is(err, calque.ErrMemoryLimit) {
+				break // replaying cannot free memory held by the run
+			}
+			if calque.IsInvalidInput(err) || calque.IsCancelled(err) {
+				break // the same input or a cancelled run fails again
+			}
 
-			// Exponential backoff
+			// Exponential backoff, at least as long as a rate limiter asked for
 			if attempt < maxAttempts-1 {
-				time.Sleep(time.Duration(1<<attempt) * 100 * time.Millisecond)
+				backoff := time.Duration(1<<attempt) * 100 * time.Millisecond
+				if wait, ok := calque.RetryAfter(err); ok && wait > backoff {
+					backoff = wait
+				}
+				timer := time.NewTimer(min(backoff, MaxRetryWait))
+				select {
+				case <-req.Context.Done():
+					timer.Stop()
+					return calque.WrapErr(req.Context, req.Context.Err(), "retry cancelled")
+				case <-timer.C:
+				}
 			}
 		}
 
 		return calque.WrapErr(req.Context, lastErr, "retry exhausted")
-	})
+	}), wrapGroup(handler))
+}
+
+type retryAttemptKey struct{}
+
+// RetryAttempt returns the attempt of the innermost ctrl.Retry running the
+// handler, 0 for the first try and when not retried.
+func RetryAttempt(ctx context.Context) int {
+	attempt, _ := ctx.Value(retryAttemptKey{}).(int)
+	return attempt
+}
+
+// wrapGroup reports a single wrapped handler for calque.Flow.Export
+func wrapGroup(handler calque.Handler) calque.Group {
+	return calque.Group{Kind: calque.GroupWrap, Children: []calque.Handler{handler}}
 }
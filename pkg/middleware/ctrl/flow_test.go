@@ -577,6 +577,32 @@ func TestRetry(t *testing.T) {
 	}
 }
 
+func TestRetryAttempt(t *testing.T) {
+	var attempts []int
+	handler := Retry(calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var in string
+		if err := calque.Read(req, &in); err != nil {
+			return err
+		}
+		attempts = append(attempts, RetryAttempt(req.Context))
+		if len(attempts) < 2 {
+			return errors.New("flaky")
+		}
+		return calque.Write(res, in)
+	}), 3)
+
+	var buf bytes.Buffer
+	if err := handler.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("x")), calque.NewResponse(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 2 || attempts[0] != 0 || attempts[1] != 1 {
+		t.Errorf("attempts = %v, want [0 1]", attempts)
+	}
+	if RetryAttempt(context.Background()) != 0 {
+		t.Error("RetryAttempt outside Retry should be 0")
+	}
+}
+
 func BenchmarkPassThrough(b *testing.B) {
 	sizes := []struct {
 		name string
@@ -883,3 +909,83 @@ func BenchmarkRetry(b *testing.B) {
 		})
 	}
 }
+
+func TestRetryMemoryLimit(t *testing.T) {
+	calls := 0
+	h := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		calls++
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+
+	ctx := calque.WithMemoryLimit(context.Background(), 10)
+	var out string
+	err := calque.NewFlow().Use(Retry(h, 3)).Run(ctx, "1234567", &out)
+	if !errors.Is(err, calque.ErrMemoryLimit) || calls != 1 {
+		t.Errorf("error = %v after %d calls, want ErrMemoryLimit after 1", err, calls)
+	}
+}
+
+func TestExportComposites(t *testing.T) {
+	flow := calque.NewFlow().Use(Retry(Branch(
+		func([]byte) bool { return true },
+		Parallel(PassThrough(), PassThrough()),
+		Chain(PassThrough()),
+	), 2))
+
+	got, err := flow.Export(calque.ExportMermaid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`subgraph n1["ctrl.Retry"]`, `subgraph n2["ctrl.Branch"]`, `subgraph n3["ctrl.Parallel"]`, `subgraph n6["ctrl.Chain"]`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("export missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestRetryErrorKinds(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCalls int
+	}{
+		{name: "invalid input", err: calque.InvalidInput(errors.New("bad")), wantCalls: 1},
+		{name: "cancelled", err: calque.Cancelled(errors.New("stop")), wantCalls: 1},
+		{name: "retryable", err: calque.Retryable(errors.New("flaky")), wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			h := calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+				calls++
+				_, _ = io.Copy(io.Discard, req.Data)
+				return tt.err
+			})
+			var out string
+			err := calque.NewFlow().Use(Retry(h, 2)).Run(context.Background(), "x", &out)
+			if !errors.Is(err, tt.err) || calls != tt.wantCalls {
+				t.Errorf("error = %v after %d calls, want %d calls", err, calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryWaitCancelled(t *testing.T) {
+	h := calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+		_, _ = io.Copy(io.Discard, req.Data)
+		return calque.RateLimited(errors.New("slow down"), time.Hour)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	var out string
+	err := calque.NewFlow().Use(Retry(h, 3)).Run(ctx, "x", &out)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want the context's deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Retry waited %v after cancellation", elapsed)
+	}
+}
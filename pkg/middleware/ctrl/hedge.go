@@ -0,0 +1,226 @@
+package ctrl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// HedgeConfig configures Hedge. Zero fields use the defaults noted.
+type HedgeConfig struct {
+	// MaxHedges is how many speculative runs may join the first (0 = 1)
+	MaxHedges int
+	// MaxCostUSD stops new hedges once the runs were charged this much via
+	// ChargeCost (0 = unlimited)
+	MaxCostUSD float64
+	// MaxTokens stops new hedges once the runs were charged this many tokens
+	// via ChargeTokens (0 = unlimited)
+	MaxTokens int
+}
+
+// HedgeStats contains the counters collected by a hedge handler.
+type HedgeStats struct {
+	Requests   int64 // requests served
+	Hedged     int64 // requests that started at least one speculative run
+	HedgeWins  int64 // requests answered by a speculative run
+	CostCapped int64 // hedges not started because a cost cap was reached
+}
+
+// HedgeHandler runs a handler again when it is slow, keeping the faster result.
+type HedgeHandler struct {
+	handler calque.Handler
+	delay   time.Duration
+	config  HedgeConfig
+
+	mu    sync.Mutex
+	stats HedgeStats
+}
+
+type hedgeRunKey struct{}
+
+// hedgeResult is the outcome of one run of the handler
+type hedgeResult struct {
+	run    int
+	output *calque.MemoryBuffer
+	err    error
+}
+
+// Hedge starts a speculative second run when the first misses a latency SLO.
+//
+// Input: any data type (buffered - replayed to every run)
+// Output: output of the first run to succeed
+// Behavior: BUFFERED - runs are buffered and the winner's output is written
+//
+// The handler runs once. If it has not finished after delay, another run on
+// the same input starts, and so on every delay up to MaxHedges extra runs.
+// The first run to succeed wins and the others are cancelled. A failed run
+// is not replaced: the error of the last run is returned once every started
+// run has failed. Invalid-input errors are returned at once.
+//
+// Speculative runs multiply model spend, so tokens and cost charged by the
+// runs (ChargeTokens, ChargeCost, ai.Agent usage) are totalled and no hedge
+// starts once MaxTokens or MaxCostUSD is reached. Charges still count against
+// enclosing Budgets, and BudgetUsed inside a run reports the total of all
+// runs. The buffered input and outputs count against the run's memory cap
+// (calque.WithMemoryLimit). The wrapped handler can read its run number with
+// HedgeRun. Counters are available via Stats.
+//
+// Example:
+//
+//	hedged := ctrl.Hedge(ai.Agent(client), 3*time.Second, ctrl.HedgeConfig{MaxCostUSD: 0.05})
+//	flow.Use(hedged)
+//
+//	// later
+//	s := hedged.Stats()
+//	fmt.Printf("hedged %d/%d, hedge won %d\n", s.Hedged, s.Requests, s.HedgeWins)
+func Hedge(handler calque.Handler, delay time.Duration, config ...HedgeConfig) *HedgeHandler {
+	cfg := HedgeConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.MaxHedges <= 0 {
+		cfg.MaxHedges = 1
+	}
+	return &HedgeHandler{handler: handler, delay: delay, config: cfg}
+}
+
+// ServeFlow implements the calque.Handler interface.
+func (h *HedgeHandler) ServeFlow(req *calque.Request, res *calque.Response) error {
+	input, err := bufferInput(req)
+	if err != nil {
+		return err
+	}
+	defer input.Release()
+
+	ctx, cancel := context.WithCancel(req.Context)
+	defer cancel()
+
+	// Totals the runs' charges without limits; parent budgets are still charged
+	usage := &budgetTracker{start: time.Now(), parent: budgetFromContext(req.Context), cancel: func(error) {}}
+	runCtx := context.WithValue(ctx, budgetContextKey{}, usage)
+
+	maxRuns := h.config.MaxHedges + 1
+	results := make(chan hedgeResult, maxRuns)
+	start := func(run int) {
+		go func() {
+			output := calque.NewMemoryBuffer(req.Context)
+			runReq := calque.NewRequest(context.WithValue(runCtx, hedgeRunKey{}, run), bytes.NewReader(input.Bytes()))
+			err := h.handler.ServeFlow(runReq, calque.NewResponse(output))
+			results <- hedgeResult{run: run, output: output, err: err}
+		}()
+	}
+
+	started, finished := 1, 0
+	start(0)
+	defer func() {
+		// Cancelled runs still hold their output until they return
+		go releaseHedges(results, started-finished)
+	}()
+
+	timer := time.NewTimer(h.delay)
+	defer timer.Stop()
+	capped := false
+
+	var lastErr error
+	for {
+		var next <-chan time.Time
+		if !capped && started < maxRuns {
+			next = timer.C
+		}
+
+		select {
+		case result := <-results:
+			finished++
+			if result.err == nil {
+				h.record(started > 1, result.run > 0, false)
+				err := calque.Write(res, result.output.Bytes())
+				result.output.Release()
+				return err
+			}
+			result.output.Release()
+			lastErr = result.err
+			if calque.IsInvalidInput(result.err) || errors.Is(result.err, calque.ErrMemoryLimit) || finished == started {
+				h.record(started > 1, false, false)
+				return result.err
+			}
+
+		case <-next:
+			if !h.withinCaps(usage) {
+				capped = true
+				h.record(false, false, true)
+				continue
+			}
+			start(started)
+			started++
+			timer.Reset(h.delay)
+
+		case <-ctx.Done():
+			h.record(started > 1, false, false)
+			if lastErr != nil {
+				return lastErr
+			}
+			return calque.WrapErr(req.Context, ctx.Err(), "hedged run cancelled")
+		}
+	}
+}
+
+// releaseHedges frees the output of n runs still in flight
+func releaseHedges(results <-chan hedgeResult, n int) {
+	for range n {
+		result := <-results
+		result.output.Release()
+	}
+}
+
+// withinCaps reports whether another run may start under the cost caps
+func (h *HedgeHandler) withinCaps(usage *budgetTracker) bool {
+	usage.mu.Lock()
+	defer usage.mu.Unlock()
+	if h.config.MaxCostUSD > 0 && usage.usage.CostUSD >= h.config.MaxCostUSD {
+		return false
+	}
+	if h.config.MaxTokens > 0 && usage.usage.Tokens >= h.config.MaxTokens {
+		return false
+	}
+	return true
+}
+
+// record updates the counters; capped alone counts a skipped hedge
+func (h *HedgeHandler) record(hedged, hedgeWon, capped bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if capped {
+		h.stats.CostCapped++
+		return
+	}
+	h.stats.Requests++
+	if hedged {
+		h.stats.Hedged++
+	}
+	if hedgeWon {
+		h.stats.HedgeWins++
+	}
+}
+
+// Group implements calque.Grouper.
+func (h *HedgeHandler) Group() calque.Group {
+	return wrapGroup(h.handler)
+}
+
+// Stats returns a snapshot of the hedging counters.
+func (h *HedgeHandler) Stats() HedgeStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.stats
+}
+
+// HedgeRun returns the run number of the innermost ctrl.Hedge running the
+// handler: 0 for the first run and when not hedged, 1 and up for hedges.
+func HedgeRun(ctx context.Context) int {
+	run, _ := ctx.Value(hedgeRunKey{}).(int)
+	return run
+}
@@ -0,0 +1,173 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// slowFirstRun answers immediately on hedges and after firstDelay on the first run
+func slowFirstRun(firstDelay time.Duration, runs *atomic.Int32) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var input string
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+		runs.Add(1)
+		run := HedgeRun(req.Context)
+		if run == 0 {
+			select {
+			case <-time.After(firstDelay):
+			case <-req.Context.Done():
+				return req.Context.Err()
+			}
+		}
+		return calque.Write(res, input+" from run "+strconv.Itoa(run))
+	})
+}
+
+func TestHedge(t *testing.T) {
+	t.Run("fast run is not hedged", func(t *testing.T) {
+		var runs atomic.Int32
+		hedged := Hedge(slowFirstRun(0, &runs), 100*time.Millisecond)
+
+		var out strings.Builder
+		if err := hedged.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("answer")), calque.NewResponse(&out)); err != nil {
+			t.Fatalf("ServeFlow() error = %v", err)
+		}
+		if out.String() != "answer from run 0" {
+			t.Errorf("output = %q", out.String())
+		}
+		if runs.Load() != 1 {
+			t.Errorf("runs = %d, want 1", runs.Load())
+		}
+		if s := hedged.Stats(); s.Requests != 1 || s.Hedged != 0 || s.HedgeWins != 0 {
+			t.Errorf("stats = %+v", s)
+		}
+	})
+
+	t.Run("slow run loses to hedge", func(t *testing.T) {
+		var runs atomic.Int32
+		hedged := Hedge(slowFirstRun(5*time.Second, &runs), 20*time.Millisecond)
+
+		var out strings.Builder
+		start := time.Now()
+		if err := hedged.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("answer")), calque.NewResponse(&out)); err != nil {
+			t.Fatalf("ServeFlow() error = %v", err)
+		}
+		if time.Since(start) > time.Second {
+			t.Errorf("hedge did not cut latency: %v", time.Since(start))
+		}
+		if out.String() != "answer from run 1" {
+			t.Errorf("output = %q", out.String())
+		}
+		if s := hedged.Stats(); s.Requests != 1 || s.Hedged != 1 || s.HedgeWins != 1 {
+			t.Errorf("stats = %+v", s)
+		}
+	})
+
+	t.Run("all runs fail", func(t *testing.T) {
+		var runs atomic.Int32
+		failing := calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+			var input string
+			_ = calque.Read(req, &input)
+			runs.Add(1)
+			time.Sleep(50 * time.Millisecond)
+			return errors.New("provider down")
+		})
+		hedged := Hedge(failing, 10*time.Millisecond, HedgeConfig{MaxHedges: 2})
+
+		var out strings.Builder
+		err := hedged.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("x")), calque.NewResponse(&out))
+		if err == nil || !strings.Contains(err.Error(), "provider down") {
+			t.Fatalf("ServeFlow() error = %v, want provider down", err)
+		}
+		if runs.Load() != 3 {
+			t.Errorf("runs = %d, want 3", runs.Load())
+		}
+	})
+
+	t.Run("invalid input is not hedged", func(t *testing.T) {
+		var runs atomic.Int32
+		rejecting := calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+			var input string
+			_ = calque.Read(req, &input)
+			runs.Add(1)
+			return calque.InvalidInput(calque.NewErr(req.Context, "bad prompt"))
+		})
+
+		var out strings.Builder
+		err := Hedge(rejecting, time.Second).ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("x")), calque.NewResponse(&out))
+		if !calque.IsInvalidInput(err) {
+			t.Fatalf("ServeFlow() error = %v, want invalid input", err)
+		}
+		if runs.Load() != 1 {
+			t.Errorf("runs = %d, want 1", runs.Load())
+		}
+	})
+
+	t.Run("cost cap stops hedges", func(t *testing.T) {
+		var runs atomic.Int32
+		expensive := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+			var input string
+			if err := calque.Read(req, &input); err != nil {
+				return err
+			}
+			runs.Add(1)
+			_ = ChargeCost(req.Context, 0.10)
+			time.Sleep(60 * time.Millisecond)
+			return calque.Write(res, "done")
+		})
+		hedged := Hedge(expensive, 10*time.Millisecond, HedgeConfig{MaxCostUSD: 0.05})
+
+		var out strings.Builder
+		if err := hedged.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("x")), calque.NewResponse(&out)); err != nil {
+			t.Fatalf("ServeFlow() error = %v", err)
+		}
+		if runs.Load() != 1 {
+			t.Errorf("runs = %d, want 1", runs.Load())
+		}
+		if s := hedged.Stats(); s.CostCapped != 1 || s.Hedged != 0 {
+			t.Errorf("stats = %+v", s)
+		}
+	})
+
+	t.Run("charges reach enclosing budget", func(t *testing.T) {
+		var used BudgetUsage
+		charging := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+			var input string
+			if err := calque.Read(req, &input); err != nil {
+				return err
+			}
+			_ = ChargeTokens(req.Context, 10)
+			return calque.Write(res, "ok")
+		})
+		inspect := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+			var out strings.Builder
+			err := Hedge(charging, time.Second).ServeFlow(req, calque.NewResponse(&out))
+			used, _ = BudgetUsed(req.Context)
+			return err
+		})
+
+		var out strings.Builder
+		err := Budget(inspect, BudgetLimits{MaxTokens: 100}).ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("x")), calque.NewResponse(&out))
+		if err != nil {
+			t.Fatalf("ServeFlow() error = %v", err)
+		}
+		if used.Tokens != 10 {
+			t.Errorf("budget tokens = %d, want 10", used.Tokens)
+		}
+	})
+}
+
+func TestHedgeRunWithoutHedge(t *testing.T) {
+	if run := HedgeRun(context.Background()); run != 0 {
+		t.Errorf("HedgeRun() = %d, want 0", run)
+	}
+}
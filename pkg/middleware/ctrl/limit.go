@@ -0,0 +1,46 @@
+package ctrl
+
+import (
+	"fmt"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Limit caps how many calls of handler run at once, independent of the
+// flow-wide FlowConfig.MaxConcurrent.
+//
+// Input: any data type (passes through to the wrapped handler)
+// Output: same as wrapped handler's output
+// Behavior: STREAMING - waits for a free slot, then streams through handler
+//
+// The cap is shared by every run using the returned handler, so a dependency
+// with a tighter concurrency budget than the flow, such as an embedding API
+// that tolerates 8 concurrent calls, is protected even when the flow admits
+// hundreds of runs. A call holds its slot until handler returns. Calls waiting
+// for a slot fail with the context error when the run is cancelled; they keep
+// their flow-wide slot while waiting.
+//
+// Example:
+//
+//	flow := calque.NewFlow(calque.FlowConfig{MaxConcurrent: 200}).
+//		Use(ctrl.Limit(embed, 8)).
+//		Use(ai.Agent(client))
+func Limit(handler calque.Handler, n int) calque.Handler {
+	if n <= 0 {
+		return calque.HandlerFunc(func(r *calque.Request, _ *calque.Response) error {
+			return calque.NewErr(r.Context, fmt.Sprintf("invalid concurrency limit: must be greater than 0, got %d", n))
+		})
+	}
+
+	slots := make(chan struct{}, n)
+	return calque.Composite("ctrl.Limit", calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		select {
+		case slots <- struct{}{}:
+		case <-req.Context.Done():
+			return calque.WrapErr(req.Context, req.Context.Err(), "concurrency limit wait failed")
+		}
+		defer func() { <-slots }()
+
+		return handler.ServeFlow(req, res)
+	}), wrapGroup(handler))
+}
@@ -0,0 +1,85 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestLimit(t *testing.T) {
+	var running, peak atomic.Int32
+	slow := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+
+	flow := calque.NewFlow(calque.FlowConfig{MaxConcurrent: 50}).Use(Limit(slow, 3))
+
+	var wg sync.WaitGroup
+	for range 12 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var out string
+			if err := flow.Run(context.Background(), "payload", &out); err != nil || out != "payload" {
+				t.Errorf("Run() = %q, %v", out, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got != 3 {
+		t.Errorf("peak concurrency = %d, want 3", got)
+	}
+}
+
+func TestLimitCancelledWhileWaiting(t *testing.T) {
+	release := make(chan struct{})
+	blocking := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		<-release
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+	limited := Limit(blocking, 1)
+
+	done := make(chan error, 1)
+	go func() {
+		var out string
+		done <- calque.NewFlow().Use(limited).Run(context.Background(), "first", &out)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var out string
+	if err := calque.NewFlow().Use(limited).Run(ctx, "second", &out); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() error = %v, want deadline exceeded while waiting", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("first Run() error = %v", err)
+	}
+}
+
+func TestLimitInvalid(t *testing.T) {
+	var out string
+	if err := calque.NewFlow().Use(Limit(PassThrough(), 0)).Run(context.Background(), "x", &out); err == nil {
+		t.Error("Limit(handler, 0) error = nil")
+	}
+}
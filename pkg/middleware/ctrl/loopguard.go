@@ -0,0 +1,249 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Loop guard defaults, used for zero LoopLimits fields.
+const (
+	DefaultMaxIdentical = 3 // occurrences of one identical action
+	DefaultMaxCycles    = 2 // consecutive repetitions of an action cycle
+	DefaultMaxCycleLen  = 4 // longest cycle detected
+)
+
+// Loop reasons reported by LoopDetectedError.
+const (
+	LoopMaxSteps    = "max_steps"
+	LoopRepeated    = "repeated"
+	LoopOscillating = "oscillating"
+)
+
+// ErrLoopDetected matches any LoopDetectedError with errors.Is.
+var ErrLoopDetected = errors.New("loop detected")
+
+// LoopLimits configures LoopGuard.
+type LoopLimits struct {
+	MaxSteps     int // actions per run (0 = unlimited)
+	MaxIdentical int // occurrences allowed of one identical action (0 = DefaultMaxIdentical)
+	MaxCycles    int // consecutive repetitions allowed of a cycle like A B A B (0 = DefaultMaxCycles)
+	MaxCycleLen  int // longest cycle checked, in actions (0 = DefaultMaxCycleLen)
+}
+
+// LoopDetectedError is returned when a guarded run repeats itself.
+//
+// Example:
+//
+//	var loopErr *ctrl.LoopDetectedError
+//	if errors.As(err, &loopErr) {
+//		log.Printf("agent stuck (%s) on %q after %d steps", loopErr.Reason, loopErr.Action, loopErr.Steps)
+//	}
+type LoopDetectedError struct {
+	Reason string   // one of LoopMaxSteps, LoopRepeated, LoopOscillating
+	Action string   // the action that crossed the limit
+	Cycle  []string // the repeated actions, for LoopOscillating
+	Count  int      // occurrences of Action, or repetitions of Cycle
+	Steps  int      // actions recorded by the run
+}
+
+func (e *LoopDetectedError) Error() string {
+	switch e.Reason {
+	case LoopMaxSteps:
+		return fmt.Sprintf("loop detected: more than %d steps", e.Steps-1)
+	case LoopOscillating:
+		return fmt.Sprintf("loop detected: cycle [%s] repeated %d times", strings.Join(e.Cycle, ", "), e.Count)
+	}
+	return fmt.Sprintf("loop detected: %q repeated %d times", e.Action, e.Count)
+}
+
+// Is reports whether target is ErrLoopDetected.
+func (e *LoopDetectedError) Is(target error) bool {
+	return target == ErrLoopDetected
+}
+
+type loopContextKey struct{}
+
+// loopTracker records the actions of one LoopGuard run
+type loopTracker struct {
+	limits LoopLimits
+	parent *loopTracker
+	cancel context.CancelCauseFunc
+
+	mu       sync.Mutex
+	steps    []string
+	counts   map[string]int
+	detected *LoopDetectedError
+}
+
+// LoopGuard stops a handler, typically an agent, that repeats itself.
+//
+// Input: any data type (streaming - passed through to handler)
+// Output: same as wrapped handler's output
+// Behavior: STREAMING - cancels the run as soon as a loop is detected
+//
+// Actions are recorded with RecordStep; tools.Execute records every tool
+// call by name and arguments. The run is stopped when an identical action
+// occurs more than MaxIdentical times, when the latest actions repeat a cycle
+// of up to MaxCycleLen actions more than MaxCycles times in a row, or after
+// MaxSteps actions. A stopped run's context is cancelled and a
+// *LoopDetectedError is returned. Nested guards all record each action.
+//
+// Example:
+//
+//	agent := ctrl.LoopGuard(researchAgent, ctrl.LoopLimits{MaxSteps: 30})
+//	flow.Use(ctrl.Budget(agent, ctrl.BudgetLimits{MaxCostUSD: 1}))
+func LoopGuard(handler calque.Handler, limits ...LoopLimits) calque.Handler {
+	cfg := LoopLimits{}
+	if len(limits) > 0 {
+		cfg = limits[0]
+	}
+	if cfg.MaxIdentical <= 0 {
+		cfg.MaxIdentical = DefaultMaxIdentical
+	}
+	if cfg.MaxCycles <= 0 {
+		cfg.MaxCycles = DefaultMaxCycles
+	}
+	if cfg.MaxCycleLen <= 0 {
+		cfg.MaxCycleLen = DefaultMaxCycleLen
+	}
+
+	return calque.Composite("ctrl.LoopGuard", calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		ctx, cancel := context.WithCancelCause(req.Context)
+		defer cancel(nil)
+
+		tracker := &loopTracker{
+			limits: cfg,
+			parent: loopFromContext(req.Context),
+			cancel: cancel,
+			counts: make(map[string]int),
+		}
+		req.Context = context.WithValue(ctx, loopContextKey{}, tracker)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- handler.ServeFlow(req, res)
+		}()
+
+		var err error
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+		}
+
+		if detected := tracker.detectedErr(); detected != nil {
+			return calque.WrapErr(req.Context, detected, "run aborted")
+		}
+		if err == nil && ctx.Err() != nil {
+			// parent context was cancelled before the handler returned
+			err = context.Cause(ctx)
+		}
+		return err
+	}), wrapGroup(handler))
+}
+
+// RecordStep records an action, such as a tool call or a hashed agent state,
+// against the run's loop guards.
+//
+// Returns a *LoopDetectedError if the action completes a loop, or nil when
+// no loop is found or no guard is in the context.
+//
+// Example:
+//
+//	if err := ctrl.RecordStep(req.Context, "plan:"+hash(state)); err != nil {
+//		return err
+//	}
+func RecordStep(ctx context.Context, action string) error {
+	var first error
+	for tracker := loopFromContext(ctx); tracker != nil; tracker = tracker.parent {
+		if err := tracker.record(action); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (t *loopTracker) record(action string) error {
+	t.mu.Lock()
+	t.steps = append(t.steps, action)
+	t.counts[action]++
+	detected := t.check(action)
+	if detected != nil && t.detected == nil {
+		t.detected = detected
+	}
+	t.mu.Unlock()
+
+	if detected != nil {
+		t.cancel(detected)
+		return detected
+	}
+	return nil
+}
+
+// check looks for a loop ending with action. Caller must hold mu.
+func (t *loopTracker) check(action string) *LoopDetectedError {
+	steps := len(t.steps)
+	if t.limits.MaxSteps > 0 && steps > t.limits.MaxSteps {
+		return &LoopDetectedError{Reason: LoopMaxSteps, Action: action, Count: t.counts[action], Steps: steps}
+	}
+	if count := t.counts[action]; count > t.limits.MaxIdentical {
+		return &LoopDetectedError{Reason: LoopRepeated, Action: action, Count: count, Steps: steps}
+	}
+	for n := 2; n <= t.limits.MaxCycleLen; n++ {
+		if repeats := t.trailingRepeats(n); repeats > t.limits.MaxCycles {
+			cycle := append([]string(nil), t.steps[steps-n:]...)
+			return &LoopDetectedError{Reason: LoopOscillating, Action: action, Cycle: cycle, Count: repeats, Steps: steps}
+		}
+	}
+	return nil
+}
+
+// trailingRepeats counts how often the last n steps repeat back to back,
+// ignoring cycles made of a single repeated action. Caller must hold mu.
+func (t *loopTracker) trailingRepeats(n int) int {
+	steps := t.steps
+	if len(steps) < 2*n {
+		return 1
+	}
+	cycle := steps[len(steps)-n:]
+	distinct := false
+	for _, s := range cycle[1:] {
+		if s != cycle[0] {
+			distinct = true
+			break
+		}
+	}
+	if !distinct {
+		return 1
+	}
+
+	repeats := 1
+	for end := len(steps) - n; end >= n; end -= n {
+		prev := steps[end-n : end]
+		for i := range prev {
+			if prev[i] != cycle[i] {
+				return repeats
+			}
+		}
+		repeats++
+	}
+	return repeats
+}
+
+func (t *loopTracker) detectedErr() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.detected == nil {
+		return nil
+	}
+	return t.detected
+}
+
+func loopFromContext(ctx context.Context) *loopTracker {
+	tracker, _ := ctx.Value(loopContextKey{}).(*loopTracker)
+	return tracker
+}
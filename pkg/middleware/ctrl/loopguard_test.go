@@ -0,0 +1,92 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// stepper records actions until one is refused, reporting how many were accepted
+func stepper(actions []string, accepted *int) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var in string
+		if err := calque.Read(req, &in); err != nil {
+			return err
+		}
+		for _, a := range actions {
+			if err := RecordStep(req.Context, a); err != nil {
+				return err
+			}
+			*accepted++
+		}
+		return calque.Write(res, in)
+	})
+}
+
+func runGuard(h calque.Handler) error {
+	var out strings.Builder
+	return h.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("go")), calque.NewResponse(&out))
+}
+
+func TestLoopGuard(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   LoopLimits
+		actions  []string
+		reason   string
+		accepted int
+	}{
+		{"distinct actions", LoopLimits{}, []string{"a", "b", "c", "a", "d"}, "", 5},
+		{"identical", LoopLimits{}, []string{"a", "b", "a", "c", "a", "a"}, LoopRepeated, 5},
+		{"oscillating", LoopLimits{}, []string{"x", "a", "b", "a", "b", "a", "b"}, LoopOscillating, 6},
+		{"long cycle", LoopLimits{MaxCycles: 1}, []string{"a", "b", "c", "a", "b", "c"}, LoopOscillating, 5},
+		{"max steps", LoopLimits{MaxSteps: 3}, []string{"a", "b", "c", "d"}, LoopMaxSteps, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accepted := 0
+			err := runGuard(LoopGuard(stepper(tt.actions, &accepted), tt.limits))
+			if accepted != tt.accepted {
+				t.Errorf("accepted %d actions, want %d", accepted, tt.accepted)
+			}
+			if tt.reason == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			var loopErr *LoopDetectedError
+			if !errors.As(err, &loopErr) || !errors.Is(err, ErrLoopDetected) || loopErr.Reason != tt.reason {
+				t.Fatalf("err = %v, want %s loop", err, tt.reason)
+			}
+			if loopErr.Action != tt.actions[tt.accepted] || loopErr.Steps != tt.accepted+1 {
+				t.Errorf("loop error = %+v", loopErr)
+			}
+		})
+	}
+}
+
+func TestLoopGuardCycle(t *testing.T) {
+	accepted := 0
+	err := runGuard(LoopGuard(stepper([]string{"a", "b", "a", "b", "a", "b"}, &accepted)))
+	var loopErr *LoopDetectedError
+	if !errors.As(err, &loopErr) || strings.Join(loopErr.Cycle, ",") != "a,b" || loopErr.Count != 3 {
+		t.Errorf("err = %v, want cycle [a b] repeated 3 times", err)
+	}
+}
+
+func TestLoopGuardNested(t *testing.T) {
+	accepted := 0
+	inner := LoopGuard(stepper([]string{"a", "b", "c"}, &accepted), LoopLimits{MaxSteps: 10})
+	err := runGuard(LoopGuard(inner, LoopLimits{MaxSteps: 2}))
+	if !errors.Is(err, ErrLoopDetected) || accepted != 2 {
+		t.Errorf("err = %v after %d actions, want the outer limit", err, accepted)
+	}
+
+	if err := RecordStep(context.Background(), "a"); err != nil {
+		t.Errorf("RecordStep without a guard = %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package ctrl
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// MaxBytes creates a size guard that aborts streams larger than n bytes.
+//
+// Input: any data type (streaming)
+// Output: same as input (pass-through up to the limit)
+// Behavior: STREAMING - data flows through until the limit is crossed
+//
+// Input is copied through unchanged while it stays within n bytes. The first
+// byte past the limit fails the handler with *calque.InputTooLargeError
+// (errors.Is matches calque.ErrInputTooLarge), aborting the flow before later
+// stages buffer the whole payload. Place it first to protect expensive
+// handlers such as ai.Agent from oversized uploads; FlowConfig.MaxInputBytes
+// applies the same guard to a whole flow.
+//
+// Example:
+//
+//	flow := calque.NewFlow().
+//		Use(ctrl.MaxBytes(1 << 20)). // 1 MB
+//		Use(ai.Agent(client))
+func MaxBytes(n int64) calque.Handler {
+	if n <= 0 {
+		return calque.HandlerFunc(func(r *calque.Request, _ *calque.Response) error {
+			return calque.NewErr(r.Context, fmt.Sprintf("invalid byte limit: must be greater than 0, got %d", n))
+		})
+	}
+
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		_, err := io.Copy(res.Data, calque.NewLimitReader(req.Data, n))
+		return err
+	})
+}
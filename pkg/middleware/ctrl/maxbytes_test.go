@@ -0,0 +1,44 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestMaxBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int64
+		input   string
+		wantErr bool
+	}{
+		{name: "under limit", limit: 10, input: "hello"},
+		{name: "exactly at limit", limit: 5, input: "hello"},
+		{name: "over limit", limit: 4, input: "hello", wantErr: true},
+		{name: "large stream", limit: 1024, input: strings.Repeat("x", 1<<20), wantErr: true},
+		{name: "invalid limit", limit: 0, input: "hello", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out string
+			err := calque.NewFlow().Use(MaxBytes(tt.limit)).Run(context.Background(), tt.input, &out)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				if tt.limit > 0 && !errors.Is(err, calque.ErrInputTooLarge) {
+					t.Errorf("error = %v, want ErrInputTooLarge", err)
+				}
+				return
+			}
+			if err != nil || out != tt.input {
+				t.Errorf("out = %q, err = %v", out, err)
+			}
+		})
+	}
+}
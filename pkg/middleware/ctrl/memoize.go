@@ -0,0 +1,127 @@
+package ctrl
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// memoEntry is one remembered output
+type memoEntry struct {
+	key     [sha256.Size]byte
+	output  []byte
+	expires time.Time
+}
+
+// memoizer is an LRU of handler outputs keyed by input hash
+type memoizer struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	order   *list.List // front = most recently used
+	entries map[[sha256.Size]byte]*list.Element
+}
+
+// Memoize remembers a handler's output per distinct input for ttl.
+//
+// Input: any data type (buffered - reads entire input to hash it)
+// Output: same as wrapped handler's output
+// Behavior: BUFFERED - replays the remembered output for a repeated input
+//
+// Unlike the cache package, the memory belongs to the returned handler: it is
+// not shared with other handlers, needs no Store, and is freed with the
+// handler. Use it for pure transformation stages, deterministic but expensive
+// (parsing, rendering, embedding lookups), whose output depends only on their
+// input. Outputs are remembered for ttl (0 = until evicted); beyond
+// maxEntries (0 = unbounded) the least recently used are evicted. Failed
+// calls are not remembered.
+//
+// Example:
+//
+//	render := ctrl.Memoize(markdownToHTML, 10*time.Minute, 1000)
+//	flow := calque.NewFlow().Use(render).Use(ai.Agent(client))
+func Memoize(handler calque.Handler, ttl time.Duration, maxEntries int) calque.Handler {
+	if ttl < 0 || maxEntries < 0 {
+		return calque.HandlerFunc(func(r *calque.Request, _ *calque.Response) error {
+			return calque.NewErr(r.Context, fmt.Sprintf("invalid memoize limits: ttl %v and maxEntries %d must not be negative", ttl, maxEntries))
+		})
+	}
+
+	m := &memoizer{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[[sha256.Size]byte]*list.Element),
+	}
+
+	return calque.Composite("ctrl.Memoize", calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		input, err := bufferInput(req)
+		if err != nil {
+			return err
+		}
+		defer input.Release()
+
+		key := sha256.Sum256(input.Bytes())
+		if output, ok := m.get(key); ok {
+			_, err := res.Data.Write(output)
+			return err
+		}
+
+		output := calque.NewMemoryBuffer(req.Context)
+		defer output.Release()
+		req.Data = bytes.NewReader(input.Bytes())
+		if err := handler.ServeFlow(req, &calque.Response{Data: output}); err != nil {
+			return err
+		}
+		m.put(key, bytes.Clone(output.Bytes()))
+		_, err = res.Data.Write(output.Bytes())
+		return err
+	}), wrapGroup(handler))
+}
+
+// get returns a live output, dropping it if expired
+func (m *memoizer) get(key [sha256.Size]byte) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	elem, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*memoEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		m.order.Remove(elem)
+		delete(m.entries, key)
+		return nil, false
+	}
+	m.order.MoveToFront(elem)
+	return entry.output, true
+}
+
+// put remembers an output, evicting the least recently used beyond maxEntries
+func (m *memoizer) put(key [sha256.Size]byte, output []byte) {
+	var expires time.Time
+	if m.ttl > 0 {
+		expires = time.Now().Add(m.ttl)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if elem, ok := m.entries[key]; ok {
+		entry := elem.Value.(*memoEntry)
+		entry.output, entry.expires = output, expires
+		m.order.MoveToFront(elem)
+		return
+	}
+	m.entries[key] = m.order.PushFront(&memoEntry{key: key, output: output, expires: expires})
+	for m.maxEntries > 0 && m.order.Len() > m.maxEntries {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoEntry).key)
+	}
+}
@@ -0,0 +1,99 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// countingUpper upper-cases its input and counts calls
+func countingUpper(calls *atomic.Int32) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		calls.Add(1)
+		data, err := io.ReadAll(req.Data)
+		if err != nil {
+			return err
+		}
+		_, err = res.Data.Write([]byte(strings.ToUpper(string(data))))
+		return err
+	})
+}
+
+func runMemo(t *testing.T, h calque.Handler, input string) string {
+	t.Helper()
+	var out string
+	if err := calque.NewFlow().Use(h).Run(context.Background(), input, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestMemoize(t *testing.T) {
+	var calls atomic.Int32
+	memo := Memoize(countingUpper(&calls), time.Minute, 0)
+
+	for range 3 {
+		if out := runMemo(t, memo, "hello"); out != "HELLO" {
+			t.Errorf("out = %q, want HELLO", out)
+		}
+	}
+	if out := runMemo(t, memo, "world"); out != "WORLD" {
+		t.Errorf("out = %q, want WORLD", out)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("calls = %d, want one per distinct input", got)
+	}
+
+	// Another instance keeps its own memory
+	var other atomic.Int32
+	runMemo(t, Memoize(countingUpper(&other), time.Minute, 0), "hello")
+	if other.Load() != 1 {
+		t.Error("memoized outputs leaked across handler instances")
+	}
+}
+
+func TestMemoizeTTLAndEviction(t *testing.T) {
+	var calls atomic.Int32
+	expiring := Memoize(countingUpper(&calls), 20*time.Millisecond, 0)
+	runMemo(t, expiring, "a")
+	time.Sleep(40 * time.Millisecond)
+	runMemo(t, expiring, "a")
+	if got := calls.Load(); got != 2 {
+		t.Errorf("calls = %d after ttl, want 2", got)
+	}
+
+	calls.Store(0)
+	bounded := Memoize(countingUpper(&calls), 0, 2)
+	for _, in := range []string{"a", "b", "a", "c", "a", "b"} {
+		runMemo(t, bounded, in)
+	}
+	// a stays recently used; b is evicted by c and recomputed
+	if got := calls.Load(); got != 4 {
+		t.Errorf("calls = %d, want 4 with LRU eviction", got)
+	}
+}
+
+func TestMemoizeSkipsErrors(t *testing.T) {
+	var calls atomic.Int32
+	failing := calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+		calls.Add(1)
+		_, _ = io.Copy(io.Discard, req.Data)
+		return errors.New("boom")
+	})
+	memo := Memoize(failing, time.Minute, 0)
+	for range 2 {
+		var out string
+		if err := calque.NewFlow().Use(memo).Run(context.Background(), "x", &out); err == nil {
+			t.Fatal("Run() error = nil")
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("calls = %d, want failures retried", got)
+	}
+}
@@ -0,0 +1,269 @@
+package ctrl
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+const (
+	// DefaultShadowTimeout bounds how long a shadow request may run after the primary completes.
+	DefaultShadowTimeout = 30 * time.Second
+
+	// DefaultShadowConcurrency caps the shadow requests running at once.
+	DefaultShadowConcurrency = 16
+)
+
+// ShadowConfig holds configuration for the Shadow middleware
+type ShadowConfig struct {
+	// Compare reports whether primary and shadow outputs are equivalent.
+	// Defaults to bytes.Equal on whitespace-trimmed outputs.
+	Compare func(primary, shadow []byte) bool
+	// Timeout bounds the shadow request (defaults to DefaultShadowTimeout)
+	Timeout time.Duration
+	// MaxConcurrent caps the shadow requests running at once; requests past
+	// it are not mirrored (defaults to DefaultShadowConcurrency)
+	MaxConcurrent int
+	// OnResult is called after each shadow comparison (optional, runs in the shadow goroutine)
+	OnResult func(ShadowResult)
+}
+
+// ShadowResult describes the outcome of a single mirrored request.
+type ShadowResult struct {
+	Match           bool
+	PrimaryOutput   []byte
+	ShadowOutput    []byte
+	ShadowErr       error
+	PrimaryDuration time.Duration
+	ShadowDuration  time.Duration
+}
+
+// ShadowStats contains divergence counters collected by a shadow handler.
+type ShadowStats struct {
+	Requests      int64 // requests mirrored to the shadow handler
+	Matches       int64 // shadow output matched primary output
+	Mismatches    int64 // shadow output diverged from primary output
+	ShadowErrors  int64 // shadow handler returned an error
+	PrimaryErrors int64 // primary failed, so the request was not mirrored
+	Dropped       int64 // not mirrored: MaxConcurrent shadows were running or the copies hit the memory cap
+}
+
+// ShadowHandler serves responses from a primary handler while mirroring traffic to a shadow.
+type ShadowHandler struct {
+	primary calque.Handler
+	shadow  calque.Handler
+	config  ShadowConfig
+	slots   chan struct{} // one per running shadow, up to MaxConcurrent
+
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	stats ShadowStats
+}
+
+// Shadow mirrors traffic to a shadow handler while serving responses from primary.
+//
+// Input: any data type (streaming to primary - a copy is buffered for the shadow)
+// Output: response from primary handler only
+// Behavior: STREAMING - primary streams normally, shadow runs asynchronously afterwards
+//
+// The primary handler's output is returned to the caller unchanged. Once primary
+// succeeds, the buffered input is sent to the shadow handler in the background and
+// the outputs are compared. Shadow errors and latency never affect the caller.
+// The shadow runs in a fresh context carrying only the caller's trace and
+// tenant IDs, so it cannot publish metadata, spend the caller's budget or
+// record usage against the run. At most ShadowConfig.MaxConcurrent shadows
+// run at once; requests past the cap are served but not mirrored.
+//
+// The buffered input and primary output are charged to the run's memory
+// account (see calque.WithMemoryLimit) until the shadow finishes; a request
+// whose copies would cross the cap is served but not mirrored. Divergence
+// counters are available via Stats. Use this to validate a new model or
+// prompt against production traffic before switching over.
+//
+// Example:
+//
+//	shadow := ctrl.Shadow(ai.Agent(currentClient), ai.Agent(candidateClient))
+//	flow.Use(shadow)
+//
+//	// later
+//	s := shadow.Stats()
+//	fmt.Printf("divergence: %d/%d\n", s.Mismatches, s.Requests)
+func Shadow(primary, shadow calque.Handler) *ShadowHandler {
+	return ShadowWithConfig(primary, shadow, &ShadowConfig{})
+}
+
+// ShadowWithConfig mirrors traffic to a shadow handler with custom configuration.
+//
+// Input: any data type (streaming to primary - a copy is buffered for the shadow)
+// Output: response from primary handler only
+// Behavior: STREAMING - primary streams normally, shadow runs asynchronously afterwards
+//
+// Example:
+//
+//	shadow := ctrl.ShadowWithConfig(primary, candidate, &ctrl.ShadowConfig{
+//		Timeout: 10 * time.Second,
+//		Compare: func(a, b []byte) bool { return strings.EqualFold(string(a), string(b)) },
+//		OnResult: func(r ctrl.ShadowResult) {
+//			if !r.Match {
+//				log.Printf("divergence: %q vs %q", r.PrimaryOutput, r.ShadowOutput)
+//			}
+//		},
+//	})
+func ShadowWithConfig(primary, shadow calque.Handler, config *ShadowConfig) *ShadowHandler {
+	cfg := ShadowConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Compare == nil {
+		cfg.Compare = func(a, b []byte) bool {
+			return bytes.Equal(bytes.TrimSpace(a), bytes.TrimSpace(b))
+		}
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultShadowTimeout
+	}
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = DefaultShadowConcurrency
+	}
+
+	return &ShadowHandler{
+		primary: primary,
+		shadow:  shadow,
+		config:  cfg,
+		slots:   make(chan struct{}, cfg.MaxConcurrent),
+	}
+}
+
+// ServeFlow implements the calque.Handler interface.
+func (s *ShadowHandler) ServeFlow(req *calque.Request, res *calque.Response) error {
+	inputCopy := &shadowCopy{buf: calque.NewMemoryBuffer(req.Context)}
+	outputCopy := &shadowCopy{buf: calque.NewMemoryBuffer(req.Context)}
+	release := func() {
+		inputCopy.buf.Release()
+		outputCopy.buf.Release()
+	}
+
+	primaryReq := &calque.Request{Context: req.Context, Data: io.TeeReader(req.Data, inputCopy)}
+	primaryRes := &calque.Response{Data: io.MultiWriter(res.Data, outputCopy)}
+
+	start := time.Now()
+	if err := s.primary.ServeFlow(primaryReq, primaryRes); err != nil {
+		release()
+		s.mu.Lock()
+		s.stats.PrimaryErrors++
+		s.mu.Unlock()
+		return err
+	}
+	primaryDuration := time.Since(start)
+
+	mirrored := !inputCopy.over && !outputCopy.over
+	if mirrored {
+		select {
+		case s.slots <- struct{}{}:
+		default:
+			mirrored = false
+		}
+	}
+	if !mirrored {
+		release()
+		s.mu.Lock()
+		s.stats.Dropped++
+		s.mu.Unlock()
+		return nil
+	}
+
+	// A fresh context outlives the flow and shares none of its per-run state
+	shadowCtx := calque.WithTenant(calque.WithTraceID(context.Background(), calque.TraceID(req.Context)), calque.TenantID(req.Context))
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() { <-s.slots }()
+		defer release()
+		s.runShadow(shadowCtx, inputCopy.buf.Bytes(), outputCopy.buf.Bytes(), primaryDuration)
+	}()
+
+	return nil
+}
+
+// shadowCopy buffers a copy for the shadow, giving up once the run's memory
+// cap is reached; its writes never fail so the primary is unaffected
+type shadowCopy struct {
+	buf  *calque.MemoryBuffer
+	over bool // the copy hit the memory cap and was dropped
+}
+
+func (c *shadowCopy) Write(p []byte) (int, error) {
+	if c.over {
+		return len(p), nil
+	}
+	if _, err := c.buf.Write(p); err != nil {
+		c.over = true
+		c.buf.Release()
+	}
+	return len(p), nil
+}
+
+// runShadow executes the shadow handler and records the comparison
+func (s *ShadowHandler) runShadow(ctx context.Context, input, primaryOutput []byte, primaryDuration time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	start := time.Now()
+	err := s.shadow.ServeFlow(calque.NewRequest(ctx, bytes.NewReader(input)), calque.NewResponse(&output))
+
+	result := ShadowResult{
+		PrimaryOutput:   primaryOutput,
+		ShadowOutput:    output.Bytes(),
+		ShadowErr:       err,
+		PrimaryDuration: primaryDuration,
+		ShadowDuration:  time.Since(start),
+	}
+	if err == nil {
+		result.Match = s.config.Compare(primaryOutput, result.ShadowOutput)
+	}
+
+	s.mu.Lock()
+	s.stats.Requests++
+	switch {
+	case err != nil:
+		s.stats.ShadowErrors++
+	case result.Match:
+		s.stats.Matches++
+	default:
+		s.stats.Mismatches++
+	}
+	s.mu.Unlock()
+
+	if s.config.OnResult != nil {
+		s.config.OnResult(result)
+	}
+}
+
+// Group implements calque.Grouper; the shadow receives a copy of the input.
+func (s *ShadowHandler) Group() calque.Group {
+	return calque.Group{
+		Kind:     calque.GroupParallel,
+		Children: []calque.Handler{s.primary, s.shadow},
+		Labels:   []string{"primary", "shadow"},
+	}
+}
+
+// Stats returns a snapshot of the divergence counters.
+func (s *ShadowHandler) Stats() ShadowStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Wait blocks until all in-flight shadow requests have completed.
+//
+// Call during graceful shutdown so mirrored requests are not dropped.
+func (s *ShadowHandler) Wait() {
+	s.wg.Wait()
+}
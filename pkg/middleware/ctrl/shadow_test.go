@@ -0,0 +1,228 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestShadow(t *testing.T) {
+	failing := calque.HandlerFunc(func(_ *calque.Request, _ *calque.Response) error {
+		return errors.New("shadow failed")
+	})
+
+	tests := []struct {
+		name      string
+		shadow    calque.Handler
+		expected  ShadowStats
+		wantMatch bool
+	}{
+		{
+			name:      "matching shadow",
+			shadow:    prefixHandler("p:"),
+			expected:  ShadowStats{Requests: 1, Matches: 1},
+			wantMatch: true,
+		},
+		{
+			name:     "diverging shadow",
+			shadow:   prefixHandler("s:"),
+			expected: ShadowStats{Requests: 1, Mismatches: 1},
+		},
+		{
+			name:     "failing shadow does not affect caller",
+			shadow:   failing,
+			expected: ShadowStats{Requests: 1, ShadowErrors: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var results []ShadowResult
+			var mu sync.Mutex
+
+			h := ShadowWithConfig(prefixHandler("p:"), tt.shadow, &ShadowConfig{
+				OnResult: func(r ShadowResult) {
+					mu.Lock()
+					results = append(results, r)
+					mu.Unlock()
+				},
+			})
+
+			var out strings.Builder
+			err := h.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("input")), calque.NewResponse(&out))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != "p:input" {
+				t.Errorf("expected primary output, got %q", out.String())
+			}
+
+			h.Wait()
+
+			if got := h.Stats(); got != tt.expected {
+				t.Errorf("expected stats %+v, got %+v", tt.expected, got)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(results) != 1 {
+				t.Fatalf("expected 1 result, got %d", len(results))
+			}
+			if results[0].Match != tt.wantMatch {
+				t.Errorf("expected match=%v, got %v", tt.wantMatch, results[0].Match)
+			}
+		})
+	}
+}
+
+func TestShadowPrimaryError(t *testing.T) {
+	shadowCalled := false
+	shadow := calque.HandlerFunc(func(_ *calque.Request, _ *calque.Response) error {
+		shadowCalled = true
+		return nil
+	})
+	primary := calque.HandlerFunc(func(_ *calque.Request, _ *calque.Response) error {
+		return errors.New("primary failed")
+	})
+
+	h := Shadow(primary, shadow)
+	var out strings.Builder
+	err := h.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("x")), calque.NewResponse(&out))
+	if err == nil || !strings.Contains(err.Error(), "primary failed") {
+		t.Fatalf("expected primary error, got %v", err)
+	}
+
+	h.Wait()
+	if shadowCalled {
+		t.Error("shadow should not run when primary fails")
+	}
+	if got := h.Stats().PrimaryErrors; got != 1 {
+		t.Errorf("expected 1 primary error, got %d", got)
+	}
+}
+
+func TestShadowSurvivesCallerCancellation(t *testing.T) {
+	shadow := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		time.Sleep(20 * time.Millisecond)
+		if err := req.Context.Err(); err != nil {
+			return err
+		}
+		return calque.Write(res, "p:x")
+	})
+
+	h := Shadow(prefixHandler("p:"), shadow)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var out strings.Builder
+	if err := h.ServeFlow(calque.NewRequest(ctx, strings.NewReader("x")), calque.NewResponse(&out)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cancel()
+	h.Wait()
+
+	if got := h.Stats(); got.Matches != 1 {
+		t.Errorf("expected shadow to complete after caller cancellation, got %+v", got)
+	}
+}
+
+func TestShadowInFlow(t *testing.T) {
+	h := Shadow(prefixHandler("p:"), prefixHandler("p:"))
+	flow := calque.NewFlow().Use(h)
+
+	var result string
+	if err := flow.Run(context.Background(), "hello", &result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "p:hello" {
+		t.Errorf("expected 'p:hello', got %q", result)
+	}
+
+	h.Wait()
+	if got := h.Stats(); got.Matches != 1 {
+		t.Errorf("expected 1 match, got %+v", got)
+	}
+}
+
+func TestShadowContext(t *testing.T) {
+	type runKey struct{}
+	var traceID, tenant string
+	var runValue any
+	shadow := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		traceID, tenant = calque.TraceID(req.Context), calque.TenantID(req.Context)
+		runValue = req.Context.Value(runKey{})
+		return calque.Write(res, "p:x")
+	})
+
+	h := Shadow(prefixHandler("p:"), shadow)
+	ctx := calque.WithTenant(calque.WithTraceID(context.Background(), "trace-1"), "acme")
+	ctx = context.WithValue(ctx, runKey{}, "per-run state")
+
+	var out strings.Builder
+	if err := h.ServeFlow(calque.NewRequest(ctx, strings.NewReader("x")), calque.NewResponse(&out)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	h.Wait()
+
+	if traceID != "trace-1" || tenant != "acme" {
+		t.Errorf("shadow trace = %q, tenant = %q, want the caller's", traceID, tenant)
+	}
+	if runValue != nil {
+		t.Errorf("shadow inherited the caller's context value %v", runValue)
+	}
+}
+
+func TestShadowConcurrencyLimit(t *testing.T) {
+	release := make(chan struct{})
+	shadow := calque.HandlerFunc(func(_ *calque.Request, res *calque.Response) error {
+		<-release
+		return calque.Write(res, "p:x")
+	})
+
+	h := ShadowWithConfig(prefixHandler("p:"), shadow, &ShadowConfig{MaxConcurrent: 2})
+	for range 5 {
+		var out strings.Builder
+		if err := h.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("x")), calque.NewResponse(&out)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out.String() != "p:x" {
+			t.Errorf("primary output = %q while shadows were saturated", out.String())
+		}
+	}
+	close(release)
+	h.Wait()
+
+	if got := h.Stats(); got.Requests != 2 || got.Dropped != 3 {
+		t.Errorf("stats = %+v, want 2 mirrored and 3 dropped", got)
+	}
+}
+
+func TestShadowMemoryLimit(t *testing.T) {
+	h := Shadow(prefixHandler("p:"), prefixHandler("p:"))
+
+	ctx := calque.WithMemoryLimit(context.Background(), 16)
+	var out strings.Builder
+	input := strings.Repeat("x", 32)
+	if err := h.ServeFlow(calque.NewRequest(ctx, strings.NewReader(input)), calque.NewResponse(&out)); err != nil {
+		t.Fatalf("copies over the memory cap failed the primary: %v", err)
+	}
+	if out.String() != "p:"+input {
+		t.Errorf("primary output = %q", out.String())
+	}
+
+	if err := h.ServeFlow(calque.NewRequest(ctx, strings.NewReader("x")), calque.NewResponse(&out)); err != nil {
+		t.Fatal(err)
+	}
+	h.Wait()
+
+	if got := h.Stats(); got.Requests != 1 || got.Dropped != 1 {
+		t.Errorf("stats = %+v, want 1 mirrored and 1 dropped", got)
+	}
+	if usage, _ := calque.MemoryUsed(ctx); usage.Used != 0 {
+		t.Errorf("memory still reserved after shadows finished: %+v", usage)
+	}
+}
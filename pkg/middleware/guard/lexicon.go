@@ -0,0 +1,262 @@
+package guard
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// ErrLexiconMatch matches any *LexiconError with errors.Is.
+var ErrLexiconMatch = errors.New("blocked term")
+
+// DefaultLexicons are starter profanity lists by locale. They are short on
+// purpose; load a full or brand-specific dictionary with LoadLexicon.
+//
+// A term ending in "*" matches every word starting with the rest of it.
+var DefaultLexicons = map[string][]string{
+	"en": {"fuck*", "motherfuck*", "shit", "shits", "shitty", "bullshit", "bitch", "bitches", "cunt*", "asshole*",
+		"bastard", "bastards", "dickhead*", "twat*", "wanker*", "piss", "pissed", "crap"},
+	"es": {"mierda", "joder", "jodido", "jodida", "puta", "putas", "puto", "putos", "coño", "cabrón", "cabrones",
+		"gilipollas", "pendejo", "pendejos", "chingar", "chingada", "verga", "culero"},
+	"fr": {"merde", "putain", "pute", "putes", "connard", "connards", "connasse", "salope", "salopes",
+		"enculé", "encule", "niquer", "nique", "bâtard", "batard", "foutre"},
+	"de": {"scheiße", "scheisse", "scheiß*", "arschloch", "arschlöcher", "fotze", "wichser", "hurensohn", "fick*"},
+	"pt": {"merda", "caralho", "porra", "puta", "putas", "foda", "fodase", "buceta", "cacete", "cu"},
+}
+
+// LexiconMatch is one flagged word found by Lexicon.
+type LexiconMatch struct {
+	Term   string // lexicon entry that matched, e.g. "fuck*"
+	Word   string // word as it appeared in the content
+	Offset int    // byte offset of the word in the content
+}
+
+// LexiconError is returned when Lexicon blocks content.
+type LexiconError struct {
+	Match LexiconMatch
+}
+
+// Error implements error.
+func (e *LexiconError) Error() string {
+	return fmt.Sprintf("blocked term: %s", e.Match.Term)
+}
+
+// Is reports whether target is ErrLexiconMatch.
+func (e *LexiconError) Is(target error) bool {
+	return target == ErrLexiconMatch
+}
+
+// LexiconConfig configures Lexicon.
+type LexiconConfig struct {
+	Locales     []string                                  // DefaultLexicons to apply, e.g. "es" or "pt-BR" (nil = "en"; empty = none)
+	Terms       []string                                  // custom single-word terms, e.g. competitor names
+	Allow       []string                                  // words never flagged, for terms ending in "*"
+	Replacement string                                    // redaction text (default: one "*" per character)
+	OnMatch     func(ctx context.Context, m LexiconMatch) // called for every match (default: logged as a warning)
+}
+
+// Lexicon flags words found in profanity and brand-safety word lists.
+//
+// Input: string content (streaming)
+// Output: the input, with flagged words replaced when action is Redact
+// Behavior: STREAMING - text is passed on word by word; only the word being
+// read is held back
+//
+// Words are compared after NFC normalization and case folding, so
+// "SHIT" and "Shit" match "shit". Matching is per word, never inside one: "*"
+// terms match prefixes, and Allow lists the words they must not catch. Block
+// fails at the first match with a *LexiconError classified as invalid input;
+// text before it has already been passed on, so buffer after Lexicon when
+// nothing may leave a blocked response. This is a cheap first line before,
+// or instead of, model-based moderation.
+//
+// Example:
+//
+//	lexicon, _ := guard.LoadLexicon(brandTerms)
+//	flow.Use(ai.Agent(client)).
+//		Use(guard.Lexicon(guard.Redact, guard.LexiconConfig{
+//			Locales: []string{"en", "es"},
+//			Terms:   lexicon,
+//		}))
+func Lexicon(action Action, config ...LexiconConfig) calque.Handler {
+	cfg := LexiconConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	lex := newLexicon(cfg)
+
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		reader := bufio.NewReader(req.Data)
+		writer := bufio.NewWriter(res.Data)
+		fold := cases.Fold() // a Caser must not be shared between runs
+
+		var word strings.Builder
+		offset, start := 0, 0
+
+		// flushWord writes the pending word, checking it first
+		flushWord := func() error {
+			if word.Len() == 0 {
+				return nil
+			}
+			w := word.String()
+			word.Reset()
+			term, ok := lex.match(fold, w)
+			if !ok {
+				_, err := writer.WriteString(w)
+				return err
+			}
+
+			m := LexiconMatch{Term: term, Word: w, Offset: start}
+			if cfg.OnMatch != nil {
+				cfg.OnMatch(req.Context, m)
+			} else {
+				calque.Logger(req.Context).Warn("blocked term", "term", m.Term, "offset", m.Offset)
+			}
+			switch action {
+			case Block:
+				if err := writer.Flush(); err != nil {
+					return err
+				}
+				return calque.InvalidInput(calque.WrapErr(req.Context, &LexiconError{Match: m}, "content blocked"))
+			case Redact:
+				replacement := cfg.Replacement
+				if replacement == "" {
+					replacement = strings.Repeat("*", utf8.RuneCountInString(w))
+				}
+				_, err := writer.WriteString(replacement)
+				return err
+			default:
+				_, err := writer.WriteString(w)
+				return err
+			}
+		}
+
+		for {
+			r, size, err := reader.ReadRune()
+			if err == io.EOF {
+				if err := flushWord(); err != nil {
+					return err
+				}
+				return writer.Flush()
+			}
+			if err != nil {
+				return err
+			}
+
+			if isWordRune(r) {
+				if word.Len() == 0 {
+					start = offset
+				}
+				word.WriteRune(r)
+			} else {
+				if err := flushWord(); err != nil {
+					return err
+				}
+				if _, err := writer.WriteRune(r); err != nil {
+					return err
+				}
+			}
+			offset += size
+
+			if word.Len() == 0 && reader.Buffered() == 0 {
+				if err := writer.Flush(); err != nil {
+					return err
+				}
+			}
+		}
+	})
+}
+
+// LoadLexicon reads a custom dictionary with one term per line. Blank lines
+// and lines starting with "#" are skipped.
+//
+// Example:
+//
+//	f, _ := os.Open("brand-safety.txt")
+//	terms, err := guard.LoadLexicon(f)
+func LoadLexicon(r io.Reader) ([]string, error) {
+	var terms []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		terms = append(terms, line)
+	}
+	return terms, scanner.Err()
+}
+
+// lexicon is a compiled term list
+type lexicon struct {
+	exact    map[string]string // folded word -> term
+	prefixes [][2]string       // folded prefix, term
+	allow    map[string]bool
+}
+
+func newLexicon(cfg LexiconConfig) *lexicon {
+	locales := cfg.Locales
+	if locales == nil {
+		locales = []string{"en"}
+	}
+	lex := &lexicon{exact: make(map[string]string), allow: make(map[string]bool)}
+	fold := cases.Fold()
+
+	add := func(term string) {
+		if prefix, ok := strings.CutSuffix(term, "*"); ok {
+			lex.prefixes = append(lex.prefixes, [2]string{normalizeWord(fold, prefix), term})
+			return
+		}
+		lex.exact[normalizeWord(fold, term)] = term
+	}
+	for _, locale := range locales {
+		language, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(locale, "_", "-")), "-")
+		for _, term := range DefaultLexicons[language] {
+			add(term)
+		}
+	}
+	for _, term := range cfg.Terms {
+		add(term)
+	}
+	for _, word := range cfg.Allow {
+		lex.allow[normalizeWord(fold, word)] = true
+	}
+	return lex
+}
+
+// match returns the term flagging word, if any
+func (l *lexicon) match(fold cases.Caser, word string) (string, bool) {
+	w := normalizeWord(fold, word)
+	if l.allow[w] {
+		return "", false
+	}
+	if term, ok := l.exact[w]; ok {
+		return term, true
+	}
+	for _, p := range l.prefixes {
+		if strings.HasPrefix(w, p[0]) {
+			return p[1], true
+		}
+	}
+	return "", false
+}
+
+// normalizeWord puts s in the form terms are compared in
+func normalizeWord(fold cases.Caser, s string) string {
+	return fold.String(norm.NFC.String(s))
+}
+
+// isWordRune reports the characters words are made of
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
@@ -0,0 +1,107 @@
+package guard
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestLexicon(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   LexiconConfig
+		input    string
+		expected string
+	}{
+		{name: "exact word", input: "well shit, that broke", expected: "well ****, that broke"},
+		{name: "case folded", input: "SHIT happens", expected: "**** happens"},
+		{name: "prefix term", input: "fucking great", expected: "******* great"},
+		{name: "no match inside words", input: "Scunthorpe and cocktails", expected: "Scunthorpe and cocktails"},
+		{name: "locale list", config: LexiconConfig{Locales: []string{"es-MX"}}, input: "¡Qué mierda!", expected: "¡Qué ******!"},
+		{name: "locale not enabled", config: LexiconConfig{Locales: []string{"en"}}, input: "quelle merde", expected: "quelle merde"},
+		{name: "german folding", config: LexiconConfig{Locales: []string{"de"}}, input: "SCHEISSE und Scheiße", expected: "******** und *******"},
+		{name: "custom terms", config: LexiconConfig{Locales: []string{}, Terms: []string{"Acme"}}, input: "Try ACME today, not shit", expected: "Try **** today, not shit"},
+		{name: "allow list", config: LexiconConfig{Terms: []string{"ass*"}, Allow: []string{"assess"}}, input: "assess the asshat", expected: "assess the ******"},
+		{name: "replacement", config: LexiconConfig{Replacement: "[removed]"}, input: "oh crap.", expected: "oh [removed]."},
+		{name: "decomposed accents", config: LexiconConfig{Locales: []string{"fr"}}, input: "encule\u0301!", expected: "*******!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := runScan(t, Lexicon(Redact, tt.config), tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tt.expected {
+				t.Errorf("Lexicon() = %q, want %q", out, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLexiconBlock(t *testing.T) {
+	var matches []LexiconMatch
+	out, err := runScan(t, Lexicon(Block, LexiconConfig{OnMatch: func(_ context.Context, m LexiconMatch) {
+		matches = append(matches, m)
+	}}), "this is bullshit and more")
+
+	if !errors.Is(err, ErrLexiconMatch) || !calque.IsInvalidInput(err) {
+		t.Fatalf("error = %v, want invalid-input ErrLexiconMatch", err)
+	}
+	if out != "this is " {
+		t.Errorf("output = %q, want text before the match", out)
+	}
+	if len(matches) != 1 || matches[0].Term != "bullshit" || matches[0].Offset != 8 {
+		t.Errorf("matches = %+v", matches)
+	}
+}
+
+func TestLexiconReport(t *testing.T) {
+	var matches []LexiconMatch
+	input := "crap, piss and crap"
+	out, err := runScan(t, Lexicon(Report, LexiconConfig{OnMatch: func(_ context.Context, m LexiconMatch) {
+		matches = append(matches, m)
+	}}), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != input {
+		t.Errorf("output = %q, want input unchanged", out)
+	}
+	if len(matches) != 3 || matches[2].Offset != 15 || matches[1].Word != "piss" {
+		t.Errorf("matches = %+v", matches)
+	}
+}
+
+func TestLexiconStreaming(t *testing.T) {
+	pr, pw := io.Pipe()
+	go func() {
+		// the flagged word is split across writes
+		for _, chunk := range []string{"what the fu", "ck is ", "this"} {
+			_, _ = pw.Write([]byte(chunk))
+		}
+		_ = pw.Close()
+	}()
+
+	var out strings.Builder
+	if err := Lexicon(Redact).ServeFlow(calque.NewRequest(context.Background(), pr), calque.NewResponse(&out)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "what the **** is this" {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func TestLoadLexicon(t *testing.T) {
+	terms, err := LoadLexicon(strings.NewReader("# competitors\nAcme\n\n  Globex*  \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(terms, ",") != "Acme,Globex*" {
+		t.Errorf("terms = %v", terms)
+	}
+}
@@ -0,0 +1,229 @@
+// Package guard stops sensitive or unwanted content from flowing through a
+// flow, in either direction: into model prompts or out of tools and models.
+//
+// SecretScan detects credentials such as API keys, tokens and private keys,
+// and blocks, redacts or reports them. ScanTool applies the same scan to the
+// output of a tool before the model sees it. Lexicon flags profanity and
+// brand-unsafe words from per-locale and custom word lists.
+//
+// Example:
+//
+//	flow := calque.NewFlow().
+//		Use(guard.SecretScan(guard.Block)).
+//		Use(ai.Agent(client, ai.WithTools(guard.ScanTool(shell, guard.Redact))))
+package guard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+	"github.com/calque-ai/go-calque/pkg/secrets"
+)
+
+// Action is what a guard does with content it flags.
+type Action int
+
+// Guard actions
+const (
+	Block  Action = iota // fail the run with an invalid-input error
+	Redact               // replace the flagged content and continue
+	Report               // pass the content through unchanged and report it
+)
+
+// ErrSecretDetected matches any *SecretError with errors.Is.
+var ErrSecretDetected = errors.New("secret detected")
+
+// SecretRule describes one kind of secret.
+type SecretRule struct {
+	ID      string         // short identifier, e.g. "aws-access-key"
+	Pattern *regexp.Regexp // matches the secret, or its surroundings with Group
+	Group   int            // submatch holding the secret (0 = whole match)
+}
+
+// DefaultSecretRules detect common credentials, after gitleaks' rules.
+var DefaultSecretRules = []SecretRule{
+	{ID: "private-key", Pattern: regexp.MustCompile(`-----BEGIN[A-Z ]*PRIVATE KEY( BLOCK)?-----[\s\S]*?-----END[A-Z ]*PRIVATE KEY( BLOCK)?-----`)},
+	{ID: "aws-access-key", Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[A-Z2-7]{16}\b`)},
+	{ID: "github-token", Pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{82})\b`)},
+	{ID: "gitlab-token", Pattern: regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20}\b`)},
+	{ID: "slack-token", Pattern: regexp.MustCompile(`\bxox[baprs]-[A-Za-z0-9-]{10,}\b`)},
+	{ID: "anthropic-api-key", Pattern: regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{32,}`)},
+	{ID: "openai-api-key", Pattern: regexp.MustCompile(`\bsk-(?:proj-|svcacct-|admin-)?[A-Za-z0-9_-]{20,}T3BlbkFJ[A-Za-z0-9_-]{20,}|\bsk-proj-[A-Za-z0-9_-]{40,}`)},
+	{ID: "google-api-key", Pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{ID: "stripe-key", Pattern: regexp.MustCompile(`\b(?:sk|rk)_(?:live|test)_[0-9A-Za-z]{24,}\b`)},
+	{ID: "jwt", Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{ID: "generic-secret", Pattern: regexp.MustCompile(`(?i)\b(?:api[_-]?key|secret|token|passw(?:or)?d|access[_-]?key)\b["']?\s*[:=]\s*["']?([A-Za-z0-9_\-+/=.]{16,})`), Group: 1},
+}
+
+// SecretFinding is one secret found by SecretScan.
+type SecretFinding struct {
+	Rule   string // SecretRule.ID
+	Offset int    // byte offset of the secret in the scanned content
+	Length int    // length of the secret in bytes
+	Masked string // first characters of the secret followed by "…"
+}
+
+// SecretError is returned when SecretScan blocks content.
+type SecretError struct {
+	Findings []SecretFinding
+}
+
+// Error implements error without repeating the secrets.
+func (e *SecretError) Error() string {
+	rules := make([]string, 0, len(e.Findings))
+	for _, f := range e.Findings {
+		rules = append(rules, f.Rule)
+	}
+	return fmt.Sprintf("secret detected: %s", strings.Join(rules, ", "))
+}
+
+// Is reports whether target is ErrSecretDetected.
+func (e *SecretError) Is(target error) bool {
+	return target == ErrSecretDetected
+}
+
+// SecretConfig configures SecretScan.
+type SecretConfig struct {
+	Rules       []SecretRule                               // rules to apply (nil = DefaultSecretRules)
+	Replacement string                                     // redaction text (default secrets.Redacted)
+	OnFinding   func(ctx context.Context, f SecretFinding) // called for every finding (default: logged as a warning)
+}
+
+// SecretScan detects credentials in the stream.
+//
+// Input: string content (buffered)
+// Output: the input, with secrets replaced when action is Redact
+// Behavior: BUFFERED - the whole input is scanned before any of it is written,
+// so a blocked secret never leaves the stage
+//
+// Every action reports findings to OnFinding. Block then fails with a
+// *SecretError classified as invalid input, so ctrl.Retry and ctrl.Fallback
+// do not resend it; Redact replaces each secret with Replacement; Report
+// passes the input through. Findings carry a masked preview, never the full
+// secret.
+//
+// Example:
+//
+//	// Keep credentials pasted by users out of model calls
+//	flow.Use(guard.SecretScan(guard.Redact)).
+//		Use(ai.Agent(client))
+func SecretScan(action Action, config ...SecretConfig) calque.Handler {
+	cfg := SecretConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Rules == nil {
+		cfg.Rules = DefaultSecretRules
+	}
+	if cfg.Replacement == "" {
+		cfg.Replacement = secrets.Redacted
+	}
+
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var input string
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+
+		findings := FindSecrets(input, cfg.Rules)
+		for _, f := range findings {
+			if cfg.OnFinding != nil {
+				cfg.OnFinding(req.Context, f)
+			} else {
+				calque.Logger(req.Context).Warn("secret detected", "rule", f.Rule, "offset", f.Offset, "masked", f.Masked)
+			}
+		}
+		if len(findings) == 0 {
+			return calque.Write(res, input)
+		}
+
+		switch action {
+		case Block:
+			return calque.InvalidInput(calque.WrapErr(req.Context, &SecretError{Findings: findings}, "content blocked"))
+		case Redact:
+			return calque.Write(res, redact(input, findings, cfg.Replacement))
+		default:
+			return calque.Write(res, input)
+		}
+	})
+}
+
+// ScanTool applies SecretScan to the output of tool, keeping its name,
+// description, schema and required scopes.
+//
+// Example:
+//
+//	agent := ai.Agent(client, ai.WithTools(guard.ScanTool(readFile, guard.Redact)))
+func ScanTool(tool tools.Tool, action Action, config ...SecretConfig) tools.Tool {
+	scanned := tools.New(tool.Name(), tool.Description(), tool.ParametersSchema(),
+		calque.NewFlow().Use(tool).Use(SecretScan(action, config...)))
+	if scopes := tools.RequiredScopes(tool); len(scopes) > 0 {
+		return tools.RequireScopes(scanned, scopes...)
+	}
+	return scanned
+}
+
+// FindSecrets returns the secrets in s matched by rules, ordered by offset.
+// Overlapping matches keep the earliest, longest one.
+func FindSecrets(s string, rules []SecretRule) []SecretFinding {
+	var findings []SecretFinding
+	for _, rule := range rules {
+		for _, loc := range rule.Pattern.FindAllStringSubmatchIndex(s, -1) {
+			start, end := loc[0], loc[1]
+			if g := rule.Group; g > 0 && 2*g+1 < len(loc) && loc[2*g] >= 0 {
+				start, end = loc[2*g], loc[2*g+1]
+			}
+			findings = append(findings, SecretFinding{
+				Rule:   rule.ID,
+				Offset: start,
+				Length: end - start,
+				Masked: mask(s[start:end]),
+			})
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Offset != findings[j].Offset {
+			return findings[i].Offset < findings[j].Offset
+		}
+		return findings[i].Length > findings[j].Length
+	})
+	kept := findings[:0]
+	end := -1
+	for _, f := range findings {
+		if f.Offset < end {
+			continue // inside an earlier finding
+		}
+		kept = append(kept, f)
+		end = f.Offset + f.Length
+	}
+	return kept
+}
+
+// redact replaces each finding in s, which must not overlap
+func redact(s string, findings []SecretFinding, replacement string) string {
+	var b strings.Builder
+	last := 0
+	for _, f := range findings {
+		b.WriteString(s[last:f.Offset])
+		b.WriteString(replacement)
+		last = f.Offset + f.Length
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// mask keeps enough of a secret to recognise its kind
+func mask(secret string) string {
+	const visible = 4
+	if len(secret) <= 2*visible {
+		return "…"
+	}
+	return secret[:visible] + "…"
+}
@@ -17,6 +17,7 @@ import (
 //
 // Maintains a rolling window of recent content with automatic token-based trimming.
 // Unlike conversation memory, stores raw content flow without message structure.
+// Keys are namespaced per tenant when the request context carries one.
 //
 // Example:
 //
@@ -134,7 +135,7 @@ func trimToTokenLimit(content []byte, maxTokens int) []byte {
 
 // getContext retrieves context data from store
 func (cm *ContextMemory) getContext(ctx context.Context, key string) (*contextData, error) {
-	data, err := cm.store.Get(key)
+	data, err := cm.store.Get(calque.TenantKey(ctx, key))
 	if err != nil {
 		return nil, err
 	}
@@ -158,7 +159,7 @@ func (cm *ContextMemory) saveContext(ctx context.Context, key string, ctxData *c
 		return calque.WrapErr(ctx, err, "failed to marshal context")
 	}
 
-	return cm.store.Set(key, data)
+	return cm.store.Set(calque.TenantKey(ctx, key), data)
 }
 
 // GetContext retrieves current context content for a key.
@@ -255,7 +256,7 @@ func (cm *ContextMemory) Info(ctx context.Context, key string) (tokenCount, maxT
 	}
 
 	if ctxData == nil {
-		exists = cm.store.Exists(key)
+		exists = cm.store.Exists(calque.TenantKey(ctx, key))
 		return 0, 0, exists, nil
 	}
 
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
 )
@@ -38,14 +39,17 @@ func (m Message) String() string {
 // ConversationMemory provides structured conversation memory using a pluggable store.
 //
 // Manages conversation history with configurable storage backends.
-// Supports multiple concurrent conversations identified by keys.
+// Supports multiple concurrent conversations identified by keys. When the
+// request context carries a tenant (calque.WithTenant), keys are namespaced
+// per tenant so tenants never see each other's history.
 //
 // Example:
 //
 //	mem := memory.NewConversation()
 //	flow.Use(mem.Input("user123")).Use(llm).Use(mem.Output("user123"))
 type ConversationMemory struct {
-	store Store
+	store     Store
+	retention RetentionPolicy
 }
 
 // NewConversation creates a conversation memory with default in-memory store.
@@ -87,37 +91,56 @@ func NewConversationWithStore(store Store) *ConversationMemory {
 
 // conversationData holds the structured conversation history
 type conversationData struct {
-	Messages []Message `json:"messages"`
+	Messages  []Message `json:"messages"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"` // Last write, for RetentionPolicy.MaxAge
 }
 
 // getConversation retrieves conversation history from store
 func (cm *ConversationMemory) getConversation(ctx context.Context, key string) ([]Message, error) {
-	data, err := cm.store.Get(key)
+	conv, err := cm.load(ctx, calque.TenantKey(ctx, key))
 	if err != nil {
 		return nil, err
 	}
 
-	if data == nil {
+	if conv == nil {
 		return []Message{}, nil // Empty conversation
 	}
 
+	return conv.Messages, nil
+}
+
+// load reads the conversation under a store key, nil if there is none
+func (cm *ConversationMemory) load(ctx context.Context, storeKey string) (*conversationData, error) {
+	data, err := cm.store.Get(storeKey)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
 	var conv conversationData
 	if err := json.Unmarshal(data, &conv); err != nil {
 		return nil, calque.WrapErr(ctx, err, "failed to unmarshal conversation")
 	}
-
-	return conv.Messages, nil
+	return &conv, nil
 }
 
 // saveConversation stores conversation history to store
 func (cm *ConversationMemory) saveConversation(ctx context.Context, key string, messages []Message) error {
-	conv := conversationData{Messages: messages}
+	return cm.save(ctx, calque.TenantKey(ctx, key), messages, time.Now().UTC())
+}
+
+// save writes a conversation under a store key, keeping only the newest
+// RetentionPolicy.MaxMessages messages
+func (cm *ConversationMemory) save(ctx context.Context, storeKey string, messages []Message, updated time.Time) error {
+	if limit := cm.retention.MaxMessages; limit > 0 && len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+	conv := conversationData{Messages: messages, UpdatedAt: updated}
 	data, err := json.Marshal(conv)
 	if err != nil {
 		return calque.WrapErr(ctx, err, "failed to marshal conversation")
 	}
 
-	return cm.store.Set(key, data)
+	return cm.store.Set(storeKey, data)
 }
 
 // Input creates a middleware that prepends conversation history and stores user input
@@ -263,7 +286,7 @@ func (cm *ConversationMemory) Info(ctx context.Context, key string) (messageCoun
 		return 0, false, err
 	}
 
-	exists = cm.store.Exists(key)
+	exists = cm.store.Exists(calque.TenantKey(ctx, key))
 	return len(history), exists, nil
 }
 
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"sort"
 	"strings"
 	"testing"
 
@@ -674,3 +675,37 @@ func TestConversationMemoryErrorHandling(t *testing.T) {
 		}
 	})
 }
+
+func TestConversationMemory_TenantIsolation(t *testing.T) {
+	conv := NewConversation()
+
+	for _, tenant := range []string{"acme", "globex"} {
+		ctx := calque.WithTenant(context.Background(), tenant)
+		var out string
+		if err := calque.NewFlow().Use(conv.Input("user1")).Run(ctx, "hello from "+tenant, &out); err != nil {
+			t.Fatalf("Input(%s) error = %v", tenant, err)
+		}
+	}
+
+	for _, tenant := range []string{"acme", "globex"} {
+		ctx := calque.WithTenant(context.Background(), tenant)
+		count, exists, err := conv.Info(ctx, "user1")
+		if err != nil {
+			t.Fatalf("Info(%s) error = %v", tenant, err)
+		}
+		if !exists || count != 1 {
+			t.Errorf("Info(%s) = %d, %v; want 1, true", tenant, count, exists)
+		}
+	}
+
+	if _, exists, _ := conv.Info(context.Background(), "user1"); exists {
+		t.Error("Expected no conversation outside a tenant")
+	}
+
+	keys := conv.ListKeys()
+	sort.Strings(keys)
+	want := []string{"tenant/acme/user1", "tenant/globex/user1"}
+	if strings.Join(keys, ",") != strings.Join(want, ",") {
+		t.Errorf("ListKeys() = %v, want %v", keys, want)
+	}
+}
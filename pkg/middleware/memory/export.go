@@ -0,0 +1,211 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// ExportVersion is the version of the conversation export format.
+const ExportVersion = 1
+
+// ExportFormat selects the layout Export writes.
+type ExportFormat string
+
+const (
+	// FormatCalque is the versioned export document (the default):
+	//
+	//	{"version": 1, "key": "user123", "exported_at": "2025-01-02T15:04:05Z",
+	//	 "messages": [{"role": "user", "content": "Hello"}]}
+	//
+	// Content that is not UTF-8 text is base64 encoded and marked with
+	// "encoding": "base64", so binary messages round-trip.
+	FormatCalque ExportFormat = "calque"
+
+	// FormatOpenAI is a bare OpenAI chat messages array:
+	//
+	//	[{"role": "user", "content": "Hello"}]
+	FormatOpenAI ExportFormat = "openai"
+)
+
+// Export encodes the conversation stored under key, for moving it to another
+// store or answering a data portability request. A conversation that does
+// not exist exports with no messages.
+//
+// Input: conversation key, optional ExportFormat (FormatCalque by default)
+// Output: JSON document
+// Behavior: Non-destructive, respects the request tenant like Info
+//
+// Example:
+//
+//	data, err := mem.Export(ctx, "user123")
+//	if err != nil {
+//		return err
+//	}
+//	err = pgMem.Import(ctx, "user123", data)
+func (cm *ConversationMemory) Export(ctx context.Context, key string, format ...ExportFormat) ([]byte, error) {
+	messages, err := cm.getConversation(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	f := FormatCalque
+	if len(format) > 0 && format[0] != "" {
+		f = format[0]
+	}
+	exported := make([]exportedMessage, len(messages))
+	var doc any
+	switch f {
+	case FormatCalque:
+		for i, msg := range messages {
+			exported[i] = exportMessage(msg)
+		}
+		doc = exportDocument{Version: ExportVersion, Key: key, ExportedAt: time.Now().UTC(), Messages: exported}
+	case FormatOpenAI:
+		for i, msg := range messages {
+			exported[i] = exportedMessage{Role: msg.Role, Content: string(msg.Content)}
+		}
+		doc = exported
+	default:
+		return nil, calque.InvalidInput(calque.NewErr(ctx, fmt.Sprintf("unknown export format %q", f)))
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to marshal conversation export")
+	}
+	return data, nil
+}
+
+// Import replaces the conversation stored under key with an exported one.
+//
+// Input: conversation key, JSON document
+// Output: error if the document is invalid or the store fails
+// Behavior: Overwrites existing history for key, respects the request tenant
+//
+// Accepts FormatCalque documents, OpenAI messages arrays and OpenAI chat
+// request bodies ({"model": ..., "messages": [...]}). OpenAI content part
+// arrays are imported as their text parts joined with newlines.
+//
+// Example:
+//
+//	err := mem.Import(ctx, "user123", data)
+func (cm *ConversationMemory) Import(ctx context.Context, key string, data []byte) error {
+	messages, err := parseExport(data)
+	if err != nil {
+		return calque.InvalidInput(calque.WrapErr(ctx, err, "invalid conversation export"))
+	}
+	return cm.saveConversation(ctx, key, messages)
+}
+
+// exportDocument is the FormatCalque layout
+type exportDocument struct {
+	Version    int               `json:"version"`
+	Key        string            `json:"key,omitempty"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Messages   []exportedMessage `json:"messages"`
+}
+
+// exportedMessage is a message in both export formats
+type exportedMessage struct {
+	Role     string `json:"role"`
+	Content  string `json:"content"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+func exportMessage(msg Message) exportedMessage {
+	if utf8.Valid(msg.Content) {
+		return exportedMessage{Role: msg.Role, Content: string(msg.Content)}
+	}
+	return exportedMessage{Role: msg.Role, Content: base64.StdEncoding.EncodeToString(msg.Content), Encoding: "base64"}
+}
+
+// importedMessage accepts string or OpenAI content part array content
+type importedMessage struct {
+	Role     string          `json:"role"`
+	Content  json.RawMessage `json:"content"`
+	Encoding string          `json:"encoding"`
+}
+
+// parseExport decodes any supported export layout
+func parseExport(data []byte) ([]Message, error) {
+	data = bytes.TrimSpace(data)
+	var raw []importedMessage
+	if len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	} else {
+		var doc struct {
+			Version  *int              `json:"version"`
+			Messages []importedMessage `json:"messages"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		if doc.Version != nil && *doc.Version != ExportVersion {
+			return nil, fmt.Errorf("unsupported export version %d", *doc.Version)
+		}
+		if doc.Messages == nil {
+			return nil, errors.New("no messages field")
+		}
+		raw = doc.Messages
+	}
+
+	messages := make([]Message, len(raw))
+	for i, msg := range raw {
+		if msg.Role == "" {
+			return nil, fmt.Errorf("message %d: missing role", i)
+		}
+		content, err := importContent(msg)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		messages[i] = Message{Role: msg.Role, Content: content}
+	}
+	return messages, nil
+}
+
+func importContent(msg importedMessage) ([]byte, error) {
+	content := bytes.TrimSpace(msg.Content)
+	switch {
+	case len(content) == 0 || string(content) == "null":
+		return []byte{}, nil // assistant tool call messages have no content
+	case content[0] == '[':
+		var parts []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(content, &parts); err != nil {
+			return nil, err
+		}
+		var texts []string
+		for _, part := range parts {
+			if part.Type == "text" {
+				texts = append(texts, part.Text)
+			}
+		}
+		return []byte(strings.Join(texts, "\n")), nil
+	}
+
+	var s string
+	if err := json.Unmarshal(content, &s); err != nil {
+		return nil, err
+	}
+	switch msg.Encoding {
+	case "":
+		return []byte(s), nil
+	case "base64":
+		return base64.StdEncoding.DecodeString(s)
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", msg.Encoding)
+	}
+}
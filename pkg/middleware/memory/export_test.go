@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	src := NewConversation()
+	history := []Message{
+		{Role: "system", Content: []byte("Be brief")},
+		{Role: "user", Content: []byte("Hello")},
+		{Role: "assistant", Content: []byte{0xff, 0x00, 0x01}},
+	}
+	if err := src.saveConversation(ctx, "user123", history); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := src.Export(ctx, "user123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["version"] != float64(ExportVersion) || doc["key"] != "user123" || doc["exported_at"] == nil {
+		t.Errorf("export document = %s", data)
+	}
+	if !strings.Contains(string(data), `"content": "Hello"`) || !strings.Contains(string(data), `"encoding": "base64"`) {
+		t.Errorf("export should keep text readable and base64 binary content: %s", data)
+	}
+
+	dst := NewConversationWithStore(NewInMemoryStore())
+	if err := dst.saveConversation(ctx, "user123", []Message{{Role: "user", Content: []byte("stale")}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.Import(ctx, "user123", data); err != nil {
+		t.Fatal(err)
+	}
+	got, err := dst.getConversation(ctx, "user123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, history) {
+		t.Errorf("imported = %v, want %v", got, history)
+	}
+}
+
+func TestExportOpenAI(t *testing.T) {
+	ctx := context.Background()
+	mem := NewConversation()
+	if err := mem.saveConversation(ctx, "k", []Message{{Role: "user", Content: []byte("Hi")}, {Role: "assistant", Content: []byte("Hello!")}}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := mem.Export(ctx, "k", FormatOpenAI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []map[string]string
+	if err := json.Unmarshal(data, &messages); err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]string{{"role": "user", "content": "Hi"}, {"role": "assistant", "content": "Hello!"}}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("openai export = %v, want %v", messages, want)
+	}
+
+	if _, err := mem.Export(ctx, "k", "xml"); !calque.IsInvalidInput(err) {
+		t.Errorf("Export() with unknown format error = %v, want invalid input", err)
+	}
+
+	empty, err := mem.Export(ctx, "missing", FormatOpenAI)
+	if err != nil || string(empty) != "[]" {
+		t.Errorf("Export() of missing conversation = %s, %v", empty, err)
+	}
+}
+
+func TestImportOpenAI(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Message
+	}{
+		{
+			name: "messages array",
+			data: `[{"role": "user", "content": "Hi"}, {"role": "assistant", "content": "Hello!"}]`,
+			want: []Message{{Role: "user", Content: []byte("Hi")}, {Role: "assistant", Content: []byte("Hello!")}},
+		},
+		{
+			name: "chat request body with content parts",
+			data: `{"model": "gpt-4o", "messages": [
+				{"role": "user", "content": [{"type": "text", "text": "What is this?"}, {"type": "image_url", "image_url": {"url": "https://x/y.png"}}, {"type": "text", "text": "Be brief"}]},
+				{"role": "assistant", "content": null, "tool_calls": [{"id": "1"}]},
+				{"role": "tool", "tool_call_id": "1", "content": "42"}
+			]}`,
+			want: []Message{
+				{Role: "user", Content: []byte("What is this?\nBe brief")},
+				{Role: "assistant", Content: []byte{}},
+				{Role: "tool", Content: []byte("42")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mem := NewConversation()
+			if err := mem.Import(ctx, "k", []byte(tt.data)); err != nil {
+				t.Fatal(err)
+			}
+			got, err := mem.getConversation(ctx, "k")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("imported = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportInvalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"not json":       `hello`,
+		"future version": `{"version": 2, "messages": []}`,
+		"no messages":    `{"version": 1}`,
+		"missing role":   `[{"content": "Hi"}]`,
+		"bad base64":     `[{"role": "user", "content": "%%", "encoding": "base64"}]`,
+		"bad encoding":   `[{"role": "user", "content": "Hi", "encoding": "rot13"}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := NewConversation().Import(context.Background(), "k", []byte(data)); !calque.IsInvalidInput(err) {
+				t.Errorf("Import() error = %v, want invalid input", err)
+			}
+		})
+	}
+}
+
+func TestExportImportTenant(t *testing.T) {
+	mem := NewConversation()
+	acme := calque.WithTenant(context.Background(), "acme")
+	if err := mem.Import(acme, "k", []byte(`[{"role": "user", "content": "Hi"}]`)); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := mem.Export(context.Background(), "k", FormatOpenAI)
+	if err != nil || string(data) != "[]" {
+		t.Errorf("Export() without tenant = %s, %v, want no messages", data, err)
+	}
+	if count, _, _ := mem.Info(acme, "k"); count != 1 {
+		t.Errorf("tenant conversation has %d messages, want 1", count)
+	}
+}
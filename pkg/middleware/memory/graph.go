@@ -0,0 +1,471 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/convert"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+// Entity is a node in the knowledge graph, such as a person, project or preference.
+type Entity struct {
+	Name         string       `json:"name"`                   // Unique name, matched case-insensitively
+	Type         string       `json:"type,omitempty"`         // Kind of entity, e.g. "person", "project"
+	Observations []string     `json:"observations,omitempty"` // Facts recorded about the entity
+	Provenance   []Provenance `json:"provenance,omitempty"`   // Where the entity and its facts came from
+}
+
+// Relation is a directed edge between two entities, e.g. "alice" -works_on-> "apollo".
+type Relation struct {
+	From       string       `json:"from"`
+	To         string       `json:"to"`
+	Type       string       `json:"type"`
+	Provenance []Provenance `json:"provenance,omitempty"`
+}
+
+// Provenance records where a piece of knowledge was learned.
+type Provenance struct {
+	Source  string    `json:"source"`            // Conversation key or other origin
+	Excerpt string    `json:"excerpt,omitempty"` // Text the knowledge was extracted from
+	Time    time.Time `json:"time"`              // When it was recorded
+}
+
+// Graph is a set of entities and the relations between them.
+type Graph struct {
+	Entities  []Entity   `json:"entities"`
+	Relations []Relation `json:"relations"`
+}
+
+// GraphMemory provides long-term knowledge-graph memory using a pluggable store.
+//
+// Where ConversationMemory keeps transcripts, GraphMemory keeps what was learned
+// from them: entities, facts about them and the relations between them, each
+// with provenance. Graphs are identified by keys and namespaced per tenant like
+// conversations.
+//
+// Example:
+//
+//	graph := memory.NewGraph()
+//	flow.Use(graph.Extract("user123", client)).
+//	    Use(ai.Agent(client, ai.WithTools(graph.QueryTool("user123"))))
+type GraphMemory struct {
+	store Store
+	mu    sync.Mutex // serializes read-modify-write of stored graphs
+}
+
+// NewGraph creates a graph memory with default in-memory store.
+//
+// Example:
+//
+//	graph := memory.NewGraph()
+func NewGraph() *GraphMemory {
+	return &GraphMemory{store: NewInMemoryStore()}
+}
+
+// NewGraphWithStore creates a graph memory with custom store.
+//
+// Graphs are stored under "graph:<key>" so the store can be shared with
+// ConversationMemory.
+//
+// Example:
+//
+//	graph := memory.NewGraphWithStore(redisStore)
+func NewGraphWithStore(store Store) *GraphMemory {
+	return &GraphMemory{store: store}
+}
+
+// graphStorageKey returns the store key for a graph
+func graphStorageKey(ctx context.Context, key string) string {
+	return calque.TenantKey(ctx, "graph:"+key)
+}
+
+// normalizeName returns the identity used to match entity names
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// load retrieves a graph from the store
+func (g *GraphMemory) load(ctx context.Context, key string) (*Graph, error) {
+	data, err := g.store.Get(graphStorageKey(ctx, key))
+	if err != nil {
+		return nil, err
+	}
+	graph := &Graph{Entities: []Entity{}, Relations: []Relation{}}
+	if data == nil {
+		return graph, nil
+	}
+	if err := json.Unmarshal(data, graph); err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to unmarshal graph")
+	}
+	return graph, nil
+}
+
+// save stores a graph
+func (g *GraphMemory) save(ctx context.Context, key string, graph *Graph) error {
+	data, err := json.Marshal(graph)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to marshal graph")
+	}
+	return g.store.Set(graphStorageKey(ctx, key), data)
+}
+
+// Get returns the full graph for a key. Missing graphs are empty.
+//
+// Example:
+//
+//	graph, err := mem.Get(ctx, "user123")
+func (g *GraphMemory) Get(ctx context.Context, key string) (*Graph, error) {
+	return g.load(ctx, key)
+}
+
+// Merge adds entities and relations to the graph for a key.
+//
+// Entities are matched by name case-insensitively: new observations are
+// appended, an empty type is filled in and prov is recorded. Relations are
+// matched by endpoints and type; missing endpoint entities are created.
+// A zero prov.Time is set to the current time.
+//
+// Example:
+//
+//	err := mem.Merge(ctx, "user123", memory.Graph{
+//	    Entities:  []memory.Entity{{Name: "Alice", Type: "person", Observations: []string{"prefers Go"}}},
+//	    Relations: []memory.Relation{{From: "Alice", To: "Apollo", Type: "works_on"}},
+//	}, memory.Provenance{Source: "import"})
+func (g *GraphMemory) Merge(ctx context.Context, key string, update Graph, prov Provenance) error {
+	if prov.Time.IsZero() {
+		prov.Time = time.Now()
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	graph, err := g.load(ctx, key)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to get graph")
+	}
+
+	index := make(map[string]int, len(graph.Entities))
+	for i, e := range graph.Entities {
+		index[normalizeName(e.Name)] = i
+	}
+	upsert := func(name, kind string, observations []string) {
+		id := normalizeName(name)
+		i, ok := index[id]
+		if !ok {
+			graph.Entities = append(graph.Entities, Entity{Name: strings.TrimSpace(name)})
+			i = len(graph.Entities) - 1
+			index[id] = i
+		}
+		entity := &graph.Entities[i]
+		if entity.Type == "" {
+			entity.Type = strings.TrimSpace(kind)
+		}
+		changed := !ok
+		for _, obs := range observations {
+			obs = strings.TrimSpace(obs)
+			if obs != "" && !slices.Contains(entity.Observations, obs) {
+				entity.Observations = append(entity.Observations, obs)
+				changed = true
+			}
+		}
+		if changed {
+			entity.Provenance = append(entity.Provenance, prov)
+		}
+	}
+
+	for _, e := range update.Entities {
+		if normalizeName(e.Name) != "" {
+			upsert(e.Name, e.Type, e.Observations)
+		}
+	}
+
+	for _, rel := range update.Relations {
+		relType := strings.TrimSpace(rel.Type)
+		if normalizeName(rel.From) == "" || normalizeName(rel.To) == "" || relType == "" {
+			continue
+		}
+		upsert(rel.From, "", nil)
+		upsert(rel.To, "", nil)
+
+		from := graph.Entities[index[normalizeName(rel.From)]].Name
+		to := graph.Entities[index[normalizeName(rel.To)]].Name
+		if findRelation(graph.Relations, from, to, relType) >= 0 {
+			continue
+		}
+		graph.Relations = append(graph.Relations, Relation{From: from, To: to, Type: relType, Provenance: []Provenance{prov}})
+	}
+
+	if err := g.save(ctx, key, graph); err != nil {
+		return calque.WrapErr(ctx, err, "failed to save graph")
+	}
+	return nil
+}
+
+// findRelation returns the index of a matching relation, or -1
+func findRelation(relations []Relation, from, to, relType string) int {
+	for i, r := range relations {
+		if normalizeName(r.From) == normalizeName(from) && normalizeName(r.To) == normalizeName(to) &&
+			strings.EqualFold(r.Type, relType) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Query returns the part of the graph relevant to a text query.
+//
+// Entities whose name, type or observations contain any query term are
+// matched, then expanded through relations up to depth hops (0 returns only
+// the matches). The result holds the matched and neighbouring entities and
+// the relations between them.
+//
+// Example:
+//
+//	sub, err := mem.Query(ctx, "user123", "apollo deadline", 1)
+func (g *GraphMemory) Query(ctx context.Context, key, query string, depth int) (*Graph, error) {
+	graph, err := g.load(ctx, key)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to get graph")
+	}
+
+	terms := strings.Fields(strings.ToLower(query))
+	included := make(map[string]bool)
+	for _, e := range graph.Entities {
+		if entityMatches(e, terms) {
+			included[normalizeName(e.Name)] = true
+		}
+	}
+
+	for range depth {
+		var frontier []string
+		for _, r := range graph.Relations {
+			from, to := normalizeName(r.From), normalizeName(r.To)
+			if included[from] && !included[to] {
+				frontier = append(frontier, to)
+			} else if included[to] && !included[from] {
+				frontier = append(frontier, from)
+			}
+		}
+		if len(frontier) == 0 {
+			break
+		}
+		for _, id := range frontier {
+			included[id] = true
+		}
+	}
+
+	result := &Graph{Entities: []Entity{}, Relations: []Relation{}}
+	for _, e := range graph.Entities {
+		if included[normalizeName(e.Name)] {
+			result.Entities = append(result.Entities, e)
+		}
+	}
+	for _, r := range graph.Relations {
+		if included[normalizeName(r.From)] && included[normalizeName(r.To)] {
+			result.Relations = append(result.Relations, r)
+		}
+	}
+	return result, nil
+}
+
+// entityMatches reports whether any term occurs in the entity's name, type or observations
+func entityMatches(e Entity, terms []string) bool {
+	text := strings.ToLower(e.Name + " " + e.Type + " " + strings.Join(e.Observations, " "))
+	for _, term := range terms {
+		if strings.Contains(text, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteEntity removes an entity and all its relations from the graph for a key.
+//
+// Example:
+//
+//	err := mem.DeleteEntity(ctx, "user123", "Alice")
+func (g *GraphMemory) DeleteEntity(ctx context.Context, key, name string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	graph, err := g.load(ctx, key)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to get graph")
+	}
+
+	id := normalizeName(name)
+	graph.Entities = slices.DeleteFunc(graph.Entities, func(e Entity) bool {
+		return normalizeName(e.Name) == id
+	})
+	graph.Relations = slices.DeleteFunc(graph.Relations, func(r Relation) bool {
+		return normalizeName(r.From) == id || normalizeName(r.To) == id
+	})
+
+	if err := g.save(ctx, key, graph); err != nil {
+		return calque.WrapErr(ctx, err, "failed to save graph")
+	}
+	return nil
+}
+
+// Clear removes the whole graph for a key.
+//
+// Example:
+//
+//	err := mem.Clear(ctx, "user123")
+func (g *GraphMemory) Clear(ctx context.Context, key string) error {
+	return g.store.Delete(graphStorageKey(ctx, key))
+}
+
+// graphExtraction is the structured output requested from the extraction model
+type graphExtraction struct {
+	Entities []struct {
+		Name         string   `json:"name" jsonschema:"required,description=Canonical entity name"`
+		Type         string   `json:"type" jsonschema:"description=Entity kind such as person or project or place"`
+		Observations []string `json:"observations" jsonschema:"description=Short standalone facts about the entity"`
+	} `json:"entities" jsonschema:"required"`
+	Relations []struct {
+		From string `json:"from" jsonschema:"required,description=Source entity name"`
+		To   string `json:"to" jsonschema:"required,description=Target entity name"`
+		Type string `json:"type" jsonschema:"required,description=Relation in snake_case such as works_on"`
+	} `json:"relations" jsonschema:"required"`
+}
+
+// maxExcerpt bounds the text stored as provenance for extracted knowledge
+const maxExcerpt = 500
+
+// Extract creates a middleware that learns entities and relations from the text passing through.
+//
+// Input: conversation text (user input or model response)
+// Output: the input, unchanged
+// Behavior: BUFFERED - reads entire input, runs extraction, then passes it on
+//
+// The AI client extracts entities, facts and relations as structured output,
+// which are merged into the graph for key with the key and an excerpt of the
+// text as provenance. Known entity names are included in the prompt so the
+// model reuses them instead of creating duplicates.
+//
+// Example:
+//
+//	graph := memory.NewGraph()
+//	flow.
+//	    Use(graph.Extract("user123", extractor)).
+//	    Use(ai.Agent(client)).
+//	    Use(graph.Extract("user123", extractor))
+func (g *GraphMemory) Extract(key string, client ai.Client) calque.Handler {
+	return calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
+		if client == nil {
+			return calque.NewErr(r.Context, "AI client cannot be nil")
+		}
+
+		var input string
+		if err := calque.Read(r, &input); err != nil {
+			return calque.WrapErr(r.Context, err, "failed to read input")
+		}
+
+		if text := strings.TrimSpace(input); text != "" {
+			if err := g.extract(r.Context, key, client, text); err != nil {
+				return err
+			}
+		}
+		return calque.Write(w, input)
+	})
+}
+
+// extract asks the model for graph updates from text and merges them
+func (g *GraphMemory) extract(ctx context.Context, key string, client ai.Client, text string) error {
+	graph, err := g.load(ctx, key)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to get graph")
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString("Extract the entities, facts and relations worth remembering long-term from the text below. ")
+	prompt.WriteString("Only include information stated in the text. Return empty lists if there is nothing to remember.\n")
+	if len(graph.Entities) > 0 {
+		names := make([]string, len(graph.Entities))
+		for i, e := range graph.Entities {
+			names[i] = e.Name
+		}
+		fmt.Fprintf(&prompt, "Reuse these known entity names when they refer to the same thing: %s\n", strings.Join(names, ", "))
+	}
+	fmt.Fprintf(&prompt, "\nText:\n%s", text)
+
+	var extraction graphExtraction
+	flow := calque.NewFlow().Use(ai.Agent(client, ai.WithSchemaFor[graphExtraction]()))
+	if err := flow.Run(ctx, prompt.String(), convert.FromJSON(&extraction)); err != nil {
+		return calque.WrapErr(ctx, err, "graph extraction failed")
+	}
+
+	update := Graph{}
+	for _, e := range extraction.Entities {
+		update.Entities = append(update.Entities, Entity{Name: e.Name, Type: e.Type, Observations: e.Observations})
+	}
+	for _, rel := range extraction.Relations {
+		update.Relations = append(update.Relations, Relation{From: rel.From, To: rel.To, Type: rel.Type})
+	}
+	if len(update.Entities) == 0 && len(update.Relations) == 0 {
+		return nil
+	}
+
+	excerpt := text
+	if runes := []rune(excerpt); len(runes) > maxExcerpt {
+		excerpt = string(runes[:maxExcerpt]) + "..."
+	}
+	return g.Merge(ctx, key, update, Provenance{Source: key, Excerpt: excerpt})
+}
+
+// graphQueryArgs are the arguments of the graph query tool
+type graphQueryArgs struct {
+	Query string `json:"query" jsonschema:"required,description=Names or keywords to look up"`
+	Depth int    `json:"depth,omitempty" jsonschema:"description=Relation hops to include around matches (default 1)"`
+}
+
+// QueryTool creates a tool that lets agents search the graph for key.
+//
+// The tool is named "query_memory" and returns the matching subgraph as JSON,
+// without provenance to keep results compact.
+//
+// Example:
+//
+//	agent := ai.Agent(client, ai.WithTools(graph.QueryTool("user123")))
+func (g *GraphMemory) QueryTool(key string) tools.Tool {
+	return tools.Typed("query_memory", "Search long-term memory for known entities, facts and relations",
+		func(ctx context.Context, args graphQueryArgs) (string, error) {
+			if strings.TrimSpace(args.Query) == "" {
+				return "", calque.NewErr(ctx, "query is required")
+			}
+			depth := args.Depth
+			if depth <= 0 {
+				depth = 1
+			}
+
+			result, err := g.Query(ctx, key, args.Query, min(depth, 3))
+			if err != nil {
+				return "", err
+			}
+			if len(result.Entities) == 0 {
+				return "No matching memories found.", nil
+			}
+			for i := range result.Entities {
+				result.Entities[i].Provenance = nil
+			}
+			for i := range result.Relations {
+				result.Relations[i].Provenance = nil
+			}
+
+			output, err := json.Marshal(result)
+			if err != nil {
+				return "", err
+			}
+			return string(output), nil
+		},
+	)
+}
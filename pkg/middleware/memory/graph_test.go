@@ -0,0 +1,228 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+)
+
+func entityNames(g *Graph) []string {
+	names := make([]string, len(g.Entities))
+	for i, e := range g.Entities {
+		names[i] = e.Name
+	}
+	return names
+}
+
+func seedGraph(t *testing.T, mem *GraphMemory) {
+	t.Helper()
+	err := mem.Merge(context.Background(), "user1", Graph{
+		Entities: []Entity{
+			{Name: "Alice", Type: "person", Observations: []string{"prefers Go"}},
+			{Name: "Apollo", Type: "project", Observations: []string{"ships in March"}},
+		},
+		Relations: []Relation{
+			{From: "Alice", To: "Apollo", Type: "works_on"},
+			{From: "Apollo", To: "Postgres", Type: "uses"},
+		},
+	}, Provenance{Source: "seed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGraphMerge(t *testing.T) {
+	ctx := context.Background()
+	mem := NewGraph()
+	seedGraph(t, mem)
+
+	err := mem.Merge(ctx, "user1", Graph{
+		Entities:  []Entity{{Name: "alice ", Observations: []string{"prefers Go", "lives in Oslo"}}},
+		Relations: []Relation{{From: "ALICE", To: "apollo", Type: "works_on"}},
+	}, Provenance{Source: "chat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graph, err := mem.Get(ctx, "user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(entityNames(graph), ","); got != "Alice,Apollo,Postgres" {
+		t.Errorf("entities = %s", got)
+	}
+	alice := graph.Entities[0]
+	if alice.Type != "person" || len(alice.Observations) != 2 || alice.Observations[1] != "lives in Oslo" {
+		t.Errorf("alice = %+v", alice)
+	}
+	if len(alice.Provenance) != 2 || alice.Provenance[1].Source != "chat" || alice.Provenance[1].Time.IsZero() {
+		t.Errorf("alice provenance = %+v", alice.Provenance)
+	}
+	if len(graph.Relations) != 2 {
+		t.Errorf("relations = %+v", graph.Relations)
+	}
+}
+
+func TestGraphQuery(t *testing.T) {
+	mem := NewGraph()
+	seedGraph(t, mem)
+
+	tests := []struct {
+		name          string
+		query         string
+		depth         int
+		wantEntities  string
+		wantRelations int
+	}{
+		{name: "match only", query: "alice", depth: 0, wantEntities: "Alice", wantRelations: 0},
+		{name: "one hop", query: "alice", depth: 1, wantEntities: "Alice,Apollo", wantRelations: 1},
+		{name: "two hops", query: "alice", depth: 2, wantEntities: "Alice,Apollo,Postgres", wantRelations: 2},
+		{name: "observation match", query: "march", depth: 0, wantEntities: "Apollo", wantRelations: 0},
+		{name: "type match", query: "person", depth: 0, wantEntities: "Alice", wantRelations: 0},
+		{name: "no match", query: "kubernetes", depth: 2, wantEntities: "", wantRelations: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := mem.Query(context.Background(), "user1", tt.query, tt.depth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(entityNames(result), ","); got != tt.wantEntities {
+				t.Errorf("entities = %q, want %q", got, tt.wantEntities)
+			}
+			if len(result.Relations) != tt.wantRelations {
+				t.Errorf("relations = %d, want %d", len(result.Relations), tt.wantRelations)
+			}
+		})
+	}
+}
+
+func TestGraphDeleteAndClear(t *testing.T) {
+	ctx := context.Background()
+	mem := NewGraph()
+	seedGraph(t, mem)
+
+	if err := mem.DeleteEntity(ctx, "user1", "apollo"); err != nil {
+		t.Fatal(err)
+	}
+	graph, _ := mem.Get(ctx, "user1")
+	if got := strings.Join(entityNames(graph), ","); got != "Alice,Postgres" || len(graph.Relations) != 0 {
+		t.Errorf("after delete: entities %s, relations %+v", got, graph.Relations)
+	}
+
+	if err := mem.Clear(ctx, "user1"); err != nil {
+		t.Fatal(err)
+	}
+	if graph, _ := mem.Get(ctx, "user1"); len(graph.Entities) != 0 {
+		t.Errorf("after clear: %+v", graph)
+	}
+}
+
+func TestGraphTenantIsolation(t *testing.T) {
+	store := NewInMemoryStore()
+	mem := NewGraphWithStore(store)
+	acme := calque.WithTenant(context.Background(), "acme")
+
+	if err := mem.Merge(acme, "user1", Graph{Entities: []Entity{{Name: "Alice"}}}, Provenance{}); err != nil {
+		t.Fatal(err)
+	}
+	if !store.Exists("tenant/acme/graph:user1") {
+		t.Errorf("keys = %v", store.List())
+	}
+	if graph, _ := mem.Get(context.Background(), "user1"); len(graph.Entities) != 0 {
+		t.Errorf("untenanted graph = %+v", graph)
+	}
+}
+
+func TestGraphExtract(t *testing.T) {
+	extraction := `{"entities":[{"name":"Bob","type":"person","observations":["is allergic to peanuts"]}],` +
+		`"relations":[{"from":"Bob","to":"Carol","type":"married_to"}]}`
+
+	tests := []struct {
+		name         string
+		client       ai.Client
+		input        string
+		wantErr      bool
+		wantEntities string
+	}{
+		{name: "extracts", client: ai.NewMockClient(extraction).WithStreamDelay(0), input: "Bob, who is married to Carol, is allergic to peanuts.", wantEntities: "Bob,Carol"},
+		{name: "empty input skips model", client: ai.NewMockClientWithError("should not be called"), input: "  ", wantEntities: ""},
+		{name: "model failure", client: ai.NewMockClientWithError("boom"), input: "Bob likes tea", wantErr: true},
+		{name: "nil client", client: nil, input: "Bob likes tea", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mem := NewGraph()
+			var out bytes.Buffer
+			err := mem.Extract("user1", tt.client).ServeFlow(
+				calque.NewRequest(context.Background(), strings.NewReader(tt.input)), calque.NewResponse(&out))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if out.String() != tt.input {
+				t.Errorf("output = %q, want input passed through", out.String())
+			}
+			graph, _ := mem.Get(context.Background(), "user1")
+			if got := strings.Join(entityNames(graph), ","); got != tt.wantEntities {
+				t.Errorf("entities = %q, want %q", got, tt.wantEntities)
+			}
+			if tt.wantEntities != "" {
+				prov := graph.Entities[0].Provenance
+				if len(prov) != 1 || prov[0].Source != "user1" || prov[0].Excerpt != tt.input {
+					t.Errorf("provenance = %+v", prov)
+				}
+				if len(graph.Relations) != 1 || graph.Relations[0].Type != "married_to" {
+					t.Errorf("relations = %+v", graph.Relations)
+				}
+			}
+		})
+	}
+}
+
+func TestGraphQueryTool(t *testing.T) {
+	mem := NewGraph()
+	seedGraph(t, mem)
+	tool := mem.QueryTool("user1")
+	if tool.Name() != "query_memory" {
+		t.Errorf("name = %s", tool.Name())
+	}
+
+	tests := []struct {
+		name    string
+		args    string
+		want    []string
+		notWant []string
+		wantErr bool
+	}{
+		{name: "default depth", args: `{"query":"alice"}`, want: []string{"Alice", "Apollo", "works_on"}, notWant: []string{"Postgres", "provenance"}},
+		{name: "deeper", args: `{"query":"alice","depth":2}`, want: []string{"Postgres"}},
+		{name: "no match", args: `{"query":"zebra"}`, want: []string{"No matching memories"}},
+		{name: "empty query", args: `{"query":" "}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := tool.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader(tt.args)), calque.NewResponse(&out))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(out.String(), s) {
+					t.Errorf("output %q missing %q", out.String(), s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(out.String(), s) {
+					t.Errorf("output %q contains %q", out.String(), s)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultJanitorInterval is how often StartJanitor sweeps when no interval is given.
+const DefaultJanitorInterval = time.Hour
+
+// RetentionPolicy limits how long and how much conversation history is kept.
+type RetentionPolicy struct {
+	// MaxAge deletes conversations with no new messages for this long.
+	// Zero keeps them forever.
+	MaxAge time.Duration
+	// MaxMessages keeps only the newest messages of each conversation.
+	// Zero keeps all messages.
+	MaxMessages int
+}
+
+// WithRetention sets the retention policy of a conversation memory.
+//
+// Input: RetentionPolicy
+// Output: the same *ConversationMemory for chaining
+// Behavior: MaxMessages is applied on every write; MaxAge by Sweep
+//
+// Call it before the memory serves requests. Run Sweep, usually from
+// StartJanitor, to delete expired conversations.
+//
+// Example:
+//
+//	mem := memory.NewConversationWithStore(store).WithRetention(memory.RetentionPolicy{
+//		MaxAge:      30 * 24 * time.Hour,
+//		MaxMessages: 200,
+//	})
+//	memory.StartJanitor(ctx, time.Hour, mem)
+func (cm *ConversationMemory) WithRetention(policy RetentionPolicy) *ConversationMemory {
+	cm.retention = policy
+	return cm
+}
+
+// Sweeper enforces a retention policy over a store.
+type Sweeper interface {
+	// Sweep deletes or trims expired data and returns how many entries it changed
+	Sweep(ctx context.Context) (int, error)
+}
+
+// Sweep enforces the retention policy across all tenants' conversations:
+// conversations idle longer than MaxAge are deleted and longer ones are
+// trimmed to MaxMessages.
+//
+// Conversations written before retention was configured have no last-write
+// time; Sweep stamps them so they expire MaxAge from the first sweep.
+//
+// Example:
+//
+//	deleted, err := mem.Sweep(ctx)
+func (cm *ConversationMemory) Sweep(ctx context.Context) (int, error) {
+	policy := cm.retention
+	if policy.MaxAge <= 0 && policy.MaxMessages <= 0 {
+		return 0, nil
+	}
+
+	var changed int
+	var errs []error
+	now := time.Now()
+	for _, storeKey := range cm.store.List() {
+		conv, err := cm.load(ctx, storeKey)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", storeKey, err))
+			continue
+		}
+		if conv == nil {
+			continue
+		}
+
+		switch {
+		case policy.MaxAge > 0 && !conv.UpdatedAt.IsZero() && now.Sub(conv.UpdatedAt) > policy.MaxAge:
+			err = cm.store.Delete(storeKey)
+		case conv.UpdatedAt.IsZero():
+			err = cm.save(ctx, storeKey, conv.Messages, now.UTC())
+		case policy.MaxMessages > 0 && len(conv.Messages) > policy.MaxMessages:
+			err = cm.save(ctx, storeKey, conv.Messages, conv.UpdatedAt) // trimming is not activity
+		default:
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", storeKey, err))
+			continue
+		}
+		changed++
+	}
+	if len(errs) > 0 {
+		return changed, calque.WrapErr(ctx, errors.Join(errs...), "conversation sweep failed")
+	}
+	return changed, nil
+}
+
+// StartJanitor sweeps the given stores now and then every interval
+// (DefaultJanitorInterval if zero) until ctx is cancelled. Results are logged.
+//
+// Example:
+//
+//	memory.StartJanitor(ctx, 15*time.Minute, conversations, supportConversations)
+func StartJanitor(ctx context.Context, interval time.Duration, sweepers ...Sweeper) {
+	if interval <= 0 {
+		interval = DefaultJanitorInterval
+	}
+	sweep := func() {
+		for _, s := range sweepers {
+			n, err := s.Sweep(ctx)
+			if err != nil {
+				calque.Logger(ctx).Warn("memory retention sweep failed", "changed", n, "error", err)
+			} else if n > 0 {
+				calque.Logger(ctx).Info("memory retention sweep", "changed", n)
+			}
+		}
+	}
+
+	go func() {
+		sweep()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sweep()
+			}
+		}
+	}()
+}
+
+// Forgetter deletes everything a store holds about a user.
+type Forgetter interface {
+	// Forget deletes the user's data and returns how many entries were removed
+	Forget(ctx context.Context, userID string) (int, error)
+}
+
+// ForgetterFunc adapts a function to the Forgetter interface.
+type ForgetterFunc func(ctx context.Context, userID string) (int, error)
+
+// Forget implements Forgetter.
+func (f ForgetterFunc) Forget(ctx context.Context, userID string) (int, error) {
+	return f(ctx, userID)
+}
+
+// Forget erases a user from every store, for GDPR erasure requests. All
+// stores are attempted even if one fails; the errors are joined.
+//
+// Data belongs to a user when its key is the user ID or starts with the user
+// ID followed by ":" or "/" (e.g. "user123:support"), within the request
+// tenant. Caches and vector stores are included with StoreKeys and Documents.
+//
+// Example:
+//
+//	deleted, err := memory.Forget(ctx, "user123",
+//		conversations,
+//		graphs,
+//		memory.StoreKeys(cacheStore),
+//		memory.Documents(vectorStore, documentIDsForUser),
+//	)
+func Forget(ctx context.Context, userID string, stores ...Forgetter) (int, error) {
+	if userID == "" {
+		return 0, calque.InvalidInput(calque.NewErr(ctx, "user ID is required"))
+	}
+	var deleted int
+	var errs []error
+	for _, store := range stores {
+		n, err := store.Forget(ctx, userID)
+		deleted += n
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return deleted, calque.WrapErr(ctx, errors.Join(errs...), "forget "+userID+" incomplete")
+	}
+	calque.Logger(ctx).Info("forgot user data", "deleted", deleted)
+	return deleted, nil
+}
+
+// KeyStore is a store whose keys can be listed and deleted, such as Store
+// and cache.Store.
+type KeyStore interface {
+	List() []string
+	Delete(key string) error
+}
+
+// StoreKeys returns a Forgetter that deletes the user's keys from store.
+// Use it for caches keyed by user, such as cache.CacheWithKey handlers
+// whose keys start with the user ID.
+func StoreKeys(store KeyStore) Forgetter {
+	return ForgetterFunc(func(ctx context.Context, userID string) (int, error) {
+		return forgetKeys(ctx, store, calque.TenantKey(ctx, userID))
+	})
+}
+
+// DocumentDeleter deletes documents by ID, such as retrieval.VectorStore.
+type DocumentDeleter interface {
+	Delete(ctx context.Context, ids []string) error
+}
+
+// Documents returns a Forgetter that deletes the documents ids reports for
+// a user from a vector store, for example the IDs recorded at ingestion or
+// found by a metadata filter.
+func Documents(store DocumentDeleter, ids func(ctx context.Context, userID string) ([]string, error)) Forgetter {
+	return ForgetterFunc(func(ctx context.Context, userID string) (int, error) {
+		docs, err := ids(ctx, userID)
+		if err != nil {
+			return 0, calque.WrapErr(ctx, err, "failed to find documents for user")
+		}
+		if len(docs) == 0 {
+			return 0, nil
+		}
+		if err := store.Delete(ctx, docs); err != nil {
+			return 0, calque.WrapErr(ctx, err, "failed to delete documents for user")
+		}
+		return len(docs), nil
+	})
+}
+
+// Forget deletes the user's conversations.
+func (cm *ConversationMemory) Forget(ctx context.Context, userID string) (int, error) {
+	return forgetKeys(ctx, cm.store, calque.TenantKey(ctx, userID))
+}
+
+// Forget deletes the user's context windows.
+func (cm *ContextMemory) Forget(ctx context.Context, userID string) (int, error) {
+	return forgetKeys(ctx, cm.store, calque.TenantKey(ctx, userID))
+}
+
+// Forget deletes the user's knowledge graphs.
+func (g *GraphMemory) Forget(ctx context.Context, userID string) (int, error) {
+	return forgetKeys(ctx, g.store, graphStorageKey(ctx, userID))
+}
+
+// forgetKeys deletes the keys owned by base: base itself and keys under
+// base followed by ":" or "/". Other tenants' keys are never matched.
+func forgetKeys(ctx context.Context, store KeyStore, base string) (int, error) {
+	var deleted int
+	var errs []error
+	for _, key := range store.List() {
+		if key != base && !strings.HasPrefix(key, base+":") && !strings.HasPrefix(key, base+"/") {
+			continue
+		}
+		if strings.HasPrefix(key, "tenant/") && !strings.HasPrefix(base, "tenant/") {
+			continue
+		}
+		if err := store.Delete(key); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+		deleted++
+	}
+	if len(errs) > 0 {
+		return deleted, calque.WrapErr(ctx, errors.Join(errs...), "failed to delete user keys")
+	}
+	return deleted, nil
+}
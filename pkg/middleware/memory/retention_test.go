@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// storeConversation writes a conversation with a given last-write time
+func storeConversation(t *testing.T, store Store, key string, updated time.Time, messages int) {
+	t.Helper()
+	conv := conversationData{UpdatedAt: updated}
+	for i := range messages {
+		conv.Messages = append(conv.Messages, Message{Role: "user", Content: []byte(strconv.Itoa(i))})
+	}
+	data, err := json.Marshal(conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(key, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRetentionMaxMessages(t *testing.T) {
+	ctx := context.Background()
+	mem := NewConversation().WithRetention(RetentionPolicy{MaxMessages: 4})
+	flow := calque.NewFlow().Use(mem.Input("k")).Use(calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
+		var in string
+		if err := calque.Read(r, &in); err != nil {
+			return err
+		}
+		return calque.Write(w, "ok")
+	})).Use(mem.Output("k"))
+
+	for i := range 3 {
+		var out string
+		if err := flow.Run(ctx, "message "+strconv.Itoa(i), &out); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := mem.getConversation(ctx, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 4 || history[0].Text() != "message 1" {
+		t.Errorf("history = %v, want the newest 4 messages", history)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryStore()
+	now := time.Now().UTC()
+	storeConversation(t, store, "expired", now.Add(-48*time.Hour), 2)
+	storeConversation(t, store, "tenant/acme/expired", now.Add(-48*time.Hour), 2)
+	storeConversation(t, store, "active", now.Add(-time.Hour), 2)
+	storeConversation(t, store, "long", now.Add(-time.Hour), 8)
+	storeConversation(t, store, "legacy", time.Time{}, 1)
+
+	mem := NewConversationWithStore(store).WithRetention(RetentionPolicy{MaxAge: 24 * time.Hour, MaxMessages: 5})
+	changed, err := mem.Sweep(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 4 {
+		t.Errorf("Sweep() changed = %d, want 4", changed)
+	}
+
+	keys := store.List()
+	sort.Strings(keys)
+	if want := []string{"active", "legacy", "long"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys after sweep = %v, want %v", keys, want)
+	}
+
+	long, err := mem.load(ctx, "long")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(long.Messages) != 5 || now.Sub(long.UpdatedAt) < time.Hour {
+		t.Errorf("trimmed conversation = %d messages updated %v, want 5 keeping its last write", len(long.Messages), long.UpdatedAt)
+	}
+	legacy, err := mem.load(ctx, "legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legacy.UpdatedAt.IsZero() {
+		t.Error("legacy conversation was not stamped")
+	}
+
+	if changed, err := mem.Sweep(ctx); err != nil || changed != 0 {
+		t.Errorf("second Sweep() = %d, %v, want nothing to do", changed, err)
+	}
+	if changed, err := NewConversationWithStore(store).Sweep(ctx); err != nil || changed != 0 {
+		t.Errorf("Sweep() without a policy = %d, %v", changed, err)
+	}
+}
+
+func TestStartJanitor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	swept := make(chan struct{}, 2)
+	sweeper := sweeperFunc(func(context.Context) (int, error) {
+		swept <- struct{}{}
+		return 1, nil
+	})
+	StartJanitor(ctx, 10*time.Millisecond, sweeper)
+
+	for range 2 {
+		select {
+		case <-swept:
+		case <-time.After(2 * time.Second):
+			t.Fatal("janitor did not sweep")
+		}
+	}
+}
+
+type sweeperFunc func(context.Context) (int, error)
+
+func (f sweeperFunc) Sweep(ctx context.Context) (int, error) { return f(ctx) }
+
+// deleteOnly records deleted documents
+type deleteOnly struct{ deleted []string }
+
+func (d *deleteOnly) Delete(_ context.Context, ids []string) error {
+	d.deleted = append(d.deleted, ids...)
+	return nil
+}
+
+func TestForget(t *testing.T) {
+	ctx := context.Background()
+	conversations := NewConversation()
+	for _, key := range []string{"user1", "user1:support", "user1/billing", "user12", "user2"} {
+		if err := conversations.saveConversation(ctx, key, []Message{{Role: "user", Content: []byte("hi")}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	acme := calque.WithTenant(ctx, "acme")
+	if err := conversations.saveConversation(acme, "user1", []Message{{Role: "user", Content: []byte("hi")}}); err != nil {
+		t.Fatal(err)
+	}
+
+	graphs := NewGraph()
+	if err := graphs.save(ctx, "user1", &Graph{}); err != nil {
+		t.Fatal(err)
+	}
+	contexts := NewContext()
+	if err := contexts.saveContext(ctx, "user1:notes", &contextData{}); err != nil {
+		t.Fatal(err)
+	}
+	cacheStore := NewInMemoryStore()
+	_ = cacheStore.Set("user1:answer", []byte("cached"))
+	_ = cacheStore.Set("3f2a9c", []byte("cached"))
+	vectors := &deleteOnly{}
+	docs := Documents(vectors, func(_ context.Context, userID string) ([]string, error) {
+		return []string{userID + "-doc1", userID + "-doc2"}, nil
+	})
+
+	deleted, err := Forget(ctx, "user1", conversations, graphs, contexts, StoreKeys(cacheStore), docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 8 {
+		t.Errorf("Forget() deleted = %d, want 8", deleted)
+	}
+
+	keys := conversations.ListKeys()
+	sort.Strings(keys)
+	if want := []string{"tenant/acme/user1", "user12", "user2"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("remaining conversations = %v, want %v", keys, want)
+	}
+	if len(graphs.store.List()) != 0 || len(contexts.store.List()) != 0 {
+		t.Error("graph or context memory was not forgotten")
+	}
+	if got := cacheStore.List(); !reflect.DeepEqual(got, []string{"3f2a9c"}) {
+		t.Errorf("remaining cache keys = %v", got)
+	}
+	if want := []string{"user1-doc1", "user1-doc2"}; !reflect.DeepEqual(vectors.deleted, want) {
+		t.Errorf("deleted documents = %v, want %v", vectors.deleted, want)
+	}
+
+	if n, err := conversations.Forget(acme, "user1"); err != nil || n != 1 {
+		t.Errorf("tenant Forget() = %d, %v, want 1", n, err)
+	}
+	if n, err := conversations.Forget(ctx, "tenant"); err != nil || n != 0 {
+		t.Errorf("Forget(\"tenant\") = %d, %v, must not match tenant keys", n, err)
+	}
+}
+
+func TestForgetErrors(t *testing.T) {
+	ctx := context.Background()
+	if _, err := Forget(ctx, ""); !calque.IsInvalidInput(err) {
+		t.Errorf("Forget() without user error = %v, want invalid input", err)
+	}
+
+	failing := ForgetterFunc(func(context.Context, string) (int, error) { return 0, errors.New("vector store down") })
+	conversations := NewConversation()
+	if err := conversations.saveConversation(ctx, "user1", nil); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := Forget(ctx, "user1", failing, conversations)
+	if err == nil || deleted != 1 {
+		t.Errorf("Forget() = %d, %v, want remaining stores forgotten and the error reported", deleted, err)
+	}
+}
@@ -0,0 +1,231 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+// Scratchpad tool names
+const (
+	ScratchpadReadTool  = "scratchpad_read"
+	ScratchpadWriteTool = "scratchpad_write"
+)
+
+// Pad is the key/value working memory of one run, safe for concurrent use.
+type Pad struct {
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+type padContextKey struct{}
+
+// WithScratchpad returns a context with a new, empty Pad, for flows run with it.
+//
+// Example:
+//
+//	ctx := memory.WithScratchpad(ctx)
+//	err := flow.Run(ctx, input, &output)
+func WithScratchpad(ctx context.Context) context.Context {
+	return context.WithValue(ctx, padContextKey{}, &Pad{values: make(map[string]string)})
+}
+
+// PadFrom returns the run's Pad, false when no scratchpad is in the context.
+func PadFrom(ctx context.Context) (*Pad, bool) {
+	pad, ok := ctx.Value(padContextKey{}).(*Pad)
+	return pad, ok
+}
+
+// Scratchpad gives handler and everything nested in it a shared Pad.
+//
+// Input: any data type (streaming - passed through to handler)
+// Output: same as wrapped handler's output
+// Behavior: STREAMING - handler runs with a new Pad in its context
+//
+// Stages of a multi-stage flow share intermediate facts through the Pad,
+// with PadFrom, Note and Recall, or through ScratchpadTools given to an
+// agent, instead of carrying them in the stream. Each run gets its own Pad,
+// discarded when it ends.
+//
+// Example:
+//
+//	flow.Use(memory.Scratchpad(calque.NewFlow().
+//		Use(ai.Agent(researcher, ai.WithTools(memory.ScratchpadTools()...))).
+//		Use(memory.Recall("Facts gathered so far:\n")).
+//		Use(ai.Agent(writer))))
+func Scratchpad(handler calque.Handler) calque.Handler {
+	return calque.Composite("memory.Scratchpad", calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		return handler.ServeFlow(req.WithContext(WithScratchpad(req.Context)), res)
+	}), calque.Group{Kind: calque.GroupWrap, Children: []calque.Handler{handler}})
+}
+
+// Set stores value under key.
+func (p *Pad) Set(key, value string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.values[key] = value
+}
+
+// Get returns the value of key.
+func (p *Pad) Get(key string) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	value, ok := p.values[key]
+	return value, ok
+}
+
+// Delete removes key.
+func (p *Pad) Delete(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.values, key)
+}
+
+// Keys returns the keys, sorted.
+func (p *Pad) Keys() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	keys := make([]string, 0, len(p.values))
+	for key := range p.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Snapshot returns a copy of every entry.
+func (p *Pad) Snapshot() map[string]string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	values := make(map[string]string, len(p.values))
+	for key, value := range p.values {
+		values[key] = value
+	}
+	return values
+}
+
+// Note stores the stream passing through under key in the run's Pad.
+//
+// Input: any data type (buffered)
+// Output: the input, unchanged
+// Behavior: BUFFERED - the whole input is stored once read
+//
+// Fails when no scratchpad is in the context.
+//
+// Example:
+//
+//	flow.Use(extractFacts).Use(memory.Note("facts")).Use(nextStage)
+func Note(key string) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var input string
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+		pad, ok := PadFrom(req.Context)
+		if !ok {
+			return calque.NewErr(req.Context, "memory.Note used outside memory.Scratchpad")
+		}
+		pad.Set(key, input)
+		return calque.Write(res, input)
+	})
+}
+
+// Recall prepends the run's Pad entries to the stream.
+//
+// Input: any data type (buffered)
+// Output: header, one "key: value" line per entry (sorted by key), a blank
+// line, then the input
+// Behavior: BUFFERED - the Pad is read once the input is complete, so
+// earlier stages have finished writing to it
+//
+// An empty or missing Pad writes only the input.
+//
+// Example:
+//
+//	flow.Use(memory.Recall("Known facts:\n")).Use(ai.Agent(client))
+func Recall(header string) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var input []byte
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+		var sb strings.Builder
+		if pad, ok := PadFrom(req.Context); ok {
+			if keys := pad.Keys(); len(keys) > 0 {
+				sb.WriteString(header)
+				for _, key := range keys {
+					value, _ := pad.Get(key)
+					sb.WriteString(key + ": " + value + "\n")
+				}
+				sb.WriteString("\n")
+			}
+		}
+		sb.Write(input)
+		return calque.Write(res, sb.String())
+	})
+}
+
+type scratchpadReadArgs struct {
+	Key string `json:"key,omitempty" jsonschema:"description=Key to read; omit to read every entry"`
+}
+
+type scratchpadWriteArgs struct {
+	Key   string `json:"key" jsonschema:"required,description=Key to store the note under"`
+	Value string `json:"value" jsonschema:"required,description=Note to remember; an empty value deletes the key"`
+}
+
+// ScratchpadTools returns tools letting a model read and write the run's Pad:
+// ScratchpadReadTool and ScratchpadWriteTool.
+//
+// Reading a missing key returns an empty result; reading without a key
+// returns every entry as a JSON object. The tools fail outside a scratchpad.
+//
+// Example:
+//
+//	agent := ai.Agent(client, ai.WithTools(append(memory.ScratchpadTools(), search)...))
+//	flow.Use(memory.Scratchpad(agent))
+func ScratchpadTools() []tools.Tool {
+	read := tools.Typed(ScratchpadReadTool, "Read notes saved earlier in this task from the scratchpad",
+		func(ctx context.Context, args scratchpadReadArgs) (string, error) {
+			pad, err := padForTool(ctx)
+			if err != nil {
+				return "", err
+			}
+			if args.Key != "" {
+				value, _ := pad.Get(args.Key)
+				return value, nil
+			}
+			data, err := json.Marshal(pad.Snapshot())
+			return string(data), err
+		})
+	write := tools.Typed(ScratchpadWriteTool, "Save a note to the scratchpad for later steps of this task",
+		func(ctx context.Context, args scratchpadWriteArgs) (string, error) {
+			pad, err := padForTool(ctx)
+			if err != nil {
+				return "", err
+			}
+			if args.Key == "" {
+				return "", calque.InvalidInput(calque.NewErr(ctx, "scratchpad key is required"))
+			}
+			if args.Value == "" {
+				pad.Delete(args.Key)
+				return "deleted " + args.Key, nil
+			}
+			pad.Set(args.Key, args.Value)
+			return "saved " + args.Key, nil
+		})
+	return []tools.Tool{read, write}
+}
+
+func padForTool(ctx context.Context) (*Pad, error) {
+	pad, ok := PadFrom(ctx)
+	if !ok {
+		return nil, calque.NewErr(ctx, "no scratchpad in this run")
+	}
+	return pad, nil
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func callScratchpadTool(t *testing.T, ctx context.Context, name, args string) (string, error) {
+	t.Helper()
+	for _, tool := range ScratchpadTools() {
+		if tool.Name() == name {
+			var out bytes.Buffer
+			err := tool.ServeFlow(calque.NewRequest(ctx, strings.NewReader(args)), calque.NewResponse(&out))
+			return out.String(), err
+		}
+	}
+	t.Fatalf("no tool %s", name)
+	return "", nil
+}
+
+func TestScratchpadSharedWithinRun(t *testing.T) {
+	flow := calque.NewFlow().
+		Use(calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+			var in string
+			if err := calque.Read(req, &in); err != nil {
+				return err
+			}
+			pad, _ := PadFrom(req.Context)
+			pad.Set("city", "Lisbon")
+			return calque.Write(res, in)
+		})).
+		Use(Note("question")).
+		Use(Recall("Facts:\n"))
+
+	var out string
+	if err := calque.NewFlow().Use(Scratchpad(flow)).Run(context.Background(), "weather?", &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Facts:\ncity: Lisbon\nquestion: weather?\n\nweather?"; out != want {
+		t.Errorf("out = %q, want %q", out, want)
+	}
+
+	// another run starts with an empty pad
+	var again string
+	if err := calque.NewFlow().Use(Scratchpad(Recall("Facts:\n"))).Run(context.Background(), "x", &again); err != nil || again != "x" {
+		t.Errorf("second run = %q, %v", again, err)
+	}
+}
+
+func TestScratchpadTools(t *testing.T) {
+	ctx := WithScratchpad(context.Background())
+	if out, err := callScratchpadTool(t, ctx, ScratchpadWriteTool, `{"key": "lead", "value": "Ada"}`); err != nil || out != "saved lead" {
+		t.Fatalf("write = %q, %v", out, err)
+	}
+	if out, _ := callScratchpadTool(t, ctx, ScratchpadReadTool, `{"key": "lead"}`); out != "Ada" {
+		t.Errorf("read = %q", out)
+	}
+	if out, _ := callScratchpadTool(t, ctx, ScratchpadReadTool, `{}`); out != `{"lead":"Ada"}` {
+		t.Errorf("read all = %q", out)
+	}
+	if _, err := callScratchpadTool(t, ctx, ScratchpadWriteTool, `{"key": "lead", "value": ""}`); err != nil {
+		t.Fatal(err)
+	}
+	if pad, _ := PadFrom(ctx); len(pad.Keys()) != 0 {
+		t.Errorf("keys after delete = %v", pad.Keys())
+	}
+
+	if _, err := callScratchpadTool(t, context.Background(), ScratchpadReadTool, `{}`); err == nil {
+		t.Error("read outside a scratchpad should fail")
+	}
+}
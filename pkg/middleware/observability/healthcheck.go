@@ -323,6 +323,42 @@ func (c *FuncHealthCheck) Timeout() time.Duration {
 	return c.CheckTimeout
 }
 
+// SaturationHealthCheck fails when a concurrency limit is nearly exhausted.
+//
+// Use it as a readiness check so a saturated instance stops receiving new
+// traffic until in-flight work drains, e.g. Usage: flow.Concurrency.
+type SaturationHealthCheck struct {
+	CheckName string                    // Name shown in health report
+	Usage     func() (inUse, limit int) // Current use and capacity (limit 0 = unlimited)
+	Threshold float64                   // Fraction of the limit in use that fails the check (0 = 1.0, fully saturated)
+}
+
+// Name returns the name of this health check
+func (c *SaturationHealthCheck) Name() string {
+	return c.CheckName
+}
+
+// Check performs the saturation check
+func (c *SaturationHealthCheck) Check(ctx context.Context) error {
+	inUse, limit := c.Usage()
+	if limit <= 0 {
+		return nil
+	}
+	threshold := c.Threshold
+	if threshold <= 0 {
+		threshold = 1
+	}
+	if float64(inUse) >= threshold*float64(limit) {
+		return calque.NewErr(ctx, fmt.Sprintf("saturated: %d of %d slots in use", inUse, limit))
+	}
+	return nil
+}
+
+// Timeout returns the timeout for this health check
+func (c *SaturationHealthCheck) Timeout() time.Duration {
+	return 0
+}
+
 // HealthCheckRegistry manages a dynamic collection of health checks.
 //
 // Use the registry when you need to add/remove health checks at runtime,
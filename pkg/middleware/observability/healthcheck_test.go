@@ -201,6 +201,36 @@ func TestFuncHealthCheck(t *testing.T) {
 	}
 }
 
+func TestSaturationHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		inUse     int
+		limit     int
+		threshold float64
+		wantErr   bool
+	}{
+		{name: "unlimited", inUse: 500, limit: 0},
+		{name: "below limit", inUse: 9, limit: 10},
+		{name: "full", inUse: 10, limit: 10, wantErr: true},
+		{name: "below threshold", inUse: 8, limit: 10, threshold: 0.9},
+		{name: "at threshold", inUse: 9, limit: 10, threshold: 0.9, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := &SaturationHealthCheck{
+				CheckName: "flow",
+				Usage:     func() (int, int) { return tt.inUse, tt.limit },
+				Threshold: tt.threshold,
+			}
+			if err := check.Check(context.Background()); (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestHealthCheckRegistry(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,224 @@
+package observability
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/secrets"
+)
+
+// DefaultLangfuseHost is the Langfuse cloud endpoint.
+const DefaultLangfuseHost = "https://cloud.langfuse.com"
+
+// LangfuseConfig configures the Langfuse exporter.
+type LangfuseConfig struct {
+	// Host is the Langfuse base URL. Default: DefaultLangfuseHost
+	Host string
+
+	// PublicKey and SecretKey are the project API keys (pk-lf-..., sk-lf-...)
+	PublicKey string
+	SecretKey string
+
+	// SecretKeyRef resolves the secret key per export so rotated keys are picked
+	// up without restarting. Takes precedence over SecretKey.
+	SecretKeyRef *secrets.Ref
+
+	// Release and Environment are attached to every trace (optional)
+	Release     string
+	Environment string
+
+	// BatchSize is the number of spans sent per request. Default: 100
+	BatchSize int
+
+	// FlushInterval is the maximum time spans are buffered. Default: 5 seconds
+	FlushInterval time.Duration
+
+	// HTTPClient is used for export requests. Default: client with 10s timeout
+	HTTPClient *http.Client
+
+	// OnError is called when a background export fails (optional)
+	OnError func(error)
+}
+
+// NewLangfuseTracerProvider creates a TracerProvider that exports spans to Langfuse.
+//
+// Root spans become Langfuse traces, nested spans become observations and spans
+// with generation attributes (see RecordGeneration) become generations with
+// model, token usage and cost.
+//
+// Example:
+//
+//	provider, err := observability.NewLangfuseTracerProvider(observability.LangfuseConfig{
+//		PublicKey: os.Getenv("LANGFUSE_PUBLIC_KEY"),
+//		SecretKey: os.Getenv("LANGFUSE_SECRET_KEY"),
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer provider.Shutdown(context.Background())
+//
+//	flow := calque.NewFlow().
+//		Use(observability.TracingHandler(provider, "chat", agent, observability.WithRecordInput(), observability.WithRecordOutput()))
+func NewLangfuseTracerProvider(cfg LangfuseConfig) (*LLMTracerProvider, error) {
+	if cfg.PublicKey == "" || (cfg.SecretKey == "" && cfg.SecretKeyRef == nil) {
+		return nil, calque.NewErr(context.Background(), "langfuse public and secret keys are required")
+	}
+	if cfg.Host == "" {
+		cfg.Host = DefaultLangfuseHost
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	exporter := &langfuseExporter{
+		url:         strings.TrimSuffix(cfg.Host, "/") + "/api/public/ingestion",
+		publicKey:   cfg.PublicKey,
+		secretKey:   cfg.SecretKey,
+		secretRef:   cfg.SecretKeyRef,
+		release:     cfg.Release,
+		environment: cfg.Environment,
+		client:      cfg.HTTPClient,
+	}
+	return newLLMTracerProvider(exporter, cfg.BatchSize, cfg.FlushInterval, cfg.OnError), nil
+}
+
+// langfuseExporter sends spans to the Langfuse batch ingestion API
+type langfuseExporter struct {
+	url         string
+	publicKey   string
+	secretKey   string
+	secretRef   *secrets.Ref
+	release     string
+	environment string
+	client      *http.Client
+}
+
+type langfuseEvent struct {
+	ID        string         `json:"id"`
+	Type      string         `json:"type"`
+	Timestamp any            `json:"timestamp"`
+	Body      map[string]any `json:"body"`
+}
+
+type langfuseResponse struct {
+	Errors []struct {
+		ID      string `json:"id"`
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (e *langfuseExporter) export(ctx context.Context, spans []*exportSpan) error {
+	events := make([]langfuseEvent, 0, len(spans)*2)
+	for _, s := range spans {
+		events = append(events, e.events(s)...)
+	}
+
+	secretKey, err := secrets.ResolveOr(ctx, e.secretRef, e.secretKey)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "langfuse")
+	}
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(e.publicKey+":"+secretKey))
+
+	respBody, err := postJSON(ctx, e.client, e.url, map[string]string{"Authorization": auth},
+		map[string]any{"batch": events})
+	if err != nil {
+		return calque.WrapErr(ctx, err, "langfuse")
+	}
+
+	// Ingestion returns 207 with per-event errors
+	var resp langfuseResponse
+	if json.Unmarshal(respBody, &resp) == nil && len(resp.Errors) > 0 {
+		first := resp.Errors[0]
+		return calque.NewErr(ctx, fmt.Sprintf("langfuse: %d of %d events rejected (first: %d %s)",
+			len(resp.Errors), len(events), first.Status, first.Message))
+	}
+	return nil
+}
+
+// events converts a span into Langfuse ingestion events
+func (e *langfuseExporter) events(s *exportSpan) []langfuseEvent {
+	data := s.snapshot()
+	now := exportTime(time.Now())
+	var events []langfuseEvent
+
+	if s.parentID == "" {
+		trace := map[string]any{
+			"id":        s.traceID,
+			"name":      s.name,
+			"timestamp": exportTime(s.start),
+		}
+		setIfNotEmpty(trace, "input", data.input)
+		setIfNotEmpty(trace, "output", data.output)
+		setIfNotEmpty(trace, "release", e.release)
+		setIfNotEmpty(trace, "environment", e.environment)
+		if len(data.attributes) > 0 {
+			trace["metadata"] = data.attributes
+		}
+		events = append(events, langfuseEvent{ID: uuid.NewString(), Type: "trace-create", Timestamp: now, Body: trace})
+	}
+
+	obs := map[string]any{
+		"id":        s.spanID,
+		"traceId":   s.traceID,
+		"name":      s.name,
+		"startTime": exportTime(s.start),
+		"endTime":   exportTime(data.end),
+	}
+	setIfNotEmpty(obs, "parentObservationId", s.parentID)
+	setIfNotEmpty(obs, "input", data.input)
+	setIfNotEmpty(obs, "output", data.output)
+	setIfNotEmpty(obs, "environment", e.environment)
+	if len(data.attributes) > 0 {
+		obs["metadata"] = data.attributes
+	}
+	if msg := data.errorMessage(); msg != "" {
+		obs["level"] = "ERROR"
+		obs["statusMessage"] = msg
+	}
+
+	eventType := "span-create"
+	if data.isGeneration() {
+		eventType = "generation-create"
+		setIfNotEmpty(obs, "model", data.model)
+		obs["usageDetails"] = map[string]int{
+			"input":  data.inputTokens,
+			"output": data.outputTokens,
+			"total":  data.inputTokens + data.outputTokens,
+		}
+		if data.cost > 0 {
+			obs["costDetails"] = map[string]float64{"total": data.cost}
+		}
+	}
+	events = append(events, langfuseEvent{ID: uuid.NewString(), Type: eventType, Timestamp: now, Body: obs})
+
+	for _, ev := range data.events {
+		body := map[string]any{
+			"id":                  uuid.NewString(),
+			"traceId":             s.traceID,
+			"parentObservationId": s.spanID,
+			"name":                ev.Name,
+			"startTime":           exportTime(ev.Time),
+		}
+		if len(ev.Attributes) > 0 {
+			body["metadata"] = ev.Attributes
+		}
+		events = append(events, langfuseEvent{ID: uuid.NewString(), Type: "event-create", Timestamp: now, Body: body})
+	}
+	return events
+}
+
+// setIfNotEmpty sets a string field only when it has a value
+func setIfNotEmpty(m map[string]any, key, value string) {
+	if value != "" {
+		m[key] = value
+	}
+}
@@ -0,0 +1,171 @@
+package observability
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/secrets"
+)
+
+// DefaultLangSmithEndpoint is the LangSmith cloud API endpoint.
+const DefaultLangSmithEndpoint = "https://api.smith.langchain.com"
+
+// LangSmithConfig configures the LangSmith exporter.
+type LangSmithConfig struct {
+	// Endpoint is the LangSmith API base URL. Default: DefaultLangSmithEndpoint
+	Endpoint string
+
+	// APIKey is the LangSmith API key (lsv2_...)
+	APIKey string
+
+	// APIKeyRef resolves the API key per export so rotated keys are picked up
+	// without restarting. Takes precedence over APIKey.
+	APIKeyRef *secrets.Ref
+
+	// Project is the LangSmith project (session) runs are logged to. Default: "default"
+	Project string
+
+	// BatchSize is the number of runs sent per request. Default: 100
+	BatchSize int
+
+	// FlushInterval is the maximum time runs are buffered. Default: 5 seconds
+	FlushInterval time.Duration
+
+	// HTTPClient is used for export requests. Default: client with 10s timeout
+	HTTPClient *http.Client
+
+	// OnError is called when a background export fails (optional)
+	OnError func(error)
+}
+
+// NewLangSmithTracerProvider creates a TracerProvider that exports spans to LangSmith.
+//
+// Each span becomes a LangSmith run nested under its parent. Spans with generation
+// attributes (see RecordGeneration) are logged as "llm" runs with model and token
+// usage; all others are "chain" runs.
+//
+// Example:
+//
+//	provider, err := observability.NewLangSmithTracerProvider(observability.LangSmithConfig{
+//		APIKey:  os.Getenv("LANGSMITH_API_KEY"),
+//		Project: "support-bot",
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer provider.Shutdown(context.Background())
+func NewLangSmithTracerProvider(cfg LangSmithConfig) (*LLMTracerProvider, error) {
+	if cfg.APIKey == "" && cfg.APIKeyRef == nil {
+		return nil, calque.NewErr(context.Background(), "langsmith api key is required")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = DefaultLangSmithEndpoint
+	}
+	if cfg.Project == "" {
+		cfg.Project = "default"
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	exporter := &langSmithExporter{
+		url:     strings.TrimSuffix(cfg.Endpoint, "/") + "/runs/batch",
+		apiKey:  cfg.APIKey,
+		keyRef:  cfg.APIKeyRef,
+		project: cfg.Project,
+		client:  cfg.HTTPClient,
+	}
+	return newLLMTracerProvider(exporter, cfg.BatchSize, cfg.FlushInterval, cfg.OnError), nil
+}
+
+// langSmithExporter sends spans to the LangSmith batch runs API
+type langSmithExporter struct {
+	url     string
+	apiKey  string
+	keyRef  *secrets.Ref
+	project string
+	client  *http.Client
+}
+
+func (e *langSmithExporter) export(ctx context.Context, spans []*exportSpan) error {
+	runs := make([]map[string]any, 0, len(spans))
+	for _, s := range spans {
+		runs = append(runs, e.run(s))
+	}
+
+	apiKey, err := secrets.ResolveOr(ctx, e.keyRef, e.apiKey)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "langsmith")
+	}
+
+	_, err = postJSON(ctx, e.client, e.url, map[string]string{"x-api-key": apiKey},
+		map[string]any{"post": runs})
+	if err != nil {
+		return calque.WrapErr(ctx, err, "langsmith")
+	}
+	return nil
+}
+
+// run converts a span into a LangSmith run
+func (e *langSmithExporter) run(s *exportSpan) map[string]any {
+	data := s.snapshot()
+
+	run := map[string]any{
+		"id":           s.spanID,
+		"trace_id":     s.traceID,
+		"dotted_order": s.dottedOrder,
+		"name":         s.name,
+		"run_type":     "chain",
+		"start_time":   exportTime(s.start),
+		"end_time":     exportTime(data.end),
+		"session_name": e.project,
+		"inputs":       map[string]any{"input": data.input},
+		"outputs":      map[string]any{"output": data.output},
+	}
+	if s.parentID != "" {
+		run["parent_run_id"] = s.parentID
+	}
+	if msg := data.errorMessage(); msg != "" {
+		run["error"] = msg
+	}
+
+	metadata := data.attributes
+	if data.isGeneration() {
+		run["run_type"] = "llm"
+		if data.model != "" {
+			metadata["ls_model_name"] = data.model
+		}
+		run["outputs"] = map[string]any{
+			"output": data.output,
+			"usage_metadata": map[string]int{
+				"input_tokens":  data.inputTokens,
+				"output_tokens": data.outputTokens,
+				"total_tokens":  data.inputTokens + data.outputTokens,
+			},
+		}
+		if data.cost > 0 {
+			run["total_cost"] = data.cost
+		}
+	}
+	if len(metadata) > 0 {
+		run["extra"] = map[string]any{"metadata": metadata}
+	}
+
+	if len(data.events) > 0 {
+		events := make([]map[string]any, len(data.events))
+		for i, ev := range data.events {
+			events[i] = map[string]any{"name": ev.Name, "time": exportTime(ev.Time), "kwargs": ev.Attributes}
+		}
+		run["events"] = events
+	}
+	return run
+}
+
+// langSmithOrder formats a dotted_order segment: start time followed by the run ID
+// e.g. "20250101T100000123456Z2f1c..."
+func langSmithOrder(start time.Time, id string) string {
+	return strings.Replace(start.UTC().Format("20060102T150405.000000Z"), ".", "", 1) + id
+}
@@ -0,0 +1,90 @@
+package observability
+
+import (
+	"context"
+)
+
+// Span attribute keys for LLM generations, following the OpenTelemetry GenAI
+// semantic conventions. LLM observability exporters (Langfuse, LangSmith) map
+// these onto their native generation fields; other providers record them as
+// regular attributes.
+const (
+	AttrGenAIModel        = "gen_ai.request.model"
+	AttrGenAIPrompt       = "gen_ai.prompt"
+	AttrGenAICompletion   = "gen_ai.completion"
+	AttrGenAIInputTokens  = "gen_ai.usage.input_tokens"
+	AttrGenAIOutputTokens = "gen_ai.usage.output_tokens"
+	AttrGenAICost         = "gen_ai.usage.cost"
+)
+
+// Generation describes a single LLM call recorded on a span.
+type Generation struct {
+	Model            string  // model identifier, e.g. "gpt-4o"
+	Prompt           string  // prompt sent to the model (optional)
+	Completion       string  // model response (optional)
+	PromptTokens     int     // input token count
+	CompletionTokens int     // output token count
+	Cost             float64 // total cost in USD (optional)
+}
+
+type spanContextKey struct{}
+
+// contextWithSpan stores a span in the context for SpanFromContext
+func contextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// SpanFromContext returns the span started by Tracing or TracingHandler for the
+// current request, or a no-op span if there is none.
+//
+// Example:
+//
+//	span := observability.SpanFromContext(req.Context)
+//	span.SetAttribute("cache_hit", true)
+func SpanFromContext(ctx context.Context) Span {
+	if span, ok := ctx.Value(spanContextKey{}).(Span); ok && span != nil {
+		return span
+	}
+	return &noopSpan{}
+}
+
+// RecordGeneration annotates the current span with LLM generation details.
+//
+// Langfuse and LangSmith exporters report spans carrying a model as generations
+// (LLM runs) with prompt, completion, token usage and cost. Zero-valued fields
+// are skipped.
+//
+// Example - capture token usage from an agent inside a traced handler:
+//
+//	traced := observability.TracingHandler(provider, "chat", calque.HandlerFunc(
+//		func(req *calque.Request, res *calque.Response) error {
+//			agent := ai.Agent(client, ai.WithUsageHandler(func(u *ai.UsageMetadata) {
+//				observability.RecordGeneration(req.Context, observability.Generation{
+//					Model:            "gpt-4o",
+//					PromptTokens:     u.PromptTokens,
+//					CompletionTokens: u.CompletionTokens,
+//				})
+//			}))
+//			return agent.ServeFlow(req, res)
+//		}))
+func RecordGeneration(ctx context.Context, gen Generation) {
+	span := SpanFromContext(ctx)
+	if gen.Model != "" {
+		span.SetAttribute(AttrGenAIModel, gen.Model)
+	}
+	if gen.Prompt != "" {
+		span.SetAttribute(AttrGenAIPrompt, gen.Prompt)
+	}
+	if gen.Completion != "" {
+		span.SetAttribute(AttrGenAICompletion, gen.Completion)
+	}
+	if gen.PromptTokens > 0 {
+		span.SetAttribute(AttrGenAIInputTokens, gen.PromptTokens)
+	}
+	if gen.CompletionTokens > 0 {
+		span.SetAttribute(AttrGenAIOutputTokens, gen.CompletionTokens)
+	}
+	if gen.Cost > 0 {
+		span.SetAttribute(AttrGenAICost, gen.Cost)
+	}
+}
@@ -0,0 +1,439 @@
+package observability
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Defaults for the Langfuse and LangSmith exporters.
+const (
+	DefaultLLMExportBatchSize     = 100
+	DefaultLLMExportFlushInterval = 5 * time.Second
+)
+
+// spanExporter sends a batch of finished spans to an LLM observability backend
+type spanExporter interface {
+	export(ctx context.Context, spans []*exportSpan) error
+}
+
+// LLMTracerProvider implements TracerProvider for LLM observability backends.
+//
+// Finished spans are buffered and sent in batches, either when BatchSize spans
+// are pending or every FlushInterval. Spans carrying generation attributes (see
+// RecordGeneration) are reported as LLM generations with token usage and cost.
+//
+// Create one with NewLangfuseTracerProvider or NewLangSmithTracerProvider.
+// Always call Shutdown before exiting to flush pending spans.
+type LLMTracerProvider struct {
+	exporter  spanExporter
+	batchSize int
+	onError   func(error)
+
+	mu      sync.Mutex
+	pending []*exportSpan
+	closed  bool
+
+	flushCh chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// newLLMTracerProvider starts the background flush loop for an exporter
+func newLLMTracerProvider(exporter spanExporter, batchSize int, interval time.Duration, onError func(error)) *LLMTracerProvider {
+	if batchSize <= 0 {
+		batchSize = DefaultLLMExportBatchSize
+	}
+	if interval <= 0 {
+		interval = DefaultLLMExportFlushInterval
+	}
+
+	p := &LLMTracerProvider{
+		exporter:  exporter,
+		batchSize: batchSize,
+		onError:   onError,
+		flushCh:   make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+
+	p.wg.Add(1)
+	go p.loop(interval)
+	return p
+}
+
+// StartSpan starts a new span, nested under the span in ctx if there is one
+func (p *LLMTracerProvider) StartSpan(ctx context.Context, name string, opts ...SpanOption) (context.Context, Span) {
+	cfg := &spanConfig{
+		kind:       SpanKindInternal,
+		attributes: make(map[string]any),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	span := newExportSpan(name, time.Now(), parentExportSpan(ctx))
+	span.provider = p
+	maps.Copy(span.attributes, cfg.attributes)
+
+	return contextWithSpan(ctx, span), span
+}
+
+// ExportFlowTrace sends a completed calque.FlowTrace as a flow span with one child
+// span per handler, using the recorded timings and payload previews.
+//
+// Example:
+//
+//	trace, err := flow.RunTraced(ctx, input, &output)
+//	provider.ExportFlowTrace(ctx, trace)
+func (p *LLMTracerProvider) ExportFlowTrace(ctx context.Context, trace *calque.FlowTrace) {
+	if trace == nil {
+		return
+	}
+
+	name := trace.Flow
+	if name == "" {
+		name = "flow"
+	}
+	root := newExportSpan(name, trace.Start, parentExportSpan(ctx))
+	root.end = trace.End
+	if trace.TraceID != "" {
+		root.attributes["calque.trace_id"] = trace.TraceID
+	}
+	if trace.RequestID != "" {
+		root.attributes["calque.request_id"] = trace.RequestID
+	}
+	if trace.Error != "" {
+		root.status = SpanStatusError
+		root.statusDesc = trace.Error
+	}
+
+	spans := []*exportSpan{root}
+	for _, h := range trace.Handlers {
+		if h.Status == calque.TraceStatusPending {
+			continue
+		}
+		child := newExportSpan(h.Name, h.Start, root)
+		child.end = h.End
+		child.attributes["calque.handler_index"] = h.Index
+		child.attributes["calque.bytes_in"] = h.BytesIn
+		child.attributes["calque.bytes_out"] = h.BytesOut
+		if h.InputPreview != "" {
+			child.attributes[attrInput] = h.InputPreview
+		}
+		if h.OutputPreview != "" {
+			child.attributes[attrOutput] = h.OutputPreview
+		}
+		if h.Error != "" {
+			child.status = SpanStatusError
+			child.statusDesc = h.Error
+		}
+		spans = append(spans, child)
+	}
+
+	// Flow input and output come from the first and last handlers
+	if len(spans) > 1 {
+		if in, ok := spans[1].attributes[attrInput]; ok {
+			root.attributes[attrInput] = in
+		}
+		if out, ok := spans[len(spans)-1].attributes[attrOutput]; ok {
+			root.attributes[attrOutput] = out
+		}
+	}
+
+	for _, s := range spans {
+		p.enqueue(s)
+	}
+}
+
+// Flush sends all pending spans immediately.
+func (p *LLMTracerProvider) Flush(ctx context.Context) error {
+	p.mu.Lock()
+	batch := p.pending
+	p.pending = nil
+	p.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	return p.exporter.export(ctx, batch)
+}
+
+// Shutdown stops the background flush loop and sends pending spans.
+func (p *LLMTracerProvider) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	close(p.done)
+	p.wg.Wait()
+	return p.Flush(ctx)
+}
+
+// enqueue adds a finished span and triggers a flush when the batch is full
+func (p *LLMTracerProvider) enqueue(span *exportSpan) {
+	p.mu.Lock()
+	p.pending = append(p.pending, span)
+	full := len(p.pending) >= p.batchSize
+	p.mu.Unlock()
+
+	if full {
+		select {
+		case p.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// loop flushes on a timer or when signalled by enqueue
+func (p *LLMTracerProvider) loop(interval time.Duration) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		case <-p.flushCh:
+		}
+		p.reportError(p.Flush(context.Background()))
+	}
+}
+
+func (p *LLMTracerProvider) reportError(err error) {
+	if err != nil && p.onError != nil {
+		p.onError(err)
+	}
+}
+
+// exportSpan is a span buffered for export to an LLM observability backend
+type exportSpan struct {
+	mu         sync.Mutex
+	provider   *LLMTracerProvider
+	name       string
+	traceID    string
+	spanID     string
+	parentID   string
+	start      time.Time
+	end        time.Time
+	attributes map[string]any
+	events     []RecordedEvent
+	status     SpanStatus
+	statusDesc string
+	ended      bool
+
+	// dottedOrder is LangSmith's hierarchical ordering key
+	dottedOrder string
+}
+
+func newExportSpan(name string, start time.Time, parent *exportSpan) *exportSpan {
+	span := &exportSpan{
+		name:       name,
+		spanID:     uuid.NewString(),
+		start:      start,
+		attributes: make(map[string]any),
+	}
+	order := langSmithOrder(start, span.spanID)
+	if parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+		span.dottedOrder = parent.dottedOrder + "." + order
+	} else {
+		span.traceID = span.spanID
+		span.dottedOrder = order
+	}
+	return span
+}
+
+// parentExportSpan returns the exportSpan stored in ctx, if any
+func parentExportSpan(ctx context.Context) *exportSpan {
+	if span, ok := SpanFromContext(ctx).(*exportSpan); ok {
+		return span
+	}
+	return nil
+}
+
+// End ends the span and queues it for export
+func (s *exportSpan) End(err error) {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	if err != nil {
+		s.status = SpanStatusError
+		if s.statusDesc == "" {
+			s.statusDesc = err.Error()
+		}
+	}
+	s.mu.Unlock()
+
+	if s.provider != nil {
+		s.provider.enqueue(s)
+	}
+}
+
+// SetAttribute sets a key-value attribute
+func (s *exportSpan) SetAttribute(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes[key] = value
+}
+
+// AddEvent adds a timestamped event to the span
+func (s *exportSpan) AddEvent(name string, attrs map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, RecordedEvent{Name: name, Attributes: attrs, Time: time.Now()})
+}
+
+// SetStatus sets the span status
+func (s *exportSpan) SetStatus(code SpanStatus, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = code
+	s.statusDesc = description
+}
+
+// SpanContext returns the span's trace and span IDs
+func (s *exportSpan) SpanContext() SpanContext {
+	return SpanContext{TraceID: s.traceID, SpanID: s.spanID}
+}
+
+// snapshot returns a consistent copy of the span's mutable fields for export
+func (s *exportSpan) snapshot() exportSpanData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := exportSpanData{
+		attributes: maps.Clone(s.attributes),
+		status:     s.status,
+		statusDesc: s.statusDesc,
+		end:        s.end,
+		events:     append([]RecordedEvent(nil), s.events...),
+	}
+	data.input, _ = data.attributes[attrInput].(string)
+	data.output, _ = data.attributes[attrOutput].(string)
+	if prompt, ok := data.attributes[AttrGenAIPrompt].(string); ok {
+		data.input = prompt
+	}
+	if completion, ok := data.attributes[AttrGenAICompletion].(string); ok {
+		data.output = completion
+	}
+	data.model, _ = data.attributes[AttrGenAIModel].(string)
+	data.inputTokens = toInt(data.attributes[AttrGenAIInputTokens])
+	data.outputTokens = toInt(data.attributes[AttrGenAIOutputTokens])
+	data.cost, _ = data.attributes[AttrGenAICost].(float64)
+
+	// Everything not mapped to a native field is reported as metadata
+	for _, key := range []string{attrInput, attrOutput, attrError, AttrGenAIPrompt, AttrGenAICompletion,
+		AttrGenAIModel, AttrGenAIInputTokens, AttrGenAIOutputTokens, AttrGenAICost} {
+		delete(data.attributes, key)
+	}
+	return data
+}
+
+// exportSpanData is the exported view of a span
+type exportSpanData struct {
+	attributes   map[string]any
+	status       SpanStatus
+	statusDesc   string
+	end          time.Time
+	events       []RecordedEvent
+	input        string
+	output       string
+	model        string
+	inputTokens  int
+	outputTokens int
+	cost         float64
+}
+
+// isGeneration reports whether the span describes an LLM call
+func (d exportSpanData) isGeneration() bool {
+	return d.model != "" || d.inputTokens > 0 || d.outputTokens > 0
+}
+
+// errorMessage returns the span error, or empty if it succeeded
+func (d exportSpanData) errorMessage() string {
+	if d.status != SpanStatusError {
+		return ""
+	}
+	if d.statusDesc == "" {
+		return "error"
+	}
+	return d.statusDesc
+}
+
+const (
+	attrInput  = "input"
+	attrOutput = "output"
+	attrError  = "error"
+)
+
+func toInt(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
+// postJSON sends a JSON payload and fails on non-2xx responses, returning the response body
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload any) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to encode export payload")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "export request failed")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, calque.NewErr(ctx, fmt.Sprintf("export failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody))))
+	}
+	return respBody, nil
+}
+
+// exportTime formats timestamps as RFC 3339 with microseconds, or nil if unset
+func exportTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format("2006-01-02T15:04:05.000000Z07:00")
+}
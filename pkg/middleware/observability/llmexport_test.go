@@ -0,0 +1,221 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// captureServer records JSON request bodies sent to it
+type captureServer struct {
+	mu      sync.Mutex
+	bodies  []map[string]any
+	headers []http.Header
+}
+
+func (c *captureServer) handler(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	c.mu.Lock()
+	c.bodies = append(c.bodies, body)
+	c.headers = append(c.headers, r.Header.Clone())
+	c.mu.Unlock()
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = w.Write([]byte(`{"successes":[],"errors":[]}`))
+}
+
+func upperHandler() calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var input string
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+		RecordGeneration(req.Context, Generation{Model: "test-model", PromptTokens: 3, CompletionTokens: 5, Cost: 0.01})
+		return calque.Write(res, strings.ToUpper(input))
+	})
+}
+
+func TestLangfuseTracerProvider(t *testing.T) {
+	capture := &captureServer{}
+	srv := httptest.NewServer(http.HandlerFunc(capture.handler))
+	defer srv.Close()
+
+	provider, err := NewLangfuseTracerProvider(LangfuseConfig{
+		Host:        srv.URL,
+		PublicKey:   "pk",
+		SecretKey:   "sk",
+		Environment: "test",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	flow := calque.NewFlow().
+		Use(TracingHandler(provider, "pipeline",
+			TracingHandler(provider, "chat", upperHandler(), WithRecordInput(), WithRecordOutput())))
+
+	var out string
+	if err := flow.Run(context.Background(), "hello", &out); err != nil {
+		t.Fatalf("flow failed: %v", err)
+	}
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	if len(capture.bodies) != 1 {
+		t.Fatalf("expected 1 export request, got %d", len(capture.bodies))
+	}
+	if user, pass, ok := (&http.Request{Header: capture.headers[0]}).BasicAuth(); !ok || user != "pk" || pass != "sk" {
+		t.Errorf("expected basic auth pk/sk, got %q/%q", user, pass)
+	}
+
+	types := map[string]map[string]any{}
+	for _, ev := range capture.bodies[0]["batch"].([]any) {
+		event := ev.(map[string]any)
+		types[event["type"].(string)] = event["body"].(map[string]any)
+	}
+
+	trace, ok := types["trace-create"]
+	if !ok || trace["name"] != "pipeline" {
+		t.Fatalf("expected trace-create for root span, got %v", types)
+	}
+	gen, ok := types["generation-create"]
+	if !ok {
+		t.Fatalf("expected generation-create event, got %v", types)
+	}
+	if gen["model"] != "test-model" || gen["input"] != "hello" || gen["output"] != "HELLO" {
+		t.Errorf("unexpected generation body: %v", gen)
+	}
+	if gen["traceId"] != trace["id"] || gen["parentObservationId"] == nil {
+		t.Errorf("expected generation nested in trace, got %v", gen)
+	}
+	usage := gen["usageDetails"].(map[string]any)
+	if usage["input"] != float64(3) || usage["output"] != float64(5) || usage["total"] != float64(8) {
+		t.Errorf("unexpected usage: %v", usage)
+	}
+	if _, ok := types["span-create"]; !ok {
+		t.Error("expected span-create for the outer span")
+	}
+}
+
+func TestLangSmithTracerProvider(t *testing.T) {
+	capture := &captureServer{}
+	srv := httptest.NewServer(http.HandlerFunc(capture.handler))
+	defer srv.Close()
+
+	provider, err := NewLangSmithTracerProvider(LangSmithConfig{Endpoint: srv.URL, APIKey: "key", Project: "proj"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, root := provider.StartSpan(context.Background(), "root")
+	childCtx, child := provider.StartSpan(ctx, "llm-call")
+	RecordGeneration(childCtx, Generation{Model: "m", PromptTokens: 1, CompletionTokens: 2})
+	child.End(nil)
+	root.End(nil)
+
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if capture.headers[0].Get("x-api-key") != "key" {
+		t.Errorf("expected api key header")
+	}
+
+	runs := capture.bodies[0]["post"].([]any)
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 runs, got %d", len(runs))
+	}
+	llm, chain := runs[0].(map[string]any), runs[1].(map[string]any)
+	if llm["run_type"] != "llm" || chain["run_type"] != "chain" {
+		t.Errorf("unexpected run types: %v / %v", llm["run_type"], chain["run_type"])
+	}
+	if llm["parent_run_id"] != chain["id"] || llm["trace_id"] != chain["id"] {
+		t.Errorf("expected child run under root, got %v", llm)
+	}
+	if !strings.HasPrefix(llm["dotted_order"].(string), chain["dotted_order"].(string)+".") {
+		t.Errorf("expected child dotted_order to extend parent, got %q", llm["dotted_order"])
+	}
+	if chain["session_name"] != "proj" {
+		t.Errorf("expected project 'proj', got %v", chain["session_name"])
+	}
+}
+
+func TestLLMTracerProvider_ExportFlowTrace(t *testing.T) {
+	capture := &captureServer{}
+	srv := httptest.NewServer(http.HandlerFunc(capture.handler))
+	defer srv.Close()
+
+	provider, err := NewLangSmithTracerProvider(LangSmithConfig{Endpoint: srv.URL, APIKey: "key"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	flow := calque.NewFlow(calque.FlowConfig{Name: "upper"}).Use(upperHandler())
+	var out string
+	trace, err := flow.RunTraced(context.Background(), "hi", &out)
+	if err != nil {
+		t.Fatalf("flow failed: %v", err)
+	}
+	provider.ExportFlowTrace(context.Background(), trace)
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	runs := capture.bodies[0]["post"].([]any)
+	if len(runs) != 2 {
+		t.Fatalf("expected flow run and handler run, got %d", len(runs))
+	}
+	root := runs[0].(map[string]any)
+	if root["name"] != "upper" || root["outputs"].(map[string]any)["output"] != "HI" {
+		t.Errorf("unexpected flow run: %v", root)
+	}
+}
+
+func TestLLMTracerProvider_Validation(t *testing.T) {
+	if _, err := NewLangfuseTracerProvider(LangfuseConfig{}); err == nil {
+		t.Error("expected error for missing langfuse keys")
+	}
+	if _, err := NewLangSmithTracerProvider(LangSmithConfig{}); err == nil {
+		t.Error("expected error for missing langsmith api key")
+	}
+}
+
+func TestLLMTracerProvider_ExportError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "bad key", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	provider, err := NewLangSmithTracerProvider(LangSmithConfig{Endpoint: srv.URL, APIKey: "key"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, span := provider.StartSpan(context.Background(), "op")
+	span.End(nil)
+
+	if err := provider.Shutdown(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected 401 export error, got %v", err)
+	}
+}
+
+func TestSpanFromContext(t *testing.T) {
+	if _, ok := SpanFromContext(context.Background()).(*noopSpan); !ok {
+		t.Error("expected noop span without tracing")
+	}
+
+	provider := NewInMemoryTracerProvider()
+	handler := TracingHandler(provider, "op", upperHandler())
+	req := calque.NewRequest(context.Background(), strings.NewReader("x"))
+	if err := handler.ServeFlow(req, calque.NewResponse(calque.NewWriter[string]())); err != nil {
+		t.Fatalf("handler failed: %v", err)
+	}
+	if got := provider.GetSpans()[0].Attributes[AttrGenAIModel]; got != "test-model" {
+		t.Errorf("expected generation recorded on span, got %v", got)
+	}
+}
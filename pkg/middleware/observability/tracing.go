@@ -111,6 +111,7 @@ func Tracing(provider TracerProvider, operationName string, opts ...TracingOptio
 
 		// Start a new span
 		ctx, span := provider.StartSpan(ctx, operationName, WithSpanKind(SpanKindInternal))
+		ctx = contextWithSpan(ctx, span)
 
 		// Optionally record input
 		if cfg.RecordInput {
@@ -187,6 +188,7 @@ func TracingHandler(provider TracerProvider, operationName string, handler calqu
 
 		// Start a new span
 		ctx, span := provider.StartSpan(ctx, operationName, WithSpanKind(SpanKindInternal))
+		ctx = contextWithSpan(ctx, span)
 
 		// Update request context with span context
 		req = req.WithContext(ctx)
@@ -0,0 +1,388 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// VersionMetadataPrefix is the MetadataBus key prefix used to tag which prompt version ran.
+// The full key is VersionMetadataPrefix + prompt name (e.g. "prompt.summarize").
+const VersionMetadataPrefix = "prompt."
+
+type overrideKey struct{}
+
+// Version is a single named, versioned prompt template.
+type Version struct {
+	Name     string
+	Version  string
+	Template string
+	Metadata map[string]string
+
+	parsed *template.Template
+}
+
+// Source loads prompt versions from an external store (database, config service, etc.).
+type Source interface {
+	Load(ctx context.Context) ([]Version, error)
+}
+
+// SourceFunc adapts a function to the Source interface.
+type SourceFunc func(ctx context.Context) ([]Version, error)
+
+// Load implements Source.
+func (f SourceFunc) Load(ctx context.Context) ([]Version, error) {
+	return f(ctx)
+}
+
+// Registry stores named, versioned prompt templates.
+//
+// Each prompt name can have many versions. Lookups resolve to, in order:
+// a per-request override from context (WithVersionOverride), a pinned
+// version (Pin), or the highest registered version.
+//
+// Example:
+//
+//	//go:embed prompts
+//	var prompts embed.FS
+//
+//	reg := prompt.NewRegistry()
+//	if err := reg.LoadFS(prompts, "prompts"); err != nil {
+//		log.Fatal(err)
+//	}
+//	reg.Pin("summarize", "v2")
+//
+//	flow.Use(reg.Use("summarize")).Use(ai.Agent(client))
+type Registry struct {
+	mu      sync.RWMutex
+	prompts map[string]map[string]*Version
+	pins    map[string]string
+	funcs   template.FuncMap
+}
+
+// NewRegistry creates an empty prompt registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		prompts: make(map[string]map[string]*Version),
+		pins:    make(map[string]string),
+	}
+}
+
+// Funcs adds template functions available to all templates registered after this call.
+func (r *Registry) Funcs(funcs template.FuncMap) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.funcs == nil {
+		r.funcs = template.FuncMap{}
+	}
+	maps.Copy(r.funcs, funcs)
+	return r
+}
+
+// Register adds a prompt version to the registry.
+//
+// The template is parsed immediately so syntax errors surface at load time.
+// Registering an existing name/version replaces it.
+//
+// Example:
+//
+//	reg.Register("summarize", "v1", "Summarize: {{.Input}}")
+//	reg.Register("summarize", "v2", "Summarize in 3 bullets: {{.Input}}", map[string]string{"author": "ana"})
+func (r *Registry) Register(name, version, tmpl string, metadata ...map[string]string) error {
+	if name == "" || version == "" {
+		return fmt.Errorf("prompt name and version are required")
+	}
+
+	v := Version{Name: name, Version: version, Template: tmpl}
+	if len(metadata) > 0 {
+		v.Metadata = maps.Clone(metadata[0])
+	}
+	return r.add(v)
+}
+
+// add parses and stores a version
+func (r *Registry) add(v Version) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := template.New(v.Name + "@" + v.Version)
+	if r.funcs != nil {
+		t = t.Funcs(r.funcs)
+	}
+	parsed, err := t.Parse(v.Template)
+	if err != nil {
+		return fmt.Errorf("failed to parse prompt %s@%s: %w", v.Name, v.Version, err)
+	}
+	v.parsed = parsed
+
+	versions, ok := r.prompts[v.Name]
+	if !ok {
+		versions = make(map[string]*Version)
+		r.prompts[v.Name] = versions
+	}
+	versions[v.Version] = &v
+	return nil
+}
+
+// LoadFS loads prompt templates from a filesystem (embed.FS, os.DirFS, fstest.MapFS).
+//
+// Files under root are registered using either layout:
+//
+//	<root>/<name>/<version>.<ext>   e.g. prompts/summarize/v2.tmpl
+//	<root>/<name>@<version>.<ext>   e.g. prompts/summarize@v2.tmpl
+//
+// Files that match neither layout are ignored.
+func (r *Registry) LoadFS(fsys fs.FS, root string) error {
+	if root == "" {
+		root = "."
+	}
+	return fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		name, version, ok := parsePromptPath(root, p)
+		if !ok {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return fmt.Errorf("failed to read prompt file %s: %w", p, err)
+		}
+		return r.add(Version{Name: name, Version: version, Template: string(data)})
+	})
+}
+
+// LoadDir loads prompt templates from a directory on disk. See LoadFS for the layout.
+func (r *Registry) LoadDir(dir string) error {
+	return r.LoadFS(os.DirFS(dir), ".")
+}
+
+// LoadSource loads prompt versions from a remote store.
+//
+// Can be called periodically to pick up newly published versions.
+func (r *Registry) LoadSource(ctx context.Context, src Source) error {
+	versions, err := src.Load(ctx)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "failed to load prompts from source")
+	}
+	for _, v := range versions {
+		if err := r.add(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pin fixes the version returned for a prompt name until Unpin is called.
+func (r *Registry) Pin(name, version string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.prompts[name][version]; !ok {
+		return fmt.Errorf("prompt %s@%s not found", name, version)
+	}
+	r.pins[name] = version
+	return nil
+}
+
+// Unpin removes a pin so the prompt resolves to its latest version again.
+func (r *Registry) Unpin(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pins, name)
+}
+
+// Names returns all registered prompt names in sorted order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.prompts))
+	for name := range r.prompts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Versions returns all versions of a prompt from oldest to newest.
+func (r *Registry) Versions(name string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return sortedVersions(r.prompts[name])
+}
+
+// Get returns the resolved version of a prompt (pinned version or latest).
+func (r *Registry) Get(name string) (*Version, error) {
+	return r.resolve(context.Background(), name)
+}
+
+// GetVersion returns a specific version of a prompt.
+func (r *Registry) GetVersion(name, version string) (*Version, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	v, ok := r.prompts[name][version]
+	if !ok {
+		return nil, fmt.Errorf("prompt %s@%s not found", name, version)
+	}
+	return v, nil
+}
+
+// resolve picks the version to run: context override, pin, then latest
+func (r *Registry) resolve(ctx context.Context, name string) (*Version, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions, ok := r.prompts[name]
+	if !ok || len(versions) == 0 {
+		return nil, fmt.Errorf("prompt %q not found", name)
+	}
+
+	if overrides, ok := ctx.Value(overrideKey{}).(map[string]string); ok {
+		if version, ok := overrides[name]; ok {
+			v, ok := versions[version]
+			if !ok {
+				return nil, fmt.Errorf("prompt override %s@%s not found", name, version)
+			}
+			return v, nil
+		}
+	}
+
+	if version, ok := r.pins[name]; ok {
+		if v, ok := versions[version]; ok {
+			return v, nil
+		}
+	}
+
+	sorted := sortedVersions(versions)
+	return versions[sorted[len(sorted)-1]], nil
+}
+
+// Use creates a handler that renders the named prompt with the input.
+//
+// Input: string data (buffered - reads entire input)
+// Output: rendered prompt
+// Behavior: BUFFERED - resolves the version at request time, then renders like FromTemplate
+//
+// The version is resolved per request, so Pin/Unpin and WithVersionOverride take
+// effect without rebuilding the flow. The version that ran is tagged on the
+// MetadataBus under VersionMetadataPrefix + name (see VersionFromContext).
+//
+// Example:
+//
+//	flow.Use(reg.Use("summarize", map[string]any{"Tone": "formal"}))
+func (r *Registry) Use(name string, data ...map[string]any) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		v, err := r.resolve(req.Context, name)
+		if err != nil {
+			return calque.WrapErr(req.Context, err, "prompt resolution failed")
+		}
+
+		if mb := calque.GetMetadataBus(req.Context); mb != nil {
+			mb.Set(VersionMetadataPrefix+name, v.Version)
+		}
+
+		return FromTemplate(v.parsed, data...).ServeFlow(req, res)
+	})
+}
+
+// WithVersionOverride returns a context that forces a prompt to a specific version.
+//
+// Overrides apply only to requests using the returned context, which makes them
+// suitable for per-tenant or per-experiment prompt selection.
+//
+// Example:
+//
+//	ctx = prompt.WithVersionOverride(ctx, "summarize", "v3-beta")
+//	flow.Run(ctx, input, &output)
+func WithVersionOverride(ctx context.Context, name, version string) context.Context {
+	overrides := map[string]string{}
+	if existing, ok := ctx.Value(overrideKey{}).(map[string]string); ok {
+		maps.Copy(overrides, existing)
+	}
+	overrides[name] = version
+	return context.WithValue(ctx, overrideKey{}, overrides)
+}
+
+// VersionFromContext returns the prompt version that was rendered in this run.
+//
+// Returns false if the prompt has not run or no MetadataBus is present.
+func VersionFromContext(ctx context.Context, name string) (string, bool) {
+	mb := calque.GetMetadataBus(ctx)
+	if mb == nil {
+		return "", false
+	}
+	return mb.GetString(VersionMetadataPrefix + name)
+}
+
+// parsePromptPath extracts name and version from a prompt file path relative to root
+func parsePromptPath(root, p string) (name, version string, ok bool) {
+	rel := strings.TrimPrefix(p, root)
+	rel = strings.TrimPrefix(rel, "/")
+	rel = strings.TrimSuffix(rel, path.Ext(rel))
+
+	if dir, file := path.Split(rel); dir != "" {
+		return strings.TrimSuffix(dir, "/"), file, file != ""
+	}
+	name, version, ok = strings.Cut(rel, "@")
+	return name, version, ok && name != "" && version != ""
+}
+
+// sortedVersions returns version keys ordered from oldest to newest
+func sortedVersions(versions map[string]*Version) []string {
+	keys := make([]string, 0, len(versions))
+	for k := range versions {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return compareVersions(keys[i], keys[j]) < 0
+	})
+	return keys
+}
+
+// compareVersions compares dotted numeric versions ("v1.10" > "v1.9"), falling back to string order
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		ai, aErr := strconv.Atoi(as[i])
+		bi, bErr := strconv.Atoi(bs[i])
+		if aErr != nil || bErr != nil {
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+			continue
+		}
+		if ai != bi {
+			if ai < bi {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	default:
+		return 0
+	}
+}
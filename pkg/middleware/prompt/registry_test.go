@@ -0,0 +1,204 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestRegistryResolution(t *testing.T) {
+	reg := NewRegistry()
+	mustRegister(t, reg, "summarize", "v1", "v1: {{.Input}}")
+	mustRegister(t, reg, "summarize", "v2", "v2: {{.Input}}")
+	mustRegister(t, reg, "summarize", "v10", "v10: {{.Input}}")
+
+	tests := []struct {
+		name     string
+		setup    func()
+		ctx      func(context.Context) context.Context
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "latest version by numeric order",
+			expected: "v10: hello",
+		},
+		{
+			name:     "pinned version",
+			setup:    func() { _ = reg.Pin("summarize", "v2") },
+			expected: "v2: hello",
+		},
+		{
+			name:  "context override wins over pin",
+			setup: func() { _ = reg.Pin("summarize", "v2") },
+			ctx: func(ctx context.Context) context.Context {
+				return WithVersionOverride(ctx, "summarize", "v1")
+			},
+			expected: "v1: hello",
+		},
+		{
+			name: "unknown override fails",
+			ctx: func(ctx context.Context) context.Context {
+				return WithVersionOverride(ctx, "summarize", "v99")
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg.Unpin("summarize")
+			if tt.setup != nil {
+				tt.setup()
+			}
+
+			mb := calque.NewMetadataBus(0)
+			defer mb.Close()
+			ctx := calque.WithMetadataBus(context.Background(), mb)
+			if tt.ctx != nil {
+				ctx = tt.ctx(ctx)
+			}
+
+			var out strings.Builder
+			err := reg.Use("summarize").ServeFlow(calque.NewRequest(ctx, strings.NewReader("hello")), calque.NewResponse(&out))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, out.String())
+			}
+
+			version, ok := VersionFromContext(ctx, "summarize")
+			if !ok || !strings.HasPrefix(tt.expected, version+":") {
+				t.Errorf("expected version tag matching %q, got %q (ok=%v)", tt.expected, version, ok)
+			}
+		})
+	}
+}
+
+func TestRegistryRegisterErrors(t *testing.T) {
+	reg := NewRegistry()
+
+	if err := reg.Register("", "v1", "x"); err == nil {
+		t.Error("expected error for empty name")
+	}
+	if err := reg.Register("bad", "v1", "{{.Input"); err == nil {
+		t.Error("expected template parse error")
+	}
+	if err := reg.Pin("missing", "v1"); err == nil {
+		t.Error("expected error pinning unknown prompt")
+	}
+	if _, err := reg.Get("missing"); err == nil {
+		t.Error("expected error getting unknown prompt")
+	}
+}
+
+func TestRegistryLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"prompts/summarize/v1.tmpl": {Data: []byte("S1 {{.Input}}")},
+		"prompts/summarize/v2.tmpl": {Data: []byte("S2 {{.Input}}")},
+		"prompts/translate@v1.txt":  {Data: []byte("T1 {{.Input}}")},
+		"prompts/README":            {Data: []byte("ignored")},
+	}
+
+	reg := NewRegistry()
+	if err := reg.LoadFS(fsys, "prompts"); err != nil {
+		t.Fatalf("LoadFS failed: %v", err)
+	}
+
+	if got := reg.Names(); strings.Join(got, ",") != "summarize,translate" {
+		t.Errorf("unexpected names: %v", got)
+	}
+	if got := reg.Versions("summarize"); strings.Join(got, ",") != "v1,v2" {
+		t.Errorf("unexpected versions: %v", got)
+	}
+
+	v, err := reg.Get("summarize")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if v.Version != "v2" || v.Template != "S2 {{.Input}}" {
+		t.Errorf("unexpected resolved version: %+v", v)
+	}
+
+	if _, err := reg.GetVersion("translate", "v1"); err != nil {
+		t.Errorf("expected translate@v1, got %v", err)
+	}
+}
+
+func TestRegistryLoadSource(t *testing.T) {
+	reg := NewRegistry()
+	src := SourceFunc(func(context.Context) ([]Version, error) {
+		return []Version{
+			{Name: "greet", Version: "2024.1", Template: "Hi {{.Input}}", Metadata: map[string]string{"owner": "team-a"}},
+		}, nil
+	})
+
+	if err := reg.LoadSource(context.Background(), src); err != nil {
+		t.Fatalf("LoadSource failed: %v", err)
+	}
+	v, err := reg.Get("greet")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if v.Metadata["owner"] != "team-a" {
+		t.Errorf("expected metadata to be preserved, got %v", v.Metadata)
+	}
+
+	failing := SourceFunc(func(context.Context) ([]Version, error) {
+		return nil, errors.New("store offline")
+	})
+	if err := reg.LoadSource(context.Background(), failing); err == nil || !strings.Contains(err.Error(), "store offline") {
+		t.Errorf("expected source error, got %v", err)
+	}
+}
+
+func TestRegistryFuncsAndData(t *testing.T) {
+	reg := NewRegistry().Funcs(template.FuncMap{"upper": strings.ToUpper})
+	mustRegister(t, reg, "shout", "v1", "{{upper .Input}} ({{.Tone}})")
+
+	var out strings.Builder
+	req := calque.NewRequest(context.Background(), strings.NewReader("hi"))
+	if err := reg.Use("shout", map[string]any{"Tone": "loud"}).ServeFlow(req, calque.NewResponse(&out)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "HI (loud)" {
+		t.Errorf("expected 'HI (loud)', got %q", out.String())
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1", "v2", -1},
+		{"v1.10", "v1.9", 1},
+		{"1.0", "1.0", 0},
+		{"v1", "v1.1", -1},
+		{"beta", "alpha", 1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func mustRegister(t *testing.T, reg *Registry, name, version, tmpl string) {
+	t.Helper()
+	if err := reg.Register(name, version, tmpl); err != nil {
+		t.Fatalf("Register(%s, %s) failed: %v", name, version, err)
+	}
+}
@@ -0,0 +1,192 @@
+// Package rag provides ready-made retrieval-augmented generation flows.
+//
+// IngestFlow and QueryFlow wire the retrieval, prompt and ai middleware into
+// the standard RAG stages with sensible defaults:
+//
+//	store := retrieval.NewBM25Index() // or qdrant, pgvector, weaviate
+//	ingest := rag.IngestFlow(nil, nil, nil, store)
+//	if err := ingest.Run(ctx, "./docs/*.md", &summary); err != nil {
+//	    return err
+//	}
+//
+//	query := rag.QueryFlow(store, client)
+//	err := query.Run(ctx, "How do I rotate API keys?", &answer)
+package rag
+
+import (
+	"text/template"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/prompt"
+	"github.com/calque-ai/go-calque/pkg/middleware/retrieval"
+)
+
+// DefaultPrompt is the QueryFlow prompt template.
+// The question is available as {{.Input}} and the numbered sources as {{.Context}}.
+const DefaultPrompt = `Answer the question using only the sources below. Cite the sources you use as [n].
+If the sources do not contain the answer, say that you don't know.
+
+Sources:
+{{if .Context}}{{.Context}}{{else}}(no relevant sources found){{end}}
+
+Question: {{.Input}}`
+
+// QueryOptions configures QueryFlow.
+type QueryOptions struct {
+	// Search configures retrieval. Default: top 5 documents by relevance, up to 3000 tokens.
+	// NumberSources is always enabled so answers can cite [n].
+	Search *retrieval.SearchOptions
+
+	// Prompt is the template combining question and sources (default: DefaultPrompt)
+	Prompt string
+
+	// Citations appends retrieval.Citations, producing CitedAnswer JSON instead of plain text
+	Citations bool
+
+	// AgentOptions are passed to the AI agent (e.g. ai.WithTools)
+	AgentOptions []ai.AgentOption
+}
+
+// IngestOptions configures IngestFlow.
+type IngestOptions struct {
+	// Manifest enables incremental ingestion through retrieval.SyncDocuments:
+	// unchanged documents are skipped and chunks of changed or removed
+	// documents are deleted. The flow then outputs retrieval.SyncResult JSON.
+	Manifest retrieval.Manifest
+
+	// KeepRemoved keeps chunks of documents missing from this run (requires Manifest)
+	KeepRemoved bool
+
+	// Sources tags loaded documents with their license and source class
+	// through retrieval.TagSources, for retrieval.SourcePolicy at query time
+	Sources []retrieval.SourceRule
+}
+
+// IngestFlow creates a flow that loads, chunks, embeds and stores documents.
+//
+// Input: whatever the loader expects; the default loader takes source paths/URLs
+// Output: retrieval.StoreResult JSON, or retrieval.SyncResult JSON with IngestOptions.Manifest
+// Behavior: BUFFERED - each stage processes the full document set
+//
+// Pass nil to use a default for any stage but store:
+//   - loader: retrieval.DocumentLoader() reading sources from the input
+//   - chunker: retrieval.TextChunker()
+//   - embedder: none - the store embeds content itself
+//
+// Example:
+//
+//	ingest := rag.IngestFlow(retrieval.DocumentLoader("./kb/*.md"), nil, embedder, store)
+//	var result retrieval.StoreResult
+//	err := ingest.Run(ctx, "", convert.FromJSON(&result))
+//
+//	// nightly re-ingestion that only embeds what changed
+//	manifest := retrieval.NewFileManifest("./kb.manifest.json")
+//	ingest := rag.IngestFlow(loader, nil, embedder, store, rag.IngestOptions{Manifest: manifest})
+func IngestFlow(loader calque.Handler, chunker retrieval.Chunker, embedder retrieval.EmbeddingProvider, store retrieval.VectorStore, opts ...IngestOptions) *calque.Flow {
+	var cfg IngestOptions
+	if len(opts) > 0 {
+		cfg = opts[0]
+	}
+	if loader == nil {
+		loader = retrieval.DocumentLoader()
+	}
+	if chunker == nil {
+		chunker = retrieval.TextChunker()
+	}
+
+	if len(cfg.Sources) > 0 {
+		loader = calque.NewFlow().Use(loader).Use(retrieval.TagSources(cfg.Sources...))
+	}
+
+	if cfg.Manifest != nil {
+		return calque.NewFlow().
+			Use(loader).
+			Use(retrieval.SyncDocuments(store, cfg.Manifest, retrieval.SyncOptions{
+				Chunker:     chunker,
+				Embedder:    embedder,
+				KeepRemoved: cfg.KeepRemoved,
+			}))
+	}
+
+	flow := calque.NewFlow().
+		Use(loader).
+		Use(retrieval.Chunk(chunker))
+	if embedder != nil {
+		flow.Use(retrieval.EmbedDocuments(embedder))
+	}
+	return flow.Use(retrieval.StoreDocuments(store))
+}
+
+// QueryFlow creates a flow that answers questions from a vector store.
+//
+// Input: string question
+// Output: model answer text, or retrieval.CitedAnswer JSON with QueryOptions.Citations
+// Behavior: STREAMING - the answer streams from the model once sources are retrieved
+//
+// The question is searched against store, the matching documents are numbered
+// into the prompt, and the AI client answers citing them as [n]. Retrieved
+// sources are available afterwards through retrieval.Sources.
+//
+// Example:
+//
+//	query := rag.QueryFlow(store, client, rag.QueryOptions{Citations: true})
+//	var answer retrieval.CitedAnswer
+//	err := query.Run(ctx, "What is our refund policy?", convert.FromJSON(&answer))
+func QueryFlow(store retrieval.VectorStore, client ai.Client, opts ...QueryOptions) *calque.Flow {
+	var cfg QueryOptions
+	if len(opts) > 0 {
+		cfg = opts[0]
+	}
+
+	search := retrieval.SearchOptions{Limit: 5, MaxTokens: 3000}
+	if cfg.Search != nil {
+		search = *cfg.Search
+	}
+	if search.Strategy == nil {
+		strategy := retrieval.StrategyRelevant
+		search.Strategy = &strategy
+	}
+	search.NumberSources = true
+
+	if cfg.Prompt == "" {
+		cfg.Prompt = DefaultPrompt
+	}
+
+	flow := calque.NewFlow().
+		Use(retrieve(store, &search, cfg.Prompt)).
+		Use(ai.Agent(client, cfg.AgentOptions...))
+	if cfg.Citations {
+		flow.Use(retrieval.Citations())
+	}
+	return flow
+}
+
+// retrieve searches for the question and renders the prompt with the found sources
+func retrieve(store retrieval.VectorStore, search *retrieval.SearchOptions, promptTemplate string) calque.Handler {
+	tmpl, parseErr := template.New("rag").Parse(promptTemplate)
+
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		if parseErr != nil {
+			return calque.WrapErr(req.Context, parseErr, "rag: prompt template parse error")
+		}
+
+		var question string
+		if err := calque.Read(req, &question); err != nil {
+			return err
+		}
+
+		var sources string
+		if err := calque.NewFlow().Use(retrieval.VectorSearch(store, search)).Run(req.Context, question, &sources); err != nil {
+			return calque.WrapErr(req.Context, err, "rag: retrieval failed")
+		}
+
+		var rendered string
+		if err := calque.NewFlow().
+			Use(prompt.FromTemplate(tmpl, map[string]any{"Context": sources})).
+			Run(req.Context, question, &rendered); err != nil {
+			return err
+		}
+		return calque.Write(res, rendered)
+	})
+}
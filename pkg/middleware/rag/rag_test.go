@@ -0,0 +1,321 @@
+package rag
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/convert"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+	"github.com/calque-ai/go-calque/pkg/middleware/retrieval"
+)
+
+// countingEmbedder returns a fixed vector and counts calls
+type countingEmbedder struct {
+	calls int
+}
+
+func (e *countingEmbedder) Embed(_ context.Context, _ string) (retrieval.EmbeddingVector, error) {
+	e.calls++
+	return retrieval.EmbeddingVector{1, 0}, nil
+}
+
+// recordingStore wraps a BM25 index and keeps the stored documents
+type recordingStore struct {
+	*retrieval.BM25Index
+	stored []retrieval.Document
+}
+
+func (s *recordingStore) Store(ctx context.Context, documents []retrieval.Document) error {
+	s.stored = append(s.stored, documents...)
+	return s.BM25Index.Store(ctx, documents)
+}
+
+func writeDocs(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"keys.md":    "Rotate API keys from the settings page. Old keys stay valid for 24 hours after rotation.",
+		"billing.md": strings.Repeat("Invoices are issued monthly and payable within thirty days. ", 40),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestIngestFlow(t *testing.T) {
+	dir := writeDocs(t)
+	store := &recordingStore{BM25Index: retrieval.NewBM25Index()}
+	embedder := &countingEmbedder{}
+
+	var result retrieval.StoreResult
+	err := IngestFlow(nil, retrieval.TextChunker(retrieval.ChunkOptions{Size: 500}), embedder, store).
+		Run(context.Background(), filepath.Join(dir, "*.md"), convert.FromJSON(&result))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Stored < 6 || result.Stored != store.Len() || result.Stored != len(result.IDs) {
+		t.Errorf("result = %+v, index has %d documents", result, store.Len())
+	}
+	if embedder.calls != result.Stored {
+		t.Errorf("embedder calls = %d, want %d", embedder.calls, result.Stored)
+	}
+	for _, doc := range store.stored {
+		if len(doc.Vector) == 0 {
+			t.Errorf("document %s stored without vector", doc.ID)
+		}
+	}
+}
+
+func TestIngestFlowWithoutEmbedder(t *testing.T) {
+	dir := writeDocs(t)
+	store := retrieval.NewBM25Index()
+
+	var result retrieval.StoreResult
+	err := IngestFlow(retrieval.DocumentLoader(filepath.Join(dir, "keys.md")), nil, nil, store).
+		Run(context.Background(), "", convert.FromJSON(&result))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Stored != 1 || store.Len() != 1 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestIngestFlowSources(t *testing.T) {
+	dir := writeDocs(t)
+	store := &recordingStore{BM25Index: retrieval.NewBM25Index()}
+
+	err := IngestFlow(nil, nil, nil, store, IngestOptions{Sources: []retrieval.SourceRule{
+		{Source: "*/billing.md", License: "proprietary", Class: "finance"},
+		{Source: "*", Class: "internal"},
+	}}).Run(context.Background(), filepath.Join(dir, "*.md"), new(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(store.stored) == 0 {
+		t.Fatal("no documents stored")
+	}
+
+	for _, doc := range store.stored {
+		source, _ := doc.Metadata["source"].(string)
+		want := "internal"
+		if strings.HasSuffix(source, "billing.md") {
+			want = "finance"
+		}
+		if doc.Metadata[retrieval.MetadataSourceClass] != want {
+			t.Errorf("chunk %s of %s class = %v, want %s", doc.ID, source, doc.Metadata[retrieval.MetadataSourceClass], want)
+		}
+	}
+}
+
+func TestIngestFlowIncremental(t *testing.T) {
+	dir := writeDocs(t)
+	store := retrieval.NewBM25Index()
+	embedder := &countingEmbedder{}
+	manifest := retrieval.NewMemoryManifest()
+	ingest := IngestFlow(nil, retrieval.TextChunker(retrieval.ChunkOptions{Size: 500}), embedder, store, IngestOptions{Manifest: manifest})
+
+	run := func() retrieval.SyncResult {
+		t.Helper()
+		var result retrieval.SyncResult
+		if err := ingest.Run(context.Background(), filepath.Join(dir, "*.md"), convert.FromJSON(&result)); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	first := run()
+	if first.Added != 2 || first.Stored != store.Len() || embedder.calls != first.Stored {
+		t.Fatalf("first run = %+v, embeds %d", first, embedder.calls)
+	}
+
+	embedder.calls = 0
+	if second := run(); second.Unchanged != 2 || second.Stored != 0 || embedder.calls != 0 {
+		t.Errorf("unchanged run = %+v, embeds %d", second, embedder.calls)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "keys.md"), []byte("Keys rotate automatically every 90 days."), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "billing.md")); err != nil {
+		t.Fatal(err)
+	}
+	third := run()
+	if third.Updated != 1 || third.Removed != 1 || embedder.calls != 1 || store.Len() != 1 {
+		t.Errorf("changed run = %+v, embeds %d, index has %d documents", third, embedder.calls, store.Len())
+	}
+}
+
+func TestQueryFlow(t *testing.T) {
+	store := retrieval.NewBM25Index()
+	err := store.Store(context.Background(), []retrieval.Document{
+		{ID: "keys", Content: "Rotate API keys from the settings page.", Metadata: map[string]any{"source": "keys.md"}},
+		{ID: "billing", Content: "Invoices are issued monthly.", Metadata: map[string]any{"source": "billing.md"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("plain answer", func(t *testing.T) {
+		client := ai.NewMockClient("Rotate API keys from the settings page [1].").WithStreamDelay(0)
+		ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+
+		var answer string
+		if err := QueryFlow(store, client).Run(ctx, "how do I rotate api keys?", &answer); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(answer, "settings page [1]") {
+			t.Errorf("answer = %q", answer)
+		}
+		if sources := retrieval.Sources(ctx); len(sources) != 1 || sources[0].ID != "keys" {
+			t.Errorf("sources = %+v", sources)
+		}
+	})
+
+	t.Run("with citations", func(t *testing.T) {
+		client := ai.NewMockClient("Rotate API keys from the settings page [1].").WithStreamDelay(0)
+
+		var answer retrieval.CitedAnswer
+		err := QueryFlow(store, client, QueryOptions{Citations: true}).
+			Run(context.Background(), "rotate api keys", convert.FromJSON(&answer))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(answer.Sources) != 1 || answer.Sources[0].Source != "keys.md" || len(answer.Unsupported) != 0 {
+			t.Errorf("answer = %+v", answer)
+		}
+	})
+
+	t.Run("bad prompt template", func(t *testing.T) {
+		var answer string
+		err := QueryFlow(store, ai.NewMockClient("x"), QueryOptions{Prompt: "{{.Input"}).Run(context.Background(), "q", &answer)
+		if err == nil || !strings.Contains(err.Error(), "prompt template parse error") {
+			t.Errorf("error = %v", err)
+		}
+	})
+}
+
+func TestRetrievePrompt(t *testing.T) {
+	store := retrieval.NewBM25Index()
+	_ = store.Store(context.Background(), []retrieval.Document{{ID: "a", Content: "Rotate API keys monthly."}})
+	strategy := retrieval.StrategyRelevant
+
+	var prompt string
+	err := calque.NewFlow().
+		Use(retrieve(store, &retrieval.SearchOptions{Strategy: &strategy, NumberSources: true}, DefaultPrompt)).
+		Run(context.Background(), "rotate keys", &prompt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(prompt, "[1] Rotate API keys monthly.") || !strings.HasSuffix(prompt, "Question: rotate keys") {
+		t.Errorf("prompt = %q", prompt)
+	}
+}
+
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "main")
+	git("config", "user.email", "test@example.com")
+	git("config", "user.name", "Test")
+
+	files := map[string]string{
+		".gitignore":       "build/\n*.log\n",
+		"main.go":          "package main\n\n// Run starts the server.\nfunc Run() {}\n",
+		"docs/guide.md":    "# Guide\n\nRun the server with make run.\n",
+		"build/out.go":     "package build\n",
+		"debug.log":        "noise\n",
+		"assets/logo.png":  "\x89PNG\x00\x00",
+		"internal/util.go": "package internal\n\nfunc Helper() {}\n",
+	}
+	for name, content := range files {
+		full := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("add", "main.go", ".gitignore", "docs", "assets")
+	git("commit", "-q", "-m", "initial")
+	return dir
+}
+
+func TestIndexRepo(t *testing.T) {
+	dir := gitRepo(t)
+	store := &recordingStore{BM25Index: retrieval.NewBM25Index()}
+	embedder := &countingEmbedder{}
+	index := IndexRepo(dir, store, RepoOptions{Name: "demo", Embedder: embedder, Exclude: []string{".gitignore"}})
+
+	var result retrieval.SyncResult
+	if err := index.Run(context.Background(), "", convert.FromJSON(&result)); err != nil {
+		t.Fatal(err)
+	}
+	// untracked internal/util.go is indexed; ignored, binary and excluded files are not
+	if result.Added != 3 || embedder.calls != len(store.stored) {
+		t.Fatalf("result = %+v, stored %d, embedded %d", result, len(store.stored), embedder.calls)
+	}
+	ids := map[string]retrieval.Document{}
+	for _, doc := range store.stored {
+		ids[doc.ID] = doc
+	}
+	for _, id := range []string{"demo/main.go", "demo/docs/guide.md", "demo/internal/util.go"} {
+		if _, ok := ids[id]; !ok {
+			t.Errorf("missing %s in %v", id, ids)
+		}
+	}
+	main := ids["demo/main.go"]
+	if main.Metadata["language"] != "go" || main.Metadata["repo"] != "demo" || main.Metadata["branch"] != "main" || len(main.Metadata["commit"].(string)) != 40 {
+		t.Errorf("metadata = %v", main.Metadata)
+	}
+	if symbols, _ := main.Metadata["symbols"].([]string); len(symbols) != 1 || symbols[0] != "Run" {
+		t.Errorf("symbols = %v", main.Metadata["symbols"])
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git", "calque-index.json")); err != nil {
+		t.Errorf("default manifest not written: %v", err)
+	}
+
+	// reindex after changing one file and deleting another
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc Stop() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "internal", "util.go")); err != nil {
+		t.Fatal(err)
+	}
+	embedder.calls = 0
+	if err := index.Run(context.Background(), "", convert.FromJSON(&result)); err != nil {
+		t.Fatal(err)
+	}
+	if result.Updated != 1 || result.Unchanged != 1 || result.Removed != 1 || embedder.calls != 1 {
+		t.Errorf("reindex result = %+v, embedded %d", result, embedder.calls)
+	}
+}
+
+func TestIndexRepoNotARepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	err := IndexRepo(t.TempDir(), retrieval.NewBM25Index()).Run(context.Background(), "", new(string))
+	if err == nil || !strings.Contains(err.Error(), "not a Git repository") {
+		t.Errorf("error = %v", err)
+	}
+}
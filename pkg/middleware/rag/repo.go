@@ -0,0 +1,235 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/retrieval"
+	"github.com/calque-ai/go-calque/pkg/middleware/text"
+)
+
+// DefaultRepoMaxFileSize is the RepoOptions.MaxFileSize used when none is given.
+const DefaultRepoMaxFileSize = 1 << 20
+
+// repoManifestFile is the default manifest, kept inside the .git directory
+const repoManifestFile = "calque-index.json"
+
+// RepoOptions configures IndexRepo.
+type RepoOptions struct {
+	// Name identifies the repository in document IDs and "repo" metadata
+	// (default: the name of the repository's top-level directory)
+	Name string
+
+	// Include limits indexing to files whose path or base name matches one of
+	// these path.Match patterns, e.g. "*.go" or "docs/*.md" (default: all files)
+	Include []string
+
+	// Exclude skips files whose path or base name matches one of these patterns
+	Exclude []string
+
+	// MaxFileSize skips larger files (default: DefaultRepoMaxFileSize)
+	MaxFileSize int64
+
+	// Chunker splits changed files (default: retrieval.CodeChunker())
+	Chunker retrieval.Chunker
+
+	// Embedder pre-computes vectors; nil lets the store embed
+	Embedder retrieval.EmbeddingProvider
+
+	// Embed sets parallelism, rate-limit pacing and checkpointing for Embedder
+	Embed retrieval.EmbedOptions
+
+	// Manifest records what was indexed, so reindexing only embeds changed
+	// files (default: a file manifest at .git/calque-index.json)
+	Manifest retrieval.Manifest
+}
+
+// IndexRepo creates a flow that indexes the files of a Git repository.
+//
+// Input: ignored
+// Output: retrieval.SyncResult JSON
+// Behavior: BUFFERED - every indexed file is read before syncing the store
+//
+// Files are listed with the git binary - tracked files plus untracked files
+// not excluded by .gitignore - so ignore rules match Git exactly. Binary,
+// non-UTF-8 and oversized files are skipped. Each file becomes a document
+// with the ID "<name>/<path>" and "repo", "path", "source", "language",
+// "branch" and "commit" metadata, chunked along function and type boundaries
+// by retrieval.CodeChunker and synced with retrieval.SyncDocuments: on later
+// runs only added and changed files are re-embedded, and chunks of deleted
+// files are removed. "commit" is HEAD when the file was last indexed.
+//
+// Example:
+//
+//	index := rag.IndexRepo("./", store, rag.RepoOptions{
+//		Embedder: embedder,
+//		Include:  []string{"*.go", "*.md"},
+//		Exclude:  []string{"*_test.go"},
+//	})
+//	var result retrieval.SyncResult
+//	err := index.Run(ctx, "", convert.FromJSON(&result))
+func IndexRepo(repoPath string, store retrieval.VectorStore, opts ...RepoOptions) *calque.Flow {
+	var cfg RepoOptions
+	if len(opts) > 0 {
+		cfg = opts[0]
+	}
+	if cfg.MaxFileSize <= 0 {
+		cfg.MaxFileSize = DefaultRepoMaxFileSize
+	}
+	if cfg.Chunker == nil {
+		cfg.Chunker = retrieval.CodeChunker()
+	}
+
+	return calque.NewFlow().Use(calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		if _, err := io.Copy(io.Discard, req.Data); err != nil {
+			return err
+		}
+
+		repo, err := openRepo(req.Context, repoPath, cfg.Name)
+		if err != nil {
+			return err
+		}
+		documents, err := repo.documents(req.Context, cfg)
+		if err != nil {
+			return err
+		}
+		input, err := json.Marshal(documents)
+		if err != nil {
+			return err
+		}
+
+		manifest := cfg.Manifest
+		if manifest == nil {
+			manifest = retrieval.NewFileManifest(filepath.Join(repo.gitDir, repoManifestFile))
+		}
+		return retrieval.SyncDocuments(store, manifest, retrieval.SyncOptions{
+			Chunker:  cfg.Chunker,
+			Embedder: cfg.Embedder,
+			Embed:    cfg.Embed,
+		}).ServeFlow(calque.NewRequest(req.Context, bytes.NewReader(input)), res)
+	}))
+}
+
+// gitWorkTree is a repository being indexed
+type gitWorkTree struct {
+	root   string
+	gitDir string
+	name   string
+	branch string
+	commit string
+}
+
+func openRepo(ctx context.Context, repoPath, name string) (*gitWorkTree, error) {
+	repo := &gitWorkTree{root: repoPath, name: name}
+	top, err := repo.git(ctx, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("rag: %s is not a Git repository", repoPath))
+	}
+	if repo.name == "" {
+		repo.name = filepath.Base(top)
+	}
+	if repo.gitDir, err = repo.git(ctx, "rev-parse", "--absolute-git-dir"); err != nil {
+		return nil, err
+	}
+	// both fail in a repository without commits
+	repo.commit, _ = repo.git(ctx, "rev-parse", "HEAD")
+	repo.branch, _ = repo.git(ctx, "rev-parse", "--abbrev-ref", "HEAD")
+	return repo, nil
+}
+
+func (r *gitWorkTree) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", r.root}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", calque.NewErr(ctx, fmt.Sprintf("git %s failed: %s", args[0], msg))
+	}
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
+
+// documents reads the indexable files of the work tree
+func (r *gitWorkTree) documents(ctx context.Context, cfg RepoOptions) ([]retrieval.Document, error) {
+	list, err := r.git(ctx, "ls-files", "-z", "--cached", "--others", "--exclude-standard", "--deduplicate")
+	if err != nil {
+		return nil, err
+	}
+
+	var documents []retrieval.Document
+	for _, rel := range strings.Split(list, "\x00") {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if rel == "" || !repoPathSelected(rel, cfg.Include, cfg.Exclude) {
+			continue
+		}
+		full := filepath.Join(r.root, filepath.FromSlash(rel))
+		info, err := os.Lstat(full)
+		if err != nil || !info.Mode().IsRegular() || info.Size() > cfg.MaxFileSize {
+			continue // deleted but still staged, a submodule, a link or too large
+		}
+		content, err := os.ReadFile(full)
+		if err != nil || !isText(content) {
+			continue
+		}
+
+		metadata := map[string]any{
+			"repo":   r.name,
+			"path":   rel,
+			"source": rel,
+			"size":   info.Size(),
+		}
+		if lang := text.CodeLanguage(rel); lang != "" {
+			metadata["language"] = lang
+		}
+		if r.commit != "" {
+			metadata["commit"] = r.commit
+			metadata["branch"] = r.branch
+		}
+		documents = append(documents, retrieval.Document{
+			ID:       r.name + "/" + rel,
+			Content:  string(content),
+			Metadata: metadata,
+			Updated:  info.ModTime(),
+		})
+	}
+	return documents, nil
+}
+
+// repoPathSelected applies the Include and Exclude patterns to a slash-separated path
+func repoPathSelected(rel string, include, exclude []string) bool {
+	matches := func(patterns []string) bool {
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, rel); ok {
+				return true
+			}
+			if ok, _ := path.Match(p, path.Base(rel)); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if len(include) > 0 && !matches(include) {
+		return false
+	}
+	return !matches(exclude)
+}
+
+// isText reports whether content looks like UTF-8 text rather than a binary file
+func isText(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), 8000)], 0) < 0 && utf8.Valid(content)
+}
@@ -0,0 +1,266 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// ListStages is the reserved stage name that Stages answers with the names of
+// its hosted stages.
+const ListStages = "calque.stages"
+
+// DefaultDiscoveryRefresh is how long a Coordinator reuses discovered stages.
+const DefaultDiscoveryRefresh = 30 * time.Second
+
+// Discovery reports which workers host which stages.
+type Discovery interface {
+	// Lookup returns the worker addresses of each stage name
+	Lookup(ctx context.Context) (map[string][]string, error)
+}
+
+// StaticDiscovery is a fixed map of stage names to worker addresses.
+type StaticDiscovery map[string][]string
+
+// Lookup implements Discovery.
+func (d StaticDiscovery) Lookup(context.Context) (map[string][]string, error) {
+	return d, nil
+}
+
+// Workers discovers stages by asking each worker, served with Stages, which
+// stages it hosts. Workers that cannot be reached are left out until the
+// next lookup; Lookup only fails when none answers.
+//
+// Example:
+//
+//	discovery := remote.Workers(transport, "gpu-0:50051", "gpu-1:50051", "cpu-0:50051")
+func Workers(transport Transport, addresses ...string) Discovery {
+	return &workerDiscovery{transport: transport, addresses: addresses}
+}
+
+type workerDiscovery struct {
+	transport Transport
+	addresses []string
+}
+
+func (d *workerDiscovery) Lookup(ctx context.Context) (map[string][]string, error) {
+	type result struct {
+		address string
+		stages  []string
+		err     error
+	}
+	results := make(chan result, len(d.addresses))
+	for _, address := range d.addresses {
+		go func() {
+			stages, err := d.list(ctx, address)
+			results <- result{address: address, stages: stages, err: err}
+		}()
+	}
+
+	found := make(map[string][]string)
+	var errs []error
+	for range d.addresses {
+		r := <-results
+		if r.err != nil {
+			calque.Logger(ctx).Debug("remote worker discovery failed", "address", r.address, "error", r.err)
+			errs = append(errs, r.err)
+			continue
+		}
+		for _, stage := range r.stages {
+			found[stage] = append(found[stage], r.address)
+		}
+	}
+	if len(errs) == len(d.addresses) && len(errs) > 0 {
+		return nil, calque.Retryable(calque.WrapErr(ctx, errors.Join(errs...), "no remote worker answered discovery"))
+	}
+	for _, addresses := range found {
+		sort.Strings(addresses) // stable worker order for round-robin
+	}
+	return found, nil
+}
+
+// list asks the worker at address for its stage names
+func (d *workerDiscovery) list(ctx context.Context, address string) ([]string, error) {
+	var out []byte
+	handler := Handler(d.transport, address, JSONCodec, HandlerConfig{Stage: ListStages})
+	if err := calque.NewFlow().Use(handler).Run(ctx, "", &out); err != nil {
+		return nil, err
+	}
+	var stages []string
+	if err := json.Unmarshal(out, &stages); err != nil {
+		return nil, calque.WrapErr(ctx, err, "invalid stage list from remote worker "+address)
+	}
+	return stages, nil
+}
+
+// CoordinatorConfig configures a Coordinator.
+type CoordinatorConfig struct {
+	// Codec labels the data of every stage (zero = TextCodec)
+	Codec Codec
+	// Refresh is how long discovered stages are reused (0 = DefaultDiscoveryRefresh)
+	Refresh time.Duration
+	// Metadata lists MetadataBus keys forwarded to every stage
+	Metadata []string
+}
+
+// Coordinator splits a flow across worker processes, each hosting some of
+// its stages, for pipelines whose stages need different hardware.
+//
+// Workers serve their stages with Stages behind any transport (for gRPC,
+// grpc.RegisterStage); the coordinator discovers where each stage runs and
+// chains one remote Handler per stage. Data streams through the coordinator
+// from stage to stage as it is produced, so a downstream worker starts on
+// the first output of the upstream one. When several workers host a stage,
+// runs are spread over them round-robin. A failed stage call triggers
+// rediscovery on the next run.
+//
+// Example:
+//
+//	// GPU worker
+//	grpc.RegisterStage(server, remote.Stages{"embed": embedder, "rerank": reranker})
+//	// CPU worker
+//	grpc.RegisterStage(server, remote.Stages{"chunk": chunker, "format": formatter})
+//
+//	// Coordinator
+//	transport := grpc.NewTransport(nil)
+//	coord := remote.NewCoordinator(transport, remote.Workers(transport, "gpu:50051", "cpu:50051"))
+//	flow, err := coord.Flow(ctx, "chunk", "embed", "rerank", "format")
+type Coordinator struct {
+	transport Transport
+	discovery Discovery
+	config    CoordinatorConfig
+
+	mu      sync.Mutex
+	stages  map[string][]string
+	fetched time.Time
+	next    map[string]int
+}
+
+// NewCoordinator creates a coordinator that reaches workers through
+// transport and finds them with discovery.
+func NewCoordinator(transport Transport, discovery Discovery, config ...CoordinatorConfig) *Coordinator {
+	var cfg CoordinatorConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Codec == (Codec{}) {
+		cfg.Codec = TextCodec
+	}
+	if cfg.Refresh <= 0 {
+		cfg.Refresh = DefaultDiscoveryRefresh
+	}
+	return &Coordinator{transport: transport, discovery: discovery, config: cfg, next: make(map[string]int)}
+}
+
+// Flow builds a flow running stages in order on the workers hosting them.
+// It fails with an invalid input error naming the stages no worker hosts.
+func (c *Coordinator) Flow(ctx context.Context, stages ...string) (*calque.Flow, error) {
+	hosted, err := c.lookup(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, stage := range stages {
+		if len(hosted[stage]) == 0 {
+			missing = append(missing, stage)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, calque.InvalidInput(calque.NewErr(ctx, "no remote worker hosts stage "+strings.Join(missing, ", ")))
+	}
+
+	flow := calque.NewFlow()
+	for _, stage := range stages {
+		flow.Use(c.Stage(stage))
+	}
+	return flow, nil
+}
+
+// Stage returns a handler running stage on one of the workers hosting it,
+// chosen per run.
+func (c *Coordinator) Stage(stage string) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		address, err := c.pick(req.Context, stage)
+		if err != nil {
+			return err
+		}
+		handler := Handler(c.transport, address, c.config.Codec, HandlerConfig{Stage: stage, Metadata: c.config.Metadata})
+		if err := handler.ServeFlow(req, res); err != nil {
+			if !calque.IsInvalidInput(err) {
+				c.invalidate()
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// Stages returns the discovered stages and the workers hosting each.
+func (c *Coordinator) Stages(ctx context.Context) (map[string][]string, error) {
+	hosted, err := c.lookup(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	copied := make(map[string][]string, len(hosted))
+	for stage, addresses := range hosted {
+		copied[stage] = append([]string(nil), addresses...)
+	}
+	return copied, nil
+}
+
+// pick returns the next worker for stage, rediscovering once when none is known
+func (c *Coordinator) pick(ctx context.Context, stage string) (string, error) {
+	hosted, err := c.lookup(ctx, false)
+	if err != nil {
+		return "", err
+	}
+	if len(hosted[stage]) == 0 {
+		if hosted, err = c.lookup(ctx, true); err != nil {
+			return "", err
+		}
+	}
+	addresses := hosted[stage]
+	if len(addresses) == 0 {
+		return "", calque.Retryable(calque.NewErr(ctx, "no remote worker hosts stage "+stage))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := c.next[stage] % len(addresses)
+	c.next[stage] = i + 1
+	return addresses[i], nil
+}
+
+// lookup returns the cached stages, refreshing them when stale or forced
+func (c *Coordinator) lookup(ctx context.Context, force bool) (map[string][]string, error) {
+	c.mu.Lock()
+	if !force && c.stages != nil && time.Since(c.fetched) < c.config.Refresh {
+		defer c.mu.Unlock()
+		return c.stages, nil
+	}
+	c.mu.Unlock()
+
+	hosted, err := c.discovery.Lookup(ctx)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "remote stage discovery failed")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stages = hosted
+	c.fetched = time.Now()
+	return hosted, nil
+}
+
+// invalidate makes the next run rediscover the workers
+func (c *Coordinator) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fetched = time.Time{}
+}
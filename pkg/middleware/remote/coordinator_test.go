@@ -0,0 +1,141 @@
+package remote
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// tag appends the worker name, to see where a stage ran
+func tag(worker string) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var s string
+		if err := calque.Read(req, &s); err != nil {
+			return err
+		}
+		return calque.Write(res, s+" ["+worker+"]")
+	})
+}
+
+func TestCoordinatorFlow(t *testing.T) {
+	gpu := startHTTPStage(t, Stages{"upper": upper, "tag": tag("gpu")})
+	cpu := startHTTPStage(t, Stages{"trim": tag("cpu")})
+
+	transport := HTTPTransport(nil)
+	coord := NewCoordinator(transport, Workers(transport, gpu, cpu))
+	flow, err := coord.Flow(context.Background(), "trim", "upper", "tag")
+	if err != nil {
+		t.Fatalf("Flow() error = %v", err)
+	}
+
+	var out string
+	if err := flow.Run(context.Background(), "split pipeline", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out != "SPLIT PIPELINE [CPU] [gpu]" {
+		t.Errorf("output = %q", out)
+	}
+
+	stages, err := coord.Stages(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{"upper": {gpu}, "tag": {gpu}, "trim": {cpu}}
+	if !reflect.DeepEqual(stages, want) {
+		t.Errorf("Stages() = %v, want %v", stages, want)
+	}
+}
+
+func TestCoordinatorRoundRobin(t *testing.T) {
+	first := startHTTPStage(t, Stages{"tag": tag("a")})
+	second := startHTTPStage(t, Stages{"tag": tag("b")})
+
+	transport := HTTPTransport(nil)
+	coord := NewCoordinator(transport, Workers(transport, first, second))
+	flow, err := coord.Flow(context.Background(), "tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]int{}
+	for range 4 {
+		var out string
+		if err := flow.Run(context.Background(), "x", &out); err != nil {
+			t.Fatal(err)
+		}
+		seen[out]++
+	}
+	if seen["x [a]"] != 2 || seen["x [b]"] != 2 {
+		t.Errorf("runs per worker = %v, want 2 each", seen)
+	}
+}
+
+func TestCoordinatorRediscovers(t *testing.T) {
+	var lookups atomic.Int32
+	live := httptest.NewServer(HTTPStage(Stages{"tag": tag("live")}))
+	defer live.Close()
+	dead := httptest.NewServer(HTTPStage(Stages{"tag": tag("dead")}))
+	deadURL := dead.URL
+	dead.Close()
+
+	discovery := discoveryFunc(func(context.Context) (map[string][]string, error) {
+		if lookups.Add(1) == 1 {
+			return map[string][]string{"tag": {deadURL}}, nil
+		}
+		return map[string][]string{"tag": {live.URL}}, nil
+	})
+	coord := NewCoordinator(HTTPTransport(nil), discovery)
+	flow, err := coord.Flow(context.Background(), "tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out string
+	if err := flow.Run(context.Background(), "x", &out); !calque.IsRetryable(err) {
+		t.Fatalf("Run() on a stopped worker error = %v, want retryable", err)
+	}
+	out = ""
+	if err := flow.Run(context.Background(), "x", &out); err != nil {
+		t.Fatalf("Run() after rediscovery error = %v", err)
+	}
+	if out != "x [live]" {
+		t.Errorf("output = %q", out)
+	}
+}
+
+type discoveryFunc func(context.Context) (map[string][]string, error)
+
+func (f discoveryFunc) Lookup(ctx context.Context) (map[string][]string, error) { return f(ctx) }
+
+func TestCoordinatorErrors(t *testing.T) {
+	worker := startHTTPStage(t, Stages{"upper": upper})
+	transport := HTTPTransport(nil)
+
+	t.Run("stage not hosted", func(t *testing.T) {
+		coord := NewCoordinator(transport, Workers(transport, worker))
+		_, err := coord.Flow(context.Background(), "upper", "embed", "rerank")
+		if !calque.IsInvalidInput(err) || !strings.Contains(err.Error(), "embed, rerank") {
+			t.Errorf("Flow() error = %v, want invalid input naming the missing stages", err)
+		}
+	})
+
+	t.Run("unreachable worker is skipped", func(t *testing.T) {
+		coord := NewCoordinator(transport, Workers(transport, "http://127.0.0.1:1/stage", worker))
+		if _, err := coord.Flow(context.Background(), "upper"); err != nil {
+			t.Errorf("Flow() error = %v", err)
+		}
+	})
+
+	t.Run("no worker answers", func(t *testing.T) {
+		coord := NewCoordinator(transport, Workers(transport, "http://127.0.0.1:1/stage"))
+		_, err := coord.Flow(context.Background(), "upper")
+		if !calque.IsRetryable(err) {
+			t.Errorf("Flow() error = %v, want retryable", err)
+		}
+	})
+}
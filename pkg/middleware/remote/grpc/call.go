@@ -4,7 +4,6 @@ package grpc
 import (
 	"context"
 	"fmt"
-	"io"
 	"reflect"
 	"strings"
 	"time"
@@ -479,23 +478,10 @@ func (sh *streamHandler) streamAIService(ctx context.Context, service *Service,
 		return grpcerrors.WrapError(ctx, err, "failed to create AI streaming client", sh.serviceName)
 	}
 
-	// Read responses from the stream
-	for {
-		resp, err := stream.Recv()
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return grpcerrors.WrapError(ctx, err, "failed to receive AI streaming response", sh.serviceName)
-		}
-
-		// Write response data as string
-		if err := calque.Write(res, resp.Response); err != nil {
-			return grpcerrors.WrapErrorSimple(ctx, err, "failed to write AI response")
-		}
-	}
-
-	return nil
+	// Relay responses at the pace the downstream handler reads them
+	return pumpServerStream(ctx, stream, func(resp *calquepb.AIResponse) []byte {
+		return []byte(resp.Response)
+	}, res)
 }
 
 // streamMemoryService streams from the Memory service (simulated streaming)
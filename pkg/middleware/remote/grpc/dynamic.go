@@ -0,0 +1,185 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	grpcerrors "github.com/calque-ai/go-calque/pkg/grpc"
+)
+
+// DynamicHandler creates a handler that calls any unary method of a service
+// that has server reflection enabled, without generated stubs.
+//
+// Input: JSON request message (protojson field names); empty input sends an empty message
+// Output: JSON response message
+// Behavior: BUFFERED - reads the full request before calling the method
+//
+// fullMethodName is "package.Service/Method" (a leading "/" is optional). The
+// request and response types are fetched with the reflection service on first
+// use and cached; a failed lookup is retried on the next request. Malformed
+// request JSON fails with calque.ErrInvalidInput.
+//
+// Example:
+//
+//	conn, _ := grpcerrors.NewClient(ctx, grpcerrors.DefaultConfig("inventory:50051"))
+//	flow := calque.NewFlow().
+//		Use(ai.Agent(client, ai.WithSchema(&StockQuery{}))).
+//		Use(grpc.DynamicHandler(conn, "shop.v1.Inventory/GetStock"))
+func DynamicHandler(client grpc.ClientConnInterface, fullMethodName string) calque.Handler {
+	return &dynamicHandler{client: client, method: strings.TrimPrefix(fullMethodName, "/")}
+}
+
+// dynamicHandler resolves method descriptors lazily through server reflection
+type dynamicHandler struct {
+	client grpc.ClientConnInterface
+	method string
+
+	mu         sync.Mutex
+	descriptor protoreflect.MethodDescriptor
+}
+
+func (dh *dynamicHandler) ServeFlow(req *calque.Request, res *calque.Response) error {
+	ctx := req.Context
+	md, err := dh.methodDescriptor(ctx)
+	if err != nil {
+		return err
+	}
+
+	var input []byte
+	if err := calque.Read(req, &input); err != nil {
+		return grpcerrors.WrapErrorSimple(ctx, err, "failed to read input data")
+	}
+	reqMsg := dynamicpb.NewMessage(md.Input())
+	if len(strings.TrimSpace(string(input))) > 0 {
+		if err := protojson.Unmarshal(input, reqMsg); err != nil {
+			return calque.InvalidInput(grpcerrors.WrapErrorfSimple(ctx, err, "failed to decode %s from JSON", md.Input().FullName()))
+		}
+	}
+
+	respMsg := dynamicpb.NewMessage(md.Output())
+	if err := dh.client.Invoke(ctx, "/"+dh.method, reqMsg, respMsg); err != nil {
+		return grpcerrors.WrapError(ctx, err, "gRPC call failed", dh.method)
+	}
+
+	out, err := protojson.Marshal(respMsg)
+	if err != nil {
+		return grpcerrors.WrapErrorfSimple(ctx, err, "failed to encode %s as JSON", md.Output().FullName())
+	}
+	_, err = res.Data.Write(out)
+	return err
+}
+
+// methodDescriptor returns the cached descriptor of the method, resolving it on first use
+func (dh *dynamicHandler) methodDescriptor(ctx context.Context) (protoreflect.MethodDescriptor, error) {
+	dh.mu.Lock()
+	defer dh.mu.Unlock()
+	if dh.descriptor != nil {
+		return dh.descriptor, nil
+	}
+
+	serviceName, methodName, ok := strings.Cut(dh.method, "/")
+	if !ok || serviceName == "" || methodName == "" {
+		return nil, grpcerrors.NewErrorSimple(ctx, "method must be package.Service/Method, got "+dh.method)
+	}
+	files, err := resolveServiceFiles(ctx, dh.client, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, grpcerrors.WrapErrorfSimple(ctx, err, "service %s not found", serviceName)
+	}
+	service, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, grpcerrors.NewErrorSimple(ctx, serviceName+" is not a service")
+	}
+	md := service.Methods().ByName(protoreflect.Name(methodName))
+	if md == nil {
+		return nil, grpcerrors.NewErrorSimple(ctx, "method "+dh.method+" not found")
+	}
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		return nil, grpcerrors.NewErrorSimple(ctx, "method "+dh.method+" is streaming, DynamicHandler supports unary methods")
+	}
+	dh.descriptor = md
+	return md, nil
+}
+
+// resolveServiceFiles fetches the file defining serviceName and all its imports from the reflection service
+func resolveServiceFiles(ctx context.Context, client grpc.ClientConnInterface, serviceName string) (*protoregistry.Files, error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := reflectionpb.NewServerReflectionClient(client).ServerReflectionInfo(streamCtx)
+	if err != nil {
+		return nil, grpcerrors.WrapError(ctx, err, "failed to open server reflection stream")
+	}
+
+	fetched := map[string]*descriptorpb.FileDescriptorProto{}
+	var order []string
+	add := func(resp *reflectionpb.ServerReflectionResponse) error {
+		if errResp := resp.GetErrorResponse(); errResp != nil {
+			return grpcerrors.NewErrorSimple(ctx, "server reflection: "+errResp.GetErrorMessage())
+		}
+		for _, raw := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fd := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(raw, fd); err != nil {
+				return grpcerrors.WrapErrorSimple(ctx, err, "invalid file descriptor from server reflection")
+			}
+			if _, ok := fetched[fd.GetName()]; !ok {
+				fetched[fd.GetName()] = fd
+				order = append(order, fd.GetName())
+			}
+		}
+		return nil
+	}
+	ask := func(req *reflectionpb.ServerReflectionRequest) error {
+		if err := stream.Send(req); err != nil {
+			return grpcerrors.WrapError(ctx, err, "server reflection request failed")
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return grpcerrors.WrapError(ctx, err, "server reflection request failed")
+		}
+		return add(resp)
+	}
+
+	if err := ask(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: serviceName},
+	}); err != nil {
+		return nil, err
+	}
+	// servers usually send the imports along; ask for any that are missing
+	for i := 0; i < len(order); i++ {
+		for _, dep := range fetched[order[i]].GetDependency() {
+			if _, ok := fetched[dep]; ok {
+				continue
+			}
+			if err := ask(&reflectionpb.ServerReflectionRequest{
+				MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{FileByFilename: dep},
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, name := range order {
+		set.File = append(set.File, fetched[name])
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, grpcerrors.WrapErrorSimple(ctx, err, "failed to build descriptors from server reflection")
+	}
+	return files, nil
+}
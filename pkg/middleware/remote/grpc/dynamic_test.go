@@ -0,0 +1,107 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/text"
+	calquepb "github.com/calque-ai/go-calque/proto"
+)
+
+// startReflectionServer serves a FlowService with an "upper" flow, with or
+// without server reflection, and returns a client connection to it
+func startReflectionServer(t *testing.T, withReflection bool) *grpc.ClientConn {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	flows := NewServer(lis.Addr().String())
+	flows.RegisterFlow("upper", calque.NewFlow().Use(text.Transform(strings.ToUpper)))
+
+	server := grpc.NewServer()
+	calquepb.RegisterFlowServiceServer(server, NewFlowService(flows))
+	if withReflection {
+		reflection.Register(server)
+	}
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestDynamicHandler(t *testing.T) {
+	conn := startReflectionServer(t, true)
+	handler := DynamicHandler(conn, "/calque.FlowService/ExecuteFlow")
+
+	// twice, so the second call uses the cached descriptors
+	for range 2 {
+		var out string
+		err := calque.NewFlow().Use(handler).Run(context.Background(),
+			`{"flowName": "upper", "input": "hello", "metadata": {"caller": "test"}}`, &out)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		var resp struct {
+			Output   string            `json:"output"`
+			Success  bool              `json:"success"`
+			Metadata map[string]string `json:"metadata"`
+		}
+		if err := json.Unmarshal([]byte(out), &resp); err != nil {
+			t.Fatalf("output is not JSON: %q", out)
+		}
+		if resp.Output != "HELLO" || !resp.Success || resp.Metadata["caller"] != "test" {
+			t.Errorf("response = %+v", resp)
+		}
+	}
+}
+
+func TestDynamicHandlerErrors(t *testing.T) {
+	conn := startReflectionServer(t, true)
+
+	tests := []struct {
+		name         string
+		conn         *grpc.ClientConn
+		method       string
+		input        string
+		invalidInput bool
+		errContains  string
+	}{
+		{name: "malformed method name", conn: conn, method: "ExecuteFlow", errContains: "package.Service/Method"},
+		{name: "unknown service", conn: conn, method: "calque.Missing/Call", errContains: "server reflection"},
+		{name: "unknown method", conn: conn, method: "calque.FlowService/Missing", errContains: "not found"},
+		{name: "streaming method", conn: conn, method: "calque.FlowService/StreamFlow", errContains: "streaming"},
+		{name: "invalid JSON", conn: conn, method: "calque.FlowService/ExecuteFlow", input: `{"flowName": 1}`, invalidInput: true},
+		{name: "unknown field", conn: conn, method: "calque.FlowService/ExecuteFlow", input: `{"nope": "x"}`, invalidInput: true},
+		{name: "no reflection", conn: startReflectionServer(t, false), method: "calque.FlowService/ExecuteFlow", errContains: "reflection"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out string
+			err := calque.NewFlow().Use(DynamicHandler(tt.conn, tt.method)).Run(context.Background(), tt.input, &out)
+			if err == nil {
+				t.Fatalf("expected error, got output %q", out)
+			}
+			if tt.invalidInput != calque.IsInvalidInput(err) {
+				t.Errorf("IsInvalidInput(%v) = %v, want %v", err, !tt.invalidInput, tt.invalidInput)
+			}
+			if tt.errContains != "" && !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.errContains)
+			}
+		})
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"google.golang.org/grpc"
@@ -13,6 +16,7 @@ import (
 	"google.golang.org/grpc/reflection"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/observability"
 	calquepb "github.com/calque-ai/go-calque/proto"
 )
 
@@ -27,9 +31,20 @@ type Server struct {
 
 // NewServer creates a new gRPC server for hosting flows.
 func NewServer(addr string) *Server {
+	return NewServerWithOptions(addr)
+}
+
+// NewServerWithOptions creates a gRPC server for hosting flows with server options
+// such as interceptors or TLS credentials.
+//
+// Example:
+//
+//	jwt := auth.JWT("https://accounts.example.com", "calque-api")
+//	server := grpc.NewServerWithOptions(":9090", jwt.ServerOptions()...)
+func NewServerWithOptions(addr string, opts ...grpc.ServerOption) *Server {
 	healthSrv := health.NewServer()
 	return &Server{
-		server:    grpc.NewServer(),
+		server:    grpc.NewServer(opts...),
 		flows:     make(map[string]*calque.Flow),
 		addr:      addr,
 		healthSrv: healthSrv,
@@ -53,10 +68,18 @@ func (s *Server) GetFlow(ctx context.Context, name string) (*calque.Flow, error)
 
 // Start starts the gRPC server.
 func (s *Server) Start() error {
-	ctx := context.Background()
+	lis, err := s.listen(context.Background())
+	if err != nil {
+		return err
+	}
+	return s.server.Serve(lis)
+}
+
+// listen opens the server address and registers the health and reflection services
+func (s *Server) listen(ctx context.Context) (net.Listener, error) {
 	lis, err := net.Listen("tcp", s.addr)
 	if err != nil {
-		return calque.WrapErr(ctx, err, fmt.Sprintf("failed to listen on %s", s.addr))
+		return nil, calque.WrapErr(ctx, err, fmt.Sprintf("failed to listen on %s", s.addr))
 	}
 
 	// Register health service
@@ -70,9 +93,95 @@ func (s *Server) Start() error {
 
 	// Register reflection service for debugging
 	reflection.Register(s.server)
+	return lis, nil
+}
 
-	// Start serving
-	return s.server.Serve(lis)
+// DefaultShutdownTimeout is how long Serve waits for in-flight calls by default.
+const DefaultShutdownTimeout = 20 * time.Second
+
+// Serve starts the gRPC server and runs it until ctx is cancelled or the
+// process receives SIGTERM or an interrupt, then shuts down gracefully: every
+// health status turns NOT_SERVING so probes and clients move away, and
+// in-flight calls get up to timeout (0 = DefaultShutdownTimeout) to finish
+// before they are cancelled.
+//
+// It returns nil after a graceful shutdown, and the error otherwise.
+//
+// Example:
+//
+//	server := grpc.NewServer(":9090")
+//	server.WatchReadiness(ctx, 10*time.Second, &observability.TCPHealthCheck{CheckName: "qdrant", Addr: "qdrant:6334"})
+//	if err := server.Serve(ctx, 0); err != nil {
+//		log.Fatal(err)
+//	}
+func (s *Server) Serve(ctx context.Context, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	sigCtx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	lis, err := s.listen(ctx)
+	if err != nil {
+		return err
+	}
+	served := make(chan error, 1)
+	go func() { served <- s.server.Serve(lis) }()
+
+	select {
+	case err := <-served:
+		return calque.WrapErr(ctx, err, "grpc server failed")
+	case <-sigCtx.Done():
+	}
+
+	calque.Logger(ctx).Info("shutting down grpc server", "timeout", timeout)
+	s.healthSrv.Shutdown()
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-time.After(timeout):
+		s.server.Stop()
+		return calque.NewErr(ctx, "grpc server did not shut down in time")
+	}
+}
+
+// WatchReadiness runs checks every interval until ctx is done and sets the
+// overall health status ("") to SERVING when all pass and NOT_SERVING
+// otherwise, so Kubernetes gRPC readiness probes follow dependency health
+// (provider reachability, store connectivity, flow saturation).
+func (s *Server) WatchReadiness(ctx context.Context, interval time.Duration, checks ...observability.HealthChecker) {
+	registry := observability.NewHealthCheckRegistry()
+	for _, check := range checks {
+		registry.Register(check)
+	}
+	update := func() {
+		status := grpc_health_v1.HealthCheckResponse_SERVING
+		if registry.RunAll(ctx).Status != observability.HealthStatusHealthy {
+			status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+		}
+		if ctx.Err() == nil {
+			s.healthSrv.SetServingStatus("", status)
+		}
+	}
+
+	update()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				update()
+			}
+		}
+	}()
 }
 
 // Stop gracefully stops the gRPC server.
@@ -2,13 +2,17 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"io"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/observability"
 	calquepb "github.com/calque-ai/go-calque/proto"
 )
 
@@ -392,3 +396,86 @@ func TestServerGetHealthServer(t *testing.T) {
 		t.Error("Expected non-nil health server")
 	}
 }
+
+func servingStatus(t *testing.T, server *Server, service string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	t.Helper()
+	resp, err := server.GetHealthServer().Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: service})
+	if err != nil {
+		t.Fatalf("health Check(%q) error = %v", service, err)
+	}
+	return resp.GetStatus()
+}
+
+func TestServerWatchReadiness(t *testing.T) {
+	t.Parallel()
+
+	var storeUp atomic.Bool
+	storeUp.Store(true)
+	store := &observability.FuncHealthCheck{CheckName: "store", CheckFunc: func(context.Context) error {
+		if !storeUp.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	}}
+
+	server := NewServer(":0")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.WatchReadiness(ctx, 10*time.Millisecond, store)
+
+	if got := servingStatus(t, server, ""); got != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("status with healthy store = %v", got)
+	}
+	storeUp.Store(false)
+	waitForStatus(t, server, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	storeUp.Store(true)
+	waitForStatus(t, server, grpc_health_v1.HealthCheckResponse_SERVING)
+}
+
+func waitForStatus(t *testing.T, server *Server, want grpc_health_v1.HealthCheckResponse_ServingStatus) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for servingStatus(t, server, "") != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("status never became %v", want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestServerServeShutdown(t *testing.T) {
+	t.Parallel()
+
+	server := NewServer("127.0.0.1:0")
+	server.RegisterFlow("upper", calque.NewFlow())
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ctx, time.Second) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err := server.GetHealthServer().Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "upper"})
+		if err == nil && resp.GetStatus() == grpc_health_v1.HealthCheckResponse_SERVING {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("server never became ready")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Serve() did not return after cancellation")
+	}
+	for _, service := range []string{"", "upper"} {
+		if got := servingStatus(t, server, service); got != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+			t.Errorf("status of %q after shutdown = %v, want NOT_SERVING", service, got)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package grpc
+
+import (
+	"context"
+	"io"
+
+	grpcclient "google.golang.org/grpc"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	grpcerrors "github.com/calque-ai/go-calque/pkg/grpc"
+)
+
+// ServerStream creates a handler from any server-streaming RPC.
+//
+// Input: request data, passed whole to open (buffered)
+// Output: render of each response message, in order
+// Behavior: STREAMING - each message is written as it is received
+//
+// The next message is only received after the previous one was read by the
+// downstream handler, so a slow consumer pauses the RPC: gRPC stops granting
+// flow control credit and the server's Send blocks, instead of responses
+// piling up in memory. Use a connection with a fixed window
+// (grpcerrors.Config.StreamWindowSize or Service.WithStreamWindow) to keep
+// the unread data small. If the downstream handler stops reading, the RPC is
+// cancelled.
+//
+// Example:
+//
+//	client := calquepb.NewAIServiceClient(conn)
+//	flow := calque.NewFlow().
+//		Use(grpc.ServerStream(
+//			func(ctx context.Context, input []byte) (grpcclient.ServerStreamingClient[calquepb.AIResponse], error) {
+//				return client.StreamChat(ctx, &calquepb.AIRequest{Prompt: string(input)})
+//			},
+//			func(resp *calquepb.AIResponse) []byte { return []byte(resp.Response) },
+//		)).
+//		Use(text.LineProcessor(render))
+func ServerStream[Resp any](
+	open func(ctx context.Context, input []byte) (grpcclient.ServerStreamingClient[Resp], error),
+	render func(*Resp) []byte,
+) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var input []byte
+		if err := calque.Read(req, &input); err != nil {
+			return grpcerrors.WrapErrorSimple(req.Context, err, "failed to read input data")
+		}
+
+		ctx, cancel := context.WithCancel(req.Context)
+		defer cancel() // ends the RPC if the stream is abandoned early
+		stream, err := open(ctx, input)
+		if err != nil {
+			return grpcerrors.WrapError(ctx, err, "failed to open server stream")
+		}
+		return pumpServerStream(ctx, stream, render, res)
+	})
+}
+
+// pumpServerStream writes every message of stream to res, receiving the next only after the last was consumed
+func pumpServerStream[Resp any](ctx context.Context, stream grpcclient.ServerStreamingClient[Resp], render func(*Resp) []byte, res *calque.Response) error {
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return grpcerrors.WrapError(ctx, err, "failed to receive streaming response")
+		}
+		if _, err := res.Data.Write(render(msg)); err != nil {
+			return grpcerrors.WrapErrorSimple(ctx, err, "failed to write streaming response")
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	grpcclient "google.golang.org/grpc"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	grpcerrors "github.com/calque-ai/go-calque/pkg/grpc"
+	calquepb "github.com/calque-ai/go-calque/proto"
+)
+
+// countingAIServer streams count messages of size bytes, recording how many Send calls completed
+type countingAIServer struct {
+	calquepb.UnimplementedAIServiceServer
+	count, size int
+	sent        atomic.Int32
+	done        chan error
+}
+
+func (s *countingAIServer) StreamChat(_ *calquepb.AIRequest, stream grpcclient.ServerStreamingServer[calquepb.AIResponse]) error {
+	chunk := strings.Repeat("x", s.size)
+	var err error
+	for range s.count {
+		if err = stream.Send(&calquepb.AIResponse{Response: chunk}); err != nil {
+			break
+		}
+		s.sent.Add(1)
+	}
+	s.done <- err
+	return err
+}
+
+// startStreamServer serves srv and returns a connection with a fixed stream window
+func startStreamServer(t *testing.T, srv calquepb.AIServiceServer) *grpcclient.ClientConn {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpcclient.NewServer()
+	calquepb.RegisterAIServiceServer(server, srv)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	config := grpcerrors.DefaultConfig(lis.Addr().String())
+	config.StreamWindowSize = grpcerrors.DefaultStreamWindowSize
+	conn, err := grpcerrors.NewClient(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func chatStream(conn *grpcclient.ClientConn) calque.Handler {
+	client := calquepb.NewAIServiceClient(conn)
+	return ServerStream(
+		func(ctx context.Context, input []byte) (grpcclient.ServerStreamingClient[calquepb.AIResponse], error) {
+			return client.StreamChat(ctx, &calquepb.AIRequest{Prompt: string(input)})
+		},
+		func(resp *calquepb.AIResponse) []byte { return []byte(resp.Response) },
+	)
+}
+
+func TestServerStreamBackpressure(t *testing.T) {
+	srv := &countingAIServer{count: 500, size: 16 * 1024, done: make(chan error, 1)}
+	conn := startStreamServer(t, srv)
+
+	// the downstream handler reads 4 MB at full speed, then stalls until released
+	const fastBytes = 4 << 20
+	release := make(chan struct{})
+	slow := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		if _, err := io.CopyN(res.Data, req.Data, fastBytes); err != nil {
+			return err
+		}
+		<-release
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+
+	var out string
+	result := make(chan error, 1)
+	go func() {
+		result <- calque.NewFlow().Use(chatStream(conn)).Use(slow).Run(context.Background(), "go", &out)
+	}()
+
+	time.Sleep(300 * time.Millisecond)
+	// a 64 KiB window plus gRPC's send quota hold a handful of 16 KiB
+	// messages beyond those consumed, rather than the rest of the stream
+	consumed := fastBytes / srv.size
+	if ahead := int(srv.sent.Load()) - consumed; ahead > 20 {
+		t.Errorf("server ran %d messages ahead of the stalled flow", ahead)
+	}
+
+	close(release)
+	if err := <-result; err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(out) != srv.count*srv.size {
+		t.Errorf("output = %d bytes, want %d", len(out), srv.count*srv.size)
+	}
+}
+
+func TestServerStreamCancelsWhenDownstreamStops(t *testing.T) {
+	srv := &countingAIServer{count: 500, size: 16 * 1024, done: make(chan error, 1)}
+	conn := startStreamServer(t, srv)
+
+	// the downstream handler gives up after the first chunk
+	failing := calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+		if _, err := io.ReadFull(req.Data, make([]byte, 1)); err != nil {
+			return err
+		}
+		return errors.New("downstream failed")
+	})
+
+	var out string
+	if err := calque.NewFlow().Use(chatStream(conn)).Use(failing).Run(context.Background(), "go", &out); err == nil {
+		t.Fatal("expected error from downstream handler")
+	}
+
+	select {
+	case err := <-srv.done:
+		if err == nil {
+			t.Errorf("server sent all %d messages, want the RPC cancelled", srv.count)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server stream was not cancelled")
+	}
+}
+
+func TestServerStreamOpenError(t *testing.T) {
+	handler := ServerStream(
+		func(context.Context, []byte) (grpcclient.ServerStreamingClient[calquepb.AIResponse], error) {
+			return nil, errors.New("connection refused")
+		},
+		func(resp *calquepb.AIResponse) []byte { return []byte(resp.Response) },
+	)
+	var out string
+	err := calque.NewFlow().Use(handler).Run(context.Background(), "go", &out)
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Run() error = %v, want open error", err)
+	}
+}
+
+func TestServiceStreamWindow(t *testing.T) {
+	if got := StreamingService("s", "localhost:0").StreamWindow; got != grpcerrors.DefaultStreamWindowSize {
+		t.Errorf("StreamingService window = %d, want %d", got, grpcerrors.DefaultStreamWindowSize)
+	}
+
+	registry := NewRegistry()
+	defer registry.Close()
+	if err := registry.Register(NewService("small", "localhost:0").WithStreamWindow(1024)); err == nil {
+		t.Error("expected error for window below 64 KiB")
+	}
+	if err := registry.Register(NewService("large", "localhost:0").WithStreamWindow(1 << 20)); err != nil {
+		t.Errorf("Register() error = %v", err)
+	}
+}
@@ -37,6 +37,7 @@ import (
 
 	grpcclient "google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
@@ -53,6 +54,13 @@ type Service struct {
 	Timeout    time.Duration // Timeout for gRPC calls
 	MaxRetries int           // Maximum number of retries for failed calls
 	RetryDelay time.Duration // Delay between retries
+	// Compression compresses messages on the connection with the named
+	// compressor, grpcerrors.CompressionGzip or CompressionZstd (empty = none)
+	Compression string
+	// StreamWindow fixes the flow control window of the connection in bytes
+	// (0 = gRPC's adaptive window), bounding how far a server stream runs
+	// ahead of the flow; see WithStreamWindow
+	StreamWindow int32
 }
 
 // Registry manages multiple gRPC services and their connections.
@@ -80,7 +88,20 @@ func (r *Registry) Register(service *Service) error {
 
 	// Connect to the service if not already connected
 	if service.Conn == nil {
-		conn, err := grpcclient.NewClient(service.Endpoint, grpcclient.WithTransportCredentials(insecure.NewCredentials()))
+		opts := []grpcclient.DialOption{grpcclient.WithTransportCredentials(insecure.NewCredentials())}
+		if service.Compression != "" {
+			if encoding.GetCompressor(service.Compression) == nil {
+				return grpcerrors.NewErrorSimple(ctx, fmt.Sprintf("unknown compressor %s for service %s", service.Compression, service.Name))
+			}
+			opts = append(opts, grpcclient.WithDefaultCallOptions(grpcclient.UseCompressor(service.Compression)))
+		}
+		if service.StreamWindow != 0 {
+			if service.StreamWindow < grpcerrors.DefaultStreamWindowSize {
+				return grpcerrors.NewErrorSimple(ctx, fmt.Sprintf("stream window of service %s must be at least 64 KiB", service.Name))
+			}
+			opts = append(opts, grpcerrors.StreamWindowOptions(service.StreamWindow)...)
+		}
+		conn, err := grpcclient.NewClient(service.Endpoint, opts...)
 		if err != nil {
 			return grpcerrors.WrapErrorfSimple(ctx, err, "failed to connect to service %s at %s", service.Name, service.Endpoint)
 		}
@@ -154,13 +175,14 @@ func ServiceWithTypes[TReq, TResp proto.Message](name, endpoint string) *Service
 // StreamingService creates a streaming gRPC service configuration.
 func StreamingService(name, endpoint string) *Service {
 	return &Service{
-		Name:       name,
-		Endpoint:   endpoint,
-		Streaming:  true,
-		Method:     "FlowService/StreamFlow", // Default streaming method
-		Timeout:    60 * time.Second,         // Longer timeout for streaming
-		MaxRetries: 3,                        // Default retries
-		RetryDelay: 1 * time.Second,          // Default retry delay
+		Name:         name,
+		Endpoint:     endpoint,
+		Streaming:    true,
+		Method:       "FlowService/StreamFlow",           // Default streaming method
+		Timeout:      60 * time.Second,                   // Longer timeout for streaming
+		MaxRetries:   3,                                  // Default retries
+		RetryDelay:   1 * time.Second,                    // Default retry delay
+		StreamWindow: grpcerrors.DefaultStreamWindowSize, // Keep server streams paced by the flow
 	}
 }
 
@@ -233,3 +255,22 @@ func (s *Service) WithRetries(maxRetries int, retryDelay time.Duration) *Service
 	s.RetryDelay = retryDelay
 	return s
 }
+
+// WithCompression compresses messages to the service, useful for large
+// documents between distributed flow stages. name is
+// grpcerrors.CompressionGzip or grpcerrors.CompressionZstd; servers using
+// this module decompress both transparently.
+func (s *Service) WithCompression(name string) *Service {
+	s.Compression = name
+	return s
+}
+
+// WithStreamWindow fixes the HTTP/2 flow control window of the connection to
+// bytes (at least 64 KiB). While a downstream handler is slow, Stream and
+// ServerStream stop receiving and the server blocks once this much data is
+// unread, instead of gRPC buffering up to 16 MiB per stream. 0 restores
+// gRPC's adaptive window for maximum throughput.
+func (s *Service) WithStreamWindow(bytes int32) *Service {
+	s.StreamWindow = bytes
+	return s
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
+	grpcerrors "github.com/calque-ai/go-calque/pkg/grpc"
 	calquepb "github.com/calque-ai/go-calque/proto"
 )
 
@@ -338,6 +339,20 @@ func TestServiceRetryConfiguration(t *testing.T) {
 	}
 }
 
+func TestServiceCompression(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry()
+	defer registry.Close()
+
+	if err := registry.Register(NewService("zstd-service", testEndpoint).WithCompression(grpcerrors.CompressionZstd)); err != nil {
+		t.Errorf("Register() with zstd error = %v", err)
+	}
+	if err := registry.Register(NewService("bad-service", testEndpoint).WithCompression("brotli")); err == nil {
+		t.Error("Register() with unknown compressor error = nil, want error")
+	}
+}
+
 // TestServiceRegistryConcurrency tests concurrent registry operations
 func TestServiceRegistryConcurrency(t *testing.T) {
 	t.Parallel()
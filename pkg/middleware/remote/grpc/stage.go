@@ -0,0 +1,180 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	grpcclient "google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	grpcerrors "github.com/calque-ai/go-calque/pkg/grpc"
+	"github.com/calque-ai/go-calque/pkg/middleware/remote"
+)
+
+// stageService carries remote stage frame streams as a bidirectional stream of
+// byte chunks, so no generated code is needed on either side
+const (
+	stageServiceName = "calque.remote.Stage"
+	stageMethod      = "/" + stageServiceName + "/Exchange"
+)
+
+var stageStreamDesc = grpcclient.StreamDesc{
+	StreamName:    "Exchange",
+	ServerStreams: true,
+	ClientStreams: true,
+}
+
+// Transport is a remote.Transport over gRPC. Addresses are gRPC targets
+// ("host:port", "dns:///...", "srv:///..."); one connection per address is
+// opened on first use and kept until Close.
+type Transport struct {
+	base *grpcerrors.Config
+
+	mu    sync.Mutex
+	conns map[string]*grpcclient.ClientConn
+}
+
+// NewTransport creates a gRPC remote.Transport whose connections use base
+// with the address as endpoint (nil = grpcerrors.DefaultConfig).
+//
+// Example:
+//
+//	transport := grpc.NewTransport(nil)
+//	defer transport.Close()
+//	flow.Use(remote.Handler(transport, "summarizer:50051", remote.TextCodec))
+func NewTransport(base *grpcerrors.Config) *Transport {
+	return &Transport{base: base, conns: make(map[string]*grpcclient.ClientConn)}
+}
+
+// Exchange implements remote.Transport.
+func (t *Transport) Exchange(ctx context.Context, address string, request io.Reader) (io.ReadCloser, error) {
+	conn, err := t.conn(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := conn.NewStream(ctx, &stageStreamDesc, stageMethod)
+	if err != nil {
+		cancel()
+		return nil, grpcerrors.WrapError(ctx, err, "failed to open remote stage stream", address)
+	}
+
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := request.Read(buf)
+			if n > 0 {
+				if stream.SendMsg(wrapperspb.Bytes(buf[:n])) != nil {
+					return // the receive side reports the stream error
+				}
+			}
+			if err == io.EOF {
+				_ = stream.CloseSend()
+				return
+			}
+			if err != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+	return &stageReader{recv: stream.RecvMsg, close: cancel}, nil
+}
+
+func (t *Transport) conn(ctx context.Context, address string) (*grpcclient.ClientConn, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if conn, ok := t.conns[address]; ok {
+		return conn, nil
+	}
+	config := grpcerrors.DefaultConfig(address)
+	if t.base != nil {
+		copied := *t.base
+		copied.Endpoint = address
+		config = &copied
+	}
+	conn, err := grpcerrors.NewClient(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	t.conns[address] = conn
+	return conn, nil
+}
+
+// Close closes all connections of the transport.
+func (t *Transport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var first error
+	for address, conn := range t.conns {
+		if err := conn.Close(); err != nil && first == nil {
+			first = grpcerrors.WrapErrorfSimple(context.Background(), err, "failed to close connection to %s", address)
+		}
+		delete(t.conns, address)
+	}
+	return first
+}
+
+// stageReader reads a stream of byte chunks as one byte stream
+type stageReader struct {
+	recv  func(any) error
+	close context.CancelFunc
+	buf   []byte
+}
+
+func (r *stageReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		msg := &wrapperspb.BytesValue{}
+		if err := r.recv(msg); err != nil {
+			return 0, err
+		}
+		r.buf = msg.GetValue()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *stageReader) Close() error {
+	r.close()
+	return nil
+}
+
+// stageWriter sends each write as one message
+type stageWriter struct {
+	send func(any) error
+}
+
+func (w *stageWriter) Write(p []byte) (int, error) {
+	if err := w.send(wrapperspb.Bytes(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// RegisterStage serves handler as a remote stage on server, for
+// remote.Handler with a Transport. Use remote.Stages to serve several.
+//
+// Example:
+//
+//	server := grpc.NewServer(":50051")
+//	grpc.RegisterStage(server.GetServer(), remote.Stages{"summarize": summarizer})
+//	server.Start()
+func RegisterStage(server *grpcclient.Server, handler calque.Handler) {
+	server.RegisterService(&grpcclient.ServiceDesc{
+		ServiceName: stageServiceName,
+		HandlerType: (*calque.Handler)(nil),
+		Streams: []grpcclient.StreamDesc{{
+			StreamName:    stageStreamDesc.StreamName,
+			ServerStreams: true,
+			ClientStreams: true,
+			Handler: func(srv any, stream grpcclient.ServerStream) error {
+				return remote.Serve(stream.Context(), srv.(calque.Handler),
+					&stageReader{recv: stream.RecvMsg, close: func() {}},
+					&stageWriter{send: stream.SendMsg})
+			},
+		}},
+	}, handler)
+}
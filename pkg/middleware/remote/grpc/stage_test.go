@@ -0,0 +1,111 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	grpcclient "google.golang.org/grpc"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/remote"
+	"github.com/calque-ai/go-calque/pkg/middleware/text"
+)
+
+// startStageServer serves handler with RegisterStage and returns the address
+func startStageServer(t *testing.T, handler calque.Handler) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpcclient.NewServer()
+	RegisterStage(server, handler)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+func TestTransport(t *testing.T) {
+	addr := startStageServer(t, remote.Stages{
+		"upper": text.Transform(strings.ToUpper),
+		"echo": calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+			_, err := io.Copy(res.Data, req.Data)
+			return err
+		}),
+		"reject": calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+			return calque.InvalidInput(calque.NewErr(req.Context, "unsupported language"))
+		}),
+	})
+	transport := NewTransport(nil)
+	defer transport.Close()
+
+	t.Run("round trip", func(t *testing.T) {
+		var out string
+		handler := remote.Handler(transport, addr, remote.TextCodec, remote.HandlerConfig{Stage: "upper"})
+		if err := calque.NewFlow().Use(handler).Run(context.Background(), "over grpc", &out); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if out != "OVER GRPC" {
+			t.Errorf("output = %q", out)
+		}
+	})
+
+	t.Run("large stream", func(t *testing.T) {
+		input := strings.Repeat("0123456789", 50_000)
+		var out string
+		handler := remote.Handler(transport, addr, remote.BinaryCodec, remote.HandlerConfig{Stage: "echo"})
+		if err := calque.NewFlow().Use(handler).Run(context.Background(), input, &out); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if out != input {
+			t.Errorf("output = %d bytes, want %d", len(out), len(input))
+		}
+	})
+
+	t.Run("remote error", func(t *testing.T) {
+		var out string
+		handler := remote.Handler(transport, addr, remote.TextCodec, remote.HandlerConfig{Stage: "reject"})
+		err := calque.NewFlow().Use(handler).Run(context.Background(), "x", &out)
+		if !calque.IsInvalidInput(err) || !strings.Contains(err.Error(), "unsupported language") {
+			t.Errorf("Run() error = %v, want the remote invalid input error", err)
+		}
+	})
+
+	if len(transport.conns) != 1 {
+		t.Errorf("transport opened %d connections to one address", len(transport.conns))
+	}
+}
+
+func TestTransportUnreachable(t *testing.T) {
+	transport := NewTransport(nil)
+	defer transport.Close()
+
+	var out string
+	err := calque.NewFlow().Use(remote.Handler(transport, "127.0.0.1:1", remote.TextCodec)).Run(context.Background(), "x", &out)
+	if err == nil {
+		t.Fatal("expected error for unreachable stage")
+	}
+}
+
+func TestTransportCoordinator(t *testing.T) {
+	gpu := startStageServer(t, remote.Stages{"upper": text.Transform(strings.ToUpper)})
+	cpu := startStageServer(t, remote.Stages{"exclaim": text.Transform(func(s string) string { return s + "!" })})
+	transport := NewTransport(nil)
+	defer transport.Close()
+
+	coord := remote.NewCoordinator(transport, remote.Workers(transport, gpu, cpu))
+	flow, err := coord.Flow(context.Background(), "exclaim", "upper")
+	if err != nil {
+		t.Fatalf("Flow() error = %v", err)
+	}
+	var out string
+	if err := flow.Run(context.Background(), "two workers", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out != "TWO WORKERS!" {
+		t.Errorf("output = %q", out)
+	}
+}
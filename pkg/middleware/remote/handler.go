@@ -0,0 +1,423 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/convert"
+)
+
+// FrameContentType is the media type of remote stage streams, for transports
+// that label their payloads.
+const FrameContentType = "application/vnd.calque.frames"
+
+// Control frames of the remote stage protocol, in the application-defined
+// range of convert.FrameType. A request is an envelope followed by data
+// frames; a response is data frames followed by an end or an error frame.
+const (
+	frameEnvelope convert.FrameType = 0x80
+	frameEnd      convert.FrameType = 0x81
+	frameError    convert.FrameType = 0x82
+)
+
+const protocolVersion = 1
+
+// Transport carries the frame streams of remote stages: HTTPTransport here,
+// and gRPC and NATS transports in the remote/grpc and remote/nats packages.
+type Transport interface {
+	// Exchange sends request to the stage at address and returns the stage's
+	// response stream. request is read until EOF while the response is being
+	// received; closing the response ends the exchange.
+	Exchange(ctx context.Context, address string, request io.Reader) (io.ReadCloser, error)
+}
+
+// Codec labels the data frames of a stage with a frame type and media type.
+// Remote stages answer with the codec of the request.
+type Codec struct {
+	Type convert.FrameType
+	MIME string
+}
+
+// Codecs for common stage payloads.
+var (
+	TextCodec   = Codec{Type: convert.FrameText, MIME: "text/plain; charset=utf-8"}
+	JSONCodec   = Codec{Type: convert.FrameText, MIME: "application/json"}
+	BinaryCodec = Codec{Type: convert.FrameBinary, MIME: "application/octet-stream"}
+)
+
+// HandlerConfig configures a remote Handler.
+type HandlerConfig struct {
+	// Stage selects a handler registered with Stages on servers hosting several
+	Stage string
+	// Metadata lists MetadataBus keys whose values are forwarded to the stage
+	Metadata []string
+}
+
+// envelope is the first frame of a request
+type envelope struct {
+	Version   int               `json:"v"`
+	Stage     string            `json:"stage,omitempty"`
+	Type      convert.FrameType `json:"type"`
+	MIME      string            `json:"mime,omitempty"`
+	TraceID   string            `json:"trace_id,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	TenantID  string            `json:"tenant_id,omitempty"`
+	Deadline  time.Time         `json:"deadline,omitzero"`
+	Trace     map[string]string `json:"trace,omitempty"` // OpenTelemetry propagation fields
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// remoteError is the payload of an error frame
+type remoteError struct {
+	Message string `json:"message"`
+	Kind    string `json:"kind,omitempty"`
+}
+
+// Handler creates a handler that runs a pipeline stage in another process.
+//
+// Input: stage input (streaming)
+// Output: stage output (streaming)
+// Behavior: STREAMING - input is framed and sent as it is read, output is
+// written as frames arrive
+//
+// Which process runs the stage is decided by transport and address (a URL for
+// HTTPTransport, a gRPC target, a NATS subject), so moving a stage out of
+// process is a one-line change:
+//
+//	flow.Use(summarize)
+//	flow.Use(remote.Handler(remote.HTTPTransport(nil), "http://summarizer/stage", remote.TextCodec))
+//
+// The trace, request and tenant IDs, the context deadline, the OpenTelemetry
+// trace context and the HandlerConfig.Metadata keys of the MetadataBus reach
+// the remote handler's context. Errors of the remote handler are returned
+// with their kind (calque.IsInvalidInput, calque.IsRetryable, ...) preserved;
+// a stream that ends before the stage finished is a retryable error.
+//
+// Example:
+//
+//	// Serve the stage in another process
+//	http.Handle("/stage", remote.HTTPStage(ai.Agent(client)))
+//
+//	// Call it from the flow
+//	flow := calque.NewFlow().
+//		Use(retrieval.VectorSearch(store)).
+//		Use(remote.Handler(remote.HTTPTransport(nil), "http://agents:8080/stage", remote.TextCodec))
+func Handler(transport Transport, address string, codec Codec, config ...HandlerConfig) calque.Handler {
+	var cfg HandlerConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		ctx, cancel := context.WithCancel(req.Context)
+		defer cancel()
+
+		pr, pw := io.Pipe()
+		defer pr.Close()
+		go func() {
+			pw.CloseWithError(writeRequest(ctx, pw, req.Data, codec, cfg))
+		}()
+
+		resp, err := transport.Exchange(ctx, address, pr)
+		if err != nil {
+			return calque.WrapErr(req.Context, err, "failed to reach remote stage "+address)
+		}
+		defer resp.Close()
+		return readResponse(req.Context, resp, res.Data, address)
+	})
+}
+
+// writeRequest writes the envelope and the input as data frames
+func writeRequest(ctx context.Context, w io.Writer, input io.Reader, codec Codec, cfg HandlerConfig) error {
+	env := envelope{
+		Version:   protocolVersion,
+		Stage:     cfg.Stage,
+		Type:      codec.Type,
+		MIME:      codec.MIME,
+		TraceID:   calque.TraceID(ctx),
+		RequestID: calque.RequestID(ctx),
+		TenantID:  calque.TenantID(ctx),
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		env.Deadline = deadline
+	}
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) > 0 {
+		env.Trace = carrier
+	}
+	if mb := calque.GetMetadataBus(ctx); mb != nil {
+		for _, key := range cfg.Metadata {
+			if v, ok := mb.GetString(key); ok {
+				if env.Metadata == nil {
+					env.Metadata = map[string]string{}
+				}
+				env.Metadata[key] = v
+			}
+		}
+	}
+
+	fw := convert.NewFrameWriter(w)
+	if err := writeJSONFrame(fw, frameEnvelope, env); err != nil {
+		return err
+	}
+	return writeData(fw, input, codec)
+}
+
+// writeData frames each chunk read from r
+func writeData(fw *convert.FrameWriter, r io.Reader, codec Codec) error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if werr := fw.WriteFrame(convert.Frame{Type: codec.Type, MIME: codec.MIME, Payload: buf[:n]}); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readResponse writes the data frames of a response until its end frame
+func readResponse(ctx context.Context, r io.Reader, w io.Writer, address string) error {
+	fr := convert.NewFrameReader(r, 0)
+	for {
+		f, err := fr.ReadFrame()
+		if err != nil {
+			if ctx.Err() != nil {
+				return calque.Cancelled(calque.WrapErr(ctx, ctx.Err(), "remote stage "+address+" cancelled"))
+			}
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return calque.Retryable(calque.WrapErr(ctx, err, "remote stage "+address+" ended before finishing"))
+		}
+		switch f.Type {
+		case frameEnd:
+			return nil
+		case frameError:
+			var re remoteError
+			if err := json.Unmarshal(f.Payload, &re); err != nil {
+				return calque.WrapErr(ctx, err, "invalid error from remote stage "+address)
+			}
+			return withRemoteKind(re.Kind, calque.NewErr(ctx, "remote stage "+address+": "+re.Message))
+		case frameEnvelope:
+			return calque.NewErr(ctx, "unexpected envelope from remote stage "+address)
+		default:
+			if _, err := w.Write(f.Payload); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Serve runs handler for one remote stage request read from request, writing
+// the framed response to response. Transports call it on the server side;
+// the envelope's IDs, deadline, trace context and metadata are applied to
+// ctx, and StageName reports the requested stage.
+//
+// Errors of the handler are sent to the caller in the response; Serve only
+// returns an error when the request is malformed or the response cannot be written.
+func Serve(ctx context.Context, handler calque.Handler, request io.Reader, response io.Writer) error {
+	fr := convert.NewFrameReader(request, 0)
+	fw := convert.NewFrameWriter(response)
+
+	var env envelope
+	first, err := fr.ReadFrame()
+	if err != nil {
+		err = calque.WrapErr(ctx, err, "failed to read remote stage envelope")
+	} else if first.Type != frameEnvelope {
+		err = calque.NewErr(ctx, "remote stage request does not start with an envelope")
+	} else if err = json.Unmarshal(first.Payload, &env); err != nil {
+		err = calque.WrapErr(ctx, err, "invalid remote stage envelope")
+	} else if env.Version != protocolVersion {
+		err = calque.NewErr(ctx, "unsupported remote stage protocol version")
+	}
+	if err != nil {
+		_ = writeError(fw, calque.InvalidInput(err))
+		return err
+	}
+
+	ctx, cancel := env.context(ctx)
+	defer cancel()
+
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	go func() {
+		for {
+			f, err := fr.ReadFrame()
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				pw.CloseWithError(err)
+				return
+			}
+			if f.Type == frameEnvelope || f.Type == frameEnd || f.Type == frameError {
+				pw.CloseWithError(calque.NewErr(ctx, "unexpected control frame in remote stage request"))
+				return
+			}
+			if _, err := pw.Write(f.Payload); err != nil {
+				return
+			}
+		}
+	}()
+
+	out := &frameOutput{fw: fw, codec: Codec{Type: env.Type, MIME: env.MIME}}
+	if herr := handler.ServeFlow(calque.NewRequest(ctx, pr), calque.NewResponse(out)); herr != nil {
+		if out.err != nil {
+			return out.err
+		}
+		return writeError(fw, herr)
+	}
+	return fw.WriteFrame(convert.Frame{Type: frameEnd})
+}
+
+// frameOutput frames every write of the remote handler with the request's codec
+type frameOutput struct {
+	fw    *convert.FrameWriter
+	codec Codec
+	err   error
+}
+
+func (o *frameOutput) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := o.fw.WriteFrame(convert.Frame{Type: o.codec.Type, MIME: o.codec.MIME, Payload: p}); err != nil {
+		o.err = err
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// context applies the envelope to the server-side context
+func (env *envelope) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if env.TraceID != "" {
+		ctx = calque.WithTraceID(ctx, env.TraceID)
+	}
+	if env.RequestID != "" {
+		ctx = calque.WithRequestID(ctx, env.RequestID)
+	}
+	if env.TenantID != "" {
+		ctx = calque.WithTenant(ctx, env.TenantID)
+	}
+	if len(env.Trace) > 0 {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(env.Trace))
+	}
+	if len(env.Metadata) > 0 {
+		mb := calque.GetMetadataBus(ctx)
+		if mb == nil {
+			mb = calque.NewMetadataBus(0)
+			ctx = calque.WithMetadataBus(ctx, mb)
+		}
+		for k, v := range env.Metadata {
+			mb.Set(k, v)
+		}
+	}
+	ctx = context.WithValue(ctx, stageKey{}, env.Stage)
+	if !env.Deadline.IsZero() {
+		return context.WithDeadline(ctx, env.Deadline)
+	}
+	return context.WithCancel(ctx)
+}
+
+type stageKey struct{}
+
+// StageName returns the stage requested by the caller (HandlerConfig.Stage)
+// inside a handler run by Serve, or "" when none was requested.
+func StageName(ctx context.Context) string {
+	name, _ := ctx.Value(stageKey{}).(string)
+	return name
+}
+
+// Stages hosts several handlers behind one server, selected by
+// HandlerConfig.Stage. A request for the ListStages stage is answered with
+// the JSON array of the hosted stage names, for Workers discovery.
+//
+// Example:
+//
+//	grpc.RegisterStage(server, remote.Stages{
+//		"summarize": summarizer,
+//		"classify":  classifier,
+//	})
+type Stages map[string]calque.Handler
+
+// ServeFlow runs the handler of the requested stage.
+func (s Stages) ServeFlow(req *calque.Request, res *calque.Response) error {
+	name := StageName(req.Context)
+	if name == ListStages {
+		names := make([]string, 0, len(s))
+		for stage := range s {
+			names = append(names, stage)
+		}
+		sort.Strings(names)
+		return json.NewEncoder(res.Data).Encode(names)
+	}
+	handler, ok := s[name]
+	if !ok {
+		return calque.InvalidInput(calque.NewErr(req.Context, "unknown remote stage "+name))
+	}
+	return handler.ServeFlow(req, res)
+}
+
+// writeError writes an error frame carrying the error kind
+func writeError(fw *convert.FrameWriter, err error) error {
+	return writeJSONFrame(fw, frameError, remoteError{Message: err.Error(), Kind: errorKind(err)})
+}
+
+func writeJSONFrame(fw *convert.FrameWriter, t convert.FrameType, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return fw.WriteFrame(convert.Frame{Type: t, MIME: "application/json", Payload: data})
+}
+
+// errorKind names the calque error kind of err for the wire
+func errorKind(err error) string {
+	switch {
+	case calque.IsInvalidInput(err):
+		return "invalid_input"
+	case calque.IsCancelled(err) || errors.Is(err, context.Canceled):
+		return "cancelled"
+	case calque.IsRateLimited(err):
+		return "rate_limited"
+	case calque.IsRetryable(err):
+		return "retryable"
+	case calque.IsProviderError(err):
+		return "provider"
+	default:
+		return ""
+	}
+}
+
+// withRemoteKind restores the kind of a remote error
+func withRemoteKind(kind string, err error) error {
+	switch kind {
+	case "invalid_input":
+		return calque.InvalidInput(err)
+	case "cancelled":
+		return calque.Cancelled(err)
+	case "rate_limited":
+		return calque.RateLimited(err, 0)
+	case "retryable":
+		return calque.Retryable(err)
+	case "provider":
+		return calque.ProviderError(err)
+	default:
+		return err
+	}
+}
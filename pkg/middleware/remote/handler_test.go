@@ -0,0 +1,200 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/convert"
+)
+
+var upper = calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+	var s string
+	if err := calque.Read(req, &s); err != nil {
+		return err
+	}
+	return calque.Write(res, strings.ToUpper(s))
+})
+
+// startHTTPStage serves handler with HTTPStage and returns its URL
+func startHTTPStage(t *testing.T, handler calque.Handler) string {
+	t.Helper()
+	server := httptest.NewServer(HTTPStage(handler))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestHandlerHTTP(t *testing.T) {
+	url := startHTTPStage(t, upper)
+
+	var out string
+	flow := calque.NewFlow().Use(Handler(HTTPTransport(nil), url, TextCodec))
+	if err := flow.Run(context.Background(), "hello remote stage", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out != "HELLO REMOTE STAGE" {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestHandlerPropagatesContext(t *testing.T) {
+	type seen struct {
+		TraceID, RequestID, Tenant, Stage, Model string
+		HasDeadline                              bool
+	}
+	echo := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		ctx := req.Context
+		s := seen{
+			TraceID:   calque.TraceID(ctx),
+			RequestID: calque.RequestID(ctx),
+			Tenant:    calque.TenantID(ctx),
+			Stage:     StageName(ctx),
+		}
+		_, s.HasDeadline = ctx.Deadline()
+		if mb := calque.GetMetadataBus(ctx); mb != nil {
+			s.Model, _ = mb.GetString("model")
+		}
+		return json.NewEncoder(res.Data).Encode(s)
+	})
+	url := startHTTPStage(t, Stages{"inspect": echo})
+
+	mb := calque.NewMetadataBus(0)
+	mb.Set("model", "small")
+	mb.Set("secret", "not forwarded")
+	ctx := calque.WithMetadataBus(context.Background(), mb)
+	ctx = calque.WithTraceID(ctx, "trace-1")
+	ctx = calque.WithRequestID(ctx, "req-1")
+	ctx = calque.WithTenant(ctx, "acme")
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	var out []byte
+	handler := Handler(HTTPTransport(nil), url, JSONCodec, HandlerConfig{Stage: "inspect", Metadata: []string{"model"}})
+	if err := calque.NewFlow().Use(handler).Run(ctx, "", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	var got seen
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("output is not JSON: %q", out)
+	}
+	want := seen{TraceID: "trace-1", RequestID: "req-1", Tenant: "acme", Stage: "inspect", Model: "small", HasDeadline: true}
+	if got != want {
+		t.Errorf("remote context = %+v, want %+v", got, want)
+	}
+}
+
+func TestHandlerStreamsBothWays(t *testing.T) {
+	// echoes each chunk as soon as it arrives
+	echo := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		_, err := io.Copy(res.Data, req.Data)
+		return err
+	})
+	url := startHTTPStage(t, echo)
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := Handler(HTTPTransport(nil), url, TextCodec).ServeFlow(
+			calque.NewRequest(context.Background(), inR), calque.NewResponse(outW))
+		outW.CloseWithError(err)
+		done <- err
+	}()
+
+	buf := make([]byte, 16)
+	for _, chunk := range []string{"first", "second"} {
+		if _, err := inW.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+		n, err := io.ReadAtLeast(outR, buf, len(chunk))
+		if err != nil {
+			t.Fatalf("no output for %q before the input ended: %v", chunk, err)
+		}
+		if string(buf[:n]) != chunk {
+			t.Errorf("output = %q, want %q", buf[:n], chunk)
+		}
+	}
+	_ = inW.Close()
+	if _, err := io.ReadAll(outR); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("ServeFlow() error = %v", err)
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	failing := Stages{
+		"invalid": calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+			return calque.InvalidInput(calque.NewErr(req.Context, "bad document"))
+		}),
+		"flaky": calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+			return calque.Retryable(calque.NewErr(req.Context, "backend busy"))
+		}),
+	}
+	url := startHTTPStage(t, failing)
+
+	tests := []struct {
+		name      string
+		transport Transport
+		address   string
+		stage     string
+		check     func(error) bool
+		contains  string
+	}{
+		{name: "invalid input", transport: HTTPTransport(nil), address: url, stage: "invalid", check: calque.IsInvalidInput, contains: "bad document"},
+		{name: "retryable", transport: HTTPTransport(nil), address: url, stage: "flaky", check: calque.IsRetryable, contains: "backend busy"},
+		{name: "unknown stage", transport: HTTPTransport(nil), address: url, stage: "missing", check: calque.IsInvalidInput, contains: "unknown remote stage"},
+		{name: "invalid URL", transport: HTTPTransport(nil), address: "://no-scheme", check: calque.IsInvalidInput, contains: "invalid remote stage URL"},
+		{name: "unreachable", transport: HTTPTransport(nil), address: "http://127.0.0.1:1/stage", check: calque.IsRetryable, contains: "failed to reach"},
+		{name: "truncated stream", transport: truncatedTransport{}, address: "mem", check: calque.IsRetryable, contains: "ended before finishing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out string
+			handler := Handler(tt.transport, tt.address, TextCodec, HandlerConfig{Stage: tt.stage})
+			err := calque.NewFlow().Use(handler).Run(context.Background(), "input", &out)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !tt.check(err) {
+				t.Errorf("error %v has the wrong kind", err)
+			}
+			if tt.contains != "" && !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.contains)
+			}
+		})
+	}
+}
+
+// truncatedTransport answers with a data frame cut off midway
+type truncatedTransport struct{}
+
+func (truncatedTransport) Exchange(_ context.Context, _ string, request io.Reader) (io.ReadCloser, error) {
+	go func() { _, _ = io.Copy(io.Discard, request) }()
+	var buf bytes.Buffer
+	_ = convert.NewFrameWriter(&buf).WriteText("partial")
+	return io.NopCloser(bytes.NewReader(buf.Bytes()[:buf.Len()-3])), nil
+}
+
+func TestServeRejectsMalformedRequests(t *testing.T) {
+	var resp bytes.Buffer
+	err := Serve(context.Background(), upper, strings.NewReader("not frames at all"), &resp)
+	if err == nil {
+		t.Fatal("expected error for a request without an envelope")
+	}
+	readErr := readResponse(context.Background(), &resp, io.Discard, "mem")
+	if !calque.IsInvalidInput(readErr) {
+		t.Errorf("caller error = %v, want invalid input", readErr)
+	}
+	if errors.Is(readErr, io.ErrUnexpectedEOF) {
+		t.Error("malformed request was reported as a truncated stream")
+	}
+}
@@ -0,0 +1,95 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// HTTPTransport sends remote stage requests as streaming POST requests to
+// the address URL, served by HTTPStage. client nil uses http.DefaultClient.
+//
+// The request body is sent while the response is read, so stages stream in
+// both directions over HTTP/2 and over HTTP/1.1 servers that allow it, as
+// HTTPStage does.
+func HTTPTransport(client *http.Client) Transport {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &httpTransport{client: client}
+}
+
+type httpTransport struct {
+	client *http.Client
+}
+
+func (t *httpTransport) Exchange(ctx context.Context, address string, request io.Reader) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, request)
+	if err != nil {
+		return nil, calque.InvalidInput(calque.WrapErr(ctx, err, "invalid remote stage URL"))
+	}
+	req.Header.Set("Content-Type", FrameContentType)
+	req.Header.Set("Accept", FrameContentType)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, calque.Retryable(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		_ = resp.Body.Close()
+		err := calque.NewErr(ctx, fmt.Sprintf("remote stage returned HTTP %d: %s", resp.StatusCode, body))
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return nil, calque.Retryable(err)
+		}
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// HTTPStage serves handler as a remote stage for HTTPTransport.
+//
+// Mount it on any path; remote stage requests are POSTs with
+// FrameContentType bodies. Handler errors are reported in the response stream,
+// so the status is 200 once the stage starts.
+//
+// Example:
+//
+//	mux := http.NewServeMux()
+//	mux.Handle("/stages/summarize", remote.HTTPStage(summarizer))
+//	http.ListenAndServe(":8080", mux)
+func HTTPStage(handler calque.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "remote stages accept POST", http.StatusMethodNotAllowed)
+			return
+		}
+		rc := http.NewResponseController(w)
+		_ = rc.EnableFullDuplex() // keep reading the request after the response starts (HTTP/1.1)
+
+		w.Header().Set("Content-Type", FrameContentType)
+		w.WriteHeader(http.StatusOK)
+		if err := Serve(r.Context(), handler, r.Body, &flushWriter{w: w, rc: rc}); err != nil {
+			calque.Logger(r.Context()).Debug("remote stage request failed", "error", err)
+		}
+	})
+}
+
+// flushWriter flushes every frame to the client as soon as it is written
+type flushWriter struct {
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	_ = f.rc.Flush()
+	return n, nil
+}
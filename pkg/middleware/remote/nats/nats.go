@@ -0,0 +1,155 @@
+// Package nats runs go-calque pipeline stages over NATS request-reply.
+//
+// A stage is a subject: RegisterStage subscribes a handler to it, usually in
+// a queue group so requests are spread over replicas, and NewTransport sends
+// remote.Handler requests to it. Output streams back as a sequence of
+// messages to the caller's inbox.
+//
+// Example:
+//
+//	// Worker process
+//	nc, _ := natsgo.Connect(natsgo.DefaultURL)
+//	nats.RegisterStage(nc, "stages.summarize", "summarizers", summarizer)
+//
+//	// Flow process
+//	flow := calque.NewFlow().
+//		Use(remote.Handler(nats.NewTransport(nc, 0), "stages.summarize", remote.TextCodec))
+package nats
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/remote"
+)
+
+// DefaultTimeout is how long a Transport waits for the next response message.
+const DefaultTimeout = 30 * time.Second
+
+// Transport is a remote.Transport over NATS. Addresses are subjects.
+//
+// The request is sent as one message once the stage input is complete, so it
+// must fit the server's max payload (1 MB by default); the response streams.
+// NATS has no flow control, so a slow downstream handler makes responses
+// queue in the subscription (up to the client's pending limits).
+type Transport struct {
+	nc      *natsgo.Conn
+	timeout time.Duration
+}
+
+// NewTransport creates a NATS remote.Transport on nc. timeout bounds the wait
+// for each response message (0 = DefaultTimeout), so a stage whose process
+// died mid-stream fails instead of hanging.
+func NewTransport(nc *natsgo.Conn, timeout time.Duration) *Transport {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Transport{nc: nc, timeout: timeout}
+}
+
+// Exchange implements remote.Transport.
+func (t *Transport) Exchange(ctx context.Context, subject string, request io.Reader) (io.ReadCloser, error) {
+	limit := t.nc.MaxPayload()
+	body, err := io.ReadAll(io.LimitReader(request, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, calque.InvalidInput(calque.NewErr(ctx, fmt.Sprintf("remote stage request exceeds the NATS max payload of %d bytes", limit)))
+	}
+
+	inbox := t.nc.NewRespInbox()
+	sub, err := t.nc.SubscribeSync(inbox)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to subscribe to remote stage replies")
+	}
+	msg := natsgo.NewMsg(subject)
+	msg.Reply = inbox
+	msg.Data = body
+	if err := t.nc.PublishMsg(msg); err != nil {
+		_ = sub.Unsubscribe()
+		return nil, calque.Retryable(calque.WrapErr(ctx, err, "failed to publish remote stage request"))
+	}
+	return &replyReader{ctx: ctx, sub: sub, timeout: t.timeout}, nil
+}
+
+// replyReader reads the messages sent to an inbox as one byte stream
+type replyReader struct {
+	ctx     context.Context
+	sub     *natsgo.Subscription
+	timeout time.Duration
+	buf     []byte
+}
+
+func (r *replyReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		ctx, cancel := context.WithTimeout(r.ctx, r.timeout)
+		msg, err := r.sub.NextMsgWithContext(ctx)
+		cancel()
+		if err != nil {
+			return 0, err
+		}
+		if msg.Header.Get("Status") == "503" {
+			return 0, calque.Retryable(calque.NewErr(r.ctx, "no NATS responders for remote stage "+msg.Subject))
+		}
+		r.buf = msg.Data
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *replyReader) Close() error {
+	return r.sub.Unsubscribe()
+}
+
+// RegisterStage serves handler as a remote stage on subject. Subscribers
+// sharing a non-empty queue group split the requests between them. Each
+// request runs in its own goroutine; unsubscribe to stop serving.
+//
+// Example:
+//
+//	sub, err := nats.RegisterStage(nc, "stages.classify", "classifiers", classifier)
+//	defer sub.Drain()
+func RegisterStage(nc *natsgo.Conn, subject, queue string, handler calque.Handler) (*natsgo.Subscription, error) {
+	serve := func(msg *natsgo.Msg) {
+		if msg.Reply == "" {
+			return
+		}
+		go func() {
+			w := &replyWriter{nc: nc, subject: msg.Reply, limit: int(nc.MaxPayload())}
+			if err := remote.Serve(context.Background(), handler, bytes.NewReader(msg.Data), w); err != nil {
+				calque.Logger(context.Background()).Debug("remote stage request failed", "subject", subject, "error", err)
+			}
+		}()
+	}
+	sub, err := nc.QueueSubscribe(subject, queue, serve)
+	if err != nil {
+		return nil, calque.WrapErr(context.Background(), err, "failed to subscribe remote stage "+subject)
+	}
+	return sub, nil
+}
+
+// replyWriter publishes writes to the caller's inbox, split to the max payload
+type replyWriter struct {
+	nc      *natsgo.Conn
+	subject string
+	limit   int
+}
+
+func (w *replyWriter) Write(p []byte) (int, error) {
+	for sent := 0; sent < len(p); {
+		end := min(sent+w.limit, len(p))
+		if err := w.nc.Publish(w.subject, p[sent:end]); err != nil {
+			return sent, err
+		}
+		sent = end
+	}
+	return len(p), nil
+}
@@ -0,0 +1,115 @@
+package nats
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	natsgo "github.com/nats-io/nats.go"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/remote"
+	"github.com/calque-ai/go-calque/pkg/middleware/text"
+)
+
+// connect starts an embedded NATS server and returns a connection to it
+func connect(t *testing.T, maxPayload int32) *natsgo.Conn {
+	t.Helper()
+	opts := natstest.DefaultTestOptions
+	opts.Port = natsserver.RANDOM_PORT
+	if maxPayload > 0 {
+		opts.MaxPayload = maxPayload
+	}
+	server := natstest.RunServer(&opts)
+	t.Cleanup(server.Shutdown)
+
+	nc, err := natsgo.Connect(server.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+	return nc
+}
+
+func TestTransport(t *testing.T) {
+	nc := connect(t, 0)
+	sub, err := RegisterStage(nc, "stages.upper", "workers", text.Transform(strings.ToUpper))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+
+	var out string
+	handler := remote.Handler(NewTransport(nc, 0), "stages.upper", remote.TextCodec)
+	if err := calque.NewFlow().Use(handler).Run(context.Background(), "over nats", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out != "OVER NATS" {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestTransportSplitsLargeResponses(t *testing.T) {
+	nc := connect(t, 4096)
+	repeat := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var s string
+		if err := calque.Read(req, &s); err != nil {
+			return err
+		}
+		return calque.Write(res, strings.Repeat(s, 10_000))
+	})
+	if _, err := RegisterStage(nc, "stages.repeat", "", repeat); err != nil {
+		t.Fatal(err)
+	}
+
+	var out string
+	handler := remote.Handler(NewTransport(nc, 0), "stages.repeat", remote.TextCodec)
+	if err := calque.NewFlow().Use(handler).Run(context.Background(), "ab", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if out != strings.Repeat("ab", 10_000) {
+		t.Errorf("output = %d bytes, want %d", len(out), 20_000)
+	}
+}
+
+func TestTransportErrors(t *testing.T) {
+	nc := connect(t, 1024)
+
+	t.Run("no responders", func(t *testing.T) {
+		var out string
+		handler := remote.Handler(NewTransport(nc, time.Second), "stages.missing", remote.TextCodec)
+		err := calque.NewFlow().Use(handler).Run(context.Background(), "x", &out)
+		if !calque.IsRetryable(err) {
+			t.Errorf("Run() error = %v, want retryable", err)
+		}
+	})
+
+	t.Run("request too large", func(t *testing.T) {
+		var out string
+		handler := remote.Handler(NewTransport(nc, time.Second), "stages.any", remote.TextCodec)
+		err := calque.NewFlow().Use(handler).Run(context.Background(), strings.Repeat("x", 4096), &out)
+		if !calque.IsInvalidInput(err) {
+			t.Errorf("Run() error = %v, want invalid input", err)
+		}
+	})
+
+	t.Run("stalled stage", func(t *testing.T) {
+		block := make(chan struct{})
+		defer close(block)
+		stalled := calque.HandlerFunc(func(*calque.Request, *calque.Response) error {
+			<-block
+			return nil
+		})
+		if _, err := RegisterStage(nc, "stages.stalled", "", stalled); err != nil {
+			t.Fatal(err)
+		}
+		var out string
+		handler := remote.Handler(NewTransport(nc, 100*time.Millisecond), "stages.stalled", remote.TextCodec)
+		if err := calque.NewFlow().Use(handler).Run(context.Background(), "x", &out); err == nil {
+			t.Error("expected timeout error for a stage that never answers")
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package retrieval
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// BM25 default parameters
+const (
+	DefaultBM25K1 = 1.2  // Term frequency saturation
+	DefaultBM25B  = 0.75 // Document length normalization
+)
+
+// BM25Config configures BM25 scoring.
+type BM25Config struct {
+	K1 float64 // Term frequency saturation (default: 1.2)
+	B  float64 // Length normalization from 0 (none) to 1 (full) (default: 0.75)
+}
+
+// BM25Index is an in-memory keyword index scored with Okapi BM25.
+//
+// It implements VectorStore so it can be used anywhere a store is expected,
+// including as the keyword side of HybridSearch. Queries match on terms, so
+// SearchQuery.Vector is ignored. BM25 scores are unbounded and
+// SearchQuery.Threshold is not applied. Filters match metadata values for
+// equality.
+//
+// Safe for concurrent use.
+//
+// Example:
+//
+//	index := retrieval.NewBM25Index()
+//	err := index.Store(ctx, docs)
+//	result, err := index.Search(ctx, retrieval.SearchQuery{Text: "ERR_CONN_RESET", Limit: 5})
+type BM25Index struct {
+	mu       sync.RWMutex
+	k1, b    float64
+	docs     map[string]*bm25Doc
+	postings map[string]map[string]int // term -> doc ID -> term frequency
+	totalLen int
+}
+
+type bm25Doc struct {
+	doc    Document
+	length int
+}
+
+// NewBM25Index creates an empty BM25 index.
+//
+// Example:
+//
+//	index := retrieval.NewBM25Index(retrieval.BM25Config{K1: 1.5, B: 0.75})
+func NewBM25Index(config ...BM25Config) *BM25Index {
+	cfg := BM25Config{K1: DefaultBM25K1, B: DefaultBM25B}
+	if len(config) > 0 {
+		if config[0].K1 > 0 {
+			cfg.K1 = config[0].K1
+		}
+		if config[0].B > 0 && config[0].B <= 1 {
+			cfg.B = config[0].B
+		}
+	}
+	return &BM25Index{
+		k1:       cfg.K1,
+		b:        cfg.B,
+		docs:     make(map[string]*bm25Doc),
+		postings: make(map[string]map[string]int),
+	}
+}
+
+// Search returns documents ranked by BM25 score for the query text
+func (idx *BM25Index) Search(ctx context.Context, query SearchQuery) (*SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	scores := make(map[string]float64)
+	if n := len(idx.docs); n > 0 {
+		avgLen := float64(idx.totalLen) / float64(n)
+		for term := range uniqueTerms(tokenize(query.Text)) {
+			postings := idx.postings[term]
+			if len(postings) == 0 {
+				continue
+			}
+			df := float64(len(postings))
+			idf := math.Log(1 + (float64(n)-df+0.5)/(df+0.5))
+			for id, tf := range postings {
+				length := float64(idx.docs[id].length)
+				freq := float64(tf)
+				scores[id] += idf * freq * (idx.k1 + 1) / (freq + idx.k1*(1-idx.b+idx.b*length/avgLen))
+			}
+		}
+	}
+
+	documents := make([]Document, 0, len(scores))
+	for id, score := range scores {
+		entry := idx.docs[id]
+		if !matchesFilter(entry.doc.Metadata, query.Filter) {
+			continue
+		}
+		doc := entry.doc
+		doc.Score = score
+		documents = append(documents, doc)
+	}
+	sort.Slice(documents, func(i, j int) bool {
+		if documents[i].Score != documents[j].Score {
+			return documents[i].Score > documents[j].Score
+		}
+		return documents[i].ID < documents[j].ID
+	})
+
+	total := len(documents)
+	if query.Limit > 0 && len(documents) > query.Limit {
+		documents = documents[:query.Limit]
+	}
+	return &SearchResult{Documents: documents, Query: query.Text, Total: total, Threshold: query.Threshold}, nil
+}
+
+// Store indexes documents, replacing any existing documents with the same IDs
+func (idx *BM25Index) Store(ctx context.Context, documents []Document) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, doc := range documents {
+		idx.remove(doc.ID)
+
+		terms := tokenize(doc.Content)
+		idx.docs[doc.ID] = &bm25Doc{doc: doc, length: len(terms)}
+		idx.totalLen += len(terms)
+		for _, term := range terms {
+			if idx.postings[term] == nil {
+				idx.postings[term] = make(map[string]int)
+			}
+			idx.postings[term][doc.ID]++
+		}
+	}
+	return nil
+}
+
+// Delete removes documents from the index
+func (idx *BM25Index) Delete(ctx context.Context, ids []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, id := range ids {
+		idx.remove(id)
+	}
+	return nil
+}
+
+// Len returns the number of indexed documents
+func (idx *BM25Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// Health always succeeds for the in-memory index
+func (idx *BM25Index) Health(_ context.Context) error {
+	return nil
+}
+
+// Close is a no-op for the in-memory index
+func (idx *BM25Index) Close() error {
+	return nil
+}
+
+// remove drops a document and its postings; callers must hold the write lock
+func (idx *BM25Index) remove(id string) {
+	entry, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for term := range uniqueTerms(tokenize(entry.doc.Content)) {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	idx.totalLen -= entry.length
+	delete(idx.docs, id)
+}
+
+// tokenize lowercases text and splits it into letter and digit runs
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// uniqueTerms returns the distinct terms in a token list
+func uniqueTerms(terms []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(terms))
+	for _, term := range terms {
+		set[term] = struct{}{}
+	}
+	return set
+}
+
+// matchesFilter reports whether metadata has every filter key with an equal value
+func matchesFilter(metadata, filter map[string]any) bool {
+	for key, want := range filter {
+		got, ok := metadata[key]
+		if !ok || !reflect.DeepEqual(got, want) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package retrieval
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func newTestBM25Index(t *testing.T) *BM25Index {
+	t.Helper()
+	index := NewBM25Index()
+	err := index.Store(context.Background(), []Document{
+		{ID: "1", Content: "Connection reset: ERR_CONN_RESET when the proxy drops idle sockets", Metadata: map[string]any{"kind": "error"}},
+		{ID: "2", Content: "Tuning the proxy for long-lived connections", Metadata: map[string]any{"kind": "guide"}},
+		{ID: "3", Content: "Release notes for version 2.4", Metadata: map[string]any{"kind": "notes"}},
+		{ID: "4", Content: "The proxy proxy proxy configuration reference", Metadata: map[string]any{"kind": "guide"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return index
+}
+
+func TestBM25IndexSearch(t *testing.T) {
+	index := newTestBM25Index(t)
+
+	tests := []struct {
+		name     string
+		query    SearchQuery
+		expected []string
+	}{
+		{name: "exact token match", query: SearchQuery{Text: "err_conn_reset"}, expected: []string{"1"}},
+		{name: "term frequency ranks higher", query: SearchQuery{Text: "proxy"}, expected: []string{"4", "2", "1"}},
+		{name: "limit", query: SearchQuery{Text: "proxy", Limit: 1}, expected: []string{"4"}},
+		{name: "metadata filter", query: SearchQuery{Text: "proxy", Filter: map[string]any{"kind": "error"}}, expected: []string{"1"}},
+		{name: "rare term outweighs common term", query: SearchQuery{Text: "proxy release"}, expected: []string{"3", "4", "2", "1"}},
+		{name: "no match", query: SearchQuery{Text: "kubernetes"}, expected: []string{}},
+		{name: "empty query", query: SearchQuery{Text: ""}, expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := index.Search(context.Background(), tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]string, len(result.Documents))
+			for i, doc := range result.Documents {
+				ids[i] = doc.ID
+				if doc.Score <= 0 {
+					t.Errorf("document %s score = %v, want > 0", doc.ID, doc.Score)
+				}
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("results = %v, want %v", ids, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBM25IndexUpdateAndDelete(t *testing.T) {
+	ctx := context.Background()
+	index := newTestBM25Index(t)
+
+	if err := index.Store(ctx, []Document{{ID: "3", Content: "Proxy timeouts explained"}}); err != nil {
+		t.Fatal(err)
+	}
+	if index.Len() != 4 {
+		t.Errorf("Len() = %d after replace, want 4", index.Len())
+	}
+	if result, _ := index.Search(ctx, SearchQuery{Text: "release"}); len(result.Documents) != 0 {
+		t.Errorf("replaced document still matches old content: %+v", result.Documents)
+	}
+
+	if err := index.Delete(ctx, []string{"1", "2", "3", "4", "missing"}); err != nil {
+		t.Fatal(err)
+	}
+	if index.Len() != 0 || len(index.postings) != 0 || index.totalLen != 0 {
+		t.Errorf("index not empty after delete: len=%d postings=%d totalLen=%d", index.Len(), len(index.postings), index.totalLen)
+	}
+}
+
+func TestBM25IndexCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewBM25Index().Search(ctx, SearchQuery{Text: "x"}); err == nil {
+		t.Error("expected error for cancelled context")
+	}
+}
@@ -0,0 +1,251 @@
+package retrieval
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/text"
+)
+
+// Default chunking configuration values
+const (
+	DefaultChunkSize    = 1000 // Characters per chunk
+	DefaultChunkOverlap = 100  // Characters shared between consecutive chunks
+)
+
+// Chunker splits a document into smaller documents for embedding.
+//
+// Chunks should carry IDs derived from the source document so re-ingesting
+// the same document replaces its chunks instead of duplicating them.
+type Chunker interface {
+	Chunk(doc Document) []Document
+}
+
+// ChunkOptions configures TextChunker.
+type ChunkOptions struct {
+	Size    int // Maximum characters per chunk (default: 1000)
+	Overlap int // Characters repeated from the previous chunk; negative disables (default: 100)
+}
+
+// textChunker splits text at paragraph, line, sentence and word boundaries
+type textChunker struct {
+	size, overlap int
+}
+
+// TextChunker creates a Chunker that splits text into overlapping chunks.
+//
+// Chunks break at the largest natural boundary that fits - paragraphs, then
+// lines, sentences and words - and only split words that are longer than a
+// whole chunk. Each chunk gets the ID "<doc ID>#<n>" and copies the document's
+// metadata plus "parent_id" and "chunk_index". Documents that fit in one chunk
+// are returned unchanged.
+//
+// Example:
+//
+//	chunker := retrieval.TextChunker(retrieval.ChunkOptions{Size: 800, Overlap: 80})
+//	chunks := chunker.Chunk(doc)
+func TextChunker(opts ...ChunkOptions) Chunker {
+	c := &textChunker{size: DefaultChunkSize, overlap: DefaultChunkOverlap}
+	if len(opts) > 0 {
+		if opts[0].Size > 0 {
+			c.size = opts[0].Size
+		}
+		if opts[0].Overlap > 0 {
+			c.overlap = opts[0].Overlap
+		} else if opts[0].Overlap < 0 {
+			c.overlap = 0
+		}
+	}
+	if c.overlap >= c.size {
+		c.overlap = c.size / 10
+	}
+	return c
+}
+
+// Chunk implements Chunker
+func (c *textChunker) Chunk(doc Document) []Document {
+	if len([]rune(doc.Content)) <= c.size {
+		return []Document{doc}
+	}
+
+	pieces := splitText(doc.Content, c.size, []string{"\n\n", "\n", ". ", " "})
+	chunks := make([]Document, 0, len(pieces))
+	current := ""
+	for _, piece := range pieces {
+		if current != "" && len([]rune(current))+len([]rune(piece)) > c.size {
+			if content := strings.TrimSpace(current); content != "" {
+				chunks = append(chunks, chunkDocument(doc, content, len(chunks)))
+			}
+			current = overlapTail(current, c.overlap)
+			// drop the overlap if it would not leave room for the next piece
+			if len([]rune(current))+len([]rune(piece)) > c.size {
+				current = ""
+			}
+		}
+		current += piece
+	}
+	if content := strings.TrimSpace(current); content != "" {
+		chunks = append(chunks, chunkDocument(doc, content, len(chunks)))
+	}
+	return chunks
+}
+
+// codeChunker splits source files with text.SplitCode
+type codeChunker struct {
+	size     int
+	fallback Chunker
+}
+
+// CodeChunker creates a Chunker that splits source files along function and
+// type boundaries with text.SplitCode.
+//
+// The language comes from the document's "language" metadata, or else the
+// extension of its "source" metadata or ID. Chunks get the IDs and metadata
+// of TextChunker plus "language", "start_line", "end_line" and "symbols", the
+// qualified names of the functions and types they contain; files that fit
+// in one chunk keep their ID and content. Documents that are not recognised
+// source code are split by TextChunker with the same
+// options. Size is the maximum chunk size; Overlap only applies to that fallback.
+//
+// Example:
+//
+//	ingest := rag.IngestFlow(retrieval.DocumentLoader("./internal/*/*.go"), retrieval.CodeChunker(), embedder, store)
+func CodeChunker(opts ...ChunkOptions) Chunker {
+	c := &codeChunker{size: text.DefaultCodeChunkSize, fallback: TextChunker(opts...)}
+	if len(opts) > 0 && opts[0].Size > 0 {
+		c.size = opts[0].Size
+	}
+	return c
+}
+
+// Chunk implements Chunker
+func (c *codeChunker) Chunk(doc Document) []Document {
+	language, _ := doc.Metadata["language"].(string)
+	if language == "" {
+		source, _ := doc.Metadata["source"].(string)
+		if source == "" {
+			source = doc.ID
+		}
+		language = text.CodeLanguage(source)
+	}
+	if language == "" {
+		return c.fallback.Chunk(doc)
+	}
+
+	pieces := text.SplitCode(doc.Content, language, text.CodeChunkOptions{MaxSize: c.size})
+	chunks := make([]Document, 0, len(pieces))
+	for i, piece := range pieces {
+		var chunk Document
+		if len(pieces) == 1 {
+			chunk = doc
+			chunk.Metadata = make(map[string]any, len(doc.Metadata)+4)
+			maps.Copy(chunk.Metadata, doc.Metadata)
+		} else {
+			chunk = chunkDocument(doc, piece.Content, i)
+		}
+		symbols := make([]string, len(piece.Symbols))
+		for j, s := range piece.Symbols {
+			symbols[j] = s.Name
+		}
+		chunk.Metadata["language"] = piece.Language
+		chunk.Metadata["start_line"] = piece.StartLine
+		chunk.Metadata["end_line"] = piece.EndLine
+		chunk.Metadata["symbols"] = symbols
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// splitText breaks text into pieces of at most size runes, preferring the
+// earliest separator in seps; separators stay attached to the preceding piece
+func splitText(text string, size int, seps []string) []string {
+	if len([]rune(text)) <= size {
+		return []string{text}
+	}
+	if len(seps) == 0 {
+		runes := []rune(text)
+		var pieces []string
+		for start := 0; start < len(runes); start += size {
+			pieces = append(pieces, string(runes[start:min(start+size, len(runes))]))
+		}
+		return pieces
+	}
+
+	var pieces []string
+	for _, part := range strings.SplitAfter(text, seps[0]) {
+		if part == "" {
+			continue
+		}
+		pieces = append(pieces, splitText(part, size, seps[1:])...)
+	}
+	return pieces
+}
+
+// overlapTail returns up to n trailing runes of s, starting at a word boundary when possible
+func overlapTail(s string, n int) string {
+	runes := []rune(s)
+	if n <= 0 {
+		return ""
+	}
+	if len(runes) <= n {
+		return s
+	}
+	tail := string(runes[len(runes)-n:])
+	if i := strings.IndexAny(tail, " \n"); i >= 0 && i < len(tail)-1 {
+		tail = tail[i+1:]
+	}
+	return tail
+}
+
+// chunkDocument builds the chunk document for index i of doc
+func chunkDocument(doc Document, content string, i int) Document {
+	metadata := make(map[string]any, len(doc.Metadata)+2)
+	maps.Copy(metadata, doc.Metadata)
+	metadata["parent_id"] = doc.ID
+	metadata["chunk_index"] = i
+
+	chunk := doc
+	chunk.ID = fmt.Sprintf("%s#%d", doc.ID, i)
+	chunk.Content = content
+	chunk.Metadata = metadata
+	chunk.Vector = nil
+	return chunk
+}
+
+// Chunk creates a document chunking middleware.
+//
+// Input: []Document JSON array (e.g. from DocumentLoader)
+// Output: []Document JSON array of chunks
+// Behavior: BUFFERED - reads entire document array for chunking
+//
+// Example:
+//
+//	flow := calque.NewFlow().
+//	    Use(retrieval.DocumentLoader("./docs/*.md")).
+//	    Use(retrieval.Chunk(retrieval.TextChunker()))
+func Chunk(chunker Chunker) calque.Handler {
+	return calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
+		if chunker == nil {
+			return calque.NewErr(r.Context, "chunker cannot be nil")
+		}
+
+		documents, err := readDocuments(r)
+		if err != nil {
+			return err
+		}
+
+		chunks := make([]Document, 0, len(documents))
+		for _, doc := range documents {
+			chunks = append(chunks, chunker.Chunk(doc)...)
+		}
+
+		result, err := json.Marshal(chunks)
+		if err != nil {
+			return err
+		}
+		return calque.Write(w, result)
+	})
+}
@@ -0,0 +1,124 @@
+package retrieval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestTextChunker(t *testing.T) {
+	paragraph := strings.Repeat("word ", 30) // 150 chars
+	tests := []struct {
+		name       string
+		opts       ChunkOptions
+		content    string
+		wantChunks int
+	}{
+		{name: "short document unchanged", opts: ChunkOptions{Size: 200}, content: "short text", wantChunks: 1},
+		{name: "paragraph boundaries", opts: ChunkOptions{Size: 200, Overlap: -1}, content: paragraph + "\n\n" + paragraph + "\n\n" + paragraph, wantChunks: 3},
+		{name: "long word is split", opts: ChunkOptions{Size: 50, Overlap: -1}, content: strings.Repeat("x", 120), wantChunks: 3},
+		{name: "overlap adds chunks", opts: ChunkOptions{Size: 100, Overlap: 40}, content: strings.Repeat("alpha beta ", 30), wantChunks: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			doc := Document{ID: "doc", Content: tt.content, Metadata: map[string]any{"source": "a.md"}}
+			chunks := TextChunker(opts).Chunk(doc)
+			if len(chunks) != tt.wantChunks {
+				t.Fatalf("got %d chunks, want %d: %+v", len(chunks), tt.wantChunks, chunks)
+			}
+			for i, chunk := range chunks {
+				if n := len([]rune(chunk.Content)); n > opts.Size || n == 0 {
+					t.Errorf("chunk %d has %d chars, want 1..%d", i, n, opts.Size)
+				}
+				if tt.wantChunks == 1 {
+					if chunk.ID != "doc" {
+						t.Errorf("unsplit document ID = %q", chunk.ID)
+					}
+					continue
+				}
+				if chunk.Metadata["parent_id"] != "doc" || chunk.Metadata["chunk_index"] != i || chunk.Metadata["source"] != "a.md" {
+					t.Errorf("chunk %d metadata = %v", i, chunk.Metadata)
+				}
+			}
+			if _, ok := doc.Metadata["parent_id"]; ok {
+				t.Error("chunking modified the source document metadata")
+			}
+		})
+	}
+}
+
+func TestTextChunkerOverlap(t *testing.T) {
+	chunks := TextChunker(ChunkOptions{Size: 60, Overlap: 20}).Chunk(Document{ID: "d", Content: "one two three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen"})
+	if len(chunks) < 2 {
+		t.Fatalf("chunks = %+v", chunks)
+	}
+	overlap := strings.Join(strings.Fields(chunks[1].Content)[:2], " ")
+	if !strings.Contains(chunks[0].Content[len(chunks[0].Content)-20:], overlap) {
+		t.Errorf("second chunk %q does not start with overlap from %q", chunks[1].Content, chunks[0].Content)
+	}
+	if chunks[0].ID != "d#0" || chunks[1].ID != "d#1" {
+		t.Errorf("IDs = %s, %s", chunks[0].ID, chunks[1].ID)
+	}
+}
+
+func TestChunkMiddleware(t *testing.T) {
+	input, _ := json.Marshal([]Document{
+		{ID: "a", Content: strings.Repeat("lorem ipsum ", 30)},
+		{ID: "b", Content: "tiny"},
+	})
+	var buf bytes.Buffer
+	req := calque.NewRequest(context.Background(), bytes.NewReader(input))
+	if err := Chunk(TextChunker(ChunkOptions{Size: 100})).ServeFlow(req, calque.NewResponse(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	var chunks []Document
+	if err := json.Unmarshal(buf.Bytes(), &chunks); err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) < 4 || chunks[len(chunks)-1].ID != "b" {
+		t.Errorf("chunks = %+v", chunks)
+	}
+
+	err := Chunk(nil).ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("[]")), calque.NewResponse(&buf))
+	if err == nil || !strings.Contains(err.Error(), "chunker cannot be nil") {
+		t.Errorf("nil chunker error = %v", err)
+	}
+}
+
+func TestCodeChunker(t *testing.T) {
+	src := "package demo\n\n// A does a.\nfunc A() {\n\tprintln(\"a\")\n}\n\n// B does b.\nfunc B() {\n\tprintln(\"b\")\n}\n"
+	chunker := CodeChunker(ChunkOptions{Size: 60})
+
+	chunks := chunker.Chunk(Document{ID: "demo/a.go", Content: src, Metadata: map[string]any{"source": "demo/a.go"}})
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2: %+v", len(chunks), chunks)
+	}
+	second := chunks[1]
+	if second.ID != "demo/a.go#1" || second.Metadata["parent_id"] != "demo/a.go" || second.Metadata["source"] != "demo/a.go" {
+		t.Errorf("chunk identity = %s %v", second.ID, second.Metadata)
+	}
+	if second.Metadata["language"] != "go" || second.Metadata["start_line"] != 8 || second.Metadata["end_line"] != 11 {
+		t.Errorf("chunk position metadata = %v", second.Metadata)
+	}
+	if symbols, _ := second.Metadata["symbols"].([]string); len(symbols) != 1 || symbols[0] != "B" {
+		t.Errorf("symbols = %v, want [B]", second.Metadata["symbols"])
+	}
+
+	// the language metadata wins over the extension
+	chunks = CodeChunker(ChunkOptions{Size: 30}).Chunk(Document{ID: "snippet", Content: "def f():\n    return 1\n\ndef g():\n    return 2\n", Metadata: map[string]any{"language": "python"}})
+	if len(chunks) != 2 || chunks[1].Metadata["symbols"].([]string)[0] != "g" {
+		t.Errorf("python chunks = %+v", chunks)
+	}
+
+	// prose falls back to TextChunker
+	prose := Document{ID: "notes.md", Content: strings.Repeat("word ", 30)}
+	if chunks := chunker.Chunk(prose); len(chunks) < 2 || chunks[0].Metadata["language"] != nil {
+		t.Errorf("markdown should use TextChunker, got %+v", chunks)
+	}
+}
@@ -0,0 +1,275 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// MetadataSources is the MetadataBus key holding the []Source from the latest retrieval step
+const MetadataSources = "retrieval.sources"
+
+// Source is a retrieved document that an answer can cite.
+type Source struct {
+	Index    int            `json:"index"`              // Citation number, starting at 1
+	ID       string         `json:"id"`                 // Document ID
+	Source   string         `json:"source,omitempty"`   // Origin from the document's "source" metadata (file path or URL)
+	Content  string         `json:"content"`            // Retrieved text
+	Metadata map[string]any `json:"metadata,omitempty"` // Document metadata
+}
+
+// Citation is a citation marker found in an answer.
+type Citation struct {
+	Marker   string  `json:"marker"`              // Marker as written, e.g. "[2]"
+	SourceID string  `json:"source_id,omitempty"` // Cited document ID; empty if the marker matches no source
+	Sentence string  `json:"sentence"`            // Sentence carrying the marker
+	Support  float64 `json:"support"`             // Fraction of the sentence's terms found in the source (0-1)
+	Verified bool    `json:"verified"`            // Source exists and Support meets MinSupport
+}
+
+// CitedAnswer is the structured output of Citations.
+type CitedAnswer struct {
+	Answer      string     `json:"answer"`                // Model output, unchanged
+	Sources     []Source   `json:"sources"`               // Sources cited by at least one verified citation
+	Citations   []Citation `json:"citations"`             // Every citation marker found
+	Unsupported []string   `json:"unsupported,omitempty"` // Claim sentences without a verified citation
+}
+
+// CitationOptions configures citation verification.
+type CitationOptions struct {
+	// MinSupport is the fraction of a sentence's terms that must appear
+	// in the cited source for the citation to count as verified (default: 0.3)
+	MinSupport float64
+
+	// MinClaimWords is the word count from which an uncited sentence is
+	// flagged as an unsupported claim (default: 5)
+	MinClaimWords int
+}
+
+// Sources returns the sources recorded by the latest retrieval step.
+//
+// VectorSearch, HybridSearch and Rerank record the documents they return (for
+// VectorSearch with a Strategy, the documents that made it into the context).
+// Returns nil if no MetadataBus is present or nothing was retrieved.
+// Each retrieval step also attaches its []Source to the run's calque.Result
+// (see Flow.RunWithResult).
+//
+// Example:
+//
+//	for _, src := range retrieval.Sources(ctx) {
+//	    fmt.Printf("[%d] %s\n", src.Index, src.Source)
+//	}
+func Sources(ctx context.Context) []Source {
+	if mb := calque.GetMetadataBus(ctx); mb != nil {
+		if v, ok := mb.Get(MetadataSources); ok {
+			sources, _ := v.([]Source)
+			return sources
+		}
+	}
+	return nil
+}
+
+// recordSources publishes retrieved documents as numbered sources on the
+// MetadataBus and as a []Source artifact on the run's calque.Result
+func recordSources(ctx context.Context, documents []Document) {
+	mb := calque.GetMetadataBus(ctx)
+	if mb == nil && calque.ResultFrom(ctx) == nil {
+		return
+	}
+	sources := make([]Source, len(documents))
+	for i, doc := range documents {
+		origin, _ := doc.Metadata["source"].(string)
+		sources[i] = Source{Index: i + 1, ID: doc.ID, Source: origin, Content: doc.Content, Metadata: doc.Metadata}
+	}
+	if mb != nil {
+		mb.Set(MetadataSources, sources)
+	}
+	calque.Attach(ctx, sources)
+}
+
+// citationMarker matches bracketed markers such as [1], [1, 3] or [doc-42]
+var citationMarker = regexp.MustCompile(`\[([^\[\]\n]{1,80})\]`)
+
+// Citations creates a middleware that verifies the citations in a model answer.
+//
+// Input: model answer text citing sources as [n] or [document-id]
+// Output: CitedAnswer JSON with verified citations, cited sources and unsupported claims
+// Behavior: BUFFERED - reads entire answer to verify citations
+//
+// Sources come from the MetadataBus, where retrieval middleware records them
+// (see Sources). Use SearchOptions.NumberSources so the context the model sees
+// carries matching [n] markers. A citation is verified when its source exists
+// and enough of the sentence's terms appear in that source; markers pointing
+// at unknown sources are kept but never verified. Sentences from MinClaimWords
+// words that have no verified citation are listed as unsupported.
+//
+// Example:
+//
+//	strategy := retrieval.StrategyRelevant
+//	flow := calque.NewFlow().
+//	    Use(retrieval.VectorSearch(store, &retrieval.SearchOptions{Strategy: &strategy, NumberSources: true})).
+//	    Use(prompt.Template("Answer using the sources and cite them as [n]:\n{{.Input}}")).
+//	    Use(ai.Agent(client)).
+//	    Use(retrieval.Citations())
+//
+//	ctx := calque.WithMetadataBus(ctx, calque.NewMetadataBus(0))
+//	err := flow.Run(ctx, question, &answerJSON)
+func Citations(opts ...CitationOptions) calque.Handler {
+	cfg := CitationOptions{MinSupport: 0.3, MinClaimWords: 5}
+	if len(opts) > 0 {
+		if opts[0].MinSupport > 0 {
+			cfg.MinSupport = opts[0].MinSupport
+		}
+		if opts[0].MinClaimWords > 0 {
+			cfg.MinClaimWords = opts[0].MinClaimWords
+		}
+	}
+
+	return calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
+		var answer string
+		if err := calque.Read(r, &answer); err != nil {
+			return err
+		}
+
+		result := verifyCitations(answer, Sources(r.Context), cfg)
+
+		output, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		return calque.Write(w, output)
+	})
+}
+
+// verifyCitations checks every citation marker in answer against sources
+func verifyCitations(answer string, sources []Source, cfg CitationOptions) CitedAnswer {
+	byIndex := make(map[string]*Source, len(sources))
+	byID := make(map[string]*Source, len(sources))
+	for i := range sources {
+		byIndex[strconv.Itoa(sources[i].Index)] = &sources[i]
+		byID[sources[i].ID] = &sources[i]
+	}
+
+	result := CitedAnswer{Answer: answer, Sources: []Source{}, Citations: []Citation{}}
+	cited := make(map[string]bool)
+
+	for _, sentence := range splitSentences(answer) {
+		plain := strings.TrimSpace(citationMarker.ReplaceAllString(sentence, ""))
+		supported := false
+
+		for _, match := range citationMarker.FindAllStringSubmatch(sentence, -1) {
+			for _, ref := range strings.Split(match[1], ",") {
+				ref = strings.TrimSpace(ref)
+				src := byIndex[ref]
+				if src == nil {
+					src = byID[ref]
+				}
+				if src == nil && !isNumber(ref) {
+					continue // not a citation, e.g. a markdown link label
+				}
+
+				citation := Citation{Marker: "[" + ref + "]", Sentence: plain}
+				if src != nil {
+					citation.SourceID = src.ID
+					citation.Support = termSupport(plain, src.Content)
+					citation.Verified = citation.Support >= cfg.MinSupport
+				}
+				if citation.Verified {
+					supported = true
+					if !cited[src.ID] {
+						cited[src.ID] = true
+						result.Sources = append(result.Sources, *src)
+					}
+				}
+				result.Citations = append(result.Citations, citation)
+			}
+		}
+
+		if !supported && len(strings.Fields(plain)) >= cfg.MinClaimWords {
+			result.Unsupported = append(result.Unsupported, plain)
+		}
+	}
+	return result
+}
+
+// splitSentences splits text at sentence-ending punctuation and line breaks,
+// keeping citation markers that follow the punctuation with their sentence
+func splitSentences(text string) []string {
+	var sentences []string
+	var current strings.Builder
+	runes := []rune(text)
+
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			sentences = append(sentences, s)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '\n' {
+			flush()
+			continue
+		}
+		current.WriteRune(r)
+		if r != '.' && r != '!' && r != '?' {
+			continue
+		}
+		// absorb trailing markers like "claim. [1][2]"
+		j := i + 1
+		for j < len(runes) {
+			rest := string(runes[j:])
+			trimmed := strings.TrimLeft(rest, " ")
+			loc := citationMarker.FindStringIndex(trimmed)
+			if loc == nil || loc[0] != 0 {
+				break
+			}
+			skip := len([]rune(rest)) - len([]rune(trimmed)) + len([]rune(trimmed[:loc[1]]))
+			current.WriteString(string(runes[j : j+skip]))
+			j += skip
+		}
+		i = j - 1
+		if j == len(runes) || runes[j] == ' ' || runes[j] == '\n' {
+			flush()
+		}
+	}
+	flush()
+	return sentences
+}
+
+// termSupport returns the fraction of the sentence's distinct terms that occur in source
+func termSupport(sentence, source string) float64 {
+	sourceTerms := uniqueTerms(tokenize(source))
+	total, found := 0, 0
+	for term := range uniqueTerms(tokenize(sentence)) {
+		if len([]rune(term)) < 3 {
+			continue // skip short function words
+		}
+		total++
+		if _, ok := sourceTerms[term]; ok {
+			found++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(found) / float64(total)
+}
+
+// isNumber reports whether s is a non-empty run of ASCII digits
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,144 @@
+package retrieval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+var citationSources = []Source{
+	{Index: 1, ID: "doc-a", Source: "docs/install.md", Content: "Install the CLI with brew install calque. Homebrew is required on macOS."},
+	{Index: 2, ID: "doc-b", Source: "docs/config.md", Content: "Configuration lives in calque.yaml in the project root directory."},
+}
+
+func TestVerifyCitations(t *testing.T) {
+	tests := []struct {
+		name            string
+		answer          string
+		wantSources     []string
+		wantVerified    []bool
+		wantUnsupported int
+	}{
+		{
+			name:         "numbered citations",
+			answer:       "Install the CLI using brew install calque [1]. Configuration lives in calque.yaml [2].",
+			wantSources:  []string{"doc-a", "doc-b"},
+			wantVerified: []bool{true, true},
+		},
+		{
+			name:         "marker after punctuation and grouped markers",
+			answer:       "Homebrew is required to install the CLI on macOS. [1, 2]",
+			wantSources:  []string{"doc-a"},
+			wantVerified: []bool{true, false},
+		},
+		{
+			name:         "document id marker",
+			answer:       "The project root holds calque.yaml for configuration [doc-b].",
+			wantSources:  []string{"doc-b"},
+			wantVerified: []bool{true},
+		},
+		{
+			name:            "citation to unknown source",
+			answer:          "The CLI supports Windows through winget packages [7].",
+			wantSources:     []string{},
+			wantVerified:    []bool{false},
+			wantUnsupported: 1,
+		},
+		{
+			name:            "uncited claim flagged and short sentence ignored",
+			answer:          "Sure! The CLI also ships a graphical dashboard for teams.",
+			wantSources:     []string{},
+			wantVerified:    []bool{},
+			wantUnsupported: 1,
+		},
+		{
+			name:         "markdown link label is not a citation",
+			answer:       "See [the docs](https://example.com) for brew install calque details [1].",
+			wantSources:  []string{"doc-a"},
+			wantVerified: []bool{true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := verifyCitations(tt.answer, citationSources, CitationOptions{MinSupport: 0.3, MinClaimWords: 5})
+			if result.Answer != tt.answer {
+				t.Errorf("answer changed: %q", result.Answer)
+			}
+			ids := make([]string, len(result.Sources))
+			for i, src := range result.Sources {
+				ids[i] = src.ID
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.wantSources, ",") {
+				t.Errorf("sources = %v, want %v", ids, tt.wantSources)
+			}
+			if len(result.Citations) != len(tt.wantVerified) {
+				t.Fatalf("citations = %+v, want %d", result.Citations, len(tt.wantVerified))
+			}
+			for i, c := range result.Citations {
+				if c.Verified != tt.wantVerified[i] {
+					t.Errorf("citation %s verified = %v (support %.2f), want %v", c.Marker, c.Verified, c.Support, tt.wantVerified[i])
+				}
+			}
+			if len(result.Unsupported) != tt.wantUnsupported {
+				t.Errorf("unsupported = %q, want %d", result.Unsupported, tt.wantUnsupported)
+			}
+		})
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+	got := splitSentences("Pi is 3.14 roughly. It was computed early. [1][2] Next line!\nLast one")
+	expected := []string{"Pi is 3.14 roughly.", "It was computed early. [1][2]", "Next line!", "Last one"}
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("splitSentences = %q, want %q", got, expected)
+	}
+}
+
+func TestCitationsFlow(t *testing.T) {
+	store := &mockVectorStore{searchResult: &SearchResult{Documents: []Document{
+		{ID: "doc-a", Content: citationSources[0].Content, Metadata: map[string]any{"source": "docs/install.md"}},
+		{ID: "doc-b", Content: citationSources[1].Content, Metadata: map[string]any{"source": "docs/config.md"}},
+	}}}
+	ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+
+	strategy := StrategyRelevant
+	var contextText string
+	err := calque.NewFlow().
+		Use(VectorSearch(store, &SearchOptions{Strategy: &strategy, NumberSources: true})).
+		Run(ctx, "how do I install it?", &contextText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(contextText, "[1] Install the CLI") || !strings.Contains(contextText, "[2] Configuration lives") {
+		t.Errorf("context = %q", contextText)
+	}
+
+	sources := Sources(ctx)
+	if len(sources) != 2 || sources[1].Source != "docs/config.md" || sources[1].Index != 2 {
+		t.Fatalf("Sources = %+v", sources)
+	}
+
+	var buf bytes.Buffer
+	req := calque.NewRequest(ctx, strings.NewReader("Run brew install calque to install the CLI [1]."))
+	if err := Citations().ServeFlow(req, calque.NewResponse(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	var answer CitedAnswer
+	if err := json.Unmarshal(buf.Bytes(), &answer); err != nil {
+		t.Fatal(err)
+	}
+	if len(answer.Sources) != 1 || answer.Sources[0].Source != "docs/install.md" || !answer.Citations[0].Verified {
+		t.Errorf("answer = %+v", answer)
+	}
+}
+
+func TestSourcesWithoutMetadataBus(t *testing.T) {
+	if Sources(context.Background()) != nil {
+		t.Error("expected nil sources without a MetadataBus")
+	}
+}
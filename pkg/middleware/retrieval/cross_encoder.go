@@ -0,0 +1,162 @@
+package retrieval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// CohereRerankConfig configures the Cohere rerank API client.
+type CohereRerankConfig struct {
+	// APIKey is the Cohere API key. Defaults to COHERE_API_KEY.
+	APIKey string
+
+	// Model is the rerank model. Default: "rerank-v3.5"
+	Model string
+
+	// BaseURL is the rerank endpoint. Default: https://api.cohere.com/v2/rerank
+	BaseURL string
+
+	// HTTPClient is used for requests. Default: client with 30s timeout
+	HTTPClient *http.Client
+}
+
+// VoyageRerankConfig configures the Voyage AI rerank API client.
+type VoyageRerankConfig struct {
+	// APIKey is the Voyage AI API key. Defaults to VOYAGE_API_KEY.
+	APIKey string
+
+	// Model is the rerank model. Default: "rerank-2"
+	Model string
+
+	// BaseURL is the rerank endpoint. Default: https://api.voyageai.com/v1/rerank
+	BaseURL string
+
+	// HTTPClient is used for requests. Default: client with 30s timeout
+	HTTPClient *http.Client
+}
+
+// crossEncoder calls a hosted cross-encoder rerank API
+type crossEncoder struct {
+	service string
+	apiKey  string
+	model   string
+	url     string
+	client  *http.Client
+	// topNField is the request field that limits returned results
+	topNField string
+	// resultsField is the response field holding the scored results
+	resultsField string
+}
+
+// NewCohereReranker creates a Reranker backed by the Cohere rerank API.
+//
+// Example:
+//
+//	reranker, err := retrieval.NewCohereReranker(retrieval.CohereRerankConfig{})
+//	if err != nil {
+//	    return err
+//	}
+//	flow.Use(retrieval.Rerank(reranker, 5))
+func NewCohereReranker(config CohereRerankConfig) (Reranker, error) {
+	return newCrossEncoder("cohere", config.APIKey, "COHERE_API_KEY", config.Model, "rerank-v3.5",
+		config.BaseURL, "https://api.cohere.com/v2/rerank", config.HTTPClient, "top_n", "results")
+}
+
+// NewVoyageReranker creates a Reranker backed by the Voyage AI rerank API.
+//
+// Example:
+//
+//	reranker, err := retrieval.NewVoyageReranker(retrieval.VoyageRerankConfig{Model: "rerank-2-lite"})
+//	if err != nil {
+//	    return err
+//	}
+//	flow.Use(retrieval.Rerank(reranker, 5))
+func NewVoyageReranker(config VoyageRerankConfig) (Reranker, error) {
+	return newCrossEncoder("voyage", config.APIKey, "VOYAGE_API_KEY", config.Model, "rerank-2",
+		config.BaseURL, "https://api.voyageai.com/v1/rerank", config.HTTPClient, "top_k", "data")
+}
+
+func newCrossEncoder(service, apiKey, keyEnv, model, defaultModel, baseURL, defaultURL string,
+	client *http.Client, topNField, resultsField string) (Reranker, error) {
+	if apiKey == "" {
+		apiKey = os.Getenv(keyEnv)
+	}
+	if apiKey == "" {
+		return nil, calque.NewErr(context.Background(), fmt.Sprintf("retrieval: %s API key is required (config APIKey or %s)", service, keyEnv))
+	}
+	if model == "" {
+		model = defaultModel
+	}
+	if baseURL == "" {
+		baseURL = defaultURL
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &crossEncoder{
+		service: service, apiKey: apiKey, model: model, url: baseURL, client: client,
+		topNField: topNField, resultsField: resultsField,
+	}, nil
+}
+
+// Rerank implements Reranker
+func (c *crossEncoder) Rerank(ctx context.Context, query string, documents []string, topN int) ([]RerankScore, error) {
+	if len(documents) == 0 {
+		return nil, nil
+	}
+	payload := map[string]any{"model": c.model, "query": query, "documents": documents}
+	if topN > 0 {
+		payload[c.topNField] = topN
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to create "+c.service+" rerank request")
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, c.service+" rerank request failed")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, calque.NewErr(ctx, fmt.Sprintf("%s rerank returned status %d: %s", c.service, resp.StatusCode, strings.TrimSpace(string(msg))))
+	}
+
+	var decoded map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to decode "+c.service+" rerank response")
+	}
+	var results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	}
+	if raw, ok := decoded[c.resultsField]; ok {
+		if err := json.Unmarshal(raw, &results); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to decode "+c.service+" rerank results")
+		}
+	}
+
+	scores := make([]RerankScore, len(results))
+	for i, r := range results {
+		scores[i] = RerankScore{Index: r.Index, Score: r.RelevanceScore}
+	}
+	return scores, nil
+}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/netpolicy"
 )
 
 const (
@@ -148,14 +149,14 @@ func loadFromSource(ctx context.Context, source string) ([]Document, error) {
 
 // loadFromURL loads document from HTTP/HTTPS URL
 func loadFromURL(ctx context.Context, url string) ([]Document, error) {
-	client := &http.Client{
+	client := netpolicy.HTTPClient(ctx, &http.Client{
 		Timeout: httpTimeout,
 		Transport: &http.Transport{
 			MaxIdleConns:      10,
 			IdleConnTimeout:   30 * time.Second,
 			DisableKeepAlives: false,
 		},
-	}
+	})
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
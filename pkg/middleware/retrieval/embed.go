@@ -0,0 +1,470 @@
+package retrieval
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/text"
+)
+
+// DefaultEmbedRetries is the EmbedOptions.MaxRetries used when none is given.
+const DefaultEmbedRetries = 5
+
+// EmbedOptions configures how EmbedDocuments and SyncDocuments call the
+// embedding provider. The zero value embeds one document at a time without
+// pacing, as a plain loop would.
+type EmbedOptions struct {
+	Workers int // concurrent Embed calls (default: 1)
+
+	// RequestsPerMinute and TokensPerMinute pace calls to the provider's
+	// limits (0 = unpaced until limits are reported, see ReportRateLimits).
+	// Reported limits lower than these replace them.
+	RequestsPerMinute int
+	TokensPerMinute   int
+
+	CountTokens text.TokenCounter // tokens of a document for TokensPerMinute (default: text.EstimateTokens)
+	MaxRetries  int               // retries of a rate-limited call (default: DefaultEmbedRetries)
+
+	// Checkpoint records every embedding as it completes, so a run that
+	// failed part way resumes without re-embedding finished documents
+	Checkpoint EmbedCheckpoint
+}
+
+// RateLimits are the rate limit headers of a provider response.
+type RateLimits struct {
+	RequestsLimit     int           // requests per minute allowed
+	TokensLimit       int           // tokens per minute allowed
+	RequestsRemaining int           // requests left in the current window (-1 = unknown)
+	TokensRemaining   int           // tokens left in the current window (-1 = unknown)
+	Reset             time.Duration // time until the exhausted limit resets (0 = unknown)
+}
+
+// ParseRateLimitHeaders reads the x-ratelimit-* and Retry-After headers used
+// by OpenAI-compatible APIs.
+//
+// Reports false when the response carries none of them.
+func ParseRateLimitHeaders(h http.Header) (RateLimits, bool) {
+	limits := RateLimits{RequestsRemaining: -1, TokensRemaining: -1}
+	found := false
+	integer := func(key string, dst *int) {
+		if n, err := strconv.Atoi(strings.TrimSpace(h.Get(key))); err == nil {
+			*dst = n
+			found = true
+		}
+	}
+	integer("x-ratelimit-limit-requests", &limits.RequestsLimit)
+	integer("x-ratelimit-limit-tokens", &limits.TokensLimit)
+	integer("x-ratelimit-remaining-requests", &limits.RequestsRemaining)
+	integer("x-ratelimit-remaining-tokens", &limits.TokensRemaining)
+
+	if limits.RequestsRemaining == 0 {
+		limits.Reset = max(limits.Reset, resetDuration(h.Get("x-ratelimit-reset-requests")))
+	}
+	if limits.TokensRemaining == 0 {
+		limits.Reset = max(limits.Reset, resetDuration(h.Get("x-ratelimit-reset-tokens")))
+	}
+	if wait := resetDuration(h.Get("Retry-After")); wait > 0 {
+		limits.Reset = max(limits.Reset, wait)
+		found = true
+	}
+	return limits, found
+}
+
+// resetDuration parses "6m0s", "20ms" or a number of seconds
+func resetDuration(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if d, err := time.ParseDuration(value); err == nil {
+		return d
+	}
+	if s, err := strconv.ParseFloat(value, 64); err == nil && s > 0 {
+		return time.Duration(s * float64(time.Second))
+	}
+	return 0
+}
+
+// ReportRateLimits passes the rate limits of a provider response to the
+// EmbedDocuments or SyncDocuments run calling the provider, which paces its
+// remaining calls to them. Call it from EmbeddingProvider implementations with
+// the context given to Embed; it does nothing outside such a run.
+func ReportRateLimits(ctx context.Context, limits RateLimits) {
+	if p, ok := ctx.Value(embedPacerKey{}).(*embedPacer); ok {
+		p.report(limits)
+	}
+}
+
+// RateLimitTransport wraps an HTTP transport to report the rate limit headers
+// of every response with ReportRateLimits.
+//
+// Set it on the HTTP client of an embedding SDK so pacing follows the
+// provider's actual limits without configuring them. A nil base uses
+// http.DefaultTransport.
+//
+// Example:
+//
+//	httpClient := &http.Client{Transport: retrieval.RateLimitTransport(nil)}
+//	embedder := newOpenAIEmbedder(option.WithHTTPClient(httpClient))
+//	flow.Use(retrieval.EmbedDocuments(embedder, retrieval.EmbedOptions{Workers: 8}))
+func RateLimitTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return rateLimitTransport{base: base}
+}
+
+type rateLimitTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		if limits, ok := ParseRateLimitHeaders(resp.Header); ok {
+			ReportRateLimits(req.Context(), limits)
+		}
+	}
+	return resp, err
+}
+
+// EmbedCheckpoint records completed embeddings, keyed by ContentHash of the
+// embedded text.
+type EmbedCheckpoint interface {
+	// Load returns every recorded embedding
+	Load(ctx context.Context) (map[string]EmbeddingVector, error)
+
+	// Add records one embedding
+	Add(ctx context.Context, hash string, vector EmbeddingVector) error
+}
+
+// memoryCheckpoint keeps embeddings in memory
+type memoryCheckpoint struct {
+	mu      sync.Mutex
+	vectors map[string]EmbeddingVector
+}
+
+// NewMemoryEmbedCheckpoint creates an EmbedCheckpoint held in memory, so a
+// long-running process can retry failed ingestion without repeating work.
+func NewMemoryEmbedCheckpoint() EmbedCheckpoint {
+	return &memoryCheckpoint{vectors: map[string]EmbeddingVector{}}
+}
+
+// Load implements EmbedCheckpoint
+func (m *memoryCheckpoint) Load(_ context.Context) (map[string]EmbeddingVector, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return maps.Clone(m.vectors), nil
+}
+
+// Add implements EmbedCheckpoint
+func (m *memoryCheckpoint) Add(_ context.Context, hash string, vector EmbeddingVector) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.vectors[hash] = vector
+	return nil
+}
+
+// fileCheckpoint appends embeddings to a JSON Lines file
+type fileCheckpoint struct {
+	mu   sync.Mutex
+	path string
+}
+
+// checkpointLine is one line of a file checkpoint
+type checkpointLine struct {
+	Hash   string          `json:"hash"`
+	Vector EmbeddingVector `json:"vector"`
+}
+
+// NewFileEmbedCheckpoint creates an EmbedCheckpoint appending to a JSON Lines
+// file at path.
+//
+// A missing file is an empty checkpoint, and a line cut short by a crash is
+// ignored. The file also acts as an embedding cache for unchanged text; remove
+// it after a successful bulk ingestion to reclaim the space.
+//
+// Example:
+//
+//	checkpoint := retrieval.NewFileEmbedCheckpoint("./data/ingest.embeddings.jsonl")
+func NewFileEmbedCheckpoint(path string) EmbedCheckpoint {
+	return &fileCheckpoint{path: path}
+}
+
+// Load implements EmbedCheckpoint
+func (f *fileCheckpoint) Load(_ context.Context) (map[string]EmbeddingVector, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	vectors := map[string]EmbeddingVector{}
+	file, err := os.Open(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return vectors, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64<<20)
+	for scanner.Scan() {
+		var line checkpointLine
+		if json.Unmarshal(scanner.Bytes(), &line) == nil && line.Hash != "" {
+			vectors[line.Hash] = line.Vector
+		}
+	}
+	return vectors, scanner.Err()
+}
+
+// Add implements EmbedCheckpoint
+func (f *fileCheckpoint) Add(_ context.Context, hash string, vector EmbeddingVector) error {
+	data, err := json.Marshal(checkpointLine{Hash: hash, Vector: vector})
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// embedAll sets Vector on every document without one
+func embedAll(ctx context.Context, provider EmbeddingProvider, documents []Document, cfg EmbedOptions) error {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.CountTokens == nil {
+		cfg.CountTokens = text.EstimateTokens
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = DefaultEmbedRetries
+	}
+
+	done := map[string]EmbeddingVector{}
+	if cfg.Checkpoint != nil {
+		var err error
+		if done, err = cfg.Checkpoint.Load(ctx); err != nil {
+			return calque.WrapErr(ctx, err, "failed to load embedding checkpoint")
+		}
+	}
+
+	var pending []int
+	for i := range documents {
+		if len(documents[i].Vector) > 0 {
+			continue
+		}
+		if vector, ok := done[ContentHash(documents[i].Content)]; ok && len(vector) > 0 {
+			documents[i].Vector = vector
+			continue
+		}
+		pending = append(pending, i)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	pacer := newEmbedPacer(cfg.RequestsPerMinute, cfg.TokensPerMinute)
+	ctx, cancel := context.WithCancelCause(context.WithValue(ctx, embedPacerKey{}, pacer))
+	defer cancel(nil)
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(cfg.Workers, len(pending)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				doc := &documents[i]
+				vector, err := pacer.embed(ctx, provider, doc.Content, cfg)
+				if err != nil {
+					cancel(calque.WrapErr(ctx, err, fmt.Sprintf("failed to generate embedding for document %s", doc.ID)))
+					continue
+				}
+				if cfg.Checkpoint != nil {
+					if err := cfg.Checkpoint.Add(ctx, ContentHash(doc.Content), vector); err != nil {
+						cancel(calque.WrapErr(ctx, err, "failed to save embedding checkpoint"))
+						continue
+					}
+				}
+				doc.Vector = vector
+			}
+		}()
+	}
+
+feed:
+	for _, i := range pending {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return context.Cause(ctx)
+}
+
+type embedPacerKey struct{}
+
+// embedPacer spaces provider calls to requests- and tokens-per-minute limits
+// and pauses every worker when the provider rejects a call
+type embedPacer struct {
+	mu       sync.Mutex
+	requests paceBucket
+	tokens   paceBucket
+	paused   time.Time
+}
+
+func newEmbedPacer(rpm, tpm int) *embedPacer {
+	now := time.Now()
+	p := &embedPacer{}
+	p.requests.setLimit(rpm, now)
+	p.tokens.setLimit(tpm, now)
+	return p
+}
+
+// embed calls the provider once paced, retrying rate-limited calls
+func (p *embedPacer) embed(ctx context.Context, provider EmbeddingProvider, content string, cfg EmbedOptions) (EmbeddingVector, error) {
+	tokens := cfg.CountTokens(content)
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := p.wait(ctx, tokens); err != nil {
+			return nil, err
+		}
+		vector, err := provider.Embed(ctx, content)
+		if err == nil || !calque.IsRateLimited(err) || attempt >= cfg.MaxRetries {
+			return vector, err
+		}
+		wait, ok := calque.RetryAfter(err)
+		if !ok {
+			wait = min(time.Second<<attempt, time.Minute)
+		}
+		p.pause(wait)
+	}
+}
+
+// wait blocks until a call of tokens fits the limits
+func (p *embedPacer) wait(ctx context.Context, tokens int) error {
+	for {
+		p.mu.Lock()
+		now := time.Now()
+		p.requests.refill(now)
+		p.tokens.refill(now)
+		delay := max(p.paused.Sub(now), p.requests.delay(1), p.tokens.delay(float64(tokens)))
+		if delay <= 0 {
+			p.requests.take(1)
+			p.tokens.take(float64(tokens))
+			p.mu.Unlock()
+			return nil
+		}
+		p.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// pause holds every call for d
+func (p *embedPacer) pause(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if until := time.Now().Add(d); until.After(p.paused) {
+		p.paused = until
+	}
+}
+
+// report adopts limits from a provider response
+func (p *embedPacer) report(limits RateLimits) {
+	p.mu.Lock()
+	now := time.Now()
+	p.requests.adopt(limits.RequestsLimit, limits.RequestsRemaining, now)
+	p.tokens.adopt(limits.TokensLimit, limits.TokensRemaining, now)
+	p.mu.Unlock()
+
+	if limits.Reset > 0 && (limits.RequestsRemaining == 0 || limits.TokensRemaining == 0) {
+		p.pause(limits.Reset)
+	}
+}
+
+// paceBucket is a token bucket refilled at limit per minute, holding at most
+// a second's worth so calls spread across the minute instead of bursting
+type paceBucket struct {
+	limit     int // per minute, 0 = unlimited
+	available float64
+	last      time.Time
+}
+
+func (b *paceBucket) capacity() float64 {
+	return max(1, float64(b.limit)/60)
+}
+
+func (b *paceBucket) setLimit(limit int, now time.Time) {
+	unlimited := b.limit == 0
+	b.limit = limit
+	b.last = now
+	if unlimited {
+		b.available = b.capacity()
+	} else {
+		b.available = min(b.available, b.capacity())
+	}
+}
+
+// adopt applies a reported limit when it is lower than the current one, and
+// the reported remaining allowance when it is below what the bucket holds
+func (b *paceBucket) adopt(limit, remaining int, now time.Time) {
+	b.refill(now)
+	if limit > 0 && (b.limit == 0 || limit < b.limit) {
+		b.setLimit(limit, now)
+	}
+	if b.limit > 0 && remaining >= 0 && float64(remaining) < b.available {
+		b.available = float64(remaining)
+	}
+}
+
+func (b *paceBucket) refill(now time.Time) {
+	if b.limit > 0 {
+		b.available = min(b.capacity(), b.available+now.Sub(b.last).Minutes()*float64(b.limit))
+	}
+	b.last = now
+}
+
+// delay is how long until n can be taken; calls larger than the capacity
+// wait for a full bucket and leave it in debt
+func (b *paceBucket) delay(n float64) time.Duration {
+	need := min(n, b.capacity())
+	if b.limit == 0 || b.available >= need {
+		return 0
+	}
+	return time.Duration((need - b.available) / float64(b.limit) * float64(time.Minute))
+}
+
+func (b *paceBucket) take(n float64) {
+	if b.limit > 0 {
+		b.available -= n
+	}
+}
@@ -0,0 +1,230 @@
+package retrieval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// funcEmbeddingProvider adapts a function to EmbeddingProvider
+type funcEmbeddingProvider func(ctx context.Context, text string) (EmbeddingVector, error)
+
+func (f funcEmbeddingProvider) Embed(ctx context.Context, text string) (EmbeddingVector, error) {
+	return f(ctx, text)
+}
+
+// lengthVector embeds text as its length, so results can be matched to documents
+func lengthVector(text string) EmbeddingVector {
+	return EmbeddingVector{float32(len(text))}
+}
+
+func numberedDocuments(n int) []Document {
+	documents := make([]Document, n)
+	for i := range documents {
+		documents[i] = Document{ID: fmt.Sprint(i), Content: strings.Repeat("x", i+1)}
+	}
+	return documents
+}
+
+func TestEmbedAllParallel(t *testing.T) {
+	var running, peak atomic.Int32
+	provider := funcEmbeddingProvider(func(_ context.Context, text string) (EmbeddingVector, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return lengthVector(text), nil
+	})
+
+	documents := numberedDocuments(8)
+	if err := embedAll(context.Background(), provider, documents, EmbedOptions{Workers: 4}); err != nil {
+		t.Fatal(err)
+	}
+	for i, doc := range documents {
+		if len(doc.Vector) != 1 || doc.Vector[0] != float32(i+1) {
+			t.Errorf("document %d vector = %v", i, doc.Vector)
+		}
+	}
+	if peak.Load() != 4 {
+		t.Errorf("peak concurrency = %d, want 4", peak.Load())
+	}
+}
+
+func TestEmbedAllPacing(t *testing.T) {
+	var calls atomic.Int32
+	provider := funcEmbeddingProvider(func(_ context.Context, text string) (EmbeddingVector, error) {
+		calls.Add(1)
+		return lengthVector(text), nil
+	})
+
+	// 6000 RPM is 100 calls a second with a burst of 100
+	start := time.Now()
+	err := embedAll(context.Background(), provider, numberedDocuments(130), EmbedOptions{Workers: 8, RequestsPerMinute: 6000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("130 calls took %v, want about 300ms at 100/s after the burst", elapsed)
+	}
+	if calls.Load() != 130 {
+		t.Errorf("calls = %d", calls.Load())
+	}
+
+	// 600 tokens a minute is 10 a second; the second 40-character document waits ~1s for its tokens
+	start = time.Now()
+	docs := []Document{{ID: "a", Content: strings.Repeat("a", 40)}, {ID: "b", Content: strings.Repeat("b", 40)}}
+	if err := embedAll(context.Background(), provider, docs, EmbedOptions{TokensPerMinute: 600}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("token pacing took %v, want about 1s", elapsed)
+	}
+}
+
+func TestEmbedAllRateLimited(t *testing.T) {
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	var rejectedAt, retriedAt time.Time
+	provider := funcEmbeddingProvider(func(_ context.Context, text string) (EmbeddingVector, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[text]++
+		if text == "busy" && attempts[text] <= 2 {
+			rejectedAt = time.Now()
+			return nil, calque.RateLimited(errors.New("429 too many requests"), 30*time.Millisecond)
+		}
+		if text == "busy" {
+			retriedAt = time.Now()
+		}
+		return lengthVector(text), nil
+	})
+
+	documents := []Document{{ID: "a", Content: "busy"}, {ID: "b", Content: "free"}}
+	if err := embedAll(context.Background(), provider, documents, EmbedOptions{Workers: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if attempts["busy"] != 3 || documents[0].Vector[0] != 4 {
+		t.Errorf("attempts = %v, vector = %v", attempts, documents[0].Vector)
+	}
+	if retriedAt.Sub(rejectedAt) < 30*time.Millisecond {
+		t.Errorf("retried %v after the rejection, want the Retry-After wait", retriedAt.Sub(rejectedAt))
+	}
+
+	always := funcEmbeddingProvider(func(_ context.Context, _ string) (EmbeddingVector, error) {
+		return nil, calque.RateLimited(errors.New("quota exceeded"), time.Millisecond)
+	})
+	err := embedAll(context.Background(), always, []Document{{ID: "a", Content: "x"}}, EmbedOptions{MaxRetries: 2})
+	if !calque.IsRateLimited(err) || !strings.Contains(err.Error(), "failed to generate embedding for document a") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestEmbedAllReportedLimits(t *testing.T) {
+	var mu sync.Mutex
+	var times []time.Time
+	provider := funcEmbeddingProvider(func(ctx context.Context, text string) (EmbeddingVector, error) {
+		mu.Lock()
+		first := len(times) == 0
+		times = append(times, time.Now())
+		mu.Unlock()
+		if first {
+			ReportRateLimits(ctx, RateLimits{RequestsRemaining: 0, TokensRemaining: -1, Reset: 80 * time.Millisecond})
+		}
+		return lengthVector(text), nil
+	})
+
+	if err := embedAll(context.Background(), provider, numberedDocuments(2), EmbedOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(times) != 2 || times[1].Sub(times[0]) < 80*time.Millisecond {
+		t.Errorf("second call %v after an exhausted window, want at least the reported reset", times[1].Sub(times[0]))
+	}
+
+	// outside a run, reports are ignored
+	ReportRateLimits(context.Background(), RateLimits{RequestsRemaining: 0, Reset: time.Hour})
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("x-ratelimit-limit-requests", "3000")
+		w.Header().Set("x-ratelimit-limit-tokens", "1000000")
+		w.Header().Set("x-ratelimit-remaining-requests", "0")
+		w.Header().Set("x-ratelimit-remaining-tokens", "999000")
+		w.Header().Set("x-ratelimit-reset-requests", "20ms")
+		w.Header().Set("x-ratelimit-reset-tokens", "6m0s")
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: RateLimitTransport(nil)}
+	pacer := newEmbedPacer(0, 0)
+	ctx := context.WithValue(context.Background(), embedPacerKey{}, pacer)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	limits, ok := ParseRateLimitHeaders(resp.Header)
+	want := RateLimits{RequestsLimit: 3000, TokensLimit: 1000000, RequestsRemaining: 0, TokensRemaining: 999000, Reset: 20 * time.Millisecond}
+	if !ok || limits != want {
+		t.Errorf("limits = %+v, want %+v", limits, want)
+	}
+	if pacer.requests.limit != 3000 || pacer.tokens.limit != 1000000 || pacer.requests.available != 0 || time.Until(pacer.paused) <= 0 {
+		t.Errorf("pacer did not adopt the reported limits: %+v", pacer)
+	}
+
+	if _, ok := ParseRateLimitHeaders(http.Header{"Retry-After": {"2"}}); !ok {
+		t.Error("Retry-After alone should be reported")
+	}
+	if _, ok := ParseRateLimitHeaders(http.Header{}); ok {
+		t.Error("no headers should report false")
+	}
+}
+
+func TestEmbedDocumentsCheckpoint(t *testing.T) {
+	checkpoint := NewFileEmbedCheckpoint(filepath.Join(t.TempDir(), "embeddings.jsonl"))
+	documents := []Document{{ID: "a", Content: "alpha"}, {ID: "b", Content: "beta"}, {ID: "c", Content: "gamma"}}
+
+	failing := funcEmbeddingProvider(func(_ context.Context, text string) (EmbeddingVector, error) {
+		if text == "gamma" {
+			return nil, errors.New("connection reset")
+		}
+		return lengthVector(text), nil
+	})
+	if _, err := serveDocuments(t, EmbedDocuments(failing, EmbedOptions{Checkpoint: checkpoint}), documents); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+
+	var embedded []string
+	provider := funcEmbeddingProvider(func(_ context.Context, text string) (EmbeddingVector, error) {
+		embedded = append(embedded, text)
+		return lengthVector(text), nil
+	})
+	if _, err := serveDocuments(t, EmbedDocuments(provider, EmbedOptions{Checkpoint: checkpoint}), documents); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(embedded, ",") != "gamma" {
+		t.Errorf("resumed run embedded %v, want only the failed document", embedded)
+	}
+
+	vectors, err := checkpoint.Load(context.Background())
+	if err != nil || len(vectors) != 3 || vectors[ContentHash("beta")][0] != 4 {
+		t.Errorf("checkpoint = %v, %v", vectors, err)
+	}
+}
@@ -0,0 +1,196 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultRRFConstant is the standard k in reciprocal rank fusion scoring 1/(k+rank)
+const DefaultRRFConstant = 60
+
+// HybridOptions configures hybrid keyword and vector search.
+type HybridOptions struct {
+	Threshold         float64           `json:"threshold"`        // Vector similarity threshold (0-1)
+	Limit             int               `json:"limit,omitempty"`  // Maximum fused results to return (default: 10)
+	Filter            map[string]any    `json:"filter,omitempty"` // Metadata filters applied to both searches
+	EmbeddingProvider EmbeddingProvider `json:"-"`                // Custom embedding provider for the vector query
+
+	// SourcePolicy drops fused results from disallowed sources (see also WithSourcePolicy)
+	SourcePolicy *SourcePolicy `json:"-"`
+
+	// CandidatesLimit is the number of results fetched from each search before fusion
+	// (default: Limit * DefaultCandidatesMultiplier)
+	CandidatesLimit int `json:"candidates_limit,omitempty"`
+
+	// RRFConstant is k in 1/(k+rank); larger values flatten rank differences (default: 60)
+	RRFConstant int `json:"rrf_constant,omitempty"`
+
+	// VectorWeight and KeywordWeight scale each ranking's contribution (default: 1)
+	VectorWeight  float64 `json:"vector_weight,omitempty"`
+	KeywordWeight float64 `json:"keyword_weight,omitempty"`
+}
+
+// HybridSearch creates a search middleware that fuses keyword and vector results.
+//
+// Input: string query text
+// Output: SearchResult JSON with fused documents, best first
+// Behavior: BUFFERED - reads entire input to perform both searches
+//
+// Vector search finds paraphrases but often misses exact terms such as error
+// codes, identifiers and names; keyword search is the opposite. HybridSearch
+// runs the query against both stores and merges the rankings with reciprocal
+// rank fusion, so documents ranked well by either search rise to the top.
+// Document scores in the output are fused RRF scores, not similarities.
+//
+// The keyword store is typically a BM25Index holding the same documents as the
+// vector store; any VectorStore that ranks by text works.
+//
+// Example:
+//
+//	index := retrieval.NewBM25Index()
+//	_ = index.Store(ctx, docs)
+//	flow := calque.NewFlow().
+//	    Use(retrieval.HybridSearch(store, index, &retrieval.HybridOptions{Threshold: 0.5, Limit: 5})).
+//	    Use(retrieval.Rerank(reranker, 3))
+func HybridSearch(vectorStore, keywordStore VectorStore, opts *HybridOptions) calque.Handler {
+	return calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
+		var queryText string
+		if err := calque.Read(r, &queryText); err != nil {
+			return err
+		}
+
+		result, err := hybridSearch(r.Context, vectorStore, keywordStore, queryText, opts)
+		if err != nil {
+			return err
+		}
+		recordSources(r.Context, result.Documents)
+
+		resultJSON, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		return calque.Write(w, resultJSON)
+	})
+}
+
+// hybridSearch runs both searches and fuses their rankings
+func hybridSearch(ctx context.Context, vectorStore, keywordStore VectorStore, queryText string, opts *HybridOptions) (*SearchResult, error) {
+	if vectorStore == nil || keywordStore == nil {
+		return nil, calque.NewErr(ctx, "hybrid search requires both a vector store and a keyword store")
+	}
+	if opts == nil {
+		opts = &HybridOptions{}
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	candidates := opts.CandidatesLimit
+	if candidates <= 0 {
+		candidates = int(float64(limit) * DefaultCandidatesMultiplier)
+	}
+	if hasSourcePolicy(ctx, opts.SourcePolicy) {
+		candidates = int(float64(candidates) * DefaultCandidatesMultiplier)
+	}
+
+	query := SearchQuery{Text: queryText, Threshold: opts.Threshold, Limit: candidates, Filter: opts.Filter}
+	if err := handleEmbeddingForQuery(ctx, vectorStore, &query, &SearchOptions{EmbeddingProvider: opts.EmbeddingProvider}); err != nil {
+		return nil, err
+	}
+	vectorResult, err := vectorStore.Search(ctx, query)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "hybrid vector search failed")
+	}
+
+	keywordResult, err := keywordStore.Search(ctx, SearchQuery{Text: queryText, Limit: candidates, Filter: opts.Filter})
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "hybrid keyword search failed")
+	}
+
+	fused := fuseRankings(opts.RRFConstant, []rankedList{
+		{documents: resultDocuments(vectorResult), weight: opts.VectorWeight},
+		{documents: resultDocuments(keywordResult), weight: opts.KeywordWeight},
+	})
+
+	result := &SearchResult{Documents: fused, Query: queryText, Total: len(fused), Threshold: opts.Threshold}
+	filterSources(ctx, result, opts.SourcePolicy, limit)
+	if len(result.Documents) > limit {
+		result.Documents = result.Documents[:limit]
+	}
+	return result, nil
+}
+
+// ReciprocalRankFusion merges ranked document lists into a single ranking.
+//
+// Each document scores the sum of 1/(k+rank) over the lists it appears in,
+// with documents matched by ID and ranks starting at 1. A k of 0 or less uses
+// DefaultRRFConstant. Documents keep the fields from their first appearance
+// and get the fused score.
+//
+// Example:
+//
+//	fused := retrieval.ReciprocalRankFusion(0, vectorResult.Documents, keywordResult.Documents)
+func ReciprocalRankFusion(k int, rankings ...[]Document) []Document {
+	lists := make([]rankedList, len(rankings))
+	for i, documents := range rankings {
+		lists[i] = rankedList{documents: documents}
+	}
+	return fuseRankings(k, lists)
+}
+
+// rankedList is one ranking with its fusion weight (0 means 1)
+type rankedList struct {
+	documents []Document
+	weight    float64
+}
+
+// fuseRankings applies weighted reciprocal rank fusion
+func fuseRankings(k int, lists []rankedList) []Document {
+	if k <= 0 {
+		k = DefaultRRFConstant
+	}
+
+	scores := make(map[string]float64)
+	docs := make(map[string]Document)
+	var order []string // first-seen order keeps ties deterministic
+	for _, list := range lists {
+		weight := list.weight
+		if weight <= 0 {
+			weight = 1
+		}
+		seen := make(map[string]bool, len(list.documents))
+		for rank, doc := range list.documents {
+			if seen[doc.ID] {
+				continue // count each document once per list, at its best rank
+			}
+			seen[doc.ID] = true
+			if _, ok := docs[doc.ID]; !ok {
+				docs[doc.ID] = doc
+				order = append(order, doc.ID)
+			}
+			scores[doc.ID] += weight / float64(k+rank+1)
+		}
+	}
+
+	fused := make([]Document, len(order))
+	for i, id := range order {
+		doc := docs[id]
+		doc.Score = scores[id]
+		fused[i] = doc
+	}
+	sort.SliceStable(fused, func(i, j int) bool {
+		return fused[i].Score > fused[j].Score
+	})
+	return fused
+}
+
+// resultDocuments returns the documents of a possibly nil result
+func resultDocuments(result *SearchResult) []Document {
+	if result == nil {
+		return nil
+	}
+	return result.Documents
+}
@@ -0,0 +1,91 @@
+package retrieval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func docIDs(documents []Document) string {
+	ids := make([]string, len(documents))
+	for i, doc := range documents {
+		ids[i] = doc.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestReciprocalRankFusion(t *testing.T) {
+	vector := []Document{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	keyword := []Document{{ID: "c"}, {ID: "d"}, {ID: "a"}}
+
+	fused := ReciprocalRankFusion(0, vector, keyword)
+	if got := docIDs(fused); got != "a,c,b,d" {
+		t.Errorf("fused order = %s, want a,c,b,d", got)
+	}
+	if want := 1.0/61 + 1.0/63; fused[0].Score != want {
+		t.Errorf("a score = %v, want %v", fused[0].Score, want)
+	}
+
+	if got := docIDs(ReciprocalRankFusion(1, []Document{{ID: "x"}, {ID: "x"}, {ID: "y"}})); got != "x,y" {
+		t.Errorf("duplicates = %s, want x,y", got)
+	}
+	if len(ReciprocalRankFusion(60)) != 0 {
+		t.Error("expected no documents without rankings")
+	}
+}
+
+func TestHybridSearch(t *testing.T) {
+	keyword := newTestBM25Index(t)
+	vector := &mockVectorStore{searchResult: &SearchResult{Documents: []Document{
+		{ID: "2", Content: "Tuning the proxy for long-lived connections", Score: 0.91},
+		{ID: "5", Content: "Why sockets close unexpectedly", Score: 0.88},
+	}}}
+
+	tests := []struct {
+		name     string
+		vector   VectorStore
+		opts     *HybridOptions
+		query    string
+		expected string
+		wantErr  string
+	}{
+		{name: "fuses both rankings", vector: vector, opts: &HybridOptions{}, query: "proxy connection reset", expected: "2,1,5,4"},
+		{name: "limit", vector: vector, opts: &HybridOptions{Limit: 2}, query: "proxy connection reset", expected: "2,1"},
+		{name: "keyword weight favors exact matches", vector: vector, opts: &HybridOptions{KeywordWeight: 3}, query: "err_conn_reset", expected: "1,2,5"},
+		{name: "nil options", vector: vector, query: "release", expected: "2,3,5"},
+		{name: "vector error", vector: &mockVectorStore{searchErr: errors.New("down")}, opts: &HybridOptions{}, query: "proxy", wantErr: "hybrid vector search failed"},
+		{name: "missing store", opts: &HybridOptions{}, query: "proxy", wantErr: "requires both a vector store and a keyword store"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			req := calque.NewRequest(context.Background(), strings.NewReader(tt.query))
+			err := HybridSearch(tt.vector, keyword, tt.opts).ServeFlow(req, calque.NewResponse(&buf))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var result SearchResult
+			if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+			if got := docIDs(result.Documents); got != tt.expected {
+				t.Errorf("results = %s, want %s", got, tt.expected)
+			}
+			if result.Query != tt.query {
+				t.Errorf("query = %q", result.Query)
+			}
+		})
+	}
+}
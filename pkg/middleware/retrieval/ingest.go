@@ -0,0 +1,124 @@
+package retrieval
+
+import (
+	"encoding/json"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// StoreResult reports what StoreDocuments wrote to the vector store.
+type StoreResult struct {
+	Stored int      `json:"stored"` // Number of documents stored
+	IDs    []string `json:"ids"`    // IDs of the stored documents
+}
+
+// EmbedDocuments creates a middleware that computes document embeddings.
+//
+// Input: []Document JSON array
+// Output: []Document JSON array with Vector set on every document
+// Behavior: BUFFERED - reads entire document array for embedding
+//
+// Documents that already have a Vector are passed through unchanged, and
+// documents with empty content are dropped. Use it in front of StoreDocuments
+// for stores that expect pre-computed vectors.
+//
+// For bulk ingestion, EmbedOptions runs Workers calls in parallel, paced to
+// the provider's requests- and tokens-per-minute limits - configured, or
+// reported through RateLimitTransport - and retries rate-limited calls after
+// the wait the provider asks for. With a Checkpoint, a run that fails part way
+// resumes from the embeddings already made.
+//
+// Example:
+//
+//	flow := calque.NewFlow().
+//	    Use(retrieval.Chunk(retrieval.TextChunker())).
+//	    Use(retrieval.EmbedDocuments(provider, retrieval.EmbedOptions{
+//	        Workers:           8,
+//	        RequestsPerMinute: 3000,
+//	        TokensPerMinute:   1_000_000,
+//	        Checkpoint:        retrieval.NewFileEmbedCheckpoint("./ingest.embeddings.jsonl"),
+//	    })).
+//	    Use(retrieval.StoreDocuments(store))
+func EmbedDocuments(provider EmbeddingProvider, opts ...EmbedOptions) calque.Handler {
+	var cfg EmbedOptions
+	if len(opts) > 0 {
+		cfg = opts[0]
+	}
+
+	return calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
+		if provider == nil {
+			return calque.NewErr(r.Context, "embedding provider cannot be nil")
+		}
+
+		documents, err := readDocuments(r)
+		if err != nil {
+			return err
+		}
+
+		embedded := make([]Document, 0, len(documents))
+		for _, doc := range documents {
+			if doc.Content != "" {
+				embedded = append(embedded, doc)
+			}
+		}
+		if err := embedAll(r.Context, provider, embedded, cfg); err != nil {
+			return err
+		}
+
+		result, err := json.Marshal(embedded)
+		if err != nil {
+			return err
+		}
+		return calque.Write(w, result)
+	})
+}
+
+// StoreDocuments creates a middleware that writes documents to a vector store.
+//
+// Input: []Document JSON array
+// Output: StoreResult JSON
+// Behavior: BUFFERED - reads entire document array before storing
+//
+// Example:
+//
+//	flow := calque.NewFlow().
+//	    Use(retrieval.DocumentLoader("./docs/*.md")).
+//	    Use(retrieval.StoreDocuments(store))
+func StoreDocuments(store VectorStore) calque.Handler {
+	return calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
+		if store == nil {
+			return calque.NewErr(r.Context, "vector store cannot be nil")
+		}
+
+		documents, err := readDocuments(r)
+		if err != nil {
+			return err
+		}
+		if err := store.Store(r.Context, documents); err != nil {
+			return calque.WrapErr(r.Context, err, "failed to store documents")
+		}
+
+		ids := make([]string, len(documents))
+		for i, doc := range documents {
+			ids[i] = doc.ID
+		}
+		result, err := json.Marshal(StoreResult{Stored: len(documents), IDs: ids})
+		if err != nil {
+			return err
+		}
+		return calque.Write(w, result)
+	})
+}
+
+// readDocuments reads a []Document JSON array from the request
+func readDocuments(r *calque.Request) ([]Document, error) {
+	var input []byte
+	if err := calque.Read(r, &input); err != nil {
+		return nil, err
+	}
+	var documents []Document
+	if err := json.Unmarshal(input, &documents); err != nil {
+		return nil, calque.WrapErr(r.Context, err, "failed to parse documents")
+	}
+	return documents, nil
+}
@@ -0,0 +1,71 @@
+package retrieval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func serveDocuments(t *testing.T, handler calque.Handler, documents []Document) (string, error) {
+	t.Helper()
+	input, _ := json.Marshal(documents)
+	var buf bytes.Buffer
+	err := handler.ServeFlow(calque.NewRequest(context.Background(), bytes.NewReader(input)), calque.NewResponse(&buf))
+	return buf.String(), err
+}
+
+func TestEmbedDocuments(t *testing.T) {
+	out, err := serveDocuments(t, EmbedDocuments(newMockEmbeddingProvider(4)), []Document{
+		{ID: "a", Content: "alpha"},
+		{ID: "b", Content: "beta", Vector: EmbeddingVector{9, 9, 9, 9}},
+		{ID: "empty"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var documents []Document
+	if err := json.Unmarshal([]byte(out), &documents); err != nil {
+		t.Fatal(err)
+	}
+	if len(documents) != 2 {
+		t.Fatalf("documents = %+v, want empty document dropped", documents)
+	}
+	if len(documents[0].Vector) != 4 || documents[1].Vector[0] != 9 {
+		t.Errorf("vectors = %v, %v", documents[0].Vector, documents[1].Vector)
+	}
+
+	if _, err := serveDocuments(t, EmbedDocuments(nil), nil); err == nil {
+		t.Error("expected error for nil provider")
+	}
+}
+
+func TestStoreDocuments(t *testing.T) {
+	store := &mockVectorStore{}
+	out, err := serveDocuments(t, StoreDocuments(store), []Document{{ID: "a", Content: "x"}, {ID: "b", Content: "y"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !store.storeCalled || out != `{"stored":2,"ids":["a","b"]}` {
+		t.Errorf("output = %s, store called = %v", out, store.storeCalled)
+	}
+
+	failing := &failingStore{err: errors.New("disk full")}
+	if _, err := serveDocuments(t, StoreDocuments(failing), []Document{{ID: "a"}}); err == nil ||
+		!strings.Contains(err.Error(), "failed to store documents") {
+		t.Errorf("store error = %v", err)
+	}
+}
+
+type failingStore struct {
+	mockVectorStore
+	err error
+}
+
+func (f *failingStore) Store(_ context.Context, _ []Document) error {
+	return f.err
+}
@@ -9,6 +9,10 @@ type SearchOptions struct {
 	Filter            map[string]any    `json:"filter,omitempty"` // Metadata filters
 	EmbeddingProvider EmbeddingProvider `json:"-"`                // Custom embedding provider
 
+	// SourcePolicy drops results from disallowed sources before they are
+	// returned or built into context (see also WithSourcePolicy)
+	SourcePolicy *SourcePolicy `json:"-"`
+
 	// Advanced search options - Strategy Processing Control
 	StrategyProcessing StrategyProcessingMode `json:"strategy_processing,omitempty"` // How to apply strategies (default: StrategyAuto)
 
@@ -28,6 +32,10 @@ type SearchOptions struct {
 	MaxTokens int              `json:"max_tokens,omitempty"` // Token limit for context
 	Separator string           `json:"separator,omitempty"`  // Document separator in context
 
+	// NumberSources prefixes each context document with its citation marker ([1], [2], ...)
+	// so the model can cite sources for retrieval.Citations
+	NumberSources bool `json:"number_sources,omitempty"`
+
 	// Summary strategy options
 	SummaryWordLimit *int `json:"summary_word_limit,omitempty"` // Word limit per document for StrategySummary (default: 500)
 
@@ -198,11 +198,6 @@ func (c *Client) Store(ctx context.Context, documents []retrieval.Document) erro
 		return err
 	}
 
-	// Check embedding provider is configured
-	if c.embeddingProvider == nil {
-		return calque.NewErr(ctx, "no embedding provider configured - cannot generate vectors for document storage")
-	}
-
 	// Use batch for efficient bulk inserts
 	batch := &pgx.Batch{}
 
@@ -211,10 +206,17 @@ func (c *Client) Store(ctx context.Context, documents []retrieval.Document) erro
 			continue // Skip documents without content
 		}
 
-		// Generate embedding for document content
-		embedding, err := c.embeddingProvider.Embed(ctx, doc.Content)
-		if err != nil {
-			return calque.WrapErr(ctx, err, fmt.Sprintf("failed to generate embedding for document %s", doc.ID))
+		// Use the pre-computed vector if present, otherwise embed with the configured provider
+		embedding := doc.Vector
+		var err error
+		if len(embedding) == 0 {
+			if c.embeddingProvider == nil {
+				return calque.NewErr(ctx, "no embedding provider configured - cannot generate vectors for document storage")
+			}
+			embedding, err = c.embeddingProvider.Embed(ctx, doc.Content)
+			if err != nil {
+				return calque.WrapErr(ctx, err, fmt.Sprintf("failed to generate embedding for document %s", doc.ID))
+			}
 		}
 
 		// Marshal metadata to JSONB
@@ -449,9 +449,11 @@ func (c *Client) storeBatch(ctx context.Context, documents []retrieval.Document)
 		// Create point ID - use document ID if available, otherwise generate UUID
 		pointID := c.createPointID(doc.ID)
 
-		// Generate embedding for document content using configured provider
+		// Use the pre-computed vector if present, otherwise embed with the configured provider
 		var vectorData []float32
-		if c.embeddingProvider != nil {
+		if len(doc.Vector) > 0 {
+			vectorData = []float32(doc.Vector)
+		} else if c.embeddingProvider != nil {
 			// Use the configured embedding provider to generate vector
 			embedding, err := c.embeddingProvider.Embed(ctx, doc.Content)
 			if err != nil {
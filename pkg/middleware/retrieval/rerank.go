@@ -0,0 +1,185 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/convert"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+)
+
+// RerankScore is the relevance score a reranker assigns to one input document.
+type RerankScore struct {
+	Index int     `json:"index"` // Position of the document in the reranker input
+	Score float64 `json:"score"` // Relevance score (higher is more relevant)
+}
+
+// Reranker scores documents against a query.
+//
+// Implementations return scores ordered by relevance, highest first, and may
+// return fewer than len(documents) scores. Indexes refer to the documents slice.
+//
+// Example:
+//
+//	reranker, err := retrieval.NewCohereReranker(retrieval.CohereRerankConfig{})
+//	scores, err := reranker.Rerank(ctx, "vector databases", docs, 3)
+type Reranker interface {
+	Rerank(ctx context.Context, query string, documents []string, topN int) ([]RerankScore, error)
+}
+
+// Rerank creates a reranking middleware for retrieval results.
+//
+// Input: SearchResult JSON (e.g. from VectorSearch without a Strategy)
+// Output: SearchResult JSON with the topN most relevant documents, best first
+// Behavior: BUFFERED - reads entire search result for scoring
+//
+// Vector similarity is a coarse first pass; a cross-encoder or LLM reranker
+// scores each document against the query and gives better precision. Fetch
+// more candidates than you need and let Rerank keep the best topN. Document
+// scores are replaced by reranker scores. A topN of 0 keeps all documents.
+//
+// Example:
+//
+//	reranker, err := retrieval.NewCohereReranker(retrieval.CohereRerankConfig{})
+//	flow := calque.NewFlow().
+//	    Use(retrieval.VectorSearch(store, &retrieval.SearchOptions{Limit: 20})).
+//	    Use(retrieval.Rerank(reranker, 5))
+func Rerank(reranker Reranker, topN int) calque.Handler {
+	return calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
+		var input []byte
+		if err := calque.Read(r, &input); err != nil {
+			return err
+		}
+
+		var result SearchResult
+		if err := json.Unmarshal(input, &result); err != nil {
+			return calque.WrapErr(r.Context, err, "failed to parse search result for reranking")
+		}
+
+		documents, err := rerankDocuments(r.Context, reranker, result.Query, result.Documents, topN)
+		if err != nil {
+			return err
+		}
+		result.Documents = documents
+		recordSources(r.Context, documents)
+
+		output, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		return calque.Write(w, output)
+	})
+}
+
+// rerankDocuments orders documents by reranker score and keeps the best topN
+func rerankDocuments(ctx context.Context, reranker Reranker, query string, documents []Document, topN int) ([]Document, error) {
+	if reranker == nil {
+		return nil, calque.NewErr(ctx, "reranker cannot be nil")
+	}
+	if len(documents) == 0 {
+		return documents, nil
+	}
+	if topN <= 0 || topN > len(documents) {
+		topN = len(documents)
+	}
+
+	contents := make([]string, len(documents))
+	for i, doc := range documents {
+		contents[i] = doc.Content
+	}
+	scores, err := reranker.Rerank(ctx, query, contents, topN)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to rerank documents")
+	}
+
+	slices.SortStableFunc(scores, func(a, b RerankScore) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	reranked := make([]Document, 0, topN)
+	seen := make(map[int]bool, len(scores))
+	for _, s := range scores {
+		if len(reranked) == topN {
+			break
+		}
+		if s.Index < 0 || s.Index >= len(documents) || seen[s.Index] {
+			continue // ignore invalid or duplicate indexes from the reranker
+		}
+		seen[s.Index] = true
+		doc := documents[s.Index]
+		doc.Score = s.Score
+		reranked = append(reranked, doc)
+	}
+	return reranked, nil
+}
+
+// llmRerankResponse is the structured output requested from the LLM reranker
+type llmRerankResponse struct {
+	Scores []llmRerankScore `json:"scores" jsonschema:"required,description=Relevance score for each document"`
+}
+
+type llmRerankScore struct {
+	Index int     `json:"index" jsonschema:"required,description=Document number as shown in the prompt"`
+	Score float64 `json:"score" jsonschema:"required,minimum=0,maximum=10,description=Relevance from 0 (unrelated) to 10 (directly answers the query)"`
+}
+
+// llmReranker scores documents by asking a language model
+type llmReranker struct {
+	client   ai.Client
+	maxChars int
+}
+
+// NewLLMReranker creates a Reranker that asks an AI client to score documents.
+//
+// Every document is sent in a single prompt (truncated to 2000 characters) and
+// scored from 0 to 10, which the reranker normalizes to 0-1. It needs no
+// dedicated reranking API, but is slower and costlier than a cross-encoder.
+//
+// Example:
+//
+//	reranker := retrieval.NewLLMReranker(client)
+//	flow.Use(retrieval.Rerank(reranker, 5))
+func NewLLMReranker(client ai.Client) Reranker {
+	return &llmReranker{client: client, maxChars: 2000}
+}
+
+// Rerank implements Reranker
+func (l *llmReranker) Rerank(ctx context.Context, query string, documents []string, _ int) ([]RerankScore, error) {
+	if l.client == nil {
+		return nil, calque.NewErr(ctx, "AI client cannot be nil")
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString("Score how relevant each document is to the query on a scale from 0 to 10. ")
+	prompt.WriteString("Return a score for every document, using the document numbers shown.\n\n")
+	fmt.Fprintf(&prompt, "Query: %s\n", query)
+	for i, doc := range documents {
+		if runes := []rune(doc); len(runes) > l.maxChars {
+			doc = string(runes[:l.maxChars]) + "..."
+		}
+		fmt.Fprintf(&prompt, "\n[%d]\n%s\n", i, doc)
+	}
+
+	var response llmRerankResponse
+	flow := calque.NewFlow().Use(ai.Agent(l.client, ai.WithSchemaFor[llmRerankResponse]()))
+	if err := flow.Run(ctx, prompt.String(), convert.FromJSON(&response)); err != nil {
+		return nil, calque.WrapErr(ctx, err, "LLM reranking failed")
+	}
+
+	scores := make([]RerankScore, 0, len(response.Scores))
+	for _, s := range response.Scores {
+		scores = append(scores, RerankScore{Index: s.Index, Score: min(max(s.Score, 0), 10) / 10})
+	}
+	return scores, nil
+}
@@ -0,0 +1,219 @@
+package retrieval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+)
+
+// fakeReranker scores documents by a fixed content-to-score table
+type fakeReranker struct {
+	scores map[string]float64
+	extra  []RerankScore
+	err    error
+	topN   int
+}
+
+func (f *fakeReranker) Rerank(_ context.Context, _ string, documents []string, topN int) ([]RerankScore, error) {
+	f.topN = topN
+	if f.err != nil {
+		return nil, f.err
+	}
+	scores := append([]RerankScore{}, f.extra...)
+	for i, doc := range documents {
+		scores = append(scores, RerankScore{Index: i, Score: f.scores[doc]})
+	}
+	return scores, nil
+}
+
+func runRerank(t *testing.T, handler calque.Handler, result SearchResult) (SearchResult, error) {
+	t.Helper()
+	input, _ := json.Marshal(result)
+	var buf bytes.Buffer
+	err := handler.ServeFlow(calque.NewRequest(context.Background(), bytes.NewReader(input)), calque.NewResponse(&buf))
+	var out SearchResult
+	if err == nil {
+		if jsonErr := json.Unmarshal(buf.Bytes(), &out); jsonErr != nil {
+			t.Fatalf("invalid output %q: %v", buf.String(), jsonErr)
+		}
+	}
+	return out, err
+}
+
+func TestRerank(t *testing.T) {
+	docs := []Document{
+		{ID: "a", Content: "alpha", Score: 0.9},
+		{ID: "b", Content: "beta", Score: 0.8},
+		{ID: "c", Content: "gamma", Score: 0.7},
+	}
+	scores := map[string]float64{"alpha": 0.1, "beta": 0.9, "gamma": 0.5}
+
+	tests := []struct {
+		name     string
+		reranker *fakeReranker
+		topN     int
+		docs     []Document
+		expected []string
+		wantErr  string
+	}{
+		{name: "reorders and truncates", reranker: &fakeReranker{scores: scores}, topN: 2, docs: docs, expected: []string{"b", "c"}},
+		{name: "zero keeps all", reranker: &fakeReranker{scores: scores}, topN: 0, docs: docs, expected: []string{"b", "c", "a"}},
+		{name: "topN larger than input", reranker: &fakeReranker{scores: scores}, topN: 10, docs: docs, expected: []string{"b", "c", "a"}},
+		{
+			name:     "ignores invalid and duplicate indexes",
+			reranker: &fakeReranker{scores: scores, extra: []RerankScore{{Index: 7, Score: 1}, {Index: 0, Score: 0.95}}},
+			topN:     2, docs: docs, expected: []string{"a", "b"},
+		},
+		{name: "no documents", reranker: &fakeReranker{}, topN: 3, docs: []Document{}, expected: []string{}},
+		{name: "reranker error", reranker: &fakeReranker{err: errors.New("boom")}, topN: 2, docs: docs, wantErr: "failed to rerank documents"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := runRerank(t, Rerank(tt.reranker, tt.topN), SearchResult{Query: "q", Documents: tt.docs, Total: len(tt.docs)})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]string, len(out.Documents))
+			for i, doc := range out.Documents {
+				ids[i] = doc.ID
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("documents = %v, want %v", ids, tt.expected)
+			}
+			if out.Query != "q" || out.Total != len(tt.docs) {
+				t.Errorf("result metadata = %+v", out)
+			}
+		})
+	}
+}
+
+func TestRerankReplacesScores(t *testing.T) {
+	reranker := &fakeReranker{scores: map[string]float64{"alpha": 0.42}}
+	out, err := runRerank(t, Rerank(reranker, 1), SearchResult{Query: "q", Documents: []Document{{ID: "a", Content: "alpha", Score: 0.9}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Documents[0].Score != 0.42 || reranker.topN != 1 {
+		t.Errorf("score = %v, topN = %d", out.Documents[0].Score, reranker.topN)
+	}
+}
+
+func TestRerankInvalidInput(t *testing.T) {
+	var buf bytes.Buffer
+	err := Rerank(&fakeReranker{}, 1).ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("not json")), calque.NewResponse(&buf))
+	if err == nil || !strings.Contains(err.Error(), "failed to parse search result") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestCrossEncoderRerankers(t *testing.T) {
+	tests := []struct {
+		name      string
+		reranker  func(url string) (Reranker, error)
+		topNField string
+		response  string
+	}{
+		{
+			name: "cohere",
+			reranker: func(url string) (Reranker, error) {
+				return NewCohereReranker(CohereRerankConfig{APIKey: "key", BaseURL: url})
+			},
+			topNField: "top_n",
+			response:  `{"results":[{"index":1,"relevance_score":0.97},{"index":0,"relevance_score":0.12}]}`,
+		},
+		{
+			name: "voyage",
+			reranker: func(url string) (Reranker, error) {
+				return NewVoyageReranker(VoyageRerankConfig{APIKey: "key", BaseURL: url})
+			},
+			topNField: "top_k",
+			response:  `{"data":[{"index":1,"relevance_score":0.97},{"index":0,"relevance_score":0.12}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var auth string
+			var body map[string]any
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth = r.Header.Get("Authorization")
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer srv.Close()
+
+			reranker, err := tt.reranker(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			scores, err := reranker.Rerank(context.Background(), "query", []string{"one", "two"}, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(scores) != 2 || scores[0] != (RerankScore{Index: 1, Score: 0.97}) {
+				t.Errorf("scores = %+v", scores)
+			}
+			if auth != "Bearer key" || body[tt.topNField] != float64(2) || body["query"] != "query" || body["model"] == "" {
+				t.Errorf("request auth = %q body = %v", auth, body)
+			}
+		})
+	}
+}
+
+func TestCrossEncoderErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "invalid api key", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	reranker, err := NewCohereReranker(CohereRerankConfig{APIKey: "bad", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reranker.Rerank(context.Background(), "q", []string{"doc"}, 1); err == nil ||
+		!strings.Contains(err.Error(), "cohere rerank returned status 401: invalid api key") {
+		t.Errorf("error = %v", err)
+	}
+
+	t.Setenv("VOYAGE_API_KEY", "")
+	if _, err := NewVoyageReranker(VoyageRerankConfig{}); err == nil || !strings.Contains(err.Error(), "VOYAGE_API_KEY") {
+		t.Errorf("missing key error = %v", err)
+	}
+}
+
+func TestLLMReranker(t *testing.T) {
+	client := ai.NewMockClient(`{"scores":[{"index":0,"score":3},{"index":1,"score":9},{"index":2,"score":14}]}`).WithStreamDelay(0)
+	scores, err := NewLLMReranker(client).Rerank(context.Background(), "query", []string{"a", "b", "c"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []RerankScore{{Index: 0, Score: 0.3}, {Index: 1, Score: 0.9}, {Index: 2, Score: 1}}
+	if len(scores) != len(expected) {
+		t.Fatalf("scores = %+v", scores)
+	}
+	for i := range expected {
+		if scores[i] != expected[i] {
+			t.Errorf("scores[%d] = %+v, want %+v", i, scores[i], expected[i])
+		}
+	}
+
+	if _, err := NewLLMReranker(ai.NewMockClientWithError("model down")).Rerank(context.Background(), "q", []string{"a"}, 1); err == nil ||
+		!strings.Contains(err.Error(), "LLM reranking failed") {
+		t.Errorf("error = %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Document metadata keys set by TagSources and read by SourcePolicy
+const (
+	MetadataLicense     = "license"      // license of the source, e.g. "cc-by-4.0" or "proprietary"
+	MetadataSourceClass = "source_class" // class of the source, e.g. "internal", "public" or "partner"
+)
+
+// SourceRule tags the documents whose source matches Source.
+type SourceRule struct {
+	// Source is matched against the document's "source" metadata, or its ID
+	// when that is missing; "*" matches any run of characters,
+	// e.g. "./kb/legal/*" or "https://wiki.corp/*"
+	Source  string
+	License string // license to record (empty = leave unset)
+	Class   string // source class to record (empty = leave unset)
+}
+
+// SourcePolicy restricts which sources can ground an answer.
+//
+// A document's license and class are read from its MetadataLicense and
+// MetadataSourceClass metadata and compared case-insensitively. Deny lists
+// always apply; when an allow list is set, documents missing that tag are
+// refused unless AllowUntagged is true.
+type SourcePolicy struct {
+	AllowLicenses []string // licenses allowed (nil = any)
+	DenyLicenses  []string // licenses refused
+	AllowClasses  []string // source classes allowed (nil = any)
+	DenyClasses   []string // source classes refused
+	AllowUntagged bool     // let untagged documents through the allow lists
+}
+
+// Allows reports whether doc satisfies the policy.
+func (p *SourcePolicy) Allows(doc Document) bool {
+	license, _ := doc.Metadata[MetadataLicense].(string)
+	class, _ := doc.Metadata[MetadataSourceClass].(string)
+	return p.allowsTag(license, p.AllowLicenses, p.DenyLicenses) &&
+		p.allowsTag(class, p.AllowClasses, p.DenyClasses)
+}
+
+func (p *SourcePolicy) allowsTag(tag string, allow, deny []string) bool {
+	if tag == "" {
+		return allow == nil || p.AllowUntagged
+	}
+	if containsFold(deny, tag) {
+		return false
+	}
+	return allow == nil || containsFold(allow, tag)
+}
+
+type sourcePolicyContextKey struct{}
+
+// WithSourcePolicy returns a context whose retrievals only return documents
+// allowed by p, in addition to any policy already in ctx or in the search
+// options. Use it to apply per-user or per-tenant entitlements to a shared flow.
+//
+// Example:
+//
+//	ctx = retrieval.WithSourcePolicy(ctx, retrieval.SourcePolicy{AllowClasses: user.Corpora})
+//	err := queryFlow.Run(ctx, question, &answer)
+func WithSourcePolicy(ctx context.Context, p SourcePolicy) context.Context {
+	policies := append(sourcePolicies(ctx), &p)
+	return context.WithValue(ctx, sourcePolicyContextKey{}, policies)
+}
+
+func sourcePolicies(ctx context.Context) []*SourcePolicy {
+	policies, _ := ctx.Value(sourcePolicyContextKey{}).([]*SourcePolicy)
+	return policies[:len(policies):len(policies)]
+}
+
+// TagSources creates a middleware that records the license and source class
+// of ingested documents.
+//
+// Input: []Document JSON array from document loader
+// Output: []Document JSON array with MetadataLicense and MetadataSourceClass set
+// Behavior: BUFFERED - reads entire document array for tagging
+//
+// Each document takes the tags of the first rule matching its source. Tags
+// already in the metadata are kept, so loaders can set them directly. Chunks
+// copy their document's metadata, so tag before chunking.
+//
+// Example:
+//
+//	flow := calque.NewFlow().
+//	    Use(retrieval.DocumentLoader("./kb/**/*.md")).
+//	    Use(retrieval.TagSources(
+//	        retrieval.SourceRule{Source: "kb/vendor/*", License: "vendor-restricted", Class: "partner"},
+//	        retrieval.SourceRule{Source: "*", License: "proprietary", Class: "internal"},
+//	    )).
+//	    Use(retrieval.Chunk(retrieval.TextChunker()))
+func TagSources(rules ...SourceRule) calque.Handler {
+	patterns := make([]*regexp.Regexp, len(rules))
+	for i, rule := range rules {
+		patterns[i] = globPattern(rule.Source)
+	}
+
+	return calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
+		var input []byte
+		if err := calque.Read(r, &input); err != nil {
+			return err
+		}
+
+		var documents []Document
+		if err := json.Unmarshal(input, &documents); err != nil {
+			return calque.InvalidInput(calque.WrapErr(r.Context, err, "failed to parse documents"))
+		}
+
+		for i := range documents {
+			source, _ := documents[i].Metadata["source"].(string)
+			if source == "" {
+				source = documents[i].ID
+			}
+			for j, rule := range rules {
+				if patterns[j].MatchString(source) {
+					tagDocument(&documents[i], rule)
+					break
+				}
+			}
+		}
+
+		result, err := json.Marshal(documents)
+		if err != nil {
+			return err
+		}
+		return calque.Write(w, result)
+	})
+}
+
+// tagDocument sets the tags of rule that doc does not already have
+func tagDocument(doc *Document, rule SourceRule) {
+	if doc.Metadata == nil {
+		doc.Metadata = make(map[string]any, 2)
+	}
+	for key, value := range map[string]string{MetadataLicense: rule.License, MetadataSourceClass: rule.Class} {
+		if value == "" {
+			continue
+		}
+		if existing, _ := doc.Metadata[key].(string); existing == "" {
+			doc.Metadata[key] = value
+		}
+	}
+}
+
+// globPattern compiles a glob where "*" matches any run of characters, "/" included
+func globPattern(glob string) *regexp.Regexp {
+	quoted := strings.ReplaceAll(regexp.QuoteMeta(glob), `\*`, ".*")
+	return regexp.MustCompile("^" + quoted + "$")
+}
+
+// filterSources drops the documents of result refused by policy or by the
+// policies in ctx, keeping at most limit (0 = all)
+func filterSources(ctx context.Context, result *SearchResult, policy *SourcePolicy, limit int) {
+	policies := sourcePolicies(ctx)
+	if policy != nil {
+		policies = append(policies, policy)
+	}
+	if result == nil || len(policies) == 0 {
+		return
+	}
+
+	allowed := make([]Document, 0, len(result.Documents))
+	for _, doc := range result.Documents {
+		if allowedByAll(policies, doc) {
+			allowed = append(allowed, doc)
+		}
+	}
+	// Total counts allowed matches only, so refused corpora are not revealed
+	result.Total = len(allowed)
+	if limit > 0 && len(allowed) > limit {
+		allowed = allowed[:limit]
+	}
+	result.Documents = allowed
+}
+
+func allowedByAll(policies []*SourcePolicy, doc Document) bool {
+	for _, p := range policies {
+		if !p.Allows(doc) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasSourcePolicy reports whether search results will be filtered, so
+// searches fetch extra candidates to make up for refused documents
+func hasSourcePolicy(ctx context.Context, policy *SourcePolicy) bool {
+	return policy != nil || len(sourcePolicies(ctx)) > 0
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func taggedDoc(id, license, class string) Document {
+	metadata := map[string]any{}
+	if license != "" {
+		metadata[MetadataLicense] = license
+	}
+	if class != "" {
+		metadata[MetadataSourceClass] = class
+	}
+	return Document{ID: id, Content: "doc " + id, Metadata: metadata}
+}
+
+func TestSourcePolicyAllows(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  SourcePolicy
+		doc     Document
+		allowed bool
+	}{
+		{name: "empty policy", doc: taggedDoc("1", "", ""), allowed: true},
+		{name: "allowed license", policy: SourcePolicy{AllowLicenses: []string{"cc-by-4.0"}}, doc: taggedDoc("1", "CC-BY-4.0", ""), allowed: true},
+		{name: "license not allowed", policy: SourcePolicy{AllowLicenses: []string{"cc-by-4.0"}}, doc: taggedDoc("1", "proprietary", ""), allowed: false},
+		{name: "untagged refused by allow list", policy: SourcePolicy{AllowLicenses: []string{"cc-by-4.0"}}, doc: taggedDoc("1", "", ""), allowed: false},
+		{name: "untagged allowed", policy: SourcePolicy{AllowLicenses: []string{"cc-by-4.0"}, AllowUntagged: true}, doc: taggedDoc("1", "", ""), allowed: true},
+		{name: "denied class", policy: SourcePolicy{DenyClasses: []string{"partner"}}, doc: taggedDoc("1", "", "partner"), allowed: false},
+		{name: "deny wins over allow", policy: SourcePolicy{AllowClasses: []string{"internal"}, DenyClasses: []string{"internal"}}, doc: taggedDoc("1", "", "internal"), allowed: false},
+		{name: "both tags checked", policy: SourcePolicy{AllowClasses: []string{"internal"}, DenyLicenses: []string{"gpl-3.0"}}, doc: taggedDoc("1", "gpl-3.0", "internal"), allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Allows(tt.doc); got != tt.allowed {
+				t.Errorf("Allows() = %v, want %v", got, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestTagSources(t *testing.T) {
+	documents := []Document{
+		{ID: "a", Metadata: map[string]any{"source": "kb/vendor/acme.md"}},
+		{ID: "b", Metadata: map[string]any{"source": "kb/handbook/leave.md"}},
+		{ID: "c", Metadata: map[string]any{"source": "kb/vendor/x.md", MetadataLicense: "cc0"}},
+		{ID: "https://wiki.corp/page"},
+	}
+	input, _ := json.Marshal(documents)
+
+	handler := TagSources(
+		SourceRule{Source: "kb/vendor/*", License: "vendor-restricted", Class: "partner"},
+		SourceRule{Source: "https://wiki.corp/*", Class: "internal"},
+		SourceRule{Source: "kb/*", License: "proprietary", Class: "internal"},
+	)
+	var out strings.Builder
+	if err := handler.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader(string(input))), calque.NewResponse(&out)); err != nil {
+		t.Fatal(err)
+	}
+
+	var tagged []Document
+	if err := json.Unmarshal([]byte(out.String()), &tagged); err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]string{
+		{"vendor-restricted", "partner"},
+		{"proprietary", "internal"},
+		{"cc0", "partner"},
+		{"", "internal"},
+	}
+	for i, doc := range tagged {
+		license, _ := doc.Metadata[MetadataLicense].(string)
+		class, _ := doc.Metadata[MetadataSourceClass].(string)
+		if license != want[i][0] || class != want[i][1] {
+			t.Errorf("%s tags = %q/%q, want %q/%q", doc.ID, license, class, want[i][0], want[i][1])
+		}
+	}
+}
+
+func TestVectorSearchSourcePolicy(t *testing.T) {
+	results := func() *mockVectorStore {
+		return &mockVectorStore{searchResult: &SearchResult{Documents: []Document{
+			taggedDoc("1", "proprietary", "internal"),
+			taggedDoc("2", "vendor-restricted", "partner"),
+			taggedDoc("3", "cc-by-4.0", "public"),
+			taggedDoc("4", "proprietary", "internal"),
+		}, Total: 4}}
+	}
+	search := func(ctx context.Context, opts *SearchOptions) *SearchResult {
+		t.Helper()
+		var out strings.Builder
+		if err := VectorSearch(results(), opts).ServeFlow(calque.NewRequest(ctx, strings.NewReader("query")), calque.NewResponse(&out)); err != nil {
+			t.Fatal(err)
+		}
+		var result SearchResult
+		if err := json.Unmarshal([]byte(out.String()), &result); err != nil {
+			t.Fatal(err)
+		}
+		return &result
+	}
+
+	t.Run("options policy", func(t *testing.T) {
+		result := search(context.Background(), &SearchOptions{SourcePolicy: &SourcePolicy{DenyClasses: []string{"partner"}}})
+		if got := docIDs(result.Documents); got != "1,3,4" {
+			t.Errorf("documents = %s, want 1,3,4", got)
+		}
+		if result.Total != 3 {
+			t.Errorf("Total = %d, want 3", result.Total)
+		}
+	})
+
+	t.Run("limit applies after filtering", func(t *testing.T) {
+		result := search(context.Background(), &SearchOptions{Limit: 2, SourcePolicy: &SourcePolicy{AllowClasses: []string{"internal"}}})
+		if got := docIDs(result.Documents); got != "1,4" {
+			t.Errorf("documents = %s, want 1,4", got)
+		}
+	})
+
+	t.Run("context policy combines with options", func(t *testing.T) {
+		ctx := WithSourcePolicy(context.Background(), SourcePolicy{DenyLicenses: []string{"proprietary"}})
+		result := search(ctx, &SearchOptions{SourcePolicy: &SourcePolicy{DenyClasses: []string{"partner"}}})
+		if got := docIDs(result.Documents); got != "3" {
+			t.Errorf("documents = %s, want 3", got)
+		}
+	})
+
+	t.Run("built context", func(t *testing.T) {
+		strategy := StrategyRelevant
+		ctx := WithSourcePolicy(context.Background(), SourcePolicy{AllowClasses: []string{"public"}})
+		var out strings.Builder
+		err := VectorSearch(results(), &SearchOptions{Strategy: &strategy}).
+			ServeFlow(calque.NewRequest(ctx, strings.NewReader("query")), calque.NewResponse(&out))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(out.String(), "doc 1") || !strings.Contains(out.String(), "doc 3") {
+			t.Errorf("context = %q, want only doc 3", out.String())
+		}
+	})
+}
+
+func TestHybridSearchSourcePolicy(t *testing.T) {
+	vector := &mockVectorStore{searchResult: &SearchResult{Documents: []Document{
+		taggedDoc("1", "", "internal"),
+		taggedDoc("2", "", "partner"),
+	}}}
+	keyword := &mockVectorStore{searchResult: &SearchResult{Documents: []Document{
+		taggedDoc("2", "", "partner"),
+		taggedDoc("3", "", "internal"),
+	}}}
+
+	result, err := hybridSearch(context.Background(), vector, keyword, "query", &HybridOptions{
+		Limit:        1,
+		SourcePolicy: &SourcePolicy{DenyClasses: []string{"partner"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := docIDs(result.Documents); got != "1" {
+		t.Errorf("documents = %s, want 1", got)
+	}
+	if result.Total != 2 {
+		t.Errorf("Total = %d, want 2", result.Total)
+	}
+}
+
+func TestWithSourcePolicyNesting(t *testing.T) {
+	outer := WithSourcePolicy(context.Background(), SourcePolicy{DenyClasses: []string{"partner"}})
+	first := WithSourcePolicy(outer, SourcePolicy{DenyLicenses: []string{"a"}})
+	second := WithSourcePolicy(outer, SourcePolicy{DenyLicenses: []string{"b"}})
+
+	if len(sourcePolicies(outer)) != 1 || len(sourcePolicies(first)) != 2 || len(sourcePolicies(second)) != 2 {
+		t.Fatal("unexpected policy counts")
+	}
+	if sourcePolicies(first)[1].DenyLicenses[0] != "a" {
+		t.Error("sibling context overwrote the inner policy")
+	}
+}
@@ -0,0 +1,291 @@
+package retrieval
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// MetadataContentHash is the chunk metadata key holding the source document's content hash
+const MetadataContentHash = "content_hash"
+
+// ManifestEntry records how a source document was last ingested.
+type ManifestEntry struct {
+	Hash     string   `json:"hash"`      // SHA-256 of the document content
+	ChunkIDs []string `json:"chunk_ids"` // IDs of the chunks stored for the document
+}
+
+// Manifest persists the content hashes of ingested documents between runs.
+//
+// Vector stores cannot list their contents cheaply, so SyncDocuments keeps
+// this record to decide what changed since the last ingestion.
+type Manifest interface {
+	// Load returns all entries keyed by source document ID
+	Load(ctx context.Context) (map[string]ManifestEntry, error)
+
+	// Save replaces all entries
+	Save(ctx context.Context, entries map[string]ManifestEntry) error
+}
+
+// SyncOptions configures SyncDocuments.
+type SyncOptions struct {
+	Chunker  Chunker           // Splits changed documents (default: TextChunker())
+	Embedder EmbeddingProvider // Pre-computes vectors; nil lets the store embed
+	Embed    EmbedOptions      // Parallelism, rate-limit pacing and checkpointing for Embedder
+
+	// KeepRemoved disables deleting chunks of documents that are in the
+	// manifest but missing from the input. Set it when ingesting a subset.
+	KeepRemoved bool
+}
+
+// SyncResult reports what SyncDocuments changed.
+type SyncResult struct {
+	Added     int      `json:"added"`             // New documents ingested
+	Updated   int      `json:"updated"`           // Changed documents re-ingested
+	Unchanged int      `json:"unchanged"`         // Documents skipped because their hash matched
+	Removed   int      `json:"removed"`           // Documents deleted because they left the input
+	Stored    int      `json:"stored"`            // Chunks written to the store
+	Deleted   []string `json:"deleted,omitempty"` // Chunk IDs deleted from the store
+}
+
+// SyncDocuments creates an incremental ingestion middleware.
+//
+// Input: []Document JSON array with the full current document set
+// Output: SyncResult JSON
+// Behavior: BUFFERED - reads entire document array to compare with the manifest
+//
+// Each document's content is hashed and compared with the manifest. Unchanged
+// documents are skipped without chunking or embedding. New and changed
+// documents are chunked, embedded and stored, with the hash recorded in each
+// chunk's "content_hash" metadata; chunks a changed document no longer produces
+// are deleted. Documents that disappeared from the input have their chunks
+// deleted unless KeepRemoved is set. The manifest is saved only after the store
+// has been updated, so a failed run is retried in full next time.
+//
+// Example:
+//
+//	manifest := retrieval.NewFileManifest("./kb.manifest.json")
+//	flow := calque.NewFlow().
+//	    Use(retrieval.DocumentLoader("./docs/*.md")).
+//	    Use(retrieval.SyncDocuments(store, manifest, retrieval.SyncOptions{Embedder: provider}))
+func SyncDocuments(store VectorStore, manifest Manifest, opts ...SyncOptions) calque.Handler {
+	var cfg SyncOptions
+	if len(opts) > 0 {
+		cfg = opts[0]
+	}
+	if cfg.Chunker == nil {
+		cfg.Chunker = TextChunker()
+	}
+
+	return calque.HandlerFunc(func(r *calque.Request, w *calque.Response) error {
+		if store == nil || manifest == nil {
+			return calque.NewErr(r.Context, "sync requires a vector store and a manifest")
+		}
+
+		documents, err := readDocuments(r)
+		if err != nil {
+			return err
+		}
+
+		result, err := syncDocuments(r.Context, store, manifest, documents, cfg)
+		if err != nil {
+			return err
+		}
+
+		output, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		return calque.Write(w, output)
+	})
+}
+
+// syncDocuments applies the difference between documents and the manifest to the store
+func syncDocuments(ctx context.Context, store VectorStore, manifest Manifest, documents []Document, cfg SyncOptions) (*SyncResult, error) {
+	previous, err := manifest.Load(ctx)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to load ingest manifest")
+	}
+	if previous == nil {
+		previous = map[string]ManifestEntry{}
+	}
+
+	result := &SyncResult{}
+	next := make(map[string]ManifestEntry, len(documents))
+	var chunks []Document
+	var stale []string
+
+	for _, doc := range documents {
+		if doc.ID == "" {
+			return nil, calque.NewErr(ctx, "documents need an ID for incremental ingestion")
+		}
+		if _, dup := next[doc.ID]; dup {
+			return nil, calque.NewErr(ctx, fmt.Sprintf("duplicate document ID %s", doc.ID))
+		}
+
+		hash := ContentHash(doc.Content)
+		old, existed := previous[doc.ID]
+		if existed && old.Hash == hash {
+			next[doc.ID] = old
+			result.Unchanged++
+			continue
+		}
+
+		docChunks := cfg.Chunker.Chunk(doc)
+		ids := make([]string, len(docChunks))
+		for i := range docChunks {
+			metadata := make(map[string]any, len(docChunks[i].Metadata)+1)
+			maps.Copy(metadata, docChunks[i].Metadata)
+			metadata[MetadataContentHash] = hash
+			docChunks[i].Metadata = metadata
+			ids[i] = docChunks[i].ID
+		}
+		chunks = append(chunks, docChunks...)
+		next[doc.ID] = ManifestEntry{Hash: hash, ChunkIDs: ids}
+
+		if existed {
+			result.Updated++
+			for _, id := range old.ChunkIDs {
+				if !slices.Contains(ids, id) {
+					stale = append(stale, id)
+				}
+			}
+		} else {
+			result.Added++
+		}
+	}
+
+	for id, entry := range previous {
+		if _, ok := next[id]; ok {
+			continue
+		}
+		if cfg.KeepRemoved {
+			next[id] = entry
+			continue
+		}
+		result.Removed++
+		stale = append(stale, entry.ChunkIDs...)
+	}
+
+	if cfg.Embedder != nil {
+		if err := embedAll(ctx, cfg.Embedder, chunks, cfg.Embed); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(chunks) > 0 {
+		if err := store.Store(ctx, chunks); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to store documents")
+		}
+		result.Stored = len(chunks)
+	}
+	if len(stale) > 0 {
+		slices.Sort(stale)
+		if err := store.Delete(ctx, stale); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to delete stale chunks")
+		}
+		result.Deleted = stale
+	}
+
+	if err := manifest.Save(ctx, next); err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to save ingest manifest")
+	}
+	return result, nil
+}
+
+// ContentHash returns the hex SHA-256 of content, as recorded by SyncDocuments
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// memoryManifest keeps manifest entries in memory
+type memoryManifest struct {
+	mu      sync.Mutex
+	entries map[string]ManifestEntry
+}
+
+// NewMemoryManifest creates a Manifest held in memory, for tests and long-running processes.
+func NewMemoryManifest() Manifest {
+	return &memoryManifest{entries: map[string]ManifestEntry{}}
+}
+
+// Load implements Manifest
+func (m *memoryManifest) Load(_ context.Context) (map[string]ManifestEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return maps.Clone(m.entries), nil
+}
+
+// Save implements Manifest
+func (m *memoryManifest) Save(_ context.Context, entries map[string]ManifestEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = maps.Clone(entries)
+	return nil
+}
+
+// fileManifest stores manifest entries as a JSON file
+type fileManifest struct {
+	path string
+}
+
+// NewFileManifest creates a Manifest stored as a JSON file at path.
+//
+// A missing file is treated as an empty manifest. Saves write a temporary
+// file and rename it, so an interrupted save never corrupts the manifest.
+//
+// Example:
+//
+//	manifest := retrieval.NewFileManifest("./data/kb.manifest.json")
+func NewFileManifest(path string) Manifest {
+	return &fileManifest{path: path}
+}
+
+// Load implements Manifest
+func (f *fileManifest) Load(ctx context.Context) (map[string]ManifestEntry, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]ManifestEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := map[string]ManifestEntry{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, calque.WrapErr(ctx, err, "invalid manifest "+f.path)
+	}
+	return entries, nil
+}
+
+// Save implements Manifest
+func (f *fileManifest) Save(_ context.Context, entries map[string]ManifestEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
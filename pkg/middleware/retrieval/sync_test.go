@@ -0,0 +1,155 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// countingEmbeddingProvider counts how many texts it embeds
+type countingEmbeddingProvider struct {
+	calls int
+}
+
+func (c *countingEmbeddingProvider) Embed(_ context.Context, _ string) (EmbeddingVector, error) {
+	c.calls++
+	return EmbeddingVector{0.5, 0.5}, nil
+}
+
+// deleteRecordingIndex is a BM25 index that records deleted IDs
+type deleteRecordingIndex struct {
+	*BM25Index
+	deleted []string
+}
+
+func (d *deleteRecordingIndex) Delete(ctx context.Context, ids []string) error {
+	d.deleted = append(d.deleted, ids...)
+	return d.BM25Index.Delete(ctx, ids)
+}
+
+func runSync(t *testing.T, store VectorStore, manifest Manifest, opts SyncOptions, documents []Document) SyncResult {
+	t.Helper()
+	out, err := serveDocuments(t, SyncDocuments(store, manifest, opts), documents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result SyncResult
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestSyncDocuments(t *testing.T) {
+	store := &deleteRecordingIndex{BM25Index: NewBM25Index()}
+	manifest := NewMemoryManifest()
+	embedder := &countingEmbeddingProvider{}
+	opts := SyncOptions{Chunker: TextChunker(ChunkOptions{Size: 60, Overlap: -1}), Embedder: embedder}
+
+	long := strings.Repeat("Chunked paragraph with several words. ", 4)
+	docs := []Document{
+		{ID: "a", Content: "Alpha document"},
+		{ID: "b", Content: long},
+		{ID: "c", Content: "Gamma document"},
+	}
+
+	first := runSync(t, store, manifest, opts, docs)
+	if first.Added != 3 || first.Unchanged != 0 || first.Stored != store.Len() || embedder.calls != first.Stored {
+		t.Fatalf("first sync = %+v, index %d, embeds %d", first, store.Len(), embedder.calls)
+	}
+	if result, _ := store.Search(context.Background(), SearchQuery{Text: "alpha"}); result.Documents[0].Metadata[MetadataContentHash] != ContentHash("Alpha document") {
+		t.Errorf("chunk metadata = %v", result.Documents[0].Metadata)
+	}
+
+	embedder.calls = 0
+	second := runSync(t, store, manifest, opts, docs)
+	if second.Unchanged != 3 || second.Stored != 0 || embedder.calls != 0 || len(second.Deleted) != 0 {
+		t.Errorf("unchanged sync = %+v, embeds %d", second, embedder.calls)
+	}
+
+	// shrink b to a single chunk, drop c
+	third := runSync(t, store, manifest, opts, []Document{docs[0], {ID: "b", Content: "Short now"}})
+	if third.Updated != 1 || third.Removed != 1 || third.Unchanged != 1 || third.Stored != 1 {
+		t.Errorf("third sync = %+v", third)
+	}
+	if store.Len() != 2 {
+		t.Errorf("index has %d documents, want 2", store.Len())
+	}
+	for _, id := range third.Deleted {
+		if !strings.HasPrefix(id, "b#") && id != "c" {
+			t.Errorf("unexpected deletion %q", id)
+		}
+	}
+	entries, _ := manifest.Load(context.Background())
+	if _, ok := entries["c"]; ok || entries["b"].Hash != ContentHash("Short now") {
+		t.Errorf("manifest = %+v", entries)
+	}
+}
+
+func TestSyncDocumentsKeepRemoved(t *testing.T) {
+	store := NewBM25Index()
+	manifest := NewMemoryManifest()
+	runSync(t, store, manifest, SyncOptions{}, []Document{{ID: "a", Content: "one"}, {ID: "b", Content: "two"}})
+
+	result := runSync(t, store, manifest, SyncOptions{KeepRemoved: true}, []Document{{ID: "a", Content: "one"}})
+	if result.Removed != 0 || store.Len() != 2 {
+		t.Errorf("result = %+v, index %d", result, store.Len())
+	}
+	if entries, _ := manifest.Load(context.Background()); len(entries) != 2 {
+		t.Errorf("manifest entries = %d, want 2", len(entries))
+	}
+}
+
+func TestSyncDocumentsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		docs    []Document
+		store   VectorStore
+		wantErr string
+	}{
+		{name: "missing ID", docs: []Document{{Content: "x"}}, store: NewBM25Index(), wantErr: "need an ID"},
+		{name: "duplicate ID", docs: []Document{{ID: "a", Content: "x"}, {ID: "a", Content: "y"}}, store: NewBM25Index(), wantErr: "duplicate document ID a"},
+		{name: "store failure", docs: []Document{{ID: "a", Content: "x"}}, store: &failingStore{err: os.ErrPermission}, wantErr: "failed to store documents"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest := NewMemoryManifest()
+			_, err := serveDocuments(t, SyncDocuments(tt.store, manifest), tt.docs)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+			if entries, _ := manifest.Load(context.Background()); len(entries) != 0 {
+				t.Errorf("manifest saved after failure: %+v", entries)
+			}
+		})
+	}
+}
+
+func TestFileManifest(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	manifest := NewFileManifest(path)
+
+	entries, err := manifest.Load(ctx)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("Load missing file = %v, %v", entries, err)
+	}
+	want := map[string]ManifestEntry{"a": {Hash: "h", ChunkIDs: []string{"a#0", "a#1"}}}
+	if err := manifest.Save(ctx, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewFileManifest(path).Load(ctx)
+	if err != nil || len(got["a"].ChunkIDs) != 2 || got["a"].Hash != "h" {
+		t.Errorf("round trip = %+v, %v", got, err)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manifest.Load(ctx); err == nil || !strings.Contains(err.Error(), "invalid manifest") {
+		t.Errorf("corrupt manifest error = %v", err)
+	}
+}
@@ -7,12 +7,13 @@ import "time"
 // Contains the document content, metadata for filtering and ranking,
 // and similarity scores from vector search operations.
 type Document struct {
-	ID       string         `json:"id"`                 // Unique document identifier
-	Content  string         `json:"content"`            // Document text content
-	Metadata map[string]any `json:"metadata,omitempty"` // Additional document metadata
-	Score    float64        `json:"score,omitempty"`    // Similarity score (0-1, higher is more similar)
-	Created  time.Time      `json:"created,omitempty"`  // Document creation timestamp
-	Updated  time.Time      `json:"updated,omitempty"`  // Last update timestamp
+	ID       string          `json:"id"`                 // Unique document identifier
+	Content  string          `json:"content"`            // Document text content
+	Metadata map[string]any  `json:"metadata,omitempty"` // Additional document metadata
+	Vector   EmbeddingVector `json:"vector,omitempty"`   // Pre-computed embedding; stores embed Content when empty
+	Score    float64         `json:"score,omitempty"`    // Similarity score (0-1, higher is more similar)
+	Created  time.Time       `json:"created,omitempty"`  // Document creation timestamp
+	Updated  time.Time       `json:"updated,omitempty"`  // Last update timestamp
 }
 
 // SearchResult represents the result of a vector search operation.
@@ -36,6 +36,8 @@ const (
 // Performs similarity search against a vector database to find relevant documents.
 // When Strategy is specified in SearchOptions, automatically builds formatted context
 // using native database capabilities when available. Otherwise returns SearchResult JSON.
+// Documents refused by SearchOptions.SourcePolicy, or by a policy attached with
+// WithSourcePolicy, are dropped before results are returned or built into context.
 //
 // Examples:
 //
@@ -67,6 +69,9 @@ func VectorSearch(store VectorStore, opts *SearchOptions) calque.Handler {
 			Limit:     opts.Limit,
 			Filter:    opts.Filter,
 		}
+		if hasSourcePolicy(ctx, opts.SourcePolicy) && query.Limit > 0 {
+			query.Limit = int(float64(query.Limit) * opts.GetCandidatesMultiplier())
+		}
 
 		// Handle embedding generation based on store capabilities
 		if err := handleEmbeddingForQuery(r.Context, store, &query, opts); err != nil {
@@ -78,9 +83,11 @@ func VectorSearch(store VectorStore, opts *SearchOptions) calque.Handler {
 		if err != nil {
 			return err
 		}
+		filterSources(ctx, result, opts.SourcePolicy, opts.Limit)
 
 		// If no strategy specified, return SearchResult JSON
 		if opts.Strategy == nil {
+			recordSources(ctx, resultDocuments(result))
 			resultJSON, err := json.Marshal(result)
 			if err != nil {
 				return err
@@ -89,12 +96,13 @@ func VectorSearch(store VectorStore, opts *SearchOptions) calque.Handler {
 		}
 
 		// Strategy specified - build formatted context
-		context, err := buildContext(ctx, result.Documents, opts, store, isNative)
+		selected, err := contextDocuments(ctx, result.Documents, opts, store, isNative)
 		if err != nil {
 			return err
 		}
+		recordSources(ctx, selected)
 
-		return calque.Write(w, context)
+		return calque.Write(w, joinContext(selected, opts))
 	})
 }
 
@@ -226,8 +234,17 @@ func tryNativeStrategy(ctx context.Context, store VectorStore, query SearchQuery
 
 // buildContext assembles documents using native store capabilities when available
 func buildContext(ctx context.Context, documents []Document, opts *SearchOptions, store VectorStore, isNative bool) (string, error) {
+	selected, err := contextDocuments(ctx, documents, opts, store, isNative)
+	if err != nil {
+		return "", err
+	}
+	return joinContext(selected, opts), nil
+}
+
+// contextDocuments selects the documents that fit in the context, in order
+func contextDocuments(ctx context.Context, documents []Document, opts *SearchOptions, store VectorStore, isNative bool) ([]Document, error) {
 	if len(documents) == 0 {
-		return "", nil
+		return nil, nil
 	}
 
 	var selectedDocs []Document
@@ -240,14 +257,12 @@ func buildContext(ctx context.Context, documents []Document, opts *SearchOptions
 		// Apply strategy-based sorting/filtering post-search
 		selectedDocs, err = applyStrategy(ctx, documents, opts)
 		if err != nil {
-			return "", calque.WrapErr(ctx, err, "failed to apply sorting/filtering strategy")
+			return nil, calque.WrapErr(ctx, err, "failed to apply sorting/filtering strategy")
 		}
 	}
 
-	// Build final context string using native token estimation if available
-	separator := opts.GetSeparator()
-
-	contextParts := make([]string, 0, len(selectedDocs))
+	// Fit documents to the token budget using native token estimation if available
+	included := make([]Document, 0, len(selectedDocs))
 	currentTokens := 0
 
 	// Check if store provides native token estimation
@@ -267,11 +282,25 @@ func buildContext(ctx context.Context, documents []Document, opts *SearchOptions
 			break
 		}
 
-		contextParts = append(contextParts, doc.Content)
+		included = append(included, doc)
 		currentTokens += docTokens
 	}
 
-	return strings.Join(contextParts, separator), nil
+	return included, nil
+}
+
+// joinContext joins document contents with the configured separator,
+// numbering them [1], [2], ... when NumberSources is set
+func joinContext(documents []Document, opts *SearchOptions) string {
+	contextParts := make([]string, len(documents))
+	for i, doc := range documents {
+		if opts.NumberSources {
+			contextParts[i] = fmt.Sprintf("[%d] %s", i+1, doc.Content)
+		} else {
+			contextParts[i] = doc.Content
+		}
+	}
+	return strings.Join(contextParts, opts.GetSeparator())
 }
 
 // applyStrategy applies the specified strategy post search to select and order documents
@@ -180,8 +180,10 @@ func (c *Client) Store(ctx context.Context, documents []retrieval.Document) erro
 		}
 
 		// Add vector if provided (required when vectorizer is "none")
-		// Note: Document struct doesn't have Vector field, so we check metadata for it
-		if vec, ok := doc.Metadata["vector"].([]float32); ok && len(vec) > 0 {
+		// Metadata "vector" is still accepted for callers predating Document.Vector
+		if len(doc.Vector) > 0 {
+			obj.Vector = []float32(doc.Vector)
+		} else if vec, ok := doc.Metadata["vector"].([]float32); ok && len(vec) > 0 {
 			obj.Vector = vec
 		}
 
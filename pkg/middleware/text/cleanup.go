@@ -0,0 +1,184 @@
+package text
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Replace substitutes regular expression matches in the input.
+//
+// Input: string content (streaming - processed line by line)
+// Output: input with every match of pattern replaced by repl
+// Behavior: STREAMING - each line is written as soon as it is complete
+//
+// repl supports regexp.Expand syntax ($1, ${name}). Matches cannot span line
+// breaks. An invalid pattern makes the handler fail on every request.
+//
+// Example:
+//
+//	// Mask email addresses in model output
+//	flow.Use(text.Replace(`[\w.+-]+@[\w-]+\.[\w.]+`, "[email]"))
+//
+//	// Drop a "Sure! " preamble
+//	flow.Use(text.Replace(`^Sure!\s*`, ""))
+func Replace(pattern, repl string) calque.Handler {
+	re, err := regexp.Compile(pattern)
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		if err != nil {
+			return calque.WrapErr(req.Context, err, "invalid replace pattern")
+		}
+
+		reader := bufio.NewReader(req.Data)
+		for {
+			line, readErr := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				body, newline := bytes.CutSuffix(line, []byte("\n"))
+				out := re.ReplaceAll(body, []byte(repl))
+				if newline {
+					out = append(out, '\n')
+				}
+				if _, err := res.Data.Write(out); err != nil {
+					return err
+				}
+			}
+			if readErr == io.EOF {
+				return nil
+			}
+			if readErr != nil {
+				return readErr
+			}
+		}
+	})
+}
+
+// TrimSpace removes leading and trailing whitespace from the stream.
+//
+// Input: string content (streaming)
+// Output: input without leading or trailing Unicode whitespace
+// Behavior: STREAMING - only a run of trailing whitespace is held back
+//
+// Example:
+//
+//	flow.Use(ai.Agent(client)).
+//		Use(text.TrimSpace())
+func TrimSpace() calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		buf := make([]byte, 4096)
+		var pending []byte // trailing whitespace and any incomplete rune
+		started := false
+
+		for {
+			n, readErr := req.Data.Read(buf)
+			if n > 0 {
+				chunk := append(pending, buf[:n]...)
+				if !started {
+					chunk = bytes.TrimLeftFunc(chunk, unicode.IsSpace)
+					started = len(chunk) > 0 && utf8.FullRune(chunk)
+				}
+
+				// hold back an incomplete rune so split whitespace is recognised
+				complete := len(chunk) - incompleteRuneSuffix(chunk)
+				end := len(bytes.TrimRightFunc(chunk[:complete], unicode.IsSpace))
+				if end > 0 {
+					if _, err := res.Data.Write(chunk[:end]); err != nil {
+						return err
+					}
+				}
+				pending = append([]byte(nil), chunk[end:]...)
+			}
+			if readErr == io.EOF {
+				return nil
+			}
+			if readErr != nil {
+				return readErr
+			}
+		}
+	})
+}
+
+// incompleteRuneSuffix returns how many trailing bytes of b form an incomplete UTF-8 rune
+func incompleteRuneSuffix(b []byte) int {
+	for i := 1; i <= utf8.UTFMax && i <= len(b); i++ {
+		c := b[len(b)-i]
+		if utf8.RuneStart(c) {
+			if utf8.FullRune(b[len(b)-i:]) {
+				return 0
+			}
+			return i
+		}
+	}
+	return 0
+}
+
+// Truncate limits the output to n characters.
+//
+// Input: string content (streaming)
+// Output: at most n runes; when input was cut, the last runes are ellipsis
+// Behavior: STREAMING - passes input through until the limit, then discards the rest
+//
+// The ellipsis counts toward n, so output never exceeds n runes. Input that
+// fits is passed through unchanged.
+//
+// Example:
+//
+//	// Keep SMS replies within 160 characters
+//	flow.Use(text.Truncate(160, "…"))
+func Truncate(n int, ellipsis string) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		tail := utf8.RuneCountInString(ellipsis)
+		if tail > n {
+			ellipsis = string([]rune(ellipsis)[:n])
+			tail = n
+		}
+
+		reader := bufio.NewReader(req.Data)
+		writer := bufio.NewWriter(res.Data)
+		held := make([]rune, 0, tail) // the last runes that the ellipsis would replace
+		count := 0
+
+		for {
+			r, _, err := reader.ReadRune()
+			if err == io.EOF {
+				for _, h := range held {
+					if _, err := writer.WriteRune(h); err != nil {
+						return err
+					}
+				}
+				return writer.Flush()
+			}
+			if err != nil {
+				return err
+			}
+
+			count++
+			switch {
+			case count <= n-tail:
+				if _, err := writer.WriteRune(r); err != nil {
+					return err
+				}
+				if reader.Buffered() == 0 {
+					if err := writer.Flush(); err != nil {
+						return err
+					}
+				}
+			case count <= n:
+				held = append(held, r)
+			default:
+				if _, err := writer.WriteString(ellipsis); err != nil {
+					return err
+				}
+				if err := writer.Flush(); err != nil {
+					return err
+				}
+				_, err := io.Copy(io.Discard, reader)
+				return err
+			}
+		}
+	})
+}
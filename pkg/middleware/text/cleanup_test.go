@@ -0,0 +1,101 @@
+package text
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// runChunked feeds input one byte per Read to exercise chunk boundaries
+func runChunked(t *testing.T, h calque.Handler, input string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	req := calque.NewRequest(context.Background(), iotest.OneByteReader(strings.NewReader(input)))
+	if err := h.ServeFlow(req, calque.NewResponse(&buf)); err != nil {
+		t.Fatalf("ServeFlow() error = %v", err)
+	}
+	return buf.String()
+}
+
+func TestReplace(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		repl     string
+		input    string
+		expected string
+	}{
+		{name: "simple", pattern: `cat`, repl: "dog", input: "cat and cat", expected: "dog and dog"},
+		{name: "groups", pattern: `(\w+)@(\w+)\.com`, repl: "$1 at $2", input: "mail bob@example.com\nor amy@test.com\n", expected: "mail bob at example\nor amy at test\n"},
+		{name: "line anchor", pattern: `^Sure!\s*`, repl: "", input: "Sure! Here it is\nSure! again", expected: "Here it is\nagain"},
+		{name: "no match", pattern: `xyz`, repl: "-", input: "abc", expected: "abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runChunked(t, Replace(tt.pattern, tt.repl), tt.input); got != tt.expected {
+				t.Errorf("Replace() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReplaceInvalidPattern(t *testing.T) {
+	err := Replace(`(`, "").ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("x")), calque.NewResponse(io.Discard))
+	if err == nil {
+		t.Error("Replace() with invalid pattern error = nil, want error")
+	}
+}
+
+func TestTrimSpace(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "both ends", input: "  \n hello world \n\t ", expected: "hello world"},
+		{name: "inner whitespace kept", input: "a  \n\n  b", expected: "a  \n\n  b"},
+		{name: "unicode spaces", input: "　 héllo 　", expected: "héllo"},
+		{name: "only whitespace", input: " \n\t ", expected: ""},
+		{name: "empty", input: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runChunked(t, TrimSpace(), tt.input); got != tt.expected {
+				t.Errorf("TrimSpace() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name     string
+		n        int
+		ellipsis string
+		input    string
+		expected string
+	}{
+		{name: "fits", n: 10, ellipsis: "...", input: "short", expected: "short"},
+		{name: "exact length", n: 5, ellipsis: "...", input: "exact", expected: "exact"},
+		{name: "cut with ellipsis", n: 8, ellipsis: "...", input: "hello world", expected: "hello..."},
+		{name: "runes not bytes", n: 4, ellipsis: "…", input: "héllo wörld", expected: "hél…"},
+		{name: "no ellipsis", n: 3, ellipsis: "", input: "abcdef", expected: "abc"},
+		{name: "ellipsis longer than limit", n: 2, ellipsis: "...", input: "abcdef", expected: ".."},
+		{name: "zero", n: 0, ellipsis: "", input: "abc", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runChunked(t, Truncate(tt.n, tt.ellipsis), tt.input); got != tt.expected {
+				t.Errorf("Truncate(%d, %q) = %q, want %q", tt.n, tt.ellipsis, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,572 @@
+package text
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultCodeChunkSize is the CodeChunkOptions.MaxSize used when none is given.
+const DefaultCodeChunkSize = 1500
+
+// CodeSymbol is a declaration found in source code.
+type CodeSymbol struct {
+	Name string `json:"name"` // qualified with its enclosing type, e.g. "Server.Handle"
+	Kind string `json:"kind"` // function, method, class, type, interface, const, var, ...
+	Line int    `json:"line"` // 1-based line of the declaration
+}
+
+// CodeChunk is a piece of a source file produced by SplitCode.
+type CodeChunk struct {
+	Content   string       `json:"content"`
+	Language  string       `json:"language,omitempty"`
+	StartLine int          `json:"start_line"` // 1-based, inclusive
+	EndLine   int          `json:"end_line"`   // 1-based, inclusive
+	Symbols   []CodeSymbol `json:"symbols,omitempty"`
+}
+
+// CodeChunkOptions configures SplitCode and ChunkCode.
+type CodeChunkOptions struct {
+	MaxSize int // maximum characters per chunk (default: DefaultCodeChunkSize)
+}
+
+// ChunkCode splits source code along function and type boundaries.
+//
+// Input: source code of one file (buffered - reads entire input into memory)
+// Output: []CodeChunk JSON array
+// Behavior: BUFFERED - declarations need the whole file
+//
+// See SplitCode for how chunks are formed and which languages are recognised.
+//
+// Example:
+//
+//	flow.Use(text.ChunkCode("python", text.CodeChunkOptions{MaxSize: 1200}))
+func ChunkCode(language string, opts ...CodeChunkOptions) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var src string
+		if err := calque.Read(req, &src); err != nil {
+			return err
+		}
+		chunks := SplitCode(src, language, opts...)
+		if chunks == nil {
+			chunks = []CodeChunk{}
+		}
+		output, err := json.Marshal(chunks)
+		if err != nil {
+			return err
+		}
+		return calque.Write(res, output)
+	})
+}
+
+// SplitCode splits src into chunks of whole declarations.
+//
+// Each function, method and type stays in one chunk together with its doc
+// comments, decorators and attributes; small neighbouring declarations share
+// a chunk up to MaxSize. A class or impl block that is too large is split
+// between its members, and a single oversized function between blank lines.
+// Each chunk lists the symbols declared in it, or the enclosing symbol for
+// the inner pieces of a split declaration.
+//
+// language is a name or file extension: go (parsed with go/parser), python,
+// javascript, typescript, java, kotlin, scala, swift, c, cpp, csharp, rust and
+// php. Other languages, and Go that does not parse, are split between blank
+// lines without symbols.
+//
+// Example:
+//
+//	for _, chunk := range text.SplitCode(src, text.CodeLanguage("server.go")) {
+//		fmt.Println(chunk.StartLine, chunk.Symbols)
+//	}
+func SplitCode(src, language string, opts ...CodeChunkOptions) []CodeChunk {
+	maxSize := DefaultCodeChunkSize
+	if len(opts) > 0 && opts[0].MaxSize > 0 {
+		maxSize = opts[0].MaxSize
+	}
+	language = normalizeCodeLanguage(language)
+
+	lines := strings.Split(strings.TrimRight(src, "\n"), "\n")
+	var blocks []codeBlock
+	switch {
+	case language == "go":
+		blocks = parseGoBlocks(src, lines)
+	case language == "python":
+		blocks = parsePythonBlocks(lines, 0, len(lines), "")
+	case braceLanguages[language]:
+		scan := scanBraces(lines)
+		blocks = scan.blocks(0, len(lines), 0, "")
+	}
+
+	c := &codeSplitter{lines: lines, maxSize: maxSize, language: language}
+	c.split(0, len(lines), blocks, nil)
+	return c.chunks
+}
+
+// CodeLanguage returns the SplitCode language for a file name, or "" when the
+// extension is not a recognised source language.
+func CodeLanguage(filename string) string {
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(strings.ReplaceAll(filename, `\`, "/")), "."))
+	if ext == "" {
+		return ""
+	}
+	lang := normalizeCodeLanguage(ext)
+	if lang == "go" || lang == "python" || braceLanguages[lang] {
+		return lang
+	}
+	return ""
+}
+
+var codeLanguageAliases = map[string]string{
+	"golang": "go",
+	"py":     "python", "pyi": "python",
+	"js": "javascript", "jsx": "javascript", "mjs": "javascript", "cjs": "javascript",
+	"ts": "typescript", "tsx": "typescript", "mts": "typescript", "cts": "typescript",
+	"kt": "kotlin", "kts": "kotlin",
+	"h":   "c",
+	"c++": "cpp", "cc": "cpp", "cxx": "cpp", "hpp": "cpp", "hh": "cpp", "hxx": "cpp",
+	"cs": "csharp", "c#": "csharp",
+	"rs": "rust",
+	"sc": "scala",
+}
+
+// braceLanguages are parsed by brace depth
+var braceLanguages = map[string]bool{
+	"javascript": true, "typescript": true, "java": true, "kotlin": true, "scala": true,
+	"swift": true, "c": true, "cpp": true, "csharp": true, "rust": true, "php": true,
+}
+
+func normalizeCodeLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if alias, ok := codeLanguageAliases[language]; ok {
+		return alias
+	}
+	return language
+}
+
+// codeBlock is a declaration spanning lines [start, end)
+type codeBlock struct {
+	start, end int
+	symbol     CodeSymbol
+	children   []codeBlock
+}
+
+// codeSplitter packs blocks into chunks of at most maxSize characters
+type codeSplitter struct {
+	lines    []string
+	maxSize  int
+	language string
+	chunks   []CodeChunk
+
+	start, end int // pending lines; start == end when empty
+	size       int
+	symbols    []CodeSymbol
+}
+
+func (c *codeSplitter) linesSize(from, to int) int {
+	n := 0
+	for _, line := range c.lines[from:to] {
+		n += utf8.RuneCountInString(line) + 1
+	}
+	return n
+}
+
+// split chunks lines [from, to); enclosing labels pieces without symbols of their own
+func (c *codeSplitter) split(from, to int, blocks []codeBlock, enclosing *CodeSymbol) {
+	cursor := from
+	for _, b := range blocks {
+		if b.start > cursor {
+			c.add(cursor, b.start, nil, nil, enclosing)
+		}
+		c.add(b.start, b.end, &b.symbol, b.children, enclosing)
+		cursor = b.end
+	}
+	if cursor < to {
+		c.add(cursor, to, nil, nil, enclosing)
+	}
+	c.flush(enclosing)
+}
+
+// add appends a segment, splitting it when it cannot fit in one chunk
+func (c *codeSplitter) add(from, to int, symbol *CodeSymbol, children []codeBlock, enclosing *CodeSymbol) {
+	size := c.linesSize(from, to)
+	owner := enclosing
+	if symbol != nil {
+		owner = symbol
+	}
+	if size > c.maxSize && len(children) > 0 {
+		// pending lines can share a chunk with the declaration's header
+		c.split(from, to, children, owner)
+		return
+	}
+
+	if c.start != c.end && c.size+size > c.maxSize {
+		c.flush(enclosing)
+	}
+	if size <= c.maxSize {
+		if c.start == c.end {
+			c.start = from
+		}
+		c.end, c.size = to, c.size+size
+		if symbol != nil {
+			c.symbols = append(c.symbols, *symbol)
+		}
+		return
+	}
+	c.splitLines(from, to, owner)
+}
+
+// splitLines breaks an oversized segment, preferring blank lines as cut points
+func (c *codeSplitter) splitLines(from, to int, owner *CodeSymbol) {
+	start, size, blank := from, 0, -1
+	for i := from; i < to; i++ {
+		n := utf8.RuneCountInString(c.lines[i]) + 1
+		if size+n > c.maxSize && i > start {
+			cut := i
+			if blank > start+(i-start)/2 {
+				cut = blank
+			}
+			c.start, c.end = start, cut
+			c.flush(owner)
+			start, size, blank = cut, c.linesSize(cut, i), -1
+		}
+		size += n
+		if strings.TrimSpace(c.lines[i]) == "" {
+			blank = i
+		}
+	}
+	c.start, c.end = start, to
+	c.flush(owner)
+}
+
+// flush emits the pending lines as a chunk, trimming blank edge lines
+func (c *codeSplitter) flush(enclosing *CodeSymbol) {
+	start, end, symbols := c.start, c.end, c.symbols
+	c.start, c.end, c.size, c.symbols = 0, 0, 0, nil
+
+	for start < end && strings.TrimSpace(c.lines[start]) == "" {
+		start++
+	}
+	for end > start && strings.TrimSpace(c.lines[end-1]) == "" {
+		end--
+	}
+	if start == end {
+		return
+	}
+	if len(symbols) == 0 && enclosing != nil {
+		symbols = []CodeSymbol{*enclosing}
+	}
+	c.chunks = append(c.chunks, CodeChunk{
+		Content:   strings.Join(c.lines[start:end], "\n"),
+		Language:  c.language,
+		StartLine: start + 1,
+		EndLine:   end,
+		Symbols:   symbols,
+	})
+}
+
+// parseGoBlocks returns the top-level declarations of a Go file, or nil if it does not parse
+func parseGoBlocks(src string, lines []string) []codeBlock {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+	line := func(p token.Pos) int { return fset.Position(p).Line - 1 }
+
+	var blocks []codeBlock
+	for _, decl := range file.Decls {
+		var b codeBlock
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			b = codeBlock{start: line(d.Pos()), end: line(d.End()) + 1, symbol: CodeSymbol{Name: d.Name.Name, Kind: "function"}}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				b.symbol = CodeSymbol{Name: goReceiverName(d.Recv.List[0].Type) + "." + d.Name.Name, Kind: "method"}
+			}
+			b.symbol.Line = b.start + 1
+			if d.Doc != nil {
+				b.start = line(d.Doc.Pos())
+			}
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT || len(d.Specs) == 0 {
+				continue
+			}
+			b = codeBlock{start: line(d.Pos()), end: line(d.End()) + 1, symbol: CodeSymbol{Kind: d.Tok.String(), Line: line(d.Pos()) + 1}}
+			switch s := d.Specs[0].(type) {
+			case *ast.TypeSpec:
+				b.symbol.Name = s.Name.Name
+				if _, ok := s.Type.(*ast.InterfaceType); ok {
+					b.symbol.Kind = "interface"
+				}
+			case *ast.ValueSpec:
+				b.symbol.Name = s.Names[0].Name
+			}
+			if d.Doc != nil {
+				b.start = line(d.Doc.Pos())
+			}
+		default:
+			continue
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+func goReceiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return goReceiverName(t.X)
+	case *ast.IndexExpr:
+		return goReceiverName(t.X)
+	case *ast.IndexListExpr:
+		return goReceiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+var pythonDecl = regexp.MustCompile(`^(\s*)(async\s+def|def|class)\s+([A-Za-z_]\w*)`)
+
+// parsePythonBlocks finds def and class blocks in lines [from, to) by indentation
+func parsePythonBlocks(lines []string, from, to int, parent string) []codeBlock {
+	var blocks []codeBlock
+	floor := from
+	for i := from; i < to; i++ {
+		m := pythonDecl.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		indent := len(m[1])
+		end := i + 1
+		for j := i + 1; j < to; j++ {
+			trimmed := strings.TrimSpace(lines[j])
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			if indentation(lines[j]) <= indent {
+				break
+			}
+			end = j + 1
+		}
+
+		kind := "function"
+		switch {
+		case m[2] == "class":
+			kind = "class"
+		case parent != "":
+			kind = "method"
+		}
+		name := qualify(parent, m[3])
+		b := codeBlock{
+			start:  leadingLines(lines, i, floor, "#", "@"),
+			end:    end,
+			symbol: CodeSymbol{Name: name, Kind: kind, Line: i + 1},
+		}
+		if kind == "class" {
+			b.children = parsePythonBlocks(lines, i+1, end, name)
+		}
+		blocks = append(blocks, b)
+		floor = end
+		i = end - 1
+	}
+	return blocks
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+func qualify(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// leadingLines moves start up over the comments and annotations directly above it
+func leadingLines(lines []string, start, floor int, prefixes ...string) int {
+	for start > floor {
+		prev := strings.TrimSpace(lines[start-1])
+		found := false
+		for _, p := range prefixes {
+			if prev != "" && strings.HasPrefix(prev, p) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+		start--
+	}
+	return start
+}
+
+var (
+	braceTypeDecl = regexp.MustCompile(`\b(class|interface|enum|struct|trait|record|object|namespace|protocol|extension|union|module|mod)\s+([A-Za-z_$][\w$]*)`)
+	braceImplDecl = regexp.MustCompile(`^(?:pub\s+)?(?:unsafe\s+)?impl(?:<[^>]*>)?\s+([\w:]+)(?:<[^>]*>)?(?:\s+for\s+([\w:]+))?`)
+	braceFuncDecl = regexp.MustCompile(`\b(function\*?|func|fn|fun|def)\s+([A-Za-z_$][\w$]*)`)
+	braceArrow    = regexp.MustCompile(`^(?:export\s+)?(?:const|let|var)\s+([A-Za-z_$][\w$]*)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:\([^)]*\)|[A-Za-z_$][\w$]*)\s*(?::[^=]+)?=>`)
+	braceSigDecl  = regexp.MustCompile(`^[\w\s*&:<>,\[\]@?~]*?([A-Za-z_~][\w]*)\s*\(`)
+)
+
+// braceKeywords look like signatures but open statements
+var braceKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true, "return": true,
+	"new": true, "else": true, "do": true, "sizeof": true, "using": true, "lock": true,
+	"foreach": true, "match": true, "when": true, "try": true, "synchronized": true, "elseif": true,
+}
+
+// braceScan is the brace depth of each line with strings and comments removed
+type braceScan struct {
+	lines []string
+	code  []string // line with strings and comments blanked
+	depth []int    // depth at the start of each line, plus one past the end
+}
+
+// scanBraces tracks brace depth through a C-family file; it understands
+// line and block comments and quoted strings, not every language's literals
+func scanBraces(lines []string) *braceScan {
+	s := &braceScan{lines: lines, code: make([]string, len(lines)), depth: make([]int, len(lines)+1)}
+	depth := 0
+	var quote byte // open string delimiter carried over lines (` and block comments)
+	for i, line := range lines {
+		s.depth[i] = depth
+		var code strings.Builder
+		for j := 0; j < len(line); j++ {
+			ch := line[j]
+			switch {
+			case quote == '*':
+				if ch == '*' && j+1 < len(line) && line[j+1] == '/' {
+					quote = 0
+					j++
+				}
+				continue
+			case quote != 0:
+				if ch == '\\' {
+					j++
+				} else if ch == quote {
+					quote = 0
+				}
+				continue
+			case ch == '/' && j+1 < len(line) && line[j+1] == '/':
+				j = len(line)
+				continue
+			case ch == '/' && j+1 < len(line) && line[j+1] == '*':
+				quote = '*'
+				j++
+				continue
+			case ch == '"' || ch == '`':
+				quote = ch
+				continue
+			case ch == '\'':
+				// a char literal, not a Rust lifetime or generic marker
+				if j+2 < len(line) && (line[j+1] == '\\' || line[j+2] == '\'') {
+					quote = ch
+					continue
+				}
+			case ch == '{':
+				depth++
+			case ch == '}':
+				depth--
+			}
+			code.WriteByte(ch)
+		}
+		if quote == '"' || quote == '\'' {
+			quote = 0 // unterminated single-line string
+		}
+		s.code[i] = code.String()
+	}
+	s.depth[len(lines)] = depth
+	return s
+}
+
+// blocks finds declarations at depth in lines [from, to)
+func (s *braceScan) blocks(from, to, depth int, parent string) []codeBlock {
+	var blocks []codeBlock
+	floor := from
+	for i := from; i < to; i++ {
+		if s.depth[i] != depth {
+			continue
+		}
+		symbol, ok := braceSymbol(strings.TrimSpace(s.code[i]), parent)
+		if !ok {
+			continue
+		}
+		end := s.blockEnd(i, to, depth)
+		if end < 0 {
+			continue
+		}
+		symbol.Line = i + 1
+		b := codeBlock{
+			start:  leadingLines(s.lines, i, floor, "//", "/*", "*", "#[", "@", "["),
+			end:    end,
+			symbol: symbol,
+		}
+		switch symbol.Kind {
+		case "function", "method":
+		default:
+			b.children = s.blocks(i+1, end, depth+1, symbol.Name)
+		}
+		blocks = append(blocks, b)
+		floor = end
+		i = end - 1
+	}
+	return blocks
+}
+
+// blockEnd returns the line after the braces opened by a declaration at
+// line i close, or -1 when the declaration has no body
+func (s *braceScan) blockEnd(i, to, depth int) int {
+	opened := false
+	for j := i; j < to; j++ {
+		if strings.Contains(s.code[j], "{") || s.depth[j+1] > depth {
+			opened = true
+		}
+		if !opened && (strings.Contains(s.code[j], ";") || j-i >= 8) {
+			return -1
+		}
+		if opened && s.depth[j+1] <= depth {
+			return j + 1
+		}
+	}
+	if opened {
+		return to
+	}
+	return -1
+}
+
+// braceSymbol recognises a declaration line
+func braceSymbol(code, parent string) (CodeSymbol, bool) {
+	if m := braceImplDecl.FindStringSubmatch(code); m != nil {
+		name := m[1]
+		if m[2] != "" {
+			name = m[2]
+		}
+		return CodeSymbol{Name: qualify(parent, name), Kind: "impl"}, true
+	}
+	if m := braceTypeDecl.FindStringSubmatch(code); m != nil && !strings.Contains(code[:strings.Index(code, m[0])], "(") {
+		return CodeSymbol{Name: qualify(parent, m[2]), Kind: m[1]}, true
+	}
+	kind := "function"
+	if parent != "" {
+		kind = "method"
+	}
+	if m := braceFuncDecl.FindStringSubmatch(code); m != nil {
+		return CodeSymbol{Name: qualify(parent, m[2]), Kind: kind}, true
+	}
+	if m := braceArrow.FindStringSubmatch(code); m != nil {
+		return CodeSymbol{Name: qualify(parent, m[1]), Kind: kind}, true
+	}
+	if m := braceSigDecl.FindStringSubmatch(code); m != nil && !braceKeywords[m[1]] && !strings.HasSuffix(code, ";") {
+		return CodeSymbol{Name: qualify(parent, m[1]), Kind: kind}, true
+	}
+	return CodeSymbol{}, false
+}
@@ -0,0 +1,218 @@
+package text
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// symbolNames lists the symbols of each chunk as "name:kind" joined by commas
+func symbolNames(chunks []CodeChunk) []string {
+	out := make([]string, len(chunks))
+	for i, c := range chunks {
+		names := make([]string, len(c.Symbols))
+		for j, s := range c.Symbols {
+			names[j] = s.Name + ":" + s.Kind
+		}
+		out[i] = strings.Join(names, ",")
+	}
+	return out
+}
+
+const goSource = `package server
+
+import "net/http"
+
+// Server handles requests.
+type Server struct {
+	mux *http.ServeMux
+}
+
+// Handle registers a route.
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, h)
+}
+
+const version = "1.0"
+
+func main() {
+	_ = &Server{}
+}
+`
+
+func TestSplitCodeGo(t *testing.T) {
+	chunks := SplitCode(goSource, "go", CodeChunkOptions{MaxSize: 120})
+	got := symbolNames(chunks)
+	want := []string{"Server:type", "Server.Handle:method", "version:const,main:function"}
+	if strings.Join(got, " | ") != strings.Join(want, " | ") {
+		t.Fatalf("symbols = %v, want %v", got, want)
+	}
+
+	// the package clause and imports go with the first declaration
+	first := chunks[0]
+	if first.StartLine != 1 || !strings.HasPrefix(first.Content, "package server") || !strings.Contains(first.Content, "// Server handles requests.") {
+		t.Errorf("first chunk = %+v", first)
+	}
+	if handle := chunks[1]; handle.StartLine != 10 || handle.EndLine != 13 || !strings.HasPrefix(handle.Content, "// Handle registers") {
+		t.Errorf("method chunk should start at its doc comment, got lines %d-%d: %q", handle.StartLine, handle.EndLine, handle.Content)
+	}
+	if chunks[1].Symbols[0].Line != 11 {
+		t.Errorf("symbol line = %d, want 11", chunks[1].Symbols[0].Line)
+	}
+
+	whole := SplitCode(goSource, "golang")
+	if len(whole) != 1 || len(whole[0].Symbols) != 4 {
+		t.Errorf("small file should be one chunk with every symbol, got %v", symbolNames(whole))
+	}
+}
+
+const pythonSource = `import os
+
+
+@dataclass
+class Store:
+    """A key-value store."""
+
+    def get(self, key):
+        return self.data[key]
+
+    def put(self, key, value):
+        # overwrite
+        self.data[key] = value
+
+
+async def fetch(url):
+    return await client.get(url)
+`
+
+func TestSplitCodePython(t *testing.T) {
+	got := symbolNames(SplitCode(pythonSource, "py", CodeChunkOptions{MaxSize: 100}))
+	want := []string{"Store:class", "Store.get:method", "Store.put:method", "fetch:function"}
+	if strings.Join(got, " | ") != strings.Join(want, " | ") {
+		t.Errorf("symbols = %v, want %v", got, want)
+	}
+
+	chunks := SplitCode(pythonSource, "python", CodeChunkOptions{MaxSize: 250})
+	if len(chunks) != 2 || !strings.HasPrefix(chunks[0].Content, "import os\n\n\n@dataclass\nclass Store:") {
+		t.Errorf("class should keep its decorator, got %+v", chunks)
+	}
+}
+
+const tsSource = `import { api } from "./api";
+
+/** Loads users. */
+export class UserService {
+  private cache = new Map<string, User>();
+
+  async load(id: string): Promise<User> {
+    const url = "/users/{" + id + "}";
+    return api.get(url);
+  }
+
+  clear() {
+    this.cache.clear();
+  }
+}
+
+export const format = (u: User): string => {
+  return u.name;
+};
+
+export interface User {
+  name: string;
+}
+`
+
+func TestSplitCodeBraces(t *testing.T) {
+	got := symbolNames(SplitCode(tsSource, "typescript", CodeChunkOptions{MaxSize: 150}))
+	want := []string{"UserService:class", "UserService.load:method", "UserService.clear:method", "format:function,User:interface"}
+	if strings.Join(got, " | ") != strings.Join(want, " | ") {
+		t.Errorf("symbols = %v, want %v", got, want)
+	}
+
+	rust := "/// A point.\n#[derive(Debug)]\npub struct Point { x: i32 }\n\nimpl<'a> Display for Point {\n    fn fmt(&self, f: &mut Formatter<'a>) -> Result {\n        write!(f, \"{}\", '}')\n    }\n}\n"
+	chunks := SplitCode(rust, "rs", CodeChunkOptions{MaxSize: 100})
+	got = symbolNames(chunks)
+	want = []string{"Point:struct", "Point.fmt:method"}
+	if strings.Join(got, " | ") != strings.Join(want, " | ") {
+		t.Errorf("rust symbols = %v, want %v", got, want)
+	}
+	if chunks[0].StartLine != 1 {
+		t.Errorf("struct chunk should include its doc comment and attribute, got %+v", chunks[0])
+	}
+}
+
+func TestSplitCodeOversized(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("function big() {\n")
+	for i := range 30 {
+		if i%10 == 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("  step();\n")
+	}
+	b.WriteString("}\n")
+
+	chunks := SplitCode(b.String(), "javascript", CodeChunkOptions{MaxSize: 120})
+	if len(chunks) < 3 {
+		t.Fatalf("expected the function to be split, got %d chunks", len(chunks))
+	}
+	for _, c := range chunks {
+		if len(c.Content) > 120 {
+			t.Errorf("chunk of %d characters exceeds MaxSize", len(c.Content))
+		}
+		if len(c.Symbols) != 1 || c.Symbols[0].Name != "big" {
+			t.Errorf("pieces should carry the enclosing symbol, got %v", c.Symbols)
+		}
+	}
+	if last := chunks[len(chunks)-1]; last.EndLine != 35 {
+		t.Errorf("last chunk ends at line %d, want 35", last.EndLine)
+	}
+}
+
+func TestSplitCodeUnknownLanguage(t *testing.T) {
+	chunks := SplitCode("a\nb\n\nc\n", "cobol")
+	if len(chunks) != 1 || chunks[0].Symbols != nil || chunks[0].Content != "a\nb\n\nc" {
+		t.Errorf("chunks = %+v", chunks)
+	}
+}
+
+func TestCodeLanguage(t *testing.T) {
+	tests := map[string]string{
+		"main.go":         "go",
+		"pkg/app.TSX":     "typescript",
+		"lib/util.h":      "c",
+		`src\main.rs`:     "rust",
+		"script.py":       "python",
+		"README.md":       "",
+		"Makefile":        "",
+		"Service.cs":      "csharp",
+		"build.gradle.kt": "kotlin",
+	}
+	for name, want := range tests {
+		if got := CodeLanguage(name); got != want {
+			t.Errorf("CodeLanguage(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestChunkCode(t *testing.T) {
+	var out string
+	if err := calque.NewFlow().Use(ChunkCode("go")).Run(context.Background(), goSource, &out); err != nil {
+		t.Fatal(err)
+	}
+	var chunks []CodeChunk
+	if err := json.Unmarshal([]byte(out), &chunks); err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 1 || chunks[0].Language != "go" || chunks[0].EndLine != 19 {
+		t.Errorf("chunks = %+v", chunks)
+	}
+
+	if err := calque.NewFlow().Use(ChunkCode("go")).Run(context.Background(), "", &out); err != nil || out != "[]" {
+		t.Errorf("empty input = %q, %v", out, err)
+	}
+}
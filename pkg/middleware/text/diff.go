@@ -0,0 +1,416 @@
+package text
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// diffContext is the number of unchanged lines shown around each hunk
+const diffContext = 3
+
+// Diff produces a unified diff from original to the incoming text.
+//
+// Input: updated text (buffered - reads entire input into memory)
+// Output: unified diff with "--- original" / "+++ updated" headers, or empty
+// output when nothing changed
+// Behavior: BUFFERED - the whole text is needed to compute the diff
+//
+// Lines are compared exactly, using a minimal (Myers) edit script with three
+// lines of context per hunk.
+//
+// Example:
+//
+//	// Show what the model changed
+//	flow.Use(ai.Agent(client)).
+//		Use(text.Diff(sourceFile))
+func Diff(original string) calque.Handler {
+	return Transform(func(updated string) string {
+		return unifiedDiff(original, updated)
+	})
+}
+
+// ApplyPatch applies the incoming unified diff to target.
+//
+// Input: unified diff (buffered - reads entire input into memory)
+// Output: target with every hunk applied
+// Behavior: BUFFERED - the whole patch is parsed before applying
+//
+// Built for model-generated patches: text around the diff (explanations,
+// ``` fences, diff --git and index lines) is ignored, hunk line numbers are
+// treated as hints and each hunk is located by its context (tolerating
+// trailing-whitespace differences), and hunk headers without counts
+// ("@@ ... @@") are accepted. Returns an error naming the first hunk whose
+// context is not found in target.
+//
+// Example:
+//
+//	flow.Use(ai.Agent(client)).
+//		Use(text.ApplyPatch(sourceFile)).
+//		Use(writeFileHandler)
+func ApplyPatch(target string) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var patch string
+		if err := calque.Read(req, &patch); err != nil {
+			return err
+		}
+
+		hunks, err := parseHunks(patch)
+		if err != nil {
+			return calque.WrapErr(req.Context, err, "invalid patch")
+		}
+		if len(hunks) == 0 {
+			return calque.NewErr(req.Context, "patch contains no hunks")
+		}
+
+		patched, err := applyHunks(target, hunks)
+		if err != nil {
+			return calque.WrapErr(req.Context, err, "failed to apply patch")
+		}
+		return calque.Write(res, patched)
+	})
+}
+
+// splitLines splits text into lines, reporting whether it ended with a newline
+func splitLines(s string) ([]string, bool) {
+	if s == "" {
+		return nil, false
+	}
+	trailing := strings.HasSuffix(s, "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n"), trailing
+}
+
+type editOp byte
+
+const (
+	opEqual  editOp = ' '
+	opDelete editOp = '-'
+	opInsert editOp = '+'
+)
+
+type edit struct {
+	op   editOp
+	line string
+}
+
+// diffLines computes a minimal edit script from a to b using Myers' algorithm
+func diffLines(a, b []string) []edit {
+	n, m := len(a), len(b)
+	maxD := n + m
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+	var trace [][]int // per d, the diagonals -d..d of v before step d
+
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, d)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the Myers trace from the end to recover the edit script.
+// trace[d][d+k] holds the furthest x on diagonal k before step d.
+func backtrack(trace [][]int, a, b []string, depth int) []edit {
+	x, y := len(a), len(b)
+	edits := make([]edit, 0, x+y)
+
+	for d := depth; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[d+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{opEqual, a[x]})
+		}
+		if x == prevX {
+			y--
+			edits = append(edits, edit{opInsert, b[y]})
+		} else {
+			x--
+			edits = append(edits, edit{opDelete, a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		edits = append(edits, edit{opEqual, a[x]})
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// noNewline marks a final line without a trailing newline so it never equals
+// the same text followed by a newline
+const noNewline = "\x00"
+
+// diffInput splits text into lines for diffing
+func diffInput(s string) []string {
+	lines, trailing := splitLines(s)
+	if len(lines) > 0 && !trailing {
+		lines[len(lines)-1] += noNewline
+	}
+	return lines
+}
+
+// unifiedDiff formats the edit script between two texts as a unified diff
+func unifiedDiff(original, updated string) string {
+	edits := diffLines(diffInput(original), diffInput(updated))
+
+	var changes []int
+	for i, e := range edits {
+		if e.op != opEqual {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	// line numbers (0-based) in each side before each edit
+	aLine, bLine := make([]int, len(edits)+1), make([]int, len(edits)+1)
+	for i, e := range edits {
+		aLine[i+1], bLine[i+1] = aLine[i], bLine[i]
+		if e.op != opInsert {
+			aLine[i+1]++
+		}
+		if e.op != opDelete {
+			bLine[i+1]++
+		}
+	}
+
+	var out strings.Builder
+	out.WriteString("--- original\n+++ updated\n")
+
+	for c := 0; c < len(changes); {
+		// merge changes separated by less than two contexts' worth of lines
+		last := c
+		for last+1 < len(changes) && changes[last+1]-changes[last] <= diffContext*2 {
+			last++
+		}
+		start := max(0, changes[c]-diffContext)
+		end := min(len(edits), changes[last]+1+diffContext)
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n",
+			hunkRange(aLine[start], aLine[end]-aLine[start]),
+			hunkRange(bLine[start], bLine[end]-bLine[start]))
+		for _, e := range edits[start:end] {
+			line, missing := strings.CutSuffix(e.line, noNewline)
+			out.WriteByte(byte(e.op))
+			out.WriteString(line)
+			out.WriteByte('\n')
+			if missing {
+				out.WriteString("\\ No newline at end of file\n")
+			}
+		}
+		c = last + 1
+	}
+	return out.String()
+}
+
+// hunkRange formats a hunk range as "start,count" (1-based)
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return strconv.Itoa(start) + ",0"
+	}
+	if count == 1 {
+		return strconv.Itoa(start + 1)
+	}
+	return strconv.Itoa(start+1) + "," + strconv.Itoa(count)
+}
+
+// hunk is a parsed patch hunk
+type hunk struct {
+	oldStart   int // 1-based hint, 0 when unknown
+	old, new   []string
+	oldMissing bool // old side ends without a trailing newline
+	newMissing bool // new side ends without a trailing newline
+}
+
+// parseHunks extracts hunks from a unified diff, skipping surrounding text
+func parseHunks(patch string) ([]hunk, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	var hunks []hunk
+
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "@@") {
+			continue
+		}
+		h, oldCount, newCount, err := parseHunkHeader(lines[i])
+		if err != nil {
+			return nil, err
+		}
+		counted := oldCount >= 0
+		var lastOp byte
+
+		for ; i+1 < len(lines); i++ {
+			line := lines[i+1]
+			if strings.HasPrefix(line, "\\") {
+				h.oldMissing = h.oldMissing || lastOp != '+'
+				h.newMissing = h.newMissing || lastOp != '-'
+				continue
+			}
+			if counted && len(h.old) >= oldCount && len(h.new) >= newCount {
+				break
+			}
+			if line == "" {
+				if !counted {
+					break // a blank line ends a hunk without counts
+				}
+				line = " " // editors and models often strip the context space
+			}
+			op := line[0]
+			if op != ' ' && op != '-' && op != '+' ||
+				!counted && (strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ ")) {
+				break
+			}
+			if op != '+' {
+				h.old = append(h.old, line[1:])
+			}
+			if op != '-' {
+				h.new = append(h.new, line[1:])
+			}
+			lastOp = op
+		}
+		hunks = append(hunks, h)
+	}
+	return hunks, nil
+}
+
+// parseHunkHeader parses "@@ -a,b +c,d @@", returning -1 counts when absent
+func parseHunkHeader(header string) (hunk, int, int, error) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return hunk{}, -1, -1, nil // "@@ ... @@" without ranges
+	}
+	oldStart, oldCount, err := parseHunkRange(fields[1][1:])
+	if err != nil {
+		return hunk{}, 0, 0, fmt.Errorf("bad hunk header %q: %w", header, err)
+	}
+	_, newCount, err := parseHunkRange(fields[2][1:])
+	if err != nil {
+		return hunk{}, 0, 0, fmt.Errorf("bad hunk header %q: %w", header, err)
+	}
+	return hunk{oldStart: oldStart}, oldCount, newCount, nil
+}
+
+func parseHunkRange(r string) (start, count int, err error) {
+	startStr, countStr, hasCount := strings.Cut(r, ",")
+	if start, err = strconv.Atoi(startStr); err != nil {
+		return 0, 0, err
+	}
+	count = 1
+	if hasCount {
+		if count, err = strconv.Atoi(countStr); err != nil {
+			return 0, 0, err
+		}
+	}
+	return start, count, nil
+}
+
+// applyHunks applies hunks in order, locating each by its context
+func applyHunks(target string, hunks []hunk) (string, error) {
+	lines, trailing := splitLines(target)
+	var result []string
+	pos := 0   // next unconsumed target line
+	shift := 0 // offset between hinted and actual positions so far
+
+	for n, h := range hunks {
+		hint := pos
+		if h.oldStart > 0 {
+			hint = max(pos, h.oldStart-1+shift)
+		}
+		at := findHunk(lines, h.old, pos, hint)
+		if at < 0 {
+			return "", fmt.Errorf("hunk %d does not match target", n+1)
+		}
+		if h.oldStart > 0 {
+			shift = at - (h.oldStart - 1)
+		}
+
+		result = append(result, lines[pos:at]...)
+		result = append(result, h.new...)
+		pos = at + len(h.old)
+		if pos == len(lines) {
+			switch {
+			case h.newMissing:
+				trailing = false
+			case h.oldMissing, len(lines) == 0:
+				// the new side ends with a newline (an empty target has none to keep)
+				trailing = true
+			}
+		}
+	}
+	result = append(result, lines[pos:]...)
+
+	out := strings.Join(result, "\n")
+	if trailing && len(result) > 0 {
+		out += "\n"
+	}
+	return out, nil
+}
+
+// findHunk returns where old occurs in lines at or after from, preferring
+// the position closest to hint, or -1
+func findHunk(lines, old []string, from, hint int) int {
+	hint = min(max(hint, from), len(lines))
+	if len(old) == 0 {
+		return hint
+	}
+	for _, equal := range []func(a, b string) bool{
+		func(a, b string) bool { return a == b },
+		func(a, b string) bool { return strings.TrimRight(a, " \t") == strings.TrimRight(b, " \t") },
+	} {
+		for delta := 0; ; delta++ {
+			before, after := hint-delta, hint+delta
+			if before < from && after+len(old) > len(lines) {
+				break
+			}
+			if after+len(old) <= len(lines) && matchAt(lines, old, after, equal) {
+				return after
+			}
+			if delta > 0 && before >= from && before+len(old) <= len(lines) && matchAt(lines, old, before, equal) {
+				return before
+			}
+		}
+	}
+	return -1
+}
+
+func matchAt(lines, old []string, at int, equal func(a, b string) bool) bool {
+	for i, l := range old {
+		if !equal(lines[at+i], l) {
+			return false
+		}
+	}
+	return true
+}
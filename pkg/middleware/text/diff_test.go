@@ -0,0 +1,182 @@
+package text
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		updated  string
+		expected string
+	}{
+		{
+			name:     "unchanged",
+			original: "a\nb\n",
+			updated:  "a\nb\n",
+			expected: "",
+		},
+		{
+			name:     "single change with context",
+			original: "1\n2\n3\n4\n5\n6\n7\n8\n",
+			updated:  "1\n2\n3\n4\nfive\n6\n7\n8\n",
+			expected: "--- original\n+++ updated\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name:     "distant changes make separate hunks",
+			original: "a\n1\n2\n3\n4\n5\n6\n7\nb\n",
+			updated:  "A\n1\n2\n3\n4\n5\n6\n7\nB\n",
+			expected: "--- original\n+++ updated\n@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n@@ -6,4 +6,4 @@\n 5\n 6\n 7\n-b\n+B\n",
+		},
+		{
+			name:     "insert into empty",
+			original: "",
+			updated:  "x\n",
+			expected: "--- original\n+++ updated\n@@ -0,0 +1 @@\n+x\n",
+		},
+		{
+			name:     "missing trailing newline",
+			original: "a\nb",
+			updated:  "a\nb\n",
+			expected: "--- original\n+++ updated\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runTextHandler(t, Diff(tt.original), tt.updated); got != tt.expected {
+				t.Errorf("Diff() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	target := "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"
+
+	tests := []struct {
+		name     string
+		target   string
+		patch    string
+		expected string
+	}{
+		{
+			name:   "exact hunk",
+			target: target,
+			patch: "--- a/main.go\n+++ b/main.go\n@@ -3,3 +3,3 @@\n func main() {\n" +
+				"-\tprintln(\"hi\")\n+\tprintln(\"hello\")\n }\n",
+			expected: "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+		},
+		{
+			name:   "wrong line numbers and surrounding prose",
+			target: target,
+			patch: "Here is the fix:\n```diff\n@@ -40,2 +40,3 @@\n func main() {\n+\tdefer cleanup()\n" +
+				" \tprintln(\"hi\")\n```\n",
+			expected: "package main\n\nfunc main() {\n\tdefer cleanup()\n\tprintln(\"hi\")\n}\n",
+		},
+		{
+			name:     "header without counts",
+			target:   target,
+			patch:    "@@ ... @@\n-package main\n+package app\n",
+			expected: "package app\n\nfunc main() {\n\tprintln(\"hi\")\n}\n",
+		},
+		{
+			name:     "trailing whitespace tolerated",
+			target:   "a  \nb\n",
+			patch:    "@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+			expected: "a\nc\n",
+		},
+		{
+			name:     "stripped blank context line",
+			target:   "a\n\nb\n",
+			patch:    "@@ -1,3 +1,3 @@\n a\n\n-b\n+c\n",
+			expected: "a\n\nc\n",
+		},
+		{
+			name:     "removes trailing newline",
+			target:   "a\nb\n",
+			patch:    "@@ -2 +2 @@\n-b\n+b\n\\ No newline at end of file\n",
+			expected: "a\nb",
+		},
+		{
+			name:     "multiple hunks",
+			target:   "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			patch:    "@@ -1,2 +1,2 @@\n-1\n+one\n 2\n@@ -9,2 +9,2 @@\n 9\n-10\n+ten\n",
+			expected: "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runTextHandler(t, ApplyPatch(tt.target), tt.patch); got != tt.expected {
+				t.Errorf("ApplyPatch() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		wantErr string
+	}{
+		{
+			name:    "no hunks",
+			patch:   "I could not produce a patch.",
+			wantErr: "no hunks",
+		},
+		{
+			name:    "context not found",
+			patch:   "@@ -1 +1 @@\n-missing\n+x\n",
+			wantErr: "hunk 1 does not match",
+		},
+		{
+			name:    "bad header",
+			patch:   "@@ -x +1 @@\n-a\n+b\n",
+			wantErr: "bad hunk header",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			req := calque.NewRequest(context.Background(), strings.NewReader(tt.patch))
+			err := ApplyPatch("a\nb\n").ServeFlow(req, calque.NewResponse(&buf))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ApplyPatch() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDiffApplyPatchRoundTrip(t *testing.T) {
+	pairs := [][2]string{
+		{"a\nb\nc\n", "a\nc\nd\n"},
+		{"", "new\nfile"},
+		{"", "x\n"},
+		{"x\n", ""},
+		{"x\ny", "x\ny\n"},
+		{"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n", "0\n1\n2\n4\n5\n6\n7\n8\n9\n10\n12\n13"},
+		{"same\n", "same\n"},
+	}
+
+	for _, p := range pairs {
+		patch := runTextHandler(t, Diff(p[0]), p[1])
+		if patch == "" {
+			if p[0] != p[1] {
+				t.Errorf("Diff(%q, %q) returned empty patch", p[0], p[1])
+			}
+			continue
+		}
+		if got := runTextHandler(t, ApplyPatch(p[0]), patch); got != p[1] {
+			t.Errorf("round trip %q -> %q: got %q\npatch:\n%s", p[0], p[1], got, patch)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package text
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// FitStrategy selects what FitToTokens keeps of input over its limit.
+type FitStrategy int
+
+const (
+	FitHead      FitStrategy = iota // keep the beginning, e.g. a document's introduction
+	FitTail                         // keep the end, e.g. the latest log lines or messages
+	FitMiddleOut                    // keep the beginning and the end, dropping the middle
+	FitSummary                      // keep the beginning and replace the rest with a summary
+)
+
+// Markers FitToTokens inserts where text was removed; {omitted} becomes the
+// number of tokens removed
+const (
+	DefaultFitMarker     = "\n[... {omitted} tokens omitted ...]\n"
+	DefaultSummaryMarker = "\n[Summary of the remaining {omitted} tokens]\n"
+)
+
+// TokenCounter counts the tokens of text as a model's tokenizer would.
+type TokenCounter func(text string) int
+
+// EstimateTokens approximates a token count at ~4 characters per token. It
+// is the default TokenCounter; pass a real tokenizer to FitOptions.Counter
+// when the limit is close to the model's context window.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// FitOptions configures FitToTokens. The zero value estimates tokens and
+// marks removed text with DefaultFitMarker.
+type FitOptions struct {
+	Counter  TokenCounter // token counter (default: EstimateTokens)
+	Marker   string       // inserted where text was removed (default: DefaultFitMarker, or DefaultSummaryMarker for FitSummary)
+	NoMarker bool         // remove text without a marker
+
+	HeadShare float64 // FitMiddleOut: share of the limit kept from the beginning (default: 0.5)
+
+	// Summarizer receives the text FitSummary removes and writes its summary,
+	// e.g. a flow calling a small model; it is required for FitSummary
+	Summarizer    calque.Handler
+	SummaryTokens int // FitSummary: tokens reserved for the summary (default: a quarter of the limit)
+}
+
+// FitToTokens shortens input to at most limit tokens.
+//
+// Input: string content
+// Output: the input when it fits; otherwise the parts kept by strategy, joined by a marker
+// Behavior: BUFFERED - reads entire input to count its tokens
+//
+// Cuts fall on line breaks where possible, then on whitespace, so lines and
+// words stay whole. The marker counts toward the limit. FitSummary sends the
+// removed text through FitOptions.Summarizer and keeps as much of the
+// beginning as fits beside the summary; a summary longer than SummaryTokens
+// is cut. Use it ahead of a model call so oversized documents, logs or
+// transcripts degrade gracefully instead of failing on the context window.
+//
+// Example:
+//
+//	// Keep the newest log lines within an 8k-token context
+//	flow.Use(text.FitToTokens(6000, text.FitTail, text.FitOptions{Counter: tokenizer.Count})).
+//		Use(prompt.Template("Why did the deploy fail?\n\n{{.Input}}")).
+//		Use(ai.Agent(client))
+func FitToTokens(limit int, strategy FitStrategy, opts ...FitOptions) calque.Handler {
+	cfg := FitOptions{}
+	if len(opts) > 0 {
+		cfg = opts[0]
+	}
+	if cfg.Counter == nil {
+		cfg.Counter = EstimateTokens
+	}
+	if cfg.Marker == "" {
+		cfg.Marker = DefaultFitMarker
+		if strategy == FitSummary {
+			cfg.Marker = DefaultSummaryMarker
+		}
+	}
+	if cfg.HeadShare <= 0 || cfg.HeadShare >= 1 {
+		cfg.HeadShare = 0.5
+	}
+	if cfg.SummaryTokens <= 0 {
+		cfg.SummaryTokens = limit / 4
+	}
+
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var input string
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+		if limit <= 0 {
+			return calque.NewErr(req.Context, "text: FitToTokens limit must be positive")
+		}
+		total := cfg.Counter(input)
+		if total <= limit {
+			return calque.Write(res, input)
+		}
+		f := fitter{cfg: cfg, total: total}
+
+		switch strategy {
+		case FitHead:
+			return calque.Write(res, f.head(input, limit))
+		case FitTail:
+			return calque.Write(res, f.tail(input, limit))
+		case FitMiddleOut:
+			return calque.Write(res, f.middleOut(input, limit))
+		case FitSummary:
+			if cfg.Summarizer == nil {
+				return calque.NewErr(req.Context, "text: FitSummary needs FitOptions.Summarizer")
+			}
+			return f.summary(req, res, input, limit)
+		}
+		return calque.NewErr(req.Context, "text: unknown FitStrategy "+strconv.Itoa(int(strategy)))
+	})
+}
+
+type fitter struct {
+	cfg   FitOptions
+	total int // tokens of the whole input
+}
+
+// marker renders the marker for the tokens removed when keeping kept tokens
+func (f fitter) marker(kept int) string {
+	if f.cfg.NoMarker {
+		return ""
+	}
+	return strings.ReplaceAll(f.cfg.Marker, "{omitted}", strconv.Itoa(max(f.total-kept, 0)))
+}
+
+// budget is the limit left for text once the marker is placed; the marker is
+// dropped when it alone does not fit
+func (f fitter) budget(limit int) (int, string) {
+	// size the marker for the largest possible omission, which the real one never exceeds
+	marker := f.marker(0)
+	if n := f.cfg.Counter(marker); n < limit {
+		return limit - n, marker
+	}
+	return limit, ""
+}
+
+func (f fitter) head(input string, limit int) string {
+	budget, marker := f.budget(limit)
+	kept := input[:f.prefix(input, budget)]
+	if marker != "" {
+		marker = strings.TrimRight(f.marker(f.cfg.Counter(kept)), "\n")
+	}
+	return strings.TrimRightFunc(kept, unicode.IsSpace) + marker
+}
+
+func (f fitter) tail(input string, limit int) string {
+	budget, marker := f.budget(limit)
+	kept := input[f.suffix(input, budget):]
+	if marker != "" {
+		marker = strings.TrimLeft(f.marker(f.cfg.Counter(kept)), "\n")
+	}
+	return marker + strings.TrimLeftFunc(kept, unicode.IsSpace)
+}
+
+func (f fitter) middleOut(input string, limit int) string {
+	budget, marker := f.budget(limit)
+	if marker == "" {
+		// without a marker the two parts still go on separate lines
+		marker = "\n"
+		budget -= f.cfg.Counter(marker)
+	}
+	headEnd := f.prefix(input, int(float64(budget)*f.cfg.HeadShare))
+	head := input[:headEnd]
+	rest := input[headEnd:]
+	tailStart := headEnd + f.suffix(rest, budget-f.cfg.Counter(head))
+	tail := input[tailStart:]
+	if marker != "\n" {
+		marker = f.marker(f.cfg.Counter(head) + f.cfg.Counter(tail))
+	}
+	return strings.TrimRightFunc(head, unicode.IsSpace) + marker + strings.TrimLeftFunc(tail, unicode.IsSpace)
+}
+
+func (f fitter) summary(req *calque.Request, res *calque.Response, input string, limit int) error {
+	reserve := min(f.cfg.SummaryTokens, limit)
+	budget, marker := f.budget(limit - reserve)
+	headEnd := f.prefix(input, budget)
+	head := input[:headEnd]
+
+	var out bytes.Buffer
+	summarize := calque.NewRequest(req.Context, strings.NewReader(input[headEnd:]))
+	if err := f.cfg.Summarizer.ServeFlow(summarize, calque.NewResponse(&out)); err != nil {
+		return calque.WrapErr(req.Context, err, "text: summarizing truncated input failed")
+	}
+	summary := strings.TrimSpace(out.String())
+	summary = strings.TrimRightFunc(summary[:f.prefix(summary, reserve)], unicode.IsSpace)
+
+	if marker != "" {
+		marker = f.marker(f.cfg.Counter(head))
+	}
+	return calque.Write(res, strings.TrimRightFunc(head, unicode.IsSpace)+marker+summary)
+}
+
+// prefix returns the end of the longest prefix within budget tokens, moved
+// back to a line break or space when one is near
+func (f fitter) prefix(s string, budget int) int {
+	if budget <= 0 {
+		return 0
+	}
+	if f.cfg.Counter(s) <= budget {
+		return len(s)
+	}
+	// binary search over rune boundaries for the longest fitting prefix
+	lo, hi := 0, len(s)
+	for lo < hi {
+		mid := runeStart(s, (lo+hi+1)/2)
+		if mid <= lo {
+			mid = lo + runeLen(s, lo)
+		}
+		if f.cfg.Counter(s[:mid]) <= budget {
+			lo = mid
+		} else {
+			hi = runeStart(s, mid-1)
+		}
+	}
+
+	window := s[lo-lo/5 : lo] // look back at most a fifth of the kept text
+	if i := strings.LastIndexByte(window, '\n'); i >= 0 {
+		return lo - len(window) + i + 1
+	}
+	if i := strings.LastIndexFunc(window, unicode.IsSpace); i >= 0 {
+		return lo - len(window) + i + runeLen(window, i)
+	}
+	return lo
+}
+
+// suffix returns the start of the longest suffix within budget tokens, moved
+// forward past a line break or space when one is near
+func (f fitter) suffix(s string, budget int) int {
+	if budget <= 0 {
+		return len(s)
+	}
+	if f.cfg.Counter(s) <= budget {
+		return 0
+	}
+	lo, hi := 0, len(s) // the answer is in (lo, hi]
+	for hi-lo > 1 {
+		mid := runeStart(s, (lo+hi)/2)
+		if mid <= lo {
+			mid = lo + runeLen(s, lo)
+			if mid >= hi {
+				break
+			}
+		}
+		if f.cfg.Counter(s[mid:]) <= budget {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+
+	kept := len(s) - hi
+	window := s[hi : hi+kept/5]
+	if i := strings.IndexByte(window, '\n'); i >= 0 {
+		return hi + i + 1
+	}
+	if i := strings.IndexFunc(window, unicode.IsSpace); i >= 0 {
+		return hi + i + runeLen(window, i)
+	}
+	return hi
+}
+
+// runeStart moves i back to the start of the rune containing it
+func runeStart(s string, i int) int {
+	for i > 0 && i < len(s) && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
+
+func runeLen(s string, i int) int {
+	_, size := utf8.DecodeRuneInString(s[i:])
+	return size
+}
@@ -0,0 +1,117 @@
+package text
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// wordCount counts whitespace-separated words, a tokenizer stand-in with exact arithmetic
+func wordCount(s string) int {
+	return len(strings.Fields(s))
+}
+
+func numberedLines(n int) string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func runFit(t *testing.T, handler calque.Handler, input string) string {
+	t.Helper()
+	var out string
+	if err := calque.NewFlow().Use(handler).Run(context.Background(), input, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestFitToTokens(t *testing.T) {
+	input := numberedLines(10) // 20 words
+	words := FitOptions{Counter: wordCount}
+	bare := FitOptions{Counter: wordCount, NoMarker: true}
+
+	tests := []struct {
+		name     string
+		limit    int
+		strategy FitStrategy
+		opts     FitOptions
+		want     string
+	}{
+		{"fits", 20, FitHead, words, input},
+		{"head", 6, FitHead, bare, "line 1\nline 2\nline 3"},
+		{"tail", 6, FitTail, bare, "line 8\nline 9\nline 10"},
+		{"middle out", 8, FitMiddleOut, bare, "line 1\nline 2\nline 9\nline 10"},
+		{"head with marker", 11, FitHead, words, "line 1\nline 2\nline 3\n[... 14 tokens omitted ...]"},
+		{"tail with marker", 11, FitTail, words, "[... 14 tokens omitted ...]\nline 8\nline 9\nline 10"},
+		{"middle out with marker", 13, FitMiddleOut, words, "line 1\nline 2\n[... 12 tokens omitted ...]\nline 9\nline 10"},
+		{"uneven share", 13, FitMiddleOut, FitOptions{Counter: wordCount, HeadShare: 0.25}, "line 1\n[... 12 tokens omitted ...]\nline 8\nline 9\nline 10"},
+		{"custom marker", 5, FitHead, FitOptions{Counter: wordCount, Marker: " …"}, "line 1\nline 2 …"},
+		{"marker larger than limit", 3, FitHead, words, "line 1\nline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runFit(t, FitToTokens(tt.limit, tt.strategy, tt.opts), input)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if n := wordCount(got); n > tt.limit {
+				t.Errorf("output has %d tokens, over the limit of %d", n, tt.limit)
+			}
+		})
+	}
+}
+
+func TestFitToTokensEstimate(t *testing.T) {
+	input := strings.Repeat("héllo wörld ", 100) // 1200 runes, ~300 tokens
+	for _, strategy := range []FitStrategy{FitHead, FitTail, FitMiddleOut} {
+		got := runFit(t, FitToTokens(50, strategy), input)
+		if n := EstimateTokens(got); n > 50 || n < 40 {
+			t.Errorf("strategy %d: %d tokens, want close to 50: %q", strategy, n, got)
+		}
+		if !strings.Contains(got, "tokens omitted") {
+			t.Errorf("strategy %d: missing marker: %q", strategy, got)
+		}
+		for _, w := range strings.Fields(strings.SplitN(got, "[", 2)[0]) {
+			if w != "héllo" && w != "wörld" {
+				t.Errorf("strategy %d: cut inside a word: %q", strategy, w)
+			}
+		}
+	}
+
+	// no whitespace: cut at a rune boundary
+	if got := runFit(t, FitToTokens(2, FitHead, FitOptions{NoMarker: true}), "ééééééééééé"); got != "éééééééé" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestFitToTokensSummary(t *testing.T) {
+	var summarized string
+	summarizer := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		if err := calque.Read(req, &summarized); err != nil {
+			return err
+		}
+		return calque.Write(res, "lines four to ten count up to ten and then the summary keeps going")
+	})
+
+	opts := FitOptions{Counter: wordCount, Summarizer: summarizer, SummaryTokens: 6}
+	got := runFit(t, FitToTokens(18, FitSummary, opts), numberedLines(10))
+
+	want := "line 1\nline 2\nline 3\n[Summary of the remaining 14 tokens]\nlines four to ten count up"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(summarized, "line 4\n") || !strings.HasSuffix(summarized, "line 10") {
+		t.Errorf("summarizer received %q", summarized)
+	}
+
+	err := calque.NewFlow().Use(FitToTokens(5, FitSummary)).Run(context.Background(), numberedLines(10), new(string))
+	if err == nil || !strings.Contains(err.Error(), "Summarizer") {
+		t.Errorf("missing summarizer: err = %v", err)
+	}
+}
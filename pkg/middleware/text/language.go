@@ -0,0 +1,222 @@
+package text
+
+import (
+	"context"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// MetadataLanguage is the MetadataBus key DetectLanguage stores the detected language under.
+const MetadataLanguage = "text.language"
+
+// LanguageUnknown is reported when no language can be identified.
+const LanguageUnknown = "und"
+
+// languageSampleSize is how much input DetectLanguage inspects before streaming the rest
+const languageSampleSize = 4096
+
+// DetectLanguage identifies the language of the input and records it on the MetadataBus.
+//
+// Input: text (streaming - only the first 4KB is inspected)
+// Output: same as input (pass-through)
+// Behavior: STREAMING - sets MetadataLanguage before writing any output
+//
+// The ISO 639-1 code (e.g. "en", "es", "ja") is stored under MetadataLanguage,
+// or LanguageUnknown when the text is too short or ambiguous. Because the value
+// is set before the first byte is passed on, downstream handlers can read it
+// with Language as soon as they receive input. Without a MetadataBus in the
+// context the input passes through unchanged.
+//
+// Non-Latin scripts are identified by character ranges; Latin-script
+// languages (en, es, fr, de, it, pt, nl, sv, pl, tr) by common-word frequency.
+//
+// Example:
+//
+//	flow.Use(text.DetectLanguage()).
+//		Use(calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+//			if text.Language(req.Context) != "en" {
+//				return ai.Translate(client, "en").ServeFlow(req, res)
+//			}
+//			_, err := io.Copy(res.Data, req.Data)
+//			return err
+//		}))
+func DetectLanguage() calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		sample := make([]byte, languageSampleSize)
+		n, err := io.ReadFull(req.Data, sample)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		sample = sample[:n]
+
+		if mb := calque.GetMetadataBus(req.Context); mb != nil {
+			mb.Set(MetadataLanguage, DetectLanguageCode(string(sample)))
+		}
+
+		if _, err := res.Data.Write(sample); err != nil {
+			return err
+		}
+		_, err = io.Copy(res.Data, req.Data)
+		return err
+	})
+}
+
+// Language returns the language recorded by DetectLanguage, or empty string.
+func Language(ctx context.Context) string {
+	if mb := calque.GetMetadataBus(ctx); mb != nil {
+		lang, _ := mb.GetString(MetadataLanguage)
+		return lang
+	}
+	return ""
+}
+
+// DetectLanguageCode returns the ISO 639-1 code for s, or LanguageUnknown.
+//
+// Example:
+//
+//	text.DetectLanguageCode("¿Dónde está la biblioteca?") // "es"
+func DetectLanguageCode(s string) string {
+	s = strings.ToValidUTF8(s, "") // a sample may end mid-rune
+	if lang := detectScript(s); lang != "" {
+		return lang
+	}
+	return detectLatin(s)
+}
+
+// scriptLanguages maps scripts to the language they most likely indicate
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+}
+
+// detectScript identifies languages written in a non-Latin script
+func detectScript(s string) string {
+	counts := make([]int, len(scriptLanguages))
+	letters := 0
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for i, sl := range scriptLanguages {
+			if unicode.Is(sl.table, r) {
+				counts[i]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	// Japanese mixes kana with Han; any meaningful share of kana means Japanese
+	if kana := counts[0] + counts[1]; kana*10 >= letters {
+		return "ja"
+	}
+	best, bestCount := "", 0
+	for i, c := range counts {
+		if c > bestCount {
+			best, bestCount = scriptLanguages[i].lang, c
+		}
+	}
+	if bestCount*2 < letters {
+		return ""
+	}
+	if best == "ru" && strings.ContainsAny(s, "іїєґІЇЄҐ") {
+		return "uk"
+	}
+	return best
+}
+
+// latinStopwords are high-frequency words that distinguish Latin-script languages
+var latinStopwords = map[string][]string{
+	"en": {"the", "and", "is", "of", "to", "in", "that", "it", "you", "for", "with", "are", "this", "what", "was", "on", "be", "have", "not", "my"},
+	"es": {"el", "la", "de", "que", "y", "en", "los", "es", "por", "un", "una", "las", "del", "para", "con", "no", "está", "se", "mi", "dónde"},
+	"fr": {"le", "la", "les", "de", "et", "est", "un", "une", "des", "du", "que", "en", "pour", "pas", "je", "vous", "dans", "qui", "ce", "où"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "zu", "den", "mit", "ein", "eine", "es", "sie", "auf", "für", "wie", "wo", "mein", "sind"},
+	"it": {"il", "di", "che", "è", "la", "e", "per", "un", "non", "sono", "una", "gli", "del", "della", "con", "mi", "dove", "ho", "questo", "le"},
+	"pt": {"o", "a", "de", "que", "e", "do", "da", "em", "um", "uma", "não", "os", "para", "com", "é", "está", "você", "onde", "meu", "eu"},
+	"nl": {"de", "het", "een", "en", "van", "is", "niet", "ik", "dat", "op", "te", "zijn", "met", "voor", "waar", "mijn", "je", "wat", "hoe", "er"},
+	"sv": {"och", "att", "det", "är", "en", "som", "på", "jag", "inte", "för", "med", "har", "var", "den", "de", "min", "vad", "hur", "till", "av"},
+	"pl": {"i", "w", "nie", "jest", "się", "na", "że", "to", "z", "do", "jak", "co", "gdzie", "mój", "ten", "czy", "jestem", "są", "dla", "od"},
+	"tr": {"bir", "ve", "bu", "için", "ne", "değil", "ben", "mi", "nerede", "da", "de", "çok", "ile", "benim", "var", "gibi", "sen", "o", "ama", "nasıl"},
+}
+
+// detectLatin scores Latin-script text against common words per language
+func detectLatin(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(words) == 0 {
+		return LanguageUnknown
+	}
+
+	scores := make(map[string]int, len(latinStopwords))
+	for _, w := range words {
+		for lang, stops := range latinStopwords {
+			for _, stop := range stops {
+				if w == stop {
+					scores[lang]++
+					break
+				}
+			}
+		}
+	}
+
+	best, bestScore, runnerUp := LanguageUnknown, 0, 0
+	for _, lang := range latinLanguageOrder {
+		switch score := scores[lang]; {
+		case score > bestScore:
+			best, bestScore, runnerUp = lang, score, bestScore
+		case score > runnerUp:
+			runnerUp = score
+		}
+	}
+	if bestScore == 0 || bestScore == runnerUp {
+		return refineByDiacritics(s, best, bestScore == runnerUp && bestScore > 0)
+	}
+	return best
+}
+
+// latinLanguageOrder fixes iteration order so ties resolve deterministically
+var latinLanguageOrder = []string{"en", "es", "fr", "de", "it", "pt", "nl", "sv", "pl", "tr"}
+
+// refineByDiacritics breaks ties using characters specific to one language
+func refineByDiacritics(s, fallback string, tied bool) string {
+	markers := []struct {
+		chars string
+		lang  string
+	}{
+		{"ñ¿¡", "es"},
+		{"ãõ", "pt"},
+		{"ßäöü", "de"},
+		{"çèêëàâœ", "fr"},
+		{"åø", "sv"},
+		{"ąęłńśźż", "pl"},
+		{"ğışİ", "tr"},
+	}
+	lower := strings.ToLower(s)
+	for _, m := range markers {
+		if strings.ContainsAny(lower, m.chars) {
+			return m.lang
+		}
+	}
+	if tied {
+		return fallback
+	}
+	return LanguageUnknown
+}
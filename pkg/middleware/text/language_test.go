@@ -0,0 +1,73 @@
+package text
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestDetectLanguageCode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"What is the capital of France and how big is it?", "en"},
+		{"¿Dónde está la biblioteca de la ciudad?", "es"},
+		{"Où est la gare, s'il vous plaît? Je ne sais pas.", "fr"},
+		{"Ich weiß nicht, wo der Bahnhof ist und wie ich dorthin komme.", "de"},
+		{"Dove si trova la stazione? Non lo so, mi dispiace.", "it"},
+		{"Onde fica a estação? Eu não sei, você sabe?", "pt"},
+		{"Waar is het station? Ik weet het niet.", "nl"},
+		{"東京は日本の首都です。とても大きな都市です。", "ja"},
+		{"北京是中国的首都。", "zh"},
+		{"서울은 한국의 수도입니다.", "ko"},
+		{"Москва — столица России.", "ru"},
+		{"Київ є столицею України.", "uk"},
+		{"القاهرة هي عاصمة مصر", "ar"},
+		{"12345 !!!", LanguageUnknown},
+		{"", LanguageUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want+"/"+tt.input, func(t *testing.T) {
+			if got := DetectLanguageCode(tt.input); got != tt.want {
+				t.Errorf("DetectLanguageCode(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	input := "¿Dónde está la biblioteca? " + strings.Repeat("texto ", 2000)
+
+	mb := calque.NewMetadataBus(0)
+	defer mb.Close()
+	ctx := calque.WithMetadataBus(context.Background(), mb)
+
+	var buf bytes.Buffer
+	if err := DetectLanguage().ServeFlow(calque.NewRequest(ctx, strings.NewReader(input)), calque.NewResponse(&buf)); err != nil {
+		t.Fatalf("DetectLanguage() error = %v", err)
+	}
+	if buf.String() != input {
+		t.Error("DetectLanguage() did not pass input through unchanged")
+	}
+	if got := Language(ctx); got != "es" {
+		t.Errorf("Language() = %q, want %q", got, "es")
+	}
+}
+
+func TestDetectLanguageWithoutMetadataBus(t *testing.T) {
+	var buf bytes.Buffer
+	if err := DetectLanguage().ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("hello")), calque.NewResponse(&buf)); err != nil {
+		t.Fatalf("DetectLanguage() error = %v", err)
+	}
+	if buf.String() != "hello" {
+		t.Errorf("output = %q, want %q", buf.String(), "hello")
+	}
+	if got := Language(context.Background()); got != "" {
+		t.Errorf("Language() = %q, want empty", got)
+	}
+}
@@ -0,0 +1,507 @@
+package text
+
+import (
+	"html"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// MarkdownToHTML renders Markdown to HTML.
+//
+// Input: Markdown text (buffered - reads entire input into memory)
+// Output: HTML fragment
+// Behavior: BUFFERED - block structure needs the whole document
+//
+// Supports the Markdown LLMs commonly produce: ATX and setext headings,
+// paragraphs, fenced code blocks (with a language-* class), ordered and
+// unordered lists, blockquotes, horizontal rules, emphasis, strong,
+// strikethrough, inline code, links and images. Text is HTML-escaped and raw
+// HTML in the input is escaped rather than passed through.
+//
+// Example:
+//
+//	flow.Use(ai.Agent(client)).
+//		Use(text.MarkdownToHTML())
+func MarkdownToHTML() calque.Handler {
+	return Transform(func(s string) string {
+		return renderMarkdownBlocks(parseMarkdown(s), true)
+	})
+}
+
+// StripMarkdown removes Markdown syntax, leaving plain text.
+//
+// Input: Markdown text (buffered - reads entire input into memory)
+// Output: plain text with one blank line between blocks
+// Behavior: BUFFERED - block structure needs the whole document
+//
+// Headings, emphasis and quote markers are dropped, links keep their text,
+// images keep their alt text, code blocks keep their contents and list items
+// are kept one per line. Useful before text-to-speech, SMS, or token counting.
+//
+// Example:
+//
+//	flow.Use(ai.Agent(client)).
+//		Use(text.StripMarkdown())
+func StripMarkdown() calque.Handler {
+	return Transform(func(s string) string {
+		return renderMarkdownBlocks(parseMarkdown(s), false)
+	})
+}
+
+// ExtractCodeBlocks outputs the contents of fenced code blocks.
+//
+// Input: Markdown text (buffered - reads entire input into memory)
+// Output: code from matching blocks, separated by a blank line
+// Behavior: BUFFERED - reads entire input to find blocks
+//
+// Only blocks tagged with lang are kept (case-insensitive); an empty lang keeps
+// every block. Output is empty when nothing matches. Useful for pulling
+// executable code or JSON out of a model's explanatory answer.
+//
+// Example:
+//
+//	flow.Use(ai.Agent(client)).
+//		Use(text.ExtractCodeBlocks("go")).
+//		Use(runGoHandler)
+func ExtractCodeBlocks(lang string) calque.Handler {
+	return Transform(func(s string) string {
+		var blocks []string
+		collectCodeBlocks(parseMarkdown(s), lang, &blocks)
+		return strings.Join(blocks, "\n\n")
+	})
+}
+
+// markdownEscapable lists the characters a backslash makes literal
+const markdownEscapable = "\\`*_{}[]()#+-.!~|<>"
+
+type mdKind int
+
+const (
+	mdParagraph mdKind = iota
+	mdHeading
+	mdCode
+	mdList
+	mdQuote
+	mdRule
+)
+
+// mdBlock is a parsed Markdown block
+type mdBlock struct {
+	kind     mdKind
+	level    int      // heading level
+	lang     string   // code block language
+	ordered  bool     // ordered list
+	lines    []string // paragraph/heading/code lines, or list items
+	children []mdBlock
+}
+
+// parseMarkdown splits a document into blocks
+func parseMarkdown(src string) []mdBlock {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var blocks []mdBlock
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			i++
+		case isFence(trimmed):
+			block, next := parseFence(lines, i)
+			blocks = append(blocks, block)
+			i = next
+		case headingLevel(trimmed) > 0:
+			level := headingLevel(trimmed)
+			text := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(trimmed[level:]), "#"))
+			blocks = append(blocks, mdBlock{kind: mdHeading, level: level, lines: []string{text}})
+			i++
+		case isRule(trimmed):
+			blocks = append(blocks, mdBlock{kind: mdRule})
+			i++
+		case strings.HasPrefix(trimmed, ">"):
+			var inner []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				l := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				inner = append(inner, strings.TrimPrefix(l, " "))
+			}
+			blocks = append(blocks, mdBlock{kind: mdQuote, children: parseMarkdown(strings.Join(inner, "\n"))})
+		case listMarker(trimmed) > 0:
+			block, next := parseList(lines, i)
+			blocks = append(blocks, block)
+			i = next
+		default:
+			block, next := parseParagraph(lines, i)
+			blocks = append(blocks, block)
+			i = next
+		}
+	}
+	return blocks
+}
+
+func isFence(trimmed string) bool {
+	return strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+}
+
+// parseFence reads a fenced code block starting at lines[start]
+func parseFence(lines []string, start int) (mdBlock, int) {
+	opening := strings.TrimSpace(lines[start])
+	marker := opening[:3]
+	info := strings.Fields(strings.TrimLeft(opening, marker[:1]))
+	block := mdBlock{kind: mdCode}
+	if len(info) > 0 {
+		block.lang = info[0]
+	}
+
+	i := start + 1
+	for ; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), marker) {
+			return block, i + 1
+		}
+		block.lines = append(block.lines, lines[i])
+	}
+	return block, i // unterminated fences run to the end of the document
+}
+
+// headingLevel returns the ATX heading level of a line, or 0
+func headingLevel(trimmed string) int {
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(trimmed) && trimmed[level] != ' ') {
+		return 0
+	}
+	return level
+}
+
+// isRule reports whether a line is a horizontal rule such as "---" or "* * *"
+func isRule(trimmed string) bool {
+	compact := strings.ReplaceAll(trimmed, " ", "")
+	if len(compact) < 3 {
+		return false
+	}
+	return strings.Count(compact, compact[:1]) == len(compact) && strings.ContainsAny(compact[:1], "-*_")
+}
+
+// listMarker returns the length of a list item marker ("- ", "1. ") or 0
+func listMarker(trimmed string) int {
+	if len(trimmed) >= 2 && strings.ContainsRune("-*+", rune(trimmed[0])) && trimmed[1] == ' ' {
+		return 2
+	}
+	digits := 0
+	for digits < len(trimmed) && trimmed[digits] >= '0' && trimmed[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 && digits+1 < len(trimmed) && (trimmed[digits] == '.' || trimmed[digits] == ')') && trimmed[digits+1] == ' ' {
+		return digits + 2
+	}
+	return 0
+}
+
+// parseList reads consecutive list items; indented or lazy lines continue the current item
+func parseList(lines []string, start int) (mdBlock, int) {
+	first := strings.TrimSpace(lines[start])
+	block := mdBlock{kind: mdList, ordered: first[0] >= '0' && first[0] <= '9'}
+
+	i := start
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" {
+			if i+1 < len(lines) && listMarker(strings.TrimSpace(lines[i+1])) > 0 {
+				continue
+			}
+			break
+		}
+		if n := listMarker(trimmed); n > 0 {
+			if ordered := trimmed[0] >= '0' && trimmed[0] <= '9'; ordered != block.ordered {
+				break // switching between bullets and numbers starts a new list
+			}
+			block.lines = append(block.lines, trimmed[n:])
+			continue
+		}
+		if isFence(trimmed) || headingLevel(trimmed) > 0 || strings.HasPrefix(trimmed, ">") {
+			break
+		}
+		block.lines[len(block.lines)-1] += " " + trimmed
+	}
+	return block, i
+}
+
+// parseParagraph reads lines up to the next blank line or block start.
+// A following "===" or "---" line turns the paragraph into a setext heading.
+func parseParagraph(lines []string, start int) (mdBlock, int) {
+	block := mdBlock{kind: mdParagraph}
+	i := start
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if i > start {
+			if trimmed == "" || isFence(trimmed) || headingLevel(trimmed) > 0 || strings.HasPrefix(trimmed, ">") || listMarker(trimmed) > 0 {
+				break
+			}
+			if strings.Trim(trimmed, "=") == "" || strings.Trim(trimmed, "-") == "" {
+				block.kind = mdHeading
+				block.level = 1
+				if trimmed[0] == '-' {
+					block.level = 2
+				}
+				block.lines = []string{strings.Join(block.lines, " ")}
+				return block, i + 1
+			}
+			if isRule(trimmed) {
+				break
+			}
+		}
+		block.lines = append(block.lines, trimmed)
+	}
+	return block, i
+}
+
+// renderMarkdownBlocks renders blocks as HTML or plain text
+func renderMarkdownBlocks(blocks []mdBlock, asHTML bool) string {
+	parts := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		if part := renderMarkdownBlock(b, asHTML); part != "" || asHTML {
+			parts = append(parts, part)
+		}
+	}
+	if asHTML {
+		return strings.Join(parts, "\n")
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func renderMarkdownBlock(b mdBlock, asHTML bool) string {
+	switch b.kind {
+	case mdHeading:
+		text := renderInline(b.lines[0], asHTML)
+		if !asHTML {
+			return text
+		}
+		tag := "h" + string(rune('0'+b.level))
+		return "<" + tag + ">" + text + "</" + tag + ">"
+	case mdCode:
+		code := strings.Join(b.lines, "\n")
+		if !asHTML {
+			return code
+		}
+		class := ""
+		if b.lang != "" {
+			class = ` class="language-` + html.EscapeString(b.lang) + `"`
+		}
+		return "<pre><code" + class + ">" + html.EscapeString(code) + "\n</code></pre>"
+	case mdList:
+		items := make([]string, len(b.lines))
+		for i, item := range b.lines {
+			items[i] = renderInline(item, asHTML)
+		}
+		if !asHTML {
+			return strings.Join(items, "\n")
+		}
+		tag := "ul"
+		if b.ordered {
+			tag = "ol"
+		}
+		return "<" + tag + ">\n<li>" + strings.Join(items, "</li>\n<li>") + "</li>\n</" + tag + ">"
+	case mdQuote:
+		inner := renderMarkdownBlocks(b.children, asHTML)
+		if !asHTML {
+			return inner
+		}
+		return "<blockquote>\n" + inner + "\n</blockquote>"
+	case mdRule:
+		if asHTML {
+			return "<hr>"
+		}
+		return ""
+	default:
+		text := renderInline(strings.Join(b.lines, "\n"), asHTML)
+		if !asHTML {
+			return text
+		}
+		return "<p>" + text + "</p>"
+	}
+}
+
+// renderInline renders inline Markdown spans as HTML or plain text
+func renderInline(s string, asHTML bool) string {
+	var out strings.Builder
+	text := func(t string) {
+		if asHTML {
+			out.WriteString(html.EscapeString(t))
+		} else {
+			out.WriteString(t)
+		}
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(markdownEscapable, s[i+1]) >= 0:
+			text(s[i+1 : i+2])
+			i += 2
+			continue
+		case c == '`':
+			if code, n, ok := codeSpan(s[i:]); ok {
+				if asHTML {
+					out.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				} else {
+					out.WriteString(code)
+				}
+				i += n
+				continue
+			}
+		case c == '!' && strings.HasPrefix(s[i+1:], "["):
+			if label, url, n, ok := linkSpan(s[i+1:]); ok {
+				if asHTML {
+					out.WriteString(`<img src="` + html.EscapeString(url) + `" alt="` + html.EscapeString(label) + `">`)
+				} else {
+					out.WriteString(label)
+				}
+				i += 1 + n
+				continue
+			}
+		case c == '[':
+			if label, url, n, ok := linkSpan(s[i:]); ok {
+				inner := renderInline(label, asHTML)
+				if asHTML {
+					out.WriteString(`<a href="` + html.EscapeString(url) + `">` + inner + "</a>")
+				} else {
+					out.WriteString(inner)
+				}
+				i += n
+				continue
+			}
+		case c == '~' && strings.HasPrefix(s[i:], "~~"):
+			if inner, n, ok := delimited(s[i:], "~~"); ok {
+				wrapInline(&out, "del", renderInline(inner, asHTML), asHTML)
+				i += n
+				continue
+			}
+		case c == '*' || c == '_':
+			if c == '_' && i > 0 && isWordByte(s[i-1]) {
+				break // intraword underscores such as snake_case stay literal
+			}
+			if tag, inner, n, ok := emphasis(s[i:]); ok {
+				wrapInline(&out, tag, renderInline(inner, asHTML), asHTML)
+				i += n
+				continue
+			}
+		}
+		text(s[i : i+1])
+		i++
+	}
+	return out.String()
+}
+
+func wrapInline(out *strings.Builder, tag, inner string, asHTML bool) {
+	if !asHTML {
+		out.WriteString(inner)
+		return
+	}
+	out.WriteString("<" + tag + ">" + inner + "</" + tag + ">")
+}
+
+// codeSpan parses a backtick code span at the start of s
+func codeSpan(s string) (code string, n int, ok bool) {
+	ticks := 0
+	for ticks < len(s) && s[ticks] == '`' {
+		ticks++
+	}
+	fence := s[:ticks]
+	end := strings.Index(s[ticks:], fence)
+	if end < 0 {
+		return "", 0, false
+	}
+	code = s[ticks : ticks+end]
+	if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' {
+		code = code[1 : len(code)-1]
+	}
+	return code, ticks + end + ticks, true
+}
+
+// linkSpan parses "[label](url)" at the start of s
+func linkSpan(s string) (label, url string, n int, ok bool) {
+	depth := 0
+	closeBracket := -1
+	for i := 0; i < len(s) && closeBracket < 0; i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closeBracket = i
+			}
+		}
+	}
+	if closeBracket < 0 || closeBracket+1 >= len(s) || s[closeBracket+1] != '(' {
+		return "", "", 0, false
+	}
+	closeParen := strings.IndexByte(s[closeBracket+2:], ')')
+	if closeParen < 0 {
+		return "", "", 0, false
+	}
+	target := strings.TrimSpace(s[closeBracket+2 : closeBracket+2+closeParen])
+	if fields := strings.Fields(target); len(fields) > 0 {
+		target = fields[0] // drop an optional "title"
+	}
+	return s[1:closeBracket], strings.Trim(target, "<>"), closeBracket + 3 + closeParen, true
+}
+
+// delimited parses "<delim>inner<delim>" at the start of s
+func delimited(s, delim string) (inner string, n int, ok bool) {
+	rest := s[len(delim):]
+	if rest == "" || rest[0] == ' ' {
+		return "", 0, false
+	}
+	end := strings.Index(rest, delim)
+	if end <= 0 || rest[end-1] == ' ' {
+		return "", 0, false
+	}
+	return rest[:end], len(delim)*2 + end, true
+}
+
+// emphasis parses *em*, **strong** or ***both*** (or the underscore forms)
+func emphasis(s string) (tag, inner string, n int, ok bool) {
+	c := s[0]
+	run := 0
+	for run < len(s) && s[run] == c && run < 3 {
+		run++
+	}
+	for ; run > 0; run-- {
+		if inner, n, ok := delimited(s, strings.Repeat(string(c), run)); ok {
+			if c == '_' && len(s) > n && isWordByte(s[n]) {
+				continue
+			}
+			switch run {
+			case 1:
+				return "em", inner, n, true
+			case 2:
+				return "strong", inner, n, true
+			default:
+				return "strong", strings.Repeat(string(c), 1) + inner + strings.Repeat(string(c), 1), n, true
+			}
+		}
+	}
+	return "", "", 0, false
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// collectCodeBlocks appends the code of blocks tagged lang, including inside quotes
+func collectCodeBlocks(blocks []mdBlock, lang string, out *[]string) {
+	for _, b := range blocks {
+		switch b.kind {
+		case mdCode:
+			if lang == "" || strings.EqualFold(b.lang, lang) {
+				*out = append(*out, strings.Join(b.lines, "\n"))
+			}
+		case mdQuote:
+			collectCodeBlocks(b.children, lang, out)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package text
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func runTextHandler(t *testing.T, h calque.Handler, input string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	req := calque.NewRequest(context.Background(), strings.NewReader(input))
+	if err := h.ServeFlow(req, calque.NewResponse(&buf)); err != nil {
+		t.Fatalf("ServeFlow() error = %v", err)
+	}
+	return buf.String()
+}
+
+func TestMarkdownToHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "headings",
+			input:    "# Title\n## Sub ##\nSetext\n===",
+			expected: "<h1>Title</h1>\n<h2>Sub</h2>\n<h1>Setext</h1>",
+		},
+		{
+			name:     "paragraph with inline spans",
+			input:    "Some **bold**, *em*, ~~gone~~ and `a<b`\nwith [a link](https://go.dev \"Go\").",
+			expected: "<p>Some <strong>bold</strong>, <em>em</em>, <del>gone</del> and <code>a&lt;b</code>\nwith <a href=\"https://go.dev\">a link</a>.</p>",
+		},
+		{
+			name:     "snake_case stays literal",
+			input:    "call my_func_name now",
+			expected: "<p>call my_func_name now</p>",
+		},
+		{
+			name:     "fenced code",
+			input:    "```go\nfmt.Println(\"<hi>\")\n```",
+			expected: "<pre><code class=\"language-go\">fmt.Println(&#34;&lt;hi&gt;&#34;)\n</code></pre>",
+		},
+		{
+			name:     "lists",
+			input:    "- one\n- two\n  continued\n\n1. first\n2. second",
+			expected: "<ul>\n<li>one</li>\n<li>two continued</li>\n</ul>\n<ol>\n<li>first</li>\n<li>second</li>\n</ol>",
+		},
+		{
+			name:     "blockquote and rule",
+			input:    "> quoted *text*\n\n---",
+			expected: "<blockquote>\n<p>quoted <em>text</em></p>\n</blockquote>\n<hr>",
+		},
+		{
+			name:     "image and escaped html",
+			input:    "![logo](logo.png) <script>\\*not em\\*",
+			expected: "<p><img src=\"logo.png\" alt=\"logo\"> &lt;script&gt;*not em*</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runTextHandler(t, MarkdownToHTML(), tt.input); got != tt.expected {
+				t.Errorf("MarkdownToHTML() =\n%s\nwant\n%s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStripMarkdown(t *testing.T) {
+	input := "# Answer\n\nThe **capital** is [Paris](https://en.wikipedia.org/wiki/Paris).\n\n- `go` tools\n- _fast_\n\n> note\n\n```\ncode here\n```\n\n***"
+	expected := "Answer\n\nThe capital is Paris.\n\ngo tools\nfast\n\nnote\n\ncode here"
+
+	if got := runTextHandler(t, StripMarkdown(), input); got != expected {
+		t.Errorf("StripMarkdown() =\n%q\nwant\n%q", got, expected)
+	}
+}
+
+func TestExtractCodeBlocks(t *testing.T) {
+	input := "Here is Go:\n\n```go\npackage main\n```\n\nAnd JSON:\n\n~~~json\n{\"a\": 1}\n~~~\n\n> ```Go\n> func x() {}\n> ```"
+
+	tests := []struct {
+		name     string
+		lang     string
+		expected string
+	}{
+		{name: "by language", lang: "go", expected: "package main\n\nfunc x() {}"},
+		{name: "other language", lang: "json", expected: "{\"a\": 1}"},
+		{name: "all blocks", lang: "", expected: "package main\n\n{\"a\": 1}\n\nfunc x() {}"},
+		{name: "no match", lang: "python", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runTextHandler(t, ExtractCodeBlocks(tt.lang), input); got != tt.expected {
+				t.Errorf("ExtractCodeBlocks(%q) = %q, want %q", tt.lang, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package text
+
+import (
+	"bufio"
+	"io"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// NormalizeOptions configures Normalize. The zero value applies every step
+// except lowercasing.
+type NormalizeOptions struct {
+	Lowercase      bool // lowercase every character
+	KeepWhitespace bool // leave whitespace as is instead of collapsing it
+	KeepNewlines   bool // collapse whitespace runs containing a line break to "\n" instead of " "
+	KeepControl    bool // leave control and zero-width characters in place
+}
+
+// Normalize puts text into a canonical form, e.g. before caching or dedup.
+//
+// Input: string content (streaming)
+// Output: NFC-normalized input without control characters, with whitespace
+// collapsed and optionally lowercased
+// Behavior: STREAMING - one pass over the input; only an unfinished
+// combining sequence or whitespace run is held back
+//
+// Unicode is normalized to NFC, so "é" typed as e + U+0301 equals the
+// precomposed character. Control characters (other than whitespace),
+// zero-width spaces and byte order marks are removed. Each run of whitespace
+// becomes a single space, and leading and trailing whitespace is dropped.
+// Invalid UTF-8 becomes U+FFFD.
+//
+// Example:
+//
+//	// Equivalent questions share one cache entry
+//	cacheM := cache.NewCache()
+//	flow.Use(text.Normalize(text.NormalizeOptions{Lowercase: true})).
+//		Use(cacheM.Cache(ai.Agent(client), time.Hour))
+func Normalize(opts NormalizeOptions) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		reader := bufio.NewReader(norm.NFC.Reader(req.Data))
+		writer := bufio.NewWriter(res.Data)
+
+		started := false // a non-space character was written
+		space := false   // a whitespace run is pending
+		newline := false // the pending run contains a line break
+
+		for {
+			r, _, err := reader.ReadRune()
+			if err == io.EOF {
+				return writer.Flush()
+			}
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case !opts.KeepWhitespace && unicode.IsSpace(r):
+				space = true
+				newline = newline || r == '\n' || r == '\r'
+				continue
+			case !opts.KeepControl && isInvisible(r):
+				continue
+			}
+
+			if space && started {
+				sep := byte(' ')
+				if opts.KeepNewlines && newline {
+					sep = '\n'
+				}
+				if err := writer.WriteByte(sep); err != nil {
+					return err
+				}
+			}
+			space, newline, started = false, false, true
+
+			if opts.Lowercase {
+				r = unicode.ToLower(r)
+			}
+			if _, err := writer.WriteRune(r); err != nil {
+				return err
+			}
+			if reader.Buffered() == 0 {
+				if err := writer.Flush(); err != nil {
+					return err
+				}
+			}
+		}
+	})
+}
+
+// isInvisible reports non-whitespace control characters, zero-width spaces
+// and byte order marks
+func isInvisible(r rune) bool {
+	switch r {
+	case '\u200b', '\u2060', '\ufeff':
+		return true
+	}
+	return unicode.IsControl(r) && !unicode.IsSpace(r)
+}
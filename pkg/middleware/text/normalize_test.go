@@ -0,0 +1,30 @@
+package text
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     NormalizeOptions
+		input    string
+		expected string
+	}{
+		{name: "nfc", input: "cafe\u0301", expected: "caf\u00e9"},
+		{name: "collapse whitespace", input: "  hello \t\n  world  \n", expected: "hello world"},
+		{name: "strip control", input: "a\x00b\x1bc\u200bd\ufeff", expected: "abcd"},
+		{name: "lowercase", opts: NormalizeOptions{Lowercase: true}, input: "Hello ÉCOLE", expected: "hello école"},
+		{name: "keep newlines", opts: NormalizeOptions{KeepNewlines: true}, input: "line one  \n\n line two", expected: "line one\nline two"},
+		{name: "keep whitespace", opts: NormalizeOptions{KeepWhitespace: true}, input: " a\t b\n", expected: " a\t b\n"},
+		{name: "keep control", opts: NormalizeOptions{KeepControl: true}, input: "a\x00b", expected: "a\x00b"},
+		{name: "invalid utf8", input: "a\xffb", expected: "a\ufffdb"},
+		{name: "empty", input: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runChunked(t, Normalize(tt.opts), tt.input); got != tt.expected {
+				t.Errorf("Normalize() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package text
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultRolePrefixes are role labels some models echo before their answer.
+var DefaultRolePrefixes = []string{"Assistant:", "assistant:", "ASSISTANT:", "AI:"}
+
+// PostProcessConfig configures PostProcess.
+type PostProcessConfig struct {
+	StopSequences []string // output ends before the first occurrence of any of these
+	StripPrefixes []string // leading artifacts removed, e.g. DefaultRolePrefixes
+	StripTags     []string // wrapper elements removed, e.g. "answer" for <answer>...</answer>
+}
+
+// PostProcess cleans up model output as it streams.
+//
+// Input: string content (streaming)
+// Output: input cut at the first stop sequence, without leading artifacts,
+// wrapper tags or broken UTF-8
+// Behavior: STREAMING - only bytes that may start a stop sequence, an
+// artifact or a split UTF-8 character are held back
+//
+// Leading whitespace, StripPrefixes and opening StripTags are removed in any
+// order at the start of the output. When an opening tag was removed, its
+// closing tag ends the output like a stop sequence. A UTF-8 character split
+// across chunks is written whole; invalid bytes become U+FFFD. Input after a
+// stop sequence is read and discarded.
+//
+// Example:
+//
+//	flow.Use(ai.Agent(client)).
+//		Use(text.PostProcess(text.PostProcessConfig{
+//			StopSequences: []string{"\nUser:", "</s>"},
+//			StripPrefixes: text.DefaultRolePrefixes,
+//			StripTags:     []string{"answer"},
+//		}))
+func PostProcess(config PostProcessConfig) calque.Handler {
+	var leading []string
+	leading = append(leading, config.StripPrefixes...)
+	for _, tag := range config.StripTags {
+		leading = append(leading, "<"+tag+">")
+	}
+
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		p := &postProcessor{
+			w:       res.Data,
+			stops:   append([]string(nil), config.StopSequences...),
+			leading: leading,
+			started: len(leading) == 0,
+		}
+		buf := make([]byte, 4096)
+		for {
+			n, readErr := req.Data.Read(buf)
+			if n > 0 {
+				done, err := p.write(buf[:n])
+				if err != nil {
+					return err
+				}
+				if done {
+					_, err := io.Copy(io.Discard, req.Data)
+					return err
+				}
+			}
+			if readErr == io.EOF {
+				return p.flush()
+			}
+			if readErr != nil {
+				return readErr
+			}
+		}
+	})
+}
+
+// StopAt ends the output before the first occurrence of any stop sequence.
+//
+// Input: string content (streaming)
+// Output: input up to, not including, the first stop sequence
+// Behavior: STREAMING - holds back only a possible partial stop sequence
+//
+// Example:
+//
+//	// Models without native stop support may continue the dialogue
+//	flow.Use(ai.Agent(client)).
+//		Use(text.StopAt("\nUser:", "\nHuman:"))
+func StopAt(sequences ...string) calque.Handler {
+	return PostProcess(PostProcessConfig{StopSequences: sequences})
+}
+
+type postProcessor struct {
+	w       io.Writer
+	stops   []string
+	leading []string
+	started bool   // leading artifacts have been handled
+	pending []byte // held back bytes
+}
+
+// write processes a chunk and reports whether a stop sequence was reached
+func (p *postProcessor) write(chunk []byte) (bool, error) {
+	p.pending = append(p.pending, chunk...)
+	if !p.started && !p.stripLeading(false) {
+		return false, nil
+	}
+
+	if idx := p.firstStop(); idx >= 0 {
+		return true, p.emit(p.pending[:idx])
+	}
+
+	keep := p.stopPrefixSuffix()
+	if r := incompleteRuneSuffix(p.pending[:len(p.pending)-keep]); r > 0 {
+		keep += r
+	}
+	if err := p.emit(p.pending[:len(p.pending)-keep]); err != nil {
+		return false, err
+	}
+	p.pending = append(p.pending[:0], p.pending[len(p.pending)-keep:]...)
+	return false, nil
+}
+
+// flush writes what is held back at the end of input
+func (p *postProcessor) flush() error {
+	if !p.started {
+		p.stripLeading(true)
+	}
+	if idx := p.firstStop(); idx >= 0 {
+		return p.emit(p.pending[:idx])
+	}
+	return p.emit(p.pending)
+}
+
+// stripLeading removes leading whitespace and artifacts from pending, reporting
+// whether the start of the output is settled. At EOF a partial artifact is kept.
+func (p *postProcessor) stripLeading(eof bool) bool {
+	for {
+		p.pending = bytes.TrimLeftFunc(p.pending, unicode.IsSpace)
+		if len(p.pending) == 0 && !eof {
+			return false
+		}
+
+		matched, partial := false, false
+		for _, artifact := range p.leading {
+			if bytes.HasPrefix(p.pending, []byte(artifact)) {
+				p.pending = p.pending[len(artifact):]
+				if strings.HasPrefix(artifact, "<") && strings.HasSuffix(artifact, ">") {
+					p.stops = append(p.stops, "</"+artifact[1:])
+				}
+				matched = true
+				break
+			}
+			if strings.HasPrefix(artifact, string(p.pending)) {
+				partial = true
+			}
+		}
+		if matched {
+			continue
+		}
+		if partial && !eof {
+			return false
+		}
+		p.started = true
+		return true
+	}
+}
+
+// firstStop returns the index of the earliest stop sequence in pending, or -1
+func (p *postProcessor) firstStop() int {
+	first := -1
+	for _, stop := range p.stops {
+		if stop == "" {
+			continue
+		}
+		if idx := bytes.Index(p.pending, []byte(stop)); idx >= 0 && (first < 0 || idx < first) {
+			first = idx
+		}
+	}
+	return first
+}
+
+// stopPrefixSuffix returns the length of the longest suffix of pending that
+// could be the start of a stop sequence
+func (p *postProcessor) stopPrefixSuffix() int {
+	longest := 0
+	for _, stop := range p.stops {
+		for n := min(len(stop)-1, len(p.pending)); n > longest; n-- {
+			if bytes.HasSuffix(p.pending, []byte(stop[:n])) {
+				longest = n
+				break
+			}
+		}
+	}
+	return longest
+}
+
+func (p *postProcessor) emit(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	_, err := p.w.Write(bytes.ToValidUTF8(b, []byte("�")))
+	return err
+}
@@ -0,0 +1,75 @@
+package text
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestPostProcess(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   PostProcessConfig
+		input    string
+		expected string
+	}{
+		{name: "stop sequence", config: PostProcessConfig{StopSequences: []string{"\nUser:"}}, input: "Hello there\nUser: next question", expected: "Hello there"},
+		{name: "earliest stop wins", config: PostProcessConfig{StopSequences: []string{"END", "</s>"}}, input: "a</s>bEND", expected: "a"},
+		{name: "partial stop at end kept", config: PostProcessConfig{StopSequences: []string{"\nUser:"}}, input: "done\nUs", expected: "done\nUs"},
+		{name: "no stop", config: PostProcessConfig{StopSequences: []string{"STOP"}}, input: "plain text", expected: "plain text"},
+		{name: "role prefix", config: PostProcessConfig{StripPrefixes: DefaultRolePrefixes}, input: "  Assistant: Paris is the capital.", expected: "Paris is the capital."},
+		{name: "prefix only at start", config: PostProcessConfig{StripPrefixes: DefaultRolePrefixes}, input: "Ask the AI: it knows", expected: "Ask the AI: it knows"},
+		{name: "wrapper tag", config: PostProcessConfig{StripTags: []string{"answer"}}, input: "<answer>\n42\n</answer>\ntrailing", expected: "42\n"},
+		{name: "prefix then tag", config: PostProcessConfig{StripPrefixes: DefaultRolePrefixes, StripTags: []string{"answer"}}, input: "AI: <answer>yes</answer>", expected: "yes"},
+		{name: "unmatched tag kept", config: PostProcessConfig{StripTags: []string{"answer"}}, input: "<ans", expected: "<ans"},
+		{name: "utf8 kept whole", config: PostProcessConfig{StopSequences: []string{"。"}}, input: "héllo 世界。rest", expected: "héllo 世界"},
+		{name: "invalid utf8 replaced", config: PostProcessConfig{}, input: "ok\xffok", expected: "ok�ok"},
+		{name: "leading whitespace kept without artifacts", config: PostProcessConfig{StopSequences: []string{"x"}}, input: "  hi", expected: "  hi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runChunked(t, PostProcess(tt.config), tt.input); got != tt.expected {
+				t.Errorf("PostProcess() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStopAt(t *testing.T) {
+	if got := runChunked(t, StopAt("\nHuman:"), "answer\nHuman: more"); got != "answer" {
+		t.Errorf("StopAt() = %q, want %q", got, "answer")
+	}
+}
+
+func TestPostProcessStreams(t *testing.T) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := StopAt("\nUser:").ServeFlow(calque.NewRequest(context.Background(), inR), calque.NewResponse(outW))
+		outW.CloseWithError(err)
+		done <- err
+	}()
+
+	go func() { _, _ = inW.Write([]byte("first chunk\n")) }()
+	buf := make([]byte, 64)
+	n, err := outR.Read(buf)
+	if err != nil || string(buf[:n]) != "first chunk" {
+		t.Fatalf("first read = %q, %v; want %q before input ends", buf[:n], err, "first chunk")
+	}
+
+	go func() {
+		_, _ = inW.Write([]byte("User: ignored"))
+		_ = inW.Close()
+	}()
+	rest, _ := io.ReadAll(outR)
+	if len(rest) != 0 {
+		t.Errorf("output after stop = %q, want none", rest)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,267 @@
+package timeseries
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// default object keys, tried in order
+var (
+	timeKeys  = []string{"time", "timestamp", "ts", "t", "@timestamp"}
+	valueKeys = []string{"value", "v", "y"}
+)
+
+// readPoints decodes a stream of JSON values, calling fn for each point.
+//
+// Top-level arrays are lists of points and are decoded element by element,
+// so large arrays are never held in memory. Prometheus matrix results
+// ({"metric": {...}, "values": [[ts, "v"], ...]}) become one series per metric.
+func readPoints(ctx context.Context, r io.Reader, cfg Options, fn func(Point) error) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	p := pointReader{ctx: ctx, cfg: cfg, fn: fn}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return p.invalid(err)
+		}
+
+		if token != json.Delim('[') {
+			value, err := p.finishValue(decoder, token)
+			if err != nil {
+				return err
+			}
+			if err := p.value(value, ""); err != nil {
+				return err
+			}
+			continue
+		}
+		for decoder.More() {
+			var value any
+			if err := decoder.Decode(&value); err != nil {
+				return p.invalid(err)
+			}
+			if err := p.value(value, ""); err != nil {
+				return err
+			}
+		}
+		if _, err := decoder.Token(); err != nil {
+			return p.invalid(err)
+		}
+	}
+}
+
+type pointReader struct {
+	ctx   context.Context
+	cfg   Options
+	fn    func(Point) error
+	count int
+}
+
+// finishValue completes a top-level value whose first token was already read
+func (p *pointReader) finishValue(decoder *json.Decoder, token json.Token) (any, error) {
+	if token != json.Delim('{') {
+		return token, nil
+	}
+	object := map[string]any{}
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return nil, p.invalid(err)
+		}
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			return nil, p.invalid(err)
+		}
+		object[fmt.Sprint(key)] = value
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, p.invalid(err)
+	}
+	return object, nil
+}
+
+// value emits the point or points of one decoded JSON value
+func (p *pointReader) value(value any, series string) error {
+	p.count++
+	switch v := value.(type) {
+	case json.Number, string:
+		f, ok := number(v)
+		if !ok {
+			return p.invalidf("value %v is not numeric", v)
+		}
+		return p.emit(Point{Series: series, Value: f})
+
+	case []any:
+		if len(v) != 2 {
+			return p.invalidf("expected a [timestamp, value] pair, got %d elements", len(v))
+		}
+		t, ok := timestamp(v[0])
+		if !ok {
+			return p.invalidf("%v is not a timestamp", v[0])
+		}
+		f, ok := number(v[1])
+		if !ok {
+			return p.invalidf("value %v is not numeric", v[1])
+		}
+		return p.emit(Point{Series: series, Time: t, Value: f})
+
+	case map[string]any:
+		if values, ok := v["values"].([]any); ok {
+			return p.matrix(v, values)
+		}
+		return p.object(v, series)
+
+	case nil:
+		return nil // null samples are gaps
+	}
+	return p.invalidf("unsupported point %v", value)
+}
+
+// matrix emits the samples of a Prometheus range-query series
+func (p *pointReader) matrix(object map[string]any, values []any) error {
+	series := ""
+	if metric, ok := object["metric"].(map[string]any); ok {
+		series = seriesName(metric, p.cfg.SeriesField)
+	} else if p.cfg.SeriesField != "" && object[p.cfg.SeriesField] != nil {
+		series = fmt.Sprint(object[p.cfg.SeriesField])
+	}
+	for _, sample := range values {
+		if err := p.value(sample, series); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// object emits a point held in an object such as {"time": ..., "value": ...}
+func (p *pointReader) object(object map[string]any, series string) error {
+	if p.cfg.SeriesField != "" && object[p.cfg.SeriesField] != nil {
+		series = fmt.Sprint(object[p.cfg.SeriesField])
+	}
+
+	raw, ok := lookup(object, p.cfg.ValueField, valueKeys)
+	if !ok {
+		return p.invalidf("point has no value field: %v", object)
+	}
+	if raw == nil {
+		return nil
+	}
+	f, ok := number(raw)
+	if !ok {
+		return p.invalidf("value %v is not numeric", raw)
+	}
+	point := Point{Series: series, Value: f}
+
+	if rawTime, ok := lookup(object, p.cfg.TimeField, timeKeys); ok {
+		if point.Time, ok = timestamp(rawTime); !ok {
+			return p.invalidf("%v is not a timestamp", rawTime)
+		}
+	} else if p.cfg.TimeField != "" {
+		return p.invalidf("point has no %q field", p.cfg.TimeField)
+	}
+	return p.emit(point)
+}
+
+func (p *pointReader) emit(point Point) error {
+	if math.IsNaN(point.Value) || math.IsInf(point.Value, 0) {
+		return nil // Prometheus reports "NaN" for missing data
+	}
+	return p.fn(point)
+}
+
+func (p *pointReader) invalid(err error) error {
+	return calque.InvalidInput(calque.WrapErr(p.ctx, err, "timeseries: invalid JSON input"))
+}
+
+func (p *pointReader) invalidf(format string, args ...any) error {
+	msg := fmt.Sprintf("timeseries: point %d: ", p.count) + fmt.Sprintf(format, args...)
+	return calque.InvalidInput(calque.NewErr(p.ctx, msg))
+}
+
+// lookup returns the configured key, or the first default key present
+func lookup(object map[string]any, key string, defaults []string) (any, bool) {
+	if key != "" {
+		v, ok := object[key]
+		return v, ok
+	}
+	for _, k := range defaults {
+		if v, ok := object[k]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// seriesName names a Prometheus series by one label, or all of them
+func seriesName(metric map[string]any, field string) string {
+	if field != "" {
+		if v, ok := metric[field]; ok {
+			return fmt.Sprint(v)
+		}
+		return ""
+	}
+	name := fmt.Sprint(metric["__name__"])
+	if metric["__name__"] == nil {
+		name = ""
+	}
+	labels := make([]string, 0, len(metric))
+	for k, v := range metric {
+		if k != "__name__" {
+			labels = append(labels, fmt.Sprintf("%s=%q", k, fmt.Sprint(v)))
+		}
+	}
+	if len(labels) == 0 {
+		return name
+	}
+	sort.Strings(labels)
+	return name + "{" + strings.Join(labels, ",") + "}"
+}
+
+// number reads a JSON number or numeric string
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// timestamp reads RFC 3339 strings and unix times in seconds or milliseconds
+func timestamp(v any) (time.Time, bool) {
+	if s, ok := v.(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return t, true
+		}
+	}
+	f, ok := number(v)
+	if !ok || f <= 0 {
+		return time.Time{}, false
+	}
+	if f > 1e11 { // later than 5138 in seconds, so milliseconds
+		return time.UnixMilli(int64(f)).UTC(), true
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e9))).UTC(), true
+}
@@ -0,0 +1,538 @@
+// Package timeseries condenses numeric series into summaries a model can
+// reason about, so observability agents describe metric behaviour without
+// sending every raw sample.
+//
+// Summarize reports per-series statistics, a trend, bucketed aggregates and
+// anomalous points as JSON; Describe renders the same summary as a few lines
+// of text for a prompt. Downsample streams fixed-window aggregates of ordered
+// points.
+//
+// Input is JSON or NDJSON: numbers, [timestamp, value] pairs (as returned by
+// Prometheus range queries) or objects such as {"time": ..., "value": ...},
+// alone or in arrays.
+//
+// Example:
+//
+//	flow := calque.NewFlow().
+//		Use(timeseries.Describe(timeseries.Options{SeriesField: "host", Buckets: 12})).
+//		Use(prompt.Template("Explain what happened to CPU usage:\n\n{{.Input}}")).
+//		Use(ai.Agent(client))
+//	err := flow.Run(ctx, promResponseValues, &explanation)
+package timeseries
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Defaults applied when Options fields are zero
+const (
+	DefaultBuckets          = 12  // buckets per series when Options.Bucket is 0
+	DefaultAnomalyThreshold = 3.5 // robust z-score above which a point is anomalous
+	DefaultMaxAnomalies     = 10  // anomalies reported per series
+)
+
+// Options configures Summarize, Describe and Downsample.
+type Options struct {
+	TimeField   string // object key of the timestamp (default: time, timestamp, ts, t or @timestamp)
+	ValueField  string // object key of the value (default: value, v or y)
+	SeriesField string // object key splitting points into separate series, e.g. "host"
+
+	Bucket  time.Duration // aggregation window (0 = span split into Buckets)
+	Buckets int           // buckets per series when Bucket is 0 (default: DefaultBuckets)
+
+	AnomalyThreshold float64 // robust z-score that flags a point (default: DefaultAnomalyThreshold)
+	MaxAnomalies     int     // most anomalous points reported per series (default: DefaultMaxAnomalies)
+}
+
+// Point is one sample of a series.
+type Point struct {
+	Series string
+	Time   time.Time // zero for untimed points, which keep their input order
+	Value  float64
+}
+
+// Bucket aggregates the points of one window.
+type Bucket struct {
+	Series    string    `json:"series,omitempty"`
+	Start     time.Time `json:"start,omitzero"`
+	End       time.Time `json:"end,omitzero"` // exclusive, except for the final bucket of Summarize
+	Count     int       `json:"count"`
+	Min       float64   `json:"min"`
+	Max       float64   `json:"max"`
+	Mean      float64   `json:"mean"`
+	Last      float64   `json:"last"`
+	Anomalies int       `json:"anomalies,omitempty"` // anomalous points in the window (Summarize only)
+}
+
+// Stats describes a whole series.
+type Stats struct {
+	Count     int     `json:"count"`
+	Min       float64 `json:"min"`
+	Max       float64 `json:"max"`
+	Mean      float64 `json:"mean"`
+	StdDev    float64 `json:"stddev"`
+	P50       float64 `json:"p50"`
+	P95       float64 `json:"p95"`
+	P99       float64 `json:"p99"`
+	First     float64 `json:"first"`
+	Last      float64 `json:"last"`
+	Trend     string  `json:"trend"`                // "rising", "falling" or "flat", from a least-squares fit
+	ChangePct float64 `json:"change_pct,omitempty"` // last bucket mean against the first, in percent
+}
+
+// Anomaly is a point far from the rest of its series.
+type Anomaly struct {
+	Time      time.Time `json:"time,omitzero"`
+	Index     int       `json:"index"` // position in the series
+	Value     float64   `json:"value"`
+	Score     float64   `json:"score"`     // robust z-score: deviation from the median in scaled MADs
+	Direction string    `json:"direction"` // "spike" or "dip"
+}
+
+// Summary condenses one series.
+type Summary struct {
+	Series    string    `json:"series,omitempty"`
+	Start     time.Time `json:"start,omitzero"`
+	End       time.Time `json:"end,omitzero"`
+	Stats     Stats     `json:"stats"`
+	Buckets   []Bucket  `json:"buckets"`
+	Anomalies []Anomaly `json:"anomalies,omitempty"`
+}
+
+// Summarize condenses numeric series into statistics, buckets and anomalies.
+//
+// Input: JSON or NDJSON points (see the package documentation)
+// Output: []Summary JSON, one per series in order of first appearance
+// Behavior: BUFFERED - statistics need every point
+//
+// Timed points are sorted by time and split into Options.Bucket windows, or
+// Options.Buckets equal spans; untimed points are split by position.
+// Anomalies are points whose robust z-score - distance from the median in
+// units of the median absolute deviation - exceeds the threshold, so a few
+// outliers cannot hide each other the way they would with a standard
+// deviation. Values are rounded to 6 significant digits.
+//
+// Example:
+//
+//	flow.Use(timeseries.Summarize(timeseries.Options{Bucket: 5 * time.Minute}))
+func Summarize(opts ...Options) calque.Handler {
+	cfg := withDefaults(opts)
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		summaries, err := summarizeInput(req, cfg)
+		if err != nil {
+			return err
+		}
+		output, err := json.Marshal(summaries)
+		if err != nil {
+			return err
+		}
+		return calque.Write(res, output)
+	})
+}
+
+// Describe renders Summarize's result as text for a prompt.
+//
+// Input: JSON or NDJSON points (see the package documentation)
+// Output: a short paragraph per series: range, statistics, trend, bucket means and anomalies
+// Behavior: BUFFERED - statistics need every point
+//
+// Example:
+//
+//	flow.Use(timeseries.Describe()).
+//		Use(prompt.Template("Is this latency series healthy?\n\n{{.Input}}"))
+func Describe(opts ...Options) calque.Handler {
+	cfg := withDefaults(opts)
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		summaries, err := summarizeInput(req, cfg)
+		if err != nil {
+			return err
+		}
+		parts := make([]string, len(summaries))
+		for i, s := range summaries {
+			parts[i] = s.String()
+		}
+		return calque.Write(res, strings.Join(parts, "\n\n"))
+	})
+}
+
+// Downsample aggregates ordered points into fixed windows.
+//
+// Input: JSON or NDJSON timestamped points, ordered by time within each series
+// Output: NDJSON, one Bucket per series and window
+// Behavior: STREAMING - a window is written when a later point of its series arrives
+//
+// Windows are aligned to multiples of bucket since the zero time, so 1m and
+// 1h windows start on the minute and hour. Points without a timestamp fail
+// the flow with an invalid-input error.
+//
+// Example:
+//
+//	// 15s scrapes to 5m aggregates before storing or prompting
+//	flow.Use(timeseries.Downsample(5*time.Minute, timeseries.Options{SeriesField: "instance"}))
+func Downsample(bucket time.Duration, opts ...Options) calque.Handler {
+	cfg := withDefaults(opts)
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		if bucket <= 0 {
+			return calque.NewErr(req.Context, "timeseries: downsample bucket must be positive")
+		}
+		encoder := json.NewEncoder(res.Data)
+		open := map[string]*Bucket{}
+		var order []string
+
+		err := readPoints(req.Context, req.Data, cfg, func(p Point) error {
+			if p.Time.IsZero() {
+				return calque.InvalidInput(calque.NewErr(req.Context, "timeseries: downsampling needs timestamped points"))
+			}
+			start := p.Time.Truncate(bucket)
+			b, ok := open[p.Series]
+			if ok && !b.Start.Equal(start) {
+				if err := encoder.Encode(roundBucket(*b)); err != nil {
+					return err
+				}
+				ok = false
+			}
+			if !ok {
+				if _, seen := open[p.Series]; !seen {
+					order = append(order, p.Series)
+				}
+				b = &Bucket{Series: p.Series, Start: start, End: start.Add(bucket)}
+				open[p.Series] = b
+			}
+			addToBucket(b, p.Value)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, series := range order {
+			if err := encoder.Encode(roundBucket(*open[series])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func withDefaults(opts []Options) Options {
+	cfg := Options{}
+	if len(opts) > 0 {
+		cfg = opts[0]
+	}
+	if cfg.Buckets <= 0 {
+		cfg.Buckets = DefaultBuckets
+	}
+	if cfg.AnomalyThreshold <= 0 {
+		cfg.AnomalyThreshold = DefaultAnomalyThreshold
+	}
+	if cfg.MaxAnomalies <= 0 {
+		cfg.MaxAnomalies = DefaultMaxAnomalies
+	}
+	return cfg
+}
+
+// summarizeInput reads all points and summarizes each series
+func summarizeInput(req *calque.Request, cfg Options) ([]Summary, error) {
+	series := map[string][]Point{}
+	var order []string
+	err := readPoints(req.Context, req.Data, cfg, func(p Point) error {
+		if _, ok := series[p.Series]; !ok {
+			order = append(order, p.Series)
+		}
+		series[p.Series] = append(series[p.Series], p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]Summary, 0, len(order))
+	for _, name := range order {
+		summaries = append(summaries, summarize(name, series[name], cfg))
+	}
+	return summaries, nil
+}
+
+// summarize condenses the points of one series
+func summarize(name string, points []Point, cfg Options) Summary {
+	timed := true
+	for _, p := range points {
+		if p.Time.IsZero() {
+			timed = false
+			break
+		}
+	}
+	if timed {
+		sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+	}
+
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Value
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	s := Summary{Series: name}
+	if timed {
+		s.Start, s.End = points[0].Time, points[len(points)-1].Time
+	}
+	s.Stats = seriesStats(points, values, sorted, timed)
+	s.Anomalies = anomalies(points, values, sorted, cfg)
+	var bucketOf []int
+	s.Buckets, bucketOf = buckets(points, timed, cfg)
+	for _, a := range s.Anomalies {
+		s.Buckets[bucketOf[a.Index]].Anomalies++
+	}
+	if len(s.Buckets) > 1 && s.Buckets[0].Mean != 0 {
+		first, last := s.Buckets[0].Mean, s.Buckets[len(s.Buckets)-1].Mean
+		s.Stats.ChangePct = round((last - first) / math.Abs(first) * 100)
+	}
+	for i := range s.Buckets {
+		s.Buckets[i] = roundBucket(s.Buckets[i])
+	}
+	return s
+}
+
+func seriesStats(points []Point, values, sorted []float64, timed bool) Stats {
+	n := float64(len(values))
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / n
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+
+	return Stats{
+		Count:  len(values),
+		Min:    round(sorted[0]),
+		Max:    round(sorted[len(sorted)-1]),
+		Mean:   round(mean),
+		StdDev: round(math.Sqrt(sq / n)),
+		P50:    round(percentile(sorted, 0.50)),
+		P95:    round(percentile(sorted, 0.95)),
+		P99:    round(percentile(sorted, 0.99)),
+		First:  round(values[0]),
+		Last:   round(values[len(values)-1]),
+		Trend:  trend(points, values, sorted, timed),
+	}
+}
+
+// percentile interpolates linearly between the closest ranks
+func percentile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
+
+// trend fits a least-squares line; changes within a tenth of the value range
+// or the scatter around the line count as flat
+func trend(points []Point, values, sorted []float64, timed bool) string {
+	if len(values) < 3 {
+		return "flat"
+	}
+	x := func(i int) float64 {
+		if timed {
+			return points[i].Time.Sub(points[0].Time).Seconds()
+		}
+		return float64(i)
+	}
+	n := float64(len(values))
+	var sx, sy, sxx, sxy float64
+	for i, v := range values {
+		xi := x(i)
+		sx += xi
+		sy += v
+		sxx += xi * xi
+		sxy += xi * v
+	}
+	den := n*sxx - sx*sx
+	valueRange := sorted[len(sorted)-1] - sorted[0]
+	if den == 0 || valueRange == 0 {
+		return "flat"
+	}
+	slope := (n*sxy - sx*sy) / den
+	intercept := (sy - slope*sx) / n
+	var residuals float64
+	for i, v := range values {
+		r := v - (intercept + slope*x(i))
+		residuals += r * r
+	}
+	// the fitted change must exceed the noise around the line as well
+	threshold := max(valueRange/10, math.Sqrt(residuals/n))
+	change := slope * (x(len(values)-1) - x(0))
+	switch {
+	case change > threshold:
+		return "rising"
+	case change < -threshold:
+		return "falling"
+	}
+	return "flat"
+}
+
+// anomalies flags points by robust z-score, most extreme first
+func anomalies(points []Point, values, sorted []float64, cfg Options) []Anomaly {
+	if len(values) < 5 {
+		return nil
+	}
+	median := percentile(sorted, 0.5)
+	deviations := make([]float64, len(values))
+	var meanAbs float64
+	for i, v := range values {
+		deviations[i] = math.Abs(v - median)
+		meanAbs += deviations[i]
+	}
+	meanAbs /= float64(len(values))
+	sort.Float64s(deviations)
+
+	// 1.4826 * MAD estimates the standard deviation of normal data; when more
+	// than half the points equal the median, fall back to the mean deviation
+	scale := 1.4826 * percentile(deviations, 0.5)
+	if scale == 0 {
+		scale = 1.2533 * meanAbs
+	}
+	if scale == 0 {
+		return nil
+	}
+
+	var found []Anomaly
+	for i, v := range values {
+		score := (v - median) / scale
+		if math.Abs(score) < cfg.AnomalyThreshold {
+			continue
+		}
+		a := Anomaly{Time: points[i].Time, Index: i, Value: round(v), Score: round(math.Abs(score)), Direction: "spike"}
+		if score < 0 {
+			a.Direction = "dip"
+		}
+		found = append(found, a)
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Score > found[j].Score })
+	if len(found) > cfg.MaxAnomalies {
+		found = found[:cfg.MaxAnomalies]
+	}
+	return found
+}
+
+// buckets aggregates points into windows of time, or of position when
+// untimed, and returns the bucket of each point
+func buckets(points []Point, timed bool, cfg Options) ([]Bucket, []int) {
+	of := make([]int, len(points))
+	if !timed {
+		count := min(cfg.Buckets, len(points))
+		out := make([]Bucket, count)
+		for i, p := range points {
+			of[i] = i * count / len(points)
+			addToBucket(&out[of[i]], p.Value)
+		}
+		return out, of
+	}
+
+	start, end := points[0].Time, points[len(points)-1].Time
+	width, last := cfg.Bucket, time.Duration(math.MaxInt64)
+	if width <= 0 {
+		// equal spans; the series end falls in the final bucket
+		width = max(end.Sub(start)/time.Duration(cfg.Buckets), 1)
+		last = time.Duration(cfg.Buckets - 1)
+	} else {
+		start = start.Truncate(width)
+	}
+
+	var out []Bucket
+	for i, p := range points {
+		bStart := start.Add(min(p.Time.Sub(start)/width, last) * width)
+		if len(out) == 0 || !out[len(out)-1].Start.Equal(bStart) {
+			out = append(out, Bucket{Start: bStart, End: bStart.Add(width)})
+		}
+		of[i] = len(out) - 1
+		addToBucket(&out[of[i]], p.Value)
+	}
+	return out, of
+}
+
+func addToBucket(b *Bucket, v float64) {
+	if b.Count == 0 || v < b.Min {
+		b.Min = v
+	}
+	if b.Count == 0 || v > b.Max {
+		b.Max = v
+	}
+	b.Mean += (v - b.Mean) / float64(b.Count+1)
+	b.Count++
+	b.Last = v
+}
+
+func roundBucket(b Bucket) Bucket {
+	b.Min, b.Max, b.Mean, b.Last = round(b.Min), round(b.Max), round(b.Mean), round(b.Last)
+	return b
+}
+
+// round keeps 6 significant digits, enough to describe a metric
+func round(v float64) float64 {
+	if v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return v
+	}
+	scale := math.Pow(10, 5-math.Floor(math.Log10(math.Abs(v))))
+	return math.Round(v*scale) / scale
+}
+
+// String describes the summary in a few lines of text.
+func (s Summary) String() string {
+	var b strings.Builder
+	name := s.Series
+	if name == "" {
+		name = "series"
+	}
+	fmt.Fprintf(&b, "%s: %d points", name, s.Stats.Count)
+	if !s.Start.IsZero() {
+		fmt.Fprintf(&b, " from %s to %s", s.Start.Format(time.RFC3339), s.End.Format(time.RFC3339))
+	}
+	st := s.Stats
+	fmt.Fprintf(&b, ".\nmean %s, min %s, max %s, p50 %s, p95 %s, stddev %s, first %s, last %s.\ntrend: %s",
+		num(st.Mean), num(st.Min), num(st.Max), num(st.P50), num(st.P95), num(st.StdDev), num(st.First), num(st.Last), st.Trend)
+	if st.ChangePct != 0 {
+		fmt.Fprintf(&b, " (%+.1f%% first to last bucket)", st.ChangePct)
+	}
+	b.WriteString(".")
+
+	if len(s.Buckets) > 0 {
+		means := make([]string, len(s.Buckets))
+		for i, bk := range s.Buckets {
+			means[i] = num(bk.Mean)
+		}
+		label := "bucket means"
+		if !s.Buckets[0].Start.IsZero() {
+			label += " (" + s.Buckets[0].End.Sub(s.Buckets[0].Start).String() + ")"
+		}
+		fmt.Fprintf(&b, "\n%s: %s.", label, strings.Join(means, ", "))
+	}
+
+	if len(s.Anomalies) == 0 {
+		b.WriteString("\nno anomalies.")
+		return b.String()
+	}
+	items := make([]string, len(s.Anomalies))
+	for i, a := range s.Anomalies {
+		at := fmt.Sprintf("#%d", a.Index)
+		if !a.Time.IsZero() {
+			at = a.Time.Format(time.RFC3339)
+		}
+		items[i] = fmt.Sprintf("%s %s %s (score %.1f)", at, a.Direction, num(a.Value), a.Score)
+	}
+	fmt.Fprintf(&b, "\nanomalies: %s.", strings.Join(items, "; "))
+	return b.String()
+}
+
+func num(v float64) string {
+	return fmt.Sprintf("%g", v)
+}
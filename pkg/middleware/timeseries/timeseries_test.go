@@ -0,0 +1,222 @@
+package timeseries
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func run(t *testing.T, handler calque.Handler, input string) string {
+	t.Helper()
+	var out string
+	if err := calque.NewFlow().Use(handler).Run(context.Background(), input, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func summaries(t *testing.T, input string, opts ...Options) []Summary {
+	t.Helper()
+	var out []Summary
+	if err := json.Unmarshal([]byte(run(t, Summarize(opts...), input)), &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// minuteSeries renders one point per minute from base as NDJSON objects
+func minuteSeries(base time.Time, values []float64) string {
+	var b strings.Builder
+	for i, v := range values {
+		fmt.Fprintf(&b, `{"time":%q,"value":%g}`+"\n", base.Add(time.Duration(i)*time.Minute).Format(time.RFC3339), v)
+	}
+	return b.String()
+}
+
+func TestSummarize(t *testing.T) {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	values := []float64{10, 11, 10, 12, 11, 10, 95, 11, 12, 10, 11, 12}
+
+	got := summaries(t, minuteSeries(base, values), Options{Buckets: 4})
+	if len(got) != 1 {
+		t.Fatalf("summaries = %+v", got)
+	}
+	s := got[0]
+	if !s.Start.Equal(base) || !s.End.Equal(base.Add(11*time.Minute)) {
+		t.Errorf("range = %v - %v", s.Start, s.End)
+	}
+	st := s.Stats
+	if st.Count != 12 || st.Min != 10 || st.Max != 95 || st.First != 10 || st.Last != 12 || st.P50 != 11 {
+		t.Errorf("stats = %+v", st)
+	}
+	if st.Mean != 17.9167 {
+		t.Errorf("mean = %v, want 6 significant digits of 17.91666", st.Mean)
+	}
+
+	if len(s.Anomalies) != 1 {
+		t.Fatalf("anomalies = %+v", s.Anomalies)
+	}
+	a := s.Anomalies[0]
+	if a.Value != 95 || a.Index != 6 || a.Direction != "spike" || !a.Time.Equal(base.Add(6*time.Minute)) || a.Score < 3.5 {
+		t.Errorf("anomaly = %+v", a)
+	}
+
+	if len(s.Buckets) != 4 {
+		t.Fatalf("buckets = %+v", s.Buckets)
+	}
+	last := s.Buckets[3]
+	if last.Count != 3 || last.Last != 12 || last.End.Before(s.End) {
+		t.Errorf("the series end should fall in the final bucket: %+v", s.Buckets)
+	}
+	if s.Buckets[2].Anomalies != 1 || s.Buckets[2].Max != 95 {
+		t.Errorf("spike bucket = %+v", s.Buckets[2])
+	}
+}
+
+func TestSummarizeTrend(t *testing.T) {
+	var rising, flat []string
+	for i := range 20 {
+		rising = append(rising, fmt.Sprint(100+i*5))
+		flat = append(flat, fmt.Sprint(50+i%2))
+	}
+
+	up := summaries(t, "["+strings.Join(rising, ",")+"]", Options{Buckets: 2})[0]
+	if up.Stats.Trend != "rising" || up.Stats.ChangePct != 40.8163 {
+		t.Errorf("rising stats = %+v", up.Stats)
+	}
+	if up.Buckets[0].Count != 10 || !up.Start.IsZero() {
+		t.Errorf("untimed points should be bucketed by position: %+v", up)
+	}
+
+	if st := summaries(t, strings.Join(flat, "\n"))[0].Stats; st.Trend != "flat" {
+		t.Errorf("alternating stats = %+v", st)
+	}
+
+	falling := summaries(t, "[5,4,3,2,1]")[0]
+	if falling.Stats.Trend != "falling" || len(falling.Buckets) != 5 {
+		t.Errorf("falling summary = %+v", falling)
+	}
+}
+
+func TestSummarizeSeries(t *testing.T) {
+	input := `[{"host":"a","ts":1700000000,"v":1},{"host":"b","ts":1700000000,"v":"7.5"},{"host":"a","ts":1700000060,"v":3}]`
+	got := summaries(t, input, Options{SeriesField: "host", Bucket: time.Minute})
+	if len(got) != 2 || got[0].Series != "a" || got[1].Series != "b" {
+		t.Fatalf("summaries = %+v", got)
+	}
+	if got[0].Stats.Mean != 2 || len(got[0].Buckets) != 2 || got[0].Buckets[1].Start.Unix() != 1700000040 {
+		t.Errorf("series a = %+v", got[0])
+	}
+	if got[1].Stats.Count != 1 || got[1].Stats.Max != 7.5 {
+		t.Errorf("series b = %+v", got[1])
+	}
+}
+
+func TestReadPointsPrometheus(t *testing.T) {
+	input := `{"metric":{"__name__":"up","job":"api"},"values":[[1700000000.5,"1"],[1700000015,"NaN"],[1700000030,"0"]]}
+{"metric":{"job":"db"},"values":[[1700000000,"1"]]}
+[1700000000000, 4]`
+
+	var points []Point
+	err := readPoints(context.Background(), strings.NewReader(input), Options{}, func(p Point) error {
+		points = append(points, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 5 {
+		t.Fatalf("points = %+v", points)
+	}
+	if points[0].Series != `up{job="api"}` || points[0].Time.UnixMilli() != 1700000000500 || points[1].Value != 0 {
+		t.Errorf("prometheus points = %+v", points[:2])
+	}
+	if points[2].Series != `{job="db"}` {
+		t.Errorf("series = %q", points[2].Series)
+	}
+	// a top-level array is a list of points, so this is two untimed values
+	if !points[3].Time.IsZero() || points[3].Value != 1700000000000 || points[4].Value != 4 {
+		t.Errorf("array points = %+v", points[3:])
+	}
+}
+
+func TestSummarizeInvalid(t *testing.T) {
+	tests := map[string]string{
+		"not json":      `{"value":`,
+		"no value":      `{"time":"2026-03-01T12:00:00Z"}`,
+		"not numeric":   `["a","b","c"]`,
+		"bad timestamp": `{"time":"yesterday","value":1}`,
+	}
+	for name, input := range tests {
+		err := calque.NewFlow().Use(Summarize()).Run(context.Background(), input, new(string))
+		if !errors.Is(err, calque.ErrInvalidInput) {
+			t.Errorf("%s: err = %v, want invalid input", name, err)
+		}
+	}
+
+	if out := run(t, Summarize(), ""); out != "[]" {
+		t.Errorf("empty input = %q", out)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	values := []float64{10, 11, 10, 12, 11, 10, 95, 11, 12, 10, 11, 12}
+	out := run(t, Describe(Options{Bucket: 4 * time.Minute}), minuteSeries(base, values))
+
+	for _, want := range []string{
+		"series: 12 points from 2026-03-01T12:00:00Z to 2026-03-01T12:11:00Z.",
+		"mean 17.9167, min 10, max 95",
+		"trend: flat",
+		"bucket means (4m0s): 10.75, 31.75, 11.25.",
+		"anomalies: 2026-03-01T12:06:00Z spike 95 (score",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("description missing %q:\n%s", want, out)
+		}
+	}
+
+	quiet := run(t, Describe(), "[1,1,1,1,1]")
+	if !strings.Contains(quiet, "no anomalies.") || strings.Contains(quiet, " from ") {
+		t.Errorf("constant description = %s", quiet)
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	input := `{"host":"a","time":"2026-03-01T12:00:10Z","value":1}
+{"host":"b","time":"2026-03-01T12:00:20Z","value":10}
+{"host":"a","time":"2026-03-01T12:00:50Z","value":3}
+{"host":"a","time":"2026-03-01T12:01:05Z","value":8}
+{"host":"b","time":"2026-03-01T12:02:00Z","value":20}
+`
+	out := run(t, Downsample(time.Minute, Options{SeriesField: "host"}), input)
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var b Bucket
+		if err := json.Unmarshal([]byte(line), &b); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%s %s n=%d mean=%g", b.Series, b.Start.Format("15:04"), b.Count, b.Mean))
+	}
+	want := []string{
+		"a 12:00 n=2 mean=2",
+		"b 12:00 n=1 mean=10",
+		"a 12:01 n=1 mean=8",
+		"b 12:02 n=1 mean=20",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("buckets:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	err := calque.NewFlow().Use(Downsample(time.Minute)).Run(context.Background(), "[1,2]", new(string))
+	if !errors.Is(err, calque.ErrInvalidInput) {
+		t.Errorf("untimed points: err = %v, want invalid input", err)
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"io"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
 )
 
 // ToolCall represents a parsed tool call from LLM output
@@ -59,6 +61,32 @@ type Config struct {
 	RawOutput bool
 }
 
+// CallObserver is notified after each tool call Execute makes, including
+// calls that fail before the tool runs (unknown tool, bad arguments, budget).
+// Calls run concurrently, so observers must be safe for concurrent use.
+type CallObserver func(ctx context.Context, result ToolResult, duration time.Duration)
+
+type callObserverKey struct{}
+
+// WithCallObserver returns a context whose tool calls are reported to observer,
+// after any observer already in ctx.
+//
+// Example:
+//
+//	ctx = tools.WithCallObserver(ctx, func(_ context.Context, r tools.ToolResult, d time.Duration) {
+//		log.Printf("%s(%s) took %s: %s", r.ToolCall.Name, r.ToolCall.Arguments, d, r.Error)
+//	})
+func WithCallObserver(ctx context.Context, observer CallObserver) context.Context {
+	if prev, ok := ctx.Value(callObserverKey{}).(CallObserver); ok {
+		next := observer
+		observer = func(ctx context.Context, result ToolResult, duration time.Duration) {
+			prev(ctx, result, duration)
+			next(ctx, result, duration)
+		}
+	}
+	return context.WithValue(ctx, callObserverKey{}, observer)
+}
+
 // Execute parses LLM output for tool calls and executes them using tools from Registry.
 //
 // Input: LLM output containing tool calls (assumes tool calls are present)
@@ -248,8 +276,18 @@ func executeToolCallsWithConfig(ctx context.Context, tools []Tool, toolCalls []T
 	return results
 }
 
-// executeToolCall executes a single tool call
+// executeToolCall executes a single tool call, notifying any CallObserver
 func executeToolCall(ctx context.Context, tools []Tool, toolCall ToolCall) ToolResult {
+	start := time.Now()
+	result := dispatchToolCall(ctx, tools, toolCall)
+	if observe, ok := ctx.Value(callObserverKey{}).(CallObserver); ok {
+		observe(ctx, result, time.Since(start))
+	}
+	return result
+}
+
+// dispatchToolCall finds and runs the tool of a call
+func dispatchToolCall(ctx context.Context, tools []Tool, toolCall ToolCall) ToolResult {
 	// If the tool call already has an error (e.g., from parsing), return it immediately
 	if toolCall.Error != "" {
 		return ToolResult{
@@ -274,7 +312,48 @@ func executeToolCall(ctx context.Context, tools []Tool, toolCall ToolCall) ToolR
 		}
 	}
 
-	// Execute the tool with panic recovery
+	if err := ctrl.ChargeToolCall(ctx); err != nil {
+		return ToolResult{
+			ToolCall: toolCall,
+			Error:    err.Error(),
+		}
+	}
+	if err := ctrl.RecordStep(ctx, stepAction(toolCall)); err != nil {
+		return ToolResult{
+			ToolCall: toolCall,
+			Error:    err.Error(),
+		}
+	}
+
+	start := time.Now()
+	result := runTool(ctx, tool, toolCall)
+	if calque.HasEvents(ctx) || calque.ResultFrom(ctx) != nil {
+		end := time.Now()
+		called := &calque.ToolCalled{
+			Tool:      toolCall.Name,
+			ID:        toolCall.ID,
+			Arguments: toolCall.Arguments,
+			Time:      end,
+			Duration:  end.Sub(start),
+			Error:     result.Error,
+		}
+		calque.EmitEvent(ctx, called)
+		calque.Attach(ctx, called)
+	}
+	return result
+}
+
+// stepAction identifies a tool call for ctrl.LoopGuard, ignoring JSON formatting
+func stepAction(toolCall ToolCall) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(toolCall.Arguments)); err == nil {
+		return toolCall.Name + " " + compact.String()
+	}
+	return toolCall.Name + " " + toolCall.Arguments
+}
+
+// runTool executes a found tool with panic recovery
+func runTool(ctx context.Context, tool Tool, toolCall ToolCall) ToolResult {
 	var result bytes.Buffer
 	args := strings.NewReader(toolCall.Arguments)
 	req := calque.NewRequest(ctx, args)
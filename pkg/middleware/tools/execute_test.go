@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
 )
 
 // Mock tools for testing
@@ -353,6 +354,81 @@ func TestExecuteToolCall(t *testing.T) {
 	}
 }
 
+func TestExecuteToolCallEmitsEvent(t *testing.T) {
+	tools := []Tool{createMockCalculator(), createErrorTool()}
+	flow := calque.NewFlow().UseFunc(func(req *calque.Request, res *calque.Response) error {
+		executeToolCall(req.Context, tools, ToolCall{Name: "calculator", Arguments: "2+2", ID: "call_1"})
+		executeToolCall(req.Context, tools, ToolCall{Name: "error_tool", Arguments: "x"})
+		return calque.Write(res, "done")
+	})
+
+	events := make(chan calque.Event, 16)
+	var out string
+	if err := flow.RunWithEvents(context.Background(), "", &out, events); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []*calque.ToolCalled
+	for ev := range events {
+		if call, ok := ev.(*calque.ToolCalled); ok {
+			calls = append(calls, call)
+		}
+	}
+	if len(calls) != 2 {
+		t.Fatalf("got %d ToolCalled events, want 2", len(calls))
+	}
+	if calls[0].Tool != "calculator" || calls[0].ID != "call_1" || calls[0].Arguments != "2+2" || calls[0].Error != "" {
+		t.Errorf("first call = %+v", calls[0])
+	}
+	if calls[1].Tool != "error_tool" || calls[1].Error == "" {
+		t.Errorf("second call = %+v", calls[1])
+	}
+}
+
+func TestWithCallObserver(t *testing.T) {
+	tools := []Tool{createMockCalculator()}
+	var first, second []string
+	ctx := WithCallObserver(context.Background(), func(_ context.Context, r ToolResult, _ time.Duration) {
+		first = append(first, r.ToolCall.Name+":"+string(r.Result))
+	})
+	ctx = WithCallObserver(ctx, func(_ context.Context, r ToolResult, _ time.Duration) {
+		second = append(second, r.ToolCall.Name+":"+r.Error)
+	})
+
+	executeToolCall(ctx, tools, ToolCall{Name: "calculator", Arguments: "2+2"})
+	executeToolCall(ctx, tools, ToolCall{Name: "unknown"})
+
+	if len(first) != 2 || first[0] != "calculator:4" {
+		t.Errorf("first observer saw %v", first)
+	}
+	if len(second) != 2 || second[1] != "unknown:Tool 'unknown' not found" {
+		t.Errorf("second observer saw %v", second)
+	}
+}
+
+func TestExecuteToolCallLoopGuard(t *testing.T) {
+	tools := []Tool{createMockCalculator()}
+	var results []ToolResult
+	done := make(chan struct{})
+	guarded := ctrl.LoopGuard(calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		defer close(done)
+		for _, args := range []string{`{"x": 1}`, `{"x":1}`, `{ "x" : 1 }`, `{"x":1}`} {
+			results = append(results, executeToolCall(req.Context, tools, ToolCall{Name: "calculator", Arguments: args}))
+		}
+		return calque.Write(res, "done")
+	}))
+
+	var out bytes.Buffer
+	err := guarded.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("")), calque.NewResponse(&out))
+	<-done // the guard returns as soon as the run is cancelled
+	if !errors.Is(err, ctrl.ErrLoopDetected) {
+		t.Fatalf("err = %v, want a loop", err)
+	}
+	if results[2].Error != "" || !strings.Contains(results[3].Error, "loop detected") {
+		t.Errorf("results = %+v", results)
+	}
+}
+
 func TestExecuteWithIOError(t *testing.T) {
 	// Create a pipeline with tools to test IO error
 	calc := createMockCalculator()
@@ -0,0 +1,224 @@
+// Package forge provides code-review tools backed by GitHub or GitLab.
+//
+// A Forge abstracts the hosting service; Tools exposes it to agents as
+// issue, pull request, review comment and CI status tools:
+//
+//	gh, err := forge.NewGitHub(forge.GitHubConfig{Owner: "calque-ai", Repo: "go-calque"})
+//	if err != nil {
+//	    return err
+//	}
+//	agent := ai.Agent(client, ai.WithTools(forge.Tools(gh)...))
+package forge
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+// Issue is an issue on the forge.
+type Issue struct {
+	Number int      `json:"number"`
+	Title  string   `json:"title"`
+	Body   string   `json:"body,omitempty"`
+	State  string   `json:"state"` // "open" or "closed"
+	Author string   `json:"author,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+	URL    string   `json:"url"`
+}
+
+// IssueQuery filters ListIssues.
+type IssueQuery struct {
+	State  string   // "open" (default), "closed" or "all"
+	Labels []string // issues must carry every label
+	Search string   // free-text search in title and body
+	Limit  int      // maximum results (default 20)
+}
+
+// PullRequest is a pull request (GitHub) or merge request (GitLab).
+type PullRequest struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+	Head   string `json:"head"`
+	Base   string `json:"base"`
+	URL    string `json:"url"`
+}
+
+// NewPullRequest describes a pull request to open.
+type NewPullRequest struct {
+	Title string
+	Body  string
+	Head  string // source branch
+	Base  string // target branch
+	Draft bool
+}
+
+// ReviewComment is a comment on a pull request. With Path and Line set it is
+// attached to that line of the new version of the file; otherwise it is a
+// general comment.
+type ReviewComment struct {
+	Body string
+	Path string
+	Line int
+}
+
+// Check is a single CI job or check run.
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // "success", "failure" or "pending"
+	URL    string `json:"url,omitempty"`
+}
+
+// CIStatus is the combined CI state of a commit.
+type CIStatus struct {
+	Ref    string  `json:"ref"`
+	State  string  `json:"state"` // "success", "failure", "pending" or "none"
+	Checks []Check `json:"checks"`
+}
+
+// Forge is a code hosting service.
+type Forge interface {
+	ListIssues(ctx context.Context, query IssueQuery) ([]Issue, error)
+	GetIssue(ctx context.Context, number int) (*Issue, error)
+	CreatePullRequest(ctx context.Context, pr NewPullRequest) (*PullRequest, error)
+	CommentOnPullRequest(ctx context.Context, number int, comment ReviewComment) error
+	CIStatus(ctx context.Context, ref string) (*CIStatus, error)
+}
+
+// Config controls which forge tools are exposed.
+type Config struct {
+	// ReadOnly - only expose tools that don't modify the forge
+	ReadOnly bool
+}
+
+// Tools exposes a forge to agents.
+//
+// Returns forge_list_issues, forge_get_issue and forge_ci_status, plus
+// forge_create_pull_request and forge_comment_pull_request unless
+// Config.ReadOnly is set. Results are returned as JSON.
+//
+// Example:
+//
+//	lab, _ := forge.NewGitLab(forge.GitLabConfig{Project: "group/service"})
+//	reviewer := ai.Agent(client, ai.WithTools(forge.Tools(lab, forge.Config{ReadOnly: true})...))
+func Tools(f Forge, config ...Config) []tools.Tool {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	forgeTools := []tools.Tool{
+		tools.Typed("forge_list_issues", "List repository issues, optionally filtered by state, labels or search text",
+			func(ctx context.Context, args listIssuesArgs) (string, error) {
+				return jsonResult(f.ListIssues(ctx, IssueQuery(args)))
+			}),
+		tools.Typed("forge_get_issue", "Get an issue including its description",
+			func(ctx context.Context, args issueArgs) (string, error) {
+				return jsonResult(f.GetIssue(ctx, args.Number))
+			}),
+		tools.Typed("forge_ci_status", "Get the CI status of a branch or commit",
+			func(ctx context.Context, args ciStatusArgs) (string, error) {
+				if args.Ref == "" {
+					return "", calque.NewErr(ctx, "ref is required")
+				}
+				return jsonResult(f.CIStatus(ctx, args.Ref))
+			}),
+	}
+	if cfg.ReadOnly {
+		return forgeTools
+	}
+
+	return append(forgeTools,
+		tools.Typed("forge_create_pull_request", "Open a pull request from a branch",
+			func(ctx context.Context, args createPullRequestArgs) (string, error) {
+				if args.Title == "" || args.Head == "" || args.Base == "" {
+					return "", calque.NewErr(ctx, "title, head and base are required")
+				}
+				return jsonResult(f.CreatePullRequest(ctx, NewPullRequest(args)))
+			}),
+		tools.Typed("forge_comment_pull_request", "Comment on a pull request, optionally on a specific file line",
+			func(ctx context.Context, args commentArgs) (string, error) {
+				if args.Body == "" {
+					return "", calque.NewErr(ctx, "body is required")
+				}
+				if (args.Path == "") != (args.Line == 0) {
+					return "", calque.NewErr(ctx, "path and line must be given together")
+				}
+				err := f.CommentOnPullRequest(ctx, args.Number, ReviewComment{Body: args.Body, Path: args.Path, Line: args.Line})
+				if err != nil {
+					return "", err
+				}
+				return "comment posted", nil
+			}),
+	)
+}
+
+type listIssuesArgs struct {
+	State  string   `json:"state,omitempty" jsonschema:"enum=open,enum=closed,enum=all,description=Issue state (default open)"`
+	Labels []string `json:"labels,omitempty" jsonschema:"description=Only issues with all of these labels"`
+	Search string   `json:"search,omitempty" jsonschema:"description=Text to search for"`
+	Limit  int      `json:"limit,omitempty" jsonschema:"description=Maximum number of issues (default 20)"`
+}
+
+type issueArgs struct {
+	Number int `json:"number" jsonschema:"required,description=Issue number"`
+}
+
+type ciStatusArgs struct {
+	Ref string `json:"ref" jsonschema:"required,description=Branch name or commit SHA"`
+}
+
+type createPullRequestArgs struct {
+	Title string `json:"title" jsonschema:"required"`
+	Body  string `json:"body,omitempty" jsonschema:"description=Pull request description"`
+	Head  string `json:"head" jsonschema:"required,description=Branch containing the changes"`
+	Base  string `json:"base" jsonschema:"required,description=Branch to merge into"`
+	Draft bool   `json:"draft,omitempty"`
+}
+
+type commentArgs struct {
+	Number int    `json:"number" jsonschema:"required,description=Pull request number"`
+	Body   string `json:"body" jsonschema:"required,description=Comment text (markdown)"`
+	Path   string `json:"path,omitempty" jsonschema:"description=File to comment on"`
+	Line   int    `json:"line,omitempty" jsonschema:"description=Line in the new version of the file"`
+}
+
+// jsonResult encodes a forge result for the model
+func jsonResult[T any](v T, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// combineChecks derives the overall CI state from individual checks
+func combineChecks(checks []Check) string {
+	if len(checks) == 0 {
+		return "none"
+	}
+	state := "success"
+	for _, c := range checks {
+		switch c.Status {
+		case "failure":
+			return "failure"
+		case "pending":
+			state = "pending"
+		}
+	}
+	return state
+}
+
+// queryLimit applies the default result limit
+func queryLimit(limit int) int {
+	if limit <= 0 {
+		return 20
+	}
+	return min(limit, 100)
+}
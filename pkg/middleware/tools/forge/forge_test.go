@@ -0,0 +1,142 @@
+package forge
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+// fakeForge records calls made through the tools
+type fakeForge struct {
+	query    IssueQuery
+	pr       NewPullRequest
+	comment  ReviewComment
+	commentN int
+}
+
+func (f *fakeForge) ListIssues(_ context.Context, query IssueQuery) ([]Issue, error) {
+	f.query = query
+	return []Issue{{Number: 7, Title: "Crash on start", State: "open"}}, nil
+}
+
+func (f *fakeForge) GetIssue(_ context.Context, number int) (*Issue, error) {
+	return &Issue{Number: number, Title: "Crash on start", Body: "stack trace"}, nil
+}
+
+func (f *fakeForge) CreatePullRequest(_ context.Context, pr NewPullRequest) (*PullRequest, error) {
+	f.pr = pr
+	return &PullRequest{Number: 12, Title: pr.Title, Head: pr.Head, Base: pr.Base, State: "open"}, nil
+}
+
+func (f *fakeForge) CommentOnPullRequest(_ context.Context, number int, comment ReviewComment) error {
+	f.commentN, f.comment = number, comment
+	return nil
+}
+
+func (f *fakeForge) CIStatus(_ context.Context, ref string) (*CIStatus, error) {
+	return &CIStatus{Ref: ref, State: "success"}, nil
+}
+
+func callTool(t *testing.T, forgeTools []tools.Tool, name, args string) (string, error) {
+	t.Helper()
+	for _, tool := range forgeTools {
+		if tool.Name() == name {
+			var buf bytes.Buffer
+			req := calque.NewRequest(context.Background(), strings.NewReader(args))
+			err := tool.ServeFlow(req, calque.NewResponse(&buf))
+			return buf.String(), err
+		}
+	}
+	t.Fatalf("tool %q not found", name)
+	return "", nil
+}
+
+func TestTools(t *testing.T) {
+	f := &fakeForge{}
+	forgeTools := Tools(f)
+	if len(forgeTools) != 5 {
+		t.Fatalf("len(Tools) = %d, want 5", len(forgeTools))
+	}
+
+	out, err := callTool(t, forgeTools, "forge_list_issues", `{"labels":["bug"],"search":"crash"}`)
+	if err != nil || !strings.Contains(out, `"number":7`) {
+		t.Errorf("forge_list_issues = %q, %v", out, err)
+	}
+	if f.query.Search != "crash" || len(f.query.Labels) != 1 {
+		t.Errorf("query = %+v", f.query)
+	}
+
+	out, err = callTool(t, forgeTools, "forge_get_issue", `{"number":3}`)
+	if err != nil || !strings.Contains(out, `"body":"stack trace"`) {
+		t.Errorf("forge_get_issue = %q, %v", out, err)
+	}
+
+	out, err = callTool(t, forgeTools, "forge_create_pull_request", `{"title":"Fix crash","head":"fix","base":"main","draft":true}`)
+	if err != nil || !strings.Contains(out, `"number":12`) || !f.pr.Draft {
+		t.Errorf("forge_create_pull_request = %q, %v (pr %+v)", out, err, f.pr)
+	}
+
+	if _, err := callTool(t, forgeTools, "forge_comment_pull_request", `{"number":12,"body":"nit","path":"main.go","line":4}`); err != nil {
+		t.Errorf("forge_comment_pull_request error = %v", err)
+	}
+	if f.commentN != 12 || f.comment.Path != "main.go" || f.comment.Line != 4 {
+		t.Errorf("comment = %d %+v", f.commentN, f.comment)
+	}
+
+	out, err = callTool(t, forgeTools, "forge_ci_status", `{"ref":"fix"}`)
+	if err != nil || !strings.Contains(out, `"state":"success"`) {
+		t.Errorf("forge_ci_status = %q, %v", out, err)
+	}
+}
+
+func TestToolsValidation(t *testing.T) {
+	forgeTools := Tools(&fakeForge{})
+
+	tests := []struct {
+		name    string
+		tool    string
+		args    string
+		wantErr string
+	}{
+		{"missing pr fields", "forge_create_pull_request", `{"title":"x"}`, "head and base are required"},
+		{"empty comment", "forge_comment_pull_request", `{"number":1}`, "body is required"},
+		{"path without line", "forge_comment_pull_request", `{"number":1,"body":"x","path":"a.go"}`, "given together"},
+		{"missing ref", "forge_ci_status", `{}`, "ref is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := callTool(t, forgeTools, tt.tool, tt.args); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestToolsReadOnly(t *testing.T) {
+	for _, tool := range Tools(&fakeForge{}, Config{ReadOnly: true}) {
+		if tool.Name() == "forge_create_pull_request" || tool.Name() == "forge_comment_pull_request" {
+			t.Errorf("read-only tools include %s", tool.Name())
+		}
+	}
+}
+
+func TestCombineChecks(t *testing.T) {
+	tests := []struct {
+		checks   []Check
+		expected string
+	}{
+		{nil, "none"},
+		{[]Check{{Status: "success"}, {Status: "success"}}, "success"},
+		{[]Check{{Status: "success"}, {Status: "pending"}}, "pending"},
+		{[]Check{{Status: "pending"}, {Status: "failure"}}, "failure"},
+	}
+	for _, tt := range tests {
+		if got := combineChecks(tt.checks); got != tt.expected {
+			t.Errorf("combineChecks(%v) = %q, want %q", tt.checks, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools/internal/httpapi"
+)
+
+// GitHubConfig configures the GitHub forge.
+type GitHubConfig struct {
+	// Owner and Repo identify the repository (required)
+	Owner string
+	Repo  string
+
+	// Token authenticates requests. Defaults to GITHUB_TOKEN.
+	Token string
+
+	// BaseURL is the REST API root. Default: https://api.github.com
+	// (use https://HOST/api/v3 for GitHub Enterprise Server)
+	BaseURL string
+
+	// HTTPClient is used for requests. Default: client with 30s timeout
+	HTTPClient *http.Client
+}
+
+// GitHub is a Forge backed by the GitHub REST API.
+type GitHub struct {
+	api  *httpapi.Client
+	repo string // "/repos/{owner}/{repo}"
+}
+
+// NewGitHub creates a GitHub forge for one repository.
+//
+// Example:
+//
+//	gh, err := forge.NewGitHub(forge.GitHubConfig{
+//		Owner: "calque-ai",
+//		Repo:  "go-calque",
+//	})
+func NewGitHub(config GitHubConfig) (*GitHub, error) {
+	if config.Owner == "" || config.Repo == "" {
+		return nil, calque.NewErr(context.Background(), "forge: github owner and repo are required")
+	}
+	if config.Token == "" {
+		config.Token = os.Getenv("GITHUB_TOKEN")
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.github.com"
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	headers := map[string]string{
+		"Accept":               "application/vnd.github+json",
+		"X-GitHub-Api-Version": "2022-11-28",
+	}
+	if config.Token != "" {
+		headers["Authorization"] = "Bearer " + config.Token
+	}
+	return &GitHub{
+		api:  &httpapi.Client{Prefix: "forge", Name: "github", BaseURL: config.BaseURL, Headers: headers, HTTP: config.HTTPClient},
+		repo: "/repos/" + url.PathEscape(config.Owner) + "/" + url.PathEscape(config.Repo),
+	}, nil
+}
+
+type githubIssue struct {
+	Number      int                     `json:"number"`
+	Title       string                  `json:"title"`
+	Body        string                  `json:"body"`
+	State       string                  `json:"state"`
+	HTMLURL     string                  `json:"html_url"`
+	User        struct{ Login string }  `json:"user"`
+	Labels      []struct{ Name string } `json:"labels"`
+	PullRequest *struct{}               `json:"pull_request"`
+}
+
+func (i githubIssue) toIssue() Issue {
+	issue := Issue{Number: i.Number, Title: i.Title, Body: i.Body, State: i.State, Author: i.User.Login, URL: i.HTMLURL}
+	for _, l := range i.Labels {
+		issue.Labels = append(issue.Labels, l.Name)
+	}
+	return issue
+}
+
+// ListIssues lists issues, excluding pull requests.
+func (g *GitHub) ListIssues(ctx context.Context, query IssueQuery) ([]Issue, error) {
+	state := query.State
+	if state == "" {
+		state = "open"
+	}
+	limit := queryLimit(query.Limit)
+
+	var found []githubIssue
+	if query.Search != "" {
+		q := fmt.Sprintf("repo:%s is:issue %s", strings.TrimPrefix(g.repo, "/repos/"), query.Search)
+		if state != "all" {
+			q += " state:" + state
+		}
+		for _, l := range query.Labels {
+			q += fmt.Sprintf(" label:%q", l)
+		}
+		var result struct {
+			Items []githubIssue `json:"items"`
+		}
+		path := "/search/issues?q=" + url.QueryEscape(q) + "&per_page=" + strconv.Itoa(limit)
+		if err := g.api.Do(ctx, http.MethodGet, path, nil, &result); err != nil {
+			return nil, err
+		}
+		found = result.Items
+	} else {
+		params := url.Values{"state": {state}, "per_page": {strconv.Itoa(limit)}}
+		if len(query.Labels) > 0 {
+			params.Set("labels", strings.Join(query.Labels, ","))
+		}
+		if err := g.api.Do(ctx, http.MethodGet, g.repo+"/issues?"+params.Encode(), nil, &found); err != nil {
+			return nil, err
+		}
+	}
+
+	issues := []Issue{}
+	for _, i := range found {
+		if i.PullRequest == nil {
+			issues = append(issues, i.toIssue())
+		}
+	}
+	return issues, nil
+}
+
+// GetIssue returns a single issue.
+func (g *GitHub) GetIssue(ctx context.Context, number int) (*Issue, error) {
+	var found githubIssue
+	if err := g.api.Do(ctx, http.MethodGet, g.repo+"/issues/"+strconv.Itoa(number), nil, &found); err != nil {
+		return nil, err
+	}
+	issue := found.toIssue()
+	return &issue, nil
+}
+
+type githubPull struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	State   string `json:"state"`
+	HTMLURL string `json:"html_url"`
+	Head    struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+// CreatePullRequest opens a pull request.
+func (g *GitHub) CreatePullRequest(ctx context.Context, pr NewPullRequest) (*PullRequest, error) {
+	body := map[string]any{"title": pr.Title, "body": pr.Body, "head": pr.Head, "base": pr.Base, "draft": pr.Draft}
+	var created githubPull
+	if err := g.api.Do(ctx, http.MethodPost, g.repo+"/pulls", body, &created); err != nil {
+		return nil, err
+	}
+	return &PullRequest{
+		Number: created.Number, Title: created.Title, State: created.State,
+		Head: created.Head.Ref, Base: created.Base.Ref, URL: created.HTMLURL,
+	}, nil
+}
+
+// CommentOnPullRequest posts a general or line review comment.
+func (g *GitHub) CommentOnPullRequest(ctx context.Context, number int, comment ReviewComment) error {
+	pr := strconv.Itoa(number)
+	if comment.Path == "" {
+		return g.api.Do(ctx, http.MethodPost, g.repo+"/issues/"+pr+"/comments", map[string]any{"body": comment.Body}, nil)
+	}
+
+	// line comments are anchored to the pull request's head commit
+	var pull githubPull
+	if err := g.api.Do(ctx, http.MethodGet, g.repo+"/pulls/"+pr, nil, &pull); err != nil {
+		return err
+	}
+	body := map[string]any{
+		"body":      comment.Body,
+		"commit_id": pull.Head.SHA,
+		"path":      comment.Path,
+		"line":      comment.Line,
+		"side":      "RIGHT",
+	}
+	return g.api.Do(ctx, http.MethodPost, g.repo+"/pulls/"+pr+"/comments", body, nil)
+}
+
+// CIStatus combines the check runs of a branch or commit.
+func (g *GitHub) CIStatus(ctx context.Context, ref string) (*CIStatus, error) {
+	var result struct {
+		CheckRuns []struct {
+			Name       string `json:"name"`
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+			HTMLURL    string `json:"html_url"`
+		} `json:"check_runs"`
+	}
+	if err := g.api.Do(ctx, http.MethodGet, g.repo+"/commits/"+url.PathEscape(ref)+"/check-runs?per_page=100", nil, &result); err != nil {
+		return nil, err
+	}
+
+	status := &CIStatus{Ref: ref, Checks: []Check{}}
+	for _, run := range result.CheckRuns {
+		check := Check{Name: run.Name, URL: run.HTMLURL, Status: "pending"}
+		if run.Status == "completed" {
+			switch run.Conclusion {
+			case "success", "neutral", "skipped":
+				check.Status = "success"
+			default:
+				check.Status = "failure"
+			}
+		}
+		status.Checks = append(status.Checks, check)
+	}
+	status.State = combineChecks(status.Checks)
+	return status, nil
+}
@@ -0,0 +1,115 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// recordingServer serves canned JSON per "METHOD path" and records request bodies
+func recordingServer(t *testing.T, routes map[string]string) (*httptest.Server, map[string]map[string]any) {
+	t.Helper()
+	bodies := make(map[string]map[string]any)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Method + " " + r.URL.Path
+		resp, ok := routes[key]
+		if !ok {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		if r.Body != nil {
+			var body map[string]any
+			if json.NewDecoder(r.Body).Decode(&body) == nil {
+				bodies[key] = body
+			}
+		}
+		bodies[key+" query"] = map[string]any{"raw": r.URL.RawQuery, "auth": r.Header.Get("Authorization") + r.Header.Get("PRIVATE-TOKEN")}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(resp))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, bodies
+}
+
+func TestGitHub(t *testing.T) {
+	srv, bodies := recordingServer(t, map[string]string{
+		"GET /repos/acme/app/issues": `[
+			{"number":1,"title":"Bug","state":"open","html_url":"u1","user":{"login":"ada"},"labels":[{"name":"bug"}]},
+			{"number":2,"title":"A PR","state":"open","pull_request":{}}
+		]`,
+		"GET /search/issues":                     `{"items":[{"number":3,"title":"Crash","state":"closed"}]}`,
+		"GET /repos/acme/app/issues/1":           `{"number":1,"title":"Bug","body":"details","state":"open"}`,
+		"POST /repos/acme/app/pulls":             `{"number":9,"title":"Fix","state":"open","html_url":"u9","head":{"ref":"fix"},"base":{"ref":"main"}}`,
+		"GET /repos/acme/app/pulls/9":            `{"number":9,"head":{"ref":"fix","sha":"abc123"}}`,
+		"POST /repos/acme/app/pulls/9/comments":  `{}`,
+		"POST /repos/acme/app/issues/9/comments": `{}`,
+		"GET /repos/acme/app/commits/fix/check-runs": `{"check_runs":[
+			{"name":"test","status":"completed","conclusion":"success"},
+			{"name":"lint","status":"completed","conclusion":"failure"}
+		]}`,
+	})
+
+	gh, err := NewGitHub(GitHubConfig{Owner: "acme", Repo: "app", Token: "tok", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	issues, err := gh.ListIssues(ctx, IssueQuery{Labels: []string{"bug"}})
+	if err != nil || len(issues) != 1 || issues[0].Author != "ada" || issues[0].Labels[0] != "bug" {
+		t.Errorf("ListIssues = %+v, %v", issues, err)
+	}
+	q := bodies["GET /repos/acme/app/issues query"]
+	if !strings.Contains(q["raw"].(string), "labels=bug") || q["auth"] != "Bearer tok" {
+		t.Errorf("list request = %v", q)
+	}
+
+	issues, err = gh.ListIssues(ctx, IssueQuery{Search: "crash", State: "all"})
+	if err != nil || len(issues) != 1 || issues[0].Number != 3 {
+		t.Errorf("ListIssues(search) = %+v, %v", issues, err)
+	}
+	if raw := bodies["GET /search/issues query"]["raw"].(string); !strings.Contains(raw, "repo%3Aacme%2Fapp") || strings.Contains(raw, "state") {
+		t.Errorf("search query = %s", raw)
+	}
+
+	issue, err := gh.GetIssue(ctx, 1)
+	if err != nil || issue.Body != "details" {
+		t.Errorf("GetIssue = %+v, %v", issue, err)
+	}
+
+	pr, err := gh.CreatePullRequest(ctx, NewPullRequest{Title: "Fix", Head: "fix", Base: "main"})
+	if err != nil || pr.Number != 9 || pr.Head != "fix" || pr.URL != "u9" {
+		t.Errorf("CreatePullRequest = %+v, %v", pr, err)
+	}
+
+	if err := gh.CommentOnPullRequest(ctx, 9, ReviewComment{Body: "nit", Path: "main.go", Line: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if body := bodies["POST /repos/acme/app/pulls/9/comments"]; body["commit_id"] != "abc123" || body["line"] != float64(3) {
+		t.Errorf("line comment body = %v", body)
+	}
+	if err := gh.CommentOnPullRequest(ctx, 9, ReviewComment{Body: "LGTM"}); err != nil {
+		t.Fatal(err)
+	}
+	if body := bodies["POST /repos/acme/app/issues/9/comments"]; body["body"] != "LGTM" {
+		t.Errorf("general comment body = %v", body)
+	}
+
+	status, err := gh.CIStatus(ctx, "fix")
+	if err != nil || status.State != "failure" || len(status.Checks) != 2 {
+		t.Errorf("CIStatus = %+v, %v", status, err)
+	}
+
+	if _, err := gh.GetIssue(ctx, 404); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Errorf("GetIssue(404) error = %v", err)
+	}
+}
+
+func TestNewGitHubRequiresRepo(t *testing.T) {
+	if _, err := NewGitHub(GitHubConfig{Owner: "acme"}); err == nil {
+		t.Error("NewGitHub without repo succeeded")
+	}
+}
@@ -0,0 +1,240 @@
+package forge
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools/internal/httpapi"
+)
+
+// GitLabConfig configures the GitLab forge.
+type GitLabConfig struct {
+	// Project is the project path ("group/name") or numeric ID (required)
+	Project string
+
+	// Token is a personal, project or group access token. Defaults to GITLAB_TOKEN.
+	Token string
+
+	// BaseURL is the GitLab instance. Default: https://gitlab.com
+	BaseURL string
+
+	// HTTPClient is used for requests. Default: client with 30s timeout
+	HTTPClient *http.Client
+}
+
+// GitLab is a Forge backed by the GitLab REST API (v4).
+// Pull requests map to merge requests and numbers to IIDs.
+type GitLab struct {
+	api     *httpapi.Client
+	project string // "/projects/{id}"
+}
+
+// NewGitLab creates a GitLab forge for one project.
+//
+// Example:
+//
+//	lab, err := forge.NewGitLab(forge.GitLabConfig{
+//		BaseURL: "https://gitlab.internal",
+//		Project: "platform/api",
+//	})
+func NewGitLab(config GitLabConfig) (*GitLab, error) {
+	if config.Project == "" {
+		return nil, calque.NewErr(context.Background(), "forge: gitlab project is required")
+	}
+	if config.Token == "" {
+		config.Token = os.Getenv("GITLAB_TOKEN")
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = "https://gitlab.com"
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	headers := map[string]string{}
+	if config.Token != "" {
+		headers["PRIVATE-TOKEN"] = config.Token
+	}
+	return &GitLab{
+		api:     &httpapi.Client{Prefix: "forge", Name: "gitlab", BaseURL: strings.TrimSuffix(config.BaseURL, "/") + "/api/v4", Headers: headers, HTTP: config.HTTPClient},
+		project: "/projects/" + url.PathEscape(config.Project),
+	}, nil
+}
+
+type gitlabIssue struct {
+	IID         int      `json:"iid"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	State       string   `json:"state"`
+	WebURL      string   `json:"web_url"`
+	Labels      []string `json:"labels"`
+	Author      struct {
+		Username string `json:"username"`
+	} `json:"author"`
+}
+
+func (i gitlabIssue) toIssue() Issue {
+	state := i.State
+	if state == "opened" {
+		state = "open"
+	}
+	return Issue{
+		Number: i.IID, Title: i.Title, Body: i.Description, State: state,
+		Author: i.Author.Username, Labels: i.Labels, URL: i.WebURL,
+	}
+}
+
+// ListIssues lists project issues.
+func (g *GitLab) ListIssues(ctx context.Context, query IssueQuery) ([]Issue, error) {
+	params := url.Values{"per_page": {strconv.Itoa(queryLimit(query.Limit))}}
+	switch query.State {
+	case "", "open":
+		params.Set("state", "opened")
+	case "closed":
+		params.Set("state", "closed")
+	}
+	if len(query.Labels) > 0 {
+		params.Set("labels", strings.Join(query.Labels, ","))
+	}
+	if query.Search != "" {
+		params.Set("search", query.Search)
+	}
+
+	var found []gitlabIssue
+	if err := g.api.Do(ctx, http.MethodGet, g.project+"/issues?"+params.Encode(), nil, &found); err != nil {
+		return nil, err
+	}
+	issues := make([]Issue, len(found))
+	for i, issue := range found {
+		issues[i] = issue.toIssue()
+	}
+	return issues, nil
+}
+
+// GetIssue returns a single issue by IID.
+func (g *GitLab) GetIssue(ctx context.Context, number int) (*Issue, error) {
+	var found gitlabIssue
+	if err := g.api.Do(ctx, http.MethodGet, g.project+"/issues/"+strconv.Itoa(number), nil, &found); err != nil {
+		return nil, err
+	}
+	issue := found.toIssue()
+	return &issue, nil
+}
+
+type gitlabMergeRequest struct {
+	IID          int    `json:"iid"`
+	Title        string `json:"title"`
+	State        string `json:"state"`
+	WebURL       string `json:"web_url"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+	DiffRefs     struct {
+		BaseSHA  string `json:"base_sha"`
+		HeadSHA  string `json:"head_sha"`
+		StartSHA string `json:"start_sha"`
+	} `json:"diff_refs"`
+}
+
+// CreatePullRequest opens a merge request.
+func (g *GitLab) CreatePullRequest(ctx context.Context, pr NewPullRequest) (*PullRequest, error) {
+	title := pr.Title
+	if pr.Draft {
+		title = "Draft: " + title
+	}
+	body := map[string]any{
+		"title":         title,
+		"description":   pr.Body,
+		"source_branch": pr.Head,
+		"target_branch": pr.Base,
+	}
+	var created gitlabMergeRequest
+	if err := g.api.Do(ctx, http.MethodPost, g.project+"/merge_requests", body, &created); err != nil {
+		return nil, err
+	}
+	state := created.State
+	if state == "opened" {
+		state = "open"
+	}
+	return &PullRequest{
+		Number: created.IID, Title: created.Title, State: state,
+		Head: created.SourceBranch, Base: created.TargetBranch, URL: created.WebURL,
+	}, nil
+}
+
+// CommentOnPullRequest posts a note, or a diff discussion for line comments.
+func (g *GitLab) CommentOnPullRequest(ctx context.Context, number int, comment ReviewComment) error {
+	mr := g.project + "/merge_requests/" + strconv.Itoa(number)
+	if comment.Path == "" {
+		return g.api.Do(ctx, http.MethodPost, mr+"/notes", map[string]any{"body": comment.Body}, nil)
+	}
+
+	var current gitlabMergeRequest
+	if err := g.api.Do(ctx, http.MethodGet, mr, nil, &current); err != nil {
+		return err
+	}
+	body := map[string]any{
+		"body": comment.Body,
+		"position": map[string]any{
+			"position_type": "text",
+			"base_sha":      current.DiffRefs.BaseSHA,
+			"head_sha":      current.DiffRefs.HeadSHA,
+			"start_sha":     current.DiffRefs.StartSHA,
+			"new_path":      comment.Path,
+			"old_path":      comment.Path,
+			"new_line":      comment.Line,
+		},
+	}
+	return g.api.Do(ctx, http.MethodPost, mr+"/discussions", body, nil)
+}
+
+// CIStatus reports the jobs of the latest pipeline for a branch or commit.
+func (g *GitLab) CIStatus(ctx context.Context, ref string) (*CIStatus, error) {
+	var commit struct {
+		LastPipeline *struct {
+			ID int `json:"id"`
+		} `json:"last_pipeline"`
+	}
+	if err := g.api.Do(ctx, http.MethodGet, g.project+"/repository/commits/"+url.PathEscape(ref), nil, &commit); err != nil {
+		return nil, err
+	}
+
+	status := &CIStatus{Ref: ref, Checks: []Check{}}
+	if commit.LastPipeline == nil {
+		status.State = combineChecks(nil)
+		return status, nil
+	}
+
+	var jobs []struct {
+		Name         string `json:"name"`
+		Status       string `json:"status"`
+		AllowFailure bool   `json:"allow_failure"`
+		WebURL       string `json:"web_url"`
+	}
+	path := g.project + "/pipelines/" + strconv.Itoa(commit.LastPipeline.ID) + "/jobs?per_page=100"
+	if err := g.api.Do(ctx, http.MethodGet, path, nil, &jobs); err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		check := Check{Name: job.Name, URL: job.WebURL}
+		switch job.Status {
+		case "success", "skipped", "manual":
+			check.Status = "success"
+		case "failed", "canceled":
+			check.Status = "failure"
+			if job.AllowFailure {
+				check.Status = "success"
+			}
+		default:
+			check.Status = "pending"
+		}
+		status.Checks = append(status.Checks, check)
+	}
+	status.State = combineChecks(status.Checks)
+	return status, nil
+}
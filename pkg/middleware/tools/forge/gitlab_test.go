@@ -0,0 +1,69 @@
+package forge
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestGitLab(t *testing.T) {
+	srv, bodies := recordingServer(t, map[string]string{
+		"GET /api/v4/projects/group/app/issues": `[
+			{"iid":4,"title":"Bug","description":"details","state":"opened","labels":["bug"],"author":{"username":"ada"}}
+		]`,
+		"POST /api/v4/projects/group/app/merge_requests":               `{"iid":5,"title":"Draft: Fix","state":"opened","source_branch":"fix","target_branch":"main"}`,
+		"GET /api/v4/projects/group/app/merge_requests/5":              `{"iid":5,"diff_refs":{"base_sha":"b","head_sha":"h","start_sha":"s"}}`,
+		"POST /api/v4/projects/group/app/merge_requests/5/discussions": `{}`,
+		"POST /api/v4/projects/group/app/merge_requests/5/notes":       `{}`,
+		"GET /api/v4/projects/group/app/repository/commits/fix":        `{"last_pipeline":{"id":77}}`,
+		"GET /api/v4/projects/group/app/pipelines/77/jobs": `[
+			{"name":"test","status":"success"},
+			{"name":"flaky","status":"failed","allow_failure":true},
+			{"name":"deploy","status":"running"}
+		]`,
+		"GET /api/v4/projects/group/app/repository/commits/new": `{"last_pipeline":null}`,
+	})
+
+	lab, err := NewGitLab(GitLabConfig{Project: "group/app", Token: "tok", BaseURL: srv.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	issues, err := lab.ListIssues(ctx, IssueQuery{Search: "bug"})
+	if err != nil || len(issues) != 1 || issues[0].Number != 4 || issues[0].State != "open" || issues[0].Body != "details" {
+		t.Errorf("ListIssues = %+v, %v", issues, err)
+	}
+	q := bodies["GET /api/v4/projects/group/app/issues query"]
+	if raw := q["raw"].(string); !strings.Contains(raw, "state=opened") || !strings.Contains(raw, "search=bug") || q["auth"] != "tok" {
+		t.Errorf("list request = %v", q)
+	}
+
+	pr, err := lab.CreatePullRequest(ctx, NewPullRequest{Title: "Fix", Head: "fix", Base: "main", Draft: true})
+	if err != nil || pr.Number != 5 || pr.State != "open" || pr.Head != "fix" {
+		t.Errorf("CreatePullRequest = %+v, %v", pr, err)
+	}
+	if body := bodies["POST /api/v4/projects/group/app/merge_requests"]; body["title"] != "Draft: Fix" || body["source_branch"] != "fix" {
+		t.Errorf("merge request body = %v", body)
+	}
+
+	if err := lab.CommentOnPullRequest(ctx, 5, ReviewComment{Body: "nit", Path: "main.go", Line: 8}); err != nil {
+		t.Fatal(err)
+	}
+	position, _ := bodies["POST /api/v4/projects/group/app/merge_requests/5/discussions"]["position"].(map[string]any)
+	if position["head_sha"] != "h" || position["new_line"] != float64(8) {
+		t.Errorf("discussion position = %v", position)
+	}
+	if err := lab.CommentOnPullRequest(ctx, 5, ReviewComment{Body: "LGTM"}); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := lab.CIStatus(ctx, "fix")
+	if err != nil || status.State != "pending" || len(status.Checks) != 3 || status.Checks[1].Status != "success" {
+		t.Errorf("CIStatus = %+v, %v", status, err)
+	}
+	status, err = lab.CIStatus(ctx, "new")
+	if err != nil || status.State != "none" {
+		t.Errorf("CIStatus(no pipeline) = %+v, %v", status, err)
+	}
+}
@@ -0,0 +1,359 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// GitConfig restricts what the Git tools may do.
+type GitConfig struct {
+	// AllowedPaths - slash-separated patterns (path.Match syntax, or a directory
+	// prefix ending in "/") limiting which files may be diffed and committed.
+	// Empty allows every path inside the repository.
+	AllowedPaths []string
+	// AllowPush - adds a git_push tool. Force pushes are never possible.
+	AllowPush bool
+	// ProtectedBranches - branches that cannot be committed to or pushed
+	// (default: main, master)
+	ProtectedBranches []string
+	// Remote - remote used by git_push (default: origin)
+	Remote string
+	// MaxLogEntries - upper bound for git_log (default: 50)
+	MaxLogEntries int
+}
+
+// DefaultGitConfig returns the default Git tool configuration.
+func DefaultGitConfig() GitConfig {
+	return GitConfig{
+		ProtectedBranches: []string{"main", "master"},
+		Remote:            "origin",
+		MaxLogEntries:     50,
+	}
+}
+
+// Git creates tools for managing the repository at repoPath.
+//
+// Returns git_status, git_diff, git_log, git_branch and git_commit tools, plus
+// git_push when GitConfig.AllowPush is set. The tools run the git binary with
+// these protections:
+//   - no force pushes, history rewrites, resets or option injection
+//   - commits and diffs limited to GitConfig.AllowedPaths, and never outside repoPath
+//   - commits and pushes refused on GitConfig.ProtectedBranches
+//
+// Example:
+//
+//	gitTools := tools.Git("./workspace", tools.GitConfig{
+//	    AllowedPaths: []string{"src/", "docs/*.md"},
+//	})
+//	agent := ai.Agent(client, ai.WithTools(gitTools...))
+func Git(repoPath string, config ...GitConfig) []Tool {
+	cfg := DefaultGitConfig()
+	if len(config) > 0 {
+		cfg = config[0]
+		defaults := DefaultGitConfig()
+		if cfg.ProtectedBranches == nil {
+			cfg.ProtectedBranches = defaults.ProtectedBranches
+		}
+		if cfg.Remote == "" {
+			cfg.Remote = defaults.Remote
+		}
+		if cfg.MaxLogEntries <= 0 {
+			cfg.MaxLogEntries = defaults.MaxLogEntries
+		}
+	}
+	g := &gitRepo{path: repoPath, config: cfg}
+
+	gitTools := []Tool{
+		Typed("git_status", "Show the current branch and changed files in the repository", g.status),
+		Typed("git_diff", "Show changes in the working tree, the staging area, or against a commit", g.diff),
+		Typed("git_log", "List recent commits, optionally for a single path", g.log),
+		Typed("git_branch", "List branches, or create or switch to a branch", g.branch),
+		Typed("git_commit", "Stage the given files and commit them with a message", g.commit),
+	}
+	if cfg.AllowPush {
+		gitTools = append(gitTools, Typed("git_push", "Push a branch to the remote (never forced)", g.push))
+	}
+	return gitTools
+}
+
+type gitRepo struct {
+	path   string
+	config GitConfig
+}
+
+type gitStatusArgs struct{}
+
+type gitDiffArgs struct {
+	Paths  []string `json:"paths,omitempty" jsonschema:"description=Limit the diff to these files"`
+	Staged bool     `json:"staged,omitempty" jsonschema:"description=Show staged changes instead of unstaged ones"`
+	Ref    string   `json:"ref,omitempty" jsonschema:"description=Compare against this commit or branch"`
+}
+
+type gitLogArgs struct {
+	Limit int    `json:"limit,omitempty" jsonschema:"description=Number of commits to show (default 10)"`
+	Path  string `json:"path,omitempty" jsonschema:"description=Only show commits touching this path"`
+}
+
+type gitBranchArgs struct {
+	Name     string `json:"name,omitempty" jsonschema:"description=Branch to create or switch to; omit to list branches"`
+	Checkout bool   `json:"checkout,omitempty" jsonschema:"description=Switch to the branch after creating it"`
+}
+
+type gitCommitArgs struct {
+	Message string   `json:"message" jsonschema:"required,description=Commit message"`
+	Paths   []string `json:"paths" jsonschema:"required,description=Files to stage and commit"`
+}
+
+type gitPushArgs struct {
+	Branch string `json:"branch,omitempty" jsonschema:"description=Branch to push (default: current branch)"`
+}
+
+func (g *gitRepo) status(ctx context.Context, _ gitStatusArgs) (string, error) {
+	return g.run(ctx, "status", "--short", "--branch")
+}
+
+func (g *gitRepo) diff(ctx context.Context, args gitDiffArgs) (string, error) {
+	if err := g.checkPaths(ctx, args.Paths, false); err != nil {
+		return "", err
+	}
+	cmd := []string{"diff"}
+	if args.Staged {
+		cmd = append(cmd, "--cached")
+	}
+	if args.Ref != "" {
+		if err := checkGitRef(ctx, args.Ref); err != nil {
+			return "", err
+		}
+		cmd = append(cmd, args.Ref)
+	}
+	cmd = append(cmd, "--")
+	cmd = append(cmd, g.pathspecs(args.Paths)...)
+
+	out, err := g.run(ctx, cmd...)
+	if err == nil && out == "" {
+		out = "no changes"
+	}
+	return out, err
+}
+
+func (g *gitRepo) log(ctx context.Context, args gitLogArgs) (string, error) {
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	limit = min(limit, g.config.MaxLogEntries)
+
+	cmd := []string{"log", "-n", strconv.Itoa(limit), "--date=short", "--format=%h %ad %an: %s", "--"}
+	if args.Path != "" {
+		if err := g.checkPaths(ctx, []string{args.Path}, false); err != nil {
+			return "", err
+		}
+		cmd = append(cmd, g.pathspecs([]string{args.Path})...)
+	}
+	return g.run(ctx, cmd...)
+}
+
+func (g *gitRepo) branch(ctx context.Context, args gitBranchArgs) (string, error) {
+	if args.Name == "" {
+		return g.run(ctx, "branch", "--list")
+	}
+	if err := checkGitRef(ctx, args.Name); err != nil {
+		return "", err
+	}
+	if _, err := g.run(ctx, "check-ref-format", "--branch", args.Name); err != nil {
+		return "", calque.NewErr(ctx, fmt.Sprintf("invalid branch name %q", args.Name))
+	}
+
+	exists := g.branchExists(ctx, args.Name)
+	switch {
+	case exists && args.Checkout:
+		if _, err := g.run(ctx, "switch", args.Name); err != nil {
+			return "", err
+		}
+		return "switched to branch " + args.Name, nil
+	case exists:
+		return "", calque.NewErr(ctx, fmt.Sprintf("branch %q already exists", args.Name))
+	case args.Checkout:
+		if _, err := g.run(ctx, "switch", "-c", args.Name); err != nil {
+			return "", err
+		}
+		return "created and switched to branch " + args.Name, nil
+	default:
+		if _, err := g.run(ctx, "branch", args.Name); err != nil {
+			return "", err
+		}
+		return "created branch " + args.Name, nil
+	}
+}
+
+func (g *gitRepo) commit(ctx context.Context, args gitCommitArgs) (string, error) {
+	if strings.TrimSpace(args.Message) == "" {
+		return "", calque.NewErr(ctx, "commit message is required")
+	}
+	if len(args.Paths) == 0 {
+		return "", calque.NewErr(ctx, "at least one path is required")
+	}
+	if err := g.checkPaths(ctx, args.Paths, true); err != nil {
+		return "", err
+	}
+	if err := g.checkBranchWritable(ctx, ""); err != nil {
+		return "", err
+	}
+
+	pathspecs := g.pathspecs(args.Paths)
+	if _, err := g.run(ctx, append([]string{"add", "--"}, pathspecs...)...); err != nil {
+		return "", err
+	}
+	if _, err := g.run(ctx, append([]string{"commit", "--no-verify", "-m", args.Message, "--"}, pathspecs...)...); err != nil {
+		return "", err
+	}
+	return g.run(ctx, "log", "-1", "--format=%h %s")
+}
+
+func (g *gitRepo) push(ctx context.Context, args gitPushArgs) (string, error) {
+	branch := args.Branch
+	if branch == "" {
+		current, err := g.currentBranch(ctx)
+		if err != nil {
+			return "", err
+		}
+		branch = current
+	}
+	if err := checkGitRef(ctx, branch); err != nil {
+		return "", err
+	}
+	if err := g.checkBranchWritable(ctx, branch); err != nil {
+		return "", err
+	}
+
+	// an explicit refs/heads refspec without "+" can only fast-forward
+	refspec := "refs/heads/" + branch + ":refs/heads/" + branch
+	out, err := g.run(ctx, "push", "--porcelain", g.config.Remote, refspec)
+	if err != nil {
+		return "", err
+	}
+	if out == "" {
+		out = "pushed " + branch
+	}
+	return out, nil
+}
+
+// run executes git in the repository and returns its trimmed output
+func (g *gitRepo) run(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", g.path, "-c", "core.pager=cat"}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", calque.NewErr(ctx, fmt.Sprintf("git %s failed: %s", args[0], msg))
+	}
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
+
+func (g *gitRepo) currentBranch(ctx context.Context) (string, error) {
+	branch, err := g.run(ctx, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return "", calque.NewErr(ctx, "repository is not on a branch")
+	}
+	return branch, nil
+}
+
+func (g *gitRepo) branchExists(ctx context.Context, name string) bool {
+	_, err := g.run(ctx, "show-ref", "--verify", "--quiet", "refs/heads/"+name)
+	return err == nil
+}
+
+// checkBranchWritable refuses protected branches; empty branch means the current one
+func (g *gitRepo) checkBranchWritable(ctx context.Context, branch string) error {
+	if branch == "" {
+		current, err := g.currentBranch(ctx)
+		if err != nil {
+			return err
+		}
+		branch = current
+	}
+	if slices.Contains(g.config.ProtectedBranches, branch) {
+		return calque.NewErr(ctx, fmt.Sprintf("branch %q is protected", branch))
+	}
+	return nil
+}
+
+// checkPaths rejects paths outside the repository or the allowlist
+func (g *gitRepo) checkPaths(ctx context.Context, paths []string, forWrite bool) error {
+	for _, p := range paths {
+		clean, ok := cleanRepoPath(p)
+		if !ok {
+			return calque.NewErr(ctx, fmt.Sprintf("path %q is outside the repository", p))
+		}
+		if forWrite && (clean == "." || strings.HasPrefix(clean, ".git/") || clean == ".git") {
+			return calque.NewErr(ctx, fmt.Sprintf("path %q cannot be committed", p))
+		}
+		if !g.pathAllowed(clean) {
+			return calque.NewErr(ctx, fmt.Sprintf("path %q is not in the allowed paths", p))
+		}
+	}
+	return nil
+}
+
+func (g *gitRepo) pathAllowed(p string) bool {
+	if len(g.config.AllowedPaths) == 0 {
+		return true
+	}
+	for _, pattern := range g.config.AllowedPaths {
+		if dir, ok := strings.CutSuffix(pattern, "/"); ok {
+			if p == dir || strings.HasPrefix(p, dir+"/") {
+				return true
+			}
+			continue
+		}
+		if matched, _ := path.Match(pattern, p); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// pathspecs converts validated paths to literal git pathspecs
+func (g *gitRepo) pathspecs(paths []string) []string {
+	specs := make([]string, 0, len(paths))
+	for _, p := range paths {
+		clean, _ := cleanRepoPath(p)
+		specs = append(specs, ":(literal)"+clean)
+	}
+	return specs
+}
+
+// cleanRepoPath normalizes a repository-relative path, reporting false if it escapes
+func cleanRepoPath(p string) (string, bool) {
+	p = filepath.ToSlash(strings.TrimSpace(p))
+	if p == "" || path.IsAbs(p) || filepath.IsAbs(p) {
+		return "", false
+	}
+	clean := path.Clean(p)
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", false
+	}
+	return clean, true
+}
+
+// checkGitRef rejects refs that git could interpret as options or refspecs
+func checkGitRef(ctx context.Context, ref string) error {
+	if ref == "" || strings.HasPrefix(ref, "-") || strings.ContainsAny(ref, " \t\n:+") {
+		return calque.NewErr(ctx, fmt.Sprintf("invalid ref %q", ref))
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func gitCmd(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newTestRepo creates a repository on branch main with one commit
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	gitCmd(t, dir, "init", "-q", "-b", "main")
+	gitCmd(t, dir, "config", "user.email", "test@example.com")
+	gitCmd(t, dir, "config", "user.name", "Test")
+	writeRepoFile(t, dir, "README.md", "hello\n")
+	gitCmd(t, dir, "add", ".")
+	gitCmd(t, dir, "commit", "-q", "-m", "initial")
+	return dir
+}
+
+func writeRepoFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	full := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func callTool(t *testing.T, gitTools []Tool, name, args string) (string, error) {
+	t.Helper()
+	for _, tool := range gitTools {
+		if tool.Name() == name {
+			var buf bytes.Buffer
+			req := calque.NewRequest(context.Background(), strings.NewReader(args))
+			err := tool.ServeFlow(req, calque.NewResponse(&buf))
+			return buf.String(), err
+		}
+	}
+	t.Fatalf("tool %q not found", name)
+	return "", nil
+}
+
+func TestGitTools(t *testing.T) {
+	dir := newTestRepo(t)
+	gitTools := Git(dir, GitConfig{AllowedPaths: []string{"src/", "*.md"}})
+
+	names := make([]string, len(gitTools))
+	for i, tool := range gitTools {
+		names[i] = tool.Name()
+		if tool.ParametersSchema() == nil || tool.ParametersSchema().Type != "object" {
+			t.Errorf("%s schema = %+v, want object", tool.Name(), tool.ParametersSchema())
+		}
+	}
+	if got := strings.Join(names, ","); got != "git_status,git_diff,git_log,git_branch,git_commit" {
+		t.Errorf("tools = %s", got)
+	}
+
+	writeRepoFile(t, dir, "src/app.go", "package app\n")
+	writeRepoFile(t, dir, "README.md", "hello world\n")
+
+	status, err := callTool(t, gitTools, "git_status", "{}")
+	if err != nil || !strings.Contains(status, "?? src/") || !strings.Contains(status, "## main") {
+		t.Errorf("git_status = %q, %v", status, err)
+	}
+
+	diff, err := callTool(t, gitTools, "git_diff", `{"paths":["README.md"]}`)
+	if err != nil || !strings.Contains(diff, "+hello world") {
+		t.Errorf("git_diff = %q, %v", diff, err)
+	}
+
+	if _, err := callTool(t, gitTools, "git_commit", `{"message":"add app","paths":["src/app.go"]}`); err == nil ||
+		!strings.Contains(err.Error(), "protected") {
+		t.Errorf("commit on main error = %v, want protected", err)
+	}
+
+	if out, err := callTool(t, gitTools, "git_branch", `{"name":"feature","checkout":true}`); err != nil ||
+		!strings.Contains(out, "created and switched") {
+		t.Fatalf("git_branch = %q, %v", out, err)
+	}
+
+	out, err := callTool(t, gitTools, "git_commit", `{"message":"add app","paths":["src/app.go"]}`)
+	if err != nil || !strings.Contains(out, "add app") {
+		t.Fatalf("git_commit = %q, %v", out, err)
+	}
+	// only the listed path is committed
+	if status := gitCmd(t, dir, "status", "--short"); status != "M README.md" {
+		t.Errorf("status after commit = %q", status)
+	}
+
+	log, err := callTool(t, gitTools, "git_log", `{"limit":5}`)
+	if err != nil || !strings.Contains(log, "add app") || !strings.Contains(log, "initial") {
+		t.Errorf("git_log = %q, %v", log, err)
+	}
+
+	branches, err := callTool(t, gitTools, "git_branch", `{}`)
+	if err != nil || !strings.Contains(branches, "* feature") || !strings.Contains(branches, "main") {
+		t.Errorf("git_branch list = %q, %v", branches, err)
+	}
+}
+
+func TestGitToolsProtections(t *testing.T) {
+	dir := newTestRepo(t)
+	gitTools := Git(dir, GitConfig{AllowedPaths: []string{"src/"}, ProtectedBranches: []string{}})
+
+	tests := []struct {
+		name    string
+		tool    string
+		args    string
+		wantErr string
+	}{
+		{"path outside allowlist", "git_commit", `{"message":"m","paths":["README.md"]}`, "not in the allowed paths"},
+		{"path escaping repo", "git_diff", `{"paths":["../etc/passwd"]}`, "outside the repository"},
+		{"absolute path", "git_commit", `{"message":"m","paths":["/etc/passwd"]}`, "outside the repository"},
+		{"option injection in ref", "git_diff", `{"ref":"--output=/tmp/x"}`, "invalid ref"},
+		{"option injection in branch", "git_branch", `{"name":"-D"}`, "invalid ref"},
+		{"empty message", "git_commit", `{"message":" ","paths":["src/a"]}`, "message is required"},
+		{"no paths", "git_commit", `{"message":"m"}`, "at least one path"},
+		{"push disabled", "git_push", `{}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.tool == "git_push" {
+				for _, tool := range gitTools {
+					if tool.Name() == "git_push" {
+						t.Error("git_push available without AllowPush")
+					}
+				}
+				return
+			}
+			_, err := callTool(t, gitTools, tt.tool, tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s error = %v, want containing %q", tt.tool, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGitPush(t *testing.T) {
+	dir := newTestRepo(t)
+	remote := t.TempDir()
+	gitCmd(t, remote, "init", "-q", "--bare")
+	gitCmd(t, dir, "remote", "add", "origin", remote)
+	gitCmd(t, dir, "switch", "-q", "-c", "feature")
+
+	gitTools := Git(dir, GitConfig{AllowPush: true})
+
+	if _, err := callTool(t, gitTools, "git_push", `{"branch":"main"}`); err == nil || !strings.Contains(err.Error(), "protected") {
+		t.Errorf("push main error = %v, want protected", err)
+	}
+	if _, err := callTool(t, gitTools, "git_push", `{"branch":"+feature"}`); err == nil || !strings.Contains(err.Error(), "invalid ref") {
+		t.Errorf("forced refspec error = %v, want invalid ref", err)
+	}
+	if _, err := callTool(t, gitTools, "git_push", `{}`); err != nil {
+		t.Fatalf("push feature error = %v", err)
+	}
+
+	// rewrite local history; a non-fast-forward push must be rejected
+	writeRepoFile(t, dir, "README.md", "rewritten\n")
+	gitCmd(t, dir, "commit", "-q", "-a", "--amend", "-m", "rewritten")
+	if _, err := callTool(t, gitTools, "git_push", `{}`); err == nil {
+		t.Error("non-fast-forward push succeeded")
+	}
+}
@@ -0,0 +1,80 @@
+// Package httpapi is the JSON REST client shared by the forge, tickets and
+// workspace tools.
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/netpolicy"
+)
+
+// maxErrorBody caps how much of a failed response is quoted in the error
+const maxErrorBody = 4096
+
+// Client sends JSON requests to a REST API through the network policy in the
+// request context.
+type Client struct {
+	Prefix  string            // package prefixing error messages, e.g. "forge"
+	Name    string            // service name for error messages, e.g. "github"
+	BaseURL string            // prepended to request paths; empty when paths are full URLs
+	Headers map[string]string // set on every request
+	HTTP    *http.Client      // nil = http.DefaultClient
+
+	// Auth, when set, adds credentials to each request before it is sent
+	Auth func(ctx context.Context, req *http.Request) error
+}
+
+// Do sends a request with an optional JSON body and decodes the JSON response into out
+func (c *Client) Do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return calque.WrapErr(ctx, err, c.Prefix+": failed to encode "+c.Name+" request")
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+path, reader)
+	if err != nil {
+		return calque.WrapErr(ctx, err, c.Prefix+": failed to create "+c.Name+" request")
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+	if c.Auth != nil {
+		if err := c.Auth(ctx, req); err != nil {
+			return err
+		}
+	}
+
+	resp, err := netpolicy.HTTPClient(ctx, c.HTTP).Do(req)
+	if err != nil {
+		return calque.WrapErr(ctx, err, c.Prefix+": "+c.Name+" request failed")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return calque.NewErr(ctx, fmt.Sprintf("%s: %s %s %s returned status %d: %s",
+			c.Prefix, c.Name, method, path, resp.StatusCode, strings.TrimSpace(string(msg))))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return calque.WrapErr(ctx, err, c.Prefix+": failed to decode "+c.Name+" response")
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientDo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"auth":    r.Header.Get("Authorization"),
+			"header":  r.Header.Get("X-Test"),
+			"type":    r.Header.Get("Content-Type"),
+			"message": body["message"],
+		})
+	}))
+	defer server.Close()
+
+	c := &Client{
+		Prefix:  "tools",
+		Name:    "test",
+		BaseURL: server.URL + "/",
+		Headers: map[string]string{"X-Test": "yes"},
+		Auth: func(_ context.Context, req *http.Request) error {
+			req.Header.Set("Authorization", "Bearer t")
+			return nil
+		},
+	}
+	ctx := context.Background()
+
+	var got map[string]string
+	if err := c.Do(ctx, http.MethodPost, "/echo", map[string]string{"message": "hi"}, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"auth": "Bearer t", "header": "yes", "type": "application/json", "message": "hi"}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+
+	err := c.Do(ctx, http.MethodGet, "/missing", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "tools: test GET /missing returned status 404: not found") {
+		t.Errorf("status error = %v", err)
+	}
+
+	c.Auth = func(context.Context, *http.Request) error { return errors.New("no token") }
+	if err := c.Do(ctx, http.MethodGet, "/echo", nil, nil); err == nil || err.Error() != "no token" {
+		t.Errorf("auth error = %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"context"
+	"slices"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// MetadataScopes is the MetadataBus key holding the caller's granted scopes.
+// It matches auth.MetadataScopes, so scopes from tokens validated by the auth
+// package apply without extra wiring.
+const MetadataScopes = "auth.scopes"
+
+// ScopedTool is implemented by tools that require caller scopes.
+type ScopedTool interface {
+	Tool
+	RequiredScopes() []string
+}
+
+// scopedTool wraps a tool with the scopes a caller must hold to use it
+type scopedTool struct {
+	Tool
+	scopes []string
+}
+
+func (s *scopedTool) RequiredScopes() []string {
+	return s.scopes
+}
+
+// RequireScopes restricts a tool to callers holding every listed scope.
+//
+// Registry hides the tool from requests whose caller lacks a scope, so the
+// model never sees it and calls to it fail as an unknown tool. Tools without
+// required scopes stay available to every caller.
+//
+// Example:
+//
+//	deleteUser := tools.RequireScopes(
+//	    tools.Simple("delete_user", "Delete a user account", deleteFn),
+//	    "admin",
+//	)
+//	agent := ai.Agent(client, ai.WithTools(searchTool, deleteUser))
+func RequireScopes(tool Tool, scopes ...string) Tool {
+	return &scopedTool{Tool: tool, scopes: scopes}
+}
+
+// RequiredScopes returns the scopes a caller must hold to use tool, or nil.
+func RequiredScopes(tool Tool) []string {
+	if scoped, ok := tool.(ScopedTool); ok {
+		return scoped.RequiredScopes()
+	}
+	return nil
+}
+
+// WithCallerScopes stores the caller's scopes on the context's MetadataBus.
+//
+// Use it when identity comes from somewhere other than the auth package.
+// A MetadataBus is created if the context doesn't carry one.
+//
+// Example:
+//
+//	ctx = tools.WithCallerScopes(ctx, "tickets:read")
+//	flow.Run(ctx, input, &output)
+func WithCallerScopes(ctx context.Context, scopes ...string) context.Context {
+	mb := calque.GetMetadataBus(ctx)
+	if mb == nil {
+		mb = calque.NewMetadataBus(0)
+		ctx = calque.WithMetadataBus(ctx, mb)
+	}
+	mb.Set(MetadataScopes, scopes)
+	return ctx
+}
+
+// CallerScopes returns the caller's scopes from the context's MetadataBus.
+// Returns nil if no identity is present.
+func CallerScopes(ctx context.Context) []string {
+	mb := calque.GetMetadataBus(ctx)
+	if mb == nil {
+		return nil
+	}
+	if v, ok := mb.Get(MetadataScopes); ok {
+		if scopes, ok := v.([]string); ok {
+			return scopes
+		}
+	}
+	return nil
+}
+
+// Permitted reports whether the caller in ctx may use tool.
+func Permitted(ctx context.Context, tool Tool) bool {
+	required := RequiredScopes(tool)
+	if len(required) == 0 {
+		return true
+	}
+	granted := CallerScopes(ctx)
+	for _, scope := range required {
+		if !slices.Contains(granted, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterPermitted returns the tools the caller in ctx may use, in order.
+func FilterPermitted(ctx context.Context, tools []Tool) []Tool {
+	var allowed []Tool
+	for _, tool := range tools {
+		if Permitted(ctx, tool) {
+			allowed = append(allowed, tool)
+		}
+	}
+	return allowed
+}
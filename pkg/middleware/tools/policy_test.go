@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestPermitted(t *testing.T) {
+	search := Simple("search", "Search the web", func(s string) string { return s })
+	admin := RequireScopes(Simple("delete_user", "Delete a user", func(s string) string { return s }), "admin", "users:write")
+
+	tests := []struct {
+		name   string
+		scopes []string
+		tool   Tool
+		want   bool
+	}{
+		{name: "unscoped tool without identity", tool: search, want: true},
+		{name: "scoped tool without identity", tool: admin, want: false},
+		{name: "scoped tool with partial scopes", scopes: []string{"admin"}, tool: admin, want: false},
+		{name: "scoped tool with all scopes", scopes: []string{"users:write", "admin", "other"}, tool: admin, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.scopes != nil {
+				ctx = WithCallerScopes(ctx, tt.scopes...)
+			}
+			if got := Permitted(ctx, tt.tool); got != tt.want {
+				t.Errorf("Permitted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireScopesPreservesTool(t *testing.T) {
+	base := Simple("delete_user", "Delete a user", func(s string) string { return "deleted " + s })
+	tool := RequireScopes(base, "admin")
+
+	if tool.Name() != "delete_user" || tool.Description() != "Delete a user" {
+		t.Errorf("metadata = %q/%q, want delete_user/Delete a user", tool.Name(), tool.Description())
+	}
+	if got := RequiredScopes(tool); !slices.Equal(got, []string{"admin"}) {
+		t.Errorf("RequiredScopes() = %v, want [admin]", got)
+	}
+	if got := RequiredScopes(base); got != nil {
+		t.Errorf("RequiredScopes(unscoped) = %v, want nil", got)
+	}
+
+	var buf bytes.Buffer
+	req := calque.NewRequest(context.Background(), strings.NewReader("bob"))
+	if err := tool.ServeFlow(req, calque.NewResponse(&buf)); err != nil {
+		t.Fatalf("ServeFlow() error = %v", err)
+	}
+	if buf.String() != "deleted bob" {
+		t.Errorf("ServeFlow() = %q, want %q", buf.String(), "deleted bob")
+	}
+}
+
+func TestRegistryFiltersByCallerScopes(t *testing.T) {
+	search := Simple("search", "Search the web", func(s string) string { return s })
+	admin := RequireScopes(Simple("delete_user", "Delete a user", func(s string) string { return s }), "admin")
+
+	tests := []struct {
+		name   string
+		scopes []string
+		want   []string
+	}{
+		{name: "anonymous caller", want: []string{"search"}},
+		{name: "user caller", scopes: []string{"flows:read"}, want: []string{"search"}},
+		{name: "admin caller", scopes: []string{"admin"}, want: []string{"search", "delete_user"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.scopes != nil {
+				ctx = WithCallerScopes(ctx, tt.scopes...)
+			}
+
+			var buf bytes.Buffer
+			req := calque.NewRequest(ctx, strings.NewReader("input"))
+			if err := Registry(search, admin).ServeFlow(req, calque.NewResponse(&buf)); err != nil {
+				t.Fatalf("Registry.ServeFlow() error = %v", err)
+			}
+
+			if got := ListToolNames(req.Context); !slices.Equal(got, tt.want) {
+				t.Errorf("ListToolNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteRejectsUnpermittedTool(t *testing.T) {
+	search := Simple("search", "Search the web", func(s string) string { return s })
+	admin := RequireScopes(Simple("delete_user", "Delete a user", func(s string) string { return s }), "admin")
+	input := `{"tool_calls": [{"type": "function", "function": {"name": "delete_user", "arguments": "{\"input\": \"bob\"}"}}]}`
+
+	// Registry and Execute share the request, as they do inside ai.Agent
+	handler := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var discard bytes.Buffer
+		regReq := calque.NewRequest(req.Context, strings.NewReader(""))
+		if err := Registry(search, admin).ServeFlow(regReq, calque.NewResponse(&discard)); err != nil {
+			return err
+		}
+		req.Context = regReq.Context
+		return Execute().ServeFlow(req, res)
+	})
+
+	var buf bytes.Buffer
+	req := calque.NewRequest(WithCallerScopes(context.Background(), "flows:read"), strings.NewReader(input))
+	err := handler.ServeFlow(req, calque.NewResponse(&buf))
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("ServeFlow() error = %v, want tool not found", err)
+	}
+}
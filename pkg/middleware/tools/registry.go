@@ -21,6 +21,9 @@ type toolsContextKey struct{}
 // Output: same as input (pass-through)
 // Behavior: STREAMING - makes tools available via GetTools() within handler execution
 //
+// Tools declared with RequireScopes are only registered when the caller's
+// scopes (see CallerScopes) include every required scope.
+//
 // Example:
 //
 //	registry := tools.Registry(calculatorTool, searchTool)
@@ -35,8 +38,8 @@ type registryHandler struct {
 }
 
 func (rh *registryHandler) ServeFlow(req *calque.Request, res *calque.Response) error {
-	// Create a context with tools for this handler's execution
-	ctx := context.WithValue(req.Context, toolsContextKey{}, rh.tools)
+	// Create a context with the caller's permitted tools for this handler's execution
+	ctx := context.WithValue(req.Context, toolsContextKey{}, FilterPermitted(req.Context, rh.tools))
 
 	// Update the request context directly so downstream handlers can access tools
 	req.Context = ctx
@@ -0,0 +1,215 @@
+package tickets
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools/internal/httpapi"
+)
+
+// JiraConfig configures the Jira tracker.
+type JiraConfig struct {
+	// BaseURL is the Jira site, e.g. https://acme.atlassian.net. Defaults to JIRA_BASE_URL.
+	BaseURL string
+
+	// Project is the project key searches and new tickets are scoped to (required)
+	Project string
+
+	// Email and Token authenticate with an Atlassian API token (basic auth).
+	// Without Email, Token is sent as a bearer personal access token (Data Center).
+	// Default to JIRA_EMAIL and JIRA_API_TOKEN.
+	Email string
+	Token string
+
+	// HTTPClient is used for requests. Default: client with 30s timeout
+	HTTPClient *http.Client
+}
+
+// Jira is a Tracker backed by the Jira REST API (v2).
+type Jira struct {
+	api     *httpapi.Client
+	baseURL string
+	project string
+}
+
+// NewJira creates a Jira tracker for one project.
+//
+// Example:
+//
+//	jira, err := tickets.NewJira(tickets.JiraConfig{
+//		BaseURL: "https://acme.atlassian.net",
+//		Project: "OPS",
+//	})
+func NewJira(config JiraConfig) (*Jira, error) {
+	if config.BaseURL == "" {
+		config.BaseURL = os.Getenv("JIRA_BASE_URL")
+	}
+	if config.Email == "" {
+		config.Email = os.Getenv("JIRA_EMAIL")
+	}
+	if config.Token == "" {
+		config.Token = os.Getenv("JIRA_API_TOKEN")
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	ctx := context.Background()
+	if config.BaseURL == "" {
+		return nil, calque.NewErr(ctx, "tickets: jira base URL is required (JiraConfig.BaseURL or JIRA_BASE_URL)")
+	}
+	if config.Project == "" {
+		return nil, calque.NewErr(ctx, "tickets: jira project is required")
+	}
+
+	headers := map[string]string{}
+	switch {
+	case config.Email != "":
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(config.Email+":"+config.Token))
+	case config.Token != "":
+		headers["Authorization"] = "Bearer " + config.Token
+	}
+	baseURL := strings.TrimSuffix(config.BaseURL, "/")
+	return &Jira{
+		api:     &httpapi.Client{Prefix: "tickets", Name: "jira", BaseURL: baseURL + "/rest/api/2", Headers: headers, HTTP: config.HTTPClient},
+		baseURL: baseURL,
+		project: config.Project,
+	}, nil
+}
+
+// jiraFields are the issue fields requested from Jira
+var jiraFields = []string{"summary", "description", "status", "assignee", "labels"}
+
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string   `json:"summary"`
+		Description string   `json:"description"`
+		Labels      []string `json:"labels"`
+		Status      struct {
+			Name string `json:"name"`
+		} `json:"status"`
+		Assignee *struct {
+			DisplayName string `json:"displayName"`
+		} `json:"assignee"`
+	} `json:"fields"`
+}
+
+func (j *Jira) toTicket(i jiraIssue) Ticket {
+	t := Ticket{
+		Key: i.Key, Title: i.Fields.Summary, Description: i.Fields.Description,
+		Status: i.Fields.Status.Name, Labels: i.Fields.Labels, URL: j.baseURL + "/browse/" + i.Key,
+	}
+	if i.Fields.Assignee != nil {
+		t.Assignee = i.Fields.Assignee.DisplayName
+	}
+	return t
+}
+
+// Search runs a JQL query built from the search fields.
+func (j *Jira) Search(ctx context.Context, query SearchQuery) ([]Ticket, error) {
+	jql := "project = " + jqlString(j.project)
+	if query.Text != "" {
+		jql += " AND text ~ " + jqlString(query.Text)
+	}
+	if query.Status != "" {
+		jql += " AND status = " + jqlString(query.Status)
+	}
+	jql += " ORDER BY updated DESC"
+
+	body := map[string]any{"jql": jql, "maxResults": searchLimit(query.Limit), "fields": jiraFields}
+	var result struct {
+		Issues []jiraIssue `json:"issues"`
+	}
+	if err := j.api.Do(ctx, http.MethodPost, "/search", body, &result); err != nil {
+		return nil, err
+	}
+	found := make([]Ticket, len(result.Issues))
+	for i, issue := range result.Issues {
+		found[i] = j.toTicket(issue)
+	}
+	return found, nil
+}
+
+// Get returns a single issue.
+func (j *Jira) Get(ctx context.Context, key string) (*Ticket, error) {
+	var issue jiraIssue
+	path := "/issue/" + url.PathEscape(key) + "?fields=" + strings.Join(jiraFields, ",")
+	if err := j.api.Do(ctx, http.MethodGet, path, nil, &issue); err != nil {
+		return nil, err
+	}
+	t := j.toTicket(issue)
+	return &t, nil
+}
+
+// Create creates an issue in the configured project.
+func (j *Jira) Create(ctx context.Context, ticket NewTicket) (*Ticket, error) {
+	issueType := ticket.Type
+	if issueType == "" {
+		issueType = "Task"
+	}
+	fields := map[string]any{
+		"project":     map[string]string{"key": j.project},
+		"summary":     ticket.Title,
+		"description": ticket.Description,
+		"issuetype":   map[string]string{"name": issueType},
+	}
+	if len(ticket.Labels) > 0 {
+		fields["labels"] = ticket.Labels
+	}
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := j.api.Do(ctx, http.MethodPost, "/issue", map[string]any{"fields": fields}, &created); err != nil {
+		return nil, err
+	}
+	return &Ticket{
+		Key: created.Key, Title: ticket.Title, Description: ticket.Description,
+		Labels: ticket.Labels, URL: j.baseURL + "/browse/" + created.Key,
+	}, nil
+}
+
+// Transition applies the workflow transition leading to status. Both the
+// transition name and its target status name are accepted.
+func (j *Jira) Transition(ctx context.Context, key, status string) error {
+	path := "/issue/" + url.PathEscape(key) + "/transitions"
+	var result struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			To   struct {
+				Name string `json:"name"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	if err := j.api.Do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return err
+	}
+
+	var available []string
+	for _, t := range result.Transitions {
+		if strings.EqualFold(t.To.Name, status) || strings.EqualFold(t.Name, status) {
+			return j.api.Do(ctx, http.MethodPost, path, map[string]any{"transition": map[string]string{"id": t.ID}}, nil)
+		}
+		available = append(available, t.To.Name)
+	}
+	return calque.NewErr(ctx, fmt.Sprintf("tickets: %s cannot move to %q (available: %s)", key, status, strings.Join(available, ", ")))
+}
+
+// Comment adds a comment to an issue.
+func (j *Jira) Comment(ctx context.Context, key, body string) error {
+	return j.api.Do(ctx, http.MethodPost, "/issue/"+url.PathEscape(key)+"/comment", map[string]string{"body": body}, nil)
+}
+
+// jqlString quotes a value for use in JQL
+func jqlString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
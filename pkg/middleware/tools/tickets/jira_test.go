@@ -0,0 +1,101 @@
+package tickets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJira(t *testing.T) {
+	var searchBody, createBody, transitionBody, commentBody map[string]any
+	var authHeader string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get("Authorization")
+		decode := func(into *map[string]any) { _ = json.NewDecoder(r.Body).Decode(into) }
+
+		switch r.Method + " " + r.URL.Path {
+		case "POST /rest/api/2/search":
+			decode(&searchBody)
+			_, _ = w.Write([]byte(`{"issues":[{"key":"OPS-1","fields":{"summary":"Disk full","status":{"name":"To Do"},"assignee":{"displayName":"Ada"},"labels":["infra"]}}]}`))
+		case "GET /rest/api/2/issue/OPS-1":
+			_, _ = w.Write([]byte(`{"key":"OPS-1","fields":{"summary":"Disk full","description":"on db-1","status":{"name":"To Do"}}}`))
+		case "POST /rest/api/2/issue":
+			decode(&createBody)
+			_, _ = w.Write([]byte(`{"key":"OPS-2"}`))
+		case "GET /rest/api/2/issue/OPS-1/transitions":
+			_, _ = w.Write([]byte(`{"transitions":[{"id":"11","name":"Start","to":{"name":"In Progress"}},{"id":"31","name":"Finish","to":{"name":"Done"}}]}`))
+		case "POST /rest/api/2/issue/OPS-1/transitions":
+			decode(&transitionBody)
+			w.WriteHeader(http.StatusNoContent)
+		case "POST /rest/api/2/issue/OPS-1/comment":
+			decode(&commentBody)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			http.Error(w, `{"errorMessages":["not found"]}`, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	jira, err := NewJira(JiraConfig{BaseURL: srv.URL, Project: "OPS", Email: "bot@acme.io", Token: "tok"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	found, err := jira.Search(ctx, SearchQuery{Text: `say "hi"`, Status: "To Do"})
+	if err != nil || len(found) != 1 || found[0].Assignee != "Ada" || found[0].URL != srv.URL+"/browse/OPS-1" {
+		t.Errorf("Search = %+v, %v", found, err)
+	}
+	if jql := searchBody["jql"]; jql != `project = "OPS" AND text ~ "say \"hi\"" AND status = "To Do" ORDER BY updated DESC` {
+		t.Errorf("jql = %v", jql)
+	}
+	if !strings.HasPrefix(authHeader, "Basic ") {
+		t.Errorf("Authorization = %q, want basic auth", authHeader)
+	}
+
+	ticket, err := jira.Get(ctx, "OPS-1")
+	if err != nil || ticket.Description != "on db-1" {
+		t.Errorf("Get = %+v, %v", ticket, err)
+	}
+
+	created, err := jira.Create(ctx, NewTicket{Title: "Rotate logs", Labels: []string{"infra"}})
+	if err != nil || created.Key != "OPS-2" {
+		t.Errorf("Create = %+v, %v", created, err)
+	}
+	fields, _ := createBody["fields"].(map[string]any)
+	if fields["summary"] != "Rotate logs" || fields["issuetype"].(map[string]any)["name"] != "Task" {
+		t.Errorf("create fields = %v", fields)
+	}
+
+	if err := jira.Transition(ctx, "OPS-1", "done"); err != nil {
+		t.Fatal(err)
+	}
+	if id := transitionBody["transition"].(map[string]any)["id"]; id != "31" {
+		t.Errorf("transition id = %v, want 31", id)
+	}
+	if err := jira.Transition(ctx, "OPS-1", "Start"); err != nil {
+		t.Errorf("transition by name error = %v", err)
+	}
+	if err := jira.Transition(ctx, "OPS-1", "Closed"); err == nil || !strings.Contains(err.Error(), "In Progress, Done") {
+		t.Errorf("unknown transition error = %v", err)
+	}
+
+	if err := jira.Comment(ctx, "OPS-1", "cleaned up"); err != nil || commentBody["body"] != "cleaned up" {
+		t.Errorf("Comment error = %v, body %v", err, commentBody)
+	}
+}
+
+func TestNewJiraValidation(t *testing.T) {
+	t.Setenv("JIRA_BASE_URL", "")
+	if _, err := NewJira(JiraConfig{Project: "OPS"}); err == nil {
+		t.Error("NewJira without base URL succeeded")
+	}
+	if _, err := NewJira(JiraConfig{BaseURL: "https://acme.atlassian.net"}); err == nil {
+		t.Error("NewJira without project succeeded")
+	}
+}
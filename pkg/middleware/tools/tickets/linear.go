@@ -0,0 +1,275 @@
+package tickets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools/internal/httpapi"
+)
+
+// LinearConfig configures the Linear tracker.
+type LinearConfig struct {
+	// Team is the team key searches and new tickets are scoped to, e.g. "ENG" (required)
+	Team string
+
+	// APIKey is a Linear personal API key. Defaults to LINEAR_API_KEY.
+	APIKey string
+
+	// BaseURL is the GraphQL endpoint. Default: https://api.linear.app/graphql
+	BaseURL string
+
+	// HTTPClient is used for requests. Default: client with 30s timeout
+	HTTPClient *http.Client
+}
+
+// Linear is a Tracker backed by the Linear GraphQL API.
+type Linear struct {
+	api  *httpapi.Client
+	team string
+}
+
+// NewLinear creates a Linear tracker for one team.
+//
+// Example:
+//
+//	linear, err := tickets.NewLinear(tickets.LinearConfig{Team: "ENG"})
+func NewLinear(config LinearConfig) (*Linear, error) {
+	if config.Team == "" {
+		return nil, calque.NewErr(context.Background(), "tickets: linear team is required")
+	}
+	if config.APIKey == "" {
+		config.APIKey = os.Getenv("LINEAR_API_KEY")
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.linear.app/graphql"
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	headers := map[string]string{}
+	if config.APIKey != "" {
+		headers["Authorization"] = config.APIKey
+	}
+	return &Linear{
+		api:  &httpapi.Client{Prefix: "tickets", Name: "linear", BaseURL: config.BaseURL, Headers: headers, HTTP: config.HTTPClient},
+		team: config.Team,
+	}, nil
+}
+
+// linearIssueFields selects the fields mapped to Ticket
+const linearIssueFields = `id identifier title description url state { name } assignee { name } labels { nodes { name } }`
+
+type linearIssue struct {
+	ID          string `json:"id"`
+	Identifier  string `json:"identifier"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	State       struct {
+		Name string `json:"name"`
+	} `json:"state"`
+	Assignee *struct {
+		Name string `json:"name"`
+	} `json:"assignee"`
+	Labels struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+}
+
+func (i linearIssue) toTicket() Ticket {
+	t := Ticket{Key: i.Identifier, Title: i.Title, Description: i.Description, Status: i.State.Name, URL: i.URL}
+	if i.Assignee != nil {
+		t.Assignee = i.Assignee.Name
+	}
+	for _, l := range i.Labels.Nodes {
+		t.Labels = append(t.Labels, l.Name)
+	}
+	return t
+}
+
+// query runs a GraphQL operation and decodes its data into out
+func (l *Linear) query(ctx context.Context, query string, variables map[string]any, out any) error {
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := l.api.Do(ctx, http.MethodPost, "", map[string]any{"query": query, "variables": variables}, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		messages := make([]string, len(resp.Errors))
+		for i, e := range resp.Errors {
+			messages[i] = e.Message
+		}
+		return calque.NewErr(ctx, "tickets: linear: "+strings.Join(messages, "; "))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Data, out); err != nil {
+		return calque.WrapErr(ctx, err, "tickets: failed to decode linear response")
+	}
+	return nil
+}
+
+// Search lists team issues matching the query, most recently updated first.
+func (l *Linear) Search(ctx context.Context, query SearchQuery) ([]Ticket, error) {
+	filter := map[string]any{"team": map[string]any{"key": map[string]any{"eq": l.team}}}
+	if query.Text != "" {
+		filter["searchableContent"] = map[string]any{"contains": query.Text}
+	}
+	if query.Status != "" {
+		filter["state"] = map[string]any{"name": map[string]any{"eqIgnoreCase": query.Status}}
+	}
+
+	var data struct {
+		Issues struct {
+			Nodes []linearIssue `json:"nodes"`
+		} `json:"issues"`
+	}
+	gql := `query($filter: IssueFilter, $first: Int) {
+		issues(filter: $filter, first: $first, orderBy: updatedAt) { nodes { ` + linearIssueFields + ` } }
+	}`
+	if err := l.query(ctx, gql, map[string]any{"filter": filter, "first": searchLimit(query.Limit)}, &data); err != nil {
+		return nil, err
+	}
+	found := make([]Ticket, len(data.Issues.Nodes))
+	for i, issue := range data.Issues.Nodes {
+		found[i] = issue.toTicket()
+	}
+	return found, nil
+}
+
+// Get returns an issue by identifier, e.g. "ENG-7".
+func (l *Linear) Get(ctx context.Context, key string) (*Ticket, error) {
+	issue, err := l.issue(ctx, key, "")
+	if err != nil {
+		return nil, err
+	}
+	t := issue.toTicket()
+	return &t, nil
+}
+
+// issue fetches an issue with optional extra fields
+func (l *Linear) issue(ctx context.Context, key, extra string) (*linearIssueWithTeam, error) {
+	var data struct {
+		Issue *linearIssueWithTeam `json:"issue"`
+	}
+	gql := `query($id: String!) { issue(id: $id) { ` + linearIssueFields + ` ` + extra + ` } }`
+	if err := l.query(ctx, gql, map[string]any{"id": key}, &data); err != nil {
+		return nil, err
+	}
+	if data.Issue == nil {
+		return nil, calque.NewErr(ctx, fmt.Sprintf("tickets: linear issue %s not found", key))
+	}
+	return data.Issue, nil
+}
+
+type linearIssueWithTeam struct {
+	linearIssue
+	Team struct {
+		States struct {
+			Nodes []struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"nodes"`
+		} `json:"states"`
+	} `json:"team"`
+}
+
+// Create creates an issue in the configured team. Labels are matched by name;
+// unknown labels are ignored.
+func (l *Linear) Create(ctx context.Context, ticket NewTicket) (*Ticket, error) {
+	var team struct {
+		Teams struct {
+			Nodes []struct {
+				ID     string `json:"id"`
+				Labels struct {
+					Nodes []struct {
+						ID   string `json:"id"`
+						Name string `json:"name"`
+					} `json:"nodes"`
+				} `json:"labels"`
+			} `json:"nodes"`
+		} `json:"teams"`
+	}
+	gql := `query($key: String!) { teams(filter: { key: { eq: $key } }) { nodes { id labels { nodes { id name } } } } }`
+	if err := l.query(ctx, gql, map[string]any{"key": l.team}, &team); err != nil {
+		return nil, err
+	}
+	if len(team.Teams.Nodes) == 0 {
+		return nil, calque.NewErr(ctx, fmt.Sprintf("tickets: linear team %s not found", l.team))
+	}
+
+	input := map[string]any{
+		"teamId":      team.Teams.Nodes[0].ID,
+		"title":       ticket.Title,
+		"description": ticket.Description,
+	}
+	var labelIDs []string
+	for _, want := range ticket.Labels {
+		for _, label := range team.Teams.Nodes[0].Labels.Nodes {
+			if strings.EqualFold(label.Name, want) {
+				labelIDs = append(labelIDs, label.ID)
+			}
+		}
+	}
+	if len(labelIDs) > 0 {
+		input["labelIds"] = labelIDs
+	}
+
+	var created struct {
+		IssueCreate struct {
+			Success bool        `json:"success"`
+			Issue   linearIssue `json:"issue"`
+		} `json:"issueCreate"`
+	}
+	mutation := `mutation($input: IssueCreateInput!) { issueCreate(input: $input) { success issue { ` + linearIssueFields + ` } } }`
+	if err := l.query(ctx, mutation, map[string]any{"input": input}, &created); err != nil {
+		return nil, err
+	}
+	if !created.IssueCreate.Success {
+		return nil, calque.NewErr(ctx, "tickets: linear did not create the issue")
+	}
+	t := created.IssueCreate.Issue.toTicket()
+	return &t, nil
+}
+
+// Transition moves the issue to the team workflow state named status.
+func (l *Linear) Transition(ctx context.Context, key, status string) error {
+	issue, err := l.issue(ctx, key, "team { states { nodes { id name } } }")
+	if err != nil {
+		return err
+	}
+
+	var available []string
+	for _, state := range issue.Team.States.Nodes {
+		if strings.EqualFold(state.Name, status) {
+			mutation := `mutation($id: String!, $stateId: String!) { issueUpdate(id: $id, input: { stateId: $stateId }) { success } }`
+			return l.query(ctx, mutation, map[string]any{"id": issue.ID, "stateId": state.ID}, nil)
+		}
+		available = append(available, state.Name)
+	}
+	return calque.NewErr(ctx, fmt.Sprintf("tickets: %s cannot move to %q (available: %s)", key, status, strings.Join(available, ", ")))
+}
+
+// Comment adds a comment to an issue.
+func (l *Linear) Comment(ctx context.Context, key, body string) error {
+	issue, err := l.issue(ctx, key, "")
+	if err != nil {
+		return err
+	}
+	mutation := `mutation($input: CommentCreateInput!) { commentCreate(input: $input) { success } }`
+	return l.query(ctx, mutation, map[string]any{"input": map[string]any{"issueId": issue.ID, "body": body}}, nil)
+}
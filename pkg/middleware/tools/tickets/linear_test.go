@@ -0,0 +1,94 @@
+package tickets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLinear(t *testing.T) {
+	var requests []map[string]any
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "lin_key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body)
+		query := body["query"].(string)
+
+		issue := `{"id":"uuid-7","identifier":"ENG-7","title":"Flaky test","state":{"name":"Todo"},"labels":{"nodes":[{"name":"ci"}]}`
+		switch {
+		case strings.Contains(query, "issues(filter"):
+			_, _ = w.Write([]byte(`{"data":{"issues":{"nodes":[` + issue + `}]}}}`))
+		case strings.Contains(query, "teams(filter"):
+			_, _ = w.Write([]byte(`{"data":{"teams":{"nodes":[{"id":"team-1","labels":{"nodes":[{"id":"l-ci","name":"CI"}]}}]}}}`))
+		case strings.Contains(query, "issueCreate"):
+			_, _ = w.Write([]byte(`{"data":{"issueCreate":{"success":true,"issue":{"identifier":"ENG-8","title":"New"}}}}`))
+		case strings.Contains(query, "issue(id") && body["variables"].(map[string]any)["id"] == "ENG-404":
+			_, _ = w.Write([]byte(`{"data":{"issue":null},"errors":[{"message":"Entity not found"}]}`))
+		case strings.Contains(query, "issue(id"):
+			_, _ = w.Write([]byte(`{"data":{"issue":` + issue + `,"team":{"states":{"nodes":[{"id":"s-todo","name":"Todo"},{"id":"s-done","name":"Done"}]}}}}}`))
+		case strings.Contains(query, "issueUpdate"), strings.Contains(query, "commentCreate"):
+			_, _ = w.Write([]byte(`{"data":{"ok":{"success":true}}}`))
+		default:
+			_, _ = w.Write([]byte(`{"errors":[{"message":"unexpected query"}]}`))
+		}
+	}))
+	defer srv.Close()
+
+	linear, err := NewLinear(LinearConfig{Team: "ENG", APIKey: "lin_key", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	lastVariables := func() map[string]any { return requests[len(requests)-1]["variables"].(map[string]any) }
+
+	found, err := linear.Search(ctx, SearchQuery{Text: "flaky", Status: "todo"})
+	if err != nil || len(found) != 1 || found[0].Key != "ENG-7" || found[0].Labels[0] != "ci" {
+		t.Errorf("Search = %+v, %v", found, err)
+	}
+	filter := lastVariables()["filter"].(map[string]any)
+	if filter["searchableContent"] == nil || filter["state"] == nil {
+		t.Errorf("search filter = %v", filter)
+	}
+
+	ticket, err := linear.Get(ctx, "ENG-7")
+	if err != nil || ticket.Status != "Todo" {
+		t.Errorf("Get = %+v, %v", ticket, err)
+	}
+	if _, err := linear.Get(ctx, "ENG-404"); err == nil || !strings.Contains(err.Error(), "Entity not found") {
+		t.Errorf("Get(missing) error = %v", err)
+	}
+
+	created, err := linear.Create(ctx, NewTicket{Title: "New", Labels: []string{"ci", "unknown"}})
+	if err != nil || created.Key != "ENG-8" {
+		t.Errorf("Create = %+v, %v", created, err)
+	}
+	input := lastVariables()["input"].(map[string]any)
+	if input["teamId"] != "team-1" || len(input["labelIds"].([]any)) != 1 {
+		t.Errorf("create input = %v", input)
+	}
+
+	if err := linear.Transition(ctx, "ENG-7", "done"); err != nil {
+		t.Fatal(err)
+	}
+	if v := lastVariables(); v["id"] != "uuid-7" || v["stateId"] != "s-done" {
+		t.Errorf("transition variables = %v", v)
+	}
+	if err := linear.Transition(ctx, "ENG-7", "Shipped"); err == nil || !strings.Contains(err.Error(), "Todo, Done") {
+		t.Errorf("unknown state error = %v", err)
+	}
+
+	if err := linear.Comment(ctx, "ENG-7", "looking"); err != nil {
+		t.Fatal(err)
+	}
+	if input := lastVariables()["input"].(map[string]any); input["issueId"] != "uuid-7" || input["body"] != "looking" {
+		t.Errorf("comment input = %v", input)
+	}
+}
@@ -0,0 +1,175 @@
+// Package tickets provides issue tracker tools backed by Jira or Linear.
+//
+// A Tracker abstracts the service; Tools exposes it to agents as search,
+// create, transition and comment tools for triage flows:
+//
+//	jira, err := tickets.NewJira(tickets.JiraConfig{
+//	    BaseURL: "https://acme.atlassian.net",
+//	    Project: "OPS",
+//	})
+//	if err != nil {
+//	    return err
+//	}
+//	triage := ai.Agent(client, ai.WithTools(tickets.Tools(jira)...))
+package tickets
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+// Ticket is an issue in the tracker.
+type Ticket struct {
+	Key         string   `json:"key"` // e.g. "OPS-42" or "ENG-7"
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Status      string   `json:"status"`
+	Assignee    string   `json:"assignee,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	URL         string   `json:"url,omitempty"`
+}
+
+// SearchQuery filters Search.
+type SearchQuery struct {
+	Text   string // free-text search in title and description
+	Status string // exact status name, case-insensitive
+	Limit  int    // maximum results (default 20)
+}
+
+// NewTicket describes a ticket to create.
+type NewTicket struct {
+	Title       string
+	Description string
+	Type        string // issue type (Jira only, default "Task")
+	Labels      []string
+}
+
+// Tracker is an issue tracking service.
+type Tracker interface {
+	Search(ctx context.Context, query SearchQuery) ([]Ticket, error)
+	Get(ctx context.Context, key string) (*Ticket, error)
+	Create(ctx context.Context, ticket NewTicket) (*Ticket, error)
+	// Transition moves the ticket to the named status.
+	Transition(ctx context.Context, key, status string) error
+	Comment(ctx context.Context, key, body string) error
+}
+
+// Config controls which ticket tools are exposed.
+type Config struct {
+	// ReadOnly - only expose ticket_search and ticket_get
+	ReadOnly bool
+}
+
+// Tools exposes a tracker to agents.
+//
+// Returns ticket_search and ticket_get, plus ticket_create, ticket_transition
+// and ticket_comment unless Config.ReadOnly is set. Results are returned as JSON.
+//
+// Example:
+//
+//	linear, _ := tickets.NewLinear(tickets.LinearConfig{Team: "ENG"})
+//	agent := ai.Agent(client, ai.WithTools(tickets.Tools(linear)...))
+func Tools(t Tracker, config ...Config) []tools.Tool {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	ticketTools := []tools.Tool{
+		tools.Typed("ticket_search", "Search tickets by text and status",
+			func(ctx context.Context, args searchArgs) (string, error) {
+				return jsonResult(t.Search(ctx, SearchQuery(args)))
+			}),
+		tools.Typed("ticket_get", "Get a ticket including its description",
+			func(ctx context.Context, args keyArgs) (string, error) {
+				if args.Key == "" {
+					return "", calque.NewErr(ctx, "key is required")
+				}
+				return jsonResult(t.Get(ctx, args.Key))
+			}),
+	}
+	if cfg.ReadOnly {
+		return ticketTools
+	}
+
+	return append(ticketTools,
+		tools.Typed("ticket_create", "Create a ticket",
+			func(ctx context.Context, args createArgs) (string, error) {
+				if strings.TrimSpace(args.Title) == "" {
+					return "", calque.NewErr(ctx, "title is required")
+				}
+				return jsonResult(t.Create(ctx, NewTicket(args)))
+			}),
+		tools.Typed("ticket_transition", "Move a ticket to another status, e.g. \"In Progress\" or \"Done\"",
+			func(ctx context.Context, args transitionArgs) (string, error) {
+				if args.Key == "" || args.Status == "" {
+					return "", calque.NewErr(ctx, "key and status are required")
+				}
+				if err := t.Transition(ctx, args.Key, args.Status); err != nil {
+					return "", err
+				}
+				return args.Key + " moved to " + args.Status, nil
+			}),
+		tools.Typed("ticket_comment", "Add a comment to a ticket",
+			func(ctx context.Context, args commentArgs) (string, error) {
+				if args.Key == "" || strings.TrimSpace(args.Body) == "" {
+					return "", calque.NewErr(ctx, "key and body are required")
+				}
+				if err := t.Comment(ctx, args.Key, args.Body); err != nil {
+					return "", err
+				}
+				return "comment added to " + args.Key, nil
+			}),
+	)
+}
+
+type searchArgs struct {
+	Text   string `json:"text,omitempty" jsonschema:"description=Text to search for"`
+	Status string `json:"status,omitempty" jsonschema:"description=Only tickets in this status"`
+	Limit  int    `json:"limit,omitempty" jsonschema:"description=Maximum number of tickets (default 20)"`
+}
+
+type keyArgs struct {
+	Key string `json:"key" jsonschema:"required,description=Ticket key such as OPS-42"`
+}
+
+type createArgs struct {
+	Title       string   `json:"title" jsonschema:"required"`
+	Description string   `json:"description,omitempty"`
+	Type        string   `json:"type,omitempty" jsonschema:"description=Issue type such as Bug or Task"`
+	Labels      []string `json:"labels,omitempty"`
+}
+
+type transitionArgs struct {
+	Key    string `json:"key" jsonschema:"required,description=Ticket key such as OPS-42"`
+	Status string `json:"status" jsonschema:"required,description=Target status name"`
+}
+
+type commentArgs struct {
+	Key  string `json:"key" jsonschema:"required,description=Ticket key such as OPS-42"`
+	Body string `json:"body" jsonschema:"required,description=Comment text"`
+}
+
+// jsonResult encodes a tracker result for the model
+func jsonResult[T any](v T, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// searchLimit applies the default result limit
+func searchLimit(limit int) int {
+	if limit <= 0 {
+		return 20
+	}
+	return min(limit, 100)
+}
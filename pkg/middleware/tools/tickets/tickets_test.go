@@ -0,0 +1,116 @@
+package tickets
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+// fakeTracker records calls made through the tools
+type fakeTracker struct {
+	query      SearchQuery
+	created    NewTicket
+	transition string
+	comment    string
+}
+
+func (f *fakeTracker) Search(_ context.Context, query SearchQuery) ([]Ticket, error) {
+	f.query = query
+	return []Ticket{{Key: "OPS-1", Title: "Disk full", Status: "To Do"}}, nil
+}
+
+func (f *fakeTracker) Get(_ context.Context, key string) (*Ticket, error) {
+	return &Ticket{Key: key, Title: "Disk full", Description: "on db-1"}, nil
+}
+
+func (f *fakeTracker) Create(_ context.Context, ticket NewTicket) (*Ticket, error) {
+	f.created = ticket
+	return &Ticket{Key: "OPS-2", Title: ticket.Title}, nil
+}
+
+func (f *fakeTracker) Transition(_ context.Context, key, status string) error {
+	f.transition = key + "->" + status
+	return nil
+}
+
+func (f *fakeTracker) Comment(_ context.Context, key, body string) error {
+	f.comment = key + ":" + body
+	return nil
+}
+
+func callTool(t *testing.T, ticketTools []tools.Tool, name, args string) (string, error) {
+	t.Helper()
+	for _, tool := range ticketTools {
+		if tool.Name() == name {
+			var buf bytes.Buffer
+			req := calque.NewRequest(context.Background(), strings.NewReader(args))
+			err := tool.ServeFlow(req, calque.NewResponse(&buf))
+			return buf.String(), err
+		}
+	}
+	t.Fatalf("tool %q not found", name)
+	return "", nil
+}
+
+func TestTools(t *testing.T) {
+	f := &fakeTracker{}
+	ticketTools := Tools(f)
+	if len(ticketTools) != 5 {
+		t.Fatalf("len(Tools) = %d, want 5", len(ticketTools))
+	}
+
+	out, err := callTool(t, ticketTools, "ticket_search", `{"text":"disk","status":"To Do"}`)
+	if err != nil || !strings.Contains(out, `"key":"OPS-1"`) || f.query.Text != "disk" || f.query.Status != "To Do" {
+		t.Errorf("ticket_search = %q, %v (query %+v)", out, err, f.query)
+	}
+
+	out, err = callTool(t, ticketTools, "ticket_get", `{"key":"OPS-1"}`)
+	if err != nil || !strings.Contains(out, `"description":"on db-1"`) {
+		t.Errorf("ticket_get = %q, %v", out, err)
+	}
+
+	out, err = callTool(t, ticketTools, "ticket_create", `{"title":"Rotate logs","type":"Bug","labels":["infra"]}`)
+	if err != nil || !strings.Contains(out, `"key":"OPS-2"`) || f.created.Type != "Bug" || f.created.Labels[0] != "infra" {
+		t.Errorf("ticket_create = %q, %v (created %+v)", out, err, f.created)
+	}
+
+	if out, err := callTool(t, ticketTools, "ticket_transition", `{"key":"OPS-1","status":"Done"}`); err != nil || f.transition != "OPS-1->Done" {
+		t.Errorf("ticket_transition = %q, %v", out, err)
+	}
+	if out, err := callTool(t, ticketTools, "ticket_comment", `{"key":"OPS-1","body":"cleaned up"}`); err != nil || f.comment != "OPS-1:cleaned up" {
+		t.Errorf("ticket_comment = %q, %v", out, err)
+	}
+}
+
+func TestToolsValidation(t *testing.T) {
+	ticketTools := Tools(&fakeTracker{})
+
+	tests := []struct {
+		tool    string
+		args    string
+		wantErr string
+	}{
+		{"ticket_get", `{}`, "key is required"},
+		{"ticket_create", `{"title":"  "}`, "title is required"},
+		{"ticket_transition", `{"key":"OPS-1"}`, "key and status are required"},
+		{"ticket_comment", `{"key":"OPS-1"}`, "key and body are required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			if _, err := callTool(t, ticketTools, tt.tool, tt.args); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestToolsReadOnly(t *testing.T) {
+	ticketTools := Tools(&fakeTracker{}, Config{ReadOnly: true})
+	if len(ticketTools) != 2 {
+		t.Errorf("len(read-only Tools) = %d, want 2", len(ticketTools))
+	}
+}
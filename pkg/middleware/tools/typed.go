@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/invopop/jsonschema"
+)
+
+// typedTool is a tool whose JSON arguments are decoded into T
+type typedTool[T any] struct {
+	name        string
+	description string
+	schema      *jsonschema.Schema
+	fn          func(ctx context.Context, args T) (string, error)
+}
+
+// Typed creates a tool whose arguments are decoded into a struct.
+//
+// The parameter schema is reflected from T's json and jsonschema struct tags,
+// and the string returned by fn becomes the tool result.
+//
+// Example:
+//
+//	type weatherArgs struct {
+//	    City string `json:"city" jsonschema:"required,description=City name"`
+//	}
+//	weather := tools.Typed("get_weather", "Get current weather for a city",
+//	    func(ctx context.Context, args weatherArgs) (string, error) {
+//	        return fetchWeather(ctx, args.City)
+//	    },
+//	)
+func Typed[T any](name, description string, fn func(ctx context.Context, args T) (string, error)) Tool {
+	reflector := jsonschema.Reflector{DoNotReference: true, ExpandedStruct: true, AllowAdditionalProperties: true}
+	var zero T
+	schema := reflector.Reflect(zero)
+	schema.Version, schema.ID = "", ""
+	return &typedTool[T]{name: name, description: description, schema: schema, fn: fn}
+}
+
+func (t *typedTool[T]) Name() string {
+	return t.name
+}
+
+func (t *typedTool[T]) Description() string {
+	return t.description
+}
+
+func (t *typedTool[T]) ParametersSchema() *jsonschema.Schema {
+	return t.schema
+}
+
+func (t *typedTool[T]) ServeFlow(req *calque.Request, res *calque.Response) error {
+	var raw string
+	if err := calque.Read(req, &raw); err != nil {
+		return err
+	}
+
+	var args T
+	if raw = strings.TrimSpace(raw); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &args); err != nil {
+			return calque.WrapErr(req.Context, err, "invalid arguments for "+t.name)
+		}
+	}
+
+	result, err := t.fn(req.Context, args)
+	if err != nil {
+		return err
+	}
+	return calque.Write(res, result)
+}
@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type greetArgs struct {
+	Name  string `json:"name" jsonschema:"required,description=Who to greet"`
+	Times int    `json:"times,omitempty"`
+}
+
+func TestTyped(t *testing.T) {
+	greet := Typed("greet", "Greet someone", func(_ context.Context, args greetArgs) (string, error) {
+		if args.Name == "" {
+			return "", errors.New("name is required")
+		}
+		return strings.Repeat("hi "+args.Name+" ", max(args.Times, 1)), nil
+	})
+
+	schema := ConvertToInternalSchema(greet.ParametersSchema())
+	if schema.Type != "object" || schema.Properties["name"] == nil || schema.Properties["name"].Description != "Who to greet" {
+		t.Errorf("schema = %+v", schema)
+	}
+	if len(schema.Required) != 1 || schema.Required[0] != "name" {
+		t.Errorf("required = %v, want [name]", schema.Required)
+	}
+
+	tests := []struct {
+		name     string
+		args     string
+		expected string
+		wantErr  string
+	}{
+		{name: "decodes arguments", args: `{"name":"Ada","times":2}`, expected: "hi Ada hi Ada "},
+		{name: "empty arguments", args: "", wantErr: "name is required"},
+		{name: "malformed arguments", args: `{"name":`, wantErr: "invalid arguments for greet"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := callTool(t, []Tool{greet}, "greet", tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || out != tt.expected {
+				t.Errorf("result = %q, %v; want %q", out, err, tt.expected)
+			}
+		})
+	}
+}
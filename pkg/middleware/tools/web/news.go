@@ -0,0 +1,111 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+// NewsConfig configures the news tool.
+type NewsConfig struct {
+	// APIKey is the NewsAPI.org key. Defaults to NEWSAPI_KEY.
+	APIKey string
+
+	// Language restricts articles to an ISO 639-1 language. Default: "en"
+	Language string
+
+	// BaseURL is the API root. Default: https://newsapi.org/v2
+	BaseURL string
+
+	// HTTPClient is used for requests. Default: client with 15s timeout
+	HTTPClient *http.Client
+}
+
+// Article is a news article.
+type Article struct {
+	Title       string `json:"title"`
+	Source      string `json:"source,omitempty"`
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+	PublishedAt string `json:"published_at,omitempty"`
+}
+
+type newsArgs struct {
+	Query    string `json:"query,omitempty" jsonschema:"description=Topic to search for; omit for top headlines"`
+	Category string `json:"category,omitempty" jsonschema:"enum=business,enum=entertainment,enum=general,enum=health,enum=science,enum=sports,enum=technology,description=Headline category (only without query)"`
+	Count    int    `json:"count,omitempty" jsonschema:"description=Number of articles (default 5; max 20)"`
+}
+
+// News creates a news_search tool backed by NewsAPI.org.
+//
+// With a query it searches recent articles, newest first; without one it
+// returns top headlines, optionally for a category.
+//
+// Example:
+//
+//	news, err := web.News(web.NewsConfig{})
+//	agent := ai.Agent(client, ai.WithTools(news))
+func News(config NewsConfig) (tools.Tool, error) {
+	if config.APIKey == "" {
+		config.APIKey = os.Getenv("NEWSAPI_KEY")
+	}
+	if config.APIKey == "" {
+		return nil, calque.NewErr(context.Background(), "web: news API key is required (NewsConfig.APIKey or NEWSAPI_KEY)")
+	}
+	if config.Language == "" {
+		config.Language = "en"
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = "https://newsapi.org/v2"
+	}
+	client := httpClientOrDefault(config.HTTPClient)
+
+	return tools.Typed("news_search", "Find recent news articles on a topic, or top headlines",
+		func(ctx context.Context, args newsArgs) (string, error) {
+			params := url.Values{"pageSize": {strconv.Itoa(resultCount(args.Count))}, "language": {config.Language}}
+			endpoint := strings.TrimSuffix(config.BaseURL, "/")
+			if query := strings.TrimSpace(args.Query); query != "" {
+				params.Set("q", query)
+				params.Set("sortBy", "publishedAt")
+				endpoint += "/everything"
+			} else {
+				params.Del("language") // top-headlines filters by country instead
+				params.Set("country", "us")
+				if args.Category != "" {
+					params.Set("category", args.Category)
+				}
+				endpoint += "/top-headlines"
+			}
+
+			var resp struct {
+				Articles []struct {
+					Title       string `json:"title"`
+					URL         string `json:"url"`
+					Description string `json:"description"`
+					PublishedAt string `json:"publishedAt"`
+					Source      struct {
+						Name string `json:"name"`
+					} `json:"source"`
+				} `json:"articles"`
+			}
+			headers := map[string]string{"X-Api-Key": config.APIKey}
+			if err := getJSON(ctx, client, "newsapi", endpoint+"?"+params.Encode(), headers, &resp); err != nil {
+				return "", err
+			}
+
+			articles := []Article{}
+			for _, a := range resp.Articles {
+				articles = append(articles, Article{
+					Title: a.Title, Source: a.Source.Name, URL: a.URL,
+					Description: a.Description, PublishedAt: a.PublishedAt,
+				})
+			}
+			return jsonResult(articles)
+		}), nil
+}
@@ -0,0 +1,62 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNews(t *testing.T) {
+	var path, query, key string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, query, key = r.URL.Path, r.URL.RawQuery, r.Header.Get("X-Api-Key")
+		_, _ = w.Write([]byte(`{"status":"ok","articles":[
+			{"source":{"name":"Daily"},"title":"Go 2 released","url":"https://news.example/go",
+			 "description":"Big news","publishedAt":"2026-03-02T09:00:00Z"}
+		]}`))
+	}))
+	defer srv.Close()
+
+	news, err := News(NewsConfig{APIKey: "news-key", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		args      string
+		wantPath  string
+		wantQuery []string
+	}{
+		{name: "search", args: `{"query":"golang"}`, wantPath: "/everything", wantQuery: []string{"q=golang", "sortBy=publishedAt", "language=en", "pageSize=5"}},
+		{name: "headlines", args: `{"category":"technology","count":3}`, wantPath: "/top-headlines", wantQuery: []string{"category=technology", "country=us", "pageSize=3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := callTool(t, news, tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out, `"source":"Daily"`) || !strings.Contains(out, `"published_at":"2026-03-02T09:00:00Z"`) {
+				t.Errorf("result = %s", out)
+			}
+			if path != tt.wantPath || key != "news-key" {
+				t.Errorf("request = %s (key %q), want %s", path, key, tt.wantPath)
+			}
+			for _, want := range tt.wantQuery {
+				if !strings.Contains(query, want) {
+					t.Errorf("query = %s, want %s", query, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewsRequiresAPIKey(t *testing.T) {
+	t.Setenv("NEWSAPI_KEY", "")
+	if _, err := News(NewsConfig{}); err == nil || !strings.Contains(err.Error(), "NEWSAPI_KEY") {
+		t.Errorf("error = %v", err)
+	}
+}
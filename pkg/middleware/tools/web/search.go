@@ -0,0 +1,152 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+// BraveConfig configures the Brave Search tool.
+type BraveConfig struct {
+	// APIKey is the Brave Search API subscription token. Defaults to BRAVE_API_KEY.
+	APIKey string
+
+	// BaseURL is the web search endpoint. Default: https://api.search.brave.com/res/v1/web/search
+	BaseURL string
+
+	// SafeSearch is "off", "moderate" or "strict". Default: "moderate"
+	SafeSearch string
+
+	// HTTPClient is used for requests. Default: client with 15s timeout
+	HTTPClient *http.Client
+}
+
+// BraveSearch creates a web_search tool backed by the Brave Search API.
+//
+// Example:
+//
+//	search, err := web.BraveSearch(web.BraveConfig{APIKey: key})
+//	agent := ai.Agent(client, ai.WithTools(search))
+func BraveSearch(config BraveConfig) (tools.Tool, error) {
+	if config.APIKey == "" {
+		config.APIKey = os.Getenv("BRAVE_API_KEY")
+	}
+	if config.APIKey == "" {
+		return nil, calque.NewErr(context.Background(), "web: brave API key is required (BraveConfig.APIKey or BRAVE_API_KEY)")
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.search.brave.com/res/v1/web/search"
+	}
+	if config.SafeSearch == "" {
+		config.SafeSearch = "moderate"
+	}
+	client := httpClientOrDefault(config.HTTPClient)
+
+	return tools.Typed("web_search", "Search the web and return titles, URLs and snippets",
+		func(ctx context.Context, args searchArgs) (string, error) {
+			if strings.TrimSpace(args.Query) == "" {
+				return "", calque.NewErr(ctx, "query is required")
+			}
+			params := url.Values{
+				"q":          {args.Query},
+				"count":      {strconv.Itoa(resultCount(args.Count))},
+				"safesearch": {config.SafeSearch},
+			}
+			var resp struct {
+				Web struct {
+					Results []struct {
+						Title       string `json:"title"`
+						URL         string `json:"url"`
+						Description string `json:"description"`
+					} `json:"results"`
+				} `json:"web"`
+			}
+			headers := map[string]string{"X-Subscription-Token": config.APIKey}
+			if err := getJSON(ctx, client, "brave", config.BaseURL+"?"+params.Encode(), headers, &resp); err != nil {
+				return "", err
+			}
+
+			results := []SearchResult{}
+			for _, r := range resp.Web.Results {
+				results = append(results, SearchResult{Title: r.Title, URL: r.URL, Snippet: r.Description})
+			}
+			return jsonResult(results)
+		}), nil
+}
+
+// SerpAPIConfig configures the SerpAPI search tool.
+type SerpAPIConfig struct {
+	// APIKey is the SerpAPI key. Defaults to SERPAPI_API_KEY.
+	APIKey string
+
+	// Engine is the SerpAPI engine. Default: "google"
+	Engine string
+
+	// BaseURL is the search endpoint. Default: https://serpapi.com/search.json
+	BaseURL string
+
+	// HTTPClient is used for requests. Default: client with 15s timeout
+	HTTPClient *http.Client
+}
+
+// SerpAPISearch creates a web_search tool backed by SerpAPI.
+//
+// It has the same name and parameters as BraveSearch, so the two are interchangeable.
+//
+// Example:
+//
+//	search, err := web.SerpAPISearch(web.SerpAPIConfig{Engine: "bing"})
+func SerpAPISearch(config SerpAPIConfig) (tools.Tool, error) {
+	if config.APIKey == "" {
+		config.APIKey = os.Getenv("SERPAPI_API_KEY")
+	}
+	if config.APIKey == "" {
+		return nil, calque.NewErr(context.Background(), "web: serpapi key is required (SerpAPIConfig.APIKey or SERPAPI_API_KEY)")
+	}
+	if config.Engine == "" {
+		config.Engine = "google"
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = "https://serpapi.com/search.json"
+	}
+	client := httpClientOrDefault(config.HTTPClient)
+
+	return tools.Typed("web_search", "Search the web and return titles, URLs and snippets",
+		func(ctx context.Context, args searchArgs) (string, error) {
+			if strings.TrimSpace(args.Query) == "" {
+				return "", calque.NewErr(ctx, "query is required")
+			}
+			count := resultCount(args.Count)
+			params := url.Values{
+				"q":       {args.Query},
+				"engine":  {config.Engine},
+				"num":     {strconv.Itoa(count)},
+				"api_key": {config.APIKey},
+			}
+			var resp struct {
+				OrganicResults []struct {
+					Title   string `json:"title"`
+					Link    string `json:"link"`
+					Snippet string `json:"snippet"`
+				} `json:"organic_results"`
+			}
+			if err := getJSON(ctx, client, "serpapi", config.BaseURL+"?"+params.Encode(), nil, &resp); err != nil {
+				return "", err
+			}
+
+			results := []SearchResult{}
+			for _, r := range resp.OrganicResults {
+				if len(results) == count {
+					break
+				}
+				results = append(results, SearchResult{Title: r.Title, URL: r.Link, Snippet: r.Snippet})
+			}
+			return jsonResult(results)
+		}), nil
+}
@@ -0,0 +1,91 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBraveSearch(t *testing.T) {
+	var token, query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("X-Subscription-Token")
+		query = r.URL.RawQuery
+		_, _ = w.Write([]byte(`{"web":{"results":[
+			{"title":"Go","url":"https://go.dev","description":"The Go language"},
+			{"title":"Tour","url":"https://go.dev/tour","description":"A tour of Go"}
+		]}}`))
+	}))
+	defer srv.Close()
+
+	search, err := BraveSearch(BraveConfig{APIKey: "brave-key", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if search.Name() != "web_search" {
+		t.Errorf("Name() = %q", search.Name())
+	}
+
+	out, err := callTool(t, search, `{"query":"golang","count":2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `"url":"https://go.dev/tour"`) || !strings.Contains(out, `"snippet":"The Go language"`) {
+		t.Errorf("result = %s", out)
+	}
+	if token != "brave-key" {
+		t.Errorf("token = %q", token)
+	}
+	if !strings.Contains(query, "q=golang") || !strings.Contains(query, "count=2") || !strings.Contains(query, "safesearch=moderate") {
+		t.Errorf("query = %s", query)
+	}
+
+	if _, err := callTool(t, search, `{"query":"  "}`); err == nil || !strings.Contains(err.Error(), "query is required") {
+		t.Errorf("empty query error = %v", err)
+	}
+}
+
+func TestSerpAPISearch(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		_, _ = w.Write([]byte(`{"organic_results":[
+			{"title":"One","link":"https://one.example","snippet":"first"},
+			{"title":"Two","link":"https://two.example","snippet":"second"},
+			{"title":"Three","link":"https://three.example","snippet":"third"}
+		]}`))
+	}))
+	defer srv.Close()
+
+	search, err := SerpAPISearch(SerpAPIConfig{APIKey: "serp-key", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := callTool(t, search, `{"query":"calque","count":2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "https://two.example") || strings.Contains(out, "three") {
+		t.Errorf("result = %s, want first two results", out)
+	}
+	if !strings.Contains(query, "api_key=serp-key") || !strings.Contains(query, "engine=google") {
+		t.Errorf("query = %s", query)
+	}
+}
+
+func TestSearchRequiresAPIKey(t *testing.T) {
+	t.Setenv("BRAVE_API_KEY", "")
+	t.Setenv("SERPAPI_API_KEY", "")
+	if _, err := BraveSearch(BraveConfig{}); err == nil || !strings.Contains(err.Error(), "BRAVE_API_KEY") {
+		t.Errorf("BraveSearch error = %v", err)
+	}
+	if _, err := SerpAPISearch(SerpAPIConfig{}); err == nil || !strings.Contains(err.Error(), "SERPAPI_API_KEY") {
+		t.Errorf("SerpAPISearch error = %v", err)
+	}
+
+	t.Setenv("BRAVE_API_KEY", "from-env")
+	if _, err := BraveSearch(BraveConfig{}); err != nil {
+		t.Errorf("BraveSearch with env key: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+// WeatherConfig configures the weather tool.
+type WeatherConfig struct {
+	// Units is "metric" (°C, km/h) or "imperial" (°F, mph). Default: "metric"
+	Units string
+
+	// GeocodingURL is the place search endpoint. Default: https://geocoding-api.open-meteo.com/v1/search
+	GeocodingURL string
+
+	// ForecastURL is the forecast endpoint. Default: https://api.open-meteo.com/v1/forecast
+	ForecastURL string
+
+	// HTTPClient is used for requests. Default: client with 15s timeout
+	HTTPClient *http.Client
+}
+
+// CurrentWeather is the current conditions at a location.
+type CurrentWeather struct {
+	Location    string  `json:"location"`
+	Time        string  `json:"time"`
+	Temperature float64 `json:"temperature"`
+	Humidity    float64 `json:"humidity_percent"`
+	WindSpeed   float64 `json:"wind_speed"`
+	Conditions  string  `json:"conditions"`
+	Units       string  `json:"units"`
+}
+
+type weatherArgs struct {
+	Location string `json:"location" jsonschema:"required,description=City or place name such as Paris or Austin Texas"`
+}
+
+// Weather creates a get_weather tool backed by Open-Meteo, which needs no API key.
+//
+// The location is resolved with Open-Meteo geocoding (best match) and the
+// current conditions are returned in the configured units.
+//
+// Example:
+//
+//	agent := ai.Agent(client, ai.WithTools(web.Weather(web.WeatherConfig{Units: "imperial"})))
+func Weather(config WeatherConfig) tools.Tool {
+	if config.Units != "imperial" {
+		config.Units = "metric"
+	}
+	if config.GeocodingURL == "" {
+		config.GeocodingURL = "https://geocoding-api.open-meteo.com/v1/search"
+	}
+	if config.ForecastURL == "" {
+		config.ForecastURL = "https://api.open-meteo.com/v1/forecast"
+	}
+	client := httpClientOrDefault(config.HTTPClient)
+
+	return tools.Typed("get_weather", "Get the current weather for a location",
+		func(ctx context.Context, args weatherArgs) (string, error) {
+			location := strings.TrimSpace(args.Location)
+			if location == "" {
+				return "", calque.NewErr(ctx, "location is required")
+			}
+
+			var places struct {
+				Results []struct {
+					Name      string  `json:"name"`
+					Admin1    string  `json:"admin1"`
+					Country   string  `json:"country"`
+					Latitude  float64 `json:"latitude"`
+					Longitude float64 `json:"longitude"`
+				} `json:"results"`
+			}
+			params := url.Values{"name": {location}, "count": {"1"}}
+			if err := getJSON(ctx, client, "geocoding", config.GeocodingURL+"?"+params.Encode(), nil, &places); err != nil {
+				return "", err
+			}
+			if len(places.Results) == 0 {
+				return "", calque.NewErr(ctx, "no location found for "+strconv.Quote(location))
+			}
+			place := places.Results[0]
+
+			params = url.Values{
+				"latitude":  {strconv.FormatFloat(place.Latitude, 'f', -1, 64)},
+				"longitude": {strconv.FormatFloat(place.Longitude, 'f', -1, 64)},
+				"current":   {"temperature_2m,relative_humidity_2m,wind_speed_10m,weather_code"},
+				"timezone":  {"auto"},
+			}
+			units := "°C, km/h"
+			if config.Units == "imperial" {
+				params.Set("temperature_unit", "fahrenheit")
+				params.Set("wind_speed_unit", "mph")
+				units = "°F, mph"
+			}
+			var forecast struct {
+				Current struct {
+					Time        string  `json:"time"`
+					Temperature float64 `json:"temperature_2m"`
+					Humidity    float64 `json:"relative_humidity_2m"`
+					WindSpeed   float64 `json:"wind_speed_10m"`
+					WeatherCode int     `json:"weather_code"`
+				} `json:"current"`
+			}
+			if err := getJSON(ctx, client, "forecast", config.ForecastURL+"?"+params.Encode(), nil, &forecast); err != nil {
+				return "", err
+			}
+
+			name := place.Name
+			for _, part := range []string{place.Admin1, place.Country} {
+				if part != "" && part != name {
+					name += ", " + part
+				}
+			}
+			return jsonResult(CurrentWeather{
+				Location:    name,
+				Time:        forecast.Current.Time,
+				Temperature: forecast.Current.Temperature,
+				Humidity:    forecast.Current.Humidity,
+				WindSpeed:   forecast.Current.WindSpeed,
+				Conditions:  weatherConditions(forecast.Current.WeatherCode),
+				Units:       units,
+			})
+		})
+}
+
+// weatherConditions describes a WMO weather interpretation code
+func weatherConditions(code int) string {
+	switch {
+	case code == 0:
+		return "clear sky"
+	case code <= 3:
+		return "partly cloudy"
+	case code == 45 || code == 48:
+		return "fog"
+	case code >= 51 && code <= 57:
+		return "drizzle"
+	case code >= 61 && code <= 67, code >= 80 && code <= 82:
+		return "rain"
+	case code >= 71 && code <= 77, code == 85 || code == 86:
+		return "snow"
+	case code >= 95:
+		return "thunderstorm"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,59 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWeather(t *testing.T) {
+	var forecastQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search":
+			if r.URL.Query().Get("name") == "Nowhere" {
+				_, _ = w.Write([]byte(`{}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"results":[{"name":"Paris","admin1":"Île-de-France","country":"France","latitude":48.85341,"longitude":2.3488}]}`))
+		case "/forecast":
+			forecastQuery = r.URL.RawQuery
+			_, _ = w.Write([]byte(`{"current":{"time":"2026-03-02T10:00","temperature_2m":52.3,"relative_humidity_2m":71,"wind_speed_10m":8.4,"weather_code":61}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	weather := Weather(WeatherConfig{Units: "imperial", GeocodingURL: srv.URL + "/search", ForecastURL: srv.URL + "/forecast"})
+	if weather.Name() != "get_weather" {
+		t.Errorf("Name() = %q", weather.Name())
+	}
+
+	out, err := callTool(t, weather, `{"location":"Paris"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"location":"Paris, Île-de-France, France"`, `"temperature":52.3`, `"conditions":"rain"`, `"units":"°F, mph"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("result = %s, want %s", out, want)
+		}
+	}
+	if !strings.Contains(forecastQuery, "latitude=48.85341") || !strings.Contains(forecastQuery, "temperature_unit=fahrenheit") {
+		t.Errorf("forecast query = %s", forecastQuery)
+	}
+
+	if _, err := callTool(t, weather, `{"location":"Nowhere"}`); err == nil || !strings.Contains(err.Error(), `no location found for "Nowhere"`) {
+		t.Errorf("unknown location error = %v", err)
+	}
+}
+
+func TestWeatherConditions(t *testing.T) {
+	tests := map[int]string{0: "clear sky", 2: "partly cloudy", 45: "fog", 53: "drizzle", 81: "rain", 73: "snow", 95: "thunderstorm", 200: "thunderstorm", 10: "unknown"}
+	for code, expected := range tests {
+		if got := weatherConditions(code); got != expected {
+			t.Errorf("weatherConditions(%d) = %q, want %q", code, got, expected)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Package web provides ready-made tools for web search, news and weather.
+//
+// Each tool calls one fixed API with a bounded number of results and a
+// request timeout; models cannot make them fetch arbitrary URLs. API keys
+// are read from the config or the provider's usual environment variable:
+//
+//	search, err := web.BraveSearch(web.BraveConfig{}) // BRAVE_API_KEY
+//	if err != nil {
+//	    return err
+//	}
+//	agent := ai.Agent(client, ai.WithTools(search, web.Weather(web.WeatherConfig{})))
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/netpolicy"
+)
+
+// defaultTimeout bounds every request made by the web tools
+const defaultTimeout = 15 * time.Second
+
+// maxResponseSize caps how much of an API response is read
+const maxResponseSize = 2 << 20
+
+// SearchResult is a single web search hit.
+type SearchResult struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+type searchArgs struct {
+	Query string `json:"query" jsonschema:"required,description=Search query"`
+	Count int    `json:"count,omitempty" jsonschema:"description=Number of results (default 5; max 20)"`
+}
+
+// resultCount applies the default and maximum result counts
+func resultCount(n int) int {
+	if n <= 0 {
+		return 5
+	}
+	return min(n, 20)
+}
+
+// httpClientOrDefault returns client, or one with the default timeout
+func httpClientOrDefault(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return &http.Client{Timeout: defaultTimeout}
+}
+
+// getJSON fetches endpoint with the given headers and decodes the JSON response into out
+func getJSON(ctx context.Context, client *http.Client, service, endpoint string, headers map[string]string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "web: failed to create "+service+" request")
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := netpolicy.HTTPClient(ctx, client).Do(req)
+	if err != nil {
+		// drop the URL from the error; it may carry an API key
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return calque.WrapErr(ctx, err, "web: "+service+" request failed")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body := io.LimitReader(resp.Body, maxResponseSize)
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(body, 1024))
+		return calque.NewErr(ctx, fmt.Sprintf("web: %s returned status %d: %s", service, resp.StatusCode, strings.TrimSpace(string(msg))))
+	}
+	if err := json.NewDecoder(body).Decode(out); err != nil {
+		return calque.WrapErr(ctx, err, "web: failed to decode "+service+" response")
+	}
+	return nil
+}
+
+// jsonResult encodes a tool result for the model
+func jsonResult(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,60 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+func callTool(t *testing.T, tool tools.Tool, args string) (string, error) {
+	t.Helper()
+	var buf bytes.Buffer
+	req := calque.NewRequest(context.Background(), strings.NewReader(args))
+	err := tool.ServeFlow(req, calque.NewResponse(&buf))
+	return buf.String(), err
+}
+
+func TestResultCount(t *testing.T) {
+	tests := []struct {
+		in, expected int
+	}{{0, 5}, {-1, 5}, {3, 3}, {20, 20}, {100, 20}}
+	for _, tt := range tests {
+		if got := resultCount(tt.in); got != tt.expected {
+			t.Errorf("resultCount(%d) = %d, want %d", tt.in, got, tt.expected)
+		}
+	}
+}
+
+func TestGetJSONErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bad" {
+			_, _ = w.Write([]byte("not json"))
+			return
+		}
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	var out map[string]any
+
+	if err := getJSON(ctx, srv.Client(), "test", srv.URL+"/limited", nil, &out); err == nil ||
+		!strings.Contains(err.Error(), "test returned status 429: rate limited") {
+		t.Errorf("status error = %v", err)
+	}
+	if err := getJSON(ctx, srv.Client(), "test", srv.URL+"/bad", nil, &out); err == nil ||
+		!strings.Contains(err.Error(), "failed to decode test response") {
+		t.Errorf("decode error = %v", err)
+	}
+
+	srv.Close()
+	err := getJSON(ctx, srv.Client(), "test", srv.URL+"/?api_key=secret", nil, &out)
+	if err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("transport error = %v, want error without the request URL", err)
+	}
+}
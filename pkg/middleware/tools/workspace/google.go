@@ -0,0 +1,151 @@
+package workspace
+
+import (
+	"context"
+	"encoding/base64"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/middleware/tools/internal/httpapi"
+)
+
+// GoogleConfig configures the Google Workspace provider.
+type GoogleConfig struct {
+	// Token supplies OAuth access tokens with the calendar.readonly and
+	// gmail.compose scopes (required)
+	Token TokenSource
+
+	// CalendarID is the calendar to read. Default: "primary"
+	CalendarID string
+
+	// CalendarURL and GmailURL override the API roots (for testing or proxies).
+	// Defaults: https://www.googleapis.com/calendar/v3 and https://gmail.googleapis.com/gmail/v1
+	CalendarURL string
+	GmailURL    string
+
+	// HTTPClient is used for requests. Default: client with 30s timeout
+	HTTPClient *http.Client
+}
+
+// Google implements Calendar and Mailer with Google Calendar and Gmail.
+type Google struct {
+	api    *httpapi.Client
+	config GoogleConfig
+}
+
+// NewGoogle creates a Google Workspace provider.
+//
+// Example:
+//
+//	google := workspace.NewGoogle(workspace.GoogleConfig{
+//		Token: func(ctx context.Context) (string, error) {
+//			tok, err := oauthTokenSource.Token()
+//			if err != nil {
+//				return "", err
+//			}
+//			return tok.AccessToken, nil
+//		},
+//	})
+func NewGoogle(config GoogleConfig) *Google {
+	if config.CalendarID == "" {
+		config.CalendarID = "primary"
+	}
+	if config.CalendarURL == "" {
+		config.CalendarURL = "https://www.googleapis.com/calendar/v3"
+	}
+	if config.GmailURL == "" {
+		config.GmailURL = "https://gmail.googleapis.com/gmail/v1"
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Google{
+		api:    &httpapi.Client{Prefix: "workspace", Name: "google", HTTP: config.HTTPClient, Auth: bearer("google", config.Token)},
+		config: config,
+	}
+}
+
+type googleTime struct {
+	DateTime string `json:"dateTime"`
+	Date     string `json:"date"`
+}
+
+func (t googleTime) parse() (time.Time, bool) {
+	if t.DateTime != "" {
+		parsed, _ := time.Parse(time.RFC3339, t.DateTime)
+		return parsed, false
+	}
+	parsed, _ := time.Parse(time.DateOnly, t.Date)
+	return parsed, true
+}
+
+// ListEvents lists single events (recurrences expanded) ordered by start time.
+func (g *Google) ListEvents(ctx context.Context, from, to time.Time) ([]Event, error) {
+	params := url.Values{
+		"timeMin":      {from.Format(time.RFC3339)},
+		"timeMax":      {to.Format(time.RFC3339)},
+		"singleEvents": {"true"},
+		"orderBy":      {"startTime"},
+		"maxResults":   {"250"},
+	}
+	endpoint := strings.TrimSuffix(g.config.CalendarURL, "/") + "/calendars/" +
+		url.PathEscape(g.config.CalendarID) + "/events?" + params.Encode()
+
+	var result struct {
+		Items []struct {
+			ID        string     `json:"id"`
+			Summary   string     `json:"summary"`
+			Location  string     `json:"location"`
+			HTMLLink  string     `json:"htmlLink"`
+			Status    string     `json:"status"`
+			Start     googleTime `json:"start"`
+			End       googleTime `json:"end"`
+			Attendees []struct {
+				Email string `json:"email"`
+			} `json:"attendees"`
+		} `json:"items"`
+	}
+	if err := g.api.Do(ctx, http.MethodGet, endpoint, nil, &result); err != nil {
+		return nil, err
+	}
+
+	events := []Event{}
+	for _, item := range result.Items {
+		if item.Status == "cancelled" {
+			continue
+		}
+		start, allDay := item.Start.parse()
+		end, _ := item.End.parse()
+		e := Event{ID: item.ID, Title: item.Summary, Start: start, End: end, AllDay: allDay, Location: item.Location, URL: item.HTMLLink}
+		for _, a := range item.Attendees {
+			e.Attendees = append(e.Attendees, a.Email)
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// CreateDraft saves a plain-text draft in Gmail.
+func (g *Google) CreateDraft(ctx context.Context, draft Draft) (*DraftResult, error) {
+	var msg strings.Builder
+	msg.WriteString("To: " + strings.Join(draft.To, ", ") + "\r\n")
+	if len(draft.Cc) > 0 {
+		msg.WriteString("Cc: " + strings.Join(draft.Cc, ", ") + "\r\n")
+	}
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", draft.Subject) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=\"UTF-8\"\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(draft.Body, "\n", "\r\n"))
+
+	body := map[string]any{"message": map[string]string{"raw": base64.URLEncoding.EncodeToString([]byte(msg.String()))}}
+	var created struct {
+		ID string `json:"id"`
+	}
+	endpoint := strings.TrimSuffix(g.config.GmailURL, "/") + "/users/me/drafts"
+	if err := g.api.Do(ctx, http.MethodPost, endpoint, body, &created); err != nil {
+		return nil, err
+	}
+	return &DraftResult{ID: created.ID, URL: "https://mail.google.com/mail/#drafts"}, nil
+}
@@ -0,0 +1,81 @@
+package workspace
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGoogle(t *testing.T) {
+	var query, raw string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer g-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /calendar/calendars/primary/events":
+			query = r.URL.RawQuery
+			_, _ = w.Write([]byte(`{"items":[
+				{"id":"e1","summary":"Review","start":{"dateTime":"2026-03-02T10:00:00Z"},"end":{"dateTime":"2026-03-02T11:00:00Z"},"attendees":[{"email":"ada@example.com"}]},
+				{"id":"e2","summary":"Holiday","start":{"date":"2026-03-03"},"end":{"date":"2026-03-04"}},
+				{"id":"e3","status":"cancelled"}
+			]}`))
+		case "POST /gmail/users/me/drafts":
+			var body struct {
+				Message struct {
+					Raw string `json:"raw"`
+				} `json:"message"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			decoded, _ := base64.URLEncoding.DecodeString(body.Message.Raw)
+			raw = string(decoded)
+			_, _ = w.Write([]byte(`{"id":"d1"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	google := NewGoogle(GoogleConfig{
+		Token:       StaticToken("g-token"),
+		CalendarURL: srv.URL + "/calendar",
+		GmailURL:    srv.URL + "/gmail",
+	})
+	ctx := context.Background()
+
+	events, err := google.ListEvents(ctx, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC))
+	if err != nil || len(events) != 2 {
+		t.Fatalf("ListEvents = %+v, %v", events, err)
+	}
+	if events[0].Attendees[0] != "ada@example.com" || events[0].End.Hour() != 11 {
+		t.Errorf("timed event = %+v", events[0])
+	}
+	if !events[1].AllDay || events[1].Start.Day() != 3 {
+		t.Errorf("all-day event = %+v", events[1])
+	}
+	if !strings.Contains(query, "singleEvents=true") || !strings.Contains(query, "timeMin=2026-03-02T00%3A00%3A00Z") {
+		t.Errorf("query = %s", query)
+	}
+
+	result, err := google.CreateDraft(ctx, Draft{To: []string{"ada@example.com"}, Subject: "Café", Body: "line 1\nline 2"})
+	if err != nil || result.ID != "d1" {
+		t.Fatalf("CreateDraft = %+v, %v", result, err)
+	}
+	if !strings.Contains(raw, "To: ada@example.com\r\n") || !strings.Contains(raw, "Subject: =?utf-8?q?Caf=C3=A9?=") ||
+		!strings.HasSuffix(raw, "\r\n\r\nline 1\r\nline 2") {
+		t.Errorf("raw message = %q", raw)
+	}
+}
+
+func TestGoogleRequiresToken(t *testing.T) {
+	if _, err := NewGoogle(GoogleConfig{}).ListEvents(context.Background(), time.Now(), time.Now()); err == nil ||
+		!strings.Contains(err.Error(), "token source is required") {
+		t.Errorf("error = %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package workspace
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// bearer authorizes requests with an access token from token
+func bearer(name string, token TokenSource) func(context.Context, *http.Request) error {
+	return func(ctx context.Context, req *http.Request) error {
+		if token == nil {
+			return calque.NewErr(ctx, "workspace: "+name+" token source is required")
+		}
+		access, err := token(ctx)
+		if err != nil {
+			return calque.WrapErr(ctx, err, "workspace: failed to get "+name+" access token")
+		}
+		req.Header.Set("Authorization", "Bearer "+access)
+		return nil
+	}
+}
@@ -0,0 +1,154 @@
+package workspace
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/middleware/tools/internal/httpapi"
+)
+
+// MicrosoftConfig configures the Microsoft 365 provider.
+type MicrosoftConfig struct {
+	// Token supplies Microsoft Graph access tokens with the Calendars.Read and
+	// Mail.ReadWrite permissions (required)
+	Token TokenSource
+
+	// User is the mailbox to act on, as a user ID or principal name. Default: the signed-in user ("me")
+	User string
+
+	// BaseURL is the Graph API root. Default: https://graph.microsoft.com/v1.0
+	BaseURL string
+
+	// HTTPClient is used for requests. Default: client with 30s timeout
+	HTTPClient *http.Client
+}
+
+// Microsoft implements Calendar and Mailer with Microsoft Graph (Outlook).
+type Microsoft struct {
+	api  *httpapi.Client
+	root string // "{BaseURL}/me" or "{BaseURL}/users/{id}"
+}
+
+// NewMicrosoft creates a Microsoft 365 provider.
+//
+// Example:
+//
+//	outlook := workspace.NewMicrosoft(workspace.MicrosoftConfig{
+//		Token: workspace.StaticToken(graphToken),
+//	})
+func NewMicrosoft(config MicrosoftConfig) *Microsoft {
+	if config.BaseURL == "" {
+		config.BaseURL = "https://graph.microsoft.com/v1.0"
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	root := strings.TrimSuffix(config.BaseURL, "/") + "/me"
+	if config.User != "" && config.User != "me" {
+		root = strings.TrimSuffix(config.BaseURL, "/") + "/users/" + url.PathEscape(config.User)
+	}
+	return &Microsoft{
+		api: &httpapi.Client{
+			Prefix:  "workspace",
+			Name:    "microsoft graph",
+			Headers: map[string]string{"Prefer": `outlook.timezone="UTC"`},
+			HTTP:    config.HTTPClient,
+			Auth:    bearer("microsoft graph", config.Token),
+		},
+		root: root,
+	}
+}
+
+type graphRecipient struct {
+	EmailAddress struct {
+		Address string `json:"address"`
+	} `json:"emailAddress"`
+}
+
+func graphRecipients(addresses []string) []graphRecipient {
+	recipients := make([]graphRecipient, len(addresses))
+	for i, a := range addresses {
+		recipients[i].EmailAddress.Address = a
+	}
+	return recipients
+}
+
+// ListEvents lists calendar view events (recurrences expanded) ordered by start time.
+func (m *Microsoft) ListEvents(ctx context.Context, from, to time.Time) ([]Event, error) {
+	params := url.Values{
+		"startDateTime": {from.UTC().Format(time.RFC3339)},
+		"endDateTime":   {to.UTC().Format(time.RFC3339)},
+		"$orderby":      {"start/dateTime"},
+		"$top":          {"250"},
+	}
+
+	var result struct {
+		Value []struct {
+			ID          string `json:"id"`
+			Subject     string `json:"subject"`
+			IsAllDay    bool   `json:"isAllDay"`
+			IsCancelled bool   `json:"isCancelled"`
+			WebLink     string `json:"webLink"`
+			Start       struct {
+				DateTime string `json:"dateTime"`
+			} `json:"start"`
+			End struct {
+				DateTime string `json:"dateTime"`
+			} `json:"end"`
+			Location struct {
+				DisplayName string `json:"displayName"`
+			} `json:"location"`
+			Attendees []graphRecipient `json:"attendees"`
+		} `json:"value"`
+	}
+	if err := m.api.Do(ctx, http.MethodGet, m.root+"/calendarView?"+params.Encode(), nil, &result); err != nil {
+		return nil, err
+	}
+
+	events := []Event{}
+	for _, item := range result.Value {
+		if item.IsCancelled {
+			continue
+		}
+		e := Event{
+			ID: item.ID, Title: item.Subject, AllDay: item.IsAllDay, URL: item.WebLink,
+			Start: parseGraphTime(item.Start.DateTime), End: parseGraphTime(item.End.DateTime),
+			Location: item.Location.DisplayName,
+		}
+		for _, a := range item.Attendees {
+			e.Attendees = append(e.Attendees, a.EmailAddress.Address)
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// parseGraphTime parses Graph's zone-less dateTime values, which are UTC given the Prefer header
+func parseGraphTime(s string) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02T15:04:05.9999999", s, time.UTC)
+	return t
+}
+
+// CreateDraft saves a plain-text draft in the Drafts folder.
+func (m *Microsoft) CreateDraft(ctx context.Context, draft Draft) (*DraftResult, error) {
+	body := map[string]any{
+		"subject":      draft.Subject,
+		"body":         map[string]string{"contentType": "Text", "content": draft.Body},
+		"toRecipients": graphRecipients(draft.To),
+	}
+	if len(draft.Cc) > 0 {
+		body["ccRecipients"] = graphRecipients(draft.Cc)
+	}
+
+	var created struct {
+		ID      string `json:"id"`
+		WebLink string `json:"webLink"`
+	}
+	if err := m.api.Do(ctx, http.MethodPost, m.root+"/messages", body, &created); err != nil {
+		return nil, err
+	}
+	return &DraftResult{ID: created.ID, URL: created.WebLink}, nil
+}
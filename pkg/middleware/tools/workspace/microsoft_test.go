@@ -0,0 +1,55 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMicrosoft(t *testing.T) {
+	var prefer string
+	var draftBody map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefer = r.Header.Get("Prefer")
+		switch r.Method + " " + r.URL.Path {
+		case "GET /users/ada@example.com/calendarView":
+			_, _ = w.Write([]byte(`{"value":[
+				{"id":"m1","subject":"1:1","start":{"dateTime":"2026-03-02T13:30:00.0000000"},"end":{"dateTime":"2026-03-02T14:00:00.0000000"},
+				 "location":{"displayName":"Room 4"},"attendees":[{"emailAddress":{"address":"bob@example.com"}}]},
+				{"id":"m2","subject":"Cancelled","isCancelled":true}
+			]}`))
+		case "POST /users/ada@example.com/messages":
+			_ = json.NewDecoder(r.Body).Decode(&draftBody)
+			_, _ = w.Write([]byte(`{"id":"msg-1","webLink":"https://outlook.office.com/draft"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	outlook := NewMicrosoft(MicrosoftConfig{Token: StaticToken("m-token"), User: "ada@example.com", BaseURL: srv.URL})
+	ctx := context.Background()
+
+	events, err := outlook.ListEvents(ctx, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC))
+	if err != nil || len(events) != 1 {
+		t.Fatalf("ListEvents = %+v, %v", events, err)
+	}
+	e := events[0]
+	if !e.Start.Equal(time.Date(2026, 3, 2, 13, 30, 0, 0, time.UTC)) || e.Location != "Room 4" || e.Attendees[0] != "bob@example.com" {
+		t.Errorf("event = %+v", e)
+	}
+	if prefer != `outlook.timezone="UTC"` {
+		t.Errorf("Prefer = %q", prefer)
+	}
+
+	result, err := outlook.CreateDraft(ctx, Draft{To: []string{"bob@example.com"}, Cc: []string{"eve@example.com"}, Subject: "Notes", Body: "See below"})
+	if err != nil || result.ID != "msg-1" || result.URL == "" {
+		t.Fatalf("CreateDraft = %+v, %v", result, err)
+	}
+	if draftBody["subject"] != "Notes" || len(draftBody["ccRecipients"].([]any)) != 1 {
+		t.Errorf("draft body = %v", draftBody)
+	}
+}
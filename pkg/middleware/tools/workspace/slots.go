@@ -0,0 +1,117 @@
+package workspace
+
+import (
+	"slices"
+	"time"
+)
+
+// Slot is a proposed meeting time.
+type Slot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// SlotOptions controls FreeSlots.
+type SlotOptions struct {
+	Location     *time.Location // time zone of working hours (default: UTC)
+	WorkdayStart int            // first working hour (default: 9)
+	WorkdayEnd   int            // hour working time ends (default: 17)
+	Weekends     bool           // also propose Saturdays and Sundays
+	Max          int            // maximum slots (default: 5)
+	Step         time.Duration  // start times are aligned to this (default: 30m)
+}
+
+// FreeSlots proposes meeting slots of the given duration that avoid events.
+//
+// Slots fall within working hours between from and to, start on Step
+// boundaries, and at most one is proposed per free gap so suggestions spread
+// across the range. All-day events block their whole day.
+//
+// Example:
+//
+//	slots := workspace.FreeSlots(events, monday, friday, 30*time.Minute, workspace.SlotOptions{
+//		Location: time.Local,
+//		Max:      3,
+//	})
+func FreeSlots(events []Event, from, to time.Time, duration time.Duration, opts SlotOptions) []Slot {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	if opts.WorkdayStart == 0 && opts.WorkdayEnd == 0 {
+		opts.WorkdayStart, opts.WorkdayEnd = 9, 17
+	}
+	if opts.Max <= 0 {
+		opts.Max = 5
+	}
+	if opts.Step <= 0 {
+		opts.Step = 30 * time.Minute
+	}
+	if duration <= 0 {
+		return nil
+	}
+
+	busy := make([]Slot, 0, len(events))
+	for _, e := range events {
+		start, end := e.Start, e.End
+		if e.AllDay {
+			y, m, d := e.Start.In(opts.Location).Date()
+			start = time.Date(y, m, d, 0, 0, 0, 0, opts.Location)
+			end = later(end, start.AddDate(0, 0, 1))
+		}
+		busy = append(busy, Slot{Start: start, End: end})
+	}
+	slices.SortFunc(busy, func(a, b Slot) int { return a.Start.Compare(b.Start) })
+
+	var slots []Slot
+	y, m, d := from.In(opts.Location).Date()
+	for day := time.Date(y, m, d, 0, 0, 0, 0, opts.Location); day.Before(to) && len(slots) < opts.Max; day = day.AddDate(0, 0, 1) {
+		if !opts.Weekends && (day.Weekday() == time.Saturday || day.Weekday() == time.Sunday) {
+			continue
+		}
+		y, m, d := day.Date()
+		windowStart := later(from, time.Date(y, m, d, opts.WorkdayStart, 0, 0, 0, opts.Location))
+		windowEnd := earlier(to, time.Date(y, m, d, opts.WorkdayEnd, 0, 0, 0, opts.Location))
+
+		cursor := windowStart
+		for _, b := range append(busy, Slot{Start: windowEnd, End: windowEnd}) {
+			if !b.End.After(cursor) {
+				continue
+			}
+			gapEnd := earlier(b.Start, windowEnd)
+			if start := alignUp(cursor, day, opts.Step); !start.Add(duration).After(gapEnd) {
+				slots = append(slots, Slot{Start: start, End: start.Add(duration)})
+				if len(slots) == opts.Max {
+					break
+				}
+			}
+			if !b.Start.Before(windowEnd) {
+				break
+			}
+			cursor = later(cursor, b.End)
+		}
+	}
+	return slots
+}
+
+// alignUp rounds t up to the next step boundary counted from midnight
+func alignUp(t, midnight time.Time, step time.Duration) time.Time {
+	offset := t.Sub(midnight)
+	if rem := offset % step; rem != 0 {
+		offset += step - rem
+	}
+	return midnight.Add(offset)
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,76 @@
+package workspace
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFreeSlots(t *testing.T) {
+	at := func(day, hour, minute int) time.Time { return time.Date(2026, 3, day, hour, minute, 0, 0, time.UTC) }
+	monday, wednesday := at(2, 0, 0), at(4, 0, 0)
+
+	tests := []struct {
+		name     string
+		events   []Event
+		from, to time.Time
+		duration time.Duration
+		opts     SlotOptions
+		expected []Slot
+	}{
+		{
+			name:     "one slot per gap around meetings",
+			events:   []Event{{Start: at(2, 9, 0), End: at(2, 10, 15)}, {Start: at(2, 11, 0), End: at(2, 16, 0)}},
+			from:     monday,
+			to:       at(3, 0, 0),
+			duration: 30 * time.Minute,
+			expected: []Slot{{at(2, 10, 30), at(2, 11, 0)}, {at(2, 16, 0), at(2, 16, 30)}},
+		},
+		{
+			name:     "gap too short",
+			events:   []Event{{Start: at(2, 9, 0), End: at(2, 12, 20)}, {Start: at(2, 13, 0), End: at(2, 17, 0)}},
+			from:     monday,
+			to:       at(3, 0, 0),
+			duration: time.Hour,
+			expected: nil,
+		},
+		{
+			name:     "all-day event blocks the day",
+			events:   []Event{{Start: monday, End: at(3, 0, 0), AllDay: true}},
+			from:     monday,
+			to:       wednesday,
+			duration: time.Hour,
+			opts:     SlotOptions{Max: 1},
+			expected: []Slot{{at(3, 9, 0), at(3, 10, 0)}},
+		},
+		{
+			name:     "weekends skipped",
+			from:     at(7, 0, 0), // Saturday
+			to:       at(10, 0, 0),
+			duration: time.Hour,
+			opts:     SlotOptions{Max: 1},
+			expected: []Slot{{at(9, 9, 0), at(9, 10, 0)}},
+		},
+		{
+			name:     "respects from within the day and max",
+			from:     at(2, 14, 5),
+			to:       wednesday,
+			duration: time.Hour,
+			opts:     SlotOptions{Max: 2},
+			expected: []Slot{{at(2, 14, 30), at(2, 15, 30)}, {at(3, 9, 0), at(3, 10, 0)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FreeSlots(tt.events, tt.from, tt.to, tt.duration, tt.opts)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("FreeSlots() = %v, want %v", got, tt.expected)
+			}
+			for i := range got {
+				if !got[i].Start.Equal(tt.expected[i].Start) || !got[i].End.Equal(tt.expected[i].End) {
+					t.Errorf("slot %d = %v-%v, want %v-%v", i, got[i].Start, got[i].End, tt.expected[i].Start, tt.expected[i].End)
+				}
+			}
+		})
+	}
+}
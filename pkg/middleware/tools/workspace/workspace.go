@@ -0,0 +1,255 @@
+// Package workspace provides calendar and email drafting tools backed by
+// Google Workspace or Microsoft 365 (Graph).
+//
+// Sending anything on a user's behalf requires approval: every tool that
+// writes to the mailbox asks the configured Approver first, and refuses to
+// act without one.
+//
+//	google := workspace.NewGoogle(workspace.GoogleConfig{Token: workspace.StaticToken(accessToken)})
+//	assistant := ai.Agent(client, ai.WithTools(workspace.Tools(google, google, workspace.Config{
+//	    Approver: workspace.ApproverFunc(askUserInSlack),
+//	})...))
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+// ErrNotApproved is returned when an Approver rejects an action.
+var ErrNotApproved = errors.New("workspace: action not approved")
+
+// TokenSource returns a current OAuth access token.
+type TokenSource func(ctx context.Context) (string, error)
+
+// StaticToken returns a TokenSource for a fixed access token.
+func StaticToken(token string) TokenSource {
+	return func(context.Context) (string, error) { return token, nil }
+}
+
+// Event is a calendar event.
+type Event struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	AllDay    bool      `json:"all_day,omitempty"`
+	Location  string    `json:"location,omitempty"`
+	Attendees []string  `json:"attendees,omitempty"`
+	URL       string    `json:"url,omitempty"`
+}
+
+// Draft is an email to save as a draft. Drafts are never sent by these tools.
+type Draft struct {
+	To      []string `json:"to" jsonschema:"required,description=Recipient email addresses"`
+	Cc      []string `json:"cc,omitempty"`
+	Subject string   `json:"subject" jsonschema:"required"`
+	Body    string   `json:"body" jsonschema:"required,description=Plain-text message body"`
+}
+
+// DraftResult identifies a saved draft.
+type DraftResult struct {
+	ID  string `json:"id"`
+	URL string `json:"url,omitempty"`
+}
+
+// Calendar lists events from a user's calendar.
+type Calendar interface {
+	ListEvents(ctx context.Context, from, to time.Time) ([]Event, error)
+}
+
+// Mailer saves email drafts in a user's mailbox.
+type Mailer interface {
+	CreateDraft(ctx context.Context, draft Draft) (*DraftResult, error)
+}
+
+// Action describes a write a tool wants to perform.
+type Action struct {
+	Tool    string // tool requesting approval, e.g. "email_create_draft"
+	Summary string // human-readable description
+	Payload any    // the data that would be written, e.g. a Draft
+}
+
+// Approver decides whether an action may proceed, typically by asking a human.
+type Approver interface {
+	Approve(ctx context.Context, action Action) (bool, error)
+}
+
+// ApproverFunc adapts a function to the Approver interface.
+type ApproverFunc func(ctx context.Context, action Action) (bool, error)
+
+// Approve calls f.
+func (f ApproverFunc) Approve(ctx context.Context, action Action) (bool, error) {
+	return f(ctx, action)
+}
+
+// Config configures the workspace tools.
+type Config struct {
+	// Approver - required for email_create_draft; without one the tool refuses every call
+	Approver Approver
+	// Location - time zone for working hours and date-only arguments (default: UTC)
+	Location *time.Location
+	// WorkdayStart, WorkdayEnd - working hours for proposed slots (default: 9 and 17)
+	WorkdayStart int
+	WorkdayEnd   int
+}
+
+// Tools exposes a calendar and mailer to agents.
+//
+// Returns calendar_list_events and calendar_propose_slots when cal is set, and
+// email_create_draft when mailer is set. Drafts are only saved after
+// Config.Approver approves them; they are never sent.
+//
+// Example:
+//
+//	outlook := workspace.NewMicrosoft(workspace.MicrosoftConfig{Token: tokenSource})
+//	agent := ai.Agent(client, ai.WithTools(workspace.Tools(outlook, outlook, workspace.Config{
+//	    Approver: approver,
+//	    Location: time.Local,
+//	})...))
+func Tools(cal Calendar, mailer Mailer, config ...Config) []tools.Tool {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	if cfg.WorkdayStart == 0 && cfg.WorkdayEnd == 0 {
+		cfg.WorkdayStart, cfg.WorkdayEnd = 9, 17
+	}
+
+	var workspaceTools []tools.Tool
+	if cal != nil {
+		workspaceTools = append(workspaceTools,
+			tools.Typed("calendar_list_events", "List calendar events between two times",
+				func(ctx context.Context, args listEventsArgs) (string, error) {
+					from, to, err := cfg.parseRange(ctx, args.From, args.To)
+					if err != nil {
+						return "", err
+					}
+					return jsonResult(cal.ListEvents(ctx, from, to))
+				}),
+			tools.Typed("calendar_propose_slots", "Propose free meeting slots within working hours",
+				func(ctx context.Context, args proposeSlotsArgs) (string, error) {
+					from, to, err := cfg.parseRange(ctx, args.From, args.To)
+					if err != nil {
+						return "", err
+					}
+					if args.DurationMinutes <= 0 {
+						return "", calque.NewErr(ctx, "duration_minutes must be positive")
+					}
+					events, err := cal.ListEvents(ctx, from, to)
+					if err != nil {
+						return "", err
+					}
+					slots := FreeSlots(events, from, to, time.Duration(args.DurationMinutes)*time.Minute, SlotOptions{
+						Location:     cfg.Location,
+						WorkdayStart: cfg.WorkdayStart,
+						WorkdayEnd:   cfg.WorkdayEnd,
+						Max:          args.Count,
+					})
+					return jsonResult(slots, nil)
+				}),
+		)
+	}
+	if mailer != nil {
+		workspaceTools = append(workspaceTools,
+			tools.Typed("email_create_draft", "Save an email draft for the user to review and send (requires approval)",
+				func(ctx context.Context, args Draft) (string, error) {
+					if len(args.To) == 0 || strings.TrimSpace(args.Subject) == "" {
+						return "", calque.NewErr(ctx, "to and subject are required")
+					}
+					for _, addr := range append(slices.Clone(args.To), args.Cc...) {
+						if _, err := mail.ParseAddress(addr); err != nil {
+							return "", calque.WrapErr(ctx, err, fmt.Sprintf("invalid address %q", addr))
+						}
+					}
+					if err := cfg.approve(ctx, Action{
+						Tool:    "email_create_draft",
+						Summary: fmt.Sprintf("Save draft %q to %s", args.Subject, strings.Join(args.To, ", ")),
+						Payload: args,
+					}); err != nil {
+						return "", err
+					}
+					return jsonResult(mailer.CreateDraft(ctx, args))
+				}),
+		)
+	}
+	return workspaceTools
+}
+
+type listEventsArgs struct {
+	From string `json:"from" jsonschema:"required,description=Start time (RFC 3339) or date (YYYY-MM-DD)"`
+	To   string `json:"to" jsonschema:"required,description=End time (RFC 3339) or date (YYYY-MM-DD); a date includes the whole day"`
+}
+
+type proposeSlotsArgs struct {
+	From            string `json:"from" jsonschema:"required,description=Earliest start (RFC 3339) or date (YYYY-MM-DD)"`
+	To              string `json:"to" jsonschema:"required,description=Latest end (RFC 3339) or date (YYYY-MM-DD)"`
+	DurationMinutes int    `json:"duration_minutes" jsonschema:"required,description=Meeting length in minutes"`
+	Count           int    `json:"count,omitempty" jsonschema:"description=Number of slots to propose (default 5)"`
+}
+
+// approve asks the approver, failing closed when none is configured
+func (c Config) approve(ctx context.Context, action Action) error {
+	if c.Approver == nil {
+		return calque.NewErr(ctx, action.Tool+" requires an Approver")
+	}
+	ok, err := c.Approver.Approve(ctx, action)
+	if err != nil {
+		return calque.WrapErr(ctx, err, "approval failed")
+	}
+	if !ok {
+		return calque.WrapErr(ctx, ErrNotApproved, action.Summary)
+	}
+	return nil
+}
+
+// parseRange parses from/to arguments; a date-only "to" covers the whole day
+func (c Config) parseRange(ctx context.Context, fromArg, toArg string) (time.Time, time.Time, error) {
+	from, _, err := c.parseTime(fromArg)
+	if err != nil {
+		return time.Time{}, time.Time{}, calque.WrapErr(ctx, err, "invalid from")
+	}
+	to, dateOnly, err := c.parseTime(toArg)
+	if err != nil {
+		return time.Time{}, time.Time{}, calque.WrapErr(ctx, err, "invalid to")
+	}
+	if dateOnly {
+		to = to.AddDate(0, 0, 1)
+	}
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, calque.NewErr(ctx, "to must be after from")
+	}
+	return from, to, nil
+}
+
+func (c Config) parseTime(s string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, false, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, s, c.Location)
+	return t, true, err
+}
+
+// jsonResult encodes a tool result for the model
+func jsonResult[T any](v T, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
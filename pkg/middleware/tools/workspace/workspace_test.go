@@ -0,0 +1,124 @@
+package workspace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/tools"
+)
+
+type fakeProvider struct {
+	events   []Event
+	from, to time.Time
+	drafts   []Draft
+}
+
+func (f *fakeProvider) ListEvents(_ context.Context, from, to time.Time) ([]Event, error) {
+	f.from, f.to = from, to
+	return f.events, nil
+}
+
+func (f *fakeProvider) CreateDraft(_ context.Context, draft Draft) (*DraftResult, error) {
+	f.drafts = append(f.drafts, draft)
+	return &DraftResult{ID: "draft-1"}, nil
+}
+
+func callTool(t *testing.T, workspaceTools []tools.Tool, name, args string) (string, error) {
+	t.Helper()
+	for _, tool := range workspaceTools {
+		if tool.Name() == name {
+			var buf bytes.Buffer
+			req := calque.NewRequest(context.Background(), strings.NewReader(args))
+			err := tool.ServeFlow(req, calque.NewResponse(&buf))
+			return buf.String(), err
+		}
+	}
+	t.Fatalf("tool %q not found", name)
+	return "", nil
+}
+
+func TestCalendarTools(t *testing.T) {
+	f := &fakeProvider{events: []Event{{
+		ID: "1", Title: "Standup",
+		Start: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+		End:   time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC),
+	}}}
+	workspaceTools := Tools(f, nil)
+	if len(workspaceTools) != 2 {
+		t.Fatalf("len(Tools) = %d, want 2 calendar tools", len(workspaceTools))
+	}
+
+	out, err := callTool(t, workspaceTools, "calendar_list_events", `{"from":"2026-03-02","to":"2026-03-02"}`)
+	if err != nil || !strings.Contains(out, "Standup") {
+		t.Errorf("calendar_list_events = %q, %v", out, err)
+	}
+	if !f.to.Equal(time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("date-only to = %v, want end of day", f.to)
+	}
+
+	out, err = callTool(t, workspaceTools, "calendar_propose_slots", `{"from":"2026-03-02","to":"2026-03-02","duration_minutes":60,"count":1}`)
+	if err != nil || !strings.Contains(out, `"start":"2026-03-02T10:00:00Z"`) {
+		t.Errorf("calendar_propose_slots = %q, %v", out, err)
+	}
+
+	if _, err := callTool(t, workspaceTools, "calendar_list_events", `{"from":"2026-03-03","to":"2026-03-02T00:00:00Z"}`); err == nil {
+		t.Error("reversed range accepted")
+	}
+	if _, err := callTool(t, workspaceTools, "calendar_propose_slots", `{"from":"2026-03-02","to":"2026-03-03"}`); err == nil {
+		t.Error("missing duration accepted")
+	}
+}
+
+func TestEmailDraftRequiresApproval(t *testing.T) {
+	draftArgs := `{"to":["ada@example.com"],"subject":"Sync","body":"Does Tuesday work?"}`
+
+	t.Run("no approver", func(t *testing.T) {
+		f := &fakeProvider{}
+		_, err := callTool(t, Tools(nil, f), "email_create_draft", draftArgs)
+		if err == nil || !strings.Contains(err.Error(), "requires an Approver") || len(f.drafts) != 0 {
+			t.Errorf("error = %v, drafts = %d", err, len(f.drafts))
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		f := &fakeProvider{}
+		var seen Action
+		approver := ApproverFunc(func(_ context.Context, a Action) (bool, error) {
+			seen = a
+			return false, nil
+		})
+		_, err := callTool(t, Tools(nil, f, Config{Approver: approver}), "email_create_draft", draftArgs)
+		if !errors.Is(err, ErrNotApproved) || len(f.drafts) != 0 {
+			t.Errorf("error = %v, drafts = %d", err, len(f.drafts))
+		}
+		if seen.Tool != "email_create_draft" || !strings.Contains(seen.Summary, "ada@example.com") {
+			t.Errorf("action = %+v", seen)
+		}
+		if d, ok := seen.Payload.(Draft); !ok || d.Body != "Does Tuesday work?" {
+			t.Errorf("payload = %#v", seen.Payload)
+		}
+	})
+
+	t.Run("approved", func(t *testing.T) {
+		f := &fakeProvider{}
+		approver := ApproverFunc(func(context.Context, Action) (bool, error) { return true, nil })
+		out, err := callTool(t, Tools(nil, f, Config{Approver: approver}), "email_create_draft", draftArgs)
+		if err != nil || !strings.Contains(out, "draft-1") || len(f.drafts) != 1 {
+			t.Errorf("result = %q, %v, drafts = %d", out, err, len(f.drafts))
+		}
+	})
+
+	t.Run("header injection", func(t *testing.T) {
+		f := &fakeProvider{}
+		approver := ApproverFunc(func(context.Context, Action) (bool, error) { return true, nil })
+		args := `{"to":["ada@example.com\r\nBcc: eve@example.com"],"subject":"x","body":"y"}`
+		if _, err := callTool(t, Tools(nil, f, Config{Approver: approver}), "email_create_draft", args); err == nil {
+			t.Error("address with CRLF accepted")
+		}
+	})
+}
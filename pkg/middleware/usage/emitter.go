@@ -0,0 +1,180 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Emitter defaults.
+const (
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = 5 * time.Second
+	DefaultMaxPending    = 10000
+)
+
+// EmitterConfig configures an Emitter.
+type EmitterConfig struct {
+	// BatchSize is the number of pending records that triggers a send.
+	// Default: DefaultBatchSize
+	BatchSize int
+	// FlushInterval is how often pending records are sent.
+	// Default: DefaultFlushInterval
+	FlushInterval time.Duration
+	// MaxPending bounds the records kept while the sink fails; the oldest
+	// are dropped beyond it. Default: DefaultMaxPending
+	MaxPending int
+	// OnError is called when a send fails or records are dropped (optional)
+	OnError func(error)
+}
+
+// Emitter batches usage records and sends them to a Sink in the background.
+//
+// Records are sent when BatchSize are pending or every FlushInterval. A
+// failed send keeps the batch and retries it with the next one, so a sink
+// outage delays records instead of losing them, up to MaxPending. Always
+// call Shutdown before exiting to send pending records.
+type Emitter struct {
+	sink   Sink
+	config EmitterConfig
+
+	mu      sync.Mutex
+	pending []Record
+	closed  bool
+
+	sendMu  sync.Mutex // one send at a time keeps records in order
+	flushCh chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewEmitter starts an emitter sending to sink.
+//
+// Example:
+//
+//	emitter := usage.NewEmitter(usage.NewHTTPSink("https://billing.internal/v1/usage"), usage.EmitterConfig{
+//		OnError: func(err error) { slog.Error("usage export failed", "error", err) },
+//	})
+//	defer emitter.Shutdown(context.Background())
+func NewEmitter(sink Sink, config ...EmitterConfig) *Emitter {
+	var cfg EmitterConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultFlushInterval
+	}
+	if cfg.MaxPending <= 0 {
+		cfg.MaxPending = DefaultMaxPending
+	}
+
+	e := &Emitter{
+		sink:    sink,
+		config:  cfg,
+		flushCh: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	e.wg.Add(1)
+	go e.loop()
+	return e
+}
+
+// Emit queues a record. It never blocks on the sink.
+func (e *Emitter) Emit(record Record) {
+	e.mu.Lock()
+	e.pending = append(e.pending, record)
+	dropped := e.trim()
+	full := len(e.pending) >= e.config.BatchSize
+	e.mu.Unlock()
+
+	e.reportDropped(dropped)
+	if full {
+		select {
+		case e.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Flush sends all pending records now.
+func (e *Emitter) Flush(ctx context.Context) error {
+	e.sendMu.Lock()
+	defer e.sendMu.Unlock()
+
+	e.mu.Lock()
+	batch := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	if err := e.sink.Write(ctx, batch); err != nil {
+		e.mu.Lock()
+		e.pending = append(batch, e.pending...)
+		dropped := e.trim()
+		e.mu.Unlock()
+		e.reportDropped(dropped)
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops the background loop and sends pending records.
+func (e *Emitter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.mu.Unlock()
+
+	close(e.done)
+	e.wg.Wait()
+	return e.Flush(ctx)
+}
+
+// trim drops the oldest records beyond MaxPending; called with mu held
+func (e *Emitter) trim() int {
+	over := len(e.pending) - e.config.MaxPending
+	if over <= 0 {
+		return 0
+	}
+	e.pending = append([]Record(nil), e.pending[over:]...)
+	return over
+}
+
+func (e *Emitter) reportDropped(dropped int) {
+	if dropped > 0 {
+		e.reportError(fmt.Errorf("usage emitter dropped %d records over MaxPending %d", dropped, e.config.MaxPending))
+	}
+}
+
+// loop flushes on a timer or when signalled by Emit
+func (e *Emitter) loop() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+		case <-e.flushCh:
+		}
+		e.reportError(e.Flush(context.Background()))
+	}
+}
+
+func (e *Emitter) reportError(err error) {
+	if err != nil && e.config.OnError != nil {
+		e.config.OnError(err)
+	}
+}
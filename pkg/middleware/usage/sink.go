@@ -0,0 +1,198 @@
+package usage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// Sink delivers batches of usage records to a billing pipeline.
+type Sink interface {
+	// Write sends records in order; on error the Emitter retries the batch
+	Write(ctx context.Context, records []Record) error
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(ctx context.Context, records []Record) error
+
+// Write implements Sink.
+func (f SinkFunc) Write(ctx context.Context, records []Record) error {
+	return f(ctx, records)
+}
+
+// WriterSink writes records to w as JSON Lines, one record per line.
+func WriterSink(w io.Writer) Sink {
+	var mu sync.Mutex
+	return SinkFunc(func(ctx context.Context, records []Record) error {
+		data, err := encodeLines(ctx, records)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if _, err := w.Write(data); err != nil {
+			return calque.WrapErr(ctx, err, "failed to write usage records")
+		}
+		return nil
+	})
+}
+
+// FileSink appends records to a JSON Lines file, synced after each batch.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink opens (or creates) a JSON Lines usage file for appending.
+//
+// Example:
+//
+//	sink, err := usage.NewFileSink("/var/log/calque/usage.jsonl")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer sink.Close()
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, calque.WrapErr(context.Background(), err, "failed to open usage file")
+	}
+	return &FileSink{file: file}, nil
+}
+
+// Write appends records as JSON lines
+func (s *FileSink) Write(ctx context.Context, records []Record) error {
+	data, err := encodeLines(ctx, records)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(data); err != nil {
+		return calque.WrapErr(ctx, err, "failed to write usage records")
+	}
+	if err := s.file.Sync(); err != nil {
+		return calque.WrapErr(ctx, err, "failed to sync usage file")
+	}
+	return nil
+}
+
+// Close closes the underlying file
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// HTTPSinkConfig configures NewHTTPSink.
+type HTTPSinkConfig struct {
+	// Client sends the requests. Default: http.DefaultClient
+	Client *http.Client
+	// Headers are added to every request, e.g. Authorization.
+	Headers map[string]string
+}
+
+// NewHTTPSink POSTs each batch to url as JSON Lines
+// (Content-Type: application/x-ndjson). Any 2xx answer accepts the batch;
+// 429 and 5xx answers are retryable.
+//
+// Example:
+//
+//	sink := usage.NewHTTPSink("https://billing.internal/v1/usage", usage.HTTPSinkConfig{
+//		Headers: map[string]string{"Authorization": "Bearer " + token},
+//	})
+func NewHTTPSink(url string, config ...HTTPSinkConfig) Sink {
+	var cfg HTTPSinkConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+
+	return SinkFunc(func(ctx context.Context, records []Record) error {
+		data, err := encodeLines(ctx, records)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+		if err != nil {
+			return calque.InvalidInput(calque.WrapErr(ctx, err, "invalid usage sink URL"))
+		}
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		for k, v := range cfg.Headers {
+			req.Header.Set(k, v)
+		}
+
+		resp, err := cfg.Client.Do(req)
+		if err != nil {
+			return calque.Retryable(calque.WrapErr(ctx, err, "usage sink request failed"))
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			err := calque.NewErr(ctx, fmt.Sprintf("usage sink returned HTTP %d: %s", resp.StatusCode, body))
+			if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+				return calque.Retryable(err)
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// Message is a record encoded for a message broker.
+type Message struct {
+	Key   []byte // the tenant, so one tenant's records stay in order on a partition
+	Value []byte // the record as JSON
+}
+
+// KafkaSink sends each record as a Message through produce, so any Kafka
+// client can be used without this package depending on one.
+//
+// Example with github.com/segmentio/kafka-go:
+//
+//	writer := &kafka.Writer{Addr: kafka.TCP("kafka:9092"), Topic: "calque-usage"}
+//	sink := usage.KafkaSink(func(ctx context.Context, messages []usage.Message) error {
+//		batch := make([]kafka.Message, len(messages))
+//		for i, m := range messages {
+//			batch[i] = kafka.Message{Key: m.Key, Value: m.Value}
+//		}
+//		return writer.WriteMessages(ctx, batch...)
+//	})
+func KafkaSink(produce func(ctx context.Context, messages []Message) error) Sink {
+	return SinkFunc(func(ctx context.Context, records []Record) error {
+		messages := make([]Message, len(records))
+		for i, record := range records {
+			value, err := json.Marshal(record)
+			if err != nil {
+				return calque.WrapErr(ctx, err, "failed to encode usage record")
+			}
+			messages[i] = Message{Key: []byte(record.Tenant), Value: value}
+		}
+		if err := produce(ctx, messages); err != nil {
+			return calque.WrapErr(ctx, err, "failed to produce usage records")
+		}
+		return nil
+	})
+}
+
+// encodeLines encodes records as JSON Lines
+func encodeLines(ctx context.Context, records []Record) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return nil, calque.WrapErr(ctx, err, "failed to encode usage record")
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,235 @@
+// Package usage meters calque flows for billing.
+//
+// Meter wraps a handler so every run produces a Record of the tokens the
+// providers reported, the tools called, the bytes in and out, wall time and
+// the tenant. Records are batched by an Emitter and delivered to a Sink: a
+// JSON Lines file, an HTTP endpoint or a Kafka topic.
+//
+// Records follow the SchemaVersion schema: one JSON object per run with the
+// fields of Record. New fields may be added within a schema version; fields
+// are never renamed or removed.
+//
+// Example:
+//
+//	sink, _ := usage.NewFileSink("/var/log/calque/usage.jsonl")
+//	emitter := usage.NewEmitter(sink)
+//	defer emitter.Shutdown(context.Background())
+//
+//	flow := calque.NewFlow().Use(usage.Meter(emitter, ai.Agent(client), usage.Config{Meter: "summarize"}))
+package usage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// SchemaVersion identifies the Record schema in each record's schema field.
+const SchemaVersion = "calque.usage.v1"
+
+// DefaultMetadataPrefixes are the MetadataBus key prefixes captured in
+// Record.Versions: model versions and prompt registry versions.
+var DefaultMetadataPrefixes = []string{"model.", "prompt."}
+
+// Record is the usage of one metered run.
+//
+// JSON schema (calque.usage.v1):
+//
+//	{
+//	  "schema": "calque.usage.v1",
+//	  "id": "9f2c...",                      // unique per record, for deduplication
+//	  "meter": "summarize",                 // Config.Meter
+//	  "tenant": "acme",                     // calque.TenantID or Config.Tenant
+//	  "subject": "user:42",                 // Config.Subject
+//	  "trace_id": "...", "request_id": "...",
+//	  "start": "2025-01-02T15:04:05.123Z", "end": "2025-01-02T15:04:06.123Z",
+//	  "wall_time_ns": 1000000000,
+//	  "prompt_tokens": 812, "completion_tokens": 120, "total_tokens": 932,
+//	  "cost_usd": 0.0032,                   // omitted when no model was priced
+//	  "tool_calls": 2, "tools": {"search": 2},
+//	  "bytes_in": 2048, "bytes_out": 512,
+//	  "versions": {"model.summarize": "gpt-4o-2024-08-06", "prompt.summarize": "v2"},
+//	  "error": "..."                        // set when the run failed
+//	}
+type Record struct {
+	Schema           string            `json:"schema"`
+	ID               string            `json:"id"`
+	Meter            string            `json:"meter,omitempty"`
+	Tenant           string            `json:"tenant,omitempty"`
+	Subject          string            `json:"subject,omitempty"`
+	TraceID          string            `json:"trace_id,omitempty"`
+	RequestID        string            `json:"request_id,omitempty"`
+	Start            time.Time         `json:"start"`
+	End              time.Time         `json:"end"`
+	WallTime         time.Duration     `json:"wall_time_ns"`
+	PromptTokens     int               `json:"prompt_tokens"`
+	CompletionTokens int               `json:"completion_tokens"`
+	TotalTokens      int               `json:"total_tokens"`
+	CostUSD          float64           `json:"cost_usd,omitempty"`
+	ToolCalls        int               `json:"tool_calls"`
+	Tools            map[string]int    `json:"tools,omitempty"`
+	BytesIn          int64             `json:"bytes_in"`
+	BytesOut         int64             `json:"bytes_out"`
+	Versions         map[string]string `json:"versions,omitempty"`
+	Error            string            `json:"error,omitempty"`
+}
+
+// Config configures Meter.
+type Config struct {
+	// Meter names what is billed (e.g. "summarize"). Optional.
+	Meter string
+	// Tenant resolves the billed tenant. Default: calque.TenantID
+	Tenant func(ctx context.Context) string
+	// Subject resolves the user or API key within the tenant. Optional.
+	Subject func(ctx context.Context) string
+	// MetadataPrefixes selects MetadataBus string values recorded as versions.
+	// Default: DefaultMetadataPrefixes
+	MetadataPrefixes []string
+}
+
+// Meter wraps a handler so every run emits a usage Record.
+//
+// Input: any data type (streaming - counted as it passes through)
+// Output: same as wrapped handler
+// Behavior: STREAMING - bytes are counted without buffering
+//
+// Tokens and tool calls are collected from the *calque.TokenUsage and
+// *calque.ToolCalled artifacts that ai.Agent and tools.Execute attach, also
+// when the caller runs the flow with RunWithResult. Failed runs are recorded
+// too, with Error set, since providers bill them.
+//
+// Example:
+//
+//	metered := usage.Meter(emitter, flow, usage.Config{
+//		Meter: "support-bot",
+//		Subject: func(ctx context.Context) string {
+//			if claims := auth.ClaimsFromContext(ctx); claims != nil {
+//				return claims.Subject
+//			}
+//			return ""
+//		},
+//	})
+func Meter(emitter *Emitter, handler calque.Handler, config ...Config) calque.Handler {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Tenant == nil {
+		cfg.Tenant = calque.TenantID
+	}
+	if cfg.MetadataPrefixes == nil {
+		cfg.MetadataPrefixes = DefaultMetadataPrefixes
+	}
+
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		ctx := req.Context
+
+		// Ensure a bus exists so versions tagged by inner handlers are visible here
+		mb := calque.GetMetadataBus(ctx)
+		if mb == nil {
+			mb = calque.NewMetadataBus(0)
+			defer mb.Close()
+			ctx = calque.WithMetadataBus(ctx, mb)
+		}
+		result := calque.NewResult()
+		ctx = calque.WithResult(ctx, result)
+
+		in := &countingReader{r: req.Data}
+		out := &countingWriter{w: res.Data}
+		start := time.Now()
+		err := handler.ServeFlow(&calque.Request{Context: ctx, Data: in}, &calque.Response{Data: out})
+		end := time.Now()
+
+		record := Record{
+			Schema:    SchemaVersion,
+			ID:        newID(),
+			Meter:     cfg.Meter,
+			Tenant:    cfg.Tenant(ctx),
+			TraceID:   calque.TraceID(ctx),
+			RequestID: calque.RequestID(ctx),
+			Start:     start.UTC(),
+			End:       end.UTC(),
+			WallTime:  end.Sub(start),
+			BytesIn:   in.n.Load(),
+			BytesOut:  out.n.Load(),
+			Versions:  versions(mb, cfg.MetadataPrefixes),
+		}
+		if cfg.Subject != nil {
+			record.Subject = cfg.Subject(ctx)
+		}
+		tokens := result.TokenUsage()
+		record.PromptTokens = tokens.PromptTokens
+		record.CompletionTokens = tokens.CompletionTokens
+		record.TotalTokens = tokens.TotalTokens
+		record.CostUSD = tokens.CostUSD
+		for _, call := range calque.Artifacts[*calque.ToolCalled](result) {
+			if record.Tools == nil {
+				record.Tools = map[string]int{}
+			}
+			record.Tools[call.Tool]++
+			record.ToolCalls++
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+
+		emitter.Emit(record)
+		return err
+	})
+}
+
+// versions collects string MetadataBus values under prefixes
+func versions(mb *calque.MetadataBus, prefixes []string) map[string]string {
+	var found map[string]string
+	mb.Range(func(key string, value any) bool {
+		str, ok := value.(string)
+		if !ok {
+			return true
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				if found == nil {
+					found = map[string]string{}
+				}
+				found[key] = str
+				break
+			}
+		}
+		return true
+	})
+	return found
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b) // crypto/rand.Read never fails
+	return hex.EncodeToString(b)
+}
+
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n atomic.Int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,283 @@
+package usage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ai"
+)
+
+// memorySink collects records, failing while err is set
+type memorySink struct {
+	mu      sync.Mutex
+	records []Record
+	err     error
+}
+
+func (s *memorySink) Write(_ context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *memorySink) get() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Record(nil), s.records...)
+}
+
+// billedModel behaves like ai.Agent with tools: it reads the input, reports
+// tokens and tool calls, tags a model version and answers
+func billedModel(answer string) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var in string
+		if err := calque.Read(req, &in); err != nil {
+			return err
+		}
+		calque.Attach(req.Context, &calque.TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, CostUSD: 0.25})
+		calque.Attach(req.Context, &calque.TokenUsage{PromptTokens: 20, CompletionTokens: 2, TotalTokens: 22})
+		calque.Attach(req.Context, &calque.ToolCalled{Tool: "search"})
+		calque.Attach(req.Context, &calque.ToolCalled{Tool: "search"})
+		calque.Attach(req.Context, &calque.ToolCalled{Tool: "calculator"})
+		if mb := calque.GetMetadataBus(req.Context); mb != nil {
+			mb.Set("model.answer", "gpt-4o-2024-08-06")
+		}
+		return calque.Write(res, answer)
+	})
+}
+
+func TestMeter(t *testing.T) {
+	sink := &memorySink{}
+	emitter := NewEmitter(sink)
+	flow := calque.NewFlow().Use(Meter(emitter, billedModel("four words of answer"), Config{
+		Meter:   "answer",
+		Subject: func(context.Context) string { return "user:42" },
+	}))
+
+	ctx := calque.WithTenant(context.Background(), "acme")
+	var out string
+	result, err := flow.RunWithResult(ctx, "question", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := emitter.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	records := sink.get()
+	if len(records) != 1 {
+		t.Fatalf("records = %d, want 1", len(records))
+	}
+	r := records[0]
+	if r.Schema != SchemaVersion || r.ID == "" || r.Meter != "answer" || r.Tenant != "acme" || r.Subject != "user:42" {
+		t.Errorf("record identity = %+v", r)
+	}
+	if r.PromptTokens != 30 || r.CompletionTokens != 7 || r.TotalTokens != 37 || r.CostUSD != 0.25 {
+		t.Errorf("tokens = %d/%d/%d $%v, want 30/7/37 $0.25", r.PromptTokens, r.CompletionTokens, r.TotalTokens, r.CostUSD)
+	}
+	if r.ToolCalls != 3 || !reflect.DeepEqual(r.Tools, map[string]int{"search": 2, "calculator": 1}) {
+		t.Errorf("tools = %d %v", r.ToolCalls, r.Tools)
+	}
+	if r.BytesIn != int64(len("question")) || r.BytesOut != int64(len(out)) {
+		t.Errorf("bytes = %d/%d", r.BytesIn, r.BytesOut)
+	}
+	if r.WallTime <= 0 || r.End.Before(r.Start) {
+		t.Errorf("timing = %v from %v to %v", r.WallTime, r.Start, r.End)
+	}
+	if r.Versions["model.answer"] != "gpt-4o-2024-08-06" {
+		t.Errorf("versions = %v", r.Versions)
+	}
+
+	if got := result.TokenUsage().TotalTokens; got != 37 {
+		t.Errorf("caller Result tokens = %d, want artifacts to reach the outer Result", got)
+	}
+}
+
+func TestMeterAgent(t *testing.T) {
+	sink := &memorySink{}
+	emitter := NewEmitter(sink)
+	flow := calque.NewFlow().Use(Meter(emitter, ai.Agent(ai.NewMockClient("hello there"))))
+
+	var out string
+	if err := flow.Run(context.Background(), "hi", &out); err != nil {
+		t.Fatal(err)
+	}
+	if err := emitter.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	records := sink.get()
+	if len(records) != 1 || records[0].BytesOut != int64(len("hello there")) || records[0].Error != "" {
+		t.Errorf("records = %+v", records)
+	}
+}
+
+func TestMeterRecordsFailures(t *testing.T) {
+	sink := &memorySink{}
+	emitter := NewEmitter(sink)
+	failing := calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+		_, _ = io.Copy(io.Discard, req.Data)
+		calque.Attach(req.Context, &calque.TokenUsage{TotalTokens: 9})
+		return errors.New("provider timeout")
+	})
+
+	var out string
+	if err := calque.NewFlow().Use(Meter(emitter, failing)).Run(context.Background(), "q", &out); err == nil {
+		t.Fatal("Run() error = nil")
+	}
+	if err := emitter.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	records := sink.get()
+	if len(records) != 1 || records[0].TotalTokens != 9 || !strings.Contains(records[0].Error, "provider timeout") {
+		t.Errorf("records = %+v, want the billed tokens and error", records)
+	}
+}
+
+func TestEmitterRetriesAndDrops(t *testing.T) {
+	sink := &memorySink{err: errors.New("billing down")}
+	var dropped []error
+	emitter := NewEmitter(sink, EmitterConfig{BatchSize: 1000, FlushInterval: time.Hour, MaxPending: 3, OnError: func(err error) {
+		dropped = append(dropped, err)
+	}})
+	defer func() { _ = emitter.Shutdown(context.Background()) }()
+
+	for _, id := range []string{"1", "2"} {
+		emitter.Emit(Record{ID: id})
+	}
+	if err := emitter.Flush(context.Background()); err == nil {
+		t.Fatal("Flush() error = nil with a failing sink")
+	}
+	for _, id := range []string{"3", "4"} {
+		emitter.Emit(Record{ID: id})
+	}
+	if len(dropped) != 1 {
+		t.Errorf("drop reports = %v, want 1", dropped)
+	}
+
+	sink.mu.Lock()
+	sink.err = nil
+	sink.mu.Unlock()
+	if err := emitter.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, r := range sink.get() {
+		ids = append(ids, r.ID)
+	}
+	if want := []string{"2", "3", "4"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("delivered = %v, want the newest %v in order", ids, want)
+	}
+}
+
+func TestEmitterBatchSize(t *testing.T) {
+	sink := &memorySink{}
+	emitter := NewEmitter(sink, EmitterConfig{BatchSize: 2, FlushInterval: time.Hour})
+	defer func() { _ = emitter.Shutdown(context.Background()) }()
+
+	emitter.Emit(Record{ID: "1"})
+	emitter.Emit(Record{ID: "2"})
+	deadline := time.Now().Add(2 * time.Second)
+	for len(sink.get()) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("full batch was not sent")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err := sink.Write(context.Background(), []Record{{Schema: SchemaVersion, ID: "a"}, {Schema: SchemaVersion, ID: "b"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil || r.Schema != SchemaVersion {
+			t.Errorf("line %d = %s, %v", lines, scanner.Text(), err)
+		}
+		lines++
+	}
+	if lines != 4 {
+		t.Errorf("lines = %d, want 4", lines)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var body []byte
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/busy" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	sink := NewHTTPSink(srv.URL+"/usage", HTTPSinkConfig{Headers: map[string]string{"Authorization": "Bearer t"}})
+	if err := sink.Write(context.Background(), []Record{{ID: "a"}, {ID: "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if header.Get("Content-Type") != "application/x-ndjson" || header.Get("Authorization") != "Bearer t" {
+		t.Errorf("headers = %v", header)
+	}
+	if got := bytes.Count(body, []byte("\n")); got != 2 {
+		t.Errorf("body has %d lines, want 2: %s", got, body)
+	}
+
+	if err := NewHTTPSink(srv.URL+"/busy").Write(context.Background(), []Record{{ID: "a"}}); !calque.IsRetryable(err) {
+		t.Errorf("Write() on 429 error = %v, want retryable", err)
+	}
+}
+
+func TestKafkaSink(t *testing.T) {
+	var got []Message
+	sink := KafkaSink(func(_ context.Context, messages []Message) error {
+		got = append(got, messages...)
+		return nil
+	})
+	if err := sink.Write(context.Background(), []Record{{ID: "a", Tenant: "acme"}}); err != nil {
+		t.Fatal(err)
+	}
+	var r Record
+	if len(got) != 1 || string(got[0].Key) != "acme" || json.Unmarshal(got[0].Value, &r) != nil || r.ID != "a" {
+		t.Errorf("messages = %q", got)
+	}
+}
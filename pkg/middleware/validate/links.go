@@ -0,0 +1,377 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/netpolicy"
+)
+
+// Link checker defaults
+const (
+	DefaultLinkTimeout      = 5 * time.Second // per URL, including redirects
+	DefaultLinkConcurrency  = 8               // URLs checked at once per output
+	DefaultLinkCacheTTL     = time.Hour       // how long a result is reused
+	DefaultLinkCacheEntries = 10000           // results kept before evicting
+	DefaultDeadLinkNote     = " (dead link)"  // appended by MarkDeadLinks
+)
+
+// ErrDeadLink matches any *DeadLinkError with errors.Is.
+var ErrDeadLink = errors.New("dead link")
+
+// DeadLinkError is returned by LinkChecker.Validator for outputs with dead links.
+type DeadLinkError struct {
+	URLs []string // dead URLs, in order of appearance
+}
+
+// Error implements error.
+func (e *DeadLinkError) Error() string {
+	return fmt.Sprintf("dead links: %s", strings.Join(e.URLs, ", "))
+}
+
+// Is reports whether target is ErrDeadLink.
+func (e *DeadLinkError) Is(target error) bool {
+	return target == ErrDeadLink
+}
+
+// LinkAction is what LinkChecker.Links does with dead links.
+type LinkAction int
+
+// Link actions
+const (
+	MarkDeadLinks  LinkAction = iota // append LinkConfig.Note after the link
+	StripDeadLinks                   // remove the URL, keeping the text of markdown links
+)
+
+// LinkConfig configures a LinkChecker.
+type LinkConfig struct {
+	Client       *http.Client  // client for the checks (nil = http.DefaultClient), restricted by any netpolicy in the context
+	Timeout      time.Duration // per URL (0 = DefaultLinkTimeout)
+	Concurrency  int           // URLs checked at once (0 = DefaultLinkConcurrency)
+	CacheTTL     time.Duration // result reuse (0 = DefaultLinkCacheTTL, negative = no caching)
+	CacheEntries int           // results kept (0 = DefaultLinkCacheEntries)
+	Note         string        // annotation for MarkDeadLinks (default DefaultDeadLinkNote)
+}
+
+// LinkChecker verifies that URLs in model output resolve, caching results
+// so repeated links are checked once.
+//
+// A link is dead when it answers 404 or 410 or cannot be reached at all
+// (unknown host, refused connection, TLS failure, timeout). Every other
+// status counts as live, so rate limits, auth walls and outages never remove
+// a real link. URLs refused by a netpolicy.Policy are left as they are.
+type LinkChecker struct {
+	cfg LinkConfig
+
+	mu    sync.Mutex
+	cache map[string]linkResult
+}
+
+type linkResult struct {
+	dead    bool
+	checked time.Time
+}
+
+// NewLinkChecker creates a LinkChecker.
+//
+// Example:
+//
+//	links := validate.NewLinkChecker(validate.LinkConfig{Timeout: 3 * time.Second})
+//	flow.Use(ai.Agent(client)).
+//		Use(links.Links(validate.StripDeadLinks))
+func NewLinkChecker(config ...LinkConfig) *LinkChecker {
+	cfg := LinkConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultLinkTimeout
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = DefaultLinkConcurrency
+	}
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = DefaultLinkCacheTTL
+	}
+	if cfg.CacheEntries <= 0 {
+		cfg.CacheEntries = DefaultLinkCacheEntries
+	}
+	if cfg.Note == "" {
+		cfg.Note = DefaultDeadLinkNote
+	}
+	return &LinkChecker{cfg: cfg, cache: make(map[string]linkResult)}
+}
+
+// Links creates a post-processor for dead links in the output of the previous stage.
+//
+// Input: text or markdown output (buffered)
+// Output: the input with dead links marked or stripped
+// Behavior: BUFFERED - links are checked concurrently once the output is read
+//
+// Bare URLs, <autolinks> and markdown [text](url) links are recognised.
+// StripDeadLinks replaces a dead markdown link with its text and removes dead
+// bare URLs; MarkDeadLinks keeps them and appends the note.
+//
+// Example:
+//
+//	flow.Use(ai.Agent(client)).
+//		Use(links.Links(validate.MarkDeadLinks))
+func (c *LinkChecker) Links(action LinkAction) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var output string
+		if err := calque.Read(req, &output); err != nil {
+			return err
+		}
+
+		spans := findLinks(output)
+		dead, err := c.deadLinks(req.Context, spans)
+		if err != nil {
+			return err
+		}
+		if len(dead) == 0 {
+			return calque.Write(res, output)
+		}
+
+		var b strings.Builder
+		last := 0
+		for _, s := range spans {
+			if !dead[s.url] {
+				continue
+			}
+			b.WriteString(output[last:s.start])
+			if action == StripDeadLinks {
+				b.WriteString(s.text)
+			} else {
+				b.WriteString(output[s.start:s.end])
+				b.WriteString(c.cfg.Note)
+			}
+			last = s.end
+		}
+		b.WriteString(output[last:])
+		return calque.Write(res, b.String())
+	})
+}
+
+// Validator rejects outputs containing dead links with a *DeadLinkError,
+// for With to route them to a correction.
+//
+// Example:
+//
+//	fixLinks := calque.NewFlow().
+//		Use(validate.FailurePrompt("Remove or replace these broken links: {{.Error}}\n\n{{.Output}}")).
+//		Use(ai.Agent(client))
+//	flow.Use(validate.With(links.Validator(), fixLinks))
+func (c *LinkChecker) Validator() Validator {
+	return func(ctx context.Context, output []byte) error {
+		spans := findLinks(string(output))
+		dead, err := c.deadLinks(ctx, spans)
+		if err != nil || len(dead) == 0 {
+			return err
+		}
+		var urls []string
+		for _, s := range spans {
+			if dead[s.url] {
+				urls = append(urls, s.url)
+				delete(dead, s.url) // report each URL once
+			}
+		}
+		return &DeadLinkError{URLs: urls}
+	}
+}
+
+// Dead reports whether rawURL is dead, using the cache when possible.
+func (c *LinkChecker) Dead(ctx context.Context, rawURL string) (bool, error) {
+	if dead, ok := c.cached(rawURL); ok {
+		return dead, nil
+	}
+	if netpolicy.CheckURL(ctx, rawURL) != nil {
+		return false, nil
+	}
+
+	dead, err := c.check(ctx, rawURL)
+	if err != nil {
+		return false, err
+	}
+	c.store(rawURL, dead)
+	return dead, nil
+}
+
+// deadLinks checks the distinct URLs of spans concurrently
+func (c *LinkChecker) deadLinks(ctx context.Context, spans []linkSpan) (map[string]bool, error) {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		dead     = make(map[string]bool)
+		seen     = make(map[string]bool)
+		slots    = make(chan struct{}, c.cfg.Concurrency)
+	)
+	for _, s := range spans {
+		if seen[s.url] {
+			continue
+		}
+		seen[s.url] = true
+
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(url string) {
+			defer func() { <-slots; wg.Done() }()
+			isDead, err := c.Dead(ctx, url)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			if isDead {
+				dead[url] = true
+			}
+		}(s.url)
+	}
+	wg.Wait()
+	return dead, firstErr
+}
+
+// check requests rawURL with HEAD, falling back to a one-byte GET for
+// servers that do not support HEAD. Only cancellation of ctx is an error.
+func (c *LinkChecker) check(ctx context.Context, rawURL string) (bool, error) {
+	client := netpolicy.HTTPClient(ctx, c.cfg.Client)
+
+	status, err := c.request(ctx, client, http.MethodHead, rawURL)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = c.request(ctx, client, http.MethodGet, rawURL)
+	}
+	switch {
+	case ctx.Err() != nil:
+		return false, calque.WrapErr(ctx, ctx.Err(), "link check cancelled")
+	case errors.Is(err, netpolicy.ErrDenied):
+		return false, nil
+	case err != nil:
+		return true, nil
+	}
+	return status == http.StatusNotFound || status == http.StatusGone, nil
+}
+
+func (c *LinkChecker) request(ctx context.Context, client *http.Client, method, rawURL string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<10))
+	_ = resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func (c *LinkChecker) cached(rawURL string) (bool, bool) {
+	if c.cfg.CacheTTL < 0 {
+		return false, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.cache[rawURL]
+	if !ok || time.Since(r.checked) > c.cfg.CacheTTL {
+		return false, false
+	}
+	return r.dead, true
+}
+
+func (c *LinkChecker) store(rawURL string, dead bool) {
+	if c.cfg.CacheTTL < 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.cache) >= c.cfg.CacheEntries {
+		for url, r := range c.cache {
+			if time.Since(r.checked) > c.cfg.CacheTTL {
+				delete(c.cache, url)
+			}
+		}
+		for url := range c.cache {
+			if len(c.cache) < c.cfg.CacheEntries {
+				break
+			}
+			delete(c.cache, url)
+		}
+	}
+	c.cache[rawURL] = linkResult{dead: dead, checked: time.Now()}
+}
+
+// linkSpan is a link in the output; text replaces it when stripped
+type linkSpan struct {
+	start, end int
+	url        string
+	text       string
+}
+
+var (
+	markdownLink = regexp.MustCompile(`\[([^\]\n]*)\]\((https?://[^\s()]+(?:\([^\s()]*\)[^\s()]*)*)(?:\s+"[^"]*")?\)`)
+	autoLink     = regexp.MustCompile(`<(https?://[^\s<>]+)>`)
+	bareURL      = regexp.MustCompile(`https?://[^\s<>"'\]\[]+`)
+)
+
+// findLinks returns the links in s, ordered and not overlapping
+func findLinks(s string) []linkSpan {
+	var spans []linkSpan
+	covered := func(start, end int) bool {
+		for _, sp := range spans {
+			if start < sp.end && end > sp.start {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, m := range markdownLink.FindAllStringSubmatchIndex(s, -1) {
+		spans = append(spans, linkSpan{start: m[0], end: m[1], url: s[m[4]:m[5]], text: s[m[2]:m[3]]})
+	}
+	for _, m := range autoLink.FindAllStringSubmatchIndex(s, -1) {
+		if !covered(m[0], m[1]) {
+			spans = append(spans, linkSpan{start: m[0], end: m[1], url: s[m[2]:m[3]]})
+		}
+	}
+	for _, m := range bareURL.FindAllStringIndex(s, -1) {
+		url := trimURL(s[m[0]:m[1]])
+		end := m[0] + len(url)
+		if !covered(m[0], end) {
+			spans = append(spans, linkSpan{start: m[0], end: end, url: url})
+		}
+	}
+
+	// spans were added per kind; order them for rebuilding
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	return spans
+}
+
+// trimURL drops sentence punctuation and unbalanced closing parentheses
+// after a bare URL
+func trimURL(url string) string {
+	for {
+		trimmed := strings.TrimRight(url, ".,;:!?*_'")
+		if strings.HasSuffix(trimmed, ")") && strings.Count(trimmed, "(") < strings.Count(trimmed, ")") {
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+		if trimmed == url {
+			return url
+		}
+		url = trimmed
+	}
+}
@@ -0,0 +1,149 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/netpolicy"
+)
+
+func linkServer(t *testing.T, hits *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		case "/throttled":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/nohead":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			_, _ = w.Write([]byte("body"))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func runLinks(t *testing.T, ctx context.Context, h calque.Handler, input string) string {
+	t.Helper()
+	var out strings.Builder
+	if err := h.ServeFlow(calque.NewRequest(ctx, strings.NewReader(input)), calque.NewResponse(&out)); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestFindLinks(t *testing.T) {
+	input := "See [the docs](https://example.com/a_(b)) and <https://example.com/auto>, " +
+		"or https://example.com/bare. Also (https://example.com/paren)."
+	var urls []string
+	for _, s := range findLinks(input) {
+		urls = append(urls, s.url)
+	}
+	want := "https://example.com/a_(b),https://example.com/auto,https://example.com/bare,https://example.com/paren"
+	if got := strings.Join(urls, ","); got != want {
+		t.Errorf("urls = %s\nwant %s", got, want)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	var hits atomic.Int32
+	server := linkServer(t, &hits)
+	input := "Read [the guide](" + server.URL + "/missing) or " + server.URL + "/gone, not " + server.URL + "/ok. " +
+		"Also " + server.URL + "/nohead and " + server.URL + "/throttled and http://127.0.0.1:1/refused."
+
+	t.Run("mark", func(t *testing.T) {
+		checker := NewLinkChecker(LinkConfig{Client: server.Client()})
+		got := runLinks(t, context.Background(), checker.Links(MarkDeadLinks), input)
+		want := "Read [the guide](" + server.URL + "/missing) (dead link) or " + server.URL + "/gone (dead link), not " + server.URL + "/ok. " +
+			"Also " + server.URL + "/nohead and " + server.URL + "/throttled and http://127.0.0.1:1/refused (dead link)."
+		if got != want {
+			t.Errorf("output = %q\nwant     %q", got, want)
+		}
+	})
+
+	t.Run("strip", func(t *testing.T) {
+		checker := NewLinkChecker(LinkConfig{Client: server.Client()})
+		got := runLinks(t, context.Background(), checker.Links(StripDeadLinks), input)
+		want := "Read the guide or , not " + server.URL + "/ok. " +
+			"Also " + server.URL + "/nohead and " + server.URL + "/throttled and ."
+		if got != want {
+			t.Errorf("output = %q\nwant     %q", got, want)
+		}
+	})
+
+	t.Run("no links", func(t *testing.T) {
+		checker := NewLinkChecker()
+		if got := runLinks(t, context.Background(), checker.Links(StripDeadLinks), "plain answer"); got != "plain answer" {
+			t.Errorf("output = %q", got)
+		}
+	})
+}
+
+func TestLinkCheckerCache(t *testing.T) {
+	var hits atomic.Int32
+	server := linkServer(t, &hits)
+	checker := NewLinkChecker(LinkConfig{Client: server.Client()})
+	input := server.URL + "/ok " + server.URL + "/ok"
+
+	runLinks(t, context.Background(), checker.Links(MarkDeadLinks), input)
+	runLinks(t, context.Background(), checker.Links(MarkDeadLinks), input)
+	if n := hits.Load(); n != 1 {
+		t.Errorf("server hits = %d, want 1", n)
+	}
+
+	uncached := NewLinkChecker(LinkConfig{Client: server.Client(), CacheTTL: -1})
+	hits.Store(0)
+	runLinks(t, context.Background(), uncached.Links(MarkDeadLinks), input)
+	runLinks(t, context.Background(), uncached.Links(MarkDeadLinks), input)
+	if n := hits.Load(); n != 2 {
+		t.Errorf("uncached server hits = %d, want 2", n)
+	}
+}
+
+func TestLinkCheckerValidator(t *testing.T) {
+	var hits atomic.Int32
+	server := linkServer(t, &hits)
+	checker := NewLinkChecker(LinkConfig{Client: server.Client()})
+	validator := checker.Validator()
+
+	err := validator(context.Background(), []byte(server.URL+"/missing and "+server.URL+"/missing and "+server.URL+"/ok"))
+	var deadErr *DeadLinkError
+	if !errors.Is(err, ErrDeadLink) || !errors.As(err, &deadErr) {
+		t.Fatalf("error = %v, want *DeadLinkError", err)
+	}
+	if len(deadErr.URLs) != 1 || deadErr.URLs[0] != server.URL+"/missing" {
+		t.Errorf("URLs = %v", deadErr.URLs)
+	}
+	if err := validator(context.Background(), []byte("see "+server.URL+"/ok")); err != nil {
+		t.Errorf("live links error = %v", err)
+	}
+}
+
+func TestLinkCheckerPolicy(t *testing.T) {
+	var hits atomic.Int32
+	server := linkServer(t, &hits)
+	checker := NewLinkChecker(LinkConfig{Client: server.Client()})
+
+	// the test server is on loopback, which the default policy refuses
+	ctx := netpolicy.WithPolicy(context.Background(), netpolicy.Policy{})
+	input := "see " + server.URL + "/missing"
+	if got := runLinks(t, ctx, checker.Links(StripDeadLinks), input); got != input {
+		t.Errorf("output = %q, want refused link left unchanged", got)
+	}
+	if n := hits.Load(); n != 0 {
+		t.Errorf("server hits = %d, want 0", n)
+	}
+}
@@ -0,0 +1,388 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultNumericTolerance is the relative difference allowed between a claim
+// and a source value, on top of the rounding implied by the claim's precision.
+const DefaultNumericTolerance = 0.005
+
+// MetadataNumericFacts is the MetadataBus key holding the sources and ranges
+// added with AddNumericSources and AddNumericRanges.
+const MetadataNumericFacts = "validate.numeric_facts"
+
+// ErrNumericMismatch matches any *NumericError with errors.Is.
+var ErrNumericMismatch = errors.New("numeric mismatch")
+
+// NumericClaim is a number found in text, converted to its base unit.
+type NumericClaim struct {
+	Text   string  // as written, e.g. "$4.2 billion"
+	Value  float64 // value in Unit, e.g. 4.2e9
+	Unit   string  // base unit: "%", "USD", "EUR", "GBP", "JPY", "s", "B" (bytes), "m", "kg", or "" for plain numbers
+	Offset int     // byte offset in the text
+
+	precision float64 // rounding implied by the digits written, in Unit
+}
+
+// NumericRange bounds the claims about one quantity.
+type NumericRange struct {
+	Label string  // word that must appear in the claim's sentence, e.g. "margin" (empty = any claim)
+	Unit  string  // unit the bounds are in, e.g. "%", "ms" or "USD" (empty = plain numbers)
+	Min   float64 // smallest allowed value, inclusive
+	Max   float64 // largest allowed value, inclusive
+}
+
+// NumericMismatch is a claim that failed a check.
+type NumericMismatch struct {
+	Claim  NumericClaim
+	Reason string // e.g. "not found in sources" or "outside margin range 0-60 %"
+}
+
+// NumericError is returned by Numbers for outputs with mismatched claims.
+type NumericError struct {
+	Mismatches []NumericMismatch
+}
+
+// Error implements error.
+func (e *NumericError) Error() string {
+	parts := make([]string, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		parts[i] = fmt.Sprintf("%q %s", m.Claim.Text, m.Reason)
+	}
+	return "numeric mismatch: " + strings.Join(parts, "; ")
+}
+
+// Is reports whether target is ErrNumericMismatch.
+func (e *NumericError) Is(target error) bool {
+	return target == ErrNumericMismatch
+}
+
+// NumericConfig configures Numbers.
+type NumericConfig struct {
+	Sources   []string       // source texts every claim must appear in, e.g. a filing or retrieved documents
+	Ranges    []NumericRange // plausible ranges for claims
+	Tolerance float64        // relative difference allowed against sources (0 = DefaultNumericTolerance)
+}
+
+// Numbers checks numeric claims in the output against source data and ranges.
+//
+// Sources and ranges come from the config and from the MetadataBus, where
+// earlier stages such as retrieval or tool calls add them with
+// AddNumericSources and AddNumericRanges. When sources are present, each
+// claim must match a number in them after unit conversion ("$4.2 billion"
+// matches "4,213 million USD"), within the rounding the claim's digits imply
+// plus Tolerance. Each claim must also fall within every range whose label
+// and unit it matches. Failures are a *NumericError listing the mismatches.
+//
+// Integers below 10 and years (1900-2100) without a unit are not checked, so
+// list numbering and dates do not trip the check. With no sources and no
+// ranges, every output is valid.
+//
+// Example:
+//
+//	checks := validate.Numbers(validate.NumericConfig{
+//		Sources: []string{quarterlyReport},
+//		Ranges:  []validate.NumericRange{{Label: "margin", Unit: "%", Min: -50, Max: 80}},
+//	})
+//	flow.Use(ai.Agent(client)).
+//		Use(validate.With(checks, fixNumbers))
+func Numbers(config ...NumericConfig) Validator {
+	cfg := NumericConfig{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Tolerance <= 0 {
+		cfg.Tolerance = DefaultNumericTolerance
+	}
+
+	return func(ctx context.Context, output []byte) error {
+		sources, ranges := cfg.Sources, cfg.Ranges
+		if facts := numericFactsFor(ctx, false); facts != nil {
+			facts.mu.Lock()
+			sources = append(sources[:len(sources):len(sources)], facts.sources...)
+			ranges = append(ranges[:len(ranges):len(ranges)], facts.ranges...)
+			facts.mu.Unlock()
+		}
+		if len(sources) == 0 && len(ranges) == 0 {
+			return nil
+		}
+
+		var known []NumericClaim
+		for _, source := range sources {
+			known = append(known, ExtractNumbers(source)...)
+		}
+
+		text := string(output)
+		var mismatches []NumericMismatch
+		for _, claim := range ExtractNumbers(text) {
+			if exempt(claim) {
+				continue
+			}
+			for _, r := range ranges {
+				if reason, ok := checkRange(text, claim, r); !ok {
+					mismatches = append(mismatches, NumericMismatch{Claim: claim, Reason: reason})
+				}
+			}
+			if len(sources) > 0 && !inSources(claim, known, cfg.Tolerance) {
+				mismatches = append(mismatches, NumericMismatch{Claim: claim, Reason: "not found in sources"})
+			}
+		}
+		if len(mismatches) > 0 {
+			return &NumericError{Mismatches: mismatches}
+		}
+		return nil
+	}
+}
+
+// numericFacts are the sources and ranges added on a MetadataBus
+type numericFacts struct {
+	mu      sync.Mutex
+	sources []string
+	ranges  []NumericRange
+}
+
+// numericFactsMu serializes creating the facts of a bus
+var numericFactsMu sync.Mutex
+
+func numericFactsFor(ctx context.Context, create bool) *numericFacts {
+	mb := calque.GetMetadataBus(ctx)
+	if mb == nil {
+		return nil
+	}
+	numericFactsMu.Lock()
+	defer numericFactsMu.Unlock()
+	if v, ok := mb.Get(MetadataNumericFacts); ok {
+		facts, _ := v.(*numericFacts)
+		return facts
+	}
+	if !create {
+		return nil
+	}
+	facts := &numericFacts{}
+	mb.Set(MetadataNumericFacts, facts)
+	return facts
+}
+
+// AddNumericSources adds source texts for Numbers to check claims against,
+// on the MetadataBus in ctx. It does nothing without a MetadataBus.
+//
+// Example:
+//
+//	// in a tool returning account data
+//	validate.AddNumericSources(ctx, balancesJSON)
+func AddNumericSources(ctx context.Context, texts ...string) {
+	if facts := numericFactsFor(ctx, true); facts != nil {
+		facts.mu.Lock()
+		facts.sources = append(facts.sources, texts...)
+		facts.mu.Unlock()
+	}
+}
+
+// AddNumericRanges adds ranges for Numbers to enforce, on the MetadataBus in
+// ctx. It does nothing without a MetadataBus.
+func AddNumericRanges(ctx context.Context, ranges ...NumericRange) {
+	if facts := numericFactsFor(ctx, true); facts != nil {
+		facts.mu.Lock()
+		facts.ranges = append(facts.ranges, ranges...)
+		facts.mu.Unlock()
+	}
+}
+
+var numberPattern = regexp.MustCompile(`(?i)([$€£¥]\s?)?(-?\d{1,3}(?:,\d{3})+(?:\.\d+)?|-?\d+(?:\.\d+)?)` +
+	`(?:\s?(thousand|million|billion|trillion|bn|mn|[kmb])\b)?` +
+	`(?:\s?(%|(?:percent|per cent|usd|eur|gbp|jpy|ms|sec|seconds?|s|min|minutes?|hours?|hrs?|h|days?|bytes?|kb|mb|gb|tb|mm|cm|km|m|mg|kg|g|lbs?)\b))?`)
+
+var (
+	currencies = map[string]string{"$": "USD", "€": "EUR", "£": "GBP", "¥": "JPY"}
+	scales     = map[string]float64{"thousand": 1e3, "k": 1e3, "million": 1e6, "mn": 1e6, "m": 1e6, "billion": 1e9, "bn": 1e9, "b": 1e9, "trillion": 1e12}
+	units      = map[string]struct {
+		base   string
+		factor float64
+	}{
+		"%": {"%", 1}, "percent": {"%", 1}, "per cent": {"%", 1},
+		"usd": {"USD", 1}, "eur": {"EUR", 1}, "gbp": {"GBP", 1}, "jpy": {"JPY", 1},
+		"ms": {"s", 1e-3}, "s": {"s", 1}, "sec": {"s", 1}, "second": {"s", 1}, "seconds": {"s", 1},
+		"min": {"s", 60}, "minute": {"s", 60}, "minutes": {"s", 60},
+		"h": {"s", 3600}, "hr": {"s", 3600}, "hrs": {"s", 3600}, "hour": {"s", 3600}, "hours": {"s", 3600},
+		"day": {"s", 86400}, "days": {"s", 86400},
+		"byte": {"B", 1}, "bytes": {"B", 1}, "kb": {"B", 1e3}, "mb": {"B", 1e6}, "gb": {"B", 1e9}, "tb": {"B", 1e12},
+		"mm": {"m", 1e-3}, "cm": {"m", 1e-2}, "m": {"m", 1}, "km": {"m", 1e3},
+		"mg": {"kg", 1e-6}, "g": {"kg", 1e-3}, "kg": {"kg", 1}, "lb": {"kg", 0.45359237}, "lbs": {"kg", 0.45359237},
+	}
+)
+
+// ExtractNumbers returns the numeric claims in s, with currencies, scale
+// words ("million", "bn", "k") and units converted to base units.
+//
+// Numbers inside identifiers, versions and dates ("v2", "3.2.1",
+// "2024-05-01") are skipped, as are citation markers like "[3]".
+func ExtractNumbers(s string) []NumericClaim {
+	var claims []NumericClaim
+	for _, m := range numberPattern.FindAllStringSubmatchIndex(s, -1) {
+		start, end := m[0], m[1]
+		numStart, numEnd := m[4], m[5]
+		if partOfToken(s, numStart, numEnd) {
+			continue
+		}
+
+		digits := s[numStart:numEnd]
+		value, err := strconv.ParseFloat(strings.ReplaceAll(digits, ",", ""), 64)
+		if err != nil {
+			continue
+		}
+		precision := 0.5
+		if dot := strings.IndexByte(digits, '.'); dot >= 0 {
+			precision = 0.5 * math.Pow(10, -float64(len(digits)-dot-1))
+		}
+
+		var currency, scale, unit string
+		if m[2] >= 0 {
+			currency = strings.TrimSpace(s[m[2]:m[3]])
+		}
+		if m[6] >= 0 {
+			scale = strings.ToLower(s[m[6]:m[7]])
+		}
+		if m[8] >= 0 {
+			unit = strings.ToLower(s[m[8]:m[9]])
+		}
+		// a lone "m" after a plain number is meters, not million
+		if scale == "m" && currency == "" && unit == "" {
+			scale, unit = "", "m"
+		}
+
+		claim := NumericClaim{Text: s[start:end], Offset: start}
+		factor := 1.0
+		if scale != "" {
+			factor = scales[scale]
+		}
+		if currency != "" {
+			claim.Unit = currencies[currency]
+		}
+		if u, ok := units[unit]; ok {
+			// an ISO code repeats the currency symbol rather than converting it
+			if claim.Unit == "" || u.base == claim.Unit {
+				claim.Unit = u.base
+				factor *= u.factor
+			}
+		}
+		claim.Value = value * factor
+		claim.precision = precision * factor
+		claims = append(claims, claim)
+	}
+	return claims
+}
+
+// partOfToken reports numbers glued to letters or other numbers, e.g. in
+// identifiers, versions, dates and citation markers
+func partOfToken(s string, start, end int) bool {
+	if start > 0 {
+		prev, _ := utf8.DecodeLastRuneInString(s[:start])
+		if unicode.IsLetter(prev) || unicode.IsDigit(prev) || strings.ContainsRune(".-_/[#", prev) {
+			return true
+		}
+	}
+	if end < len(s) {
+		next, _ := utf8.DecodeRuneInString(s[end:])
+		if next == ']' || next == '-' || next == '/' || next == '_' {
+			return true
+		}
+		if next == '.' && end+1 < len(s) && s[end+1] >= '0' && s[end+1] <= '9' {
+			return true
+		}
+	}
+	return false
+}
+
+// exempt reports plain small integers, years and decades ("1990s")
+func exempt(c NumericClaim) bool {
+	text := c.Text
+	if c.Unit == "s" {
+		text = strings.TrimSuffix(text, "s")
+	}
+	if isYear(text) {
+		return true
+	}
+	return c.Unit == "" && !strings.ContainsAny(c.Text, ".,") && math.Abs(c.Value) < 10
+}
+
+func isYear(text string) bool {
+	if len(text) != 4 {
+		return false
+	}
+	year, err := strconv.Atoi(text)
+	return err == nil && year >= 1900 && year <= 2100
+}
+
+// checkRange applies r to claim when its label and unit match
+func checkRange(text string, claim NumericClaim, r NumericRange) (string, bool) {
+	base, factor := "", 1.0
+	if r.Unit != "" {
+		u, ok := units[strings.ToLower(r.Unit)]
+		if !ok {
+			// currency symbols and unknown units are compared as written
+			u.base, u.factor = r.Unit, 1
+			if code, ok := currencies[r.Unit]; ok {
+				u.base = code
+			}
+		}
+		base, factor = u.base, u.factor
+	}
+	if claim.Unit != base {
+		return "", true
+	}
+	if r.Label != "" && !strings.Contains(strings.ToLower(sentenceAround(text, claim.Offset)), strings.ToLower(r.Label)) {
+		return "", true
+	}
+	if claim.Value >= r.Min*factor && claim.Value <= r.Max*factor {
+		return "", true
+	}
+	label := r.Label
+	if label == "" {
+		label = "allowed"
+	}
+	return strings.TrimSpace(fmt.Sprintf("outside %s range %g-%g %s", label, r.Min, r.Max, r.Unit)), false
+}
+
+// sentenceAround returns the sentence of text containing offset
+func sentenceAround(text string, offset int) string {
+	start := 0
+	for _, sep := range []string{". ", "! ", "? ", "\n"} {
+		if i := strings.LastIndex(text[:offset], sep); i >= 0 && i+len(sep) > start {
+			start = i + len(sep)
+		}
+	}
+	end := len(text)
+	for _, sep := range []string{". ", "! ", "? ", "\n"} {
+		if i := strings.Index(text[offset:], sep); i >= 0 && offset+i < end {
+			end = offset + i
+		}
+	}
+	return text[start:end]
+}
+
+// inSources reports whether a source number matches claim; plain source
+// numbers match claims in any unit, as tables often state units in headers
+func inSources(claim NumericClaim, known []NumericClaim, tolerance float64) bool {
+	for _, k := range known {
+		if k.Unit != "" && claim.Unit != "" && k.Unit != claim.Unit {
+			continue
+		}
+		allowed := claim.precision + tolerance*math.Max(math.Abs(claim.Value), math.Abs(k.Value))
+		if math.Abs(claim.Value-k.Value) <= allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestExtractNumbers(t *testing.T) {
+	tests := []struct {
+		input string
+		value float64
+		unit  string
+	}{
+		{input: "revenue of $4.2 billion", value: 4.2e9, unit: "USD"},
+		{input: "€3bn in bonds", value: 3e9, unit: "EUR"},
+		{input: "a 12.5% margin", value: 12.5, unit: "%"},
+		{input: "40 percent", value: 40, unit: "%"},
+		{input: "p99 of 250 ms", value: 0.25, unit: "s"},
+		{input: "took 3 minutes", value: 180, unit: "s"},
+		{input: "a 2.5 GB file", value: 2.5e9, unit: "B"},
+		{input: "ran 10 km", value: 1e4, unit: "m"},
+		{input: "4,213 million USD", value: 4.213e9, unit: "USD"},
+		{input: "12k users", value: 12000, unit: ""},
+		{input: "1,234,567 rows", value: 1234567, unit: ""},
+		{input: "a loss of -3.5%", value: -3.5, unit: "%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			claims := ExtractNumbers(tt.input)
+			if len(claims) != 1 {
+				t.Fatalf("claims = %+v, want 1", claims)
+			}
+			if math.Abs(claims[0].Value-tt.value) > 1e-9*math.Abs(tt.value) || claims[0].Unit != tt.unit {
+				t.Errorf("claim = %v %q, want %v %q", claims[0].Value, claims[0].Unit, tt.value, tt.unit)
+			}
+		})
+	}
+}
+
+func TestExtractNumbersSkipsTokens(t *testing.T) {
+	for _, input := range []string{"gpt4o", "v2", "version 3.2.1", "as cited [12]", "issue #42", "ISO_8601"} {
+		if claims := ExtractNumbers(input); len(claims) != 0 {
+			t.Errorf("ExtractNumbers(%q) = %+v, want none", input, claims)
+		}
+	}
+	// only the year survives in a date, and years are exempt
+	for _, c := range ExtractNumbers("on 2024-05-01") {
+		if !exempt(c) {
+			t.Errorf("date part %q is checked", c.Text)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	source := "Q3 revenue was 4,213 million USD, up 12.4% year over year. Operating margin: 31%. Latency p99 250 ms."
+
+	tests := []struct {
+		name    string
+		config  NumericConfig
+		output  string
+		invalid []string
+	}{
+		{name: "matches after conversion", config: NumericConfig{Sources: []string{source}}, output: "Revenue reached $4.2 billion, growing 12%."},
+		{name: "units converted", config: NumericConfig{Sources: []string{source}}, output: "p99 latency was 0.25 s."},
+		{name: "invented figure", config: NumericConfig{Sources: []string{source}}, output: "Revenue reached $5.1 billion.", invalid: []string{"$5.1 billion"}},
+		{name: "wrong unit", config: NumericConfig{Sources: []string{source}}, output: "Margin was 31 ms.", invalid: []string{"31 ms"}},
+		{name: "precision counts", config: NumericConfig{Sources: []string{source}}, output: "Growth was 12.9%.", invalid: []string{"12.9%"}},
+		{name: "small integers and years exempt", config: NumericConfig{Sources: []string{source}}, output: "1. In 2024 there were 3 launches in the 1990s style."},
+		{name: "range", config: NumericConfig{Ranges: []NumericRange{{Label: "margin", Unit: "%", Min: 0, Max: 60}}}, output: "Margin hit 95%. Growth hit 95%.", invalid: []string{"95%"}},
+		{name: "range units converted", config: NumericConfig{Ranges: []NumericRange{{Unit: "ms", Min: 0, Max: 500}}}, output: "Responses took 0.3 s."},
+		{name: "no facts", output: "Revenue was $9 trillion."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Numbers(tt.config)(context.Background(), []byte(tt.output))
+			if len(tt.invalid) == 0 {
+				if err != nil {
+					t.Errorf("error = %v, want valid", err)
+				}
+				return
+			}
+			var numErr *NumericError
+			if !errors.Is(err, ErrNumericMismatch) || !errors.As(err, &numErr) {
+				t.Fatalf("error = %v, want *NumericError", err)
+			}
+			var got []string
+			for _, m := range numErr.Mismatches {
+				got = append(got, m.Claim.Text)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.invalid, ",") {
+				t.Errorf("mismatches = %+v, want %v", numErr.Mismatches, tt.invalid)
+			}
+		})
+	}
+}
+
+func TestNumbersRangeUnits(t *testing.T) {
+	err := Numbers(NumericConfig{Ranges: []NumericRange{{Unit: "ms", Min: 0, Max: 500}}})(context.Background(), []byte("Responses took 2 s."))
+	if !errors.Is(err, ErrNumericMismatch) {
+		t.Errorf("error = %v, want 2 s outside 0-500 ms", err)
+	}
+}
+
+func TestNumbersMetadata(t *testing.T) {
+	ctx := calque.WithMetadataBus(context.Background(), calque.NewMetadataBus(0))
+	AddNumericSources(ctx, "Balance: $1,250.00")
+	AddNumericRanges(ctx, NumericRange{Label: "interest", Unit: "%", Min: 0, Max: 25})
+
+	validator := Numbers()
+	if err := validator(ctx, []byte("Your balance is $1,250.")); err != nil {
+		t.Errorf("error = %v, want valid", err)
+	}
+	if err := validator(ctx, []byte("Your balance is $1,520.")); !errors.Is(err, ErrNumericMismatch) {
+		t.Errorf("error = %v, want mismatch", err)
+	}
+
+	var numErr *NumericError
+	err := validator(ctx, []byte("The interest rate is 1,250%."))
+	if !errors.As(err, &numErr) || !strings.Contains(numErr.Mismatches[0].Reason, "interest range") {
+		t.Errorf("error = %v, want interest range mismatch", err)
+	}
+
+	// without a bus, adding is a no-op
+	AddNumericSources(context.Background(), "ignored")
+}
@@ -0,0 +1,234 @@
+// Package validate checks handler outputs and routes invalid ones to
+// correction handlers instead of failing the run.
+//
+// With buffers the output of the preceding stage and runs a Validator on it.
+// Valid outputs pass through unchanged; invalid ones are sent to an onFail
+// handler, typically a correction sub-flow asking a model to fix its answer,
+// whose output is validated again. Route dispatches failures by error type,
+// so bad JSON and broken business rules get different corrections.
+// LinkChecker catches hallucinated links, marking or stripping dead URLs or
+// rejecting outputs that contain them, and Numbers checks numeric claims
+// against source data and plausible ranges.
+//
+// Example:
+//
+//	fixJSON := calque.NewFlow().
+//		Use(validate.FailurePrompt("Fix this JSON. Error: {{.Error}}\n\n{{.Output}}")).
+//		Use(ai.Agent(client))
+//
+//	flow := calque.NewFlow().
+//		Use(ai.Agent(client)).
+//		Use(validate.With(validate.JSON(), fixJSON))
+package validate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultMaxAttempts is how many times With runs onFail before giving up.
+const DefaultMaxAttempts = 1
+
+// ErrInvalidOutput matches every *Failure with errors.Is.
+var ErrInvalidOutput = errors.New("invalid output")
+
+// Validator checks a complete output, returning an error describing why it is invalid.
+type Validator func(ctx context.Context, output []byte) error
+
+// Failure is an output that failed validation.
+//
+// It is returned when no correction succeeds and is available to onFail
+// handlers through FailureFrom. errors.Is matches ErrInvalidOutput and,
+// through Unwrap, the validator's error.
+type Failure struct {
+	Output  []byte // the invalid output
+	Err     error  // why it is invalid
+	Attempt int    // corrections already tried for this output (0 = the original)
+}
+
+// Error implements error.
+func (f *Failure) Error() string {
+	return fmt.Sprintf("invalid output: %v", f.Err)
+}
+
+// Unwrap returns the validator error.
+func (f *Failure) Unwrap() error { return f.Err }
+
+// Is reports whether target is ErrInvalidOutput.
+func (f *Failure) Is(target error) bool { return target == ErrInvalidOutput }
+
+type failureKey struct{}
+
+// FailureFrom returns the failure an onFail handler is correcting.
+func FailureFrom(ctx context.Context) (*Failure, bool) {
+	f, ok := ctx.Value(failureKey{}).(*Failure)
+	return f, ok
+}
+
+// Config configures With.
+type Config struct {
+	// MaxAttempts bounds the onFail runs per output; each corrected
+	// output is validated again (0 = DefaultMaxAttempts).
+	MaxAttempts int
+}
+
+// With validates the preceding stage's output, routing invalid output to onFail.
+//
+// Input: output of the previous handler (buffered)
+// Output: the input if valid, else the first valid correction from onFail
+// Behavior: BUFFERED - the whole output is needed to validate it
+//
+// onFail receives the invalid output as its input and the *Failure through
+// FailureFrom. Its output is validated again, up to MaxAttempts corrections;
+// if none is valid, or onFail is nil, the last *Failure is returned. Errors
+// from onFail itself are returned as they are.
+//
+// Example:
+//
+//	total := validate.Rule("totals match", func(out []byte) bool { return totalsMatch(out) })
+//	flow.Use(ai.Agent(client)).
+//		Use(validate.With(validate.All(validate.JSON(), total), correction))
+func With(validator Validator, onFail calque.Handler, config ...Config) calque.Handler {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+
+	handler := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var output []byte
+		if err := calque.Read(req, &output); err != nil {
+			return err
+		}
+
+		var failure *Failure
+		for attempt := 0; ; attempt++ {
+			err := validator(req.Context, output)
+			if err == nil {
+				return calque.Write(res, output)
+			}
+			failure = &Failure{Output: output, Err: err, Attempt: attempt}
+			if onFail == nil || attempt >= cfg.MaxAttempts {
+				return failure
+			}
+
+			var corrected bytes.Buffer
+			ctx := context.WithValue(req.Context, failureKey{}, failure)
+			if err := onFail.ServeFlow(calque.NewRequest(ctx, bytes.NewReader(output)), calque.NewResponse(&corrected)); err != nil {
+				return calque.WrapErr(req.Context, err, "output correction failed")
+			}
+			output = corrected.Bytes()
+		}
+	})
+	if onFail == nil {
+		return handler
+	}
+	return calque.Composite("validate.With", handler, calque.Group{Kind: calque.GroupBranch, Children: []calque.Handler{onFail}, Labels: []string{"invalid"}})
+}
+
+// Case routes failures whose error matches to a handler.
+type Case struct {
+	Match   func(err error) bool
+	Handler calque.Handler
+}
+
+// Route creates an onFail handler choosing a correction by failure type.
+//
+// Input: the invalid output, from With
+// Output: the output of the first Case matching the failure's error
+// Behavior: STREAMING - delegates to the chosen handler
+//
+// Cases are tried in order; fallback handles unmatched failures (nil returns
+// the *Failure). Used outside With, it fails.
+//
+// Example:
+//
+//	onFail := validate.Route(humanReview,
+//		validate.Case{Match: validate.IsError(validate.ErrInvalidJSON), Handler: fixJSON},
+//		validate.Case{Match: validate.IsRule("totals match"), Handler: recomputeTotals},
+//	)
+//	flow.Use(validate.With(validate.All(validate.JSON(), totals), onFail))
+func Route(fallback calque.Handler, cases ...Case) calque.Handler {
+	children := make([]calque.Handler, 0, len(cases)+1)
+	labels := make([]string, 0, len(cases)+1)
+	for i, c := range cases {
+		children = append(children, c.Handler)
+		labels = append(labels, fmt.Sprintf("case %d", i+1))
+	}
+	if fallback != nil {
+		children = append(children, fallback)
+		labels = append(labels, "fallback")
+	}
+
+	handler := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		failure, ok := FailureFrom(req.Context)
+		if !ok {
+			_, _ = io.Copy(io.Discard, req.Data)
+			return calque.NewErr(req.Context, "validate.Route used outside validate.With")
+		}
+		for _, c := range cases {
+			if c.Match(failure.Err) {
+				return c.Handler.ServeFlow(req, res)
+			}
+		}
+		if fallback == nil {
+			return failure
+		}
+		return fallback.ServeFlow(req, res)
+	})
+	return calque.Composite("validate.Route", handler, calque.Group{Kind: calque.GroupBranch, Children: children, Labels: labels})
+}
+
+// IsError matches errors wrapping target, for Case.Match.
+func IsError(target error) func(error) bool {
+	return func(err error) bool { return errors.Is(err, target) }
+}
+
+// FailurePrompt creates an onFail stage rendering a correction prompt.
+//
+// Input: the invalid output, from With
+// Output: the rendered template
+// Behavior: BUFFERED - renders once the input is read
+//
+// The template receives .Output (the invalid output), .Error (the validation
+// error message) and .Attempt. Chain it before an ai.Agent in a correction flow.
+//
+// Example:
+//
+//	fix := calque.NewFlow().
+//		Use(validate.FailurePrompt("Your answer was rejected: {{.Error}}\nRewrite it:\n{{.Output}}")).
+//		Use(ai.Agent(client))
+func FailurePrompt(text string) calque.Handler {
+	tmpl, tmplErr := template.New("validate.FailurePrompt").Parse(text)
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var output string
+		if err := calque.Read(req, &output); err != nil {
+			return err
+		}
+		if tmplErr != nil {
+			return calque.InvalidInput(calque.WrapErr(req.Context, tmplErr, "invalid failure prompt template"))
+		}
+		data := struct {
+			Output  string
+			Error   string
+			Attempt int
+		}{Output: output}
+		if failure, ok := FailureFrom(req.Context); ok {
+			data.Error, data.Attempt = failure.Err.Error(), failure.Attempt
+		}
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err != nil {
+			return calque.WrapErr(req.Context, err, "failed to render failure prompt")
+		}
+		return calque.Write(res, sb.String())
+	})
+}
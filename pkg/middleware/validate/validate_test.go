@@ -0,0 +1,124 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// fixed replies with answer after reading its input, recording failures it saw
+func fixed(answer string, seen *[]string) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var in string
+		if err := calque.Read(req, &in); err != nil {
+			return err
+		}
+		if f, ok := FailureFrom(req.Context); ok && seen != nil {
+			*seen = append(*seen, f.Err.Error())
+		}
+		return calque.Write(res, answer)
+	})
+}
+
+func run(t *testing.T, h calque.Handler, input string) (string, error) {
+	t.Helper()
+	var out string
+	err := calque.NewFlow().Use(h).Run(context.Background(), input, &out)
+	return out, err
+}
+
+func TestWithValid(t *testing.T) {
+	var seen []string
+	out, err := run(t, With(JSON(), fixed(`{}`, &seen)), ` {"ok": true} `)
+	if err != nil || out != ` {"ok": true} ` || len(seen) != 0 {
+		t.Errorf("out = %q, err = %v, corrections = %v", out, err, seen)
+	}
+}
+
+func TestWithCorrects(t *testing.T) {
+	var seen []string
+	out, err := run(t, With(JSON(), fixed(`{"fixed": 1}`, &seen)), `{"broken": `)
+	if err != nil || out != `{"fixed": 1}` {
+		t.Fatalf("out = %q, err = %v", out, err)
+	}
+	if len(seen) != 1 || !strings.Contains(seen[0], "invalid JSON") {
+		t.Errorf("correction saw %v", seen)
+	}
+}
+
+func TestWithGivesUp(t *testing.T) {
+	var seen []string
+	_, err := run(t, With(JSON(), fixed("still broken", &seen), Config{MaxAttempts: 2}), "broken")
+	var failure *Failure
+	if !errors.As(err, &failure) || !errors.Is(err, ErrInvalidOutput) || !errors.Is(err, ErrInvalidJSON) {
+		t.Fatalf("err = %v, want a *Failure wrapping ErrInvalidJSON", err)
+	}
+	if failure.Attempt != 2 || string(failure.Output) != "still broken" || len(seen) != 2 {
+		t.Errorf("failure = %+v, corrections = %d", failure, len(seen))
+	}
+
+	if _, err := run(t, With(JSON(), nil), "broken"); !errors.Is(err, ErrInvalidOutput) {
+		t.Errorf("nil onFail err = %v", err)
+	}
+}
+
+func TestRoute(t *testing.T) {
+	positive := Rule("positive", func(out []byte) bool { return !strings.Contains(string(out), "-") })
+	onFail := Route(fixed("7", nil),
+		Case{Match: IsError(ErrInvalidJSON), Handler: fixed(`{"n": 1}`, nil)},
+		Case{Match: IsRule("positive"), Handler: fixed(`{"n": 2}`, nil)},
+	)
+	h := With(All(JSON(), positive), onFail)
+
+	for input, want := range map[string]string{
+		"not json":  `{"n": 1}`,
+		`{"n": -1}`: `{"n": 2}`,
+		`{"n": 3}`:  `{"n": 3}`,
+	} {
+		if out, err := run(t, h, input); err != nil || out != want {
+			t.Errorf("%q: out = %q, err = %v, want %q", input, out, err, want)
+		}
+	}
+
+	other := With(Matches(regexp.MustCompile(`^\d+$`)), onFail)
+	if out, _ := run(t, other, "abc"); out != "7" {
+		t.Errorf("unmatched failure went to %q, want the fallback", out)
+	}
+}
+
+func TestJSONInto(t *testing.T) {
+	type invoice struct {
+		Total float64 `json:"total"`
+	}
+	v := JSONInto[invoice]()
+	if err := v(context.Background(), []byte(`{"total": 3}`)); err != nil {
+		t.Error(err)
+	}
+	for _, bad := range []string{`{"totl": 3}`, `{"total": "x"}`, `{"total": 1} {}`} {
+		if err := v(context.Background(), []byte(bad)); !errors.Is(err, ErrInvalidJSON) {
+			t.Errorf("%s: err = %v", bad, err)
+		}
+	}
+}
+
+func TestFailurePrompt(t *testing.T) {
+	var prompt string
+	capture := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		if err := calque.Read(req, &prompt); err != nil {
+			return err
+		}
+		return calque.Write(res, "42")
+	})
+	fix := calque.NewFlow().Use(FailurePrompt("Error: {{.Error}} | Output: {{.Output}}")).Use(capture)
+
+	if out, err := run(t, With(Matches(regexp.MustCompile(`^\d+$`)), fix), "forty-two"); err != nil || out != "42" {
+		t.Fatalf("out = %q, err = %v", out, err)
+	}
+	if !strings.HasPrefix(prompt, "Error: output does not match") || !strings.HasSuffix(prompt, "Output: forty-two") {
+		t.Errorf("prompt = %q", prompt)
+	}
+}
@@ -0,0 +1,105 @@
+package validate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// ErrInvalidJSON is wrapped by the errors of JSON and JSONInto.
+var ErrInvalidJSON = errors.New("invalid JSON")
+
+// RuleError is returned by Rule validators.
+type RuleError struct {
+	Rule string // the rule name
+}
+
+// Error implements error.
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("rule %q failed", e.Rule)
+}
+
+// JSON accepts outputs that are a single JSON value. Leading and trailing
+// whitespace is allowed.
+func JSON() Validator {
+	return func(_ context.Context, output []byte) error {
+		if !json.Valid(bytes.TrimSpace(output)) {
+			return ErrInvalidJSON
+		}
+		return nil
+	}
+}
+
+// JSONInto accepts outputs that decode into T without unknown fields.
+//
+// Example:
+//
+//	type Invoice struct {
+//		Total float64 `json:"total"`
+//	}
+//	flow.Use(validate.With(validate.JSONInto[Invoice](), fixJSON))
+func JSONInto[T any]() Validator {
+	return func(_ context.Context, output []byte) error {
+		dec := json.NewDecoder(bytes.NewReader(output))
+		dec.DisallowUnknownFields()
+		var v T
+		if err := dec.Decode(&v); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+		}
+		if dec.More() {
+			return fmt.Errorf("%w: trailing data after the value", ErrInvalidJSON)
+		}
+		return nil
+	}
+}
+
+// Matches accepts outputs matching re, e.g. an anchored pattern for the whole output.
+func Matches(re *regexp.Regexp) Validator {
+	return func(_ context.Context, output []byte) error {
+		if !re.Match(output) {
+			return fmt.Errorf("output does not match %s", re)
+		}
+		return nil
+	}
+}
+
+// Rule creates a named business rule; failures are *RuleError, matched by IsRule.
+//
+// Example:
+//
+//	positive := validate.Rule("positive total", func(out []byte) bool {
+//		var inv Invoice
+//		return json.Unmarshal(out, &inv) == nil && inv.Total > 0
+//	})
+func Rule(name string, ok func(output []byte) bool) Validator {
+	return func(_ context.Context, output []byte) error {
+		if !ok(output) {
+			return &RuleError{Rule: name}
+		}
+		return nil
+	}
+}
+
+// IsRule matches *RuleError failures of the named rule, for Case.Match.
+func IsRule(name string) func(error) bool {
+	return func(err error) bool {
+		var rule *RuleError
+		return errors.As(err, &rule) && rule.Rule == name
+	}
+}
+
+// All runs validators in order and returns the first error, so failures are
+// routed by the first check that fails.
+func All(validators ...Validator) Validator {
+	return func(ctx context.Context, output []byte) error {
+		for _, v := range validators {
+			if err := v(ctx, output); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,460 @@
+// Package netpolicy restricts the network calls agents can trigger.
+//
+// A Policy allows or denies destination domains, blocks loopback, private
+// and link-local addresses, and caps response sizes. Enforce attaches a
+// policy to a flow; HTTP tools and loaders in this module fetch through
+// HTTPClient, so a prompt-injected model cannot make them reach internal
+// services (SSRF) or pull unbounded responses. Custom tools do the same with
+// HTTPClient or CheckURL.
+//
+// Example:
+//
+//	agent := netpolicy.Enforce(ai.Agent(client, ai.WithTools(search)), netpolicy.Policy{
+//		AllowDomains:     []string{"wikipedia.org", "api.search.brave.com"},
+//		MaxResponseBytes: 1 << 20,
+//	})
+package netpolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultMaxResponseBytes caps response bodies when Policy.MaxResponseBytes is 0.
+const DefaultMaxResponseBytes = 10 << 20
+
+var (
+	// ErrDenied matches any *DeniedError with errors.Is.
+	ErrDenied = errors.New("network call denied by policy")
+
+	// ErrResponseTooLarge is returned when reading past the response size cap.
+	ErrResponseTooLarge = errors.New("response exceeds policy size limit")
+)
+
+// Policy describes the network calls allowed. The zero value allows public
+// http and https destinations with DefaultMaxResponseBytes responses.
+type Policy struct {
+	// AllowDomains, when set, are the only hosts allowed; "example.com"
+	// also allows its subdomains. IP literals must be listed exactly.
+	AllowDomains []string
+	// DenyDomains are hosts refused even when allowed, with subdomains
+	DenyDomains []string
+	// AllowPrivate permits loopback, private, link-local, CGNAT and
+	// unspecified addresses, which are refused by default
+	AllowPrivate bool
+	// AllowSchemes are the URL schemes allowed (nil = http and https)
+	AllowSchemes []string
+	// MaxResponseBytes caps each response body (0 = DefaultMaxResponseBytes,
+	// negative = unlimited)
+	MaxResponseBytes int64
+}
+
+// DeniedError is returned for a destination a policy refuses.
+type DeniedError struct {
+	URL    string // the refused URL, without credentials and query
+	Reason string
+}
+
+// Error implements error.
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("network call denied by policy: %s (%s)", e.URL, e.Reason)
+}
+
+// Is reports whether target is ErrDenied.
+func (e *DeniedError) Is(target error) bool {
+	return target == ErrDenied
+}
+
+// enforcer is a policy attached to a context, with the clients built for it
+type enforcer struct {
+	policy Policy
+	parent *enforcer
+
+	mu      sync.Mutex
+	clients map[*http.Client]*http.Client
+}
+
+type policyContextKey struct{}
+
+// WithPolicy returns a context whose network calls must satisfy p, in
+// addition to any policy already in ctx.
+//
+// Example:
+//
+//	ctx = netpolicy.WithPolicy(ctx, netpolicy.Policy{AllowDomains: []string{"example.com"}})
+//	err := flow.Run(ctx, input, &output)
+func WithPolicy(ctx context.Context, p Policy) context.Context {
+	return context.WithValue(ctx, policyContextKey{}, newEnforcer(fromContext(ctx), p))
+}
+
+func newEnforcer(parent *enforcer, p Policy) *enforcer {
+	return &enforcer{policy: p, parent: parent, clients: make(map[*http.Client]*http.Client)}
+}
+
+// Enforce applies p to the network calls of handler and everything nested in it.
+//
+// Input: any data type (streaming - passed through to handler)
+// Output: same as wrapped handler's output
+// Behavior: STREAMING - handler runs with p in its context
+//
+// Nested policies all apply, so an inner policy can only tighten an outer
+// one. Refused calls fail with a *DeniedError classified as invalid input.
+// The policy and the clients HTTPClient builds for it are created once and
+// shared by every run, so connections are pooled across runs.
+//
+// Example:
+//
+//	flow.Use(netpolicy.Enforce(researchAgent, netpolicy.Policy{DenyDomains: []string{"internal.corp"}}))
+func Enforce(handler calque.Handler, p Policy) calque.Handler {
+	root := newEnforcer(nil, p)
+
+	// under an enclosing policy the chain differs; keep the last one built
+	var mu sync.Mutex
+	var nested *enforcer
+
+	return calque.Composite("netpolicy.Enforce", calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		e := root
+		if parent := fromContext(req.Context); parent != nil {
+			mu.Lock()
+			if nested == nil || nested.parent != parent {
+				nested = newEnforcer(parent, p)
+			}
+			e = nested
+			mu.Unlock()
+		}
+		return handler.ServeFlow(req.WithContext(context.WithValue(req.Context, policyContextKey{}, e)), res)
+	}), calque.Group{Kind: calque.GroupWrap, Children: []calque.Handler{handler}})
+}
+
+// CheckURL reports whether the policies in ctx allow a request to rawURL,
+// returning nil when no policy is in the context.
+//
+// Host names are checked against the domain lists; the addresses they
+// resolve to are only checked when connecting through HTTPClient.
+func CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return calque.InvalidInput(calque.WrapErr(ctx, err, "invalid URL"))
+	}
+	for e := fromContext(ctx); e != nil; e = e.parent {
+		if err := e.policy.checkURL(u); err != nil {
+			return calque.InvalidInput(calque.WrapErr(ctx, err, "request refused"))
+		}
+	}
+	return nil
+}
+
+// HTTPClient returns base restricted by the policies in ctx, or base itself
+// when no policy is in the context. A nil base means http.DefaultClient.
+//
+// Every request, including each redirect, is checked with CheckURL;
+// connections to refused addresses fail after DNS resolution, so rebinding
+// a public name to an internal address does not help; and response bodies
+// fail with ErrResponseTooLarge past MaxResponseBytes. Checking addresses
+// needs base's Transport to be an *http.Transport (or nil); any other
+// transport refuses every request unless all policies set AllowPrivate.
+// Proxies from the transport's Proxy setting are dialed even on private
+// addresses, and resolve names themselves, so requests through them get
+// only the CheckURL checks. The address-checking transport built for a base
+// transport is shared by every policy, keeping its connection pool.
+//
+// Example:
+//
+//	resp, err := netpolicy.HTTPClient(ctx, c.http).Do(req)
+func HTTPClient(ctx context.Context, base *http.Client) *http.Client {
+	if base == nil {
+		base = http.DefaultClient
+	}
+	e := fromContext(ctx)
+	if e == nil {
+		return base
+	}
+	return e.client(base)
+}
+
+func fromContext(ctx context.Context) *enforcer {
+	e, _ := ctx.Value(policyContextKey{}).(*enforcer)
+	return e
+}
+
+// policies returns the policy of e and of every enclosing enforcer
+func (e *enforcer) policies() []*Policy {
+	var policies []*Policy
+	for ; e != nil; e = e.parent {
+		policies = append(policies, &e.policy)
+	}
+	return policies
+}
+
+// client returns base with every enclosing policy applied, building it once
+func (e *enforcer) client(base *http.Client) *http.Client {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if c, ok := e.clients[base]; ok {
+		return c
+	}
+
+	policies := e.policies()
+	next := base.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if !allowsPrivate(policies) {
+		if t, ok := next.(*http.Transport); ok {
+			next = guardedTransport(t)
+		} else {
+			next = unguardedTransport{reason: fmt.Sprintf("transport %T cannot check resolved addresses", next)}
+		}
+	}
+
+	c := *base
+	c.Transport = &policyTransport{policies: policies, next: next}
+	e.clients[base] = &c
+	return &c
+}
+
+// guardedTransports maps each base *http.Transport to its address-checking
+// clone, shared by every policy so connections are pooled once per base
+var guardedTransports sync.Map
+
+// guardedTransport returns t with connections to private addresses refused
+// after DNS resolution, except to t's proxies
+func guardedTransport(t *http.Transport) *http.Transport {
+	if g, ok := guardedTransports.Load(t); ok {
+		return g.(*http.Transport)
+	}
+
+	g := t.Clone()
+	var proxies sync.Map // addresses dialed for the proxies t.Proxy chose
+	if proxy := g.Proxy; proxy != nil {
+		g.Proxy = func(req *http.Request) (*url.URL, error) {
+			u, err := proxy(req)
+			if u != nil {
+				proxies.Store(proxyAddress(u), true)
+			}
+			return u, err
+		}
+	}
+	dial := g.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	checked := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: func(_, address string, _ syscall.RawConn) error {
+		return checkAddress(address)
+	}}
+	g.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if _, ok := proxies.Load(address); ok {
+			return dial(ctx, network, address) // configured by the application, not the model
+		}
+		return checked.DialContext(ctx, network, address)
+	}
+	g.DialTLSContext = nil
+
+	actual, _ := guardedTransports.LoadOrStore(t, g)
+	return actual.(*http.Transport)
+}
+
+// proxyAddress is the host:port http.Transport dials for proxy u
+func proxyAddress(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// unguardedTransport refuses every request, for a base transport whose
+// connections cannot be checked
+type unguardedTransport struct {
+	reason string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t unguardedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	err := &DeniedError{URL: shownURL(req.URL), Reason: t.reason}
+	return nil, calque.InvalidInput(calque.WrapErr(req.Context(), err, "request refused"))
+}
+
+func allowsPrivate(policies []*Policy) bool {
+	for _, p := range policies {
+		if !p.AllowPrivate {
+			return false
+		}
+	}
+	return true
+}
+
+// policyTransport checks every request and caps every response
+type policyTransport struct {
+	policies []*Policy
+	next     http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *policyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	limit := int64(-1)
+	for _, p := range t.policies {
+		if err := p.checkURL(req.URL); err != nil {
+			return nil, calque.InvalidInput(calque.WrapErr(req.Context(), err, "request refused"))
+		}
+		if n := p.maxResponseBytes(); n > 0 && (limit < 0 || n < limit) {
+			limit = n
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if limit >= 0 {
+		if resp.ContentLength > limit {
+			_ = resp.Body.Close()
+			return nil, calque.WrapErr(req.Context(), ErrResponseTooLarge, fmt.Sprintf("%d byte response from %s", resp.ContentLength, req.URL.Host))
+		}
+		resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: limit}
+	}
+	return resp, nil
+}
+
+// limitedBody fails reads past the size cap instead of truncating silently
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// allow a clean EOF exactly at the limit
+		var probe [1]byte
+		if n, err := b.ReadCloser.Read(probe[:]); n == 0 {
+			return 0, err
+		}
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (p *Policy) maxResponseBytes() int64 {
+	if p.MaxResponseBytes == 0 {
+		return DefaultMaxResponseBytes
+	}
+	return p.MaxResponseBytes
+}
+
+// checkURL applies the scheme and domain rules, and the address rules to IP literals
+func (p *Policy) checkURL(u *url.URL) error {
+	shown := shownURL(u)
+	deny := func(reason string) error { return &DeniedError{URL: shown, Reason: reason} }
+
+	schemes := p.AllowSchemes
+	if schemes == nil {
+		schemes = []string{"http", "https"}
+	}
+	if !containsFold(schemes, u.Scheme) {
+		return deny("scheme " + u.Scheme + " not allowed")
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return deny("missing host")
+	}
+	if matchesDomain(p.DenyDomains, host) {
+		return deny("domain denied")
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if len(p.AllowDomains) > 0 && !containsFold(p.AllowDomains, addr.String()) {
+			return deny("address not in allowlist")
+		}
+		if !p.AllowPrivate && isPrivate(addr) {
+			return deny("private address")
+		}
+		return nil
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		if !p.AllowPrivate {
+			return deny("private address")
+		}
+	}
+	if len(p.AllowDomains) > 0 && !matchesDomain(p.AllowDomains, host) {
+		return deny("domain not in allowlist")
+	}
+	return nil
+}
+
+// shownURL is u without credentials and query, for errors
+func shownURL(u *url.URL) string {
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String()
+}
+
+// checkAddress refuses connections to private addresses, after DNS resolution
+func checkAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if isPrivate(addr) {
+		return &DeniedError{URL: address, Reason: "resolves to a private address"}
+	}
+	return nil
+}
+
+// cgnat is the shared address space (RFC 6598), used by some cloud metadata services
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+func isPrivate(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsUnspecified() || addr.IsMulticast() ||
+		cgnat.Contains(addr)
+}
+
+// matchesDomain reports whether host is one of domains or a subdomain of one
+func matchesDomain(domains []string, host string) bool {
+	for _, d := range domains {
+		d = strings.TrimSuffix(strings.ToLower(strings.TrimPrefix(d, "*.")), ".")
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
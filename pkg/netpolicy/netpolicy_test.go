@@ -0,0 +1,243 @@
+package netpolicy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *Policy
+		url     string
+		allowed bool
+	}{
+		{name: "no policy", url: "http://127.0.0.1/admin", allowed: true},
+		{name: "public host", policy: &Policy{}, url: "https://example.com/page", allowed: true},
+		{name: "loopback literal", policy: &Policy{}, url: "http://127.0.0.1:8080/", allowed: false},
+		{name: "metadata service", policy: &Policy{}, url: "http://169.254.169.254/latest/meta-data", allowed: false},
+		{name: "private range", policy: &Policy{}, url: "http://10.0.0.5/", allowed: false},
+		{name: "ipv6 loopback", policy: &Policy{}, url: "http://[::1]/", allowed: false},
+		{name: "ipv4-mapped ipv6", policy: &Policy{}, url: "http://[::ffff:127.0.0.1]/", allowed: false},
+		{name: "cgnat", policy: &Policy{}, url: "http://100.100.100.200/", allowed: false},
+		{name: "localhost name", policy: &Policy{}, url: "http://localhost:3000/", allowed: false},
+		{name: "private allowed", policy: &Policy{AllowPrivate: true}, url: "http://127.0.0.1/", allowed: true},
+		{name: "file scheme", policy: &Policy{}, url: "file:///etc/passwd", allowed: false},
+		{name: "custom scheme", policy: &Policy{AllowSchemes: []string{"https"}}, url: "http://example.com/", allowed: false},
+		{name: "allowlisted", policy: &Policy{AllowDomains: []string{"example.com"}}, url: "https://example.com/", allowed: true},
+		{name: "allowlisted subdomain", policy: &Policy{AllowDomains: []string{"example.com"}}, url: "https://api.example.com/", allowed: true},
+		{name: "lookalike domain", policy: &Policy{AllowDomains: []string{"example.com"}}, url: "https://evilexample.com/", allowed: false},
+		{name: "not allowlisted", policy: &Policy{AllowDomains: []string{"example.com"}}, url: "https://other.org/", allowed: false},
+		{name: "denied domain", policy: &Policy{DenyDomains: []string{"internal.corp"}}, url: "https://wiki.internal.corp/", allowed: false},
+		{name: "trailing dot", policy: &Policy{DenyDomains: []string{"internal.corp"}}, url: "https://internal.corp./", allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.policy != nil {
+				ctx = WithPolicy(ctx, *tt.policy)
+			}
+			err := CheckURL(ctx, tt.url)
+			if tt.allowed && err != nil {
+				t.Errorf("CheckURL(%s) error = %v, want allowed", tt.url, err)
+			}
+			if !tt.allowed && (!errors.Is(err, ErrDenied) || !calque.IsInvalidInput(err)) {
+				t.Errorf("CheckURL(%s) error = %v, want invalid-input ErrDenied", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestNestedPolicies(t *testing.T) {
+	ctx := WithPolicy(context.Background(), Policy{AllowDomains: []string{"example.com"}})
+	ctx = WithPolicy(ctx, Policy{AllowPrivate: true})
+
+	if err := CheckURL(ctx, "https://other.org/"); !errors.Is(err, ErrDenied) {
+		t.Errorf("inner policy loosened the outer allowlist: %v", err)
+	}
+}
+
+func TestHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/big":
+			_, _ = w.Write([]byte(strings.Repeat("x", 100)))
+		case "/stream":
+			for range 10 {
+				_, _ = w.Write([]byte(strings.Repeat("y", 10)))
+				w.(http.Flusher).Flush()
+			}
+		case "/redirect":
+			http.Redirect(w, r, "http://169.254.169.254/", http.StatusFound)
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	get := func(ctx context.Context, path string) (string, error) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+		resp, err := HTTPClient(ctx, server.Client()).Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	t.Run("no policy", func(t *testing.T) {
+		base := server.Client()
+		if HTTPClient(context.Background(), base) != base {
+			t.Error("HTTPClient without a policy should return base")
+		}
+	})
+
+	t.Run("private server refused", func(t *testing.T) {
+		_, err := get(WithPolicy(context.Background(), Policy{}), "/")
+		if !errors.Is(err, ErrDenied) {
+			t.Errorf("error = %v, want ErrDenied", err)
+		}
+	})
+
+	t.Run("private server allowed", func(t *testing.T) {
+		body, err := get(WithPolicy(context.Background(), Policy{AllowPrivate: true}), "/")
+		if err != nil || body != "ok" {
+			t.Errorf("body = %q, err = %v", body, err)
+		}
+	})
+
+	t.Run("redirect checked", func(t *testing.T) {
+		ctx := WithPolicy(context.Background(), Policy{AllowPrivate: true, DenyDomains: []string{"169.254.169.254"}})
+		_, err := get(ctx, "/redirect")
+		if !errors.Is(err, ErrDenied) {
+			t.Errorf("error = %v, want ErrDenied", err)
+		}
+	})
+
+	t.Run("response too large", func(t *testing.T) {
+		ctx := WithPolicy(context.Background(), Policy{AllowPrivate: true, MaxResponseBytes: 50})
+		if _, err := get(ctx, "/big"); !errors.Is(err, ErrResponseTooLarge) {
+			t.Errorf("sized response error = %v, want ErrResponseTooLarge", err)
+		}
+		if _, err := get(ctx, "/stream"); !errors.Is(err, ErrResponseTooLarge) {
+			t.Errorf("streamed response error = %v, want ErrResponseTooLarge", err)
+		}
+	})
+
+	t.Run("response within limit", func(t *testing.T) {
+		ctx := WithPolicy(context.Background(), Policy{AllowPrivate: true, MaxResponseBytes: 100})
+		body, err := get(ctx, "/stream")
+		if err != nil || len(body) != 100 {
+			t.Errorf("len(body) = %d, err = %v", len(body), err)
+		}
+	})
+
+	t.Run("client reused", func(t *testing.T) {
+		ctx := WithPolicy(context.Background(), Policy{})
+		if HTTPClient(ctx, server.Client()) != HTTPClient(ctx, server.Client()) {
+			t.Error("HTTPClient built a new client for the same base")
+		}
+		other := WithPolicy(context.Background(), Policy{DenyDomains: []string{"example.org"}})
+		first := HTTPClient(ctx, server.Client()).Transport.(*policyTransport).next
+		if HTTPClient(other, server.Client()).Transport.(*policyTransport).next != first {
+			t.Error("policies built separate transports, and connection pools, for the same base")
+		}
+	})
+
+	t.Run("unguarded transport refused", func(t *testing.T) {
+		called := false
+		base := &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			called = true
+			return nil, errors.New("unreachable")
+		})}
+		req, _ := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+		_, err := HTTPClient(WithPolicy(context.Background(), Policy{}), base).Do(req)
+		if !errors.Is(err, ErrDenied) || called {
+			t.Errorf("error = %v, transport called = %v; want ErrDenied without a call", err, called)
+		}
+	})
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	// a forward proxy on loopback, as a corporate proxy on a private address would be
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("proxied " + r.URL.Host))
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	base := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	ctx := WithPolicy(context.Background(), Policy{})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+	resp, err := HTTPClient(ctx, base).Do(req)
+	if err != nil {
+		t.Fatalf("request through a private proxy: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if body, _ := io.ReadAll(resp.Body); string(body) != "proxied example.com" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	if err := checkAddress("127.0.0.1:80"); !errors.Is(err, ErrDenied) {
+		t.Errorf("loopback error = %v, want ErrDenied", err)
+	}
+	if err := checkAddress("[fe80::1]:443"); !errors.Is(err, ErrDenied) {
+		t.Errorf("link-local error = %v, want ErrDenied", err)
+	}
+	if err := checkAddress("93.184.216.34:443"); err != nil {
+		t.Errorf("public address error = %v", err)
+	}
+}
+
+func TestEnforce(t *testing.T) {
+	var checkErr error
+	handler := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		var input string
+		if err := calque.Read(req, &input); err != nil {
+			return err
+		}
+		checkErr = CheckURL(req.Context, input)
+		return calque.Write(res, input)
+	})
+
+	var out strings.Builder
+	flow := Enforce(handler, Policy{})
+	if err := flow.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("http://10.1.2.3/")), calque.NewResponse(&out)); err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(checkErr, ErrDenied) {
+		t.Errorf("CheckURL inside Enforce = %v, want ErrDenied", checkErr)
+	}
+
+	var clients []*http.Client
+	record := Enforce(calque.HandlerFunc(func(req *calque.Request, _ *calque.Response) error {
+		clients = append(clients, HTTPClient(req.Context, http.DefaultClient))
+		return nil
+	}), Policy{})
+	for range 2 {
+		if err := record.ServeFlow(calque.NewRequest(context.Background(), strings.NewReader("")), calque.NewResponse(io.Discard)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if clients[0] != clients[1] {
+		t.Error("Enforce built a new client for each run")
+	}
+}
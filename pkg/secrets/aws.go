@@ -0,0 +1,209 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// AWSConfig configures the AWS Secrets Manager provider.
+//
+// Credentials default to the standard AWS_* environment variables.
+type AWSConfig struct {
+	// Region is the AWS region. Defaults to AWS_REGION, then AWS_DEFAULT_REGION.
+	Region string
+
+	// AccessKeyID, SecretAccessKey and SessionToken are static credentials.
+	// Default to AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// Endpoint overrides the service URL (e.g. for LocalStack or VPC endpoints)
+	Endpoint string
+
+	// HTTPClient is used for requests. Default: client with 10s timeout
+	HTTPClient *http.Client
+}
+
+// AWSSecretsManagerProvider reads secrets from AWS Secrets Manager.
+//
+// Names are secret IDs or ARNs. For JSON secrets, append "#field" to select a
+// key, e.g. "prod/calque#openai_api_key". Requests are signed with SigV4.
+type AWSSecretsManagerProvider struct {
+	config   AWSConfig
+	endpoint string
+	now      func() time.Time
+}
+
+// NewAWSSecretsManagerProvider creates an AWS Secrets Manager provider.
+//
+// Example:
+//
+//	aws, err := secrets.NewAWSSecretsManagerProvider(secrets.AWSConfig{Region: "us-east-1"})
+//	key := secrets.NewRef(aws, "prod/calque#openai_api_key", secrets.WithTTL(time.Hour))
+func NewAWSSecretsManagerProvider(config AWSConfig) (*AWSSecretsManagerProvider, error) {
+	if config.Region == "" {
+		config.Region = os.Getenv("AWS_REGION")
+	}
+	if config.Region == "" {
+		config.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if config.AccessKeyID == "" {
+		config.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		config.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		config.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	ctx := context.Background()
+	if config.Region == "" {
+		return nil, calque.NewErr(ctx, "secrets: aws region is required (AWSConfig.Region or AWS_REGION)")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, calque.NewErr(ctx, "secrets: aws credentials are required (AWSConfig or AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY)")
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + config.Region + ".amazonaws.com"
+	}
+	return &AWSSecretsManagerProvider{config: config, endpoint: endpoint, now: time.Now}, nil
+}
+
+// GetSecret fetches the current version of a secret.
+func (p *AWSSecretsManagerProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	id, field := splitField(name)
+
+	body, err := json.Marshal(map[string]string{"SecretId": id})
+	if err != nil {
+		return "", calque.WrapErr(ctx, err, "secrets: failed to encode aws request")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", calque.WrapErr(ctx, err, "secrets: failed to create aws request")
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	p.sign(req, body)
+
+	resp, err := p.config.HTTPClient.Do(req)
+	if err != nil {
+		return "", calque.WrapErr(ctx, err, "secrets: aws request failed")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(respBody, &apiErr)
+		if strings.HasSuffix(apiErr.Type, "ResourceNotFoundException") {
+			return "", calque.WrapErr(ctx, ErrNotFound, "secrets: aws secret "+id)
+		}
+		return "", calque.NewErr(ctx, fmt.Sprintf("secrets: aws returned status %d: %s %s", resp.StatusCode, apiErr.Type, apiErr.Message))
+	}
+
+	var result struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", calque.WrapErr(ctx, err, "secrets: failed to decode aws response")
+	}
+	if field == "" {
+		return result.SecretString, nil
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal([]byte(result.SecretString), &data); err != nil {
+		return "", calque.WrapErr(ctx, err, "secrets: aws secret "+id+" is not a JSON object")
+	}
+	return selectField(ctx, name, data, field)
+}
+
+// sign adds AWS Signature Version 4 headers to the request
+func (p *AWSSecretsManagerProvider) sign(req *http.Request, body []byte) {
+	const service = "secretsmanager"
+
+	now := p.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if p.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", p.config.SessionToken)
+	}
+
+	payloadHash := sha256Hex(body)
+	signedHeaders := []string{"content-type", "host", "x-amz-date", "x-amz-target"}
+	if p.config.SessionToken != "" {
+		signedHeaders = []string{"content-type", "host", "x-amz-date", "x-amz-security-token", "x-amz-target"}
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, h := range signedHeaders {
+		value := req.Header.Get(h)
+		if h == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + p.config.Region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+p.config.SecretAccessKey), date)
+	key = hmacSHA256(key, p.config.Region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+p.config.AccessKeyID+"/"+scope+
+		", SignedHeaders="+strings.Join(signedHeaders, ";")+", Signature="+signature)
+}
+
+func canonicalQuery(values url.Values) string {
+	// url.Values.Encode sorts by key, which is what SigV4 requires
+	return strings.ReplaceAll(values.Encode(), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
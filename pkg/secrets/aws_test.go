@@ -0,0 +1,99 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAWSSecretsManagerProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/20250101/us-east-1/secretsmanager/aws4_request") ||
+			!strings.Contains(auth, "SignedHeaders=content-type;host;x-amz-date;x-amz-target") {
+			http.Error(w, "bad signature", http.StatusForbidden)
+			return
+		}
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" {
+			http.Error(w, "bad target", http.StatusBadRequest)
+			return
+		}
+
+		var body struct{ SecretId string }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch body.SecretId {
+		case "plain":
+			_, _ = w.Write([]byte(`{"SecretString":"plain-value"}`))
+		case "prod/calque":
+			_, _ = w.Write([]byte(`{"SecretString":"{\"openai_api_key\":\"sk-aws\"}"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"not found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	p, err := NewAWSSecretsManagerProvider(AWSConfig{
+		Region:          "us-east-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		Endpoint:        srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.now = func() time.Time { return time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		secret  string
+		want    string
+		wantErr error
+	}{
+		{"plain string", "plain", "plain-value", nil},
+		{"json field", "prod/calque#openai_api_key", "sk-aws", nil},
+		{"missing", "nope", "", ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.GetSecret(ctx, tt.secret)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("expected %q, got %q / %v", tt.want, got, err)
+			}
+		})
+	}
+}
+
+func TestAWSSign_Deterministic(t *testing.T) {
+	p := &AWSSecretsManagerProvider{
+		config: AWSConfig{Region: "us-east-1", AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "tok"},
+		now:    func() time.Time { return time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC) },
+	}
+	sign := func() string {
+		req, _ := http.NewRequest(http.MethodPost, "https://secretsmanager.us-east-1.amazonaws.com/", strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+		req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+		p.sign(req, []byte("{}"))
+		return req.Header.Get("Authorization")
+	}
+
+	first := sign()
+	if first != sign() {
+		t.Error("expected deterministic signature")
+	}
+	if !strings.Contains(first, "x-amz-security-token") {
+		t.Errorf("expected session token to be signed, got %s", first)
+	}
+}
@@ -0,0 +1,69 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// EnvProvider resolves secrets from environment variables.
+type EnvProvider struct {
+	// Prefix is prepended to every name (e.g. "MYAPP_")
+	Prefix string
+}
+
+// Env returns a provider that reads environment variables.
+//
+// Example:
+//
+//	key := secrets.NewRef(secrets.Env(), "OPENAI_API_KEY")
+func Env() *EnvProvider {
+	return &EnvProvider{}
+}
+
+// GetSecret returns the value of the environment variable Prefix+name.
+func (p *EnvProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(p.Prefix + name)
+	if !ok || value == "" {
+		return "", calque.WrapErr(ctx, ErrNotFound, "secrets: environment variable "+p.Prefix+name)
+	}
+	return value, nil
+}
+
+// FileProvider resolves secrets from files, one secret per file.
+//
+// This matches Kubernetes and Docker secret mounts, where each key is a file
+// under a directory. Files are re-read on every fetch, so rotated mounts are
+// picked up once a Ref's TTL expires.
+type FileProvider struct {
+	dir string
+}
+
+// Files returns a provider that reads secrets from files under dir.
+//
+// Example:
+//
+//	// /var/run/secrets/calque/openai-api-key
+//	key := secrets.NewRef(secrets.Files("/var/run/secrets/calque"), "openai-api-key")
+func Files(dir string) *FileProvider {
+	return &FileProvider{dir: dir}
+}
+
+// GetSecret returns the whitespace-trimmed contents of dir/name.
+func (p *FileProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	if name == "" || filepath.IsAbs(name) || !filepath.IsLocal(name) {
+		return "", calque.NewErr(ctx, "secrets: invalid file secret name "+name)
+	}
+
+	data, err := os.ReadFile(filepath.Join(p.dir, name))
+	if os.IsNotExist(err) {
+		return "", calque.WrapErr(ctx, ErrNotFound, "secrets: file "+name)
+	}
+	if err != nil {
+		return "", calque.WrapErr(ctx, err, "secrets: failed to read "+name)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
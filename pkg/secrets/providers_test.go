@@ -0,0 +1,59 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvProvider(t *testing.T) {
+	t.Setenv("CALQUE_TEST_KEY", "from-env")
+	ctx := context.Background()
+
+	p := &EnvProvider{Prefix: "CALQUE_"}
+	if v, err := p.GetSecret(ctx, "TEST_KEY"); err != nil || v != "from-env" {
+		t.Errorf("expected from-env, got %q / %v", v, err)
+	}
+	if _, err := Env().GetSecret(ctx, "CALQUE_MISSING_KEY"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "api-key"), []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	p := Files(dir)
+
+	tests := []struct {
+		name    string
+		secret  string
+		want    string
+		wantErr bool
+	}{
+		{"reads and trims", "api-key", "from-file", false},
+		{"missing", "nope", "", true},
+		{"path traversal", "../etc/passwd", "", true},
+		{"absolute", "/etc/passwd", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.GetSecret(ctx, tt.secret)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("expected %q (err=%v), got %q / %v", tt.want, tt.wantErr, got, err)
+			}
+		})
+	}
+
+	// Rotation: file changes are picked up on the next fetch
+	if err := os.WriteFile(filepath.Join(dir, "api-key"), []byte("rotated"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := p.GetSecret(ctx, "api-key"); v != "rotated" {
+		t.Errorf("expected rotated value, got %q", v)
+	}
+}
@@ -0,0 +1,215 @@
+// Package secrets provides a pluggable abstraction for resolving credentials
+// such as API keys and tokens at runtime.
+//
+// Handlers and clients accept a *Ref instead of a raw string. A Ref names a
+// secret in a Provider (environment, files, HashiCorp Vault, AWS Secrets
+// Manager, or a custom backend), caches the resolved value for a TTL and then
+// re-fetches it, so rotated credentials are picked up without restarts. Ref
+// values never print their contents: fmt, JSON and slog output is redacted.
+//
+// Example:
+//
+//	vault, _ := secrets.NewVaultProvider(secrets.VaultConfig{Address: "https://vault:8200"})
+//	key := secrets.NewRef(vault, "calque/openai#api_key")
+//
+//	client, _ := openai.New("gpt-4o", openai.WithConfig(&openai.Config{APIKeyRef: key}))
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// DefaultRefTTL is how long a Ref caches a resolved value before re-fetching it.
+const DefaultRefTTL = 5 * time.Minute
+
+// Redacted is printed in place of secret values.
+const Redacted = "[REDACTED]"
+
+// ErrNotFound is returned by providers when a secret doesn't exist.
+var ErrNotFound = errors.New("secret not found")
+
+// Provider resolves secret values by name.
+//
+// Name syntax is provider-specific; providers backed by structured secrets
+// (Vault, AWS Secrets Manager) accept "path#field" to select a single field.
+type Provider interface {
+	GetSecret(ctx context.Context, name string) (string, error)
+}
+
+// ProviderFunc adapts a function to the Provider interface.
+type ProviderFunc func(ctx context.Context, name string) (string, error)
+
+// GetSecret calls f(ctx, name).
+func (f ProviderFunc) GetSecret(ctx context.Context, name string) (string, error) {
+	return f(ctx, name)
+}
+
+// Ref is a reference to a secret that is resolved lazily and cached.
+//
+// A Ref is safe for concurrent use. Printing it (fmt, JSON, slog) never reveals
+// the secret value.
+type Ref struct {
+	provider Provider
+	name     string
+	ttl      time.Duration
+
+	mu        sync.Mutex
+	value     string
+	fetchedAt time.Time
+	static    bool
+}
+
+// RefOption configures a Ref
+type RefOption func(*Ref)
+
+// WithTTL sets how long a resolved value is cached (default DefaultRefTTL).
+// A negative TTL disables caching so every Resolve hits the provider.
+func WithTTL(ttl time.Duration) RefOption {
+	return func(r *Ref) {
+		r.ttl = ttl
+	}
+}
+
+// NewRef creates a reference to the named secret in a provider.
+//
+// Example:
+//
+//	key := secrets.NewRef(secrets.Env(), "OPENAI_API_KEY")
+//	value, err := key.Resolve(ctx)
+func NewRef(provider Provider, name string, opts ...RefOption) *Ref {
+	r := &Ref{provider: provider, name: name, ttl: DefaultRefTTL}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Static wraps a fixed value in a Ref, for tests and migrating existing string configs.
+func Static(value string) *Ref {
+	return &Ref{name: "static", value: value, static: true}
+}
+
+// Name returns the secret name the reference points to.
+func (r *Ref) Name() string {
+	return r.name
+}
+
+// Resolve returns the secret value, fetching it from the provider if the cached
+// value is missing or older than the TTL.
+//
+// If a refresh fails after the TTL expires, the error is returned and the stale
+// value is discarded so callers never silently use revoked credentials.
+func (r *Ref) Resolve(ctx context.Context) (string, error) {
+	if r == nil {
+		return "", calque.NewErr(ctx, "secrets: nil reference")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.static {
+		return r.value, nil
+	}
+	if r.value != "" && r.ttl >= 0 && time.Since(r.fetchedAt) < r.ttl {
+		return r.value, nil
+	}
+	if r.provider == nil {
+		return "", calque.NewErr(ctx, "secrets: reference has no provider")
+	}
+
+	value, err := r.provider.GetSecret(ctx, r.name)
+	if err != nil {
+		r.value = ""
+		return "", calque.WrapErr(ctx, err, "secrets: failed to resolve "+r.name)
+	}
+	r.value = value
+	r.fetchedAt = time.Now()
+	return value, nil
+}
+
+// Invalidate drops the cached value so the next Resolve re-fetches it.
+//
+// Call this when a backend rejects a credential (e.g. HTTP 401) to pick up a
+// rotated secret immediately.
+func (r *Ref) Invalidate() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.static {
+		r.value = ""
+	}
+}
+
+// String returns a redacted description of the reference.
+func (r *Ref) String() string {
+	if r == nil {
+		return "secrets.Ref(nil)"
+	}
+	return "secrets.Ref(" + r.name + ")"
+}
+
+// GoString returns a redacted description for %#v.
+func (r *Ref) GoString() string {
+	return r.String()
+}
+
+// MarshalJSON encodes the reference as a redacted string.
+func (r *Ref) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Redacted)
+}
+
+// LogValue implements slog.LogValuer so secrets never reach structured logs.
+func (r *Ref) LogValue() slog.Value {
+	return slog.StringValue(Redacted)
+}
+
+// ResolveOr returns the value of ref if set, or fallback otherwise.
+//
+// Useful for configs that accept either a raw string or a Ref during migration.
+func ResolveOr(ctx context.Context, ref *Ref, fallback string) (string, error) {
+	if ref == nil {
+		return fallback, nil
+	}
+	return ref.Resolve(ctx)
+}
+
+// splitField splits "path#field" into its parts
+func splitField(name string) (path, field string) {
+	if idx := strings.LastIndex(name, "#"); idx >= 0 {
+		return name[:idx], name[idx+1:]
+	}
+	return name, ""
+}
+
+// selectField extracts a field from a JSON object secret, or the single value if field is empty
+func selectField(ctx context.Context, name string, data map[string]any, field string) (string, error) {
+	if field == "" {
+		if len(data) == 1 {
+			for _, v := range data {
+				if s, ok := v.(string); ok {
+					return s, nil
+				}
+			}
+		}
+		return "", calque.NewErr(ctx, "secrets: "+name+" has multiple fields, select one with \"#field\"")
+	}
+	v, ok := data[field]
+	if !ok {
+		return "", calque.WrapErr(ctx, ErrNotFound, "secrets: field "+field+" in "+name)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", calque.NewErr(ctx, "secrets: field "+field+" in "+name+" is not a string")
+	}
+	return s, nil
+}
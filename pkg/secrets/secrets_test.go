@@ -0,0 +1,139 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func countingProvider(values ...string) (Provider, *atomic.Int32) {
+	var calls atomic.Int32
+	return ProviderFunc(func(_ context.Context, name string) (string, error) {
+		n := int(calls.Add(1)) - 1
+		if n >= len(values) {
+			return "", fmt.Errorf("no value for %s", name)
+		}
+		return values[n], nil
+	}), &calls
+}
+
+func TestRef_Resolve(t *testing.T) {
+	provider, calls := countingProvider("v1", "v2")
+	ref := NewRef(provider, "api-key")
+	ctx := context.Background()
+
+	for range 3 {
+		value, err := ref.Resolve(ctx)
+		if err != nil || value != "v1" {
+			t.Fatalf("expected cached v1, got %q / %v", value, err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected 1 provider call, got %d", calls.Load())
+	}
+
+	// Rotation: invalidating picks up the new value
+	ref.Invalidate()
+	if value, _ := ref.Resolve(ctx); value != "v2" {
+		t.Errorf("expected rotated v2, got %q", value)
+	}
+}
+
+func TestRef_TTL(t *testing.T) {
+	provider, calls := countingProvider("a", "b")
+	ref := NewRef(provider, "key", WithTTL(time.Millisecond))
+	ctx := context.Background()
+
+	first, _ := ref.Resolve(ctx)
+	time.Sleep(5 * time.Millisecond)
+	second, _ := ref.Resolve(ctx)
+	if first != "a" || second != "b" || calls.Load() != 2 {
+		t.Errorf("expected refresh after TTL, got %q then %q (%d calls)", first, second, calls.Load())
+	}
+
+	// A failed refresh returns an error instead of the stale value
+	time.Sleep(5 * time.Millisecond)
+	if _, err := ref.Resolve(ctx); err == nil {
+		t.Error("expected error when refresh fails")
+	}
+}
+
+func TestRef_Redaction(t *testing.T) {
+	ref := Static("sk-super-secret")
+
+	outputs := map[string]string{
+		"%v":  fmt.Sprintf("%v", ref),
+		"%+v": fmt.Sprintf("%+v", ref),
+		"%#v": fmt.Sprintf("%#v", ref),
+		"%s":  fmt.Sprintf("%s", ref),
+	}
+	data, _ := json.Marshal(struct{ Key *Ref }{ref})
+	outputs["json"] = string(data)
+
+	var logBuf strings.Builder
+	slog.New(slog.NewTextHandler(&logBuf, nil)).Info("config", "key", ref)
+	outputs["slog"] = logBuf.String()
+
+	for format, out := range outputs {
+		if strings.Contains(out, "sk-super-secret") {
+			t.Errorf("%s leaked secret: %s", format, out)
+		}
+	}
+
+	if value, _ := ref.Resolve(context.Background()); value != "sk-super-secret" {
+		t.Errorf("expected static value, got %q", value)
+	}
+}
+
+func TestResolveOr(t *testing.T) {
+	ctx := context.Background()
+	if v, _ := ResolveOr(ctx, nil, "fallback"); v != "fallback" {
+		t.Errorf("expected fallback, got %q", v)
+	}
+	if v, _ := ResolveOr(ctx, Static("ref"), "fallback"); v != "ref" {
+		t.Errorf("expected ref value, got %q", v)
+	}
+	var nilRef *Ref
+	if _, err := nilRef.Resolve(ctx); err == nil {
+		t.Error("expected error resolving nil ref")
+	}
+}
+
+func TestSelectField(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		data    map[string]any
+		field   string
+		want    string
+		wantErr error
+	}{
+		{"named field", map[string]any{"a": "1", "b": "2"}, "b", "2", nil},
+		{"single field", map[string]any{"only": "x"}, "", "x", nil},
+		{"missing field", map[string]any{"a": "1"}, "b", "", ErrNotFound},
+		{"ambiguous", map[string]any{"a": "1", "b": "2"}, "", "", errors.New("")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectField(ctx, "secret", tt.data, tt.field)
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				if errors.Is(tt.wantErr, ErrNotFound) && !errors.Is(err, ErrNotFound) {
+					t.Errorf("expected ErrNotFound, got %v", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("expected %q, got %q / %v", tt.want, got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// VaultConfig configures the HashiCorp Vault provider.
+type VaultConfig struct {
+	// Address is the Vault server URL. Defaults to VAULT_ADDR.
+	Address string
+
+	// Token authenticates requests. Defaults to VAULT_TOKEN.
+	// Use TokenRef instead to rotate the Vault token itself.
+	Token string
+
+	// TokenRef resolves the Vault token (optional, takes precedence over Token)
+	TokenRef *Ref
+
+	// Mount is the KV v2 secrets engine mount path. Default: "secret"
+	Mount string
+
+	// Namespace is the Vault Enterprise namespace (optional). Defaults to VAULT_NAMESPACE.
+	Namespace string
+
+	// HTTPClient is used for requests. Default: client with 10s timeout
+	HTTPClient *http.Client
+}
+
+// VaultProvider reads secrets from a Vault KV version 2 engine over the HTTP API.
+//
+// Names have the form "path#field", e.g. "calque/openai#api_key" reads the
+// api_key field of secret/data/calque/openai. The field may be omitted when the
+// secret has exactly one key.
+type VaultProvider struct {
+	config VaultConfig
+}
+
+// NewVaultProvider creates a Vault KV v2 provider.
+//
+// Example:
+//
+//	vault, err := secrets.NewVaultProvider(secrets.VaultConfig{
+//		Address: "https://vault.internal:8200",
+//		Mount:   "kv",
+//	})
+//	key := secrets.NewRef(vault, "calque/openai#api_key")
+func NewVaultProvider(config VaultConfig) (*VaultProvider, error) {
+	if config.Address == "" {
+		config.Address = os.Getenv("VAULT_ADDR")
+	}
+	if config.Token == "" && config.TokenRef == nil {
+		config.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if config.Namespace == "" {
+		config.Namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	if config.Mount == "" {
+		config.Mount = "secret"
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	ctx := context.Background()
+	if config.Address == "" {
+		return nil, calque.NewErr(ctx, "secrets: vault address is required (VaultConfig.Address or VAULT_ADDR)")
+	}
+	if config.Token == "" && config.TokenRef == nil {
+		return nil, calque.NewErr(ctx, "secrets: vault token is required (VaultConfig.Token, TokenRef or VAULT_TOKEN)")
+	}
+	return &VaultProvider{config: config}, nil
+}
+
+// GetSecret reads a field from a KV v2 secret.
+func (p *VaultProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	path, field := splitField(name)
+
+	token, err := ResolveOr(ctx, p.config.TokenRef, p.config.Token)
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := strings.TrimSuffix(p.config.Address, "/") + "/v1/" +
+		strings.Trim(p.config.Mount, "/") + "/data/" + escapePath(strings.Trim(path, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", calque.WrapErr(ctx, err, "secrets: failed to create vault request")
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if p.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.config.Namespace)
+	}
+
+	resp, err := p.config.HTTPClient.Do(req)
+	if err != nil {
+		return "", calque.WrapErr(ctx, err, "secrets: vault request failed")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", calque.WrapErr(ctx, ErrNotFound, "secrets: vault path "+path)
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", calque.NewErr(ctx, fmt.Sprintf("secrets: vault returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body))))
+	}
+
+	var payload struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", calque.WrapErr(ctx, err, "secrets: failed to decode vault response")
+	}
+	return selectField(ctx, name, payload.Data.Data, field)
+}
+
+// escapePath URL-escapes each segment of a slash-separated path
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
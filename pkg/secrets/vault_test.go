@@ -0,0 +1,53 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVaultProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/kv/data/calque/openai" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"api_key":"sk-vault","org":"o-1"},"metadata":{"version":3}}}`))
+	}))
+	defer srv.Close()
+
+	p, err := NewVaultProvider(VaultConfig{Address: srv.URL, Token: "root", Mount: "kv"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	if v, err := p.GetSecret(ctx, "calque/openai#api_key"); err != nil || v != "sk-vault" {
+		t.Errorf("expected sk-vault, got %q / %v", v, err)
+	}
+	if _, err := p.GetSecret(ctx, "calque/missing#api_key"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	denied, _ := NewVaultProvider(VaultConfig{Address: srv.URL, TokenRef: Static("wrong"), Mount: "kv"})
+	if _, err := denied.GetSecret(ctx, "calque/openai#api_key"); err == nil {
+		t.Error("expected permission error")
+	}
+}
+
+func TestNewVaultProvider_Validation(t *testing.T) {
+	t.Setenv("VAULT_ADDR", "")
+	t.Setenv("VAULT_TOKEN", "")
+	if _, err := NewVaultProvider(VaultConfig{}); err == nil {
+		t.Error("expected error without address")
+	}
+	if _, err := NewVaultProvider(VaultConfig{Address: "http://vault"}); err == nil {
+		t.Error("expected error without token")
+	}
+}
@@ -0,0 +1,323 @@
+// Package secure provides encryption-at-rest for calque memory and cache stores
+// and encryption of flow streams.
+//
+// WrapStore and WrapCacheStore wrap an existing store so every value is
+// encrypted with envelope encryption before it reaches the backend: each value
+// gets a fresh AES-256-GCM data key, and the data key is itself encrypted
+// ("wrapped") with a key-encryption key from a KeyProvider. Values are bound to
+// their storage key, so ciphertext copied between keys fails to decrypt.
+//
+// Key rotation: add a new key to the Keyring with Rotate. New writes use it
+// immediately, existing values stay readable with their original key, and
+// Store.Rewrap re-wraps stored data keys so old keys can be retired.
+//
+// Example:
+//
+//	key, _ := secure.GenerateKey()
+//	keys, _ := secure.NewKeyring("2025-01", key)
+//
+//	store := secure.WrapStore(redisStore, keys)
+//	mem := memory.NewConversationWithStore(store)
+package secure
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// KeySize is the size in bytes of generated keys (AES-256).
+const KeySize = 32
+
+// Errors returned when values can't be decrypted.
+var (
+	// ErrUnknownKey is returned when a value was encrypted with a key the provider doesn't have.
+	ErrUnknownKey = errors.New("unknown encryption key")
+	// ErrInvalidCiphertext is returned when a stored value is not a valid envelope or fails authentication.
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// envelope format: magic (3) | version (1) | key id length (1) | key id |
+// wrapped data key length (2) | wrapped data key | sealed value.
+// Sealed data is nonce || AES-GCM ciphertext.
+var envelopeMagic = []byte("CQE")
+
+const envelopeVersion = 1
+
+// KeyProvider supplies key-encryption keys.
+//
+// Implementations must be safe for concurrent use. Keys must be 16, 24 or 32
+// bytes (AES-128, AES-192 or AES-256).
+type KeyProvider interface {
+	// PrimaryKey returns the ID and value of the key used to encrypt new values.
+	PrimaryKey() (id string, key []byte, err error)
+	// Key returns the key with the given ID, for decrypting existing values.
+	Key(id string) ([]byte, error)
+}
+
+// Keyring is an in-memory KeyProvider holding a primary key and retired keys.
+type Keyring struct {
+	mu      sync.RWMutex
+	primary string
+	keys    map[string][]byte
+}
+
+// NewKeyring creates a keyring with a single primary key.
+//
+// Example:
+//
+//	keys, err := secure.NewKeyring("v1", key)
+func NewKeyring(id string, key []byte) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string][]byte)}
+	if err := k.Rotate(id, key); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Rotate adds a key and makes it the primary. Previous keys remain available for decryption.
+//
+// Example:
+//
+//	newKey, _ := secure.GenerateKey()
+//	keys.Rotate("v2", newKey)
+//	store.Rewrap() // optional: re-wrap existing values with v2
+func (k *Keyring) Rotate(id string, key []byte) error {
+	ctx := context.Background()
+	if id == "" || len(id) > 255 {
+		return calque.NewErr(ctx, "secure: key id must be 1-255 bytes")
+	}
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[id] = append([]byte(nil), key...)
+	k.primary = id
+	return nil
+}
+
+// Remove retires a key. Values still encrypted with it can no longer be read.
+// The primary key can't be removed.
+func (k *Keyring) Remove(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if id == k.primary {
+		return calque.NewErr(context.Background(), "secure: cannot remove primary key "+id)
+	}
+	delete(k.keys, id)
+	return nil
+}
+
+// PrimaryKey returns the current primary key.
+func (k *Keyring) PrimaryKey() (string, []byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.primary, k.keys[k.primary], nil
+}
+
+// Key returns the key with the given ID.
+func (k *Keyring) Key(id string) ([]byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, calque.WrapErr(context.Background(), ErrUnknownKey, "secure: key "+id)
+	}
+	return key, nil
+}
+
+// GenerateKey returns a random AES-256 key.
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, calque.WrapErr(context.Background(), err, "secure: failed to generate key")
+	}
+	return key, nil
+}
+
+// validateKey checks that a key has a valid AES length
+func validateKey(key []byte) error {
+	switch len(key) {
+	case 16, 24, 32:
+		return nil
+	default:
+		return calque.NewErr(context.Background(), "secure: key must be 16, 24 or 32 bytes")
+	}
+}
+
+// seal encrypts plaintext bound to name using a fresh data key wrapped with the primary key
+func seal(keys KeyProvider, name string, plaintext []byte) ([]byte, error) {
+	ctx := context.Background()
+
+	kid, kek, err := keys.PrimaryKey()
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "secure: failed to get primary key")
+	}
+	if len(kid) == 0 || len(kid) > 255 {
+		return nil, calque.NewErr(ctx, "secure: key id must be 1-255 bytes")
+	}
+
+	dek, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := gcmSeal(kek, dek, []byte(kid))
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := gcmSeal(dek, plaintext, []byte(name))
+	if err != nil {
+		return nil, err
+	}
+
+	return (&envelope{kid: kid, wrapped: wrapped, sealed: sealed}).encode(), nil
+}
+
+// envelope is a parsed encrypted value
+type envelope struct {
+	kid     string
+	wrapped []byte
+	sealed  []byte
+}
+
+// encode serializes the envelope
+func (e *envelope) encode() []byte {
+	out := make([]byte, 0, len(envelopeMagic)+2+len(e.kid)+2+len(e.wrapped)+len(e.sealed))
+	out = append(out, envelopeMagic...)
+	out = append(out, envelopeVersion, byte(len(e.kid)))
+	out = append(out, e.kid...)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(e.wrapped)))
+	out = append(out, e.wrapped...)
+	out = append(out, e.sealed...)
+	return out
+}
+
+// parseEnvelope splits an encrypted value into its parts
+func parseEnvelope(data []byte) (*envelope, error) {
+	ctx := context.Background()
+	header := len(envelopeMagic) + 2
+	if len(data) < header || string(data[:len(envelopeMagic)]) != string(envelopeMagic) {
+		return nil, calque.WrapErr(ctx, ErrInvalidCiphertext, "secure: value is not encrypted")
+	}
+	if data[len(envelopeMagic)] != envelopeVersion {
+		return nil, calque.WrapErr(ctx, ErrInvalidCiphertext, "secure: unsupported envelope version")
+	}
+
+	kidLen := int(data[len(envelopeMagic)+1])
+	rest := data[header:]
+	if len(rest) < kidLen+2 {
+		return nil, calque.WrapErr(ctx, ErrInvalidCiphertext, "secure: truncated envelope")
+	}
+	env := &envelope{kid: string(rest[:kidLen])}
+	rest = rest[kidLen:]
+
+	wrappedLen := int(binary.BigEndian.Uint16(rest))
+	rest = rest[2:]
+	if len(rest) < wrappedLen {
+		return nil, calque.WrapErr(ctx, ErrInvalidCiphertext, "secure: truncated envelope")
+	}
+	env.wrapped = rest[:wrappedLen]
+	env.sealed = rest[wrappedLen:]
+	return env, nil
+}
+
+// open decrypts a value sealed for name
+func open(keys KeyProvider, name string, data []byte) ([]byte, error) {
+	env, err := parseEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	dek, err := unwrapKey(keys, env)
+	if err != nil {
+		return nil, err
+	}
+	return gcmOpen(dek, env.sealed, []byte(name))
+}
+
+// rewrap re-encrypts a value's data key with the primary key, leaving the value itself untouched.
+// Returns nil when the value already uses the primary key.
+func rewrap(keys KeyProvider, data []byte) ([]byte, error) {
+	env, err := parseEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	kid, kek, err := keys.PrimaryKey()
+	if err != nil {
+		return nil, calque.WrapErr(context.Background(), err, "secure: failed to get primary key")
+	}
+	if env.kid == kid {
+		return nil, nil
+	}
+
+	dek, err := unwrapKey(keys, env)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := gcmSeal(kek, dek, []byte(kid))
+	if err != nil {
+		return nil, err
+	}
+
+	return (&envelope{kid: kid, wrapped: wrapped, sealed: env.sealed}).encode(), nil
+}
+
+// unwrapKey decrypts the envelope's data key with its key-encryption key
+func unwrapKey(keys KeyProvider, env *envelope) ([]byte, error) {
+	kek, err := keys.Key(env.kid)
+	if err != nil {
+		return nil, err
+	}
+	return gcmOpen(kek, env.wrapped, []byte(env.kid))
+}
+
+// gcmSeal encrypts plaintext with AES-GCM, returning nonce || ciphertext
+func gcmSeal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, calque.WrapErr(context.Background(), err, "secure: failed to generate nonce")
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// gcmOpen decrypts nonce || ciphertext produced by gcmSeal
+func gcmOpen(key, data, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, calque.WrapErr(context.Background(), ErrInvalidCiphertext, "secure: truncated ciphertext")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], aad)
+	if err != nil {
+		return nil, calque.WrapErr(context.Background(), ErrInvalidCiphertext, "secure: decryption failed")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, calque.WrapErr(context.Background(), err, "secure: invalid key")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, calque.WrapErr(context.Background(), err, "secure: failed to create cipher")
+	}
+	return gcm, nil
+}
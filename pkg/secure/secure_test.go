@@ -0,0 +1,138 @@
+package secure
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func newTestKeyring(t *testing.T, id string) *Keyring {
+	t.Helper()
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	keys, err := NewKeyring(id, key)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return keys
+}
+
+func TestNewKeyring_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		key     []byte
+		wantErr bool
+	}{
+		{"aes-128", "v1", make([]byte, 16), false},
+		{"aes-256", "v1", make([]byte, 32), false},
+		{"short key", "v1", make([]byte, 10), true},
+		{"empty id", "", make([]byte, 32), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyring(tt.id, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewKeyring() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	keys := newTestKeyring(t, "v1")
+	plaintext := []byte("the quick brown fox")
+
+	sealed, err := seal(keys, "user1", plaintext)
+	if err != nil {
+		t.Fatalf("seal() error = %v", err)
+	}
+	if bytes.Contains(sealed, plaintext) {
+		t.Error("sealed value contains plaintext")
+	}
+
+	got, err := open(keys, "user1", sealed)
+	if err != nil {
+		t.Fatalf("open() error = %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("open() = %q, want %q", got, plaintext)
+	}
+
+	t.Run("bound to storage key", func(t *testing.T) {
+		if _, err := open(keys, "user2", sealed); !errors.Is(err, ErrInvalidCiphertext) {
+			t.Errorf("open() with other key error = %v, want ErrInvalidCiphertext", err)
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		tampered := append([]byte(nil), sealed...)
+		tampered[len(tampered)-1] ^= 0xff
+		if _, err := open(keys, "user1", tampered); !errors.Is(err, ErrInvalidCiphertext) {
+			t.Errorf("open() tampered error = %v, want ErrInvalidCiphertext", err)
+		}
+	})
+
+	t.Run("plaintext value", func(t *testing.T) {
+		if _, err := open(keys, "user1", plaintext); !errors.Is(err, ErrInvalidCiphertext) {
+			t.Errorf("open() plaintext error = %v, want ErrInvalidCiphertext", err)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		if _, err := open(keys, "user1", sealed[:8]); !errors.Is(err, ErrInvalidCiphertext) {
+			t.Errorf("open() truncated error = %v, want ErrInvalidCiphertext", err)
+		}
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		other := newTestKeyring(t, "other")
+		if _, err := open(other, "user1", sealed); !errors.Is(err, ErrUnknownKey) {
+			t.Errorf("open() error = %v, want ErrUnknownKey", err)
+		}
+	})
+}
+
+func TestKeyring_Rotate(t *testing.T) {
+	keys := newTestKeyring(t, "v1")
+	old, err := seal(keys, "k", []byte("old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newKey, _ := GenerateKey()
+	if err := keys.Rotate("v2", newKey); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if id, _, _ := keys.PrimaryKey(); id != "v2" {
+		t.Errorf("PrimaryKey() id = %q, want v2", id)
+	}
+
+	if got, err := open(keys, "k", old); err != nil || string(got) != "old" {
+		t.Errorf("open() old value = %q, %v", got, err)
+	}
+
+	rewrapped, err := rewrap(keys, old)
+	if err != nil {
+		t.Fatalf("rewrap() error = %v", err)
+	}
+	if again, _ := rewrap(keys, rewrapped); again != nil {
+		t.Error("rewrap() of current value should be a no-op")
+	}
+
+	if err := keys.Remove("v2"); err == nil {
+		t.Error("Remove() of primary key should fail")
+	}
+	if err := keys.Remove("v1"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := open(keys, "k", old); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("open() with removed key error = %v, want ErrUnknownKey", err)
+	}
+	if got, err := open(keys, "k", rewrapped); err != nil || string(got) != "old" {
+		t.Errorf("open() rewrapped value = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,146 @@
+package secure
+
+import (
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/middleware/cache"
+	"github.com/calque-ai/go-calque/pkg/middleware/memory"
+)
+
+// Store is a memory.Store that encrypts values before writing them to an underlying store.
+type Store struct {
+	store memory.Store
+	keys  KeyProvider
+}
+
+// WrapStore wraps a memory store so conversation and context history is encrypted at rest.
+//
+// Keys (the conversation IDs) are stored in plaintext; only values are encrypted.
+//
+// Example:
+//
+//	store := secure.WrapStore(memory.NewInMemoryStore(), keys)
+//	mem := memory.NewConversationWithStore(store)
+func WrapStore(store memory.Store, keys KeyProvider) *Store {
+	return &Store{store: store, keys: keys}
+}
+
+// Get retrieves and decrypts data for a key
+func (s *Store) Get(key string) ([]byte, error) {
+	data, err := s.store.Get(key)
+	if err != nil || data == nil {
+		return data, err
+	}
+	return open(s.keys, key, data)
+}
+
+// Set encrypts and stores data for a key
+func (s *Store) Set(key string, value []byte) error {
+	data, err := seal(s.keys, key, value)
+	if err != nil {
+		return err
+	}
+	return s.store.Set(key, data)
+}
+
+// Delete removes data for a key
+func (s *Store) Delete(key string) error {
+	return s.store.Delete(key)
+}
+
+// List returns all keys
+func (s *Store) List() []string {
+	return s.store.List()
+}
+
+// Exists checks if a key exists
+func (s *Store) Exists(key string) bool {
+	return s.store.Exists(key)
+}
+
+// Rewrap re-encrypts the data keys of all stored values with the primary key.
+//
+// Call after Keyring.Rotate to migrate existing values before removing the old
+// key. Values themselves are not re-encrypted, so this is cheap even for long
+// histories. Returns the number of values rewrapped.
+func (s *Store) Rewrap() (int, error) {
+	count := 0
+	for _, key := range s.store.List() {
+		data, err := s.store.Get(key)
+		if err != nil {
+			return count, err
+		}
+		if data == nil {
+			continue
+		}
+		rewrapped, err := rewrap(s.keys, data)
+		if err != nil {
+			return count, err
+		}
+		if rewrapped == nil {
+			continue
+		}
+		if err := s.store.Set(key, rewrapped); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// CacheStore is a cache.Store that encrypts values before writing them to an underlying store.
+type CacheStore struct {
+	store cache.Store
+	keys  KeyProvider
+}
+
+// WrapCacheStore wraps a cache store so cached completions are encrypted at rest.
+//
+// Cache entries expire on their own, so there is no Rewrap: once the TTL of
+// entries written before a rotation has passed, the old key can be removed.
+//
+// Example:
+//
+//	store := secure.WrapCacheStore(cache.NewInMemoryStore(), keys)
+//	c := cache.NewCacheWithStore(store)
+func WrapCacheStore(store cache.Store, keys KeyProvider) *CacheStore {
+	return &CacheStore{store: store, keys: keys}
+}
+
+// Get retrieves and decrypts data for a key
+func (s *CacheStore) Get(key string) ([]byte, error) {
+	data, err := s.store.Get(key)
+	if err != nil || data == nil {
+		return data, err
+	}
+	return open(s.keys, key, data)
+}
+
+// Set encrypts and stores data for a key with TTL
+func (s *CacheStore) Set(key string, value []byte, ttl time.Duration) error {
+	data, err := seal(s.keys, key, value)
+	if err != nil {
+		return err
+	}
+	return s.store.Set(key, data, ttl)
+}
+
+// Delete removes data for a key
+func (s *CacheStore) Delete(key string) error {
+	return s.store.Delete(key)
+}
+
+// Clear removes all cached data
+func (s *CacheStore) Clear() error {
+	return s.store.Clear()
+}
+
+// Exists checks if a key exists and hasn't expired
+func (s *CacheStore) Exists(key string) bool {
+	return s.store.Exists(key)
+}
+
+// List returns all non-expired keys
+func (s *CacheStore) List() []string {
+	return s.store.List()
+}
@@ -0,0 +1,112 @@
+package secure
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/cache"
+	"github.com/calque-ai/go-calque/pkg/middleware/memory"
+)
+
+func TestStore_Conversation(t *testing.T) {
+	keys := newTestKeyring(t, "v1")
+	backend := memory.NewInMemoryStore()
+	conv := memory.NewConversationWithStore(WrapStore(backend, keys))
+
+	var out string
+	if err := calque.NewFlow().Use(conv.Input("user1")).Run(context.Background(), "my secret question", &out); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	raw, _ := backend.Get("user1")
+	if raw == nil || bytes.Contains(raw, []byte("secret question")) {
+		t.Errorf("backend value not encrypted: %q", raw)
+	}
+
+	count, exists, err := conv.Info(context.Background(), "user1")
+	if err != nil || !exists || count != 1 {
+		t.Errorf("Info() = %d, %v, %v; want 1, true, nil", count, exists, err)
+	}
+}
+
+func TestStore_Operations(t *testing.T) {
+	keys := newTestKeyring(t, "v1")
+	store := WrapStore(memory.NewInMemoryStore(), keys)
+
+	if got, err := store.Get("missing"); got != nil || err != nil {
+		t.Errorf("Get(missing) = %q, %v; want nil, nil", got, err)
+	}
+
+	if err := store.Set("a", []byte("alpha")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got, _ := store.Get("a"); string(got) != "alpha" {
+		t.Errorf("Get() = %q, want alpha", got)
+	}
+	if !store.Exists("a") || len(store.List()) != 1 {
+		t.Error("Expected key a to exist")
+	}
+	if err := store.Delete("a"); err != nil || store.Exists("a") {
+		t.Errorf("Delete() error = %v, exists = %v", err, store.Exists("a"))
+	}
+}
+
+func TestStore_Rewrap(t *testing.T) {
+	keys := newTestKeyring(t, "v1")
+	store := WrapStore(memory.NewInMemoryStore(), keys)
+	for _, k := range []string{"a", "b"} {
+		if err := store.Set(k, []byte("value-"+k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	newKey, _ := GenerateKey()
+	_ = keys.Rotate("v2", newKey)
+	if err := store.Set("c", []byte("value-c")); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := store.Rewrap()
+	if err != nil {
+		t.Fatalf("Rewrap() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Rewrap() = %d, want 2", n)
+	}
+
+	_ = keys.Remove("v1")
+	for _, k := range []string{"a", "b", "c"} {
+		if got, err := store.Get(k); err != nil || string(got) != "value-"+k {
+			t.Errorf("Get(%s) = %q, %v", k, got, err)
+		}
+	}
+}
+
+func TestCacheStore(t *testing.T) {
+	keys := newTestKeyring(t, "v1")
+	backend := cache.NewInMemoryStore()
+	store := WrapCacheStore(backend, keys)
+
+	if err := store.Set("prompt-hash", []byte("cached completion"), time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	raw, _ := backend.Get("prompt-hash")
+	if bytes.Contains(raw, []byte("cached completion")) {
+		t.Errorf("backend value not encrypted: %q", raw)
+	}
+
+	got, err := store.Get("prompt-hash")
+	if err != nil || string(got) != "cached completion" {
+		t.Errorf("Get() = %q, %v", got, err)
+	}
+	if !store.Exists("prompt-hash") || len(store.List()) != 1 {
+		t.Error("Expected cached key to exist")
+	}
+	if err := store.Clear(); err != nil || store.Exists("prompt-hash") {
+		t.Errorf("Clear() error = %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package secure
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+// stream format: magic (3) | version (1) | key id length (1) | key id |
+// wrapped data key length (2) | wrapped data key | nonce prefix (7), then
+// segments of final flag (1) | ciphertext length (4) | AES-GCM ciphertext.
+// Segment nonces are the prefix, a big-endian segment counter and the final
+// flag, so reordered, dropped or truncated segments fail to decrypt.
+var streamMagic = []byte("CQS")
+
+const (
+	streamVersion     = 1
+	streamPrefixSize  = 7
+	streamSegmentSize = 64 * 1024 // largest plaintext per segment
+	gcmTagSize        = 16
+)
+
+// Encrypt creates a handler that encrypts the stream for transit or storage.
+//
+// Input: any bytes (streaming)
+// Output: encrypted stream, readable only by Decrypt with the same keys
+// Behavior: STREAMING - each input chunk (up to 64 KiB) becomes an
+// authenticated segment as soon as it is read
+//
+// Each stream gets a fresh AES-256-GCM data key wrapped with the primary key
+// of keys, as for WrapStore, so keys can be rotated while encrypted payloads
+// sit in queues. Decrypt rejects streams that were modified, reordered or cut short.
+//
+// Example:
+//
+//	// Producer: never put plaintext on the queue
+//	producer := calque.NewFlow().Use(secure.Encrypt(keys)).Use(queuePublisher)
+//
+//	// Consumer
+//	consumer := calque.NewFlow().Use(secure.Decrypt(keys)).Use(ai.Agent(client))
+func Encrypt(keys KeyProvider) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		ctx := req.Context
+		kid, kek, err := keys.PrimaryKey()
+		if err != nil {
+			return calque.WrapErr(ctx, err, "secure: failed to get primary key")
+		}
+		if len(kid) == 0 || len(kid) > 255 {
+			return calque.NewErr(ctx, "secure: key id must be 1-255 bytes")
+		}
+		dek, err := GenerateKey()
+		if err != nil {
+			return err
+		}
+		wrapped, err := gcmSeal(kek, dek, []byte(kid))
+		if err != nil {
+			return err
+		}
+		gcm, err := newGCM(dek)
+		if err != nil {
+			return err
+		}
+
+		header := append([]byte(nil), streamMagic...)
+		header = append(header, streamVersion, byte(len(kid)))
+		header = append(header, kid...)
+		header = binary.BigEndian.AppendUint16(header, uint16(len(wrapped)))
+		header = append(header, wrapped...)
+		prefix := make([]byte, streamPrefixSize)
+		if _, err := rand.Read(prefix); err != nil {
+			return calque.WrapErr(ctx, err, "secure: failed to generate nonce")
+		}
+		header = append(header, prefix...)
+		if _, err := res.Data.Write(header); err != nil {
+			return err
+		}
+
+		buf := make([]byte, streamSegmentSize)
+		var counter uint32
+		writeSegment := func(plaintext []byte, final bool) error {
+			ciphertext := gcm.Seal(nil, segmentNonce(prefix, counter, final), plaintext, nil)
+			segment := make([]byte, 5, 5+len(ciphertext))
+			if final {
+				segment[0] = 1
+			}
+			binary.BigEndian.PutUint32(segment[1:5], uint32(len(ciphertext)))
+			if _, err := res.Data.Write(append(segment, ciphertext...)); err != nil {
+				return err
+			}
+			if counter == math.MaxUint32 {
+				return calque.NewErr(ctx, "secure: stream too long")
+			}
+			counter++
+			return nil
+		}
+
+		for {
+			n, readErr := req.Data.Read(buf)
+			if n > 0 {
+				if err := writeSegment(buf[:n], false); err != nil {
+					return err
+				}
+			}
+			if readErr == io.EOF {
+				return writeSegment(nil, true)
+			}
+			if readErr != nil {
+				return readErr
+			}
+		}
+	})
+}
+
+// Decrypt creates a handler that decrypts a stream produced by Encrypt.
+//
+// Input: encrypted stream
+// Output: original bytes
+// Behavior: STREAMING - each segment is written once it is authenticated
+//
+// Fails with ErrInvalidCiphertext when the stream was tampered with or ends
+// before its final segment, and ErrUnknownKey when its key was removed from
+// keys. Output already written before a failure is authentic but incomplete.
+//
+// Example:
+//
+//	flow := calque.NewFlow().Use(secure.Decrypt(keys)).Use(handler)
+func Decrypt(keys KeyProvider) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		ctx := req.Context
+		r := bufio.NewReader(req.Data)
+
+		fixed := make([]byte, len(streamMagic)+2)
+		if _, err := io.ReadFull(r, fixed); err != nil || string(fixed[:len(streamMagic)]) != string(streamMagic) {
+			return invalidStream(ctx, err, "stream is not encrypted")
+		}
+		if fixed[len(streamMagic)] != streamVersion {
+			return invalidStream(ctx, nil, "unsupported stream version")
+		}
+		kid := make([]byte, fixed[len(streamMagic)+1])
+		var wrappedLen uint16
+		if _, err := io.ReadFull(r, kid); err != nil {
+			return invalidStream(ctx, err, "truncated stream header")
+		}
+		if err := binary.Read(r, binary.BigEndian, &wrappedLen); err != nil {
+			return invalidStream(ctx, err, "truncated stream header")
+		}
+		wrapped := make([]byte, wrappedLen)
+		prefix := make([]byte, streamPrefixSize)
+		if _, err := io.ReadFull(r, wrapped); err != nil {
+			return invalidStream(ctx, err, "truncated stream header")
+		}
+		if _, err := io.ReadFull(r, prefix); err != nil {
+			return invalidStream(ctx, err, "truncated stream header")
+		}
+
+		dek, err := unwrapKey(keys, &envelope{kid: string(kid), wrapped: wrapped})
+		if err != nil {
+			return err
+		}
+		gcm, err := newGCM(dek)
+		if err != nil {
+			return err
+		}
+
+		segHeader := make([]byte, 5)
+		ciphertext := make([]byte, 0, streamSegmentSize+gcmTagSize)
+		for counter := uint32(0); ; counter++ {
+			if _, err := io.ReadFull(r, segHeader); err != nil {
+				return invalidStream(ctx, err, "stream ended before its final segment")
+			}
+			final := segHeader[0] == 1
+			size := binary.BigEndian.Uint32(segHeader[1:5])
+			if segHeader[0] > 1 || size > streamSegmentSize+gcmTagSize {
+				return invalidStream(ctx, nil, "malformed segment")
+			}
+			ciphertext = ciphertext[:size]
+			if _, err := io.ReadFull(r, ciphertext); err != nil {
+				return invalidStream(ctx, err, "truncated segment")
+			}
+			plaintext, err := gcm.Open(ciphertext[:0], segmentNonce(prefix, counter, final), ciphertext, nil)
+			if err != nil {
+				return invalidStream(ctx, nil, "segment failed authentication")
+			}
+			if _, err := res.Data.Write(plaintext); err != nil {
+				return err
+			}
+			if final {
+				if _, err := r.ReadByte(); err != io.EOF {
+					return invalidStream(ctx, err, "data after final segment")
+				}
+				return nil
+			}
+		}
+	})
+}
+
+// segmentNonce builds the 12-byte nonce of a segment
+func segmentNonce(prefix []byte, counter uint32, final bool) []byte {
+	nonce := make([]byte, 0, streamPrefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+	if final {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// invalidStream reports a malformed stream, keeping read errors other than EOF visible
+func invalidStream(ctx context.Context, err error, msg string) error {
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return calque.WrapErr(ctx, err, "secure: "+msg)
+	}
+	return calque.WrapErr(ctx, ErrInvalidCiphertext, "secure: "+msg)
+}
@@ -0,0 +1,124 @@
+package secure
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+)
+
+func encryptStream(t *testing.T, keys KeyProvider, input string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	req := calque.NewRequest(context.Background(), strings.NewReader(input))
+	if err := Encrypt(keys).ServeFlow(req, calque.NewResponse(&buf)); err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func decryptStream(keys KeyProvider, data []byte) (string, error) {
+	var buf bytes.Buffer
+	req := calque.NewRequest(context.Background(), iotest.HalfReader(bytes.NewReader(data)))
+	err := Decrypt(keys).ServeFlow(req, calque.NewResponse(&buf))
+	return buf.String(), err
+}
+
+func TestEncryptDecryptStream(t *testing.T) {
+	keys := newTestKeyring(t, "v1")
+	inputs := map[string]string{
+		"empty":           "",
+		"short":           "patient record 42",
+		"multi segment":   strings.Repeat("sensitive payload ", 10000),
+		"binary-ish utf8": "héllo\x00world",
+	}
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			sealed := encryptStream(t, keys, input)
+			if input != "" && bytes.Contains(sealed, []byte(input[:min(len(input), 10)])) {
+				t.Error("ciphertext contains plaintext")
+			}
+			got, err := decryptStream(keys, sealed)
+			if err != nil {
+				t.Fatalf("Decrypt() error = %v", err)
+			}
+			if got != input {
+				t.Error("round trip changed the data")
+			}
+		})
+	}
+}
+
+func TestDecryptStreamTampering(t *testing.T) {
+	keys := newTestKeyring(t, "v1")
+	sealed := encryptStream(t, keys, strings.Repeat("x", 3*streamSegmentSize))
+	wrappedLen := 12 + KeySize + gcmTagSize // nonce, data key, tag
+	headerLen := len(streamMagic) + 2 + len("v1") + 2 + wrappedLen + streamPrefixSize
+	firstSegmentEnd := headerLen + 5 + streamSegmentSize + gcmTagSize
+
+	flipped := append([]byte(nil), sealed...)
+	flipped[headerLen+100] ^= 1
+
+	// swap the first two segments
+	segLen := 5 + streamSegmentSize + gcmTagSize
+	reordered := append([]byte(nil), sealed[:headerLen]...)
+	reordered = append(reordered, sealed[headerLen+segLen:headerLen+2*segLen]...)
+	reordered = append(reordered, sealed[headerLen:headerLen+segLen]...)
+	reordered = append(reordered, sealed[headerLen+2*segLen:]...)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "plaintext", data: []byte("not encrypted at all")},
+		{name: "flipped bit", data: flipped},
+		{name: "reordered segments", data: reordered},
+		{name: "truncated before final segment", data: sealed[:firstSegmentEnd]},
+		{name: "trailing data", data: append(append([]byte(nil), sealed...), 'x')},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decryptStream(keys, tt.data); !errors.Is(err, ErrInvalidCiphertext) {
+				t.Errorf("Decrypt() error = %v, want ErrInvalidCiphertext", err)
+			}
+		})
+	}
+}
+
+func TestDecryptStreamKeyRotation(t *testing.T) {
+	keys := newTestKeyring(t, "v1")
+	sealed := encryptStream(t, keys, "queued message")
+
+	key2, _ := GenerateKey()
+	if err := keys.Rotate("v2", key2); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := decryptStream(keys, sealed); err != nil || got != "queued message" {
+		t.Fatalf("Decrypt() after rotation = %q, %v", got, err)
+	}
+
+	if err := keys.Remove("v1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decryptStream(keys, sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt() with removed key error = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestEncryptInFlow(t *testing.T) {
+	keys := newTestKeyring(t, "v1")
+	var out string
+	flow := calque.NewFlow().Use(Encrypt(keys)).Use(Decrypt(keys))
+	if err := flow.Run(context.Background(), "end to end", &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != "end to end" {
+		t.Errorf("output = %q", out)
+	}
+}
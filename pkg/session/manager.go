@@ -0,0 +1,417 @@
+package session
+
+import (
+	"container/list"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/httpserver"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+)
+
+// DefaultTTL is the default idle time after which a session expires.
+const DefaultTTL = 30 * time.Minute
+
+// Header is the default HTTP header carrying the session ID.
+const Header = "X-Session-ID"
+
+// EndReason describes why a session ended.
+type EndReason string
+
+// Reasons passed to Config.OnExpire.
+const (
+	ReasonExpired EndReason = "expired" // idle for longer than the TTL
+	ReasonEvicted EndReason = "evicted" // least recently used when MaxSessions was reached
+	ReasonDeleted EndReason = "deleted" // removed with Manager.Delete
+)
+
+// Config holds configuration for a session Manager.
+type Config struct {
+	// TTL is the idle time after which a session expires; negative disables expiry. Default: DefaultTTL
+	TTL time.Duration
+	// MaxSessions caps live sessions, evicting the least recently used (0 = unlimited)
+	MaxSessions int
+	// Budget is the session-wide budget given to new sessions (zero = unlimited)
+	Budget ctrl.BudgetLimits
+	// Header is the HTTP header read and written by Manager.HTTP. Default: Header
+	Header string
+
+	// OnCreate is called after a session is created (optional)
+	OnCreate func(ctx context.Context, s *Session)
+	// OnExpire is called after a session ends for any EndReason (optional)
+	OnExpire func(s *Session, reason EndReason)
+
+	// Now returns the current time (default: time.Now); override in tests
+	Now func() time.Time
+}
+
+// Manager creates, looks up and expires sessions.
+//
+// Create it with NewManager. Sessions are kept in memory in least recently
+// used order; expired sessions are removed when looked up, by Sweep, or by
+// the background sweeper started with Start.
+type Manager struct {
+	config Config
+
+	mu       sync.Mutex
+	sessions map[string]*list.Element // values are *Session
+	lru      *list.List               // front is most recently used
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewManager creates a session manager.
+//
+// Example:
+//
+//	sessions := session.NewManager(session.Config{
+//		TTL:      time.Hour,
+//		OnExpire: func(s *session.Session, _ session.EndReason) { mem.Clear(s.MemoryKey) },
+//	})
+func NewManager(config ...Config) *Manager {
+	var cfg Config
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = DefaultTTL
+	}
+	if cfg.Header == "" {
+		cfg.Header = Header
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &Manager{
+		config:   cfg,
+		sessions: make(map[string]*list.Element),
+		lru:      list.New(),
+		stop:     make(chan struct{}),
+	}
+}
+
+// ended is a session removed from the manager whose hook has yet to run
+type ended struct {
+	session *Session
+	reason  EndReason
+}
+
+// GetOrCreate returns the live session with id, creating it if needed.
+//
+// An empty id creates a session with a random ID. The boolean reports whether
+// the session was created. Creating a session beyond MaxSessions evicts the
+// least recently used one. The id also becomes the session's MemoryKey, so
+// pass only IDs the server chose or authenticated, never one a client sent;
+// Manager.HTTP resolves client IDs with Get instead.
+//
+// Example:
+//
+//	s, created, err := sessions.GetOrCreate(ctx, "user:"+claims.Subject)
+func (m *Manager) GetOrCreate(ctx context.Context, id string) (*Session, bool, error) {
+	if id == "" {
+		var err error
+		if id, err = newID(); err != nil {
+			return nil, false, calque.WrapErr(ctx, err, "failed to generate session ID")
+		}
+	}
+
+	now := m.config.Now()
+	var ends []ended
+
+	m.mu.Lock()
+	if s, ok := m.lookup(id, now, &ends); ok {
+		m.mu.Unlock()
+		m.notify(ends)
+		return s, false, nil
+	}
+
+	s := &Session{ID: id, CreatedAt: now, MemoryKey: id, Budget: m.config.Budget, lastAccess: now}
+	m.sessions[id] = m.lru.PushFront(s)
+	for m.config.MaxSessions > 0 && m.lru.Len() > m.config.MaxSessions {
+		ends = append(ends, ended{m.remove(m.lru.Back()), ReasonEvicted})
+	}
+	m.mu.Unlock()
+
+	m.notify(ends)
+	if m.config.OnCreate != nil {
+		m.config.OnCreate(ctx, s)
+	}
+	return s, true, nil
+}
+
+// Get returns the live session with id and marks it as recently used.
+//
+// Returns false if the session does not exist or has expired.
+func (m *Manager) Get(id string) (*Session, bool) {
+	var ends []ended
+	m.mu.Lock()
+	s, ok := m.lookup(id, m.config.Now(), &ends)
+	m.mu.Unlock()
+	m.notify(ends)
+	return s, ok
+}
+
+// lookup finds a live session and refreshes it, expiring it if idle too long.
+// Caller must hold mu.
+func (m *Manager) lookup(id string, now time.Time, ends *[]ended) (*Session, bool) {
+	elem, ok := m.sessions[id]
+	if !ok {
+		return nil, false
+	}
+	s := elem.Value.(*Session)
+	if m.expired(s, now) {
+		*ends = append(*ends, ended{m.remove(elem), ReasonExpired})
+		return nil, false
+	}
+	s.mu.Lock()
+	s.lastAccess = now
+	s.mu.Unlock()
+	m.lru.MoveToFront(elem)
+	return s, true
+}
+
+// Delete ends the session with id, reporting whether it existed.
+func (m *Manager) Delete(id string) bool {
+	m.mu.Lock()
+	elem, ok := m.sessions[id]
+	var s *Session
+	if ok {
+		s = m.remove(elem)
+	}
+	m.mu.Unlock()
+
+	if ok {
+		m.notify([]ended{{s, ReasonDeleted}})
+	}
+	return ok
+}
+
+// Len returns the number of sessions held, including expired ones not yet swept.
+func (m *Manager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lru.Len()
+}
+
+// Sweep removes all expired sessions and returns how many were removed.
+func (m *Manager) Sweep() int {
+	now := m.config.Now()
+	var ends []ended
+
+	m.mu.Lock()
+	// walk from least recently used; stop at the first live session
+	for elem := m.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		s := elem.Value.(*Session)
+		if !m.expired(s, now) {
+			break
+		}
+		ends = append(ends, ended{m.remove(elem), ReasonExpired})
+		elem = prev
+	}
+	m.mu.Unlock()
+
+	m.notify(ends)
+	return len(ends)
+}
+
+// Start runs Sweep every interval in the background until Close is called.
+//
+// Example:
+//
+//	sessions.Start(time.Minute)
+//	defer sessions.Close()
+func (m *Manager) Start(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.Sweep()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the background sweeper. Sessions stay available.
+func (m *Manager) Close() error {
+	m.stopOnce.Do(func() { close(m.stop) })
+	return nil
+}
+
+// expired reports whether s has been idle longer than the TTL
+func (m *Manager) expired(s *Session, now time.Time) bool {
+	if m.config.TTL < 0 {
+		return false
+	}
+	return now.Sub(s.LastAccess()) > m.config.TTL
+}
+
+// remove unlinks a session. Caller must hold mu.
+func (m *Manager) remove(elem *list.Element) *Session {
+	s := m.lru.Remove(elem).(*Session)
+	delete(m.sessions, s.ID)
+	return s
+}
+
+// notify releases the handlers of ended sessions and runs OnExpire, outside the lock
+func (m *Manager) notify(ends []ended) {
+	for _, e := range ends {
+		e.session.release()
+		if m.config.OnExpire != nil {
+			m.config.OnExpire(e.session, e.reason)
+		}
+	}
+}
+
+// newID returns a random 128-bit hex session ID
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HTTP is httpserver middleware that resolves the session for each request.
+//
+// Input: HTTP request, optionally with the session ID in Config.Header
+// Output: request passed to next with the session in its context
+// Behavior: Looks up the session and echoes its ID in the response header
+//
+// Only IDs of live sessions are honoured. A missing, unknown or expired ID
+// gets a new session with a random ID, so a client cannot choose its session
+// ID or another user's memory key.
+//
+// Example:
+//
+//	http.Handle("POST /chat", sessions.HTTP(httpserver.Handler(flow)))
+func (m *Manager) HTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := m.Get(r.Header.Get(m.config.Header))
+		if !ok {
+			var err error
+			if s, _, err = m.GetOrCreate(r.Context(), ""); err != nil {
+				httpserver.WriteError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+				return
+			}
+		}
+		w.Header().Set(m.config.Header, s.ID)
+		next.ServeHTTP(w, r.WithContext(WithSession(r.Context(), s)))
+	})
+}
+
+// Handler enforces the session budget on a handler.
+//
+// Input: any data type (passed to the wrapped handler)
+// Output: same as wrapped handler's output
+// Behavior: STREAMING - runs the handler under ctrl.Budget
+//
+// Each run gets the budget still left in the request's session, and its
+// consumption is added to the session's Usage afterwards. Runs are refused
+// with a *ctrl.BudgetExceededError once any session limit is used up.
+// Requests without a session run unchanged.
+//
+// Example:
+//
+//	http.Handle("POST /chat", sessions.HTTP(httpserver.Handler(sessions.Handler(flow))))
+func (m *Manager) Handler(h calque.Handler) calque.Handler {
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		s := FromContext(req.Context)
+		if s == nil {
+			return h.ServeFlow(req, res)
+		}
+
+		limits, err := s.remaining()
+		if err != nil {
+			return calque.WrapErr(req.Context, err, "session "+s.ID)
+		}
+
+		tracked := calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+			defer func() {
+				if usage, ok := ctrl.BudgetUsed(req.Context); ok {
+					s.addUsage(usage)
+				}
+			}()
+			return h.ServeFlow(req, res)
+		})
+		return ctrl.Budget(tracked, limits).ServeFlow(req, res)
+	})
+}
+
+// perSession builds and caches a handler per session
+type perSession struct {
+	factory func(s *Session) (calque.Handler, error)
+}
+
+// PerSession creates a handler that delegates to a per-session instance built by factory.
+//
+// Input: any data type (passed to the session's handler)
+// Output: same as the session's handler
+// Behavior: STREAMING - delegates directly to the session's handler
+//
+// The factory is called once per session and its handler is reused for the
+// session's later requests. When the session ends the handler is dropped and,
+// if it implements io.Closer, closed. Use it to
+// bind middleware that takes a key at construction, such as conversation
+// memory, to Session.MemoryKey. Requests without a session are rejected.
+//
+// Example:
+//
+//	mem := memory.NewConversation()
+//	flow.Use(sessions.PerSession(func(s *session.Session) (calque.Handler, error) {
+//		return calque.NewFlow().Use(mem.Input(s.MemoryKey)).Use(ai.Agent(client)).Use(mem.Output(s.MemoryKey)), nil
+//	}))
+func (m *Manager) PerSession(factory func(s *Session) (calque.Handler, error)) calque.Handler {
+	p := &perSession{factory: factory}
+	return calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		s := FromContext(req.Context)
+		if s == nil {
+			return calque.NewErr(req.Context, "no session in context")
+		}
+		h, err := p.handler(req.Context, s)
+		if err != nil {
+			return err
+		}
+		return h.ServeFlow(req, res)
+	})
+}
+
+// handler returns the cached handler for the session, building it on first use
+func (p *perSession) handler(ctx context.Context, s *Session) (calque.Handler, error) {
+	s.mu.Lock()
+	h, ok := s.handlers[p]
+	s.mu.Unlock()
+	if ok {
+		return h, nil
+	}
+
+	// build outside the lock so the factory can use the session
+	h, err := p.factory(s)
+	if err != nil {
+		return nil, calque.WrapErr(ctx, err, "failed to create handler for session "+s.ID)
+	}
+	if h == nil {
+		return nil, calque.NewErr(ctx, "no handler for session "+s.ID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.handlers[p]; ok {
+		return existing, nil // a concurrent request built it first
+	}
+	if s.handlers == nil {
+		s.handlers = make(map[*perSession]calque.Handler)
+	}
+	s.handlers[p] = h
+	return h, nil
+}
@@ -0,0 +1,348 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+)
+
+// fakeClock is a manually advanced clock
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// hookRecorder records OnCreate and OnExpire calls
+type hookRecorder struct {
+	mu      sync.Mutex
+	created []string
+	ended   []string
+}
+
+func (h *hookRecorder) config(cfg Config) Config {
+	cfg.OnCreate = func(_ context.Context, s *Session) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.created = append(h.created, s.ID)
+	}
+	cfg.OnExpire = func(s *Session, reason EndReason) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.ended = append(h.ended, s.ID+":"+string(reason))
+	}
+	return cfg
+}
+
+func (h *hookRecorder) endedString() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return strings.Join(h.ended, ",")
+}
+
+func TestManagerGetOrCreate(t *testing.T) {
+	ctx := context.Background()
+	hooks := &hookRecorder{}
+	m := NewManager(hooks.config(Config{}))
+
+	s, created, err := m.GetOrCreate(ctx, "alice")
+	if err != nil || !created || s.ID != "alice" || s.MemoryKey != "alice" {
+		t.Fatalf("GetOrCreate = %+v, %v, %v", s, created, err)
+	}
+	again, created, _ := m.GetOrCreate(ctx, "alice")
+	if created || again != s {
+		t.Errorf("second GetOrCreate created = %v, same = %v", created, again == s)
+	}
+
+	anon, created, _ := m.GetOrCreate(ctx, "")
+	if !created || len(anon.ID) != 32 {
+		t.Errorf("anonymous session ID = %q", anon.ID)
+	}
+	if len(hooks.created) != 2 || m.Len() != 2 {
+		t.Errorf("created hooks = %v, len = %d", hooks.created, m.Len())
+	}
+}
+
+func TestManagerTTL(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	hooks := &hookRecorder{}
+	m := NewManager(hooks.config(Config{TTL: time.Minute, Now: clock.Now}))
+
+	_, _, _ = m.GetOrCreate(ctx, "a")
+	_, _, _ = m.GetOrCreate(ctx, "b")
+
+	clock.Advance(50 * time.Second)
+	if _, ok := m.Get("a"); !ok {
+		t.Fatal("session a expired before its TTL")
+	}
+
+	// a was refreshed 50s ago; b was last used 100s ago
+	clock.Advance(50 * time.Second)
+	if _, ok := m.Get("b"); ok {
+		t.Error("session b outlived its TTL")
+	}
+	if _, ok := m.Get("a"); !ok {
+		t.Error("refreshed session a expired")
+	}
+
+	clock.Advance(2 * time.Minute)
+	if n := m.Sweep(); n != 1 || m.Len() != 0 {
+		t.Errorf("Sweep removed %d, %d left", n, m.Len())
+	}
+	if got := hooks.endedString(); got != "b:expired,a:expired" {
+		t.Errorf("ended = %s", got)
+	}
+
+	// an expired ID is recreated fresh
+	s, created, _ := m.GetOrCreate(ctx, "a")
+	if !created || !s.CreatedAt.Equal(clock.Now()) {
+		t.Errorf("recreated = %v, created at %v", created, s.CreatedAt)
+	}
+}
+
+func TestManagerNoExpiry(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	m := NewManager(Config{TTL: -1, Now: clock.Now})
+	_, _, _ = m.GetOrCreate(context.Background(), "a")
+	clock.Advance(24 * time.Hour)
+	if _, ok := m.Get("a"); !ok || m.Sweep() != 0 {
+		t.Error("session expired with expiry disabled")
+	}
+}
+
+func TestManagerEviction(t *testing.T) {
+	ctx := context.Background()
+	hooks := &hookRecorder{}
+	m := NewManager(hooks.config(Config{MaxSessions: 2}))
+
+	_, _, _ = m.GetOrCreate(ctx, "a")
+	_, _, _ = m.GetOrCreate(ctx, "b")
+	m.Get("a") // b is now least recently used
+	_, _, _ = m.GetOrCreate(ctx, "c")
+
+	if _, ok := m.Get("b"); ok {
+		t.Error("least recently used session b was not evicted")
+	}
+	if m.Len() != 2 || hooks.endedString() != "b:evicted" {
+		t.Errorf("len = %d, ended = %s", m.Len(), hooks.endedString())
+	}
+
+	if !m.Delete("a") || m.Delete("a") {
+		t.Error("Delete did not report existence correctly")
+	}
+	if got := hooks.endedString(); got != "b:evicted,a:deleted" {
+		t.Errorf("ended = %s", got)
+	}
+}
+
+func TestManagerStartClose(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	expired := make(chan string, 1)
+	m := NewManager(Config{TTL: time.Second, Now: clock.Now, OnExpire: func(s *Session, _ EndReason) { expired <- s.ID }})
+	_, _, _ = m.GetOrCreate(context.Background(), "a")
+
+	clock.Advance(time.Minute)
+	m.Start(time.Millisecond)
+	defer m.Close()
+
+	select {
+	case id := <-expired:
+		if id != "a" {
+			t.Errorf("expired %s", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("background sweep did not run")
+	}
+	if err := m.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+}
+
+func TestManagerHTTP(t *testing.T) {
+	m := NewManager()
+	var seen *Session
+	h := m.HTTP(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		seen = FromContext(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat", nil))
+	id := rec.Header().Get(Header)
+	if seen == nil || id == "" || seen.ID != id {
+		t.Fatalf("new session: header %q, context %+v", id, seen)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/chat", nil)
+	req.Header.Set(Header, id)
+	first := seen
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if seen != first {
+		t.Error("request with session header got a different session")
+	}
+}
+
+func TestManagerHTTPUnknownID(t *testing.T) {
+	m := NewManager()
+	var seen *Session
+	h := m.HTTP(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		seen = FromContext(r.Context())
+	}))
+
+	victim, _, _ := m.GetOrCreate(context.Background(), "")
+	m.Delete(victim.ID)
+
+	for _, id := range []string{"attacker-chosen", victim.ID} {
+		req := httptest.NewRequest(http.MethodPost, "/chat", nil)
+		req.Header.Set(Header, id)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if seen == nil || seen.ID == id || seen.MemoryKey == id {
+			t.Errorf("client ID %q was adopted: %+v", id, seen)
+		}
+		if got := rec.Header().Get(Header); got != seen.ID {
+			t.Errorf("response header = %q, want the new ID %q", got, seen.ID)
+		}
+		if _, ok := m.Get(id); ok {
+			t.Errorf("session %q was created from the client's ID", id)
+		}
+	}
+}
+
+func TestManagerHandlerBudget(t *testing.T) {
+	m := NewManager(Config{Budget: ctrl.BudgetLimits{MaxTokens: 100}})
+	s, _, _ := m.GetOrCreate(context.Background(), "a")
+	ctx := WithSession(context.Background(), s)
+
+	charges := []int{60, 40}
+	run := 0
+	h := m.Handler(calque.HandlerFunc(func(req *calque.Request, res *calque.Response) error {
+		if err := ctrl.ChargeTokens(req.Context, charges[run]); err != nil {
+			return err
+		}
+		run++
+		return calque.Write(res, "ok")
+	}))
+
+	for i := range charges {
+		var out string
+		if err := calque.NewFlow().Use(h).Run(ctx, "hi", &out); err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+	}
+	if got := s.Usage().Tokens; got != 100 {
+		t.Errorf("session tokens = %d, want 100", got)
+	}
+
+	var out string
+	err := calque.NewFlow().Use(h).Run(ctx, "hi", &out)
+	if !errors.Is(err, ctrl.ErrBudgetExceeded) || run != 2 {
+		t.Errorf("run over budget: err = %v, handler runs = %d", err, run)
+	}
+
+	// requests without a session are not budgeted
+	if err := calque.NewFlow().Use(m.Handler(calque.HandlerFunc(func(_ *calque.Request, res *calque.Response) error {
+		return calque.Write(res, "ok")
+	}))).Run(context.Background(), "hi", &out); err != nil || out != "ok" {
+		t.Errorf("no session: out = %q, err = %v", out, err)
+	}
+}
+
+func TestManagerPerSession(t *testing.T) {
+	m := NewManager()
+	builds := map[string]int{}
+	h := m.PerSession(func(s *Session) (calque.Handler, error) {
+		builds[s.ID]++
+		if s.ID == "broken" {
+			return nil, errors.New("no config")
+		}
+		key := s.MemoryKey
+		return calque.HandlerFunc(func(_ *calque.Request, res *calque.Response) error {
+			return calque.Write(res, key)
+		}), nil
+	})
+
+	run := func(ctx context.Context) (string, error) {
+		var out string
+		err := calque.NewFlow().Use(h).Run(ctx, "hi", &out)
+		return out, err
+	}
+
+	for _, id := range []string{"a", "b", "a"} {
+		s, _, _ := m.GetOrCreate(context.Background(), id)
+		out, err := run(WithSession(context.Background(), s))
+		if err != nil || out != id {
+			t.Errorf("session %s: out = %q, err = %v", id, out, err)
+		}
+	}
+	if builds["a"] != 1 || builds["b"] != 1 {
+		t.Errorf("builds = %v, want one per session", builds)
+	}
+
+	broken, _, _ := m.GetOrCreate(context.Background(), "broken")
+	if _, err := run(WithSession(context.Background(), broken)); err == nil || !strings.Contains(err.Error(), "no config") {
+		t.Errorf("factory error = %v", err)
+	}
+	if _, err := run(context.Background()); err == nil {
+		t.Error("expected error without a session")
+	}
+}
+
+// closingHandler records when a per-session handler is closed
+type closingHandler struct {
+	closed bool
+}
+
+func (h *closingHandler) ServeFlow(_ *calque.Request, res *calque.Response) error {
+	return calque.Write(res, "ok")
+}
+
+func (h *closingHandler) Close() error {
+	h.closed = true
+	return nil
+}
+
+func TestManagerPerSessionRelease(t *testing.T) {
+	m := NewManager(Config{MaxSessions: 1})
+	built := map[string]*closingHandler{}
+	h := m.PerSession(func(s *Session) (calque.Handler, error) {
+		built[s.ID] = &closingHandler{}
+		return built[s.ID], nil
+	})
+
+	for _, id := range []string{"a", "b"} {
+		s, _, _ := m.GetOrCreate(context.Background(), id)
+		var out string
+		if err := calque.NewFlow().Use(h).Run(WithSession(context.Background(), s), "hi", &out); err != nil {
+			t.Fatalf("session %s: %v", id, err)
+		}
+	}
+	if !built["a"].closed {
+		t.Error("evicted session's handler was not closed")
+	}
+
+	m.Delete("b")
+	if !built["b"].closed {
+		t.Error("deleted session's handler was not closed")
+	}
+}
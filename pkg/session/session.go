@@ -0,0 +1,175 @@
+// Package session manages per-user session objects shared by flow adapters.
+//
+// A Manager hands out Sessions keyed by ID, expires them after a period of
+// inactivity and evicts the least recently used ones beyond a maximum count.
+// Each Session carries the memory key, budget and metadata for one user, so
+// the HTTP adapter (Manager.HTTP) and any WebSocket or chat adapter resolve
+// the same state once and pass it to flows in the context:
+//
+//	sessions := session.NewManager(session.Config{
+//		TTL:         30 * time.Minute,
+//		MaxSessions: 10000,
+//		Budget:      ctrl.BudgetLimits{MaxTokens: 200000},
+//	})
+//	sessions.Start(time.Minute)
+//	defer sessions.Close()
+//
+//	mem := memory.NewConversation()
+//	flow := calque.NewFlow().Use(sessions.PerSession(func(s *session.Session) (calque.Handler, error) {
+//		return calque.NewFlow().Use(mem.Input(s.MemoryKey)).Use(ai.Agent(client)).Use(mem.Output(s.MemoryKey)), nil
+//	}))
+//	http.Handle("POST /chat", sessions.HTTP(httpserver.Handler(sessions.Handler(flow))))
+package session
+
+import (
+	"context"
+	"io"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/calque"
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+)
+
+// Session is the per-user state kept between requests.
+//
+// ID, CreatedAt, MemoryKey and Budget are set when the session is created and
+// must not be changed afterwards; metadata and usage are safe for concurrent use.
+type Session struct {
+	ID        string
+	CreatedAt time.Time
+
+	// MemoryKey is the key for memory middleware such as ConversationMemory (default: ID)
+	MemoryKey string
+
+	// Budget caps the total consumption across all runs in the session (see Manager.Handler)
+	Budget ctrl.BudgetLimits
+
+	mu         sync.Mutex
+	lastAccess time.Time
+	usage      ctrl.BudgetUsage
+	metadata   map[string]any
+	handlers   map[*perSession]calque.Handler
+}
+
+// release drops the cached per-session handlers, closing those that are io.Closers
+func (s *Session) release() {
+	s.mu.Lock()
+	handlers := s.handlers
+	s.handlers = nil
+	s.mu.Unlock()
+	for _, h := range handlers {
+		if closer, ok := h.(io.Closer); ok {
+			_ = closer.Close()
+		}
+	}
+}
+
+// LastAccess returns when the session was last retrieved from its Manager.
+func (s *Session) LastAccess() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastAccess
+}
+
+// Get returns a metadata value.
+func (s *Session) Get(key string) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.metadata[key]
+	return v, ok
+}
+
+// Set stores a metadata value.
+func (s *Session) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.metadata == nil {
+		s.metadata = make(map[string]any)
+	}
+	s.metadata[key] = value
+}
+
+// Metadata returns a copy of all metadata values.
+func (s *Session) Metadata() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.metadata)
+}
+
+// Usage returns the consumption recorded across the session's runs.
+func (s *Session) Usage() ctrl.BudgetUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage
+}
+
+// addUsage accumulates the consumption of one run
+func (s *Session) addUsage(u ctrl.BudgetUsage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usage.Tokens += u.Tokens
+	s.usage.CostUSD += u.CostUSD
+	s.usage.ToolCalls += u.ToolCalls
+	s.usage.Elapsed += u.Elapsed
+}
+
+// remaining returns the per-run limits left in the session budget, or an
+// error if a limit is already used up
+func (s *Session) remaining() (ctrl.BudgetLimits, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limits, used := s.Budget, s.usage
+	switch {
+	case limits.MaxTokens > 0 && used.Tokens >= limits.MaxTokens:
+		return limits, &ctrl.BudgetExceededError{Resource: ctrl.BudgetTokens, Limit: float64(limits.MaxTokens), Used: float64(used.Tokens)}
+	case limits.MaxCostUSD > 0 && used.CostUSD >= limits.MaxCostUSD:
+		return limits, &ctrl.BudgetExceededError{Resource: ctrl.BudgetCost, Limit: limits.MaxCostUSD, Used: used.CostUSD}
+	case limits.MaxToolCalls > 0 && used.ToolCalls >= limits.MaxToolCalls:
+		return limits, &ctrl.BudgetExceededError{Resource: ctrl.BudgetToolCalls, Limit: float64(limits.MaxToolCalls), Used: float64(used.ToolCalls)}
+	case limits.MaxDuration > 0 && used.Elapsed >= limits.MaxDuration:
+		return limits, &ctrl.BudgetExceededError{Resource: ctrl.BudgetDuration, Limit: limits.MaxDuration.Seconds(), Used: used.Elapsed.Seconds()}
+	}
+
+	if limits.MaxTokens > 0 {
+		limits.MaxTokens -= used.Tokens
+	}
+	if limits.MaxCostUSD > 0 {
+		limits.MaxCostUSD -= used.CostUSD
+	}
+	if limits.MaxToolCalls > 0 {
+		limits.MaxToolCalls -= used.ToolCalls
+	}
+	if limits.MaxDuration > 0 {
+		limits.MaxDuration -= used.Elapsed
+	}
+	return limits, nil
+}
+
+type sessionContextKey struct{}
+
+// WithSession stores the session for the current request in the context.
+//
+// Adapters call it after resolving the session; Manager.HTTP does so for HTTP.
+//
+// Example:
+//
+//	s, _ := sessions.GetOrCreate(ctx, conn.UserID())
+//	err := flow.Run(session.WithSession(ctx, s), msg, &reply)
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, s)
+}
+
+// FromContext returns the session in the context, or nil if none is set.
+//
+// Example:
+//
+//	if s := session.FromContext(ctx); s != nil {
+//	    s.Set("last_topic", topic)
+//	}
+func FromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionContextKey{}).(*Session)
+	return s
+}
@@ -0,0 +1,79 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/calque-ai/go-calque/pkg/middleware/ctrl"
+)
+
+func TestSessionMetadata(t *testing.T) {
+	s := &Session{ID: "s1"}
+	if _, ok := s.Get("topic"); ok {
+		t.Error("Get on empty session found a value")
+	}
+	s.Set("topic", "billing")
+	if v, ok := s.Get("topic"); !ok || v != "billing" {
+		t.Errorf("Get = %v, %v", v, ok)
+	}
+
+	copied := s.Metadata()
+	copied["topic"] = "changed"
+	if v, _ := s.Get("topic"); v != "billing" {
+		t.Errorf("Metadata returned a live map, topic = %v", v)
+	}
+}
+
+func TestSessionRemaining(t *testing.T) {
+	tests := []struct {
+		name     string
+		budget   ctrl.BudgetLimits
+		usage    ctrl.BudgetUsage
+		want     ctrl.BudgetLimits
+		resource string
+	}{
+		{name: "unlimited", budget: ctrl.BudgetLimits{}, usage: ctrl.BudgetUsage{Tokens: 500}, want: ctrl.BudgetLimits{}},
+		{
+			name:   "partly used",
+			budget: ctrl.BudgetLimits{MaxTokens: 1000, MaxToolCalls: 5, MaxDuration: time.Minute},
+			usage:  ctrl.BudgetUsage{Tokens: 400, ToolCalls: 2, Elapsed: 20 * time.Second},
+			want:   ctrl.BudgetLimits{MaxTokens: 600, MaxToolCalls: 3, MaxDuration: 40 * time.Second},
+		},
+		{name: "tokens used up", budget: ctrl.BudgetLimits{MaxTokens: 100}, usage: ctrl.BudgetUsage{Tokens: 100}, resource: ctrl.BudgetTokens},
+		{name: "cost used up", budget: ctrl.BudgetLimits{MaxCostUSD: 1}, usage: ctrl.BudgetUsage{CostUSD: 1.5}, resource: ctrl.BudgetCost},
+		{name: "duration used up", budget: ctrl.BudgetLimits{MaxDuration: time.Second}, usage: ctrl.BudgetUsage{Elapsed: time.Second}, resource: ctrl.BudgetDuration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Session{Budget: tt.budget}
+			s.addUsage(tt.usage)
+			got, err := s.remaining()
+
+			if tt.resource != "" {
+				var budgetErr *ctrl.BudgetExceededError
+				if !errors.As(err, &budgetErr) || budgetErr.Resource != tt.resource {
+					t.Fatalf("error = %v, want %s exceeded", err, tt.resource)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("remaining = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	if FromContext(context.Background()) != nil {
+		t.Error("FromContext on empty context returned a session")
+	}
+	s := &Session{ID: "s1"}
+	if got := FromContext(WithSession(context.Background(), s)); got != s {
+		t.Errorf("FromContext = %v, want %v", got, s)
+	}
+}